@@ -0,0 +1,517 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"melibot/database"
+	"melibot/internal/capture"
+	"melibot/internal/handlers"
+	"melibot/internal/repository"
+	"melibot/internal/secrets"
+	"melibot/internal/sentry"
+	"melibot/internal/service"
+	"melibot/internal/storage"
+	"melibot/internal/warehouse"
+	"melibot/pkg/meli"
+	"melibot/pkg/meli/vcr"
+)
+
+// app is the configuration shared by every subcommand: OAuth, sandbox and
+// multi-tenant mode and the Mercado Livre client options, read from the
+// environment.
+type app struct {
+	clientID    string
+	clientOpts  []meli.Option
+	sandboxMode bool
+	multiTenant bool
+	// limiter paces the outbound calls of every client
+	limiter *meli.Limiter
+	// orgs holds the tokens of each organization, nil unless multi-tenant
+	orgs *service.OrganizationService
+	// refreshSecrets fetches the secrets of secretEnv again, nil when none
+	// comes from a secret manager
+	refreshSecrets func(ctx context.Context) error
+	// capture keeps recent HTTP exchanges, redacted, for debugging
+	capture *capture.Recorder
+	// tracker reports errors to Sentry, nil unless SENTRY_DSN is set
+	tracker *sentry.Tracker
+	// settings reloads the tuning settings of .env, see SettingsService
+	settings *service.SettingsService
+}
+
+func newApp() (*app, error) {
+	// Secrets kept in a secret manager, before anything reads them
+	refreshSecrets, err := resolveSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	// Outbound proxies for Mercado Livre calls, OAuth included
+	proxies, err := proxyRouter()
+	if err != nil {
+		return nil, err
+	}
+	var transport http.RoundTripper = http.DefaultTransport
+	var oauthOpts []meli.OAuthOption
+	if proxies != nil {
+		t := meli.NewProxyTransport(proxies.Proxy)
+		transport = t
+		oauthOpts = append(oauthOpts, meli.WithOAuthTransport(t))
+	}
+	// Debug capture of recent exchanges, e.g. DEBUG_CAPTURE=true; admins can
+	// also switch it on at runtime, so Mercado Livre calls always go through it
+	recorder := capture.New(envInt("DEBUG_CAPTURE_SIZE", 200))
+	recorder.SetEnabled(os.Getenv("DEBUG_CAPTURE") == "true")
+	transport = recorder.Transport(transport)
+	// Error reports to Sentry, with the Mercado Livre calls leading to them
+	tracker, err := errorTracker()
+	if err != nil {
+		return nil, err
+	}
+	if tracker != nil {
+		transport = sentry.Transport(transport)
+	}
+
+	// Initialize OAuth client with loaded environment variables
+	handlers.InitializeOAuth(oauthOpts...)
+
+	a := &app{
+		clientID:       os.Getenv("ML_CLIENT_ID"),
+		refreshSecrets: refreshSecrets,
+		capture:        recorder,
+		tracker:        tracker,
+		settings:       service.NewSettingsService(".env"),
+		// Sandbox mode: work against Mercado Livre test users only
+		sandboxMode: os.Getenv("ML_ENVIRONMENT") == "sandbox",
+		// Multi-tenant mode: every row belongs to an organization, reached
+		// with its API key
+		multiTenant: os.Getenv("MULTI_TENANT") == "true",
+	}
+
+	a.clientOpts = append(a.clientOpts, meli.WithTransport(transport))
+	if siteID := os.Getenv("ML_SITE_ID"); siteID != "" {
+		a.clientOpts = append(a.clientOpts, meli.WithSiteID(siteID))
+	}
+	// Per-endpoint timeouts, e.g. ML_ENDPOINT_TIMEOUTS=detail=3s,search=20s
+	for _, pair := range splitList(os.Getenv("ML_ENDPOINT_TIMEOUTS")) {
+		name, value, _ := strings.Cut(pair, "=")
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("[WARN] invalid ML_ENDPOINT_TIMEOUTS entry %q: %v", pair, err)
+			continue
+		}
+		a.clientOpts = append(a.clientOpts, meli.WithEndpointTimeout(name, d))
+	}
+	// Listing statuses seen while validating best prices, shared by every
+	// client so concurrent and back-to-back requests reuse them
+	statuses := meli.NewItemStatusCache(envDuration("ITEM_STATUS_TTL", meli.DefaultItemStatusTTL))
+	a.clientOpts = append(a.clientOpts, meli.WithItemStatusCache(statuses))
+	// Listing and catalog product details, revalidated by last_updated once
+	// stale, so repeated trend entries are not fetched again
+	details := meli.NewDetailCache(envDuration("ITEM_DETAIL_TTL", meli.DefaultDetailTTL))
+	a.clientOpts = append(a.clientOpts, meli.WithDetailCache(details))
+	// Outbound calls shared by every client, e.g. ML_MAX_CONCURRENCY=10 and
+	// ML_RATE_LIMIT=20 (calls per second); interactive calls go first
+	a.limiter = meli.NewLimiter(envFloat("ML_RATE_LIMIT", 0), envInt("ML_MAX_CONCURRENCY", 10))
+	a.clientOpts = append(a.clientOpts, meli.WithLimiter(a.limiter))
+	// Tuning settings picked up by a reload, see SettingsService
+	a.settings.Watch(func() { setLogLevel(os.Getenv("LOG_LEVEL")) }, "LOG_LEVEL")
+	a.settings.Watch(func() {
+		statuses.SetTTL(envDuration("ITEM_STATUS_TTL", meli.DefaultItemStatusTTL))
+	}, "ITEM_STATUS_TTL")
+	a.settings.Watch(func() {
+		details.SetTTL(envDuration("ITEM_DETAIL_TTL", meli.DefaultDetailTTL))
+	}, "ITEM_DETAIL_TTL")
+	a.settings.Watch(func() {
+		a.limiter.SetLimits(envFloat("ML_RATE_LIMIT", 0), envInt("ML_MAX_CONCURRENCY", 10))
+	}, "ML_RATE_LIMIT", "ML_MAX_CONCURRENCY")
+	// Refresh expired tokens and retry once on 401
+	a.clientOpts = append(a.clientOpts, meli.WithTokenRefresher(handlers.Tokens()))
+	// Verbose price lookup tracing, see /api/admin/debug/traces
+	meli.SetDebugProducts(splitList(os.Getenv("ML_DEBUG_PRODUCTS")))
+	if mode := os.Getenv("ML_VCR_MODE"); mode != "" {
+		dir := os.Getenv("ML_VCR_DIR")
+		if dir == "" {
+			dir = "testdata/vcr"
+		}
+		recorder, err := vcr.New(mode, dir, transport)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up VCR: %w", err)
+		}
+		a.clientOpts = append(a.clientOpts, meli.WithTransport(recorder))
+		log.Printf("[WARN] VCR %s mode enabled, fixtures in %s", mode, dir)
+	}
+	return a, nil
+}
+
+// proxyRouter reads the outbound proxies: ML_PROXY is a comma-separated
+// pool used in turn for every call, and ML_ACCOUNT_PROXIES gives accounts
+// their own pools, e.g. 123456789=http://p1:3128|http://p2:3128. It
+// returns nil when neither is set, leaving the standard HTTPS_PROXY and
+// NO_PROXY variables in charge.
+func proxyRouter() (*meli.ProxyRouter, error) {
+	defaultPool := splitList(os.Getenv("ML_PROXY"))
+	accounts := splitList(os.Getenv("ML_ACCOUNT_PROXIES"))
+	if len(defaultPool) == 0 && len(accounts) == 0 {
+		return nil, nil
+	}
+
+	router := &meli.ProxyRouter{Accounts: make(map[int64]*meli.ProxyPool)}
+	if len(defaultPool) > 0 {
+		pool, err := meli.NewProxyPool(defaultPool)
+		if err != nil {
+			return nil, fmt.Errorf("ML_PROXY: %w", err)
+		}
+		router.Default = pool
+		log.Printf("[INFO] Mercado Livre calls go through %s", pool)
+	}
+	for _, entry := range accounts {
+		user, list, _ := strings.Cut(entry, "=")
+		id, err := strconv.ParseInt(strings.TrimSpace(user), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ML_ACCOUNT_PROXIES: %q is not a user ID", user)
+		}
+		pool, err := meli.NewProxyPool(strings.Split(list, "|"))
+		if err != nil {
+			return nil, fmt.Errorf("ML_ACCOUNT_PROXIES: account %d: %w", id, err)
+		}
+		router.Accounts[id] = pool
+		log.Printf("[INFO] Mercado Livre calls of account %d go through %s", id, pool)
+	}
+	return router, nil
+}
+
+// exportBucket reads the object storage export jobs upload to:
+// EXPORT_STORAGE is s3 (any S3-compatible service, see
+// EXPORT_STORAGE_ENDPOINT) or gcs (HMAC keys of the XML API). The keys are
+// read from the s3 credential named by EXPORT_STORAGE_CREDENTIAL
+// (export-storage by default) when creds has it, or from the environment.
+// It returns nil when EXPORT_STORAGE is unset.
+func exportBucket(creds *service.CredentialService) (*storage.Bucket, error) {
+	provider := os.Getenv("EXPORT_STORAGE")
+	if provider == "" {
+		return nil, nil
+	}
+	accessKey := envString("EXPORT_STORAGE_ACCESS_KEY", os.Getenv("AWS_ACCESS_KEY_ID"))
+	secretKey := envString("EXPORT_STORAGE_SECRET_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	if name := envString("EXPORT_STORAGE_CREDENTIAL", "export-storage"); creds != nil {
+		access, secret, err := creds.S3Keys(instanceCredentials(), name)
+		switch {
+		case err == nil:
+			accessKey, secretKey = access, secret
+			log.Printf("[INFO] Export storage keys read from credential %s", name)
+		case !errors.Is(err, service.ErrCredentialNotFound):
+			return nil, fmt.Errorf("EXPORT_STORAGE_CREDENTIAL %s: %w", name, err)
+		}
+	}
+	bucket, err := storage.New(storage.Config{
+		Provider:  provider,
+		Endpoint:  os.Getenv("EXPORT_STORAGE_ENDPOINT"),
+		Region:    os.Getenv("EXPORT_STORAGE_REGION"),
+		Bucket:    os.Getenv("EXPORT_STORAGE_BUCKET"),
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("EXPORT_STORAGE: %w", err)
+	}
+	log.Printf("[INFO] Exports are uploaded to %s", bucket)
+	return bucket, nil
+}
+
+// alertNotifier reads the channels alerts are sent to: a Telegram chat
+// (TELEGRAM_CHAT_ID) and email (SMTP_HOST, SMTP_PORT, SMTP_USERNAME,
+// SMTP_FROM and the comma-separated ALERT_EMAIL_TO). The bot token and SMTP
+// password are read from the credentials named by TELEGRAM_CREDENTIAL
+// (telegram by default) and SMTP_CREDENTIAL (smtp) when creds has them, or
+// from TELEGRAM_BOT_TOKEN and SMTP_PASSWORD. It returns nil without
+// channels.
+func alertNotifier(creds *service.CredentialService) (*service.AlertNotifier, error) {
+	var channels []service.AlertChannel
+	if chatID := os.Getenv("TELEGRAM_CHAT_ID"); chatID != "" {
+		token, err := storedSecret(creds, "TELEGRAM_CREDENTIAL", "telegram", "TELEGRAM_BOT_TOKEN")
+		if err != nil {
+			return nil, err
+		}
+		if token == "" {
+			return nil, errors.New("TELEGRAM_CHAT_ID needs a telegram credential or TELEGRAM_BOT_TOKEN")
+		}
+		channels = append(channels, service.NewTelegramChannel(token, chatID))
+	}
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		to := splitList(os.Getenv("ALERT_EMAIL_TO"))
+		if len(to) == 0 {
+			return nil, errors.New("SMTP_HOST needs ALERT_EMAIL_TO")
+		}
+		password, err := storedSecret(creds, "SMTP_CREDENTIAL", "smtp", "SMTP_PASSWORD")
+		if err != nil {
+			return nil, err
+		}
+		username := os.Getenv("SMTP_USERNAME")
+		addr := net.JoinHostPort(host, envString("SMTP_PORT", "587"))
+		channels = append(channels, service.NewSMTPChannel(addr, username, password, envString("SMTP_FROM", username), to))
+	}
+	for _, ch := range channels {
+		log.Printf("[INFO] Alerts are sent to %s", ch)
+	}
+	return service.NewAlertNotifier(channels...), nil
+}
+
+// storedSecret returns the credential named by nameEnv (def by default)
+// when creds has it, or the value of fallbackEnv.
+func storedSecret(creds *service.CredentialService, nameEnv, def, fallbackEnv string) (string, error) {
+	if creds != nil {
+		name := envString(nameEnv, def)
+		secret, err := creds.Secret(instanceCredentials(), name)
+		if err == nil {
+			return secret, nil
+		}
+		if !errors.Is(err, service.ErrCredentialNotFound) {
+			return "", fmt.Errorf("%s %s: %w", nameEnv, name, err)
+		}
+	}
+	return os.Getenv(fallbackEnv), nil
+}
+
+// instanceCredentials is the context integrations of the whole instance
+// read their credentials with: the ones an operator saved with the admin
+// key, which belong to no organization.
+func instanceCredentials() context.Context {
+	return database.WithOrg(context.Background(), 0)
+}
+
+// warehouseTarget reads the analytics warehouse snapshots, orders and price
+// history are replicated to: WAREHOUSE_PROVIDER is clickhouse (see
+// WAREHOUSE_URL) or bigquery (WAREHOUSE_PROJECT, WAREHOUSE_DATASET and the
+// service account key in WAREHOUSE_CREDENTIALS_FILE). The ClickHouse
+// password or BigQuery key is read from the credential named by
+// WAREHOUSE_CREDENTIAL (warehouse by default) when creds has it. It returns
+// nil when WAREHOUSE_PROVIDER is unset.
+func warehouseTarget(creds *service.CredentialService) (warehouse.Target, error) {
+	provider := os.Getenv("WAREHOUSE_PROVIDER")
+	if provider == "" {
+		return nil, nil
+	}
+	cfg := warehouse.Config{
+		Provider: provider,
+		URL:      os.Getenv("WAREHOUSE_URL"),
+		Database: os.Getenv("WAREHOUSE_DATABASE"),
+		User:     os.Getenv("WAREHOUSE_USER"),
+		Password: os.Getenv("WAREHOUSE_PASSWORD"),
+		Project:  os.Getenv("WAREHOUSE_PROJECT"),
+		Dataset:  os.Getenv("WAREHOUSE_DATASET"),
+	}
+	if path := os.Getenv("WAREHOUSE_CREDENTIALS_FILE"); path != "" {
+		key, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("WAREHOUSE_CREDENTIALS_FILE: %w", err)
+		}
+		cfg.CredentialsJSON = key
+	}
+	secret, err := storedSecret(creds, "WAREHOUSE_CREDENTIAL", "warehouse", "")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case secret != "" && provider == "bigquery":
+		cfg.CredentialsJSON = []byte(secret)
+	case secret != "":
+		cfg.Password = secret
+	}
+	target, err := warehouse.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("WAREHOUSE_PROVIDER: %w", err)
+	}
+	log.Printf("[INFO] Snapshots, orders and prices are replicated to %s", target)
+	return target, nil
+}
+
+// secretEnv are the variables that may hold a reference to a secret
+// manager (see secrets.Resolver) instead of the secret, e.g.
+// DB_PASSWORD=vault:secret/data/melibot#db_password.
+var secretEnv = []string{"ML_CLIENT_SECRET", "DB_USER", "DB_PASSWORD"}
+
+// resolveSecrets replaces the references in secretEnv with the secrets
+// they point to, reaching Vault with VAULT_ADDR and VAULT_TOKEN (or AppRole
+// with VAULT_ROLE_ID and VAULT_SECRET_ID) and AWS with AWS_REGION and its
+// usual keys. It returns a func fetching them again, which also hands a
+// rotated ML_CLIENT_SECRET to the OAuth client; the database reads its
+// credentials for every new connection. It returns nil when no variable
+// holds a reference.
+func resolveSecrets() (func(ctx context.Context) error, error) {
+	refs := map[string]string{}
+	for _, key := range secretEnv {
+		if value := os.Getenv(key); secrets.IsRef(value) {
+			refs[key] = value
+		}
+	}
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	resolver := secrets.NewResolver(secrets.Config{
+		VaultAddr:       os.Getenv("VAULT_ADDR"),
+		VaultToken:      os.Getenv("VAULT_TOKEN"),
+		VaultRoleID:     os.Getenv("VAULT_ROLE_ID"),
+		VaultSecretID:   os.Getenv("VAULT_SECRET_ID"),
+		VaultNamespace:  os.Getenv("VAULT_NAMESPACE"),
+		AWSRegion:       envString("AWS_REGION", os.Getenv("AWS_DEFAULT_REGION")),
+		AWSAccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		AWSSecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		AWSSessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	})
+
+	fetch := func(ctx context.Context) error {
+		var errs []error
+		for key, ref := range refs {
+			value, err := resolver.Resolve(ctx, ref)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+				continue
+			}
+			if os.Getenv(key) == value {
+				continue
+			}
+			if err := os.Setenv(key, value); err != nil {
+				return err
+			}
+			if oauth := handlers.OAuthClient(); key == "ML_CLIENT_SECRET" && oauth != nil {
+				oauth.SetClientSecret(value)
+				log.Println("[INFO] ML_CLIENT_SECRET was rotated")
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := fetch(ctx); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] %d secrets fetched from secret managers", len(refs))
+	return fetch, nil
+}
+
+// credentialBox reads the master key third-party credentials are sealed
+// under, base64 or hex, from CREDENTIALS_MASTER_KEY or the file in
+// CREDENTIALS_MASTER_KEY_FILE (e.g. written by a KMS or secret manager). It
+// returns nil when neither is set.
+func credentialBox() (*secrets.Box, error) {
+	encoded, source := os.Getenv("CREDENTIALS_MASTER_KEY"), "CREDENTIALS_MASTER_KEY"
+	if path := os.Getenv("CREDENTIALS_MASTER_KEY_FILE"); encoded == "" && path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("CREDENTIALS_MASTER_KEY_FILE: %w", err)
+		}
+		encoded, source = string(b), "CREDENTIALS_MASTER_KEY_FILE"
+	}
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := secrets.ParseKey(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	box, err := secrets.New(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	log.Printf("[INFO] Credentials are encrypted under master key %s", box.KeyID())
+	return box, nil
+}
+
+// errorTracker reads the Sentry settings: SENTRY_DSN, SENTRY_ENVIRONMENT
+// (by default ML_ENVIRONMENT, or production) and SENTRY_RELEASE. It returns
+// nil when no DSN is set.
+func errorTracker() (*sentry.Tracker, error) {
+	environment := os.Getenv("SENTRY_ENVIRONMENT")
+	if environment == "" {
+		environment = os.Getenv("ML_ENVIRONMENT")
+	}
+	if environment == "" {
+		environment = "production"
+	}
+	tracker, err := sentry.New(sentry.Config{
+		DSN:         os.Getenv("SENTRY_DSN"),
+		Environment: environment,
+		Release:     os.Getenv("SENTRY_RELEASE"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if tracker != nil {
+		log.Printf("[INFO] Errors are reported to Sentry (%s)", environment)
+	}
+	return tracker, nil
+}
+
+// connectDB connects the database, tagging rows in sandbox mode and scoping
+// them by organization in multi-tenant mode.
+func (a *app) connectDB() {
+	database.Connect()
+	if a.sandboxMode {
+		database.EnableSandboxTagging()
+	}
+	if a.multiTenant {
+		database.EnableTenancy()
+		a.orgs = service.NewOrganizationService(repository.NewOrganizationRepository(), handlers.OAuthClient())
+	}
+}
+
+// newClient returns a client authenticated with the token currently in
+// memory, falling back to ML_ACCESS_TOKEN.
+func (a *app) newClient(opts ...meli.Option) *meli.MeliClient {
+	token := handlers.GetCurrentToken()
+	if token == "" {
+		token = os.Getenv("ML_ACCESS_TOKEN")
+	}
+	return meli.NewMeliClient(token, a.clientID, slices.Concat(a.clientOpts, opts)...)
+}
+
+// clientFor returns a client for the organization of ctx in multi-tenant
+// mode, authenticated with its own tokens, and a.newClient otherwise. The
+// token in memory is never used for an organization.
+func (a *app) clientFor(ctx context.Context, opts ...meli.Option) *meli.MeliClient {
+	if a.orgs == nil {
+		return a.newClient(opts...)
+	}
+	tokens, err := a.orgs.Tokens(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Failed to load organization tokens: %v", err)
+	}
+	opts = append([]meli.Option{meli.WithTokenRefresher(tokens)}, opts...)
+	return meli.NewMeliClient(tokens.AccessToken(), a.clientID, slices.Concat(a.clientOpts, opts)...)
+}
+
+// orgContext scopes ctx to an organization for the one-shot commands; it is
+// ctx itself outside multi-tenant mode, where orgID must be 0.
+func (a *app) orgContext(ctx context.Context, orgID uint) (context.Context, error) {
+	if !a.multiTenant {
+		if orgID != 0 {
+			return nil, errors.New("--org needs MULTI_TENANT=true")
+		}
+		return ctx, nil
+	}
+	if orgID == 0 {
+		return nil, errors.New("--org is required in multi-tenant mode")
+	}
+	return database.WithOrg(ctx, orgID), nil
+}
+
+// loadEnvTokens seeds the token manager from ML_ACCESS_TOKEN and
+// ML_REFRESH_TOKEN, so one-shot commands can refresh an expired token
+// without an interactive login.
+func loadEnvTokens() {
+	if refresh := os.Getenv("ML_REFRESH_TOKEN"); refresh != "" {
+		handlers.Tokens().Set(os.Getenv("ML_ACCESS_TOKEN"), refresh)
+	}
+}
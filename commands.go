@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"melibot/internal/cache"
+	"melibot/internal/handlers"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+	"melibot/pkg/meli"
+)
+
+// runSnapshot fetches the top sellers of each category and stores them, as
+// the cache warmer does, so snapshots can be taken from cron.
+func runSnapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ContinueOnError)
+	categories := fs.String("category", "", "comma-separated category IDs, e.g. MLB1055")
+	limit := fs.Int("limit", 10, "products per category")
+	timeout := fs.Duration("timeout", 2*time.Minute, "time budget per category")
+	org := fs.Uint("org", 0, "organization to store the snapshots for (multi-tenant mode only)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	ids := splitList(*categories)
+	if len(ids) == 0 {
+		return errors.New("--category is required")
+	}
+	if *limit <= 0 {
+		return errors.New("--limit must be positive")
+	}
+
+	a, err := newApp()
+	if err != nil {
+		return err
+	}
+	a.connectDB()
+	loadEnvTokens()
+	orgCtx, err := a.orgContext(context.Background(), uint(*org))
+	if err != nil {
+		return err
+	}
+
+	svc := service.NewMarketingService(a.clientFor(orgCtx), repository.NewTrendRepository(), cache.New(time.Minute), nil)
+	failed := 0
+	for _, id := range ids {
+		ctx, cancel := context.WithTimeout(orgCtx, *timeout)
+		result, err := svc.RefreshTopTrends(ctx, id, *limit)
+		cancel()
+		if err != nil {
+			log.Printf("[ERROR] snapshot %s: %v", id, err)
+			failed++
+			continue
+		}
+		note := ""
+		if result.Partial {
+			note = " (partial)"
+		}
+		fmt.Printf("%s: stored %d of %d products%s\n", id, len(result.Items), result.Total, note)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d categories failed", failed, len(ids))
+	}
+	return nil
+}
+
+// runExport writes the latest stored product trends to stdout or a file.
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "csv", "csv, json or parquet")
+	category := fs.String("category", "", "only products of this category")
+	limit := fs.Int("limit", 0, "at most this many products, best sellers first (0 = all)")
+	output := fs.String("output", "-", "file to write, - for stdout")
+	org := fs.Uint("org", 0, "organization whose products to export (multi-tenant mode only)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !service.IsExportFormat(*format) {
+		return service.ErrUnsupportedExportFormat
+	}
+
+	a, err := newApp()
+	if err != nil {
+		return err
+	}
+	a.connectDB()
+	ctx, err := a.orgContext(context.Background(), uint(*org))
+	if err != nil {
+		return err
+	}
+
+	trends, err := repository.NewTrendRepository().LatestProductTrends(ctx, *category, *limit)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := service.WriteProductTrends(w, *format, trends); err != nil {
+		return err
+	}
+	if *output != "-" {
+		log.Printf("[INFO] exported %d products to %s", len(trends), *output)
+	}
+	return nil
+}
+
+// runMigrate creates or updates the database schema and exits.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	a, err := newApp()
+	if err != nil {
+		return err
+	}
+	a.connectDB()
+	if err := repository.AutoMigrate(); err != nil {
+		return err
+	}
+	log.Println("[INFO] migrations applied")
+	return nil
+}
+
+// runToken handles "token refresh": it exchanges the refresh token for a new
+// pair and prints it. Mercado Livre refresh tokens are single-use, so the
+// printed ML_REFRESH_TOKEN must replace the old one.
+func runToken(args []string) error {
+	if len(args) == 0 || args[0] != "refresh" {
+		return errors.New("usage: melibot token refresh [--format env|json]")
+	}
+	fs := flag.NewFlagSet("token refresh", flag.ContinueOnError)
+	refreshToken := fs.String("refresh-token", os.Getenv("ML_REFRESH_TOKEN"), "refresh token (default ML_REFRESH_TOKEN)")
+	format := fs.String("format", "env", "env (KEY=value lines) or json")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *refreshToken == "" {
+		return errors.New("no refresh token: set ML_REFRESH_TOKEN or pass --refresh-token")
+	}
+	if *format != "env" && *format != "json" {
+		return errors.New("format must be env or json")
+	}
+
+	if _, err := newApp(); err != nil {
+		return err
+	}
+	tokens := handlers.Tokens()
+	tokens.Set("", *refreshToken)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	access, err := tokens.Refresh(ctx, "")
+	if errors.Is(err, meli.ErrNoRefreshToken) {
+		// The refresh token is set, so OAuth itself is missing
+		return errors.New("OAuth is not configured: set ML_CLIENT_ID, ML_CLIENT_SECRET and ML_REDIRECT_URI")
+	}
+	if err != nil {
+		return err
+	}
+
+	if *format == "json" {
+		return json.NewEncoder(os.Stdout).Encode(map[string]string{
+			"access_token":  access,
+			"refresh_token": tokens.RefreshToken(),
+		})
+	}
+	fmt.Printf("ML_ACCESS_TOKEN=%s\nML_REFRESH_TOKEN=%s\n", access, tokens.RefreshToken())
+	return nil
+}
@@ -1,17 +1,21 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-// Connect initializes the global DB connection using environment variables.
+// Connect initializes the global DB connection using environment variables,
+// and the read replicas of DB_REPLICA_DSNS.
 func Connect() {
 	host := os.Getenv("DB_HOST")
 	port := os.Getenv("DB_PORT")
@@ -33,12 +37,24 @@ func Connect() {
 		host, user, password, dbName, port, sslMode,
 	)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// Credentials are read again for every new connection, so ones rotated
+	// in a secret manager take effect without a restart
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		log.Fatalf("invalid database configuration: %v", err)
+	}
+	sqlDB := stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+		cc.User, cc.Password = os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD")
+		return nil
+	}))
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
 	DB = db
 	log.Println("database connected successfully")
-}
 
+	connectReplicas()
+}
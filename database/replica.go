@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// replicas are the connection pools of the read replicas, see Replica.
+var replicas []gorm.ConnPool
+
+var nextReplica atomic.Uint64
+
+// connectReplicas opens the read replicas in DB_REPLICA_DSNS, a
+// comma-separated list of PostgreSQL DSNs.
+func connectReplicas() {
+	for _, dsn := range strings.Split(os.Getenv("DB_REPLICA_DSNS"), ",") {
+		dsn = strings.TrimSpace(dsn)
+		if dsn == "" {
+			continue
+		}
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		if err != nil {
+			log.Fatalf("failed to connect to read replica %d: %v", len(replicas)+1, err)
+		}
+		pool, err := db.DB()
+		if err != nil {
+			log.Fatalf("failed to connect to read replica %d: %v", len(replicas)+1, err)
+		}
+		replicas = append(replicas, pool)
+	}
+	if len(replicas) > 0 {
+		log.Printf("[INFO] History and report queries go to %d read replicas", len(replicas))
+	}
+}
+
+// Replica returns a session of DB whose statements run on the next read
+// replica, round-robin, or on DB itself without replicas. It keeps DB's
+// callbacks, so organization scoping applies as usual. Only reads that
+// tolerate replication lag belong on it, such as history and reports:
+// replicas trail the primary and reject writes.
+func Replica(ctx context.Context) *gorm.DB {
+	tx := DB.Session(&gorm.Session{Context: ctx})
+	if len(replicas) > 0 {
+		tx.Statement.ConnPool = replicas[(nextReplica.Add(1)-1)%uint64(len(replicas))]
+	}
+	return tx
+}
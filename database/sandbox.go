@@ -0,0 +1,28 @@
+package database
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// sandboxField is the column that marks rows created in sandbox mode.
+const sandboxField = "Sandbox"
+
+// EnableSandboxTagging sets Sandbox=true on every created row whose model has
+// a Sandbox field, so data produced against Mercado Livre test accounts can
+// never be confused with production data.
+func EnableSandboxTagging() {
+	err := DB.Callback().Create().Before("gorm:create").Register("melibot:sandbox_tag", func(tx *gorm.DB) {
+		if tx.Statement.Schema == nil {
+			return
+		}
+		if field := tx.Statement.Schema.LookUpField(sandboxField); field != nil {
+			tx.Statement.SetColumn(sandboxField, true, true)
+		}
+	})
+	if err != nil {
+		log.Fatalf("failed to register sandbox tagging: %v", err)
+	}
+	log.Println("[WARN] SANDBOX MODE: new records are tagged as sandbox data")
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// orgField is the column that scopes rows to an organization in
+// multi-tenant mode.
+const orgField = "OrgID"
+
+// ErrNoOrganization is returned in multi-tenant mode by statements on
+// organization-scoped tables whose context carries no organization.
+var ErrNoOrganization = errors.New("no organization in context")
+
+type orgKey struct{}
+
+type allOrgsKey struct{}
+
+// multiTenant is set by EnableTenancy.
+var multiTenant bool
+
+// MultiTenant reports whether rows are scoped by organization.
+func MultiTenant() bool {
+	return multiTenant
+}
+
+// WithOrg scopes the statements run with the returned context to an
+// organization: rows created get its ID and only its rows are read,
+// updated or deleted.
+func WithOrg(ctx context.Context, orgID uint) context.Context {
+	return context.WithValue(ctx, orgKey{}, orgID)
+}
+
+// OrgFromContext returns the organization statements run with ctx are
+// scoped to.
+func OrgFromContext(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(orgKey{}).(uint)
+	return id, ok
+}
+
+// AllOrgs lets the statements run with the returned context see the rows of
+// every organization, for work that spans them such as claiming jobs. Rows
+// created with it belong to no organization.
+func AllOrgs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allOrgsKey{}, true)
+}
+
+func isAllOrgs(ctx context.Context) bool {
+	all, _ := ctx.Value(allOrgsKey{}).(bool)
+	return all
+}
+
+// EnableTenancy scopes every statement on a model with an OrgID field to
+// the organization of its context, see WithOrg. Statements without one fail
+// with ErrNoOrganization unless their context comes from AllOrgs, so a
+// missing scope never reads another organization's rows.
+func EnableTenancy() {
+	cb := DB.Callback()
+	errs := []error{
+		cb.Create().Before("gorm:create").Register("melibot:org_tag", tagOrg),
+		cb.Query().Before("gorm:query").Register("melibot:org_scope", scopeOrg),
+		cb.Row().Before("gorm:row").Register("melibot:org_scope", scopeOrg),
+		cb.Update().Before("gorm:update").Register("melibot:org_scope", scopeOrgUpdate),
+		cb.Delete().Before("gorm:delete").Register("melibot:org_scope", scopeOrg),
+	}
+	if err := errors.Join(errs...); err != nil {
+		log.Fatalf("failed to register organization scoping: %v", err)
+	}
+	multiTenant = true
+	log.Println("[INFO] Multi-tenant mode: rows are scoped by organization")
+}
+
+// orgFieldOf returns the OrgID field of the statement's model, nil when it
+// is not organization-scoped.
+func orgFieldOf(tx *gorm.DB) *schema.Field {
+	if tx.Statement.Schema == nil {
+		return nil
+	}
+	return tx.Statement.Schema.LookUpField(orgField)
+}
+
+func tagOrg(tx *gorm.DB) {
+	if orgFieldOf(tx) == nil {
+		return
+	}
+	ctx := tx.Statement.Context
+	if id, ok := OrgFromContext(ctx); ok {
+		tx.Statement.SetColumn(orgField, id, true)
+		return
+	}
+	if !isAllOrgs(ctx) {
+		tx.AddError(ErrNoOrganization)
+	}
+}
+
+// scopeOrgUpdate also keeps the OrgID of the updated rows, which Save of a
+// struct built from a request would otherwise reset.
+func scopeOrgUpdate(tx *gorm.DB) {
+	scopeOrg(tx)
+	if tx.Error != nil || orgFieldOf(tx) == nil {
+		return
+	}
+	if id, ok := OrgFromContext(tx.Statement.Context); ok {
+		tx.Statement.SetColumn(orgField, id, true)
+	}
+}
+
+func scopeOrg(tx *gorm.DB) {
+	field := orgFieldOf(tx)
+	if field == nil {
+		return
+	}
+	// Statements over a derived table, e.g. Table("(?) AS latest", q), are
+	// scoped through their subqueries
+	if tx.Statement.TableExpr != nil && tx.Statement.Table != tx.Statement.Schema.Table {
+		return
+	}
+	ctx := tx.Statement.Context
+	id, ok := OrgFromContext(ctx)
+	if !ok {
+		if !isAllOrgs(ctx) {
+			tx.AddError(ErrNoOrganization)
+		}
+		return
+	}
+	tx.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: id},
+	}})
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type entry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// Cache is a concurrency-safe in-memory cache with per-entry TTL.
+type Cache struct {
+	mu      sync.RWMutex
+	entries map[string]entry
+	ttl     time.Duration // guarded by mu
+	hits    atomic.Int64
+	misses  atomic.Int64
+}
+
+// Stats reports cache effectiveness counters.
+type Stats struct {
+	Entries int     `json:"entries"`
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+// New returns a cache whose entries expire after ttl by default.
+func New(ttl time.Duration) *Cache {
+	return &Cache{
+		entries: make(map[string]entry),
+		ttl:     ttl,
+	}
+}
+
+// Get returns the value stored under key if it exists and has not expired.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	e, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(e.expiresAt) {
+		c.misses.Add(1)
+		return nil, false
+	}
+	c.hits.Add(1)
+	return e.value, true
+}
+
+// Set stores value under key with the default TTL.
+func (c *Cache) Set(key string, value interface{}) {
+	c.mu.RLock()
+	ttl := c.ttl
+	c.mu.RUnlock()
+	c.SetWithTTL(key, value, ttl)
+}
+
+// SetTTL changes the default TTL of the entries stored from now on.
+func (c *Cache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// SetWithTTL stores value under key with a specific TTL.
+func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Opportunistically drop expired entries so the map doesn't grow forever.
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = entry{value: value, expiresAt: now.Add(ttl)}
+}
+
+// Delete removes key from the cache.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// Stats returns a snapshot of the hit/miss counters.
+func (c *Cache) Stats() Stats {
+	c.mu.RLock()
+	n := len(c.entries)
+	c.mu.RUnlock()
+
+	s := Stats{Entries: n, Hits: c.hits.Load(), Misses: c.misses.Load()}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	}
+	return s
+}
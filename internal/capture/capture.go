@@ -0,0 +1,154 @@
+// Package capture keeps the latest inbound and outbound HTTP exchanges in
+// memory for debugging production, with tokens, cookies and buyer personal
+// data redacted before anything is stored.
+package capture
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Directions of an Exchange.
+const (
+	Inbound  = "inbound"
+	Outbound = "outbound"
+)
+
+// maxBody caps how much of each redacted body is kept. Bodies are redacted
+// whole, as a cut JSON document no longer parses; those over maxRead are
+// not kept at all.
+const (
+	maxBody = 16 << 10
+	maxRead = 1 << 20
+)
+
+// Exchange is one captured request and its response, redacted.
+type Exchange struct {
+	ID              uint64              `json:"id"`
+	Direction       string              `json:"direction"`
+	At              time.Time           `json:"at"`
+	DurationMs      int64               `json:"duration_ms"`
+	Method          string              `json:"method"`
+	URL             string              `json:"url"`
+	Status          int                 `json:"status,omitempty"`
+	Error           string              `json:"error,omitempty"`
+	RequestHeaders  map[string][]string `json:"request_headers"`
+	RequestBody     string              `json:"request_body,omitempty"`
+	ResponseHeaders map[string][]string `json:"response_headers,omitempty"`
+	ResponseBody    string              `json:"response_body,omitempty"`
+	// Truncated is set when a body was longer than what was kept
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Filter selects captured exchanges.
+type Filter struct {
+	Direction string
+	// Contains matches a substring of the URL
+	Contains string
+	// MinStatus keeps exchanges with at least this status, e.g. 400 for
+	// failures; failed outbound calls have no status and always match
+	MinStatus int
+	Limit     int
+}
+
+// Recorder is a ring buffer of the latest exchanges. Capturing can be
+// switched on and off at runtime; while off, nothing is read or stored.
+type Recorder struct {
+	enabled atomic.Bool
+
+	mu     sync.Mutex
+	ring   []Exchange
+	next   int
+	full   bool
+	lastID uint64
+}
+
+// New returns a disabled Recorder keeping the latest size exchanges.
+func New(size int) *Recorder {
+	if size <= 0 {
+		size = 200
+	}
+	return &Recorder{ring: make([]Exchange, size)}
+}
+
+// Enabled reports whether exchanges are being captured. A nil Recorder
+// never captures.
+func (r *Recorder) Enabled() bool {
+	return r != nil && r.enabled.Load()
+}
+
+// SetEnabled switches capturing on or off.
+func (r *Recorder) SetEnabled(on bool) {
+	r.enabled.Store(on)
+}
+
+// Size is how many exchanges are kept.
+func (r *Recorder) Size() int {
+	return len(r.ring)
+}
+
+func (r *Recorder) add(e Exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastID++
+	e.ID = r.lastID
+	r.ring[r.next] = e
+	r.next = (r.next + 1) % len(r.ring)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Recent returns the captured exchanges matching f, newest first.
+func (r *Recorder) Recent(f Filter) []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.ring)
+	}
+	out := make([]Exchange, 0, count)
+	for i := 1; i <= count; i++ {
+		e := r.ring[(r.next-i+len(r.ring))%len(r.ring)]
+		if f.Direction != "" && e.Direction != f.Direction {
+			continue
+		}
+		if f.Contains != "" && !strings.Contains(e.URL, f.Contains) {
+			continue
+		}
+		if f.MinStatus > 0 && e.Status != 0 && e.Status < f.MinStatus {
+			continue
+		}
+		out = append(out, e)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// Clear drops every captured exchange.
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.ring)
+	r.next = 0
+	r.full = false
+}
+
+// keep returns the redacted beginning of a body and whether it was cut.
+// size is the full length of the body, of which body may hold only the
+// first maxRead bytes.
+func keep(body []byte, size int64, contentType string) (string, bool) {
+	if size > maxRead {
+		return "", true
+	}
+	out := RedactBody(body, contentType)
+	if len(out) > maxBody {
+		return out[:maxBody], true
+	}
+	return out, false
+}
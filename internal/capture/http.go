@@ -0,0 +1,152 @@
+package capture
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware captures the requests the server receives while the Recorder
+// is enabled, except those whose path starts with one of skip (e.g. the
+// endpoint listing the captures).
+func (r *Recorder) Middleware(skip ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !r.Enabled() {
+			c.Next()
+			return
+		}
+		for _, prefix := range skip {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		start := time.Now()
+		e := Exchange{
+			Direction:      Inbound,
+			At:             start,
+			Method:         c.Request.Method,
+			URL:            RedactURL(c.Request.URL),
+			RequestHeaders: RedactHeader(c.Request.Header),
+		}
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			if err == nil {
+				var cut bool
+				e.RequestBody, cut = keep(body, int64(len(body)), c.GetHeader("Content-Type"))
+				e.Truncated = e.Truncated || cut
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		rec := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+
+		c.Next()
+
+		e.DurationMs = time.Since(start).Milliseconds()
+		e.Status = c.Writer.Status()
+		e.ResponseHeaders = RedactHeader(c.Writer.Header())
+		var cut bool
+		e.ResponseBody, cut = keep(rec.buf.Bytes(), rec.size, c.Writer.Header().Get("Content-Type"))
+		e.Truncated = e.Truncated || cut
+		if len(c.Errors) > 0 {
+			e.Error = RedactText(c.Errors.String())
+		}
+		r.add(e)
+	}
+}
+
+// bodyRecorder keeps the first maxRead bytes of the response body.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	buf  bytes.Buffer
+	size int64
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	if room := maxRead - w.buf.Len(); room > 0 {
+		w.buf.Write(b[:min(room, len(b))])
+	}
+	w.size += int64(len(b))
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Transport returns a RoundTripper capturing the calls made through next
+// while the Recorder is enabled.
+func (r *Recorder) Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{recorder: r, next: next}
+}
+
+type transport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.recorder.Enabled() {
+		return t.next.RoundTrip(req)
+	}
+
+	start := time.Now()
+	e := Exchange{
+		Direction:      Outbound,
+		At:             start,
+		Method:         req.Method,
+		URL:            RedactURL(req.URL),
+		RequestHeaders: RedactHeader(req.Header),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		var cut bool
+		e.RequestBody, cut = keep(body, int64(len(body)), req.Header.Get("Content-Type"))
+		e.Truncated = e.Truncated || cut
+		// The request is the caller's: send a copy with the body restored
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	e.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		e.Error = RedactText(err.Error())
+		t.recorder.add(e)
+		return nil, err
+	}
+
+	e.Status = resp.StatusCode
+	e.ResponseHeaders = RedactHeader(resp.Header)
+	body, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if readErr != nil {
+		// The caller still sees the body fail where it did
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{readErr}))
+		e.Error = RedactText(readErr.Error())
+	} else {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		var cut bool
+		e.ResponseBody, cut = keep(body, int64(len(body)), resp.Header.Get("Content-Type"))
+		e.Truncated = e.Truncated || cut
+	}
+	t.recorder.add(e)
+	return resp, nil
+}
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,126 @@
+package capture
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "REDACTED"
+
+// secretHeaders carry credentials.
+var secretHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+	"X-API-Key", "X-Org-Key", "X-Admin-Key", "X-CSRF-Token", "X-Vault-Token",
+}
+
+// secretParams are query and form parameters holding credentials.
+var secretParams = map[string]bool{
+	"access_token": true, "refresh_token": true, "client_secret": true,
+	"code": true, "org_key": true, "token": true, "password": true,
+}
+
+// secretFields are JSON fields holding credentials or buyer personal data;
+// objects under them (e.g. phone, billing_info) are dropped whole.
+var secretFields = map[string]bool{
+	"access_token": true, "refresh_token": true, "client_secret": true, "password": true,
+	"secret": true, "token": true, "api_key": true, "private_key": true, "value": true,
+	"email": true, "phone": true, "alternative_phone": true, "first_name": true, "last_name": true,
+	"identification": true, "doc_number": true, "billing_info": true, "receiver_name": true,
+	"receiver_phone": true, "receiver_address": true, "address_line": true, "street_name": true,
+	"street_number": true, "zip_code": true,
+}
+
+// secretPatterns catch credentials and emails anywhere else: Mercado Livre
+// access (APP_USR-...) and refresh (TG-...) tokens.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`APP_USR-[0-9A-Za-z-]+`),
+	regexp.MustCompile(`\bTG-[0-9a-f]{16,}\b`),
+	regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+}
+
+// RedactHeader returns a copy of h without credentials.
+func RedactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range secretHeaders {
+		if out.Get(name) != "" {
+			out.Set(name, redacted)
+		}
+	}
+	return out
+}
+
+// RedactURL returns u without credentials in its query or user info.
+func RedactURL(u *url.URL) string {
+	clone := *u
+	clone.User = nil
+	if clone.RawQuery != "" {
+		clone.RawQuery = redactForm(clone.RawQuery)
+	}
+	return RedactText(clone.String())
+}
+
+func redactForm(raw string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return RedactText(raw)
+	}
+	for key := range values {
+		if secretParams[strings.ToLower(key)] {
+			values.Set(key, redacted)
+		}
+	}
+	return RedactText(values.Encode())
+}
+
+// RedactBody returns a body without credentials or buyer personal data,
+// walking JSON and form bodies field by field.
+func RedactBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var doc interface{}
+	if json.Unmarshal(body, &doc) == nil {
+		if out, err := json.Marshal(redactJSON(doc)); err == nil {
+			return string(out)
+		}
+	}
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		return redactForm(string(body))
+	}
+	return RedactText(string(body))
+}
+
+func redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if secretFields[strings.ToLower(key)] && field != nil {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactJSON(field)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redactJSON(v[i])
+		}
+		return v
+	case string:
+		return RedactText(v)
+	default:
+		return v
+	}
+}
+
+// RedactText hides tokens and email addresses in free text, e.g. error
+// messages.
+func RedactText(s string) string {
+	for _, p := range secretPatterns {
+		s = p.ReplaceAllString(s, redacted)
+	}
+	return s
+}
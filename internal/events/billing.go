@@ -0,0 +1,19 @@
+package events
+
+import "time"
+
+// NamePlanChanged is the name of PlanChanged.
+const NamePlanChanged = "billing.plan_changed"
+
+// PlanChanged is published when the subscription plan of an organization
+// changes plan or status, e.g. a payment failed and its grace period
+// started, or the grace period ran out.
+type PlanChanged struct {
+	OrgID      uint
+	Plan       string
+	From       string
+	To         string
+	GraceUntil *time.Time
+}
+
+func (PlanChanged) EventName() string { return NamePlanChanged }
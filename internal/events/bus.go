@@ -0,0 +1,90 @@
+// Package events is an in-process publish/subscribe bus so modules can react
+// to each other without calling each other directly.
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Event is something that happened in one module that others may react to.
+type Event interface {
+	EventName() string
+}
+
+// Handler reacts to an event.
+type Handler func(ctx context.Context, e Event)
+
+// Names lists every event name, for callers that let users pick events.
+var Names = []string{
+	NameOrderCreated,
+	NameOrderStatusChanged,
+	NamePriceChanged,
+	NameTrendSnapshotCompleted,
+	NameQuestionReceived,
+	NameCompetitorPriceDropped,
+	NameCompetitorListingLaunched,
+	NameRankDropped,
+	NameCostsUpdated,
+	NamePlanChanged,
+	NameSourcingSignal,
+}
+
+// Bus delivers published events to the handlers subscribed to their name.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	all      []Handler
+}
+
+func New() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers h for events with the given name.
+func (b *Bus) Subscribe(name string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], h)
+}
+
+// SubscribeAll registers h for every event.
+func (b *Bus) SubscribeAll(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all = append(b.all, h)
+}
+
+// Subscribe registers fn for events of type T, e.g.
+//
+//	events.Subscribe(bus, func(ctx context.Context, e events.OrderCreated) { ... })
+func Subscribe[T Event](b *Bus, fn func(ctx context.Context, e T)) {
+	var zero T
+	b.Subscribe(zero.EventName(), func(ctx context.Context, e Event) {
+		fn(ctx, e.(T))
+	})
+}
+
+// Publish calls the handlers of e in subscription order, then those
+// subscribed to every event. A panicking handler is logged and does not
+// affect the others. Publishing on a nil Bus is a no-op.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[e.EventName()]...), b.all...)
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("[ERROR] %s handler panicked: %v", e.EventName(), r)
+				}
+			}()
+			h(ctx, e)
+		}()
+	}
+}
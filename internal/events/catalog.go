@@ -0,0 +1,29 @@
+package events
+
+// Catalog event names.
+const (
+	NamePriceChanged           = "price.changed"
+	NameTrendSnapshotCompleted = "trend_snapshot.completed"
+)
+
+// PriceChanged is published when the best price of a watched product moves.
+// Alerts lists the watchlist thresholds the move crossed, if any.
+type PriceChanged struct {
+	ProductID string
+	Title     string
+	OldPrice  float64
+	NewPrice  float64
+	Alerts    []string
+}
+
+func (PriceChanged) EventName() string { return NamePriceChanged }
+
+// TrendSnapshotCompleted is published after the top sellers of a category
+// were fetched and stored.
+type TrendSnapshotCompleted struct {
+	CategoryID string
+	Products   int
+	Partial    bool
+}
+
+func (TrendSnapshotCompleted) EventName() string { return NameTrendSnapshotCompleted }
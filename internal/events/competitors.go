@@ -0,0 +1,32 @@
+package events
+
+// Competitor event names.
+const (
+	NameCompetitorPriceDropped    = "competitor.price_dropped"
+	NameCompetitorListingLaunched = "competitor.listing_launched"
+)
+
+// CompetitorPriceDropped is published when a watched competitor lowers the
+// price of one of their listings.
+type CompetitorPriceDropped struct {
+	SellerID int64
+	Nickname string
+	ItemID   string
+	Title    string
+	OldPrice float64
+	NewPrice float64
+}
+
+func (CompetitorPriceDropped) EventName() string { return NameCompetitorPriceDropped }
+
+// CompetitorListingLaunched is published when a watched competitor starts
+// selling a listing that was not in their previous snapshot.
+type CompetitorListingLaunched struct {
+	SellerID int64
+	Nickname string
+	ItemID   string
+	Title    string
+	Price    float64
+}
+
+func (CompetitorListingLaunched) EventName() string { return NameCompetitorListingLaunched }
@@ -0,0 +1,15 @@
+package events
+
+// NameCostsUpdated is the name of CostsUpdated.
+const NameCostsUpdated = "costs.updated"
+
+// CostsUpdated is published after a supplier price list changed the unit
+// cost of some SKUs. LossMaking lists my listings of those SKUs that now
+// sell below cost after fees.
+type CostsUpdated struct {
+	Source     string
+	SKUs       []string
+	LossMaking []string
+}
+
+func (CostsUpdated) EventName() string { return NameCostsUpdated }
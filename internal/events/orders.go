@@ -0,0 +1,26 @@
+package events
+
+import "melibot/internal/repository"
+
+// Order event names.
+const (
+	NameOrderCreated       = "order.created"
+	NameOrderStatusChanged = "order.status_changed"
+)
+
+// OrderCreated is published the first time an order is stored.
+type OrderCreated struct {
+	Order *repository.Order
+}
+
+func (OrderCreated) EventName() string { return NameOrderCreated }
+
+// OrderStatusChanged is published when a stored order moves to another
+// status.
+type OrderStatusChanged struct {
+	Order *repository.Order
+	From  string
+	To    string
+}
+
+func (OrderStatusChanged) EventName() string { return NameOrderStatusChanged }
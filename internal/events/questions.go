@@ -0,0 +1,13 @@
+package events
+
+// NameQuestionReceived is the name of QuestionReceived.
+const NameQuestionReceived = "question.received"
+
+// QuestionReceived is published when a buyer asks a question on one of the
+// seller's listings.
+type QuestionReceived struct {
+	QuestionID int64
+	SellerID   int64
+}
+
+func (QuestionReceived) EventName() string { return NameQuestionReceived }
@@ -0,0 +1,16 @@
+package events
+
+// NameRankDropped is the name of RankDropped.
+const NameRankDropped = "rank.dropped"
+
+// RankDropped is published when one of my listings loses positions in the
+// search for a tracked keyword. NewPosition is 0 when the listing fell out
+// of the tracked depth.
+type RankDropped struct {
+	Keyword     string
+	ItemID      string
+	OldPosition int
+	NewPosition int
+}
+
+func (RankDropped) EventName() string { return NameRankDropped }
@@ -0,0 +1,19 @@
+package events
+
+// NameSourcingSignal is the name of SourcingSignal.
+const NameSourcingSignal = "sourcing.signal"
+
+// SourcingSignal is published when the market price of a product I'm
+// considering sourcing turns it into a buy (margin at or above my
+// threshold) or an abort (below break-even).
+type SourcingSignal struct {
+	ProductID    string
+	Title        string
+	Signal       string
+	Price        float64
+	LandedCost   float64
+	MarginPct    float64
+	MinMarginPct float64
+}
+
+func (SourcingSignal) EventName() string { return NameSourcingSignal }
@@ -0,0 +1,7 @@
+// Package graph serves the dashboard's GraphQL schema (schema.graphqls)
+// with gqlgen. Resolvers read through service.GraphService; the
+// executable schema in generated.go is written by gqlgen from the schema
+// and gqlgen.yml.
+package graph
+
+//go:generate go run github.com/99designs/gqlgen generate --config gqlgen.yml
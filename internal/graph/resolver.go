@@ -0,0 +1,8 @@
+package graph
+
+import "melibot/internal/service"
+
+// Resolver resolves the schema for one request.
+type Resolver struct {
+	svc *service.GraphService
+}
@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// MarshalTime writes a Time as an RFC 3339 timestamp.
+func MarshalTime(t time.Time) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(t.Format(time.RFC3339)))
+	})
+}
+
+// UnmarshalTime reads an RFC 3339 timestamp, or a date (2006-01-02) as
+// midnight UTC.
+func UnmarshalTime(v interface{}) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("Time cannot represent %v", v)
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Time cannot represent %q: use RFC 3339 or YYYY-MM-DD", s)
+	}
+	return t, nil
+}
+
+// MarshalInt64ID writes an int64 ID, such as an order's, as a string.
+func MarshalInt64ID(id int64) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(strconv.FormatInt(id, 10)))
+	})
+}
+
+// UnmarshalInt64ID reads an int64 ID given as a string or a number.
+func UnmarshalInt64ID(v interface{}) (int64, error) {
+	switch v := v.(type) {
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	}
+	return 0, fmt.Errorf("ID cannot represent %v", v)
+}
@@ -0,0 +1,153 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.45
+
+import (
+	"context"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+	"melibot/pkg/meli"
+	"strconv"
+	"time"
+)
+
+// Competitors is the resolver for the competitors field.
+func (r *itemResolver) Competitors(ctx context.Context, obj *meli.Item, limit int) (*service.Competitors, error) {
+	return r.svc.Competitors(ctx, obj, limit)
+}
+
+// SellerID is the resolver for the sellerId field.
+func (r *listingResolver) SellerID(ctx context.Context, obj *meli.CategorySearchResult) (*string, error) {
+	id := strconv.FormatInt(obj.Seller.ID, 10)
+	return &id, nil
+}
+
+// FreeShipping is the resolver for the freeShipping field.
+func (r *listingResolver) FreeShipping(ctx context.Context, obj *meli.CategorySearchResult) (*bool, error) {
+	return &obj.Shipping.FreeShipping, nil
+}
+
+// LogisticType is the resolver for the logisticType field.
+func (r *listingResolver) LogisticType(ctx context.Context, obj *meli.CategorySearchResult) (*string, error) {
+	return &obj.Shipping.LogisticType, nil
+}
+
+// History is the resolver for the history field.
+func (r *orderResolver) History(ctx context.Context, obj *repository.Order) ([]repository.OrderStatusChange, error) {
+	return r.svc.OrderHistory(ctx, obj.ID)
+}
+
+// Item is the resolver for the item field.
+func (r *orderItemResolver) Item(ctx context.Context, obj *repository.OrderItem) (*meli.Item, error) {
+	return r.svc.Item(ctx, obj.ItemID)
+}
+
+// Item is the resolver for the item field.
+func (r *productResolver) Item(ctx context.Context, obj *repository.ProductTrend) (*meli.Item, error) {
+	return r.svc.Item(ctx, obj.ProductID)
+}
+
+// Snapshots is the resolver for the snapshots field.
+func (r *productResolver) Snapshots(ctx context.Context, obj *repository.ProductTrend, days int) ([]repository.SoldSnapshot, error) {
+	return r.svc.Snapshots(ctx, obj.ProductID, days)
+}
+
+// Trends is the resolver for the trends field.
+func (r *queryResolver) Trends(ctx context.Context, categoryID string, limit int) (*service.TrendsResult, error) {
+	return r.svc.Trends(ctx, categoryID, limit)
+}
+
+// Products is the resolver for the products field.
+func (r *queryResolver) Products(ctx context.Context, query string, limit int) ([]repository.ProductTrend, error) {
+	return r.svc.Products(ctx, query, limit)
+}
+
+// Item is the resolver for the item field.
+func (r *queryResolver) Item(ctx context.Context, id string) (*meli.Item, error) {
+	return r.svc.Item(ctx, id)
+}
+
+// Snapshots is the resolver for the snapshots field.
+func (r *queryResolver) Snapshots(ctx context.Context, productID string, days int) ([]repository.SoldSnapshot, error) {
+	return r.svc.Snapshots(ctx, productID, days)
+}
+
+// Orders is the resolver for the orders field.
+func (r *queryResolver) Orders(ctx context.Context, status *string, from *time.Time, to *time.Time, limit int) ([]repository.Order, error) {
+	f := repository.OrderFilter{Limit: limit}
+	if status != nil {
+		f.Status = *status
+	}
+	if from != nil {
+		f.From = *from
+	}
+	if to != nil {
+		f.To = *to
+	}
+	return r.svc.Orders(ctx, f)
+}
+
+// Order is the resolver for the order field.
+func (r *queryResolver) Order(ctx context.Context, id string) (*repository.Order, error) {
+	return r.svc.Order(ctx, id)
+}
+
+// Watchlist is the resolver for the watchlist field.
+func (r *queryResolver) Watchlist(ctx context.Context) ([]repository.WatchedProduct, error) {
+	return r.svc.Watchlist(ctx)
+}
+
+// Item is the resolver for the item field.
+func (r *trendResolver) Item(ctx context.Context, obj *meli.SearchItem) (*meli.Item, error) {
+	return r.svc.Item(ctx, obj.ID)
+}
+
+// Snapshots is the resolver for the snapshots field.
+func (r *trendResolver) Snapshots(ctx context.Context, obj *meli.SearchItem, days int) ([]repository.SoldSnapshot, error) {
+	return r.svc.Snapshots(ctx, obj.ID, days)
+}
+
+// Alerts is the resolver for the alerts field.
+func (r *watchedProductResolver) Alerts(ctx context.Context, obj *repository.WatchedProduct, limit int) ([]repository.PriceAlert, error) {
+	return r.svc.Alerts(ctx, obj.ProductID, limit)
+}
+
+// Item is the resolver for the item field.
+func (r *watchedProductResolver) Item(ctx context.Context, obj *repository.WatchedProduct) (*meli.Item, error) {
+	return r.svc.Item(ctx, obj.ProductID)
+}
+
+// Item returns ItemResolver implementation.
+func (r *Resolver) Item() ItemResolver { return &itemResolver{r} }
+
+// Listing returns ListingResolver implementation.
+func (r *Resolver) Listing() ListingResolver { return &listingResolver{r} }
+
+// Order returns OrderResolver implementation.
+func (r *Resolver) Order() OrderResolver { return &orderResolver{r} }
+
+// OrderItem returns OrderItemResolver implementation.
+func (r *Resolver) OrderItem() OrderItemResolver { return &orderItemResolver{r} }
+
+// Product returns ProductResolver implementation.
+func (r *Resolver) Product() ProductResolver { return &productResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// Trend returns TrendResolver implementation.
+func (r *Resolver) Trend() TrendResolver { return &trendResolver{r} }
+
+// WatchedProduct returns WatchedProductResolver implementation.
+func (r *Resolver) WatchedProduct() WatchedProductResolver { return &watchedProductResolver{r} }
+
+type itemResolver struct{ *Resolver }
+type listingResolver struct{ *Resolver }
+type orderResolver struct{ *Resolver }
+type orderItemResolver struct{ *Resolver }
+type productResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type trendResolver struct{ *Resolver }
+type watchedProductResolver struct{ *Resolver }
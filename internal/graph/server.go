@@ -0,0 +1,72 @@
+package graph
+
+import (
+	_ "embed"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+
+	"melibot/internal/service"
+)
+
+// SDL is the schema in the GraphQL schema definition language.
+//
+//go:embed schema.graphqls
+var SDL string
+
+// DefaultMaxComplexity is the complexity limit of NewServer when none is
+// given. It lets a dashboard query such as trends(limit: 10) with the item
+// and competitors(limit: 10) of each product through, but not several of
+// them under aliases.
+const DefaultMaxComplexity = 2000
+
+// Complexity weights. Fields calling Mercado Livre cost mlRequest on top of
+// their selection; lists multiply their selection by their limit, or by
+// watchlistSize for the watchlist, which has none.
+const (
+	mlRequest     = 10
+	watchlistSize = 50
+)
+
+// NewServer returns the GraphQL handler of one request, resolving through
+// svc. Queries over maxComplexity (DefaultMaxComplexity when zero) are
+// rejected before anything is resolved, so aliases and nesting cannot
+// multiply the requests sent to Mercado Livre.
+func NewServer(svc *service.GraphService, maxComplexity int) *handler.Server {
+	if maxComplexity <= 0 {
+		maxComplexity = DefaultMaxComplexity
+	}
+	srv := handler.New(NewExecutableSchema(Config{
+		Resolvers:  &Resolver{svc: svc},
+		Complexity: complexityRoot(),
+	}))
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.FixedComplexityLimit(maxComplexity))
+	return srv
+}
+
+func complexityRoot() ComplexityRoot {
+	var c ComplexityRoot
+	item := func(child int) int { return mlRequest + child }
+
+	c.Query.Trends = func(child int, _ string, limit int) int {
+		// Each product of the category is enriched with its own requests
+		return limit * (mlRequest + child)
+	}
+	c.Query.Products = func(child int, _ string, limit int) int { return limit * child }
+	c.Query.Item = func(child int, _ string) int { return item(child) }
+	c.Query.Orders = func(child int, _ *string, _, _ *time.Time, limit int) int { return limit * child }
+	c.Query.Watchlist = func(child int) int { return watchlistSize * child }
+
+	c.Trend.Item = item
+	c.Product.Item = item
+	c.OrderItem.Item = item
+	c.WatchedProduct.Item = item
+	c.Item.Competitors = func(child int, limit int) int { return mlRequest + limit*child }
+	c.WatchedProduct.Alerts = func(child int, limit int) int { return limit * child }
+	return c
+}
@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/vektah/gqlparser/v2"
+
+	"melibot/internal/service"
+	"melibot/pkg/meli"
+	"melibot/pkg/meli/apitest"
+)
+
+func newTestServer(t *testing.T) (*apitest.Server, *client.Client) {
+	t.Helper()
+	api := apitest.NewServer()
+	t.Cleanup(api.Close)
+	meliClient := meli.NewMeliClient("test-token", "test-app", meli.WithBaseURL(api.URL))
+	svc := service.NewGraphService(meliClient, nil, nil, nil, nil)
+	return api, client.New(NewServer(svc, 0))
+}
+
+func TestItemWithCompetitors(t *testing.T) {
+	_, c := newTestServer(t)
+
+	var resp struct {
+		Item struct {
+			ID          string
+			Title       string
+			SellerID    string
+			Fulfilled   bool
+			Competitors struct {
+				Total    int
+				Listings []struct {
+					ID           string
+					SellerID     string
+					FreeShipping *bool
+				}
+			}
+		}
+	}
+	err := c.Post(`query($id: ID!) {
+		item(id: $id) {
+			id title sellerId fulfilled
+			competitors(limit: 5) { total listings { id sellerId freeShipping } }
+		}
+	}`, &resp, client.Var("id", "MLB3456789012"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Item.ID != "MLB3456789012" || resp.Item.SellerID != "123456789" {
+		t.Errorf("item = %+v", resp.Item)
+	}
+	// The search fixture has two results, one of them the item itself
+	if resp.Item.Competitors.Total != 1 || len(resp.Item.Competitors.Listings) != 1 {
+		t.Fatalf("competitors = %+v", resp.Item.Competitors)
+	}
+	if l := resp.Item.Competitors.Listings[0]; l.ID == "MLB3456789012" || l.SellerID == "" || l.FreeShipping == nil {
+		t.Errorf("listing = %+v", l)
+	}
+}
+
+func TestLimitOutOfRange(t *testing.T) {
+	_, c := newTestServer(t)
+
+	var resp map[string]interface{}
+	err := c.Post(`{ item(id: "MLB3456789012") { competitors(limit: 500) { total } } }`, &resp)
+	if err == nil || !strings.Contains(err.Error(), "limit must be between 1 and 50") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestComplexity(t *testing.T) {
+	trend := `trends(categoryId: "MLB1055", limit: 10) {
+		items { id title item { price competitors(limit: 10) { total listings { id price } } } }
+	}`
+	tests := []struct {
+		name    string
+		query   string
+		allowed bool
+	}{
+		{"scalar fields", `{ item(id: "MLB1") { id title price } }`, true},
+		{"trends with competitors", "{" + trend + "}", true},
+		{"aliased trends", "{ a: " + trend + " b: " + trend + " c: " + trend + " }", false},
+		{"largest limits", `{ trends(categoryId: "MLB1055", limit: 50) {
+			items { item { competitors(limit: 50) { listings { id } } } }
+		} }`, false},
+		{"watchlist items", `{ watchlist { productId item { id } } }`, true},
+	}
+
+	es := NewExecutableSchema(Config{Resolvers: &Resolver{}, Complexity: complexityRoot()})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, errs := gqlparser.LoadQuery(es.Schema(), tt.query)
+			if errs != nil {
+				t.Fatal(errs)
+			}
+			got := complexity.Calculate(es, doc.Operations[0], nil)
+			if allowed := got <= DefaultMaxComplexity; allowed != tt.allowed {
+				t.Errorf("complexity %d, allowed = %v, want %v", got, allowed, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestComplexityLimitRejectsBeforeResolving(t *testing.T) {
+	api, c := newTestServer(t)
+
+	trend := `trends(categoryId: "MLB1055", limit: 20) {
+		items { item { competitors(limit: 10) { listings { id } } } }
+	}`
+	query := "{ a: " + trend + " b: " + trend + " c: " + trend + " }"
+
+	var resp map[string]interface{}
+	err := c.Post(query, &resp)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Fatalf("err = %v", err)
+	}
+	if n := len(api.Requests()); n != 0 {
+		t.Errorf("sent %d requests to Mercado Livre", n)
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+	"melibot/pkg/meli"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+
+	defaultFailedJobsLimit = 50
+	maxFailedJobsLimit     = 500
+)
+
+type AdminHandler struct {
+	auditRepo *repository.AuditRepository
+	statusSvc *service.StatusService
+	quotaSvc  *service.QuotaService
+}
+
+func NewAdminHandler(auditRepo *repository.AuditRepository, statusSvc *service.StatusService, quotaSvc *service.QuotaService) *AdminHandler {
+	return &AdminHandler{auditRepo: auditRepo, statusSvc: statusSvc, quotaSvc: quotaSvc}
+}
+
+// GetStatus returns an at-a-glance overview of the system's health.
+func (h *AdminHandler) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.statusSvc.Status(c.Request.Context()))
+}
+
+// GetQuota returns the Mercado Livre calls of each account in the current
+// hour and day against the request budget, with their hourly history.
+func (h *AdminHandler) GetQuota(c *gin.Context) {
+	report, err := h.quotaSvc.Report(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ListFailedJobs lists the jobs that failed for good, optionally of one
+// type, and the scheduled tasks whose last run failed.
+func (h *AdminHandler) ListFailedJobs(c *gin.Context) {
+	limit, offset := defaultFailedJobsLimit, 0
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		limit = min(n, maxFailedJobsLimit)
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "offset must be a non-negative integer")})
+			return
+		}
+		offset = n
+	}
+
+	failed, err := h.statusSvc.FailedJobs(c.Request.Context(), c.Query("type"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, failed)
+}
+
+// GetAuditLog lists audit entries, filtered by actor, path prefix and an
+// RFC3339 from/to window.
+func (h *AdminHandler) GetAuditLog(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := repository.AuditFilter{
+		Actor: c.Query("actor"),
+		Path:  c.Query("path"),
+		Limit: defaultAuditLimit,
+	}
+
+	var err error
+	if v := c.Query("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "from must be RFC3339")})
+			return
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "to must be RFC3339")})
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		filter.Limit = min(n, maxAuditLimit)
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "offset must be a non-negative integer")})
+			return
+		}
+		filter.Offset = n
+	}
+
+	entries, err := h.auditRepo.Find(ctx, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// GetDebugTraces returns the stored product traces, newest first. Traces are
+// captured for products in ML_DEBUG_PRODUCTS or requested with
+// /api/trends?debug_product=ID.
+func (h *AdminHandler) GetDebugTraces(c *gin.Context) {
+	c.JSON(http.StatusOK, meli.RecentTraces(c.Param("product_id")))
+}
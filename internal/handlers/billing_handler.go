@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+// maxBillingWebhookBytes caps the body of a payment provider webhook.
+const maxBillingWebhookBytes = 1 << 20
+
+// BillingHandler serves the subscription plans of organizations: the
+// payment provider's webhooks, each organization's plan and the operators'
+// overrides.
+type BillingHandler struct {
+	svc *service.BillingService
+}
+
+func NewBillingHandler(svc *service.BillingService) *BillingHandler {
+	return &BillingHandler{svc: svc}
+}
+
+type planRequest struct {
+	Plan      string     `json:"plan"`
+	PeriodEnd *time.Time `json:"period_end"`
+}
+
+// ReceiveStripe applies a Stripe webhook. Bad signatures get 400; failures
+// to store the change get 500 so Stripe delivers the event again.
+func (h *BillingHandler) ReceiveStripe(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBillingWebhookBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	err = h.svc.HandleStripe(c.Request.Context(), payload, c.GetHeader(service.StripeSignatureHeader))
+	if errors.Is(err, service.ErrBillingSignature) || errors.Is(err, service.ErrBillingPayload) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		log.Printf("[ERROR] Failed to apply Stripe webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// GetPlan returns the plan of the request's organization and what it
+// allows, with the plans on offer.
+func (h *BillingHandler) GetPlan(c *gin.Context) {
+	e, err := h.svc.Entitlement(c.Request.Context())
+	if errors.Is(err, service.ErrOrganizationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entitlement": e, "plans": service.Plans})
+}
+
+// SetPlan puts an organization on a plan by hand; an empty plan
+// deactivates it.
+func (h *BillingHandler) SetPlan(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req planRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	e, err := h.svc.SetPlan(c.Request.Context(), id, req.Plan, req.PeriodEnd)
+	if errors.Is(err, service.ErrUnknownPlan) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if errors.Is(err, service.ErrOrganizationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/capture"
+	"melibot/internal/i18n"
+)
+
+// CaptureHandler exposes the debug capture of HTTP exchanges to admins.
+type CaptureHandler struct {
+	recorder *capture.Recorder
+}
+
+func NewCaptureHandler(recorder *capture.Recorder) *CaptureHandler {
+	return &CaptureHandler{recorder: recorder}
+}
+
+type captureRequest struct {
+	Enabled *bool `json:"enabled"`
+	Clear   bool  `json:"clear"`
+}
+
+// ListCaptures returns the captured exchanges, newest first, optionally
+// filtered by direction (inbound, outbound), URL substring, minimum status
+// and count.
+func (h *CaptureHandler) ListCaptures(c *gin.Context) {
+	filter := capture.Filter{Direction: c.Query("direction"), Contains: c.Query("contains")}
+	switch filter.Direction {
+	case "", capture.Inbound, capture.Outbound:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "direction must be inbound or outbound")})
+		return
+	}
+	if v := c.Query("min_status"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "min_status must be a positive integer")})
+			return
+		}
+		filter.MinStatus = n
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		filter.Limit = n
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":   h.recorder.Enabled(),
+		"size":      h.recorder.Size(),
+		"exchanges": h.recorder.Recent(filter),
+	})
+}
+
+// SetCapture switches capturing on or off and optionally drops what was
+// captured so far.
+func (h *CaptureHandler) SetCapture(c *gin.Context) {
+	var req captureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if req.Enabled != nil {
+		h.recorder.SetEnabled(*req.Enabled)
+	}
+	if req.Clear {
+		h.recorder.Clear()
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": h.recorder.Enabled(), "size": h.recorder.Size()})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type CategoryStatsHandler struct {
+	svc *service.CategoryStatsService
+}
+
+func NewCategoryStatsHandler(svc *service.CategoryStatsService) *CategoryStatsHandler {
+	return &CategoryStatsHandler{svc: svc}
+}
+
+// GetCategoryStats returns the latest crawl statistics for a category.
+func (h *CategoryStatsHandler) GetCategoryStats(c *gin.Context) {
+	ctx := c.Request.Context()
+	categoryID := c.Param("id")
+
+	stats, err := h.svc.LatestStats(ctx, categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if stats == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Tr(c, "no stats yet; start a crawl with POST /api/categories/%s/crawl", categoryID)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"category_id":         stats.CategoryID,
+		"total_listings":      stats.TotalListings,
+		"sampled_listings":    stats.SampledListings,
+		"price_min":           stats.PriceMin,
+		"price_p25":           stats.PriceP25,
+		"price_p50":           stats.PriceP50,
+		"price_p75":           stats.PriceP75,
+		"price_max":           stats.PriceMax,
+		"sold_total":          stats.SoldTotal,
+		"sold_p50":            stats.SoldP50,
+		"free_shipping_share": stats.FreeShippingShare,
+		"top_seller_health":   stats.TopSellerHealth,
+		"crawled_at":          stats.CrawledAt,
+	})
+}
+
+// GetEntryReport answers how hard it is to enter a category: its listing
+// rules and required attributes and certifications, with the median price,
+// seller concentration, FULL share and listing quality of its top results.
+func (h *CategoryStatsHandler) GetEntryReport(c *gin.Context) {
+	report, err := h.svc.EntryReport(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+)
+
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+)
+
+// changeKinds are the values ?kind= accepts on the change feed.
+var changeKinds = map[string]bool{
+	repository.ChangeKindNewListing:    true,
+	repository.ChangeKindRemoved:       true,
+	repository.ChangeKindPriceDrop:     true,
+	repository.ChangeKindPriceIncrease: true,
+	repository.ChangeKindStock:         true,
+}
+
+type CompetitorHandler struct {
+	svc *service.CompetitorService
+}
+
+func NewCompetitorHandler(svc *service.CompetitorService) *CompetitorHandler {
+	return &CompetitorHandler{svc: svc}
+}
+
+type watchCompetitorRequest struct {
+	SellerID int64 `json:"seller_id"`
+}
+
+// ListCompetitors returns the watched competitors.
+func (h *CompetitorHandler) ListCompetitors(c *gin.Context) {
+	competitors, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(competitors))
+	for i := range competitors {
+		out = append(out, competitorResponse(&competitors[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Watch starts tracking a competitor and takes the baseline snapshot of
+// their listings.
+func (h *CompetitorHandler) Watch(c *gin.Context) {
+	var req watchCompetitorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if req.SellerID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "seller_id is required")})
+		return
+	}
+
+	competitor, err := h.svc.Watch(c.Request.Context(), req.SellerID)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, competitorResponse(competitor))
+}
+
+// Unwatch stops tracking a competitor.
+func (h *CompetitorHandler) Unwatch(c *gin.Context) {
+	sellerID, ok := sellerIDParam(c)
+	if !ok {
+		return
+	}
+
+	err := h.svc.Unwatch(c.Request.Context(), sellerID)
+	if errors.Is(err, service.ErrNotCompetitor) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetChanges returns a competitor's change feed, newest first: new and
+// removed listings, price moves and stock hints over the last ?days=
+// (default 30), optionally of one ?kind=.
+func (h *CompetitorHandler) GetChanges(c *gin.Context) {
+	sellerID, ok := sellerIDParam(c)
+	if !ok {
+		return
+	}
+	days, ok := historyDays(c)
+	if !ok {
+		return
+	}
+	kind := c.Query("kind")
+	if kind != "" && !changeKinds[kind] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "invalid %s", "kind")})
+		return
+	}
+	limit := defaultChangesLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		limit = min(n, maxChangesLimit)
+	}
+
+	since := time.Now().AddDate(0, 0, -days)
+	changes, err := h.svc.Changes(c.Request.Context(), sellerID, kind, since, limit)
+	if errors.Is(err, service.ErrNotCompetitor) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(changes))
+	for _, ch := range changes {
+		out = append(out, gin.H{
+			"item_id":    ch.ItemID,
+			"title":      ch.Title,
+			"kind":       ch.Kind,
+			"old_value":  ch.OldValue,
+			"new_value":  ch.NewValue,
+			"created_at": ch.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// sellerIDParam reads the :id path parameter, answering 400 itself when it
+// is invalid.
+func sellerIDParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "invalid seller id")})
+		return 0, false
+	}
+	return id, true
+}
+
+func competitorResponse(c *repository.Competitor) gin.H {
+	return gin.H{
+		"seller_id":        c.SellerID,
+		"nickname":         c.Nickname,
+		"listings":         c.Listings,
+		"last_snapshot_at": c.LastSnapshotAt,
+		"created_at":       c.CreatedAt,
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+// maxConfigBytes caps the size of an imported config document.
+const maxConfigBytes = 4 << 20
+
+// ConfigHandler exports and imports the automation config as YAML.
+type ConfigHandler struct {
+	svc *service.ConfigService
+}
+
+func NewConfigHandler(svc *service.ConfigService) *ConfigHandler {
+	return &ConfigHandler{svc: svc}
+}
+
+// Export downloads the watchlist, listing rules, listing and answer
+// templates and scoring profiles of the organization as YAML.
+func (h *ConfigHandler) Export(c *gin.Context) {
+	doc, err := h.svc.Export(c.Request.Context())
+	if errors.Is(err, service.ErrConfigNoOrganization) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	out, err := service.MarshalConfig(doc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="melibot-config.yaml"`)
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", out)
+}
+
+// Import loads a YAML document made by Export, creating and updating
+// entries; ?prune=true also deletes the ones missing from it. Nothing is
+// imported when an entry is invalid.
+func (h *ConfigHandler) Import(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxConfigBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(body) > maxConfigBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": i18n.Tr(c, "config document too large")})
+		return
+	}
+	doc, err := service.UnmarshalConfig(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "invalid config document: %s", err.Error())})
+		return
+	}
+
+	res, err := h.svc.Import(c.Request.Context(), doc, c.Query("prune") == "true")
+	var entryErr *service.ConfigError
+	switch {
+	case errors.As(err, &entryErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   i18n.TrError(c, entryErr.Err),
+			"section": entryErr.Section,
+			"index":   entryErr.Index,
+		})
+		return
+	case errors.Is(err, service.ErrConfigNoOrganization), errors.Is(err, service.ErrConfigVersion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	case errors.Is(err, service.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.TrError(c, err), "imported": res})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "imported": res})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
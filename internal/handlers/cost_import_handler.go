@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net/http"
+	"net/mail"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+// maxInboundFormMemory is how much of a multipart inbound email is kept in
+// memory; the rest spills to temporary files.
+const maxInboundFormMemory = 32 << 20
+
+type CostImportHandler struct {
+	svc *service.CostImportService
+	// emailToken authenticates the inbound email service; inbound emails
+	// are refused while it is empty.
+	emailToken string
+}
+
+func NewCostImportHandler(svc *service.CostImportService, emailToken string) *CostImportHandler {
+	return &CostImportHandler{svc: svc, emailToken: emailToken}
+}
+
+// ImportCosts records a supplier price list, a CSV with sku and cost
+// columns as a multipart "file" field or as a text/csv body, and reports
+// the costs it changed and the margins of the listings they affect.
+func (h *CostImportHandler) ImportCosts(c *gin.Context) {
+	var (
+		rows []service.CostRow
+		err  error
+	)
+	if file, _, formErr := c.Request.FormFile("file"); formErr == nil {
+		defer file.Close()
+		rows, err = service.ParseCostCSV(file)
+	} else {
+		rows, err = service.ParseCostCSV(c.Request.Body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	report, err := h.svc.Import(c.Request.Context(), "upload", rows)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ReceiveEmail imports the CSV attachments of a supplier email forwarded by
+// an inbound email service to ?token=. It accepts the raw message as the
+// body, as the "email" or "body-mime" field of a form, or a form with the
+// attachments as files and the sender in "sender" or "from".
+func (h *CostImportHandler) ReceiveEmail(c *gin.Context) {
+	if h.emailToken == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Tr(c, "inbound emails disabled; set SUPPLIER_EMAIL_TOKEN")})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(h.emailToken)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": i18n.Tr(c, "invalid token")})
+		return
+	}
+
+	email, err := inboundEmail(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	report, err := h.svc.ImportEmail(c.Request.Context(), email)
+	switch {
+	case errors.Is(err, service.ErrSenderNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.TrError(c, err)})
+		return
+	case errors.Is(err, service.ErrNoCostAttachments):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	case err != nil:
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// inboundEmail reads the email of an inbound request, see ReceiveEmail.
+func inboundEmail(c *gin.Context) (*service.CostEmail, error) {
+	if !strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		return service.ParseCostEmail(c.Request.Body)
+	}
+	if err := c.Request.ParseMultipartForm(maxInboundFormMemory); err != nil {
+		return nil, err
+	}
+	for _, field := range []string{"email", "body-mime"} {
+		if raw := c.Request.FormValue(field); raw != "" {
+			return service.ParseCostEmail(strings.NewReader(raw))
+		}
+	}
+
+	email := &service.CostEmail{Subject: c.Request.FormValue("subject")}
+	sender := c.Request.FormValue("sender")
+	if sender == "" {
+		sender = c.Request.FormValue("from")
+	}
+	if from, err := mail.ParseAddress(sender); err == nil {
+		email.From = from.Address
+	}
+	for _, files := range c.Request.MultipartForm.File {
+		for _, fh := range files {
+			if !strings.EqualFold(path.Ext(fh.Filename), ".csv") {
+				continue
+			}
+			f, err := fh.Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(f)
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			email.Attachments = append(email.Attachments, service.CostAttachment{Name: fh.Filename, Data: data})
+		}
+	}
+	return email, nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+// CredentialHandler manages the encrypted credentials of third-party
+// integrations. Values are write-only: reads return them masked.
+type CredentialHandler struct {
+	svc *service.CredentialService
+}
+
+func NewCredentialHandler(svc *service.CredentialService) *CredentialHandler {
+	return &CredentialHandler{svc: svc}
+}
+
+type credentialRequest struct {
+	Kind        string `json:"kind"`
+	Description string `json:"description"`
+	Value       string `json:"value"`
+}
+
+// ListCredentials returns the stored credentials, masked.
+func (h *CredentialHandler) ListCredentials(c *gin.Context) {
+	creds, err := h.svc.Credentials(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, creds)
+}
+
+// GetCredential returns a single credential, masked.
+func (h *CredentialHandler) GetCredential(c *gin.Context) {
+	cred, err := h.svc.Credential(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, service.ErrCredentialNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cred)
+}
+
+// PutCredential creates or replaces a credential; without a value the
+// stored one is kept.
+func (h *CredentialHandler) PutCredential(c *gin.Context) {
+	var req credentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	cred, created, err := h.svc.SaveCredential(c.Request.Context(), c.Param("name"), req.Kind, req.Description, req.Value)
+	switch {
+	case errors.Is(err, service.ErrCredentialName), errors.Is(err, service.ErrCredentialKind),
+		errors.Is(err, service.ErrCredentialValue), errors.Is(err, service.ErrCredentialServiceAccount),
+		errors.Is(err, service.ErrCredentialS3):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if created {
+		c.JSON(http.StatusCreated, cred)
+		return
+	}
+	c.JSON(http.StatusOK, cred)
+}
+
+// DeleteCredential removes a credential.
+func (h *CredentialHandler) DeleteCredential(c *gin.Context) {
+	err := h.svc.DeleteCredential(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, service.ErrCredentialNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
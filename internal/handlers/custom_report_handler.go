@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+)
+
+// CustomReportHandler manages the reports users build over orders and
+// snapshots, and runs them.
+type CustomReportHandler struct {
+	svc *service.CustomReportService
+}
+
+func NewCustomReportHandler(svc *service.CustomReportService) *CustomReportHandler {
+	return &CustomReportHandler{svc: svc}
+}
+
+// ListSources returns the data sources reports can read and their fields.
+func (h *CustomReportHandler) ListSources(c *gin.Context) {
+	c.JSON(http.StatusOK, service.ReportSources())
+}
+
+// List returns the report definitions. Webhook secrets are masked.
+func (h *CustomReportHandler) List(c *gin.Context) {
+	defs, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(defs))
+	for i := range defs {
+		out = append(out, customReportResponse(&defs[i], false))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Get returns a report definition.
+func (h *CustomReportHandler) Get(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	def, err := h.svc.Get(c.Request.Context(), id)
+	if errors.Is(err, service.ErrCustomReportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, customReportResponse(def, false))
+}
+
+// Create adds a report definition. Only the responses of Create and Put
+// show the full secret of a webhook destination.
+func (h *CustomReportHandler) Create(c *gin.Context) {
+	h.save(c, 0, http.StatusCreated)
+}
+
+// Put replaces a report definition.
+func (h *CustomReportHandler) Put(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	h.save(c, id, http.StatusOK)
+}
+
+func (h *CustomReportHandler) save(c *gin.Context, id uint, status int) {
+	var spec service.ReportSpec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	spec.DestinationURL = strings.TrimSpace(spec.DestinationURL)
+	if err := spec.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	def, err := h.svc.Save(c.Request.Context(), id, spec)
+	if errors.Is(err, service.ErrCustomReportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, customReportResponse(def, true))
+}
+
+// Delete removes a report definition.
+func (h *CustomReportHandler) Delete(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	err := h.svc.Delete(c.Request.Context(), id)
+	if errors.Is(err, service.ErrCustomReportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Run runs a report now and delivers it to its destination. Failed
+// deliveries get 502.
+func (h *CustomReportHandler) Run(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	run, err := h.svc.Run(c.Request.Context(), id)
+	if errors.Is(err, service.ErrCustomReportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if errors.Is(err, service.ErrExportStorageDisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// GetData runs a report and returns its table right away, as ?format=csv
+// or json (default the report's format), without delivering it.
+func (h *CustomReportHandler) GetData(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	def, err := h.svc.Get(c.Request.Context(), id)
+	if errors.Is(err, service.ErrCustomReportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	format := c.DefaultQuery("format", def.Format)
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, service.ErrCustomReportFormat)})
+		return
+	}
+
+	table, err := h.svc.Execute(c.Request.Context(), def)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	c.Status(http.StatusOK)
+	if err := service.WriteReportTable(c.Writer, format, table); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+func customReportResponse(def *repository.ReportDefinition, showSecret bool) gin.H {
+	spec, err := service.DefinitionSpec(def)
+	resp := gin.H{
+		"id":              def.ID,
+		"name":            def.Name,
+		"source":          def.Source,
+		"filters":         spec.Filters,
+		"group_by":        spec.GroupBy,
+		"metrics":         spec.Metrics,
+		"days":            def.Days,
+		"schedule":        def.Schedule,
+		"format":          def.Format,
+		"destination":     def.Destination,
+		"destination_url": def.DestinationURL,
+		"enabled":         def.Enabled,
+		"next_run_at":     def.NextRunAt,
+		"last_run_at":     def.LastRunAt,
+		"last_rows":       def.LastRows,
+		"last_key":        def.LastKey,
+		"last_error":      def.LastError,
+		"created_at":      def.CreatedAt,
+	}
+	if err != nil {
+		resp["error"] = err.Error()
+	}
+	if def.Secret != "" {
+		secret := def.Secret
+		if !showSecret && len(secret) > 4 {
+			secret = strings.Repeat("*", 8) + secret[len(secret)-4:]
+		}
+		resp["secret"] = secret
+	}
+	return resp
+}
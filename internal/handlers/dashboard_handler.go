@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+const (
+	defaultDashboardLimit = 5
+	maxDashboardLimit     = 20
+)
+
+type DashboardHandler struct {
+	svc *service.DashboardService
+}
+
+func NewDashboardHandler(svc *service.DashboardService) *DashboardHandler {
+	return &DashboardHandler{svc: svc}
+}
+
+// GetSummary returns every widget of the index page in one response. The
+// category and price drop lists hold up to ?limit= entries (default 5).
+// Widgets that failed are null and listed under "errors"; the status is
+// still 200.
+func (h *DashboardHandler) GetSummary(c *gin.Context) {
+	limit := defaultDashboardLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxDashboardLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be between 1 and %d", maxDashboardLimit)})
+			return
+		}
+		limit = n
+	}
+
+	c.JSON(http.StatusOK, h.svc.Summary(c.Request.Context(), limit))
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type E2EHandler struct {
+	svc *service.E2EService
+}
+
+func NewE2EHandler(svc *service.E2EService) *E2EHandler {
+	return &E2EHandler{svc: svc}
+}
+
+// StartRun starts an end-to-end check of the order pipeline: it creates a
+// buyer test user and a test listing in {"category_id"}, and answers with
+// what the operator has to buy as the buyer.
+func (h *E2EHandler) StartRun(c *gin.Context) {
+	var req service.E2ERequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.CategoryID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "category_id is required")})
+		return
+	}
+
+	report, err := h.svc.Start(c.Request.Context(), req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, report)
+}
+
+// VerifyRun checks how far the order of a run went through the pipeline
+// and reports each step's result.
+func (h *E2EHandler) VerifyRun(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "invalid run id")})
+		return
+	}
+
+	report, err := h.svc.Verify(c.Request.Context(), uint(id))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *E2EHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSandboxDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.TrError(c, err)})
+	case errors.Is(err, service.ErrE2EInvalidPrice):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+	case errors.Is(err, service.ErrE2ERunNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+	default:
+		respondUpstreamError(c, err)
+	}
+}
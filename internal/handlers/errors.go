@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/pkg/meli"
+)
+
+// respondUpstreamError maps a MeliClient error to a response: 404 and 401 pass
+// through, 429 keeps Retry-After, anything else is a 502.
+func respondUpstreamError(c *gin.Context, err error) {
+	var rateLimited *meli.ErrRateLimited
+	switch {
+	case errors.Is(err, meli.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, meli.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.As(err, &rateLimited):
+		if rateLimited.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(rateLimited.RetryAfter.Seconds()))))
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	}
+}
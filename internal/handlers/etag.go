@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+)
+
+// respondWithETag writes payload as JSON with a weak ETag derived from its
+// encoded bytes, answering 304 Not Modified when the client already has it.
+func respondWithETag(c *gin.Context, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// etagMatches implements the weak comparison used by If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// setVersionETag sets the ETag of a versioned rule or template to its
+// version, for the If-Match of the next edit.
+func setVersionETag(c *gin.Context, version uint) {
+	c.Header("ETag", `"`+strconv.FormatUint(uint64(version), 10)+`"`)
+}
+
+// ifMatchVersion returns the version an edit is based on: the one in the
+// If-Match header, an ETag set by setVersionETag, or else the version sent
+// in the body (0 for none). It answers 400 and returns false when If-Match
+// is not such an ETag.
+func ifMatchVersion(c *gin.Context, body uint) (uint, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return body, true
+	}
+	v, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 0)
+	if err != nil || v == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "If-Match must be the ETag of the version being edited")})
+		return 0, false
+	}
+	return uint(v), true
+}
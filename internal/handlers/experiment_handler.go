@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type ExperimentHandler struct {
+	svc *service.ExperimentService
+}
+
+func NewExperimentHandler(svc *service.ExperimentService) *ExperimentHandler {
+	return &ExperimentHandler{svc: svc}
+}
+
+// ListExperiments returns the experiments, of one listing with ?item_id=.
+func (h *ExperimentHandler) ListExperiments(c *gin.Context) {
+	experiments, err := h.svc.Experiments(c.Request.Context(), c.Query("item_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, experiments)
+}
+
+// ListItemExperiments returns the experiments of the listing in the path.
+func (h *ExperimentHandler) ListItemExperiments(c *gin.Context) {
+	experiments, err := h.svc.Experiments(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, experiments)
+}
+
+// CreateExperiment starts an experiment on one of my listings.
+func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
+	var req service.NewExperiment
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	h.start(c, &req)
+}
+
+// CreateItemExperiment starts an experiment on the listing in the path,
+// e.g. an A/B test of two titles.
+func (h *ExperimentHandler) CreateItemExperiment(c *gin.Context) {
+	var req service.NewExperiment
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	req.ItemID = c.Param("id")
+	h.start(c, &req)
+}
+
+func (h *ExperimentHandler) start(c *gin.Context, req *service.NewExperiment) {
+	if err := service.ValidateExperiment(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	experiment, err := h.svc.Start(c.Request.Context(), req)
+	switch {
+	case errors.Is(err, service.ErrNotOwnItem):
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.TrError(c, err)})
+		return
+	case errors.Is(err, service.ErrExperimentRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.TrError(c, err)})
+		return
+	case errors.Is(err, service.ErrExperimentInactive), errors.Is(err, service.ErrExperimentVariations),
+		errors.Is(err, service.ErrExperimentTitleLocked):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	case err != nil:
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, experiment)
+}
+
+// GetExperiment returns an experiment with the visits, sales and
+// conversion of each variant so far.
+func (h *ExperimentHandler) GetExperiment(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	report, err := h.svc.Report(c.Request.Context(), id)
+	if errors.Is(err, service.ErrExperimentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// StopExperiment ends a running experiment and restores the listing.
+func (h *ExperimentHandler) StopExperiment(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	experiment, err := h.svc.Stop(c.Request.Context(), id)
+	switch {
+	case errors.Is(err, service.ErrExperimentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	case errors.Is(err, service.ErrExperimentNotRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.TrError(c, err)})
+		return
+	case err != nil:
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, experiment)
+}
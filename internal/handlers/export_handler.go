@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/jobs"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+	"melibot/internal/storage"
+)
+
+type ExportHandler struct {
+	queue  *jobs.Queue
+	bucket *storage.Bucket
+	urlTTL time.Duration
+}
+
+// NewExportHandler serves exports uploaded to bucket, which is nil when
+// object storage is not configured.
+func NewExportHandler(queue *jobs.Queue, bucket *storage.Bucket, urlTTL time.Duration) *ExportHandler {
+	return &ExportHandler{queue: queue, bucket: bucket, urlTTL: urlTTL}
+}
+
+// EnqueueExport starts an export job: product trends, trend snapshots,
+// daily price history, a year of orders or a full category crawl, as CSV,
+// JSON or Parquet. The job uploads the file to object storage and its
+// result holds a signed URL to download it.
+func (h *ExportHandler) EnqueueExport(c *gin.Context) {
+	if h.bucket == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": i18n.TrError(c, service.ErrExportStorageDisabled)})
+		return
+	}
+	var req service.ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	enqueueJob(c, h.queue, jobs.TypeExport, req)
+}
+
+// DownloadExport redirects to a freshly signed URL of a finished export, so
+// links keep working after the one in the job result expires.
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	if h.bucket == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": i18n.TrError(c, service.ErrExportStorageDisabled)})
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "invalid job id")})
+		return
+	}
+
+	job, err := h.queue.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if job == nil || job.Type != jobs.TypeExport {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Tr(c, "export job not found")})
+		return
+	}
+	if job.Status != repository.JobStatusSucceeded {
+		c.JSON(http.StatusConflict, jobResponse(job))
+		return
+	}
+
+	var result service.ExportResult
+	if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	url, _, err := h.bucket.SignedURL(result.Key, h.urlTTL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+)
+
+// fieldSet holds the fields a client picked with ?fields=. A nil set means
+// every field.
+type fieldSet map[string]bool
+
+func (f fieldSet) has(name string) bool {
+	return f == nil || f[name]
+}
+
+// jsonFields lists the JSON names of the fields of a struct type, including
+// those of embedded structs.
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			names = append(names, jsonFields(f.Type)...)
+			continue
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Pointer && f.Type.Elem().Kind() == reflect.Struct {
+			names = append(names, jsonFields(f.Type.Elem())...)
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// requestedFields reads ?fields=, a comma-separated list of JSON field
+// names out of allowed, answering 400 itself on an unknown one. "id" is
+// always included so projected entries can still be told apart.
+func requestedFields(c *gin.Context, allowed []string) (fieldSet, bool) {
+	v := c.Query("fields")
+	if v == "" {
+		return nil, true
+	}
+	fields := fieldSet{"id": true}
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, a := range allowed {
+			if a == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "unknown field %s; fields accepts %s", name, strings.Join(allowed, ", "))})
+			return nil, false
+		}
+		fields[name] = true
+	}
+	return fields, true
+}
+
+// projectFields keeps only the requested fields of payload: those of each
+// entry of "items" when the payload is a list response, otherwise those of
+// the payload itself.
+func projectFields(payload interface{}, fields fieldSet) (interface{}, error) {
+	if fields == nil {
+		return payload, nil
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, err
+	}
+
+	rawItems, ok := obj["items"]
+	if !ok {
+		return pickFields(obj, fields), nil
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(rawItems, &items); err != nil {
+		return nil, err
+	}
+	for i, item := range items {
+		items[i] = pickFields(item, fields)
+	}
+	if obj["items"], err = json.Marshal(items); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func pickFields(obj map[string]json.RawMessage, fields fieldSet) map[string]json.RawMessage {
+	out := make(map[string]json.RawMessage, len(fields))
+	for name, value := range obj {
+		if fields[name] {
+			out[name] = value
+		}
+	}
+	return out
+}
+
+// respondWithFields projects payload to the requested fields and answers
+// with respondWithETag.
+func respondWithFields(c *gin.Context, payload interface{}, fields fieldSet) {
+	projected, err := projectFields(payload, fields)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondWithETag(c, projected)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+const defaultReconciliationRange = 30 * 24 * time.Hour
+
+type FinanceHandler struct {
+	svc *service.FinanceService
+}
+
+func NewFinanceHandler(svc *service.FinanceService) *FinanceHandler {
+	return &FinanceHandler{svc: svc}
+}
+
+// SyncPayments pulls the Mercado Pago payments updated since the last sync.
+func (h *FinanceHandler) SyncPayments(c *gin.Context) {
+	n, err := h.svc.SyncPayments(c.Request.Context())
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"synced": n})
+}
+
+// GetReconciliation matches my orders created over ?from=&to= (default:
+// last 30 days) against their payments, showing which were paid out,
+// optionally only those with ?settlement=released|pending|unpaid|refunded.
+func (h *FinanceHandler) GetReconciliation(c *gin.Context) {
+	from, to, ok := dateRange(c, defaultReconciliationRange)
+	if !ok {
+		return
+	}
+
+	report, err := h.svc.Reconciliation(c.Request.Context(), from, to, c.Query("settlement"))
+	if errors.Is(err, service.ErrInvalidSettlement) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetProjection projects the cash released to me per day over the next
+// ?days= (default 30, at most 90) from the payments not released yet.
+func (h *FinanceHandler) GetProjection(c *gin.Context) {
+	days := service.DefaultProjectionDays
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "%s must be an integer", "days")})
+			return
+		}
+		days = n
+	}
+
+	projection, err := h.svc.Projection(c.Request.Context(), days)
+	if errors.Is(err, service.ErrInvalidProjectionDays) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, projection)
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/service"
+)
+
+type FulfillmentHandler struct {
+	svc *service.FulfillmentService
+}
+
+func NewFulfillmentHandler(svc *service.FulfillmentService) *FulfillmentHandler {
+	return &FulfillmentHandler{svc: svc}
+}
+
+// GetStock returns FULL and seller-fulfilled stock per SKU, least FULL cover
+// first.
+func (h *FulfillmentHandler) GetStock(c *gin.Context) {
+	report, err := h.svc.StockReport(c.Request.Context())
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
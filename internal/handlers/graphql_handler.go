@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/graph"
+	"melibot/internal/service"
+)
+
+type GraphQLHandler struct {
+	svc           *service.GraphService
+	maxComplexity int
+}
+
+// NewGraphQLHandler returns a handler rejecting queries over maxComplexity,
+// or graph.DefaultMaxComplexity when zero.
+func NewGraphQLHandler(svc *service.GraphService, maxComplexity int) *GraphQLHandler {
+	return &GraphQLHandler{svc: svc, maxComplexity: maxComplexity}
+}
+
+// Query runs a GraphQL query, POSTed as {"query", "operationName",
+// "variables"} or passed as GET parameters of the same names (variables
+// JSON-encoded). Field errors are reported in the body next to the data
+// that could be resolved.
+func (h *GraphQLHandler) Query(c *gin.Context) {
+	// Nested fields fan out to Mercado Livre; bound the whole request like
+	// /api/trends.
+	budget, _ := trendsBudget("")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), budget)
+	defer cancel()
+
+	graph.NewServer(h.svc, h.maxComplexity).ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
+
+// GetSchema returns the schema in the GraphQL schema definition language.
+func (h *GraphQLHandler) GetSchema(c *gin.Context) {
+	c.String(http.StatusOK, graph.SDL)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+const (
+	defaultHistoryDays = 30
+	maxHistoryDays     = 365
+
+	defaultSeasonalityMonths = 24
+	maxSeasonalityMonths     = 60
+)
+
+type HistoryHandler struct {
+	svc *service.HistoryService
+}
+
+func NewHistoryHandler(svc *service.HistoryService) *HistoryHandler {
+	return &HistoryHandler{svc: svc}
+}
+
+// GetRankHistory returns a product's daily position in a category's
+// highlights, from stored snapshots.
+func (h *HistoryHandler) GetRankHistory(c *gin.Context) {
+	categoryID := c.Query("category_id")
+	if categoryID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "category_id is required")})
+		return
+	}
+	days, ok := historyDays(c)
+	if !ok {
+		return
+	}
+
+	series, err := h.svc.RankHistory(c.Request.Context(), c.Param("id"), categoryID, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// GetSeasonality aggregates a category's sold growth and trending keywords
+// by ?group_by=week|month (default month) over the last ?months= months.
+func (h *HistoryHandler) GetSeasonality(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", "month")
+	months := defaultSeasonalityMonths
+	if v := c.Query("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "months must be a positive integer")})
+			return
+		}
+		months = min(n, maxSeasonalityMonths)
+	}
+
+	seasonality, err := h.svc.Seasonality(c.Request.Context(), c.Param("id"), groupBy, months)
+	if errors.Is(err, service.ErrInvalidGroupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, seasonality)
+}
+
+// historyDays reads ?days=, answering 400 itself when it is invalid.
+func historyDays(c *gin.Context) (int, bool) {
+	v := c.Query("days")
+	if v == "" {
+		return defaultHistoryDays, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "days must be a positive integer")})
+		return 0, false
+	}
+	return min(n, maxHistoryDays), true
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type InventoryHandler struct {
+	svc *service.InventoryService
+}
+
+func NewInventoryHandler(svc *service.InventoryService) *InventoryHandler {
+	return &InventoryHandler{svc: svc}
+}
+
+// GetForecast returns days until stockout and reorder suggestions per SKU.
+// ?window_days=, ?lead_time_days=, ?safety_days= and ?coverage_days=
+// override the configured settings.
+func (h *InventoryHandler) GetForecast(c *gin.Context) {
+	settings := h.svc.Settings()
+	for key, field := range map[string]*int{
+		"window_days":    &settings.WindowDays,
+		"lead_time_days": &settings.LeadTimeDays,
+		"safety_days":    &settings.SafetyDays,
+		"coverage_days":  &settings.CoverageDays,
+	} {
+		v := c.Query(key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "%s must be an integer", key)})
+			return
+		}
+		*field = n
+	}
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	forecast, err := h.svc.Forecast(c.Request.Context(), settings)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, forecast)
+}
+
+// ListReorderAlerts returns recent reorder alerts; ?open=true keeps only the
+// SKUs still at their reorder point.
+func (h *InventoryHandler) ListReorderAlerts(c *gin.Context) {
+	limit := defaultAlertsLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		limit = min(n, maxAlertsLimit)
+	}
+
+	alerts, err := h.svc.Alerts(c.Request.Context(), c.Query("open") == "true", limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(alerts))
+	for _, a := range alerts {
+		out = append(out, gin.H{
+			"sku":            a.SKU,
+			"title":          a.Title,
+			"stock":          a.Stock,
+			"daily_velocity": a.DailyVelocity,
+			"reorder_point":  a.ReorderPoint,
+			"reorder_qty":    a.ReorderQty,
+			"created_at":     a.CreatedAt,
+			"resolved_at":    a.ResolvedAt,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
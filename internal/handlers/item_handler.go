@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/service"
+)
+
+// itemFields are the fields ?fields= accepts on /api/items/:id.
+var itemFields = jsonFields(reflect.TypeOf(service.ItemDetail{}))
+
+type ItemHandler struct {
+	svc *service.ItemService
+}
+
+func NewItemHandler(svc *service.ItemService) *ItemHandler {
+	return &ItemHandler{svc: svc}
+}
+
+// GetItem returns a listing with the buy box of its catalog product.
+// ?fields= trims the response to the listed fields; leaving out buy_box
+// skips its lookup.
+func (h *ItemHandler) GetItem(c *gin.Context) {
+	fields, ok := requestedFields(c, itemFields)
+	if !ok {
+		return
+	}
+
+	item, err := h.svc.Item(c.Request.Context(), c.Param("id"), fields.has("buy_box"))
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+
+	respondWithFields(c, item, fields)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/service"
+	"melibot/pkg/meli"
+	"melibot/pkg/meli/apitest"
+)
+
+func newItemRouter(t *testing.T) (*apitest.Server, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	api := apitest.NewServer()
+	t.Cleanup(api.Close)
+
+	client := meli.NewMeliClient("test-token", "test-app", meli.WithBaseURL(api.URL))
+	h := NewItemHandler(service.NewItemService(client))
+	r := gin.New()
+	r.GET("/api/items/:id", h.GetItem)
+	return api, r
+}
+
+func TestGetItem(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		fail       int
+		wantStatus int
+		wantKeys   []string
+		absentKeys []string
+		wantCalls  int
+	}{
+		{
+			name:       "with buy box",
+			path:       "/api/items/MLB3456789012",
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"id", "title", "price", "buy_box"},
+			// The item, its product's offers and the multiget of the winner
+			wantCalls: 3,
+		},
+		{
+			name:       "fields without buy box",
+			path:       "/api/items/MLB3456789012?fields=title,price",
+			wantStatus: http.StatusOK,
+			wantKeys:   []string{"id", "title", "price"},
+			absentKeys: []string{"buy_box", "permalink"},
+			wantCalls:  1,
+		},
+		{
+			name:       "unknown field",
+			path:       "/api/items/MLB3456789012?fields=nope",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown item",
+			path:       "/api/items/MLB1",
+			wantStatus: http.StatusNotFound,
+			wantCalls:  1,
+		},
+		{
+			name:       "upstream failure",
+			path:       "/api/items/MLB3456789012?fields=title",
+			fail:       http.StatusInternalServerError,
+			wantStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, r := newItemRouter(t)
+			if tt.fail != 0 {
+				// Enough failures to outlast the client's retries
+				api.FailNext(http.MethodGet, "/items/MLB3456789012", tt.fail, 10)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			for _, k := range tt.wantKeys {
+				if _, ok := body[k]; !ok {
+					t.Errorf("missing %q in %s", k, w.Body)
+				}
+			}
+			for _, k := range tt.absentKeys {
+				if _, ok := body[k]; ok {
+					t.Errorf("unexpected %q in %s", k, w.Body)
+				}
+			}
+			if tt.wantStatus != http.StatusOK {
+				if _, ok := body["error"]; !ok {
+					t.Errorf("missing error in %s", w.Body)
+				}
+			}
+			if tt.wantCalls > 0 {
+				if n := len(api.Requests()); n != tt.wantCalls {
+					t.Errorf("sent %d requests to Mercado Livre, want %d", n, tt.wantCalls)
+				}
+			}
+		})
+	}
+}
+
+func TestGetItemBuyBox(t *testing.T) {
+	_, r := newItemRouter(t)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/items/MLB3456789012", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var item service.ItemDetail
+	if err := json.Unmarshal(w.Body.Bytes(), &item); err != nil {
+		t.Fatal(err)
+	}
+	// The item is the cheapest offer of its catalog product
+	want := service.BuyBox{ItemID: "MLB3456789012", Price: 1499.9, Winning: true}
+	if item.BuyBox == nil || *item.BuyBox != want {
+		t.Errorf("buy_box = %+v, want %+v", item.BuyBox, want)
+	}
+	if len(item.Errors) != 0 {
+		t.Errorf("errors = %v", item.Errors)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/jobs"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+)
+
+type JobHandler struct {
+	queue *jobs.Queue
+}
+
+func NewJobHandler(queue *jobs.Queue) *JobHandler {
+	return &JobHandler{queue: queue}
+}
+
+// GetJob returns the status (and result, once finished) of a background job.
+// Lookups run with the request context, so in multi-tenant mode jobs of
+// other organizations are not found.
+func (h *JobHandler) GetJob(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "invalid job id")})
+		return
+	}
+
+	job, err := h.queue.Get(ctx, uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Tr(c, "job not found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, jobResponse(job))
+}
+
+// EnqueueTopTrends is the async variant of GET /api/trends.
+func (h *JobHandler) EnqueueTopTrends(c *gin.Context) {
+	categoryID := c.Query("category_id")
+	if categoryID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "category_id is required")})
+		return
+	}
+	enqueueJob(c, h.queue, jobs.TypeTopTrends, jobs.TopTrendsPayload{CategoryID: categoryID, Limit: 10})
+}
+
+// EnqueueCatalogEligibility is the async variant of the catalog eligibility report.
+func (h *JobHandler) EnqueueCatalogEligibility(c *gin.Context) {
+	enqueueJob(c, h.queue, jobs.TypeCatalogEligibility, struct{}{})
+}
+
+// EnqueueCategoryAudit is the async variant of the category audit of my
+// listings.
+func (h *JobHandler) EnqueueCategoryAudit(c *gin.Context) {
+	minConfidence, ok := auditConfidence(c)
+	if !ok {
+		return
+	}
+	enqueueJob(c, h.queue, jobs.TypeCategoryAudit, jobs.CategoryAuditPayload{MinConfidence: minConfidence})
+}
+
+// EnqueueCategoryCrawl starts a full crawl of a category; its stats become
+// available at /api/categories/:id/stats once the job succeeds.
+func (h *JobHandler) EnqueueCategoryCrawl(c *gin.Context) {
+	enqueueJob(c, h.queue, jobs.TypeCategoryCrawl, jobs.CategoryCrawlPayload{CategoryID: c.Param("id")})
+}
+
+// EnqueueSupplierScreening accepts a supplier catalog CSV (title, cost, EAN),
+// as a multipart "file" field or as the raw request body, and starts a job
+// that ranks which products are worth listing.
+func (h *JobHandler) EnqueueSupplierScreening(c *gin.Context) {
+	var (
+		body     io.Reader = c.Request.Body
+		filename string
+	)
+	if file, header, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		body, filename = file, header.Filename
+	}
+
+	rows, err := service.ParseSupplierCSV(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	enqueueJob(c, h.queue, jobs.TypeSupplierScreening, jobs.SupplierScreeningPayload{Filename: filename, Rows: rows})
+}
+
+// GetScreeningReport downloads the result of a supplier screening job as CSV.
+func (h *JobHandler) GetScreeningReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "invalid job id")})
+		return
+	}
+
+	job, err := h.queue.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if job == nil || job.Type != jobs.TypeSupplierScreening {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Tr(c, "screening job not found")})
+		return
+	}
+	if job.Status != repository.JobStatusSucceeded {
+		c.JSON(http.StatusConflict, jobResponse(job))
+		return
+	}
+
+	var report service.ScreeningReport
+	if err := json.Unmarshal([]byte(job.Result), &report); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="supplier-screening-%d.csv"`, job.ID))
+	c.Status(http.StatusOK)
+	if err := report.WriteCSV(c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// enqueueJob enqueues a job and answers 202 with where to poll its status.
+func enqueueJob(c *gin.Context, queue *jobs.Queue, jobType string, payload interface{}) {
+	job, err := queue.Enqueue(c.Request.Context(), jobType, payload)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	statusURL := fmt.Sprintf("/api/jobs/%d", job.ID)
+	c.Header("Location", statusURL)
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"status_url": statusURL,
+	})
+}
+
+func jobResponse(job *repository.Job) gin.H {
+	resp := gin.H{
+		"id":           job.ID,
+		"type":         job.Type,
+		"status":       job.Status,
+		"attempts":     job.Attempts,
+		"max_attempts": job.MaxAttempts,
+		"created_at":   job.CreatedAt,
+		"started_at":   job.StartedAt,
+		"finished_at":  job.FinishedAt,
+	}
+	if job.LastError != "" {
+		resp["error"] = job.LastError
+	}
+	if job.Result != "" {
+		resp["result"] = json.RawMessage(job.Result)
+	}
+	return resp
+}
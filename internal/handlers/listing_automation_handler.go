@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+)
+
+type ListingAutomationHandler struct {
+	svc *service.ListingAutomationService
+}
+
+func NewListingAutomationHandler(svc *service.ListingAutomationService) *ListingAutomationHandler {
+	return &ListingAutomationHandler{svc: svc}
+}
+
+type listingRuleRequest struct {
+	ItemID   string     `json:"item_id"`
+	Trigger  string     `json:"trigger"`
+	PauseAt  *time.Time `json:"pause_at"`
+	ResumeAt *time.Time `json:"resume_at"`
+	Enabled  *bool      `json:"enabled"`
+	Version  uint       `json:"version"`
+}
+
+// ListRules returns the pause/reactivate rules of my listings.
+func (h *ListingAutomationHandler) ListRules(c *gin.Context) {
+	rules, err := h.svc.Rules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(rules))
+	for i := range rules {
+		out = append(out, listingRuleResponse(&rules[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// CreateRule adds a pause/reactivate rule.
+func (h *ListingAutomationHandler) CreateRule(c *gin.Context) {
+	h.saveRule(c, 0, http.StatusCreated)
+}
+
+// PutRule replaces a pause/reactivate rule. An If-Match header or a
+// "version" in the body makes it fail with 409 when the rule changed since
+// that version.
+func (h *ListingAutomationHandler) PutRule(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	h.saveRule(c, id, http.StatusOK)
+}
+
+func (h *ListingAutomationHandler) saveRule(c *gin.Context, id uint, status int) {
+	var req listingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	r := &repository.ListingRule{
+		ID:       id,
+		ItemID:   req.ItemID,
+		Trigger:  req.Trigger,
+		PauseAt:  req.PauseAt,
+		ResumeAt: req.ResumeAt,
+		Enabled:  req.Enabled == nil || *req.Enabled,
+	}
+	if err := service.ValidateListingRule(r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	var ok bool
+	if r.Version, ok = ifMatchVersion(c, req.Version); !ok {
+		return
+	}
+
+	err := h.svc.SaveRule(c.Request.Context(), r)
+	if errors.Is(err, service.ErrListingRuleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if errors.Is(err, service.ErrVersionConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	setVersionETag(c, r.Version)
+	c.JSON(status, listingRuleResponse(r))
+}
+
+// DeleteRule moves a pause/reactivate rule to the trash.
+func (h *ListingAutomationHandler) DeleteRule(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	err := h.svc.DeleteRule(c.Request.Context(), id)
+	if errors.Is(err, service.ErrListingRuleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListTrashedRules returns the deleted pause/reactivate rules, last
+// deleted first.
+func (h *ListingAutomationHandler) ListTrashedRules(c *gin.Context) {
+	rules, err := h.svc.TrashedRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(rules))
+	for i := range rules {
+		out = append(out, listingRuleResponse(&rules[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// RestoreRule takes a pause/reactivate rule out of the trash.
+func (h *ListingAutomationHandler) RestoreRule(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	rule, err := h.svc.RestoreRule(c.Request.Context(), id)
+	if errors.Is(err, service.ErrListingRuleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, listingRuleResponse(rule))
+}
+
+// ListActions returns the latest pauses and reactivations made by the
+// rules, of one listing with ?item_id=.
+func (h *ListingAutomationHandler) ListActions(c *gin.Context) {
+	actions, err := h.svc.Actions(c.Request.Context(), c.Query("item_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(actions))
+	for _, a := range actions {
+		out = append(out, gin.H{
+			"id":         a.ID,
+			"rule_id":    a.RuleID,
+			"item_id":    a.ItemID,
+			"action":     a.Action,
+			"reason":     a.Reason,
+			"error":      a.Error,
+			"created_at": a.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Evaluate applies the rules right away instead of waiting for the
+// scheduler.
+func (h *ListingAutomationHandler) Evaluate(c *gin.Context) {
+	report, err := h.svc.Evaluate(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func listingRuleResponse(r *repository.ListingRule) gin.H {
+	resp := gin.H{
+		"id":         r.ID,
+		"item_id":    r.ItemID,
+		"trigger":    r.Trigger,
+		"pause_at":   r.PauseAt,
+		"resume_at":  r.ResumeAt,
+		"enabled":    r.Enabled,
+		"holding":    r.Holding,
+		"updated_at": r.UpdatedAt,
+		"version":    r.Version,
+	}
+	if r.DeletedAt.Valid {
+		resp["deleted_at"] = r.DeletedAt.Time
+	}
+	return resp
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+	"melibot/pkg/meli"
+)
+
+type ListingHandler struct {
+	svc *service.ListingService
+}
+
+func NewListingHandler(svc *service.ListingService) *ListingHandler {
+	return &ListingHandler{svc: svc}
+}
+
+// ValidateItem runs a listing draft, in the body of POST /items, through
+// the preflight checks and lists its problems without publishing it.
+func (h *ListingHandler) ValidateItem(c *gin.Context) {
+	var draft meli.NewItem
+	if err := c.ShouldBindJSON(&draft); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.svc.Validate(c.Request.Context(), draft)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ListTemplates returns the listing templates.
+func (h *ListingHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.svc.Templates(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// CreateTemplate adds a listing template.
+func (h *ListingHandler) CreateTemplate(c *gin.Context) {
+	h.saveTemplate(c, 0, http.StatusCreated)
+}
+
+// PutTemplate replaces a listing template. An If-Match header or a
+// "version" in the body makes it fail with 409 when the template changed
+// since that version.
+func (h *ListingHandler) PutTemplate(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	h.saveTemplate(c, id, http.StatusOK)
+}
+
+func (h *ListingHandler) saveTemplate(c *gin.Context, id uint, status int) {
+	var t service.ListingTemplate
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	t.ID = id
+	if err := service.ValidateListingTemplate(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	var ok bool
+	if t.Version, ok = ifMatchVersion(c, t.Version); !ok {
+		return
+	}
+
+	err := h.svc.SaveTemplate(c.Request.Context(), &t)
+	if errors.Is(err, service.ErrListingTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if errors.Is(err, service.ErrVersionConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	setVersionETag(c, t.Version)
+	c.JSON(status, t)
+}
+
+// DeleteTemplate moves a listing template to the trash.
+func (h *ListingHandler) DeleteTemplate(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	err := h.svc.DeleteTemplate(c.Request.Context(), id)
+	if errors.Is(err, service.ErrListingTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListTrashedTemplates returns the deleted listing templates, last deleted
+// first.
+func (h *ListingHandler) ListTrashedTemplates(c *gin.Context) {
+	templates, err := h.svc.TrashedTemplates(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// RestoreTemplate takes a listing template out of the trash.
+func (h *ListingHandler) RestoreTemplate(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	t, err := h.svc.RestoreTemplate(c.Request.Context(), id)
+	if errors.Is(err, service.ErrListingTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+// CreateFromTemplate publishes a listing from {"template_id": ...} and the
+// fields that set it apart from the template. With ?dry_run=true it only
+// returns the merged draft and its validation.
+func (h *ListingHandler) CreateFromTemplate(c *gin.Context) {
+	var req struct {
+		TemplateID uint `json:"template_id"`
+		service.ListingOverrides
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if req.TemplateID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "template_id is required")})
+		return
+	}
+	ctx := c.Request.Context()
+
+	if c.Query("dry_run") == "true" {
+		draft, err := h.svc.DraftFromTemplate(ctx, req.TemplateID, req.ListingOverrides)
+		if errors.Is(err, service.ErrListingTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		validation, err := h.svc.Validate(ctx, *draft)
+		if err != nil {
+			respondUpstreamError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"draft": draft, "validation": validation})
+		return
+	}
+
+	item, validation, err := h.svc.PublishFromTemplate(ctx, req.TemplateID, req.ListingOverrides)
+	switch {
+	case errors.Is(err, service.ErrListingTemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+	case errors.Is(err, service.ErrListingInvalid):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": i18n.TrError(c, err), "validation": validation})
+	case err != nil:
+		respondUpstreamError(c, err)
+	default:
+		c.JSON(http.StatusCreated, gin.H{"item": item, "validation": validation})
+	}
+}
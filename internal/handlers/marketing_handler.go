@@ -1,19 +1,33 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"melibot/internal/i18n"
 	"melibot/internal/service"
+	"melibot/pkg/meli"
+)
+
+var (
+	errInvalidBudget    = errors.New("budget must be a positive duration, e.g. 5s")
+	errInvalidMinHealth = errors.New("min_health must be between 0 and 1")
 )
 
 type MarketingHandler struct {
-	svc *service.MarketingService
+	svc     *service.MarketingService
+	scoring *service.ScoringService
 }
 
-func NewMarketingHandler(svc *service.MarketingService) *MarketingHandler {
-	return &MarketingHandler{svc: svc}
+func NewMarketingHandler(svc *service.MarketingService, scoring *service.ScoringService) *MarketingHandler {
+	return &MarketingHandler{svc: svc, scoring: scoring}
 }
 
 // RegisterRoutes wires marketing-related routes into the given router group.
@@ -23,6 +37,7 @@ func (h *MarketingHandler) RegisterRoutes(r *gin.Engine) {
 		api.GET("/categories", h.GetCategories)
 		api.GET("/trends", h.GetTopTrends)
 		api.GET("/category_suggest", h.SuggestCategory)
+		api.POST("/category_suggest/batch", h.SuggestCategoryBatch)
 	}
 }
 
@@ -32,43 +47,156 @@ func (h *MarketingHandler) GetCategories(c *gin.Context) {
 
 	cats, err := h.svc.RootCategories(ctx)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		respondUpstreamError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, cats)
+	respondWithETag(c, cats)
 }
 
 // GetTopTrends returns the top sold products for a given category.
+// ?fields= trims the items to the listed fields; leaving out price and
+// link_venda also skips the best-price lookup of each catalog product.
+// ?min_health= drops the items whose health score is lower or unknown.
 func (h *MarketingHandler) GetTopTrends(c *gin.Context) {
 	ctx := c.Request.Context()
 	categoryID := c.Query("category_id")
 	if categoryID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "category_id is required"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "category_id is required")})
 		return
 	}
 
-	items, err := h.svc.TopTrendsByCategory(ctx, categoryID, 10)
+	budget, err := trendsBudget(c.Query("budget"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	fields, ok := requestedFields(c, trendFields)
+	if !ok {
+		return
+	}
+	minHealth, err := trendsMinHealth(c.Query("min_health"))
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	fetch := h.svc.TopTrendsByCategory
+	if productID := c.Query("debug_product"); productID != "" {
+		// Traced requests skip the cache so the trace reflects a real fetch.
+		ctx = meli.WithProductTrace(ctx, productID)
+		fetch = h.svc.RefreshTopTrends
+	} else if !fields.has("price") && !fields.has("link_venda") && c.Query("profile") == "" {
+		// Scoring profiles may weigh the price, so they keep the lookup.
+		fetch = h.svc.TopTrendsWithoutPrices
+	}
+
+	items, err := fetch(ctx, categoryID, 10)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	if minHealth > 0 {
+		items = items.WithMinHealth(minHealth)
+	}
+
+	// ?profile= ranks the items by a scoring profile instead of sales rank.
+	if profile := c.Query("profile"); profile != "" {
+		scored, err := h.scoring.Rank(context.WithoutCancel(ctx), profile, categoryID, items)
+		if errors.Is(err, service.ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		respondWithFields(c, scored, fields)
+		return
+	}
+
+	respondWithFields(c, items, fields)
+}
+
+// trendFields are the item fields ?fields= accepts on /api/trends.
+var trendFields = append(jsonFields(reflect.TypeOf(meli.SearchItem{})), "score", "score_breakdown")
+
+const (
+	defaultTrendsBudget = 20 * time.Second
+	maxTrendsBudget     = 60 * time.Second
+)
+
+// trendsBudget returns the time /api/trends may spend fetching before it
+// answers with partial results: the ?budget= value, TRENDS_BUDGET, or the
+// default, capped at maxTrendsBudget.
+func trendsBudget(query string) (time.Duration, error) {
+	budget := defaultTrendsBudget
+	if v := os.Getenv("TRENDS_BUDGET"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			budget = d
+		}
+	}
+	if query != "" {
+		d, err := time.ParseDuration(query)
+		if err != nil || d <= 0 {
+			return 0, errInvalidBudget
+		}
+		budget = d
+	}
+	return min(budget, maxTrendsBudget), nil
+}
+
+// trendsMinHealth reads ?min_health=, a 0-1 health score. Zero keeps every
+// item, those without a health score included.
+func trendsMinHealth(query string) (float64, error) {
+	if query == "" {
+		return 0, nil
+	}
+	h, err := strconv.ParseFloat(query, 64)
+	if err != nil || h < 0 || h > 1 {
+		return 0, errInvalidMinHealth
+	}
+	return h, nil
+}
+
+// maxSuggestBatch is the most titles a batch suggestion accepts.
+const maxSuggestBatch = 50
+
+type suggestBatchRequest struct {
+	Titles []string `json:"titles"`
+}
+
+// SuggestCategoryBatch runs the category predictor over a list of titles,
+// e.g. pasted from a supplier price list.
+func (h *MarketingHandler) SuggestCategoryBatch(c *gin.Context) {
+	var req suggestBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if len(req.Titles) == 0 || len(req.Titles) > maxSuggestBatch {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "titles must have between 1 and %d entries", maxSuggestBatch)})
 		return
 	}
 
-	c.JSON(http.StatusOK, items)
+	c.JSON(http.StatusOK, gin.H{"results": h.svc.SuggestCategoriesBatch(c.Request.Context(), req.Titles)})
 }
 
-// SuggestCategory uses the category predictor to suggest categories from free text.
+// SuggestCategory uses the category predictor to suggest categories from free
+// text, each with its path and what listing in it requires.
 func (h *MarketingHandler) SuggestCategory(c *gin.Context) {
 	ctx := c.Request.Context()
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "q is required"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "q is required")})
 		return
 	}
 
 	preds, err := h.svc.SuggestCategories(ctx, query)
 	if err != nil {
-		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		respondUpstreamError(c, err)
 		return
 	}
 
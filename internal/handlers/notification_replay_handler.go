@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/service"
+)
+
+type NotificationReplayHandler struct {
+	svc *service.NotificationReplayService
+}
+
+func NewNotificationReplayHandler(svc *service.NotificationReplayService) *NotificationReplayHandler {
+	return &NotificationReplayHandler{svc: svc}
+}
+
+// ListFailed lists the Mercado Livre notifications whose processing failed
+// and that no retry has resolved yet.
+func (h *NotificationReplayHandler) ListFailed(c *gin.Context) {
+	failed, err := h.svc.Failed(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notifications": failed, "count": len(failed)})
+}
+
+// Replay processes failed notifications again right away: those listed in
+// {"ids": [...]}, or every pending one without a body.
+func (h *NotificationReplayHandler) Replay(c *gin.Context) {
+	var req struct {
+		IDs []uint `json:"ids"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	report, err := h.svc.Replay(c.Request.Context(), req.IDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
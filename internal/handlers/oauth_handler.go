@@ -5,23 +5,25 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"sync"
 
 	"github.com/gin-gonic/gin"
 
-	"melibot/internal/api"
+	"melibot/internal/i18n"
+	"melibot/pkg/meli"
 )
 
 var (
 	// Global token storage (in production, use Redis or database)
-	currentToken string
-	tokenMutex   sync.RWMutex
-	oauthClient  *api.OAuthClient
+	tokens      = meli.NewTokenManager(nil)
+	oauthClient *meli.OAuthClient
+	// stateCallback finishes logins started elsewhere with a state, see
+	// HandleStateCallbacks
+	stateCallback func(c *gin.Context) bool
 )
 
 // InitializeOAuth configures OAuth client with credentials from environment
 // This should be called AFTER godotenv.Load() in main.go
-func InitializeOAuth() {
+func InitializeOAuth(opts ...meli.OAuthOption) {
 	clientID := os.Getenv("ML_CLIENT_ID")
 	clientSecret := os.Getenv("ML_CLIENT_SECRET")
 	redirectURI := os.Getenv("ML_REDIRECT_URI")
@@ -31,22 +33,36 @@ func InitializeOAuth() {
 		return
 	}
 
-	oauthClient = api.NewOAuthClient(clientID, clientSecret, redirectURI)
+	oauthClient = meli.NewOAuthClient(clientID, clientSecret, redirectURI, opts...)
+	tokens = meli.NewTokenManager(oauthClient)
 	log.Printf("[INFO] OAuth initialized successfully with client_id: %s", clientID)
 }
 
 // GetCurrentToken returns the current access token (thread-safe)
 func GetCurrentToken() string {
-	tokenMutex.RLock()
-	defer tokenMutex.RUnlock()
-	return currentToken
+	return tokens.AccessToken()
 }
 
 // SetCurrentToken sets the current access token (thread-safe)
 func SetCurrentToken(token string) {
-	tokenMutex.Lock()
-	defer tokenMutex.Unlock()
-	currentToken = token
+	tokens.Set(token, "")
+}
+
+// Tokens returns the token manager, for meli.WithTokenRefresher.
+func Tokens() *meli.TokenManager {
+	return tokens
+}
+
+// OAuthClient returns the OAuth client, nil when OAuth is not configured.
+func OAuthClient() *meli.OAuthClient {
+	return oauthClient
+}
+
+// HandleStateCallbacks lets fn handle OAuth callbacks carrying a state
+// first, e.g. the logins of organizations in multi-tenant mode. fn reports
+// whether it handled the callback.
+func HandleStateCallbacks(fn func(c *gin.Context) bool) {
+	stateCallback = fn
 }
 
 // GetTokenFromContext tries to get the access token from:
@@ -56,16 +72,17 @@ func SetCurrentToken(token string) {
 func GetTokenFromContext(c *gin.Context) string {
 	// Try to get from memory first
 	if token := GetCurrentToken(); token != "" {
-		log.Printf("[DEBUG] Token found in MEMORY: first 20 chars: %s...", token[:20])
+		log.Println("[DEBUG] Token found in MEMORY")
 		return token
 	}
 	log.Println("[DEBUG] Token NOT in memory, checking cookie...")
 
 	// Try to get from cookie
 	if cookie, err := c.Cookie("ml_access_token"); err == nil && cookie != "" {
-		log.Printf("[DEBUG] Token found in COOKIE: first 20 chars: %s...", cookie[:20])
+		log.Println("[DEBUG] Token found in COOKIE")
 		// Update in-memory token for future requests
-		SetCurrentToken(cookie)
+		refresh, _ := c.Cookie("ml_refresh_token")
+		tokens.Set(cookie, refresh)
 		return cookie
 	}
 	log.Println("[DEBUG] Token NOT in cookie, using .env fallback")
@@ -73,7 +90,7 @@ func GetTokenFromContext(c *gin.Context) string {
 	// Fallback to environment variable
 	envToken := os.Getenv("ML_ACCESS_TOKEN")
 	if envToken != "" {
-		log.Printf("[DEBUG] Using .env token: first 20 chars: %s...", envToken[:20])
+		log.Println("[DEBUG] Using .env token")
 	}
 	return envToken
 }
@@ -109,10 +126,14 @@ func HandleLogin(c *gin.Context) {
 func HandleCallback(c *gin.Context) {
 	log.Println("[DEBUG] HandleCallback called!")
 
+	if stateCallback != nil && c.Query("state") != "" && stateCallback(c) {
+		return
+	}
+
 	if oauthClient == nil {
 		log.Println("[ERROR] oauthClient is nil!")
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "OAuth not configured",
+			"error": i18n.Tr(c, "OAuth not configured"),
 		})
 		return
 	}
@@ -123,7 +144,7 @@ func HandleCallback(c *gin.Context) {
 		errorParam := c.Query("error")
 		errorDesc := c.Query("error_description")
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error":             "Authorization failed",
+			"error":             i18n.Tr(c, "Authorization failed"),
 			"error_code":        errorParam,
 			"error_description": errorDesc,
 		})
@@ -134,18 +155,21 @@ func HandleCallback(c *gin.Context) {
 	tokenResp, err := oauthClient.ExchangeCodeForToken(ctx, code)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to exchange code for token: " + err.Error(),
+			"error": i18n.Tr(c, "Failed to exchange code for token: %s", err.Error()),
 		})
 		return
 	}
 
-	// Store the access token in memory
-	SetCurrentToken(tokenResp.AccessToken)
+	// Store the tokens in memory
+	tokens.Set(tokenResp.AccessToken, tokenResp.RefreshToken)
 
-	// Also store the token in an HTTP-only secure cookie for persistence
+	// Also store the token in an HTTP-only cookie for persistence, marked
+	// Secure when served over HTTPS
 	// maxAge: 86400 = 1 day (adjust as needed for your token expiration)
-	c.SetCookie("ml_access_token", tokenResp.AccessToken, 86400, "/", "", false, true)
-	c.SetCookie("ml_user_id", fmt.Sprintf("%d", tokenResp.UserID), 86400, "/", "", false, true)
+	secure := c.Request.TLS != nil
+	c.SetCookie("ml_access_token", tokenResp.AccessToken, 86400, "/", "", secure, true)
+	c.SetCookie("ml_refresh_token", tokenResp.RefreshToken, 86400*180, "/", "", secure, true)
+	c.SetCookie("ml_user_id", fmt.Sprintf("%d", tokenResp.UserID), 86400, "/", "", secure, true)
 
 	// Redirect to dashboard with success message
 	c.Redirect(http.StatusFound, "/?auth=success&user_id="+fmt.Sprintf("%d", tokenResp.UserID))
@@ -157,28 +181,30 @@ func HandleAuthStatus(c *gin.Context) {
 	if token == "" {
 		c.JSON(http.StatusOK, gin.H{
 			"authenticated": false,
-			"message":       "Not authenticated. Visit /auth/login to authenticate",
+			"message":       i18n.Tr(c, "Not authenticated. Visit /auth/login to authenticate"),
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"authenticated": true,
-		"message":       "Authenticated successfully",
+		"message":       i18n.Tr(c, "Authenticated successfully"),
 	})
 }
 
 // HandleLogout clears the authentication tokens
 func HandleLogout(c *gin.Context) {
-	// Clear in-memory token
-	SetCurrentToken("")
+	// Clear in-memory tokens
+	tokens.Clear()
 
 	// Clear cookies
-	c.SetCookie("ml_access_token", "", -1, "/", "", false, true)
-	c.SetCookie("ml_user_id", "", -1, "/", "", false, true)
+	secure := c.Request.TLS != nil
+	c.SetCookie("ml_access_token", "", -1, "/", "", secure, true)
+	c.SetCookie("ml_refresh_token", "", -1, "/", "", secure, true)
+	c.SetCookie("ml_user_id", "", -1, "/", "", secure, true)
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Logged out successfully",
+		"message": i18n.Tr(c, "Logged out successfully"),
 	})
 }
 
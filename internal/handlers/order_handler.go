@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+const defaultSalesRange = 30 * 24 * time.Hour
+
+type OrderHandler struct {
+	svc *service.OrderService
+}
+
+func NewOrderHandler(svc *service.OrderService) *OrderHandler {
+	return &OrderHandler{svc: svc}
+}
+
+// SyncOrders pulls the orders updated since the last sync.
+func (h *OrderHandler) SyncOrders(c *gin.Context) {
+	n, err := h.svc.SyncOrders(c.Request.Context())
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"synced": n})
+}
+
+// GetOrderHistory returns a synced order with its status transitions.
+func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "invalid order id")})
+		return
+	}
+
+	order, changes, err := h.svc.OrderHistory(c.Request.Context(), id)
+	if errors.Is(err, service.ErrOrderNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	items := make([]gin.H, 0, len(order.Items))
+	for _, it := range order.Items {
+		items = append(items, gin.H{
+			"item_id":    it.ItemID,
+			"title":      it.Title,
+			"sku":        it.SKU,
+			"quantity":   it.Quantity,
+			"unit_price": it.UnitPrice,
+			"sale_fee":   it.SaleFee,
+		})
+	}
+	transitions := make([]gin.H, 0, len(changes))
+	for _, ch := range changes {
+		transitions = append(transitions, gin.H{"from": ch.FromStatus, "to": ch.ToStatus, "changed_at": ch.ChangedAt})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":             order.ID,
+		"status":         order.Status,
+		"date_created":   order.DateCreated,
+		"last_updated":   order.LastUpdated,
+		"total_amount":   order.TotalAmount,
+		"paid_amount":    order.PaidAmount,
+		"currency":       order.Currency,
+		"buyer_id":       order.BuyerID,
+		"buyer_nickname": order.BuyerNickname,
+		"shipping_id":    order.ShippingID,
+		"shipping_cost":  order.ShippingCost,
+		"items":          items,
+		"transitions":    transitions,
+	})
+}
+
+// GetSalesAnalytics returns revenue, units, AOV and top SKUs of my stored
+// orders, grouped by ?group_by=day|week over ?from=&to= (default: last 30 days).
+func (h *OrderHandler) GetSalesAnalytics(c *gin.Context) {
+	from, to, ok := dateRange(c, defaultSalesRange)
+	if !ok {
+		return
+	}
+
+	analytics, err := h.svc.SalesAnalytics(c.Request.Context(), c.DefaultQuery("group_by", "day"), from, to)
+	if errors.Is(err, service.ErrInvalidSalesGrouping) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, analytics)
+}
+
+// dateRange reads ?from= and ?to= as dates (2006-01-02, to inclusive) or
+// RFC3339 timestamps, answering 400 itself when they are invalid. The range
+// defaults to the last def.
+func dateRange(c *gin.Context, def time.Duration) (from, to time.Time, ok bool) {
+	to = time.Now()
+	if v := c.Query("to"); v != "" {
+		t, dateOnly, err := parseDateParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "to must be a date (YYYY-MM-DD) or RFC3339")})
+			return from, to, false
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = t
+	}
+	from = to.Add(-def)
+	if v := c.Query("from"); v != "" {
+		t, _, err := parseDateParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "from must be a date (YYYY-MM-DD) or RFC3339")})
+			return from, to, false
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "from must be before to")})
+		return from, to, false
+	}
+	return from, to, true
+}
+
+func parseDateParam(v string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, v, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, false, err
+}
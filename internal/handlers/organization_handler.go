@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+// OrganizationHandler manages the organizations of multi-tenant mode: the
+// operators' endpoints and each organization's own Mercado Livre login.
+type OrganizationHandler struct {
+	svc *service.OrganizationService
+}
+
+func NewOrganizationHandler(svc *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{svc: svc}
+}
+
+type organizationRequest struct {
+	Name string `json:"name"`
+}
+
+// ListOrganizations returns every organization.
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.svc.Organizations(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// CreateOrganization adds an organization. The response is the only one
+// that shows its API key.
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	org, err := h.svc.Create(c.Request.Context(), req.Name)
+	if errors.Is(err, service.ErrOrganizationName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, org)
+}
+
+// RotateKey gives an organization a new API key, shown in the response
+// only.
+func (h *OrganizationHandler) RotateKey(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	org, err := h.svc.RotateKey(c.Request.Context(), id)
+	if errors.Is(err, service.ErrOrganizationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// DeleteOrganization removes an organization; its data is kept.
+func (h *OrganizationHandler) DeleteOrganization(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	err := h.svc.Delete(c.Request.Context(), id)
+	if errors.Is(err, service.ErrOrganizationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetCurrent returns the organization of the request's API key.
+func (h *OrganizationHandler) GetCurrent(c *gin.Context) {
+	org, err := h.svc.Current(c.Request.Context())
+	if errors.Is(err, service.ErrOrganizationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, org)
+}
+
+// Login redirects to Mercado Livre to connect an account to the
+// organization of the request; the OAuth callback finishes it, see
+// Callback.
+func (h *OrganizationHandler) Login(c *gin.Context) {
+	authURL, err := h.svc.LoginURL(c.Request.Context())
+	if errors.Is(err, service.ErrOAuthNotConfigured) {
+		c.Redirect(http.StatusFound, "/oauth-help")
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback finishes a login started by Login. It reports whether the
+// callback's state belongs to one, leaving other callbacks to
+// HandleCallback.
+func (h *OrganizationHandler) Callback(c *gin.Context) bool {
+	state := c.Query("state")
+	if state == "" || !h.svc.PendingLogin(c.Request.Context(), state) {
+		return false
+	}
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             i18n.Tr(c, "Authorization failed"),
+			"error_code":        c.Query("error"),
+			"error_description": c.Query("error_description"),
+		})
+		return true
+	}
+
+	org, err := h.svc.CompleteLogin(c.Request.Context(), state, code)
+	if errors.Is(err, service.ErrOrgLoginExpired) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return true
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": i18n.Tr(c, "Failed to exchange code for token: %s", err.Error()),
+		})
+		return true
+	}
+	log.Printf("[INFO] Organization %d connected Mercado Livre account %d", org.ID, org.SellerID)
+	c.Redirect(http.StatusFound, fmt.Sprintf("/?auth=success&user_id=%d", org.SellerID))
+	return true
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type ProfitHandler struct {
+	svc *service.ProfitService
+}
+
+func NewProfitHandler(svc *service.ProfitService) *ProfitHandler {
+	return &ProfitHandler{svc: svc}
+}
+
+type productCostRequest struct {
+	UnitCost *float64 `json:"unit_cost"`
+	Currency string   `json:"currency"`
+}
+
+// ListCosts returns the recorded unit cost of each SKU.
+func (h *ProfitHandler) ListCosts(c *gin.Context) {
+	costs, err := h.svc.Costs(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(costs))
+	for _, cost := range costs {
+		out = append(out, gin.H{"sku": cost.SKU, "unit_cost": cost.UnitCost, "currency": cost.Currency, "updated_at": cost.UpdatedAt})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// PutCost records the unit cost of a SKU.
+func (h *ProfitHandler) PutCost(c *gin.Context) {
+	var req productCostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if req.UnitCost == nil || *req.UnitCost < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "unit_cost must be a non-negative number")})
+		return
+	}
+
+	cost, err := h.svc.SetCost(c.Request.Context(), c.Param("sku"), *req.UnitCost, req.Currency)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sku": cost.SKU, "unit_cost": cost.UnitCost, "currency": cost.Currency})
+}
+
+// DeleteCost forgets the unit cost of a SKU.
+func (h *ProfitHandler) DeleteCost(c *gin.Context) {
+	err := h.svc.DeleteCost(c.Request.Context(), c.Param("sku"))
+	if errors.Is(err, service.ErrCostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetProfit returns margins per SKU and per ?group_by=day|week|month period
+// over ?from=&to= (default: last 30 days).
+func (h *ProfitHandler) GetProfit(c *gin.Context) {
+	from, to, ok := dateRange(c, defaultSalesRange)
+	if !ok {
+		return
+	}
+
+	report, err := h.svc.ProfitReport(c.Request.Context(), c.DefaultQuery("group_by", "month"), from, to)
+	if errors.Is(err, service.ErrInvalidProfitGrouping) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+)
+
+type QuestionHandler struct {
+	svc *service.QuestionService
+}
+
+func NewQuestionHandler(svc *service.QuestionService) *QuestionHandler {
+	return &QuestionHandler{svc: svc}
+}
+
+type answerTemplateRequest struct {
+	Name       string `json:"name"`
+	MatchType  string `json:"match_type"`
+	Pattern    string `json:"pattern"`
+	Answer     string `json:"answer"`
+	ItemID     string `json:"item_id"`
+	CategoryID string `json:"category_id"`
+	Enabled    *bool  `json:"enabled"`
+	Version    uint   `json:"version"`
+}
+
+type autoResponderRequest struct {
+	Enabled       *bool    `json:"enabled"`
+	MinConfidence *float64 `json:"min_confidence"`
+}
+
+type approveRequest struct {
+	Text string `json:"text"`
+}
+
+// ListTemplates returns the answer templates.
+func (h *QuestionHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.svc.Templates(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(templates))
+	for i := range templates {
+		out = append(out, answerTemplateResponse(&templates[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// CreateTemplate adds an answer template.
+func (h *QuestionHandler) CreateTemplate(c *gin.Context) {
+	h.saveTemplate(c, 0, http.StatusCreated)
+}
+
+// PutTemplate replaces an answer template. An If-Match header or a
+// "version" in the body makes it fail with 409 when the template changed
+// since that version.
+func (h *QuestionHandler) PutTemplate(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	h.saveTemplate(c, id, http.StatusOK)
+}
+
+func (h *QuestionHandler) saveTemplate(c *gin.Context, id uint, status int) {
+	var req answerTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	t := &repository.AnswerTemplate{
+		ID:         id,
+		Name:       req.Name,
+		MatchType:  req.MatchType,
+		Pattern:    req.Pattern,
+		Answer:     req.Answer,
+		ItemID:     req.ItemID,
+		CategoryID: req.CategoryID,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+	}
+	if err := service.ValidateTemplate(t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	var ok bool
+	if t.Version, ok = ifMatchVersion(c, req.Version); !ok {
+		return
+	}
+
+	err := h.svc.SaveTemplate(c.Request.Context(), t)
+	if errors.Is(err, service.ErrTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if errors.Is(err, service.ErrVersionConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	setVersionETag(c, t.Version)
+	c.JSON(status, answerTemplateResponse(t))
+}
+
+// DeleteTemplate moves an answer template to the trash.
+func (h *QuestionHandler) DeleteTemplate(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	err := h.svc.DeleteTemplate(c.Request.Context(), id)
+	if errors.Is(err, service.ErrTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListTrashedTemplates returns the deleted answer templates, last deleted
+// first.
+func (h *QuestionHandler) ListTrashedTemplates(c *gin.Context) {
+	templates, err := h.svc.TrashedTemplates(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(templates))
+	for i := range templates {
+		out = append(out, answerTemplateResponse(&templates[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// RestoreTemplate takes an answer template out of the trash.
+func (h *QuestionHandler) RestoreTemplate(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	t, err := h.svc.RestoreTemplate(c.Request.Context(), id)
+	if errors.Is(err, service.ErrTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, answerTemplateResponse(t))
+}
+
+// GetAutoResponder returns whether the auto-responder is on, its confidence
+// threshold and the opted-out listings.
+func (h *QuestionHandler) GetAutoResponder(c *gin.Context) {
+	status, err := h.svc.Status(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// PutAutoResponder opts in to (or out of) automatic answers.
+func (h *QuestionHandler) PutAutoResponder(c *gin.Context) {
+	var req autoResponderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if req.Enabled == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "enabled is required")})
+		return
+	}
+	if req.MinConfidence != nil && (*req.MinConfidence <= 0 || *req.MinConfidence > 1) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "min_confidence must be in (0, 1]")})
+		return
+	}
+
+	status, err := h.svc.Configure(c.Request.Context(), *req.Enabled, req.MinConfidence)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// OptOutItem stops automatic answers on one listing.
+func (h *QuestionHandler) OptOutItem(c *gin.Context) {
+	if err := h.svc.OptOut(c.Request.Context(), c.Param("item_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// OptInItem resumes automatic answers on one listing.
+func (h *QuestionHandler) OptInItem(c *gin.Context) {
+	if err := h.svc.OptIn(c.Request.Context(), c.Param("item_id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListReviewQueue returns low-confidence matches waiting for approval.
+func (h *QuestionHandler) ListReviewQueue(c *gin.Context) {
+	matches, err := h.svc.ReviewQueue(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(matches))
+	for i := range matches {
+		out = append(out, questionMatchResponse(&matches[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// ApproveAnswer sends the suggested answer, or the edited {"text": ...}.
+func (h *QuestionHandler) ApproveAnswer(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	var req approveRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+			return
+		}
+	}
+
+	m, err := h.svc.Approve(c.Request.Context(), id, req.Text)
+	if h.reviewError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, questionMatchResponse(m))
+}
+
+// DismissAnswer drops a question from the review queue.
+func (h *QuestionHandler) DismissAnswer(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	if h.reviewError(c, h.svc.Dismiss(c.Request.Context(), id)) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// reviewError writes the response for a failed review action and reports
+// whether there was one.
+func (h *QuestionHandler) reviewError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, service.ErrMatchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+	case errors.Is(err, service.ErrMatchHandled):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.TrError(c, err)})
+	default:
+		respondUpstreamError(c, err)
+	}
+	return true
+}
+
+func uintParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "invalid %s", name)})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func answerTemplateResponse(t *repository.AnswerTemplate) gin.H {
+	resp := gin.H{
+		"id":          t.ID,
+		"name":        t.Name,
+		"match_type":  t.MatchType,
+		"pattern":     t.Pattern,
+		"answer":      t.Answer,
+		"item_id":     t.ItemID,
+		"category_id": t.CategoryID,
+		"enabled":     t.Enabled,
+		"updated_at":  t.UpdatedAt,
+		"version":     t.Version,
+	}
+	if t.DeletedAt.Valid {
+		resp["deleted_at"] = t.DeletedAt.Time
+	}
+	return resp
+}
+
+func questionMatchResponse(m *repository.QuestionMatch) gin.H {
+	return gin.H{
+		"id":               m.ID,
+		"question_id":      m.QuestionID,
+		"item_id":          m.ItemID,
+		"text":             m.Text,
+		"template_id":      m.TemplateID,
+		"suggested_answer": m.SuggestedAnswer,
+		"confidence":       m.Confidence,
+		"status":           m.Status,
+		"answer_text":      m.AnswerText,
+		"answered_at":      m.AnsweredAt,
+		"created_at":       m.CreatedAt,
+	}
+}
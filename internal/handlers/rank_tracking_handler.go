@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+)
+
+type RankTrackingHandler struct {
+	svc *service.RankTrackingService
+}
+
+func NewRankTrackingHandler(svc *service.RankTrackingService) *RankTrackingHandler {
+	return &RankTrackingHandler{svc: svc}
+}
+
+type rankTrackerRequest struct {
+	Keyword string `json:"keyword"`
+	ItemID  string `json:"item_id"`
+}
+
+// ListTrackers returns the tracked keywords with the last position of my
+// listing on each.
+func (h *RankTrackingHandler) ListTrackers(c *gin.Context) {
+	trackers, err := h.svc.Trackers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(trackers))
+	for i := range trackers {
+		out = append(out, rankTrackerResponse(&trackers[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// CreateTracker starts tracking one of my listings on a keyword and
+// returns its first position.
+func (h *RankTrackingHandler) CreateTracker(c *gin.Context) {
+	var req rankTrackerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err := service.ValidateRankTracker(req.Keyword, req.ItemID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	tracker, err := h.svc.Track(c.Request.Context(), req.Keyword, req.ItemID)
+	if errors.Is(err, service.ErrNotOwnItem) {
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, rankTrackerResponse(tracker))
+}
+
+// DeleteTracker stops tracking a keyword.
+func (h *RankTrackingHandler) DeleteTracker(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	err := h.svc.Untrack(c.Request.Context(), id)
+	if errors.Is(err, service.ErrRankTrackerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetHistory returns the positions of a tracked keyword over the last
+// ?days= (default 30).
+func (h *RankTrackingHandler) GetHistory(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	days, ok := historyDays(c)
+	if !ok {
+		return
+	}
+
+	tracker, snapshots, err := h.svc.History(c.Request.Context(), id, time.Now().AddDate(0, 0, -days))
+	if errors.Is(err, service.ErrRankTrackerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	history := make([]gin.H, 0, len(snapshots))
+	for _, s := range snapshots {
+		history = append(history, gin.H{
+			"position":   s.Position,
+			"page":       s.Page,
+			"total":      s.Total,
+			"dropped":    s.Dropped,
+			"checked_at": s.CheckedAt,
+		})
+	}
+	resp := rankTrackerResponse(tracker)
+	resp["history"] = history
+	c.JSON(http.StatusOK, resp)
+}
+
+// CheckNow checks every tracked keyword right away.
+func (h *RankTrackingHandler) CheckNow(c *gin.Context) {
+	if err := h.svc.CheckAll(c.Request.Context()); err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	h.ListTrackers(c)
+}
+
+func rankTrackerResponse(t *repository.RankTracker) gin.H {
+	return gin.H{
+		"id":              t.ID,
+		"keyword":         t.Keyword,
+		"item_id":         t.ItemID,
+		"position":        t.Position,
+		"page":            t.Page,
+		"last_checked_at": t.LastCheckedAt,
+		"created_at":      t.CreatedAt,
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+const (
+	defaultMoversWindow = "7d"
+	maxMoversWindow     = 90 * 24 * time.Hour
+
+	defaultMoversLimit = 20
+	maxMoversLimit     = 100
+)
+
+type ReportHandler struct {
+	svc *service.ReportService
+}
+
+func NewReportHandler(svc *service.ReportService) *ReportHandler {
+	return &ReportHandler{svc: svc}
+}
+
+// parseWindow reads a report window: a number of days ("7d"), of weeks
+// ("2w") or a Go duration ("36h").
+func parseWindow(v string) (time.Duration, bool) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(v, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(v, "w"):
+		unit = 7 * 24 * time.Hour
+	default:
+		d, err := time.ParseDuration(v)
+		return d, err == nil && d > 0
+	}
+	n, err := strconv.Atoi(v[:len(v)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// GetMovers returns the products that moved the most across every
+// snapshotted category in the last ?window= (default 7d, at most 90d):
+// rank gainers and losers, price drops and demand spikes, up to ?limit=
+// each. It reads stored snapshots only.
+func (h *ReportHandler) GetMovers(c *gin.Context) {
+	name := c.DefaultQuery("window", defaultMoversWindow)
+	window, ok := parseWindow(name)
+	if !ok || window > maxMoversWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "window must be a duration such as 7d, 2w or 36h, up to 90d")})
+		return
+	}
+	limit := defaultMoversLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		limit = min(n, maxMoversLimit)
+	}
+
+	report, err := h.svc.Movers(c.Request.Context(), name, window, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondWithETag(c, report)
+}
+
+// HeatmapHandler serves the category heatmap, which needs a Mercado Livre
+// client to place categories in the category tree.
+type HeatmapHandler struct {
+	svc *service.HeatmapService
+}
+
+func NewHeatmapHandler(svc *service.HeatmapService) *HeatmapHandler {
+	return &HeatmapHandler{svc: svc}
+}
+
+// GetHeatmap returns the units sold and the demand and price change of every
+// level-2 category in the last ?window= (default 7d, at most 90d), nested
+// under their level-1 categories for a treemap.
+func (h *HeatmapHandler) GetHeatmap(c *gin.Context) {
+	name := c.DefaultQuery("window", defaultMoversWindow)
+	window, ok := parseWindow(name)
+	if !ok || window > maxMoversWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "window must be a duration such as 7d, 2w or 36h, up to 90d")})
+		return
+	}
+
+	report, err := h.svc.Heatmap(c.Request.Context(), name, window)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	respondWithETag(c, report)
+}
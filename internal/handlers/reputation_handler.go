@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type ReputationHandler struct {
+	svc *service.ReputationService
+}
+
+func NewReputationHandler(svc *service.ReputationService) *ReputationHandler {
+	return &ReputationHandler{svc: svc}
+}
+
+// GetStanding returns my claim, delayed dispatch and cancellation rates
+// against Mercado Livre's reputation levels, with the orders behind each.
+func (h *ReputationHandler) GetStanding(c *gin.Context) {
+	standing, err := h.svc.Standing(c.Request.Context())
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, standing)
+}
+
+// ListAlerts returns recent reputation alerts; ?open=true keeps only the
+// metrics still close to dropping a level.
+func (h *ReputationHandler) ListAlerts(c *gin.Context) {
+	limit := defaultAlertsLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		limit = min(n, maxAlertsLimit)
+	}
+
+	alerts, err := h.svc.Alerts(c.Request.Context(), c.Query("open") == "true", limit)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+
+	out := make([]gin.H, 0, len(alerts))
+	for _, a := range alerts {
+		out = append(out, gin.H{
+			"metric":      a.Metric,
+			"level":       a.Level,
+			"rate_pct":    a.Rate,
+			"limit_pct":   a.Limit,
+			"count":       a.Count,
+			"sales":       a.Sales,
+			"created_at":  a.CreatedAt,
+			"resolved_at": a.ResolvedAt,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+const defaultReturnsRange = 90 * 24 * time.Hour
+
+type ReturnsHandler struct {
+	svc *service.ReturnsService
+}
+
+func NewReturnsHandler(svc *service.ReturnsService) *ReturnsHandler {
+	return &ReturnsHandler{svc: svc}
+}
+
+// SyncClaims pulls the claims updated since the last sync.
+func (h *ReturnsHandler) SyncClaims(c *gin.Context) {
+	n, err := h.svc.SyncClaims(c.Request.Context())
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"synced": n})
+}
+
+// GetReturns returns the claim and return rates of my orders per SKU,
+// category and period, grouped by ?group_by=day|week|month over ?from=&to=
+// (default: last 90 days), flagging SKUs with abnormal claim rates.
+func (h *ReturnsHandler) GetReturns(c *gin.Context) {
+	from, to, ok := dateRange(c, defaultReturnsRange)
+	if !ok {
+		return
+	}
+
+	report, err := h.svc.ReturnsReport(c.Request.Context(), c.DefaultQuery("group_by", "week"), from, to)
+	if errors.Is(err, service.ErrInvalidReturnsGrouping) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+	"melibot/pkg/meli"
+)
+
+type SandboxHandler struct {
+	svc *service.SandboxService
+}
+
+func NewSandboxHandler(svc *service.SandboxService) *SandboxHandler {
+	return &SandboxHandler{svc: svc}
+}
+
+// CreateTestUser creates a new Mercado Livre test user.
+func (h *SandboxHandler) CreateTestUser(c *gin.Context) {
+	user, err := h.svc.CreateTestUser(c.Request.Context())
+	if errors.Is(err, service.ErrSandboxDisabled) {
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+
+	// The password is only shown here, to log in as the test user; it is
+	// left out of every other response
+	c.JSON(http.StatusCreated, createdTestUser{TestUser: user, Password: user.Password})
+}
+
+type createdTestUser struct {
+	*repository.TestUser
+	Password string `json:"password"`
+}
+
+// ListTestUsers lists the test users created so far, without passwords.
+func (h *SandboxHandler) ListTestUsers(c *gin.Context) {
+	users, err := h.svc.ListTestUsers(c.Request.Context())
+	if errors.Is(err, service.ErrSandboxDisabled) {
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// RequireTestAccount returns a middleware that rejects mutating requests
+// made with a non-test account, so sandbox deployments can never change real
+// listings. The account is the one the request's token belongs to, asked to
+// Mercado Livre, since the ml_user_id cookie is set by the client. Read-only
+// requests pass through.
+func RequireTestAccount(testUserRepo *repository.TestUserRepository, clientFor func(c *gin.Context) *meli.MeliClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		me, err := clientFor(c).Me(c.Request.Context())
+		if errors.Is(err, meli.ErrUnauthorized) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": i18n.Tr(c, "sandbox mode: log in with a test user before making changes")})
+			return
+		}
+		if err != nil {
+			respondUpstreamError(c, err)
+			c.Abort()
+			return
+		}
+
+		ok, err := testUserRepo.Exists(c.Request.Context(), me.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": i18n.Tr(c, "sandbox mode: the logged-in account is not a test user")})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/scheduler"
+	"melibot/internal/service"
+)
+
+// ScheduleHandler lists the scheduled tasks and edits their schedules.
+type ScheduleHandler struct {
+	svc *service.ScheduleService
+}
+
+func NewScheduleHandler(svc *service.ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{svc: svc}
+}
+
+// ListSchedules returns every scheduled task with its schedule, last and
+// next run and last error.
+func (h *ScheduleHandler) ListSchedules(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.Schedules())
+}
+
+// GetSchedule returns a single scheduled task.
+func (h *ScheduleHandler) GetSchedule(c *gin.Context) {
+	st, err := h.svc.Schedule(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+// UpdateSchedule changes the cron expression of a task or enables and
+// disables it, e.g. {"schedule": "0 3 * * *"} or {"enabled": false}. An
+// empty schedule restores the default interval.
+func (h *ScheduleHandler) UpdateSchedule(c *gin.Context) {
+	var req service.ScheduleUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	st, err := h.svc.UpdateSchedule(c.Request.Context(), c.Param("name"), req)
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownTask):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+	case errors.Is(err, scheduler.ErrInvalidSchedule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, st)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+)
+
+type ScoringHandler struct {
+	svc *service.ScoringService
+}
+
+func NewScoringHandler(svc *service.ScoringService) *ScoringHandler {
+	return &ScoringHandler{svc: svc}
+}
+
+type scoringProfileRequest struct {
+	WeightSold        float64  `json:"weight_sold"`
+	WeightPrice       float64  `json:"weight_price"`
+	WeightCompetition float64  `json:"weight_competition"`
+	WeightHealth      float64  `json:"weight_health"`
+	PriceBandMin      *float64 `json:"price_band_min"`
+	PriceBandMax      *float64 `json:"price_band_max"`
+}
+
+// ListProfiles returns the stored scoring profiles.
+func (h *ScoringHandler) ListProfiles(c *gin.Context) {
+	profiles, err := h.svc.Profiles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(profiles))
+	for i := range profiles {
+		out = append(out, scoringProfileResponse(&profiles[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// GetProfile returns a single scoring profile.
+func (h *ScoringHandler) GetProfile(c *gin.Context) {
+	p, err := h.svc.Profile(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, service.ErrProfileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scoringProfileResponse(p))
+}
+
+// PutProfile creates or replaces a scoring profile, selectable with
+// /api/trends?profile=<name>.
+func (h *ScoringHandler) PutProfile(c *gin.Context) {
+	var req scoringProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	profile := repository.ScoringProfile{
+		Name:              c.Param("name"),
+		WeightSold:        req.WeightSold,
+		WeightPrice:       req.WeightPrice,
+		WeightCompetition: req.WeightCompetition,
+		WeightHealth:      req.WeightHealth,
+		PriceBandMin:      req.PriceBandMin,
+		PriceBandMax:      req.PriceBandMax,
+	}
+	if err := service.ValidateProfile(profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	saved, err := h.svc.SaveProfile(c.Request.Context(), profile)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scoringProfileResponse(saved))
+}
+
+// DeleteProfile removes a scoring profile.
+func (h *ScoringHandler) DeleteProfile(c *gin.Context) {
+	err := h.svc.DeleteProfile(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, service.ErrProfileNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func scoringProfileResponse(p *repository.ScoringProfile) gin.H {
+	return gin.H{
+		"name":               p.Name,
+		"weight_sold":        p.WeightSold,
+		"weight_price":       p.WeightPrice,
+		"weight_competition": p.WeightCompetition,
+		"weight_health":      p.WeightHealth,
+		"price_band_min":     p.PriceBandMin,
+		"price_band_max":     p.PriceBandMax,
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type SearchHandler struct {
+	svc *service.SearchService
+}
+
+func NewSearchHandler(svc *service.SearchService) *SearchHandler {
+	return &SearchHandler{svc: svc}
+}
+
+// SearchLocal searches persisted data; the only Mercado Livre call finds
+// out which seller's orders and questions to search.
+func (h *SearchHandler) SearchLocal(c *gin.Context) {
+	ctx := c.Request.Context()
+	query := c.Query("q")
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "q is required")})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	results, err := h.svc.SearchLocal(ctx, query, limit)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type SellerHandler struct {
+	svc *service.SellerService
+}
+
+func NewSellerHandler(svc *service.SellerService) *SellerHandler {
+	return &SellerHandler{svc: svc}
+}
+
+// GetCatalogEligibility returns the catalog eligibility report for all of the
+// authenticated seller's listings.
+func (h *SellerHandler) GetCatalogEligibility(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	report, err := h.svc.CatalogEligibilityReport(ctx)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetOverlaps reports my listings that compete with each other for the same
+// catalog product or with near-identical titles.
+func (h *SellerHandler) GetOverlaps(c *gin.Context) {
+	report, err := h.svc.OverlapReport(c.Request.Context())
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetCategoryAudit reports my listings the category predictor would place
+// in another category with at least ?min_confidence= (default 0.8).
+func (h *SellerHandler) GetCategoryAudit(c *gin.Context) {
+	minConfidence, ok := auditConfidence(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.svc.CategoryAudit(c.Request.Context(), minConfidence)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// auditConfidence reads ?min_confidence= of the category audit, answering
+// 400 itself when it is not in (0, 1].
+func auditConfidence(c *gin.Context) (float64, bool) {
+	v := c.Query("min_confidence")
+	if v == "" {
+		return service.DefaultAuditConfidence, true
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 || f > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "min_confidence must be in (0, 1]")})
+		return 0, false
+	}
+	return f, true
+}
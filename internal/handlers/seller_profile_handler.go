@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/service"
+)
+
+type SellerProfileHandler struct {
+	svc *service.SellerProfileService
+}
+
+func NewSellerProfileHandler(svc *service.SellerProfileService) *SellerProfileHandler {
+	return &SellerProfileHandler{svc: svc}
+}
+
+// GetSellerProfile returns another seller's reputation, item count, best
+// sellers and the listings of theirs found in the stored trends.
+func (h *SellerProfileHandler) GetSellerProfile(c *gin.Context) {
+	id, ok := sellerIDParam(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.svc.Profile(c.Request.Context(), id)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/service"
+)
+
+// SettingsHandler shows and reloads the tuning settings of the server.
+type SettingsHandler struct {
+	svc *service.SettingsService
+}
+
+func NewSettingsHandler(svc *service.SettingsService) *SettingsHandler {
+	return &SettingsHandler{svc: svc}
+}
+
+// GetSettings returns the reloadable settings, their current values and the
+// last reload.
+func (h *SettingsHandler) GetSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.Settings())
+}
+
+// Reload reads the .env file again and applies the changed settings, like
+// SIGHUP. Only the instance answering the request reloads.
+func (h *SettingsHandler) Reload(c *gin.Context) {
+	result, err := h.svc.Reload()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type ShareOfShelfHandler struct {
+	svc *service.ShareOfShelfService
+}
+
+func NewShareOfShelfHandler(svc *service.ShareOfShelfService) *ShareOfShelfHandler {
+	return &ShareOfShelfHandler{svc: svc}
+}
+
+// GetShareOfShelf returns what share of the top 50 search results of my
+// categories belongs to me and to each other seller, grouped by
+// ?group_by=day|week|month over ?from=&to= (default: last 30 days), of one
+// category with ?category_id=.
+func (h *ShareOfShelfHandler) GetShareOfShelf(c *gin.Context) {
+	from, to, ok := dateRange(c, defaultSalesRange)
+	if !ok {
+		return
+	}
+
+	report, err := h.svc.Report(c.Request.Context(), c.DefaultQuery("group_by", "week"), c.Query("category_id"), from, to)
+	if errors.Is(err, service.ErrInvalidShelfGrouping) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// TakeSnapshot samples the top results of my categories right away.
+func (h *ShareOfShelfHandler) TakeSnapshot(c *gin.Context) {
+	n, err := h.svc.Snapshot(c.Request.Context())
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": n})
+}
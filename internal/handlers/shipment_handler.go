@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/service"
+)
+
+type ShipmentHandler struct {
+	svc *service.ShipmentService
+}
+
+func NewShipmentHandler(svc *service.ShipmentService) *ShipmentHandler {
+	return &ShipmentHandler{svc: svc}
+}
+
+// GetPendingShipments returns orders awaiting dispatch with their handling
+// deadlines, most urgent first.
+func (h *ShipmentHandler) GetPendingShipments(c *gin.Context) {
+	pending, err := h.svc.PendingShipments(c.Request.Context())
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pending)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type SKUMappingHandler struct {
+	svc *service.SKUMappingService
+}
+
+func NewSKUMappingHandler(svc *service.SKUMappingService) *SKUMappingHandler {
+	return &SKUMappingHandler{svc: svc}
+}
+
+// ListMappings returns the mappings of my SKUs to listings, of one SKU with
+// ?sku=.
+func (h *SKUMappingHandler) ListMappings(c *gin.Context) {
+	mappings, err := h.svc.Mappings(c.Request.Context(), c.Query("sku"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, mappings)
+}
+
+// PutMappings maps listings or variations to my SKUs from a JSON list of
+// {sku, item_id, variation_id}, replacing their previous SKU.
+func (h *SKUMappingHandler) PutMappings(c *gin.Context) {
+	var mappings []service.SKUMapping
+	if err := c.ShouldBindJSON(&mappings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err := service.ValidateSKUMappings(mappings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	saved, err := h.svc.Save(c.Request.Context(), mappings)
+	if errors.Is(err, service.ErrNotOwnItem) || errors.Is(err, service.ErrUnknownVariation) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteMapping removes a mapping.
+func (h *SKUMappingHandler) DeleteMapping(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	err := h.svc.Delete(c.Request.Context(), id)
+	if errors.Is(err, service.ErrSKUMappingNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
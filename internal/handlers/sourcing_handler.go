@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+)
+
+type SourcingHandler struct {
+	svc *service.SourcingService
+}
+
+func NewSourcingHandler(svc *service.SourcingService) *SourcingHandler {
+	return &SourcingHandler{svc: svc}
+}
+
+type sourcingRequest struct {
+	ProductID string `json:"product_id"`
+	service.SourcingTerms
+}
+
+// ListCandidates returns the sourcing candidates with their last price,
+// margin and signal.
+func (h *SourcingHandler) ListCandidates(c *gin.Context) {
+	candidates, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(candidates))
+	for i := range candidates {
+		out = append(out, sourcingCandidateResponse(&candidates[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Track registers a catalog product I'm considering sourcing with its
+// landed cost, or updates its terms if it is already registered.
+func (h *SourcingHandler) Track(c *gin.Context) {
+	var req sourcingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if req.ProductID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "product_id is required")})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	candidate, err := h.svc.Track(c.Request.Context(), req.ProductID, req.SourcingTerms)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sourcingCandidateResponse(candidate))
+}
+
+// UpdateTerms replaces the landed cost and margin threshold of a candidate.
+func (h *SourcingHandler) UpdateTerms(c *gin.Context) {
+	var t service.SourcingTerms
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err := t.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	candidate, err := h.svc.UpdateTerms(c.Request.Context(), c.Param("product_id"), t)
+	if errors.Is(err, service.ErrNotSourcing) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, sourcingCandidateResponse(candidate))
+}
+
+// Untrack removes a sourcing candidate.
+func (h *SourcingHandler) Untrack(c *gin.Context) {
+	err := h.svc.Untrack(c.Request.Context(), c.Param("product_id"))
+	if errors.Is(err, service.ErrNotSourcing) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListSignals returns recent buy and abort signals, optionally filtered by
+// ?product_id=.
+func (h *SourcingHandler) ListSignals(c *gin.Context) {
+	limit := defaultAlertsLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		limit = min(n, maxAlertsLimit)
+	}
+
+	signals, err := h.svc.Signals(c.Request.Context(), c.Query("product_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(signals))
+	for _, s := range signals {
+		out = append(out, gin.H{
+			"product_id":     s.ProductID,
+			"signal":         s.Signal,
+			"price":          s.Price,
+			"fees":           s.Fees,
+			"landed_cost":    s.LandedCost,
+			"margin_pct":     s.MarginPct,
+			"min_margin_pct": s.MinMarginPct,
+			"created_at":     s.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func sourcingCandidateResponse(s *repository.SourcingCandidate) gin.H {
+	return gin.H{
+		"product_id":      s.ProductID,
+		"title":           s.Title,
+		"permalink":       s.Permalink,
+		"landed_cost":     s.LandedCost,
+		"min_margin_pct":  s.MinMarginPct,
+		"listing_type":    s.ListingType,
+		"category_id":     s.CategoryID,
+		"last_price":      s.LastPrice,
+		"last_fees":       s.LastFees,
+		"last_margin_pct": s.LastMarginPct,
+		"signal":          s.Signal,
+		"last_checked_at": s.LastCheckedAt,
+	}
+}
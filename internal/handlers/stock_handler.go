@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type StockHandler struct {
+	svc *service.StockService
+}
+
+func NewStockHandler(svc *service.StockService) *StockHandler {
+	return &StockHandler{svc: svc}
+}
+
+// AdjustStock changes the stock of many of my listings at once, e.g. after
+// an inventory count. It takes a JSON list of {item_id or sku, delta or
+// absolute}, or a CSV with those columns as a multipart "file" field or as
+// a text/csv body, and reports the outcome of every row.
+func (h *StockHandler) AdjustStock(c *gin.Context) {
+	var (
+		rows []service.StockAdjustment
+		err  error
+	)
+	if file, _, formErr := c.Request.FormFile("file"); formErr == nil {
+		defer file.Close()
+		rows, err = service.ParseStockCSV(file)
+	} else if strings.HasPrefix(c.ContentType(), "text/csv") {
+		rows, err = service.ParseStockCSV(c.Request.Body)
+	} else {
+		err = c.ShouldBindJSON(&rows)
+	}
+	if err == nil {
+		err = service.ValidateStockAdjustments(rows)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	report, err := h.svc.Adjust(c.Request.Context(), rows)
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+const (
+	defaultStreamLimit = 10000
+	maxStreamLimit     = 100000
+)
+
+type StreamHandler struct {
+	svc *service.StreamService
+}
+
+func NewStreamHandler(svc *service.StreamService) *StreamHandler {
+	return &StreamHandler{svc: svc}
+}
+
+// Stream writes the records of ?entity= as newline-delimited JSON, oldest
+// first, optionally only those created ?since= a date or RFC3339 time. Each
+// record carries its id; ?cursor=<id of the last record received> resumes
+// after it, and the X-Next-Cursor trailer holds the cursor to resume from.
+// A pull returns at most ?limit= records (default 10000); fewer means it
+// caught up.
+func (h *StreamHandler) Stream(c *gin.Context) {
+	entity := c.Query("entity")
+	afterID, err := h.svc.ParseStreamCursor(entity, c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	var since time.Time
+	if v := c.Query("since"); v != "" {
+		if since, _, err = parseDateParam(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "since must be a date (YYYY-MM-DD) or RFC3339")})
+			return
+		}
+	}
+	limit := defaultStreamLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		limit = min(n, maxStreamLimit)
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Trailer", "X-Next-Cursor")
+	c.Status(http.StatusOK)
+	result, err := h.svc.Stream(c.Request.Context(), c.Writer, c.Writer.Flush, entity, afterID, since, limit)
+	if err != nil {
+		// The response has started: without the trailer, the client resumes
+		// from the id of the last record it got.
+		log.Printf("[ERROR] Stream of %s interrupted: %v", entity, err)
+		_ = c.Error(err)
+		return
+	}
+	c.Writer.Header().Set("X-Next-Cursor", result.NextCursor)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+type TitleHandler struct {
+	svc *service.TitleService
+}
+
+func NewTitleHandler(svc *service.TitleService) *TitleHandler {
+	return &TitleHandler{svc: svc}
+}
+
+// GetTitleSuggestions compares one of my listing titles with the category's
+// top sellers and returns concrete suggestions.
+func (h *TitleHandler) GetTitleSuggestions(c *gin.Context) {
+	report, err := h.svc.TitleSuggestions(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, service.ErrNotOwnItem) {
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
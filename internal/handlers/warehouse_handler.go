@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/service"
+)
+
+// WarehouseHandler shows and runs the replication to the analytics
+// warehouse.
+type WarehouseHandler struct {
+	svc *service.WarehouseService
+}
+
+func NewWarehouseHandler(svc *service.WarehouseService) *WarehouseHandler {
+	return &WarehouseHandler{svc: svc}
+}
+
+// GetCheckpoints returns how far each table was replicated and the outcome
+// of its last sync.
+func (h *WarehouseHandler) GetCheckpoints(c *gin.Context) {
+	checkpoints, err := h.svc.Checkpoints(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(checkpoints))
+	for _, cp := range checkpoints {
+		out = append(out, gin.H{
+			"target":       cp.Target,
+			"table":        cp.TableName,
+			"cursor":       cp.Cursor,
+			"cursor_id":    cp.CursorID,
+			"rows":         cp.Rows,
+			"last_sync_at": cp.LastSyncAt,
+			"last_error":   cp.LastError,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Sync replicates what changed since the last sync now. Failed syncs get
+// 502; a sync already running gets 409.
+func (h *WarehouseHandler) Sync(c *gin.Context) {
+	err := h.svc.Sync(c.Request.Context())
+	if errors.Is(err, service.ErrWarehouseSyncRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	h.GetCheckpoints(c)
+}
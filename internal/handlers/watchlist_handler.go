@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+)
+
+const (
+	defaultAlertsLimit = 50
+	maxAlertsLimit     = 500
+)
+
+type WatchlistHandler struct {
+	svc *service.WatchlistService
+}
+
+func NewWatchlistHandler(svc *service.WatchlistService) *WatchlistHandler {
+	return &WatchlistHandler{svc: svc}
+}
+
+type watchRequest struct {
+	ProductID string `json:"product_id"`
+	service.Thresholds
+}
+
+// ListWatched returns the watched products with their thresholds and last price.
+func (h *WatchlistHandler) ListWatched(c *gin.Context) {
+	products, err := h.svc.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(products))
+	for i := range products {
+		out = append(out, watchedProductResponse(&products[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Watch adds a product to the watchlist, or updates its thresholds if it is
+// already watched.
+func (h *WatchlistHandler) Watch(c *gin.Context) {
+	var req watchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if req.ProductID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "product_id is required")})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	p, err := h.svc.Watch(c.Request.Context(), req.ProductID, req.Thresholds)
+	if errors.Is(err, service.ErrVersionConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		respondUpstreamError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, watchedProductResponse(p))
+}
+
+// UpdateThresholds replaces the thresholds of a watched product. An
+// If-Match header or a "version" in the body makes it fail with 409 when
+// they were changed since that version.
+func (h *WatchlistHandler) UpdateThresholds(c *gin.Context) {
+	var req struct {
+		service.Thresholds
+		Version uint `json:"version"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	version, ok := ifMatchVersion(c, req.Version)
+	if !ok {
+		return
+	}
+
+	p, err := h.svc.UpdateThresholds(c.Request.Context(), c.Param("product_id"), req.Thresholds, version)
+	if errors.Is(err, service.ErrNotWatched) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if errors.Is(err, service.ErrVersionConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	setVersionETag(c, p.Version)
+	c.JSON(http.StatusOK, watchedProductResponse(p))
+}
+
+// Unwatch moves a product from the watchlist to its trash.
+func (h *WatchlistHandler) Unwatch(c *gin.Context) {
+	err := h.svc.Unwatch(c.Request.Context(), c.Param("product_id"))
+	if errors.Is(err, service.ErrNotWatched) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListTrash returns the products removed from the watchlist, last removed
+// first.
+func (h *WatchlistHandler) ListTrash(c *gin.Context) {
+	products, err := h.svc.Trash(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(products))
+	for i := range products {
+		out = append(out, watchedProductResponse(&products[i]))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Restore puts a removed product back on the watchlist.
+func (h *WatchlistHandler) Restore(c *gin.Context) {
+	p, err := h.svc.Restore(c.Request.Context(), c.Param("product_id"))
+	if errors.Is(err, service.ErrNotTrashed) || errors.Is(err, service.ErrNotWatched) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, watchedProductResponse(p))
+}
+
+// ListAlerts returns recent price alerts, optionally filtered by ?product_id=.
+func (h *WatchlistHandler) ListAlerts(c *gin.Context) {
+	limit := defaultAlertsLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Tr(c, "limit must be a positive integer")})
+			return
+		}
+		limit = min(n, maxAlertsLimit)
+	}
+
+	alerts, err := h.svc.Alerts(c.Request.Context(), c.Query("product_id"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(alerts))
+	for _, a := range alerts {
+		out = append(out, gin.H{
+			"product_id":     a.ProductID,
+			"kind":           a.Kind,
+			"price":          a.Price,
+			"previous_price": a.PreviousPrice,
+			"threshold":      a.Threshold,
+			"created_at":     a.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func watchedProductResponse(p *repository.WatchedProduct) gin.H {
+	resp := gin.H{
+		"product_id":      p.ProductID,
+		"title":           p.Title,
+		"permalink":       p.Permalink,
+		"alert_below":     p.AlertBelow,
+		"alert_above":     p.AlertAbove,
+		"change_pct":      p.ChangePct,
+		"last_price":      p.LastPrice,
+		"reference_price": p.ReferencePrice,
+		"below_active":    p.BelowActive,
+		"above_active":    p.AboveActive,
+		"last_checked_at": p.LastCheckedAt,
+		"version":         p.Version,
+	}
+	if p.DeletedAt.Valid {
+		resp["deleted_at"] = p.DeletedAt.Time
+	}
+	return resp
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/pkg/meli"
+)
+
+// notificationTimeout bounds the processing of a single notification.
+const notificationTimeout = time.Minute
+
+// NotificationFunc processes a Mercado Livre notification of one topic.
+type NotificationFunc func(ctx context.Context, n meli.Notification) error
+
+// FailureFunc keeps a notification whose processing failed with err.
+type FailureFunc func(ctx context.Context, n meli.Notification, err error) error
+
+// WebhookHandler receives Mercado Livre notifications and dispatches them by
+// topic. Mercado Livre expects a quick 200 and redelivers otherwise, so the
+// work runs in the background.
+type WebhookHandler struct {
+	applicationID int64
+	topics        map[string]NotificationFunc
+	onFailure     FailureFunc
+}
+
+// NewWebhookHandler rejects notifications for other applications when
+// applicationID is not zero.
+func NewWebhookHandler(applicationID int64) *WebhookHandler {
+	return &WebhookHandler{applicationID: applicationID, topics: make(map[string]NotificationFunc)}
+}
+
+// Handle registers the processor of a topic.
+func (h *WebhookHandler) Handle(topic string, fn NotificationFunc) {
+	h.topics[topic] = fn
+}
+
+// OnFailure registers where notifications whose processing fails are kept
+// for replaying; without it they are only logged.
+func (h *WebhookHandler) OnFailure(fn FailureFunc) {
+	h.onFailure = fn
+}
+
+// Process runs the processor of the notification's topic, if any.
+func (h *WebhookHandler) Process(ctx context.Context, n meli.Notification) error {
+	fn, ok := h.topics[n.Topic]
+	if !ok {
+		return nil
+	}
+	return fn(ctx, n)
+}
+
+// Receive accepts a notification POSTed to the callback URL.
+func (h *WebhookHandler) Receive(c *gin.Context) {
+	var n meli.Notification
+	if err := c.ShouldBindJSON(&n); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if h.applicationID != 0 && n.ApplicationID != h.applicationID {
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.Tr(c, "unknown application")})
+		return
+	}
+
+	if _, ok := h.topics[n.Topic]; !ok {
+		c.Status(http.StatusOK)
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		defer cancel()
+		err := h.Process(ctx, n)
+		if err == nil {
+			return
+		}
+		log.Printf("[ERROR] %s notification %s failed: %v", n.Topic, n.Resource, err)
+		if h.onFailure != nil {
+			// The processing may have used up ctx
+			if err := h.onFailure(context.WithoutCancel(ctx), n, err); err != nil {
+				log.Printf("[ERROR] Failed to keep %s notification %s for replay: %v", n.Topic, n.Resource, err)
+			}
+		}
+	}()
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+	"melibot/internal/service"
+)
+
+// WebhookSubscriptionHandler manages the outbound webhooks that receive
+// internal events.
+type WebhookSubscriptionHandler struct {
+	svc *service.OutboundWebhookService
+}
+
+func NewWebhookSubscriptionHandler(svc *service.OutboundWebhookService) *WebhookSubscriptionHandler {
+	return &WebhookSubscriptionHandler{svc: svc}
+}
+
+type webhookSubscriptionRequest struct {
+	URL     string   `json:"url"`
+	Secret  string   `json:"secret"`
+	Events  []string `json:"events"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// List returns the subscriptions. Secrets are masked.
+func (h *WebhookSubscriptionHandler) List(c *gin.Context) {
+	subs, err := h.svc.Subscriptions(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	out := make([]gin.H, 0, len(subs))
+	for i := range subs {
+		out = append(out, webhookSubscriptionResponse(&subs[i], false))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// Create registers a subscription. The response is the only one that shows
+// the full secret.
+func (h *WebhookSubscriptionHandler) Create(c *gin.Context) {
+	h.save(c, 0, http.StatusCreated)
+}
+
+// Put replaces a subscription; an empty secret keeps the current one.
+func (h *WebhookSubscriptionHandler) Put(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	h.save(c, id, http.StatusOK)
+}
+
+func (h *WebhookSubscriptionHandler) save(c *gin.Context, id uint, status int) {
+	var req webhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	sub := &repository.WebhookSubscription{
+		ID:      id,
+		URL:     strings.TrimSpace(req.URL),
+		Secret:  req.Secret,
+		Events:  strings.Join(req.Events, ","),
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if err := service.ValidateWebhook(sub); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+
+	err := h.svc.SaveSubscription(c.Request.Context(), sub)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, webhookSubscriptionResponse(sub, id == 0 || req.Secret != ""))
+}
+
+// Delete removes a subscription.
+func (h *WebhookSubscriptionHandler) Delete(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	err := h.svc.DeleteSubscription(c.Request.Context(), id)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Test sends a signed webhook.test payload right away and reports whether
+// the receiver accepted it.
+func (h *WebhookSubscriptionHandler) Test(c *gin.Context) {
+	id, ok := uintParam(c, "id")
+	if !ok {
+		return
+	}
+	d, err := h.svc.TestDelivery(c.Request.Context(), id)
+	if errors.Is(err, service.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.TrError(c, err)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.Deliver(c.Request.Context(), *d); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"delivery_id": d.DeliveryID, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"delivery_id": d.DeliveryID, "delivered": true})
+}
+
+func webhookSubscriptionResponse(sub *repository.WebhookSubscription, showSecret bool) gin.H {
+	secret := sub.Secret
+	if !showSecret && len(secret) > 4 {
+		secret = strings.Repeat("*", 8) + secret[len(secret)-4:]
+	}
+	return gin.H{
+		"id":               sub.ID,
+		"url":              sub.URL,
+		"secret":           secret,
+		"events":           append([]string{}, service.WebhookEvents(sub)...),
+		"enabled":          sub.Enabled,
+		"last_delivery_at": sub.LastDeliveryAt,
+		"last_status":      sub.LastStatus,
+		"last_error":       sub.LastError,
+		"created_at":       sub.CreatedAt,
+	}
+}
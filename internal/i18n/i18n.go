@@ -0,0 +1,121 @@
+// Package i18n translates user-facing API messages. Messages are keyed by
+// their English text, so untranslated strings still read fine and handlers
+// keep their messages inline; the catalog in messages.go holds the pt-BR and
+// es translations.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Lang is a supported language tag.
+type Lang string
+
+const (
+	English    Lang = "en"
+	Portuguese Lang = "pt-BR"
+	Spanish    Lang = "es"
+)
+
+// Default is the language used when Accept-Language names none of ours.
+var Default = English
+
+// contextKey is where Middleware stores the negotiated language.
+const contextKey = "i18n.lang"
+
+// Parse maps a language tag to a supported language, matching on the
+// primary subtag ("pt-PT" is Portuguese, "es-AR" Spanish).
+func Parse(tag string) (Lang, bool) {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	switch primary {
+	case "en":
+		return English, true
+	case "pt":
+		return Portuguese, true
+	case "es":
+		return Spanish, true
+	}
+	return "", false
+}
+
+// Negotiate picks the supported language the Accept-Language header prefers
+// most, or Default.
+func Negotiate(header string) Lang {
+	type candidate struct {
+		lang Lang
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if lang, ok := Parse(tag); ok && q > 0 {
+			candidates = append(candidates, candidate{lang, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// T translates msg, then formats it with args like fmt.Sprintf.
+func T(lang Lang, msg string, args ...interface{}) string {
+	if translated, ok := catalog[msg][lang]; ok {
+		msg = translated
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Error translates an error whose message is in the catalog, such as the
+// services' sentinel errors. Other errors are returned as is.
+func Error(lang Lang, err error) string {
+	return T(lang, err.Error())
+}
+
+// Middleware negotiates the language of each request once and announces
+// it, so caches keep one copy per language.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := Negotiate(c.GetHeader("Accept-Language"))
+		c.Set(contextKey, lang)
+		c.Header("Content-Language", string(lang))
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
+
+// FromContext returns the language Middleware negotiated, negotiating it
+// now for routes registered without the middleware.
+func FromContext(c *gin.Context) Lang {
+	if v, ok := c.Get(contextKey); ok {
+		return v.(Lang)
+	}
+	return Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// Tr translates msg into the request's language.
+func Tr(c *gin.Context, msg string, args ...interface{}) string {
+	return T(FromContext(c), msg, args...)
+}
+
+// TrError translates err into the request's language.
+func TrError(c *gin.Context, err error) string {
+	return Error(FromContext(c), err)
+}
@@ -0,0 +1,674 @@
+package i18n
+
+// catalog maps an English message (a fmt format when it takes arguments) to
+// its translations. Keep the verbs of a message in the same order in every
+// language.
+var catalog = map[string]map[Lang]string{
+	// Authentication
+	"Authentication required. Please log in first.": {
+		Portuguese: "Autenticação necessária. Por favor, faça login primeiro.",
+		Spanish:    "Autenticación requerida. Por favor, inicia sesión primero.",
+	},
+	"Authenticated successfully": {
+		Portuguese: "Autenticado com sucesso",
+		Spanish:    "Autenticado correctamente",
+	},
+	"Authorization failed": {
+		Portuguese: "Falha na autorização",
+		Spanish:    "La autorización falló",
+	},
+	"Failed to exchange code for token: %s": {
+		Portuguese: "Falha ao trocar o código pelo token: %s",
+		Spanish:    "No se pudo canjear el código por el token: %s",
+	},
+	"Logged out successfully": {
+		Portuguese: "Sessão encerrada com sucesso",
+		Spanish:    "Sesión cerrada correctamente",
+	},
+	"Not authenticated. Visit /auth/login to authenticate": {
+		Portuguese: "Não autenticado. Acesse /auth/login para se autenticar",
+		Spanish:    "No autenticado. Visita /auth/login para autenticarte",
+	},
+	"OAuth not configured": {
+		Portuguese: "OAuth não configurado",
+		Spanish:    "OAuth no configurado",
+	},
+	"admin endpoints disabled; set ADMIN_API_KEY": {
+		Portuguese: "endpoints de administração desativados; defina ADMIN_API_KEY",
+		Spanish:    "endpoints de administración desactivados; define ADMIN_API_KEY",
+	},
+	"invalid admin key": {
+		Portuguese: "chave de administração inválida",
+		Spanish:    "clave de administración inválida",
+	},
+	"debug_product requires the admin key": {
+		Portuguese: "debug_product exige a chave de administração",
+		Spanish:    "debug_product requiere la clave de administración",
+	},
+	"rate limit exceeded, slow down": {
+		Portuguese: "limite de requisições excedido, diminua o ritmo",
+		Spanish:    "límite de solicitudes excedido, reduce el ritmo",
+	},
+	"unknown application": {
+		Portuguese: "aplicação desconhecida",
+		Spanish:    "aplicación desconocida",
+	},
+	"sandbox mode: log in with a test user before making changes": {
+		Portuguese: "modo sandbox: faça login com um usuário de teste antes de fazer alterações",
+		Spanish:    "modo sandbox: inicia sesión con un usuario de prueba antes de hacer cambios",
+	},
+	"sandbox mode: the logged-in account is not a test user": {
+		Portuguese: "modo sandbox: a conta logada não é um usuário de teste",
+		Spanish:    "modo sandbox: la cuenta conectada no es un usuario de prueba",
+	},
+	"sandbox mode is disabled; set ML_ENVIRONMENT=sandbox": {
+		Portuguese: "o modo sandbox está desativado; defina ML_ENVIRONMENT=sandbox",
+		Spanish:    "el modo sandbox está desactivado; define ML_ENVIRONMENT=sandbox",
+	},
+
+	// Parameters
+	"%s must be an integer": {
+		Portuguese: "%s deve ser um número inteiro",
+		Spanish:    "%s debe ser un número entero",
+	},
+	"invalid %s": {
+		Portuguese: "%s inválido",
+		Spanish:    "%s inválido",
+	},
+	"category_id is required": {
+		Portuguese: "category_id é obrigatório",
+		Spanish:    "category_id es obligatorio",
+	},
+	"product_id is required": {
+		Portuguese: "product_id é obrigatório",
+		Spanish:    "product_id es obligatorio",
+	},
+	"q is required": {
+		Portuguese: "q é obrigatório",
+		Spanish:    "q es obligatorio",
+	},
+	"seller_id is required": {
+		Portuguese: "seller_id é obrigatório",
+		Spanish:    "seller_id es obligatorio",
+	},
+	"enabled is required": {
+		Portuguese: "enabled é obrigatório",
+		Spanish:    "enabled es obligatorio",
+	},
+	"template_id is required": {
+		Portuguese: "template_id é obrigatório",
+		Spanish:    "template_id es obligatorio",
+	},
+	"limit must be a positive integer": {
+		Portuguese: "limit deve ser um inteiro positivo",
+		Spanish:    "limit debe ser un entero positivo",
+	},
+	"limit must be between 1 and %d": {
+		Portuguese: "limit deve estar entre 1 e %d",
+		Spanish:    "limit debe estar entre 1 y %d",
+	},
+	"offset must be a non-negative integer": {
+		Portuguese: "offset deve ser um inteiro não negativo",
+		Spanish:    "offset debe ser un entero no negativo",
+	},
+	"days must be a positive integer": {
+		Portuguese: "days deve ser um inteiro positivo",
+		Spanish:    "days debe ser un entero positivo",
+	},
+	"months must be a positive integer": {
+		Portuguese: "months deve ser um inteiro positivo",
+		Spanish:    "months debe ser un entero positivo",
+	},
+	"from must be RFC3339": {
+		Portuguese: "from deve estar em RFC3339",
+		Spanish:    "from debe estar en RFC3339",
+	},
+	"to must be RFC3339": {
+		Portuguese: "to deve estar em RFC3339",
+		Spanish:    "to debe estar en RFC3339",
+	},
+	"from must be a date (YYYY-MM-DD) or RFC3339": {
+		Portuguese: "from deve ser uma data (AAAA-MM-DD) ou RFC3339",
+		Spanish:    "from debe ser una fecha (AAAA-MM-DD) o RFC3339",
+	},
+	"to must be a date (YYYY-MM-DD) or RFC3339": {
+		Portuguese: "to deve ser uma data (AAAA-MM-DD) ou RFC3339",
+		Spanish:    "to debe ser una fecha (AAAA-MM-DD) o RFC3339",
+	},
+	"from must be before to": {
+		Portuguese: "from deve ser anterior a to",
+		Spanish:    "from debe ser anterior a to",
+	},
+	"titles must have between 1 and %d entries": {
+		Portuguese: "titles deve ter entre 1 e %d itens",
+		Spanish:    "titles debe tener entre 1 y %d elementos",
+	},
+	"unit_cost must be a non-negative number": {
+		Portuguese: "unit_cost deve ser um número não negativo",
+		Spanish:    "unit_cost debe ser un número no negativo",
+	},
+	"min_health must be between 0 and 1": {
+		Portuguese: "min_health deve estar entre 0 e 1",
+		Spanish:    "min_health debe estar entre 0 y 1",
+	},
+	"min_confidence must be in (0, 1]": {
+		Portuguese: "min_confidence deve estar em (0, 1]",
+		Spanish:    "min_confidence debe estar en (0, 1]",
+	},
+	"budget must be a positive duration, e.g. 5s": {
+		Portuguese: "budget deve ser uma duração positiva, ex.: 5s",
+		Spanish:    "budget debe ser una duración positiva, p. ej. 5s",
+	},
+	"group_by must be day or week": {
+		Portuguese: "group_by deve ser day ou week",
+		Spanish:    "group_by debe ser day o week",
+	},
+	"group_by must be week or month": {
+		Portuguese: "group_by deve ser week ou month",
+		Spanish:    "group_by debe ser week o month",
+	},
+	"group_by must be day, week or month": {
+		Portuguese: "group_by deve ser day, week ou month",
+		Spanish:    "group_by debe ser day, week o month",
+	},
+	"format must be csv, json or parquet": {
+		Portuguese: "format deve ser csv, json ou parquet",
+		Spanish:    "format debe ser csv, json o parquet",
+	},
+	"unknown field %s; fields accepts %s": {
+		Portuguese: "campo desconhecido %s; fields aceita %s",
+		Spanish:    "campo desconocido %s; fields acepta %s",
+	},
+
+	// Not found
+	"invalid job id": {
+		Portuguese: "id de job inválido",
+		Spanish:    "id de trabajo inválido",
+	},
+	"invalid seller id": {
+		Portuguese: "id de vendedor inválido",
+		Spanish:    "id de vendedor inválido",
+	},
+	"job not found": {
+		Portuguese: "job não encontrado",
+		Spanish:    "trabajo no encontrado",
+	},
+	"entity must be trend_snapshots, category_stats, keyword_trends or competitor_changes": {
+		Portuguese: "entity deve ser trend_snapshots, category_stats, keyword_trends ou competitor_changes",
+		Spanish:    "entity debe ser trend_snapshots, category_stats, keyword_trends o competitor_changes",
+	},
+	"cursor must be the id of the last record received": {
+		Portuguese: "cursor deve ser o id do último registro recebido",
+		Spanish:    "cursor debe ser el id del último registro recibido",
+	},
+	"since must be a date (YYYY-MM-DD) or RFC3339": {
+		Portuguese: "since deve ser uma data (AAAA-MM-DD) ou RFC3339",
+		Spanish:    "since debe ser una fecha (AAAA-MM-DD) o RFC3339",
+	},
+	"export job not found": {
+		Portuguese: "job de exportação não encontrado",
+		Spanish:    "trabajo de exportación no encontrado",
+	},
+	"export storage is not configured": {
+		Portuguese: "o armazenamento de exportações não está configurado",
+		Spanish:    "el almacenamiento de exportaciones no está configurado",
+	},
+	"kind must be product_trends, trend_snapshots, price_history, orders or category_listings": {
+		Portuguese: "kind deve ser product_trends, trend_snapshots, price_history, orders ou category_listings",
+		Spanish:    "kind debe ser product_trends, trend_snapshots, price_history, orders o category_listings",
+	},
+	"days must not be negative": {
+		Portuguese: "days não pode ser negativo",
+		Spanish:    "days no puede ser negativo",
+	},
+	"year must be between 2000 and the current year": {
+		Portuguese: "year deve estar entre 2000 e o ano atual",
+		Spanish:    "year debe estar entre 2000 y el año actual",
+	},
+	"screening job not found": {
+		Portuguese: "job de triagem não encontrado",
+		Spanish:    "trabajo de evaluación no encontrado",
+	},
+	"invalid run id": {
+		Portuguese: "id de execução inválido",
+		Spanish:    "id de ejecución inválido",
+	},
+	"end-to-end run not found": {
+		Portuguese: "execução ponta a ponta não encontrada",
+		Spanish:    "ejecución de extremo a extremo no encontrada",
+	},
+	"price must not be negative": {
+		Portuguese: "price não pode ser negativo",
+		Spanish:    "price no puede ser negativo",
+	},
+	"listing template not found": {
+		Portuguese: "modelo de anúncio não encontrado",
+		Spanish:    "plantilla de publicación no encontrada",
+	},
+	"name and category_id are required": {
+		Portuguese: "name e category_id são obrigatórios",
+		Spanish:    "name y category_id son obligatorios",
+	},
+	"every attribute needs an id": {
+		Portuguese: "todo atributo precisa de um id",
+		Spanish:    "cada atributo necesita un id",
+	},
+	"the listing has problems; fix them and publish again": {
+		Portuguese: "o anúncio tem problemas; corrija-os e publique novamente",
+		Spanish:    "la publicación tiene problemas; corrígelos y publica de nuevo",
+	},
+	"listing rule not found": {
+		Portuguese: "regra de anúncio não encontrada",
+		Spanish:    "regla de publicación no encontrada",
+	},
+	"item_id is required": {
+		Portuguese: "item_id é obrigatório",
+		Spanish:    "item_id es obligatorio",
+	},
+	"pause_at is required for schedule rules": {
+		Portuguese: "pause_at é obrigatório para regras de agendamento",
+		Spanish:    "pause_at es obligatorio para reglas programadas",
+	},
+	"resume_at must be after pause_at": {
+		Portuguese: "resume_at deve ser posterior a pause_at",
+		Spanish:    "resume_at debe ser posterior a pause_at",
+	},
+	"trigger must be schedule or full_stock": {
+		Portuguese: "trigger deve ser schedule ou full_stock",
+		Spanish:    "trigger debe ser schedule o full_stock",
+	},
+	"invalid order id": {
+		Portuguese: "id de pedido inválido",
+		Spanish:    "id de pedido inválido",
+	},
+	"order not found": {
+		Portuguese: "pedido não encontrado",
+		Spanish:    "pedido no encontrado",
+	},
+	"no stats yet; start a crawl with POST /api/categories/%s/crawl": {
+		Portuguese: "ainda não há estatísticas; inicie uma varredura com POST /api/categories/%s/crawl",
+		Spanish:    "aún no hay estadísticas; inicia un rastreo con POST /api/categories/%s/crawl",
+	},
+	"scoring profile not found": {
+		Portuguese: "perfil de pontuação não encontrado",
+		Spanish:    "perfil de puntuación no encontrado",
+	},
+	"seller is not a watched competitor": {
+		Portuguese: "o vendedor não é um concorrente acompanhado",
+		Spanish:    "el vendedor no es un competidor seguido",
+	},
+	"product is not on the watchlist": {
+		Portuguese: "o produto não está na lista de acompanhamento",
+		Spanish:    "el producto no está en la lista de seguimiento",
+	},
+	"no cost recorded for this SKU": {
+		Portuguese: "nenhum custo registrado para este SKU",
+		Spanish:    "no hay costo registrado para este SKU",
+	},
+	"answer template not found": {
+		Portuguese: "modelo de resposta não encontrado",
+		Spanish:    "plantilla de respuesta no encontrada",
+	},
+	"question not in the review queue": {
+		Portuguese: "a pergunta não está na fila de revisão",
+		Spanish:    "la pregunta no está en la cola de revisión",
+	},
+	"question was already answered or dismissed": {
+		Portuguese: "a pergunta já foi respondida ou descartada",
+		Spanish:    "la pregunta ya fue respondida o descartada",
+	},
+	"webhook subscription not found": {
+		Portuguese: "assinatura de webhook não encontrada",
+		Spanish:    "suscripción de webhook no encontrada",
+	},
+	"item does not belong to the authenticated seller": {
+		Portuguese: "o anúncio não pertence ao vendedor autenticado",
+		Spanish:    "la publicación no pertenece al vendedor autenticado",
+	},
+
+	// Validation of request bodies
+	"alert_below must be lower than alert_above": {
+		Portuguese: "alert_below deve ser menor que alert_above",
+		Spanish:    "alert_below debe ser menor que alert_above",
+	},
+	"a template is scoped to an item or a category, not both": {
+		Portuguese: "um modelo vale para um anúncio ou uma categoria, não ambos",
+		Spanish:    "una plantilla aplica a una publicación o a una categoría, no a ambas",
+	},
+	"name and answer are required": {
+		Portuguese: "name e answer são obrigatórios",
+		Spanish:    "name y answer son obligatorios",
+	},
+	"match_type must be keywords or regex": {
+		Portuguese: "match_type deve ser keywords ou regex",
+		Spanish:    "match_type debe ser keywords o regex",
+	},
+	"pattern must list at least one keyword": {
+		Portuguese: "pattern deve listar ao menos uma palavra-chave",
+		Spanish:    "pattern debe incluir al menos una palabra clave",
+	},
+	"weights must not be negative": {
+		Portuguese: "os pesos não podem ser negativos",
+		Spanish:    "los pesos no pueden ser negativos",
+	},
+	"at least one weight must be positive": {
+		Portuguese: "ao menos um peso deve ser positivo",
+		Spanish:    "al menos un peso debe ser positivo",
+	},
+	"price_band_min must not exceed price_band_max": {
+		Portuguese: "price_band_min não pode ser maior que price_band_max",
+		Spanish:    "price_band_min no puede superar price_band_max",
+	},
+	"lead_time_days, safety_days and coverage_days must not be negative": {
+		Portuguese: "lead_time_days, safety_days e coverage_days não podem ser negativos",
+		Spanish:    "lead_time_days, safety_days y coverage_days no pueden ser negativos",
+	},
+	"window_days must be between 1 and 365": {
+		Portuguese: "window_days deve estar entre 1 e 365",
+		Spanish:    "window_days debe estar entre 1 y 365",
+	},
+	"window must be a duration such as 7d, 2w or 36h, up to 90d": {
+		Portuguese: "window deve ser uma duração como 7d, 2w ou 36h, de até 90d",
+		Spanish:    "window debe ser una duración como 7d, 2w o 36h, de hasta 90d",
+	},
+	"url must be an absolute http or https URL": {
+		Portuguese: "url deve ser uma URL http ou https absoluta",
+		Spanish:    "url debe ser una URL http o https absoluta",
+	},
+	"the CSV is empty": {
+		Portuguese: "o CSV está vazio",
+		Spanish:    "el CSV está vacío",
+	},
+	"the CSV has no products": {
+		Portuguese: "o CSV não tem produtos",
+		Spanish:    "el CSV no tiene productos",
+	},
+	"the CSV has no rows": {
+		Portuguese: "o CSV não tem linhas",
+		Spanish:    "el CSV no tiene filas",
+	},
+	"the CSV needs a header with an item_id or sku column and a delta or absolute column": {
+		Portuguese: "o CSV precisa de um cabeçalho com uma coluna item_id ou sku e uma coluna delta ou absolute",
+		Spanish:    "el CSV necesita un encabezado con una columna item_id o sku y una columna delta o absolute",
+	},
+	"no stock adjustments": {
+		Portuguese: "nenhum ajuste de estoque",
+		Spanish:    "ningún ajuste de stock",
+	},
+	"no SKU mappings": {
+		Portuguese: "nenhum mapeamento de SKU",
+		Spanish:    "ningún mapeo de SKU",
+	},
+	"SKU mapping not found": {
+		Portuguese: "mapeamento de SKU não encontrado",
+		Spanish:    "mapeo de SKU no encontrado",
+	},
+	"keyword is required": {
+		Portuguese: "keyword é obrigatório",
+		Spanish:    "keyword es obligatorio",
+	},
+	"rank tracker not found": {
+		Portuguese: "monitoramento de posição não encontrado",
+		Spanish:    "seguimiento de posición no encontrado",
+	},
+	"experiment not found": {
+		Portuguese: "experimento não encontrado",
+		Spanish:    "experimento no encontrado",
+	},
+	"the listing already has a running experiment": {
+		Portuguese: "o anúncio já tem um experimento em andamento",
+		Spanish:    "la publicación ya tiene un experimento en curso",
+	},
+	"the experiment is not running": {
+		Portuguese: "o experimento não está em andamento",
+		Spanish:    "el experimento no está en curso",
+	},
+	"the listing must be active to run an experiment": {
+		Portuguese: "o anúncio precisa estar ativo para rodar um experimento",
+		Spanish:    "la publicación debe estar activa para correr un experimento",
+	},
+	"price experiments do not support listings with variations": {
+		Portuguese: "experimentos de preço não suportam anúncios com variações",
+		Spanish:    "los experimentos de precio no admiten publicaciones con variaciones",
+	},
+	"kind must be price or title": {
+		Portuguese: "kind deve ser price ou title",
+		Spanish:    "kind debe ser price o title",
+	},
+	"set either prices or price_steps_pct": {
+		Portuguese: "informe prices ou price_steps_pct",
+		Spanish:    "indique prices o price_steps_pct",
+	},
+	"prices must be positive": {
+		Portuguese: "os preços devem ser positivos",
+		Spanish:    "los precios deben ser positivos",
+	},
+	"titles are only for title experiments": {
+		Portuguese: "titles é só para experimentos de título",
+		Spanish:    "titles es solo para experimentos de título",
+	},
+	"prices are only for price experiments": {
+		Portuguese: "preços são só para experimentos de preço",
+		Spanish:    "los precios son solo para experimentos de precio",
+	},
+	"titles must not be empty": {
+		Portuguese: "os títulos não podem ser vazios",
+		Spanish:    "los títulos no pueden estar vacíos",
+	},
+	"the titles must differ": {
+		Portuguese: "os títulos devem ser diferentes",
+		Spanish:    "los títulos deben ser distintos",
+	},
+	"the title of a listing with sales cannot be changed": {
+		Portuguese: "o título de um anúncio com vendas não pode ser alterado",
+		Spanish:    "el título de una publicación con ventas no se puede cambiar",
+	},
+	"the CSV needs a header with a sku column and a cost column": {
+		Portuguese: "o CSV precisa de um cabeçalho com uma coluna sku e uma coluna cost",
+		Spanish:    "el CSV necesita un encabezado con una columna sku y una columna cost",
+	},
+	"the email has no CSV attachment": {
+		Portuguese: "o e-mail não tem anexo CSV",
+		Spanish:    "el correo no tiene un adjunto CSV",
+	},
+	"the sender is not an allowed supplier": {
+		Portuguese: "o remetente não é um fornecedor permitido",
+		Spanish:    "el remitente no es un proveedor permitido",
+	},
+	"inbound emails disabled; set SUPPLIER_EMAIL_TOKEN": {
+		Portuguese: "e-mails de entrada desativados; defina SUPPLIER_EMAIL_TOKEN",
+		Spanish:    "correos entrantes desactivados; defina SUPPLIER_EMAIL_TOKEN",
+	},
+	"invalid token": {
+		Portuguese: "token inválido",
+		Spanish:    "token inválido",
+	},
+	"organization key required": {
+		Portuguese: "chave da organização obrigatória",
+		Spanish:    "se requiere la clave de la organización",
+	},
+	"invalid organization key": {
+		Portuguese: "chave da organização inválida",
+		Spanish:    "clave de organización inválida",
+	},
+	"organization not found": {
+		Portuguese: "organização não encontrada",
+		Spanish:    "organización no encontrada",
+	},
+	"organization name is required": {
+		Portuguese: "o nome da organização é obrigatório",
+		Spanish:    "el nombre de la organización es obligatorio",
+	},
+	"login expired or unknown; start it again": {
+		Portuguese: "login expirado ou desconhecido; comece de novo",
+		Spanish:    "inicio de sesión vencido o desconocido; vuelva a empezar",
+	},
+	"invalid billing webhook signature": {
+		Portuguese: "assinatura do webhook de cobrança inválida",
+		Spanish:    "firma del webhook de facturación inválida",
+	},
+	"invalid billing webhook payload": {
+		Portuguese: "conteúdo do webhook de cobrança inválido",
+		Spanish:    "contenido del webhook de facturación inválido",
+	},
+	"unknown plan": {
+		Portuguese: "plano desconhecido",
+		Spanish:    "plan desconocido",
+	},
+	"subscription plan inactive; renew it to continue": {
+		Portuguese: "plano de assinatura inativo; renove-o para continuar",
+		Spanish:    "plan de suscripción inactivo; renuévelo para continuar",
+	},
+	"settlement must be released, pending, unpaid or refunded": {
+		Portuguese: "settlement deve ser released, pending, unpaid ou refunded",
+		Spanish:    "settlement debe ser released, pending, unpaid o refunded",
+	},
+	"days must be between 1 and 90": {
+		Portuguese: "days deve estar entre 1 e 90",
+		Spanish:    "days debe estar entre 1 y 90",
+	},
+	"product is not a sourcing candidate": {
+		Portuguese: "o produto não é um candidato de compra",
+		Spanish:    "el producto no es un candidato de compra",
+	},
+	"landed_cost must be positive": {
+		Portuguese: "landed_cost deve ser positivo",
+		Spanish:    "landed_cost debe ser positivo",
+	},
+	"min_margin_pct must be between 0 and 100": {
+		Portuguese: "min_margin_pct deve estar entre 0 e 100",
+		Spanish:    "min_margin_pct debe estar entre 0 y 100",
+	},
+	"report not found": {
+		Portuguese: "relatório não encontrado",
+		Spanish:    "informe no encontrado",
+	},
+	"name is required": {
+		Portuguese: "name é obrigatório",
+		Spanish:    "name es obligatorio",
+	},
+	"source must be orders or trend_snapshots": {
+		Portuguese: "source deve ser orders ou trend_snapshots",
+		Spanish:    "source debe ser orders o trend_snapshots",
+	},
+	"at least one metric is required": {
+		Portuguese: "é necessária pelo menos uma métrica",
+		Spanish:    "se requiere al menos una métrica",
+	},
+	"days must be between 1 and 366": {
+		Portuguese: "days deve estar entre 1 e 366",
+		Spanish:    "days debe estar entre 1 y 366",
+	},
+	"schedule must be hourly, daily, weekly or empty": {
+		Portuguese: "schedule deve ser hourly, daily, weekly ou vazio",
+		Spanish:    "schedule debe ser hourly, daily, weekly o vacío",
+	},
+	"format must be csv or json": {
+		Portuguese: "format deve ser csv ou json",
+		Spanish:    "format debe ser csv o json",
+	},
+	"destination must be storage or webhook": {
+		Portuguese: "destination deve ser storage ou webhook",
+		Spanish:    "destination debe ser storage o webhook",
+	},
+	"destination_url must be an absolute http or https URL": {
+		Portuguese: "destination_url deve ser uma URL http ou https absoluta",
+		Spanish:    "destination_url debe ser una URL http o https absoluta",
+	},
+	"destination resolves to a private, loopback or link-local address": {
+		Portuguese: "o destino aponta para um endereço privado, de loopback ou link-local",
+		Spanish:    "el destino apunta a una dirección privada, de loopback o link-local",
+	},
+	"a warehouse sync is already running": {
+		Portuguese: "uma sincronização com o data warehouse já está em andamento",
+		Spanish:    "ya hay una sincronización con el data warehouse en curso",
+	},
+	"product is not in the watchlist trash": {
+		Portuguese: "o produto não está na lixeira da lista de acompanhamento",
+		Spanish:    "el producto no está en la papelera de la lista de seguimiento",
+	},
+	"it was changed by someone else since you loaded it; reload it and try again": {
+		Portuguese: "outra pessoa alterou isto depois que você carregou; recarregue e tente de novo",
+		Spanish:    "otra persona lo modificó después de que lo cargaste; recárgalo e inténtalo de nuevo",
+	},
+	"If-Match must be the ETag of the version being edited": {
+		Portuguese: "If-Match deve ser o ETag da versão sendo editada",
+		Spanish:    "If-Match debe ser el ETag de la versión que se edita",
+	},
+	"config export and import need an organization key": {
+		Portuguese: "exportar e importar a configuração exige uma chave de organização",
+		Spanish:    "exportar e importar la configuración requiere una clave de organización",
+	},
+	"unsupported config version": {
+		Portuguese: "versão de configuração não suportada",
+		Spanish:    "versión de configuración no soportada",
+	},
+	"duplicate entry": {
+		Portuguese: "entrada duplicada",
+		Spanish:    "entrada duplicada",
+	},
+	"config document too large": {
+		Portuguese: "documento de configuração grande demais",
+		Spanish:    "documento de configuración demasiado grande",
+	},
+	"invalid config document: %s": {
+		Portuguese: "documento de configuração inválido: %s",
+		Spanish:    "documento de configuración no válido: %s",
+	},
+	"credential not found": {
+		Portuguese: "credencial não encontrada",
+		Spanish:    "credencial no encontrada",
+	},
+	"credential name must be 1-128 letters, digits, dots, dashes or underscores": {
+		Portuguese: "o nome da credencial deve ter de 1 a 128 letras, dígitos, pontos, hífens ou sublinhados",
+		Spanish:    "el nombre de la credencial debe tener de 1 a 128 letras, dígitos, puntos, guiones o guiones bajos",
+	},
+	"kind must be smtp, telegram, s3, google_service_account or generic": {
+		Portuguese: "kind deve ser smtp, telegram, s3, google_service_account ou generic",
+		Spanish:    "kind debe ser smtp, telegram, s3, google_service_account o generic",
+	},
+	"a google_service_account value must be the JSON key of the service account": {
+		Portuguese: "o valor de uma google_service_account deve ser a chave JSON da conta de serviço",
+		Spanish:    "el valor de una google_service_account debe ser la clave JSON de la cuenta de servicio",
+	},
+	"an s3 value must be a JSON object with access_key_id and secret_access_key": {
+		Portuguese: "o valor de uma credencial s3 deve ser um objeto JSON com access_key_id e secret_access_key",
+		Spanish:    "el valor de una credencial s3 debe ser un objeto JSON con access_key_id y secret_access_key",
+	},
+	"credential was sealed under another master key; save it again": {
+		Portuguese: "a credencial foi cifrada com outra chave mestra; salve-a novamente",
+		Spanish:    "la credencial se cifró con otra clave maestra; guárdala de nuevo",
+	},
+	"value is required": {
+		Portuguese: "value é obrigatório",
+		Spanish:    "value es obligatorio",
+	},
+	"too many wrong keys; try again later": {
+		Portuguese: "chaves incorretas demais; tente novamente mais tarde",
+		Spanish:    "demasiadas claves incorrectas; inténtalo de nuevo más tarde",
+	},
+	"cross-site request refused": {
+		Portuguese: "requisição de outro site recusada",
+		Spanish:    "solicitud de otro sitio rechazada",
+	},
+	"missing or invalid CSRF token": {
+		Portuguese: "token CSRF ausente ou inválido",
+		Spanish:    "token CSRF ausente o no válido",
+	},
+	"direction must be inbound or outbound": {
+		Portuguese: "direction deve ser inbound ou outbound",
+		Spanish:    "direction debe ser inbound u outbound",
+	},
+	"min_status must be a positive integer": {
+		Portuguese: "min_status deve ser um inteiro positivo",
+		Spanish:    "min_status debe ser un entero positivo",
+	},
+	"scheduled task not found": {
+		Portuguese: "tarefa agendada não encontrada",
+		Spanish:    "tarea programada no encontrada",
+	},
+	"invalid schedule; use a cron expression such as \"*/15 * * * *\" or @every 10m": {
+		Portuguese: "agendamento inválido; use uma expressão cron como \"*/15 * * * *\" ou @every 10m",
+		Spanish:    "programación no válida; use una expresión cron como \"*/15 * * * *\" o @every 10m",
+	},
+}
@@ -0,0 +1,266 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"melibot/database"
+	"melibot/internal/repository"
+	"melibot/internal/retry"
+	"melibot/internal/service"
+)
+
+const (
+	defaultPollInterval = 2 * time.Second
+	defaultJobTimeout   = 10 * time.Minute
+	defaultMaxAttempts  = 3
+	baseRetryDelay      = 5 * time.Second
+	// A running job's lease is renewed every third of jobLease; jobs of an
+	// instance that stopped renewing are requeued once it expires
+	jobLease = time.Minute
+)
+
+// HandlerFunc executes a job. The returned value is stored as the job result.
+type HandlerFunc func(ctx context.Context, payload json.RawMessage) (interface{}, error)
+
+// Queue runs persisted jobs on a pool of worker goroutines. Several
+// instances may share the queue: each claims jobs under its own name and
+// only requeues the jobs whose lease expired.
+type Queue struct {
+	repo     *repository.JobRepository
+	owner    string
+	workers  int
+	handlers map[string]HandlerFunc
+	wake     chan struct{}
+	mu       sync.RWMutex
+}
+
+func NewQueue(repo *repository.JobRepository, workers int) *Queue {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Queue{
+		repo:     repo,
+		owner:    service.InstanceID(),
+		workers:  workers,
+		handlers: make(map[string]HandlerFunc),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register associates a job type with the function that executes it.
+// It must be called before Start.
+func (q *Queue) Register(jobType string, fn HandlerFunc) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = fn
+}
+
+// Enqueue persists a new job and wakes an idle worker.
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}) (*repository.Job, error) {
+	q.mu.RLock()
+	_, ok := q.handlers[jobType]
+	q.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("jobs: unknown job type %q", jobType)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &repository.Job{
+		Type:        jobType,
+		Status:      repository.JobStatusQueued,
+		Payload:     string(body),
+		MaxAttempts: defaultMaxAttempts,
+		RunAt:       time.Now(),
+	}
+	if err := q.repo.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Get returns a job by ID, or nil if it does not exist.
+func (q *Queue) Get(ctx context.Context, id uint) (*repository.Job, error) {
+	return q.repo.FindByID(ctx, id)
+}
+
+// Start launches the workers, and requeues the jobs abandoned by stopped
+// instances now and then. Workers stop when ctx is cancelled. In
+// multi-tenant mode ctx must come from database.AllOrgs.
+func (q *Queue) Start(ctx context.Context) {
+	go q.requeueExpired(ctx)
+	for i := 0; i < q.workers; i++ {
+		go q.work(ctx)
+	}
+	log.Printf("[INFO] jobs: started %d workers", q.workers)
+}
+
+func (q *Queue) requeueExpired(ctx context.Context) {
+	ticker := time.NewTicker(jobLease)
+	defer ticker.Stop()
+	for {
+		// Jobs of this instance are renewed while they run, so only the
+		// ones of instances that stopped are requeued
+		requeued, failed, err := q.repo.RequeueExpired(ctx, defaultJobTimeout)
+		if err != nil {
+			log.Printf("[ERROR] jobs: failed to requeue interrupted jobs: %v", err)
+		}
+		if requeued > 0 {
+			log.Printf("[INFO] jobs: requeued %d interrupted jobs", requeued)
+		}
+		if failed > 0 {
+			log.Printf("[WARN] jobs: failed %d jobs interrupted on their last attempt", failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) types() []string {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	types := make([]string, 0, len(q.handlers))
+	for t := range q.handlers {
+		types = append(types, t)
+	}
+	return types
+}
+
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(defaultPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain all due jobs before sleeping again.
+		for {
+			job, err := q.repo.ClaimNext(ctx, q.types(), q.owner, jobLease)
+			if err != nil {
+				log.Printf("[ERROR] jobs: failed to claim job: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			q.run(ctx, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job *repository.Job) {
+	q.mu.RLock()
+	fn := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	// In multi-tenant mode workers claim jobs of every organization, see
+	// Start, and each one runs for its own
+	if database.MultiTenant() {
+		ctx = database.WithOrg(ctx, job.OrgID)
+	}
+	jobCtx, cancel := context.WithTimeout(ctx, defaultJobTimeout)
+	go q.renewLease(jobCtx, job.ID)
+	var result interface{}
+	// A panicking job fails alone instead of taking the worker down
+	err := retry.Run(func() error {
+		var err error
+		result, err = fn(jobCtx, json.RawMessage(job.Payload))
+		return err
+	})
+	cancel()
+
+	now := time.Now()
+	job.PanicStack = ""
+	if err != nil {
+		job.LastError = err.Error()
+		job.ErrorClass = retry.Class(err)
+		var panicked *retry.PanicError
+		if errors.As(err, &panicked) {
+			job.PanicStack = string(panicked.Stack)
+			log.Printf("[ERROR] jobs: job %d (%s) panicked: %v\n%s", job.ID, job.Type, panicked.Value, panicked.Stack)
+		}
+		if retry.Retryable(err) && job.Attempts < job.MaxAttempts {
+			// Exponential backoff: 5s, 10s, 20s...
+			job.Status = repository.JobStatusQueued
+			job.RunAt = now.Add(retry.Delay(baseRetryDelay, job.Attempts))
+			log.Printf("[WARN] jobs: job %d (%s) failed on attempt %d/%d, retrying: %v", job.ID, job.Type, job.Attempts, job.MaxAttempts, err)
+		} else {
+			job.Status = repository.JobStatusFailed
+			job.FinishedAt = &now
+			log.Printf("[ERROR] jobs: job %d (%s) failed permanently (%s): %v", job.ID, job.Type, job.ErrorClass, err)
+		}
+	} else {
+		body, mErr := json.Marshal(result)
+		if mErr != nil {
+			job.Status = repository.JobStatusFailed
+			job.LastError = "encode result: " + mErr.Error()
+			job.ErrorClass = retry.Class(retry.Fatal(mErr))
+		} else {
+			job.Status = repository.JobStatusSucceeded
+			job.Result = string(body)
+			job.LastError = ""
+			job.ErrorClass = ""
+		}
+		job.FinishedAt = &now
+	}
+	job.Owner = ""
+	job.LeaseUntil = nil
+
+	// Use a context without cancellation so results are recorded even
+	// during shutdown. A job whose lease lapsed was requeued and may be
+	// running elsewhere; its state is the new owner's to record.
+	ok, err := q.repo.Finish(context.WithoutCancel(ctx), job, q.owner)
+	if err != nil {
+		log.Printf("[ERROR] jobs: failed to save job %d: %v", job.ID, err)
+	} else if !ok {
+		log.Printf("[WARN] jobs: lost the lease of job %d, its outcome was discarded", job.ID)
+	}
+}
+
+// renewLease keeps the lease of a running job until ctx is done. When the
+// lease was lost, the job was requeued and may run elsewhere, which is
+// logged rather than cancelled: jobs are retried anyway, so they must be
+// safe to run twice.
+func (q *Queue) renewLease(ctx context.Context, id uint) {
+	ticker := time.NewTicker(jobLease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		renewCtx, cancel := context.WithTimeout(ctx, jobLease/3)
+		ok, err := q.repo.RenewLease(renewCtx, id, q.owner, jobLease)
+		cancel()
+		if err != nil {
+			log.Printf("[WARN] jobs: failed to renew the lease of job %d: %v", id, err)
+		} else if !ok {
+			log.Printf("[WARN] jobs: lost the lease of job %d", id)
+			return
+		}
+	}
+}
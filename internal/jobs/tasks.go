@@ -0,0 +1,193 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"melibot/internal/cache"
+	"melibot/internal/events"
+	"melibot/internal/repository"
+	"melibot/internal/retry"
+	"melibot/internal/service"
+	"melibot/internal/storage"
+	"melibot/pkg/meli"
+)
+
+// Job types.
+const (
+	TypeTopTrends          = "top_trends"
+	TypeCatalogEligibility = "catalog_eligibility"
+	TypeCategoryAudit      = "category_audit"
+	TypeCategoryCrawl      = "category_crawl"
+	TypeSupplierScreening  = "supplier_screening"
+	TypeWebhookDelivery    = "webhook_delivery"
+	TypeExport             = "export"
+)
+
+// crawlHTTPTimeout is the per-request timeout used by crawl jobs.
+const crawlHTTPTimeout = 30 * time.Second
+
+// TopTrendsPayload is the payload of a TypeTopTrends job.
+type TopTrendsPayload struct {
+	CategoryID string `json:"category_id"`
+	Limit      int    `json:"limit"`
+}
+
+// CategoryCrawlPayload is the payload of a TypeCategoryCrawl job.
+type CategoryCrawlPayload struct {
+	CategoryID string `json:"category_id"`
+}
+
+// CategoryAuditPayload is the payload of a TypeCategoryAudit job.
+type CategoryAuditPayload struct {
+	MinConfidence float64 `json:"min_confidence"`
+}
+
+// SupplierScreeningPayload is the payload of a TypeSupplierScreening job.
+type SupplierScreeningPayload struct {
+	Filename string                `json:"filename,omitempty"`
+	Rows     []service.SupplierRow `json:"rows"`
+}
+
+// Deps holds what the built-in tasks need to run outside of an HTTP request.
+type Deps struct {
+	// NewMeliClient returns a client authenticated with the token of the
+	// organization of ctx, or the current token outside multi-tenant mode.
+	NewMeliClient func(ctx context.Context, opts ...meli.Option) *meli.MeliClient
+	TrendRepo     *repository.TrendRepository
+	StatsRepo     *repository.CategoryStatsRepository
+	OrderRepo     *repository.OrderRepository
+	Cache         *cache.Cache
+	Bus           *events.Bus
+	Webhooks      *service.OutboundWebhookService
+	// Exports is where export jobs upload their files, nil when object
+	// storage is not configured; ExportURLTTL is how long their signed
+	// URLs last.
+	Exports      *storage.Bucket
+	ExportURLTTL time.Duration
+	// Quota refuses crawls once the background share of the request
+	// budget is used; nil leaves them unbounded.
+	Quota *service.QuotaService
+}
+
+// checkBudget refuses non-essential work once the account of client has
+// used the background share of its request budget.
+func (d Deps) checkBudget(ctx context.Context, client *meli.MeliClient) error {
+	if d.Quota == nil {
+		return nil
+	}
+	return d.Quota.CheckBackground(ctx, client)
+}
+
+// RegisterTasks registers the built-in job types on the queue.
+func RegisterTasks(q *Queue, deps Deps) {
+	q.Register(TypeTopTrends, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var p TopTrendsPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil, err
+		}
+		if p.CategoryID == "" {
+			return nil, retry.Fatal(errors.New("category_id is required"))
+		}
+		svc := service.NewMarketingService(deps.NewMeliClient(ctx), deps.TrendRepo, deps.Cache, deps.Bus)
+		return svc.TopTrendsByCategory(ctx, p.CategoryID, p.Limit)
+	})
+
+	q.Register(TypeCatalogEligibility, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		svc := service.NewSellerService(deps.NewMeliClient(ctx))
+		return svc.CatalogEligibilityReport(ctx)
+	})
+
+	q.Register(TypeCategoryAudit, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var p CategoryAuditPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil, err
+		}
+		if p.MinConfidence <= 0 || p.MinConfidence > 1 {
+			p.MinConfidence = service.DefaultAuditConfidence
+		}
+		// One predictor call per listing: it counts as a crawl.
+		client := deps.NewMeliClient(ctx, meli.WithTimeout(crawlHTTPTimeout))
+		if err := deps.checkBudget(ctx, client); err != nil {
+			return nil, err
+		}
+		return service.NewSellerService(client).CategoryAudit(ctx, p.MinConfidence)
+	})
+
+	q.Register(TypeCategoryCrawl, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var p CategoryCrawlPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil, err
+		}
+		if p.CategoryID == "" {
+			return nil, retry.Fatal(errors.New("category_id is required"))
+		}
+		// Crawls page through slow search results; give them more room than
+		// interactive requests.
+		client := deps.NewMeliClient(ctx, meli.WithTimeout(crawlHTTPTimeout))
+		if err := deps.checkBudget(ctx, client); err != nil {
+			return nil, err
+		}
+		svc := service.NewCategoryStatsService(client, deps.StatsRepo)
+		return svc.CrawlCategory(ctx, p.CategoryID)
+	})
+
+	q.Register(TypeSupplierScreening, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var p SupplierScreeningPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil, err
+		}
+		if len(p.Rows) == 0 {
+			return nil, retry.Fatal(errors.New("rows are required"))
+		}
+		svc := service.NewScreeningService(deps.NewMeliClient(ctx))
+		return svc.ScreenSupplierCatalog(ctx, p.Rows)
+	})
+
+	q.Register(TypeWebhookDelivery, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var d service.WebhookDelivery
+		if err := json.Unmarshal(payload, &d); err != nil {
+			return nil, err
+		}
+		if deps.Webhooks == nil {
+			return nil, retry.Fatal(errors.New("outbound webhooks are not configured"))
+		}
+		return nil, deps.Webhooks.Deliver(ctx, d)
+	})
+
+	q.Register(TypeExport, func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+		var req service.ExportRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, err
+		}
+		client := deps.NewMeliClient(ctx, meli.WithTimeout(crawlHTTPTimeout))
+		if req.Kind == service.ExportCategoryListings {
+			if err := deps.checkBudget(ctx, client); err != nil {
+				return nil, err
+			}
+		}
+		svc := service.NewExportService(client, deps.TrendRepo, deps.OrderRepo, deps.Exports, deps.ExportURLTTL)
+		return svc.Export(ctx, req)
+	})
+}
+
+// ForwardEvents enqueues a TypeWebhookDelivery job for every subscription
+// interested in each event published on bus, so slow or failing receivers
+// are retried by the queue instead of blocking the publisher.
+func ForwardEvents(q *Queue, bus *events.Bus, webhooks *service.OutboundWebhookService) {
+	bus.SubscribeAll(func(ctx context.Context, e events.Event) {
+		deliveries, err := webhooks.Prepare(ctx, e)
+		if err != nil {
+			log.Printf("[ERROR] webhooks: %s: %v", e.EventName(), err)
+			return
+		}
+		for _, d := range deliveries {
+			if _, err := q.Enqueue(ctx, TypeWebhookDelivery, d); err != nil {
+				log.Printf("[ERROR] webhooks: enqueue %s for subscription %d: %v", e.EventName(), d.SubscriptionID, err)
+			}
+		}
+	})
+}
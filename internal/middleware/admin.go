@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+)
+
+// RequireAdmin protects operator endpoints with a shared key sent in the
+// X-Admin-Key header. When no key is configured the endpoints are disabled.
+// Wrong keys count towards lockout, which may be nil.
+func RequireAdmin(adminKey string, lockout *Lockout) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": i18n.Tr(c, "admin endpoints disabled; set ADMIN_API_KEY")})
+			return
+		}
+		if wait := lockout.locked(c.Request.Context(), c.ClientIP()); wait > 0 {
+			rejectLocked(c, wait)
+			return
+		}
+		if !IsAdmin(c, adminKey) {
+			if c.GetHeader("X-Admin-Key") != "" {
+				lockout.fail(c.Request.Context(), c.ClientIP(), accountAdmin)
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": i18n.Tr(c, "invalid admin key")})
+			return
+		}
+		setActor(c, accountAdmin)
+		c.Next()
+	}
+}
+
+// IsAdmin reports whether the request carries the admin key, for handlers that
+// unlock extra behaviour for operators without being admin-only.
+func IsAdmin(c *gin.Context, adminKey string) bool {
+	if adminKey == "" {
+		return false
+	}
+	got := c.GetHeader("X-Admin-Key")
+	return subtle.ConstantTimeCompare([]byte(got), []byte(adminKey)) == 1
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/capture"
+	"melibot/internal/repository"
+)
+
+const (
+	// maxAuditResult caps how much of an error response is kept in the
+	// audit log.
+	maxAuditResult = 1024
+	// maxAuditPayload caps how much of a payload is read for its digest;
+	// larger payloads reach the handler untouched and get no digest.
+	maxAuditPayload = 1 << 20
+)
+
+// actorKey is the gin context key under which the auth middlewares record
+// the identity they validated.
+const actorKey = "audit_actor"
+
+// Audit records every mutating request (POST, PUT, PATCH, DELETE) in the
+// audit log with the caller, a digest of the payload and the outcome: the
+// status and, for errors only, the redacted beginning of the response, since
+// successful ones may carry secrets such as new API keys.
+func Audit(repo *repository.AuditRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+
+		var digest string
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuditPayload+1))
+			if err == nil && len(body) > 0 && len(body) <= maxAuditPayload {
+				sum := sha256.Sum256(body)
+				digest = hex.EncodeToString(sum[:])
+			}
+			c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+		}
+
+		rec := &resultRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+
+		c.Next()
+
+		entry := &repository.AuditLog{
+			Actor:         AuditActor(c),
+			ClientIP:      c.ClientIP(),
+			Method:        c.Request.Method,
+			Path:          c.Request.URL.Path,
+			Route:         c.FullPath(),
+			PayloadDigest: digest,
+			Status:        c.Writer.Status(),
+			Result:        rec.result(c.Writer.Status(), c.Writer.Header().Get("Content-Type")),
+			DurationMs:    time.Since(start).Milliseconds(),
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := repo.Create(ctx, entry); err != nil {
+			log.Printf("[ERROR] audit: failed to record %s %s: %v", entry.Method, entry.Path, err)
+		}
+	}
+}
+
+// AuditActor describes who made the request, as validated by RequireAdmin or
+// RequireOrg: "admin", "org:<id>", or anonymous. Headers and cookies the
+// client sets are not trusted.
+func AuditActor(c *gin.Context) string {
+	if actor := c.GetString(actorKey); actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+// setActor records the identity an auth middleware validated.
+func setActor(c *gin.Context, actor string) {
+	c.Set(actorKey, actor)
+}
+
+// readCloser reads the audited beginning of a payload and then the rest,
+// closing the original body.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// resultRecorder keeps the beginning of the response body so the outcome of
+// a failed request (e.g. an upstream error message) lands in the audit log.
+type resultRecorder struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (r *resultRecorder) Write(b []byte) (int, error) {
+	if room := maxAuditResult - r.buf.Len(); room > 0 {
+		r.buf.Write(b[:min(room, len(b))])
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *resultRecorder) WriteString(s string) (int, error) {
+	return r.Write([]byte(s))
+}
+
+// result returns the redacted beginning of an error response, empty for
+// successful ones.
+func (r *resultRecorder) result(status int, contentType string) string {
+	if status < http.StatusBadRequest {
+		return ""
+	}
+	return capture.RedactBody(r.buf.Bytes(), contentType)
+}
@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// CompressionConfig controls the Compress middleware.
+type CompressionConfig struct {
+	// Level is the compression level (1-9 for gzip, mapped to 0-11 for brotli).
+	Level int
+	// MinSize is the smallest body, in bytes, worth compressing.
+	MinSize int
+	// ExcludePaths are path prefixes that are never compressed.
+	ExcludePaths []string
+}
+
+// compressibleTypes are the content types worth compressing.
+var compressibleTypes = []string{
+	"application/json",
+	"application/javascript",
+	"image/svg+xml",
+	"text/",
+}
+
+// Compress encodes responses with brotli or gzip, depending on the client's
+// Accept-Encoding. The body is buffered until MinSize bytes are written so
+// small responses go out untouched. Server-sent events (text/event-stream)
+// and flushed responses are never compressed.
+func Compress(cfg CompressionConfig) gin.HandlerFunc {
+	if cfg.Level < gzip.BestSpeed || cfg.Level > gzip.BestCompression {
+		cfg.Level = 5
+	}
+	if cfg.MinSize <= 0 {
+		cfg.MinSize = 1024
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || isExcluded(c.Request.URL.Path, cfg.ExcludePaths) {
+			c.Next()
+			return
+		}
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, cfg: cfg, encoding: encoding}
+		c.Writer = w
+		defer w.close()
+
+		c.Next()
+	}
+}
+
+func isExcluded(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding picks br over gzip when both are acceptable.
+func negotiateEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, f := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(f), "q="); ok {
+				if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		accepted[name] = q > 0
+	}
+
+	switch {
+	case accepted["br"]:
+		return "br"
+	case accepted["gzip"]:
+		return "gzip"
+	}
+	return ""
+}
+
+// compressWriter buffers the beginning of the body to decide whether the
+// response is worth compressing.
+type compressWriter struct {
+	gin.ResponseWriter
+	cfg      CompressionConfig
+	encoding string
+	buf      bytes.Buffer
+	enc      io.WriteCloser
+	decided  bool
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	n, _ := w.buf.Write(b)
+	if w.buf.Len() >= w.cfg.MinSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush disables compression for streaming responses that were not yet
+// decided, and flushes the encoder otherwise.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if f, ok := w.enc.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return w.ResponseWriter.Hijack()
+}
+
+// decide picks compressed or plain output and writes the buffered bytes.
+func (w *compressWriter) decide(bigEnough bool) error {
+	w.decided = true
+
+	if bigEnough && w.shouldCompress() {
+		h := w.Header()
+		h.Set("Content-Encoding", w.encoding)
+		h.Add("Vary", "Accept-Encoding")
+		h.Del("Content-Length")
+
+		if w.encoding == "br" {
+			w.enc = brotli.NewWriterLevel(w.ResponseWriter, w.cfg.Level*brotli.BestCompression/gzip.BestCompression)
+		} else {
+			gz, err := gzip.NewWriterLevel(w.ResponseWriter, w.cfg.Level)
+			if err != nil {
+				return err
+			}
+			w.enc = gz
+		}
+	}
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+func (w *compressWriter) shouldCompress() bool {
+	status := w.Status()
+	if status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent || status < http.StatusOK {
+		return false
+	}
+
+	h := w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	ct := strings.ToLower(h.Get("Content-Type"))
+	if strings.HasPrefix(ct, "text/event-stream") {
+		return false
+	}
+	for _, t := range compressibleTypes {
+		if strings.HasPrefix(ct, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		_ = w.decide(false)
+	}
+	if w.enc != nil {
+		_ = w.enc.Close()
+	}
+}
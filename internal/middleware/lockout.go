@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/i18n"
+	"melibot/internal/repository"
+)
+
+// accountAdmin is the account of the admin key in the failure counters.
+const accountAdmin = "admin"
+
+// LockoutConfig controls Lockout.
+type LockoutConfig struct {
+	// MaxFailures is how many wrong keys a client IP may send within Window
+	// before it is locked out.
+	MaxFailures int
+	// AccountMaxFailures is how many wrong admin keys may arrive within
+	// Window, from any IP, before guessing spread over many addresses is
+	// logged as a security warning. The key itself is never locked, so
+	// nobody can lock the operator out by sending wrong keys.
+	AccountMaxFailures int
+	Window             time.Duration
+	Lockout            time.Duration
+}
+
+// Lockout throttles guessing of the admin and organization keys: client IPs
+// sending too many wrong keys are locked out for a while, and lockouts and
+// wrong admin keys piling up across addresses are logged as security
+// warnings. Counters live in the database, so every instance counts the
+// same failures, and expire on their own.
+type Lockout struct {
+	cfg  LockoutConfig
+	repo *repository.LockoutRepository
+}
+
+func NewLockout(cfg LockoutConfig, repo *repository.LockoutRepository) *Lockout {
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = 10
+	}
+	if cfg.AccountMaxFailures <= 0 {
+		cfg.AccountMaxFailures = 50
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 15 * time.Minute
+	}
+	if cfg.Lockout <= 0 {
+		cfg.Lockout = 15 * time.Minute
+	}
+	return &Lockout{cfg: cfg, repo: repo}
+}
+
+// locked returns how long the client IP stays locked out; 0 when it is not.
+// A nil Lockout never locks. When the counters cannot be read the request
+// goes on: the key is still checked.
+func (l *Lockout) locked(ctx context.Context, ip string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	wait, err := l.repo.Locked(ctx, "ip:"+ip)
+	if err != nil {
+		log.Printf("[ERROR] security: failed to read the lockout of %s: %v", ip, err)
+		return 0
+	}
+	return wait
+}
+
+// fail records a wrong key from a client IP, for the account when set. The
+// account is only watched: crossing its limit logs a warning, at most once
+// per lockout period, and locks nothing.
+func (l *Lockout) fail(ctx context.Context, ip, account string) {
+	if l == nil {
+		return
+	}
+	// Counted even when the client gives up on the request
+	ctx = context.WithoutCancel(ctx)
+	lockedNow, err := l.repo.Fail(ctx, "ip:"+ip, l.cfg.MaxFailures, l.cfg.Window, l.cfg.Lockout)
+	if err != nil {
+		log.Printf("[ERROR] security: failed to count a wrong key from %s: %v", ip, err)
+	} else if lockedNow {
+		log.Printf("[WARN] security: %s locked out for %s after %d wrong keys", ip, l.cfg.Lockout, l.cfg.MaxFailures)
+	}
+	if account == "" {
+		return
+	}
+	// For an account, the lock only holds back the next warning
+	lockedNow, err = l.repo.Fail(ctx, "account:"+account, l.cfg.AccountMaxFailures, l.cfg.Window, l.cfg.Lockout)
+	if err != nil {
+		log.Printf("[ERROR] security: failed to count a wrong %s key: %v", account, err)
+	} else if lockedNow {
+		log.Printf("[WARN] security: %d wrong %s keys within %s from several addresses, last %s; the key may be under a distributed guessing attack", l.cfg.AccountMaxFailures, account, l.cfg.Window, ip)
+	}
+}
+
+// rejectLocked answers 429 to a locked out client, telling it when to
+// retry.
+func rejectLocked(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": i18n.Tr(c, "too many wrong keys; try again later")})
+}
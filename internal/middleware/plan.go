@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/database"
+	"melibot/internal/i18n"
+)
+
+// PlanLimit is what the subscription plan of an organization allows.
+type PlanLimit struct {
+	Plan    string
+	Status  string
+	Allowed bool
+	// RPS and Burst rate limit the organization; zero leaves it to the
+	// per-client limit only.
+	RPS   float64
+	Burst int
+}
+
+// PlanLookup returns the plan limits of the organization of ctx.
+type PlanLookup func(ctx context.Context) (PlanLimit, error)
+
+// PlanLimits enforces subscription plans; it goes after RequireOrg.
+// Organizations whose plan lapsed, past its grace period, get 402, and the
+// others share one token bucket per organization sized by their plan.
+// Requests not scoped to one organization, i.e. operators', pass.
+func PlanLimits(lookup PlanLookup) gin.HandlerFunc {
+	var mu sync.Mutex
+	limiters := make(map[string]*rateLimiter)
+	limiter := func(limit PlanLimit) *rateLimiter {
+		mu.Lock()
+		defer mu.Unlock()
+		key := limit.Plan + ":" + strconv.FormatFloat(limit.RPS, 'f', -1, 64) + ":" + strconv.Itoa(limit.Burst)
+		rl, ok := limiters[key]
+		if !ok {
+			rl = newRateLimiter(RateLimitConfig{RPS: limit.RPS, Burst: limit.Burst})
+			limiters[key] = rl
+		}
+		return rl
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		orgID, ok := database.OrgFromContext(ctx)
+		if !ok {
+			c.Next()
+			return
+		}
+		limit, err := lookup(ctx)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !limit.Allowed {
+			c.AbortWithStatusJSON(http.StatusPaymentRequired, gin.H{
+				"error":  i18n.Tr(c, "subscription plan inactive; renew it to continue"),
+				"plan":   limit.Plan,
+				"status": limit.Status,
+			})
+			return
+		}
+		if limit.RPS > 0 && limit.Burst > 0 {
+			if ok, wait := limiter(limit).allow("org:"+strconv.FormatUint(uint64(orgID), 10), time.Now()); !ok {
+				rejectRate(c, wait)
+				return
+			}
+		}
+		c.Next()
+	}
+}
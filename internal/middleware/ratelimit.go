@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/database"
+	"melibot/internal/i18n"
+)
+
+// RateLimitConfig controls the RateLimit middleware.
+type RateLimitConfig struct {
+	// RPS is the sustained number of requests per second allowed per client.
+	RPS float64
+	// Burst is the bucket size, i.e. how many requests may arrive at once.
+	Burst int
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// rateLimiter keeps one token bucket per client key.
+type rateLimiter struct {
+	mu        sync.Mutex
+	cfg       RateLimitConfig
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// idleBucketTTL is how long an untouched bucket is kept before being dropped.
+const idleBucketTTL = 10 * time.Minute
+
+// RateLimit limits inbound requests with a token bucket per client, see
+// ClientKey; it goes after RequireOrg. Rejected requests get 429 with a
+// Retry-After header.
+func RateLimit(cfg RateLimitConfig) gin.HandlerFunc {
+	return NewRateLimiter(cfg).Handler()
+}
+
+// RateLimiter is a RateLimit middleware whose limits can change at runtime.
+type RateLimiter struct {
+	rl *rateLimiter
+}
+
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	return &RateLimiter{rl: newRateLimiter(cfg.withDefaults())}
+}
+
+// Handler returns the middleware.
+func (l *RateLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, wait := l.rl.allow(ClientKey(c), time.Now())
+		if !ok {
+			rejectRate(c, wait)
+			return
+		}
+		c.Next()
+	}
+}
+
+// SetConfig changes the limits; clients keep the tokens they have, up to
+// the new burst.
+func (l *RateLimiter) SetConfig(cfg RateLimitConfig) {
+	l.rl.mu.Lock()
+	defer l.rl.mu.Unlock()
+	l.rl.cfg = cfg.withDefaults()
+}
+
+func (cfg RateLimitConfig) withDefaults() RateLimitConfig {
+	if cfg.RPS <= 0 {
+		cfg.RPS = 5
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = int(math.Ceil(cfg.RPS * 2))
+	}
+	return cfg
+}
+
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	return &rateLimiter{cfg: cfg, buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+// rejectRate answers 429, telling the client when to retry.
+func rejectRate(c *gin.Context, wait time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": i18n.Tr(c, "rate limit exceeded, slow down")})
+}
+
+// ClientKey identifies the caller for per-client accounting: the
+// organization RequireOrg resolved from a valid key, otherwise the client
+// IP. Unvalidated headers are not used, as a new value on every request
+// would get a new bucket every time.
+func ClientKey(c *gin.Context) string {
+	if orgID, ok := database.OrgFromContext(c.Request.Context()); ok {
+		return "org:" + strconv.FormatUint(uint64(orgID), 10)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// allow consumes a token for key, or reports how long until one is available.
+func (rl *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) > idleBucketTTL {
+		for k, b := range rl.buckets {
+			if now.Sub(b.lastSeen) > idleBucketTTL {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rl.cfg.Burst), lastSeen: now}
+		rl.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(rl.cfg.Burst), b.tokens+now.Sub(b.lastSeen).Seconds()*rl.cfg.RPS)
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / rl.cfg.RPS * float64(time.Second))
+	return false, wait
+}
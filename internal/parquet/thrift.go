@@ -0,0 +1,96 @@
+package parquet
+
+import "encoding/binary"
+
+// Thrift compact protocol types, as used by the Parquet footer and page
+// headers.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes Thrift structs with the compact protocol. Fields must
+// be written in increasing ID order within each struct.
+type thriftWriter struct {
+	buf    []byte
+	lastID []int16
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	last := &t.lastID[len(t.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		t.buf = append(t.buf, byte(delta)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.varint(int64(id))
+	}
+	*last = id
+}
+
+func (t *thriftWriter) varint(v int64) {
+	t.buf = binary.AppendUvarint(t.buf, uint64((v<<1)^(v>>63)))
+}
+
+func (t *thriftWriter) beginStruct() {
+	t.lastID = append(t.lastID, 0)
+}
+
+func (t *thriftWriter) endStruct() {
+	t.buf = append(t.buf, 0)
+	t.lastID = t.lastID[:len(t.lastID)-1]
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.varint(int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) string(id int16, v string) {
+	t.fieldHeader(id, thriftBinary)
+	t.binary(v)
+}
+
+func (t *thriftWriter) binary(v string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(v)))
+	t.buf = append(t.buf, v...)
+}
+
+// field starts a struct-typed field; close it with endStruct.
+func (t *thriftWriter) field(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.beginStruct()
+}
+
+// list starts a list field of n elements of typ. Struct elements are
+// written with beginStruct/endStruct.
+func (t *thriftWriter) list(id int16, typ byte, n int) {
+	t.fieldHeader(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|typ)
+	} else {
+		t.buf = append(t.buf, 0xf0|typ)
+		t.buf = binary.AppendUvarint(t.buf, uint64(n))
+	}
+}
+
+func (t *thriftWriter) listI32(id int16, vs ...int32) {
+	t.list(id, thriftI32, len(vs))
+	for _, v := range vs {
+		t.varint(int64(v))
+	}
+}
+
+func (t *thriftWriter) listString(id int16, vs ...string) {
+	t.list(id, thriftBinary, len(vs))
+	for _, v := range vs {
+		t.binary(v)
+	}
+}
@@ -0,0 +1,313 @@
+// Package parquet writes flat structs as Parquet files: one required
+// column per exported field, named after its json tag, PLAIN encoded and
+// gzip-compressed. It covers what the exports need, not the whole format.
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DefaultRowGroupSize is how many rows a row group holds.
+const DefaultRowGroupSize = 100_000
+
+const magic = "PAR1"
+
+// Parquet physical types.
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+)
+
+// Parquet enums the writer uses.
+const (
+	convertedNone            = -1
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+	repetitionRequired       = 0
+	encodingPlain            = 0
+	encodingRLE              = 3
+	codecGzip                = 2
+	pageTypeData             = 0
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type column struct {
+	name      string
+	index     []int
+	typ       int32
+	converted int32
+	values    bytes.Buffer
+	bools     []bool
+}
+
+type columnChunk struct {
+	offset       int64
+	uncompressed int64
+	compressed   int64
+}
+
+type rowGroup struct {
+	rows    int64
+	size    int64
+	columns []columnChunk
+}
+
+// Writer writes rows of T to a Parquet file. Close writes the footer; the
+// file is unreadable without it.
+type Writer[T any] struct {
+	w            io.Writer
+	offset       int64
+	columns      []*column
+	rows         int
+	groups       []rowGroup
+	RowGroupSize int
+}
+
+// NewWriter starts a Parquet file of T, a struct of string, integer,
+// float64, bool and time.Time fields.
+func NewWriter[T any](w io.Writer) (*Writer[T], error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("parquet: %s is not a struct", t)
+	}
+	columns, err := columnsOf(t, nil)
+	if err != nil {
+		return nil, err
+	}
+	pw := &Writer[T]{w: w, columns: columns, RowGroupSize: DefaultRowGroupSize}
+	if err := pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func columnsOf(t reflect.Type, index []int) ([]*column, error) {
+	var columns []*column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			embedded, err := columnsOf(f.Type, idx)
+			if err != nil {
+				return nil, err
+			}
+			columns = append(columns, embedded...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		c := &column{name: name, index: idx, converted: convertedNone}
+		switch {
+		case f.Type == timeType:
+			c.typ, c.converted = typeInt64, convertedTimestampMillis
+		case f.Type.Kind() == reflect.String:
+			c.typ, c.converted = typeByteArray, convertedUTF8
+		case f.Type.Kind() == reflect.Bool:
+			c.typ = typeBoolean
+		case f.Type.Kind() == reflect.Float64 || f.Type.Kind() == reflect.Float32:
+			c.typ = typeDouble
+		case f.Type.Kind() >= reflect.Int && f.Type.Kind() <= reflect.Uint64:
+			c.typ = typeInt64
+		default:
+			return nil, fmt.Errorf("parquet: field %s has unsupported type %s", f.Name, f.Type)
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// Write appends rows, flushing a row group every RowGroupSize rows.
+func (w *Writer[T]) Write(rows ...T) error {
+	for i := range rows {
+		v := reflect.ValueOf(&rows[i]).Elem()
+		for _, c := range w.columns {
+			c.append(v.FieldByIndex(c.index))
+		}
+		w.rows++
+		if w.rows >= w.RowGroupSize {
+			if err := w.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *column) append(v reflect.Value) {
+	switch {
+	case c.converted == convertedTimestampMillis:
+		c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v.Interface().(time.Time).UnixMilli())))
+	case c.typ == typeByteArray:
+		s := v.String()
+		c.values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+		c.values.WriteString(s)
+	case c.typ == typeBoolean:
+		c.bools = append(c.bools, v.Bool())
+	case c.typ == typeDouble:
+		c.values.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v.Float())))
+	case v.CanInt():
+		c.values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v.Int())))
+	default:
+		c.values.Write(binary.LittleEndian.AppendUint64(nil, v.Uint()))
+	}
+}
+
+// page returns the PLAIN encoded values of the current row group.
+func (c *column) page() []byte {
+	if c.typ != typeBoolean {
+		return c.values.Bytes()
+	}
+	packed := make([]byte, (len(c.bools)+7)/8)
+	for i, b := range c.bools {
+		if b {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	return packed
+}
+
+func (c *column) reset() {
+	c.values.Reset()
+	c.bools = c.bools[:0]
+}
+
+// flush writes the buffered rows as a row group, one data page per column.
+func (w *Writer[T]) flush() error {
+	if w.rows == 0 {
+		return nil
+	}
+	group := rowGroup{rows: int64(w.rows)}
+	for _, c := range w.columns {
+		data := c.page()
+		var compressed bytes.Buffer
+		zw := gzip.NewWriter(&compressed)
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+
+		header := thriftWriter{}
+		header.beginStruct()
+		header.i32(1, pageTypeData)
+		header.i32(2, int32(len(data)))
+		header.i32(3, int32(compressed.Len()))
+		header.field(5)
+		header.i32(1, int32(w.rows))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		chunk := columnChunk{
+			offset:       w.offset,
+			uncompressed: int64(len(header.buf) + len(data)),
+			compressed:   int64(len(header.buf) + compressed.Len()),
+		}
+		if err := w.write(header.buf); err != nil {
+			return err
+		}
+		if err := w.write(compressed.Bytes()); err != nil {
+			return err
+		}
+		group.columns = append(group.columns, chunk)
+		group.size += chunk.uncompressed
+		c.reset()
+	}
+	w.groups = append(w.groups, group)
+	w.rows = 0
+	return nil
+}
+
+// Close flushes the last row group and writes the footer. It does not
+// close the underlying writer.
+func (w *Writer[T]) Close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+
+	var numRows int64
+	for _, g := range w.groups {
+		numRows += g.rows
+	}
+
+	meta := thriftWriter{}
+	meta.beginStruct()
+	meta.i32(1, 1)
+	meta.list(2, thriftStruct, len(w.columns)+1)
+	meta.beginStruct()
+	meta.string(4, "schema")
+	meta.i32(5, int32(len(w.columns)))
+	meta.endStruct()
+	for _, c := range w.columns {
+		meta.beginStruct()
+		meta.i32(1, c.typ)
+		meta.i32(3, repetitionRequired)
+		meta.string(4, c.name)
+		if c.converted != convertedNone {
+			meta.i32(6, c.converted)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, numRows)
+	meta.list(4, thriftStruct, len(w.groups))
+	for _, g := range w.groups {
+		meta.beginStruct()
+		meta.list(1, thriftStruct, len(g.columns))
+		for i, chunk := range g.columns {
+			c := w.columns[i]
+			meta.beginStruct()
+			meta.i64(2, chunk.offset)
+			meta.field(3)
+			meta.i32(1, c.typ)
+			meta.listI32(2, encodingPlain, encodingRLE)
+			meta.listString(3, c.name)
+			meta.i32(4, codecGzip)
+			meta.i64(5, g.rows)
+			meta.i64(6, chunk.uncompressed)
+			meta.i64(7, chunk.compressed)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64(2, g.size)
+		meta.i64(3, g.rows)
+		meta.endStruct()
+	}
+	meta.string(6, "melibot")
+	meta.endStruct()
+
+	if err := w.write(meta.buf); err != nil {
+		return err
+	}
+	footer := binary.LittleEndian.AppendUint32(nil, uint32(len(meta.buf)))
+	return w.write(append(footer, magic...))
+}
+
+func (w *Writer[T]) write(p []byte) error {
+	n, err := w.w.Write(p)
+	w.offset += int64(n)
+	return err
+}
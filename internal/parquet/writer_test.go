@@ -0,0 +1,148 @@
+package parquet
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+)
+
+type base struct {
+	ID int64 `json:"id"`
+}
+
+type exportRow struct {
+	base
+	Title    string    `json:"title"`
+	Quantity int       `json:"quantity"`
+	Price    float64   `json:"price"`
+	Active   bool      `json:"active"`
+	Sold     uint32    `json:"sold"`
+	Created  time.Time `json:"created_at"`
+	Internal string    `json:"-"`
+	hidden   string
+}
+
+// readRow is exportRow as parquet-go reads it back.
+type readRow struct {
+	ID       int64     `parquet:"id"`
+	Title    string    `parquet:"title"`
+	Quantity int64     `parquet:"quantity"`
+	Price    float64   `parquet:"price"`
+	Active   bool      `parquet:"active"`
+	Sold     int64     `parquet:"sold"`
+	Created  time.Time `parquet:"created_at,timestamp(millisecond)"`
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 8, 30, 0, 123_000_000, time.UTC)
+	var rows []exportRow
+	for i := 0; i < 25; i++ {
+		rows = append(rows, exportRow{
+			base:     base{ID: int64(2000001234567890 + i)},
+			Title:    "Fone Bluetooth ção " + string(rune('A'+i)),
+			Quantity: i - 3,
+			Price:    float64(i) * 10.25,
+			Active:   i%3 == 0,
+			Sold:     uint32(i * 7),
+			Created:  created.Add(time.Duration(i) * time.Hour),
+			Internal: "not exported",
+		})
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter[exportRow](&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Several row groups, the last one partial
+	w.RowGroupSize = 10
+	if err := w.Write(rows[:7]...); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(rows[7:]...); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := pq.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := f.NumRows(); n != int64(len(rows)) {
+		t.Errorf("rows = %d, want %d", n, len(rows))
+	}
+	if n := len(f.RowGroups()); n != 3 {
+		t.Errorf("row groups = %d, want 3", n)
+	}
+	var names []string
+	for _, field := range f.Schema().Fields() {
+		names = append(names, field.Name())
+	}
+	wantNames := []string{"id", "title", "quantity", "price", "active", "sold", "created_at"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("columns = %v, want %v", names, wantNames)
+	}
+
+	got, err := pq.Read[readRow](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(rows) {
+		t.Fatalf("read %d rows, want %d", len(got), len(rows))
+	}
+	for i, r := range rows {
+		want := readRow{
+			ID:       r.ID,
+			Title:    r.Title,
+			Quantity: int64(r.Quantity),
+			Price:    r.Price,
+			Active:   r.Active,
+			Sold:     int64(r.Sold),
+			Created:  r.Created,
+		}
+		g := got[i]
+		g.Created = g.Created.UTC()
+		if !reflect.DeepEqual(g, want) {
+			t.Errorf("row %d = %+v, want %+v", i, g, want)
+		}
+	}
+}
+
+func TestWriterEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter[exportRow](&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := pq.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := f.NumRows(); n != 0 {
+		t.Errorf("rows = %d, want 0", n)
+	}
+	if n := len(f.Schema().Fields()); n != 7 {
+		t.Errorf("columns = %d, want 7", n)
+	}
+}
+
+func TestNewWriterUnsupported(t *testing.T) {
+	if _, err := NewWriter[int](&bytes.Buffer{}); err == nil {
+		t.Error("NewWriter[int] succeeded")
+	}
+	type withSlice struct {
+		Tags []string `json:"tags"`
+	}
+	if _, err := NewWriter[withSlice](&bytes.Buffer{}); err == nil {
+		t.Error("NewWriter with a slice field succeeded")
+	}
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// AuditLog records a single mutating request: who made it, what it touched
+// and how it ended (including Mercado Livre errors surfaced by the handler).
+type AuditLog struct {
+	ID            uint   `gorm:"primaryKey"`
+	Actor         string `gorm:"size:128;index;not null"`
+	ClientIP      string `gorm:"size:64"`
+	Method        string `gorm:"size:8;not null"`
+	Path          string `gorm:"size:256;index;not null"`
+	Route         string `gorm:"size:256"`
+	PayloadDigest string `gorm:"size:64"`
+	Status        int    `gorm:"not null"`
+	Result        string `gorm:"type:text"`
+	DurationMs    int64
+	Sandbox       bool      `gorm:"not null;default:false"`
+	CreatedAt     time.Time `gorm:"index"`
+}
+
+// AuditFilter narrows audit log queries. Zero values are ignored.
+type AuditFilter struct {
+	Actor  string
+	Path   string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+}
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{
+		db: database.DB,
+	}
+}
+
+// Create persists an audit entry.
+func (r *AuditRepository) Create(ctx context.Context, entry *AuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// Find returns audit entries matching the filter, newest first.
+func (r *AuditRepository) Find(ctx context.Context, f AuditFilter) ([]AuditLog, error) {
+	q := r.db.WithContext(ctx).Model(&AuditLog{})
+	if f.Actor != "" {
+		q = q.Where("actor = ?", f.Actor)
+	}
+	if f.Path != "" {
+		q = q.Where("path LIKE ?", f.Path+"%")
+	}
+	if !f.From.IsZero() {
+		q = q.Where("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("created_at < ?", f.To)
+	}
+
+	var entries []AuditLog
+	err := q.Order("created_at DESC").Limit(f.Limit).Offset(f.Offset).Find(&entries).Error
+	return entries, err
+}
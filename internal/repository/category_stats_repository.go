@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// CategoryStats stores the price/sold distribution of a category computed by
+// a full crawl of the site search.
+type CategoryStats struct {
+	ID                uint     `gorm:"primaryKey"`
+	CategoryID        string   `gorm:"index;not null"`
+	TotalListings     int      `gorm:"not null"`
+	SampledListings   int      `gorm:"not null"`
+	PriceMin          float64  `gorm:"not null"`
+	PriceP25          float64  `gorm:"not null"`
+	PriceP50          float64  `gorm:"not null"`
+	PriceP75          float64  `gorm:"not null"`
+	PriceMax          float64  `gorm:"not null"`
+	SoldTotal         int      `gorm:"not null"`
+	SoldP50           float64  `gorm:"not null"`
+	FreeShippingShare float64  `gorm:"not null"`
+	TopSellerHealth   *float64 // average 0-1 health of the best sellers, nil when unknown
+	OrgID             uint     `gorm:"not null;default:0;index"`
+	Sandbox           bool     `gorm:"not null;default:false"`
+	CrawledAt         time.Time
+	CreatedAt         time.Time
+}
+
+type CategoryStatsRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryStatsRepository() *CategoryStatsRepository {
+	return &CategoryStatsRepository{
+		db: database.DB,
+	}
+}
+
+// Save persists a new stats record; history is kept for later comparison.
+func (r *CategoryStatsRepository) Save(ctx context.Context, stats *CategoryStats) error {
+	return r.db.WithContext(ctx).Create(stats).Error
+}
+
+// Latest returns the most recent stats for a category, or nil if the
+// category was never crawled.
+func (r *CategoryStatsRepository) Latest(ctx context.Context, categoryID string) (*CategoryStats, error) {
+	var stats CategoryStats
+	err := r.db.WithContext(ctx).
+		Where("category_id = ?", categoryID).
+		Order("crawled_at DESC").
+		First(&stats).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
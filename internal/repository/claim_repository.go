@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Claim is a buyer's claim about one of the seller's orders. The primary
+// key is Mercado Livre's claim ID. Returned is set when the claim led to a
+// return of the product.
+type Claim struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement:false"`
+	OrderID     int64     `gorm:"index;not null"`
+	SellerID    int64     `gorm:"index;not null"`
+	Type        string    `gorm:"size:32;index"`
+	Stage       string    `gorm:"size:32"`
+	Status      string    `gorm:"size:32"`
+	ReasonID    string    `gorm:"size:32"`
+	Returned    bool      `gorm:"not null;default:false"`
+	DateCreated time.Time `gorm:"index;not null"`
+	LastUpdated time.Time `gorm:"index"`
+	OrgID       uint      `gorm:"not null;default:0;index"`
+	Sandbox     bool      `gorm:"not null;default:false"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ClaimLine is an order line of one of the seller's orders, with a claim
+// about the order when there is one. An order with several claims has a
+// line per claim.
+type ClaimLine struct {
+	OrderID     int64
+	DateCreated time.Time
+	SKU         string
+	ItemID      string
+	Title       string
+	Quantity    int
+	ClaimID     *int64
+	ClaimType   string
+	ReasonID    string
+	Returned    bool
+}
+
+type ClaimRepository struct {
+	db *gorm.DB
+}
+
+func NewClaimRepository() *ClaimRepository {
+	return &ClaimRepository{
+		db: database.DB,
+	}
+}
+
+// Upsert inserts or updates a claim.
+func (r *ClaimRepository) Upsert(ctx context.Context, claim *Claim) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"type", "stage", "status", "reason_id", "returned", "last_updated", "updated_at"}),
+		}).
+		Create(claim).Error
+}
+
+// LastUpdated returns the most recent last_updated among a seller's stored
+// claims, or the zero time if there are none.
+func (r *ClaimRepository) LastUpdated(ctx context.Context, sellerID int64) (time.Time, error) {
+	var last *time.Time
+	err := r.db.WithContext(ctx).
+		Model(&Claim{}).
+		Select("MAX(last_updated)").
+		Where("seller_id = ?", sellerID).
+		Scan(&last).Error
+	if err != nil || last == nil {
+		return time.Time{}, err
+	}
+	return *last, nil
+}
+
+// ClaimLines returns the lines of a seller's orders created in [from, to)
+// that were paid or claimed, with their mapped SKU and claims, oldest
+// first. Claims of the given types, e.g. cancellations, are left out.
+func (r *ClaimRepository) ClaimLines(ctx context.Context, sellerID int64, from, to time.Time, excludeTypes []string) ([]ClaimLine, error) {
+	claims := r.db.WithContext(ctx).Model(&Claim{}).Where("seller_id = ?", sellerID)
+	if len(excludeTypes) > 0 {
+		claims = claims.Where("type NOT IN ?", excludeTypes)
+	}
+	var lines []ClaimLine
+	err := r.db.WithContext(ctx).
+		Model(&OrderItem{}).
+		Select("orders.id AS order_id, orders.date_created, "+mappedSKU+" AS sku, order_items.item_id, order_items.title, order_items.quantity, "+
+			"c.id AS claim_id, c.type AS claim_type, c.reason_id, COALESCE(c.returned, false) AS returned").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Joins(mappedSKUJoins).
+		Joins("LEFT JOIN (?) AS c ON c.order_id = orders.id", claims).
+		Where("orders.seller_id = ? AND orders.date_created >= ? AND orders.date_created < ?", sellerID, from, to).
+		Where("orders.status = ? OR c.id IS NOT NULL", OrderStatusPaid).
+		Order("orders.date_created").
+		Scan(&lines).Error
+	return lines, err
+}
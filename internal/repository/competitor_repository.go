@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// Competitor is another seller whose listings are snapshotted by the
+// scheduler.
+type Competitor struct {
+	ID             uint   `gorm:"primaryKey"`
+	SellerID       int64  `gorm:"uniqueIndex:idx_competitors_org_seller;not null"`
+	Nickname       string `gorm:"size:128"`
+	Listings       int    `gorm:"not null;default:0"` // active listings in the last snapshot
+	LastSnapshotAt *time.Time
+	OrgID          uint `gorm:"not null;default:0;uniqueIndex:idx_competitors_org_seller,priority:1"`
+	Sandbox        bool `gorm:"not null;default:false"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// CompetitorListing is the last known state of a competitor's listing.
+// AvailableQuantity is only a hint: the search reports it in ranges.
+type CompetitorListing struct {
+	ID                uint    `gorm:"primaryKey"`
+	SellerID          int64   `gorm:"uniqueIndex:idx_competitor_listings_org;not null"`
+	ItemID            string  `gorm:"size:64;uniqueIndex:idx_competitor_listings_org;not null"`
+	Title             string  `gorm:"size:512"`
+	CatalogProductID  string  `gorm:"size:64"`
+	Price             float64 `gorm:"not null"`
+	AvailableQuantity int     `gorm:"not null;default:0"`
+	SoldQuantity      int     `gorm:"not null;default:0"`
+	Active            bool    `gorm:"not null;default:true"`
+	FirstSeenAt       time.Time
+	LastSeenAt        time.Time
+	OrgID             uint `gorm:"not null;default:0;uniqueIndex:idx_competitor_listings_org,priority:1"`
+	Sandbox           bool `gorm:"not null;default:false"`
+}
+
+// Competitor change kinds.
+const (
+	ChangeKindNewListing    = "new_listing"
+	ChangeKindRemoved       = "removed"
+	ChangeKindPriceDrop     = "price_drop"
+	ChangeKindPriceIncrease = "price_increase"
+	ChangeKindStock         = "stock"
+)
+
+// CompetitorChange is a difference found between two snapshots of a
+// competitor. OldValue and NewValue hold the price or available quantity,
+// depending on Kind.
+type CompetitorChange struct {
+	ID        uint   `gorm:"primaryKey"`
+	SellerID  int64  `gorm:"index;not null"`
+	ItemID    string `gorm:"size:64;not null"`
+	Title     string `gorm:"size:512"`
+	Kind      string `gorm:"size:32;not null"`
+	OldValue  float64
+	NewValue  float64
+	OrgID     uint      `gorm:"not null;default:0;index"`
+	Sandbox   bool      `gorm:"not null;default:false"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+type CompetitorRepository struct {
+	db *gorm.DB
+}
+
+func NewCompetitorRepository() *CompetitorRepository {
+	return &CompetitorRepository{
+		db: database.DB,
+	}
+}
+
+// List returns every watched competitor.
+func (r *CompetitorRepository) List(ctx context.Context) ([]Competitor, error) {
+	var competitors []Competitor
+	err := r.db.WithContext(ctx).Order("created_at").Find(&competitors).Error
+	return competitors, err
+}
+
+// Find returns a watched competitor, or nil if the seller is not watched.
+func (r *CompetitorRepository) Find(ctx context.Context, sellerID int64) (*Competitor, error) {
+	var c Competitor
+	err := r.db.WithContext(ctx).Where("seller_id = ?", sellerID).First(&c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Delete stops watching a seller and drops their snapshots; the change feed
+// is kept. It reports whether the seller was watched.
+func (r *CompetitorRepository) Delete(ctx context.Context, sellerID int64) (bool, error) {
+	var deleted bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("seller_id = ?", sellerID).Delete(&Competitor{})
+		if res.Error != nil {
+			return res.Error
+		}
+		deleted = res.RowsAffected > 0
+		return tx.Where("seller_id = ?", sellerID).Delete(&CompetitorListing{}).Error
+	})
+	return deleted, err
+}
+
+// Listings returns the known listings of a competitor, active or not.
+func (r *CompetitorRepository) Listings(ctx context.Context, sellerID int64) ([]CompetitorListing, error) {
+	var listings []CompetitorListing
+	err := r.db.WithContext(ctx).Where("seller_id = ?", sellerID).Find(&listings).Error
+	return listings, err
+}
+
+// SaveSnapshot stores a competitor, the new state of their listings and the
+// changes found, in one transaction.
+func (r *CompetitorRepository) SaveSnapshot(ctx context.Context, c *Competitor, listings []CompetitorListing, changes []CompetitorChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(c).Error; err != nil {
+			return err
+		}
+		for i := range listings {
+			if err := tx.Save(&listings[i]).Error; err != nil {
+				return err
+			}
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		return tx.Create(&changes).Error
+	})
+}
+
+// Changes returns the most recent changes of a competitor since a point in
+// time, newest first. An empty kind returns every kind.
+func (r *CompetitorRepository) Changes(ctx context.Context, sellerID int64, kind string, since time.Time, limit int) ([]CompetitorChange, error) {
+	q := r.db.WithContext(ctx).Where("seller_id = ? AND created_at >= ?", sellerID, since)
+	if kind != "" {
+		q = q.Where("kind = ?", kind)
+	}
+	var changes []CompetitorChange
+	err := q.Order("created_at DESC, id DESC").Limit(limit).Find(&changes).Error
+	return changes, err
+}
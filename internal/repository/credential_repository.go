@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// Credential is a secret of a third-party integration (SMTP password, bot
+// token, access keys, service account key). Value holds it sealed under the
+// master key KeyID identifies; it is never stored in the clear.
+type Credential struct {
+	ID          uint   `gorm:"primaryKey"`
+	Name        string `gorm:"size:128;not null;uniqueIndex:idx_credentials_org_name"`
+	Kind        string `gorm:"size:32;not null"`
+	Description string `gorm:"size:255"`
+	Value       string `gorm:"type:text;not null"`
+	KeyID       string `gorm:"size:16;not null"`
+	OrgID       uint   `gorm:"not null;default:0;uniqueIndex:idx_credentials_org_name,priority:1"`
+	Sandbox     bool   `gorm:"not null;default:false"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type CredentialRepository struct {
+	db *gorm.DB
+}
+
+func NewCredentialRepository() *CredentialRepository {
+	return &CredentialRepository{
+		db: database.DB,
+	}
+}
+
+// List returns every credential ordered by name.
+func (r *CredentialRepository) List(ctx context.Context) ([]Credential, error) {
+	var creds []Credential
+	err := r.db.WithContext(ctx).Order("name").Find(&creds).Error
+	return creds, err
+}
+
+// FindByName returns a credential, or nil if it does not exist.
+func (r *CredentialRepository) FindByName(ctx context.Context, name string) (*Credential, error) {
+	var cred Credential
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&cred).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cred, nil
+}
+
+// Save creates or updates a credential.
+func (r *CredentialRepository) Save(ctx context.Context, cred *Credential) error {
+	return r.db.WithContext(ctx).Save(cred).Error
+}
+
+// Delete removes a credential. It reports whether it existed.
+func (r *CredentialRepository) Delete(ctx context.Context, name string) (bool, error) {
+	res := r.db.WithContext(ctx).Where("name = ?", name).Delete(&Credential{})
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// E2ERun is an end-to-end check of the order pipeline with test users:
+// a buyer test user buys a test listing of the seller, and the order is
+// followed through the webhook, the order sync and the analytics. Steps
+// is the JSON of the result of each step.
+type E2ERun struct {
+	ID            uint   `gorm:"primaryKey"`
+	Status        string `gorm:"size:32;index;not null"`
+	SellerID      int64
+	BuyerID       int64
+	BuyerNickname string `gorm:"size:128"`
+	BuyerPassword string `gorm:"size:128"`
+	ItemID        string `gorm:"size:64"`
+	Permalink     string `gorm:"size:512"`
+	OrderID       int64
+	Steps         string `gorm:"type:text;not null"`
+	OrgID         uint   `gorm:"not null;default:0;index"`
+	Sandbox       bool   `gorm:"not null;default:false"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type E2ERunRepository struct {
+	db *gorm.DB
+}
+
+func NewE2ERunRepository() *E2ERunRepository {
+	return &E2ERunRepository{
+		db: database.DB,
+	}
+}
+
+// Save creates or updates a run.
+func (r *E2ERunRepository) Save(ctx context.Context, run *E2ERun) error {
+	return r.db.WithContext(ctx).Save(run).Error
+}
+
+// FindByID returns a run, or nil if there is none.
+func (r *E2ERunRepository) FindByID(ctx context.Context, id uint) (*E2ERun, error) {
+	var run E2ERun
+	err := r.db.WithContext(ctx).First(&run, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
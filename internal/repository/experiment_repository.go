@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// Experiment kinds.
+const (
+	ExperimentKindPrice = "price"
+	ExperimentKindTitle = "title"
+)
+
+// Experiment statuses.
+const (
+	ExperimentStatusRunning   = "running"
+	ExperimentStatusCompleted = "completed"
+	ExperimentStatusStopped   = "stopped"
+)
+
+// Experiment rotates a listing through variants, price points or titles, one
+// phase of PhaseDuration each, Cycles times over. Variants and Original
+// (what the listing had before) are JSON, see the experiment service.
+type Experiment struct {
+	ID            uint   `gorm:"primaryKey"`
+	ItemID        string `gorm:"size:64;index;not null"`
+	Kind          string `gorm:"size:16;not null"`
+	Status        string `gorm:"size:16;index;not null"`
+	Variants      string `gorm:"type:text;not null"`
+	Original      string `gorm:"type:text;not null"`
+	PhaseDuration time.Duration
+	Cycles        int `gorm:"not null;default:1"`
+	// Phase is the index of the running phase; variant Phase % len(Variants)
+	// is live on the listing.
+	Phase     int `gorm:"not null;default:0"`
+	EndedAt   *time.Time
+	Error     string `gorm:"size:512"`
+	Phases    []ExperimentPhase
+	OrgID     uint `gorm:"not null;default:0;index"`
+	Sandbox   bool `gorm:"not null;default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ExperimentPhase is a period during which one variant was live. EndedAt is
+// nil for the running phase.
+type ExperimentPhase struct {
+	ID           uint `gorm:"primaryKey"`
+	ExperimentID uint `gorm:"index;not null"`
+	Variant      int  `gorm:"not null"`
+	StartedAt    time.Time
+	EndedAt      *time.Time
+	OrgID        uint `gorm:"not null;default:0;index"`
+	Sandbox      bool `gorm:"not null;default:false"`
+}
+
+type ExperimentRepository struct {
+	db *gorm.DB
+}
+
+func NewExperimentRepository() *ExperimentRepository {
+	return &ExperimentRepository{
+		db: database.DB,
+	}
+}
+
+// List returns the experiments with their phases, newest first, of one
+// listing when itemID is set.
+func (r *ExperimentRepository) List(ctx context.Context, itemID string) ([]Experiment, error) {
+	q := r.db.WithContext(ctx).Preload("Phases", func(db *gorm.DB) *gorm.DB { return db.Order("started_at") })
+	if itemID != "" {
+		q = q.Where("item_id = ?", itemID)
+	}
+	var experiments []Experiment
+	err := q.Order("created_at DESC").Find(&experiments).Error
+	return experiments, err
+}
+
+// Running returns the running experiments with their phases.
+func (r *ExperimentRepository) Running(ctx context.Context) ([]Experiment, error) {
+	var experiments []Experiment
+	err := r.db.WithContext(ctx).
+		Preload("Phases", func(db *gorm.DB) *gorm.DB { return db.Order("started_at") }).
+		Where("status = ?", ExperimentStatusRunning).
+		Order("created_at").
+		Find(&experiments).Error
+	return experiments, err
+}
+
+// Find returns an experiment with its phases, or nil if there is none with
+// the ID.
+func (r *ExperimentRepository) Find(ctx context.Context, id uint) (*Experiment, error) {
+	var e Experiment
+	err := r.db.WithContext(ctx).
+		Preload("Phases", func(db *gorm.DB) *gorm.DB { return db.Order("started_at") }).
+		First(&e, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// RunningFor reports whether a listing has a running experiment.
+func (r *ExperimentRepository) RunningFor(ctx context.Context, itemID string) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&Experiment{}).
+		Where("item_id = ? AND status = ?", itemID, ExperimentStatusRunning).
+		Count(&n).Error
+	return n > 0, err
+}
+
+// Save stores an experiment and its phases.
+func (r *ExperimentRepository) Save(ctx context.Context, e *Experiment) error {
+	return r.db.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Save(e).Error
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// ReorderAlert records that a SKU's stock fell to its reorder point. It stays
+// open until the stock is back above the reorder point, so an alert fires
+// once per shortage.
+type ReorderAlert struct {
+	ID            uint    `gorm:"primaryKey"`
+	SKU           string  `gorm:"size:128;index;not null"`
+	Title         string  `gorm:"size:512"`
+	Stock         int     `gorm:"not null"`
+	DailyVelocity float64 `gorm:"not null"`
+	ReorderPoint  int     `gorm:"not null"`
+	ReorderQty    int     `gorm:"not null"`
+	ResolvedAt    *time.Time
+	OrgID         uint      `gorm:"not null;default:0;index"`
+	Sandbox       bool      `gorm:"not null;default:false"`
+	CreatedAt     time.Time `gorm:"index"`
+}
+
+type InventoryRepository struct {
+	db *gorm.DB
+}
+
+func NewInventoryRepository() *InventoryRepository {
+	return &InventoryRepository{
+		db: database.DB,
+	}
+}
+
+// OpenAlerts returns the unresolved reorder alerts keyed by SKU.
+func (r *InventoryRepository) OpenAlerts(ctx context.Context) (map[string]*ReorderAlert, error) {
+	var alerts []ReorderAlert
+	if err := r.db.WithContext(ctx).Where("resolved_at IS NULL").Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+	open := make(map[string]*ReorderAlert, len(alerts))
+	for i := range alerts {
+		open[alerts[i].SKU] = &alerts[i]
+	}
+	return open, nil
+}
+
+// CreateAlert stores a new reorder alert.
+func (r *InventoryRepository) CreateAlert(ctx context.Context, alert *ReorderAlert) error {
+	return r.db.WithContext(ctx).Create(alert).Error
+}
+
+// ResolveAlerts closes the given alerts.
+func (r *InventoryRepository) ResolveAlerts(ctx context.Context, ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Model(&ReorderAlert{}).
+		Where("id IN ?", ids).
+		Update("resolved_at", at).Error
+}
+
+// Alerts returns the most recent reorder alerts, open ones only when
+// openOnly is set.
+func (r *InventoryRepository) Alerts(ctx context.Context, openOnly bool, limit int) ([]ReorderAlert, error) {
+	q := r.db.WithContext(ctx).Order("created_at DESC").Limit(limit)
+	if openOnly {
+		q = q.Where("resolved_at IS NULL")
+	}
+	var alerts []ReorderAlert
+	err := q.Find(&alerts).Error
+	return alerts, err
+}
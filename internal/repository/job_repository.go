@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Job statuses.
+const (
+	JobStatusQueued    = "queued"
+	JobStatusRunning   = "running"
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed"
+)
+
+// Job is a unit of background work persisted so it survives restarts.
+// Payload and Result hold JSON documents.
+type Job struct {
+	ID        uint   `gorm:"primaryKey"`
+	Type      string `gorm:"size:64;index;not null"`
+	Status    string `gorm:"size:16;index;not null"`
+	Payload   string `gorm:"type:text"`
+	Result    string `gorm:"type:text"`
+	LastError string `gorm:"type:text"`
+	// ErrorClass tells whether LastError was retryable or fatal, see
+	// retry.Class
+	ErrorClass string `gorm:"size:16"`
+	// PanicStack is the stack of the panic that failed the job, shown to
+	// admins only
+	PanicStack  string    `gorm:"type:text"`
+	Attempts    int       `gorm:"not null;default:0"`
+	MaxAttempts int       `gorm:"not null;default:3"`
+	RunAt       time.Time `gorm:"index;not null"`
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+	// Owner is the instance running the job, which renews LeaseUntil
+	// while it runs; a running job whose lease expired was abandoned
+	Owner      string     `gorm:"size:128"`
+	LeaseUntil *time.Time `gorm:"index"`
+	OrgID      uint       `gorm:"not null;default:0;index"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository() *JobRepository {
+	return &JobRepository{
+		db: database.DB,
+	}
+}
+
+// Create persists a new job.
+func (r *JobRepository) Create(ctx context.Context, job *Job) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+// FindByID returns a job by ID, or nil if it does not exist.
+func (r *JobRepository) FindByID(ctx context.Context, id uint) (*Job, error) {
+	var job Job
+	err := r.db.WithContext(ctx).First(&job, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ClaimNext atomically picks the oldest due job of one of the given types and
+// marks it running by owner, leased for lease. It returns nil when there is
+// nothing to do. SKIP LOCKED lets several workers (or instances) poll the
+// same table safely.
+func (r *JobRepository) ClaimNext(ctx context.Context, types []string, owner string, lease time.Duration) (*Job, error) {
+	var claimed *Job
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var job Job
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ? AND type IN ?", JobStatusQueued, time.Now(), types).
+			Order("run_at").
+			First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		job.Status = JobStatusRunning
+		job.Attempts++
+		job.StartedAt = &now
+		job.Owner = owner
+		until := now.Add(lease)
+		job.LeaseUntil = &until
+		if err := tx.Save(&job).Error; err != nil {
+			return err
+		}
+		claimed = &job
+		return nil
+	})
+
+	return claimed, err
+}
+
+// Finish records the outcome of a job owner ran, updating every column. It
+// reports false, and changes nothing, when the job is no longer running
+// under owner: its lease expired and it may belong to another worker now.
+func (r *JobRepository) Finish(ctx context.Context, job *Job, owner string) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(job).
+		Where("status = ? AND owner = ?", JobStatusRunning, owner).
+		Select("*").
+		Updates(job)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RenewLease extends the lease of a job owner is running. It reports false
+// when the job is no longer running under owner: its lease expired and it
+// was requeued.
+func (r *JobRepository) RenewLease(ctx context.Context, id uint, owner string, lease time.Duration) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&Job{}).
+		Where("id = ? AND status = ? AND owner = ?", id, JobStatusRunning, owner).
+		Update("lease_until", time.Now().Add(lease))
+	return res.RowsAffected > 0, res.Error
+}
+
+// errJobAbandoned is the error of jobs whose worker stopped on every
+// attempt, e.g. because the job crashes it.
+const errJobAbandoned = "the worker stopped before the job finished"
+
+// RequeueExpired puts running jobs whose lease expired, left by a crashed
+// or stopped instance, back in the queue. The interrupted run counts as an
+// attempt (it was counted when claimed), so jobs that used their last one
+// fail instead of crashing workers forever. Jobs claimed before leases
+// existed count as abandoned after stale. It returns how many jobs were
+// requeued and failed.
+func (r *JobRepository) RequeueExpired(ctx context.Context, stale time.Duration) (requeued, failed int64, err error) {
+	now := time.Now()
+	expired := func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&Job{}).
+			Where("status = ?", JobStatusRunning).
+			Where("lease_until < ? OR (lease_until IS NULL AND started_at < ?)", now, now.Add(-stale))
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := expired(tx).
+			Where("attempts >= max_attempts").
+			Updates(map[string]interface{}{
+				"status": JobStatusFailed, "finished_at": now, "last_error": errJobAbandoned,
+				"error_class": "fatal", "owner": "", "lease_until": nil,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		failed = res.RowsAffected
+
+		res = expired(tx).
+			Updates(map[string]interface{}{"status": JobStatusQueued, "run_at": now, "owner": "", "lease_until": nil})
+		requeued = res.RowsAffected
+		return res.Error
+	})
+	return requeued, failed, err
+}
+
+// ListFailed returns failed jobs, most recent first, optionally of one
+// type.
+func (r *JobRepository) ListFailed(ctx context.Context, jobType string, limit, offset int) ([]Job, error) {
+	q := r.db.WithContext(ctx).Where("status = ?", JobStatusFailed)
+	if jobType != "" {
+		q = q.Where("type = ?", jobType)
+	}
+	var jobs []Job
+	err := q.Order("finished_at DESC, id DESC").Limit(limit).Offset(offset).Find(&jobs).Error
+	return jobs, err
+}
+
+// CountByStatus returns how many jobs are in each status.
+func (r *JobRepository) CountByStatus(ctx context.Context) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	err := r.db.WithContext(ctx).
+		Model(&Job{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// KeywordTrend is one trending search term of a category at snapshot time.
+type KeywordTrend struct {
+	ID         uint      `gorm:"primaryKey"`
+	CategoryID string    `gorm:"size:64;index;not null"`
+	Keyword    string    `gorm:"size:256;not null"`
+	Position   int       `gorm:"not null"`
+	OrgID      uint      `gorm:"not null;default:0;index"`
+	Sandbox    bool      `gorm:"not null;default:false"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+// KeywordActivity counts how often a keyword trended within a period.
+type KeywordActivity struct {
+	Period      time.Time
+	Keyword     string
+	Appearances int
+}
+
+type KeywordTrendRepository struct {
+	db *gorm.DB
+}
+
+func NewKeywordTrendRepository() *KeywordTrendRepository {
+	return &KeywordTrendRepository{
+		db: database.DB,
+	}
+}
+
+// SaveKeywordTrends persists a snapshot of trending keywords.
+func (r *KeywordTrendRepository) SaveKeywordTrends(ctx context.Context, trends []KeywordTrend) error {
+	if len(trends) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&trends).Error
+}
+
+// ActivityByPeriod counts keyword appearances per period ("week" or
+// "month") since a point in time, oldest period first.
+func (r *KeywordTrendRepository) ActivityByPeriod(ctx context.Context, categoryID, period string, since time.Time) ([]KeywordActivity, error) {
+	var rows []KeywordActivity
+	err := r.db.WithContext(ctx).
+		Model(&KeywordTrend{}).
+		Select("date_trunc(?, created_at) AS period, keyword, COUNT(*) AS appearances", period).
+		Where("category_id = ? AND created_at >= ?", categoryID, since).
+		Group("period, keyword").
+		Order("period, appearances DESC").
+		Scan(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// Listing rule triggers.
+const (
+	// RuleTriggerSchedule pauses the listing from PauseAt until ResumeAt.
+	RuleTriggerSchedule = "schedule"
+	// RuleTriggerFullStock pauses a FULL listing while its stock in
+	// Mercado Livre's warehouses is zero.
+	RuleTriggerFullStock = "full_stock"
+)
+
+// ListingRule pauses one of the seller's listings while its trigger holds
+// and reactivates it afterwards. Holding is set while the rule keeps the
+// listing paused, so listings the seller paused by hand are never
+// reactivated.
+type ListingRule struct {
+	ID        uint   `gorm:"primaryKey"`
+	ItemID    string `gorm:"size:64;index;not null"`
+	Trigger   string `gorm:"size:16;not null"`
+	PauseAt   *time.Time
+	ResumeAt  *time.Time
+	Enabled   bool `gorm:"not null;default:true"`
+	Holding   bool `gorm:"not null;default:false"`
+	Version   uint `gorm:"not null;default:1"`
+	OrgID     uint `gorm:"not null;default:0;index"`
+	Sandbox   bool `gorm:"not null;default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// Listing actions.
+const (
+	ListingActionPause    = "pause"
+	ListingActionActivate = "activate"
+)
+
+// ListingAction is a pause or reactivation made by the listing rules.
+// Error is set when Mercado Livre refused it.
+type ListingAction struct {
+	ID        uint   `gorm:"primaryKey"`
+	RuleID    uint   `gorm:"index"`
+	ItemID    string `gorm:"size:64;index;not null"`
+	Action    string `gorm:"size:16;not null"`
+	Reason    string `gorm:"size:255"`
+	Error     string `gorm:"type:text"`
+	OrgID     uint   `gorm:"not null;default:0;index"`
+	Sandbox   bool   `gorm:"not null;default:false"`
+	CreatedAt time.Time
+}
+
+type ListingRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewListingRuleRepository() *ListingRuleRepository {
+	return &ListingRuleRepository{
+		db: database.DB,
+	}
+}
+
+// Rules returns every listing rule.
+func (r *ListingRuleRepository) Rules(ctx context.Context) ([]ListingRule, error) {
+	var rules []ListingRule
+	err := r.db.WithContext(ctx).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// EnabledRules returns the enabled rules, of one listing when itemID is
+// set.
+func (r *ListingRuleRepository) EnabledRules(ctx context.Context, itemID string) ([]ListingRule, error) {
+	q := r.db.WithContext(ctx).Where("enabled")
+	if itemID != "" {
+		q = q.Where("item_id = ?", itemID)
+	}
+	var rules []ListingRule
+	err := q.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// FindRule returns a rule by ID, or nil if it does not exist.
+func (r *ListingRuleRepository) FindRule(ctx context.Context, id uint) (*ListingRule, error) {
+	var rule ListingRule
+	err := r.db.WithContext(ctx).First(&rule, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SaveRule creates or updates a rule.
+func (r *ListingRuleRepository) SaveRule(ctx context.Context, rule *ListingRule) error {
+	return r.db.WithContext(ctx).Save(rule).Error
+}
+
+// SetHolding records whether a rule keeps its listing paused, leaving the
+// rest of the rule, which the seller may be editing, untouched.
+func (r *ListingRuleRepository) SetHolding(ctx context.Context, id uint, holding bool) error {
+	return r.db.WithContext(ctx).Model(&ListingRule{ID: id}).Update("holding", holding).Error
+}
+
+// DeleteRule moves a rule to the trash. It reports whether it existed.
+func (r *ListingRuleRepository) DeleteRule(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Delete(&ListingRule{}, id)
+	return res.RowsAffected > 0, res.Error
+}
+
+// LogAction records a pause or reactivation.
+func (r *ListingRuleRepository) LogAction(ctx context.Context, a *ListingAction) error {
+	return r.db.WithContext(ctx).Create(a).Error
+}
+
+// Actions returns the latest actions, of one listing when itemID is set.
+func (r *ListingRuleRepository) Actions(ctx context.Context, itemID string, limit int) ([]ListingAction, error) {
+	q := r.db.WithContext(ctx)
+	if itemID != "" {
+		q = q.Where("item_id = ?", itemID)
+	}
+	var actions []ListingAction
+	err := q.Order("id DESC").Limit(limit).Find(&actions).Error
+	return actions, err
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// ListingTemplate holds what repeated listings of a product type share, so
+// each new one only needs what sets it apart. Attributes is the JSON of the
+// default attributes and DescriptionBlocks the JSON of the description's
+// paragraphs.
+type ListingTemplate struct {
+	ID                uint   `gorm:"primaryKey"`
+	Name              string `gorm:"size:128;not null"`
+	CategoryID        string `gorm:"size:64;index;not null"`
+	ListingType       string `gorm:"size:32"`
+	Condition         string `gorm:"size:16"`
+	CurrencyID        string `gorm:"size:8"`
+	BuyingMode        string `gorm:"size:32"`
+	Attributes        string `gorm:"type:text;not null"`
+	DescriptionBlocks string `gorm:"type:text;not null"`
+	ShippingMode      string `gorm:"size:32"`
+	FreeShipping      bool   `gorm:"not null;default:false"`
+	LocalPickUp       bool   `gorm:"not null;default:false"`
+	Version           uint   `gorm:"not null;default:1"`
+	OrgID             uint   `gorm:"not null;default:0;index"`
+	Sandbox           bool   `gorm:"not null;default:false"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
+}
+
+type ListingTemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewListingTemplateRepository() *ListingTemplateRepository {
+	return &ListingTemplateRepository{
+		db: database.DB,
+	}
+}
+
+// List returns every listing template.
+func (r *ListingTemplateRepository) List(ctx context.Context) ([]ListingTemplate, error) {
+	var templates []ListingTemplate
+	err := r.db.WithContext(ctx).Order("id").Find(&templates).Error
+	return templates, err
+}
+
+// FindByID returns a template, or nil if it does not exist.
+func (r *ListingTemplateRepository) FindByID(ctx context.Context, id uint) (*ListingTemplate, error) {
+	var t ListingTemplate
+	err := r.db.WithContext(ctx).First(&t, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Save creates or updates a template.
+func (r *ListingTemplateRepository) Save(ctx context.Context, t *ListingTemplate) error {
+	return r.db.WithContext(ctx).Save(t).Error
+}
+
+// Delete moves a template to the trash. It reports whether it existed.
+func (r *ListingTemplateRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Delete(&ListingTemplate{}, id)
+	return res.RowsAffected > 0, res.Error
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// LockoutCounter counts the wrong keys sent by a client IP, or for an
+// account, within a window, so that every instance enforces the same
+// lockout (see middleware.Lockout). Times come from the database clock;
+// rows past ExpiresAt are stale and purged.
+type LockoutCounter struct {
+	Key         string    `gorm:"primaryKey;size:160"`
+	Failures    int       `gorm:"not null;default:0"`
+	WindowStart time.Time `gorm:"not null"`
+	LockedUntil *time.Time
+	ExpiresAt   time.Time `gorm:"index;not null"`
+}
+
+type LockoutRepository struct {
+	db *gorm.DB
+}
+
+func NewLockoutRepository() *LockoutRepository {
+	return &LockoutRepository{
+		db: database.DB,
+	}
+}
+
+// Fail counts a failure of key, starting a new count when the window of the
+// current one is over. When the count reaches limit and key is not locked,
+// key is locked for lockout and its count starts over; only the call that
+// locked it reports true, whichever instance it ran on.
+func (r *LockoutRepository) Fail(ctx context.Context, key string, limit int, window, lockout time.Duration) (lockedNow bool, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO lockout_counters (key, failures, window_start, expires_at)
+			VALUES (@key, 1, NOW(), NOW() + make_interval(secs => @window))
+			ON CONFLICT (key) DO UPDATE SET
+				failures = CASE WHEN lockout_counters.window_start < NOW() - make_interval(secs => @window)
+					THEN 1 ELSE lockout_counters.failures + 1 END,
+				window_start = CASE WHEN lockout_counters.window_start < NOW() - make_interval(secs => @window)
+					THEN NOW() ELSE lockout_counters.window_start END,
+				expires_at = GREATEST(NOW() + make_interval(secs => @window), lockout_counters.locked_until)`,
+			map[string]interface{}{"key": key, "window": window.Seconds()}).Error
+		if err != nil {
+			return err
+		}
+
+		res := tx.Model(&LockoutCounter{}).
+			Where("key = ? AND failures >= ? AND (locked_until IS NULL OR locked_until < NOW())", key, limit).
+			Updates(map[string]interface{}{
+				"failures":     0,
+				"window_start": gorm.Expr("NOW()"),
+				"locked_until": leaseEnd(lockout),
+				"expires_at":   leaseEnd(max(window, lockout)),
+			})
+		lockedNow = res.RowsAffected == 1
+		return res.Error
+	})
+	return lockedNow, err
+}
+
+// Locked returns how long key stays locked, 0 when it is not.
+func (r *LockoutRepository) Locked(ctx context.Context, key string) (time.Duration, error) {
+	var secs []float64
+	err := r.db.WithContext(ctx).
+		Model(&LockoutCounter{}).
+		Where("key = ? AND locked_until > NOW()", key).
+		Pluck("EXTRACT(EPOCH FROM locked_until - NOW())", &secs).Error
+	if err != nil || len(secs) == 0 {
+		return 0, err
+	}
+	return time.Duration(secs[0] * float64(time.Second)), nil
+}
+
+// Purge deletes the counters that expired.
+func (r *LockoutRepository) Purge(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < NOW()").
+		Delete(&LockoutCounter{}).Error
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FailedNotification is a Mercado Livre notification whose processing
+// failed, kept until a retry succeeds. Key identifies the notification
+// across redeliveries; Payload is its original JSON body.
+type FailedNotification struct {
+	ID            uint   `gorm:"primaryKey"`
+	Key           string `gorm:"size:128;uniqueIndex;not null"`
+	Topic         string `gorm:"size:64;index"`
+	Resource      string `gorm:"size:255"`
+	UserID        int64
+	Payload       string     `gorm:"type:text;not null"`
+	LastError     string     `gorm:"type:text"`
+	Attempts      int        `gorm:"not null;default:1"`
+	NextAttemptAt time.Time  `gorm:"index"`
+	ResolvedAt    *time.Time `gorm:"index"`
+	Sandbox       bool       `gorm:"not null;default:false"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{
+		db: database.DB,
+	}
+}
+
+// RecordFailure stores a failed notification. One already stored under the
+// same key, e.g. after a redelivery failed again, is reopened with the new
+// error and attempt instead.
+func (r *NotificationRepository) RecordFailure(ctx context.Context, n *FailedNotification) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"payload":         gorm.Expr("excluded.payload"),
+				"last_error":      gorm.Expr("excluded.last_error"),
+				"attempts":        gorm.Expr("failed_notifications.attempts + 1"),
+				"next_attempt_at": gorm.Expr("excluded.next_attempt_at"),
+				"resolved_at":     nil,
+				"updated_at":      gorm.Expr("excluded.updated_at"),
+			}),
+		}).
+		Create(n).Error
+}
+
+// Pending lists the unresolved notifications, oldest first.
+func (r *NotificationRepository) Pending(ctx context.Context, limit int) ([]FailedNotification, error) {
+	var rows []FailedNotification
+	err := r.db.WithContext(ctx).
+		Where("resolved_at IS NULL").
+		Order("id").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+// Due lists the unresolved notifications whose next retry is at or before
+// now, oldest first.
+func (r *NotificationRepository) Due(ctx context.Context, now time.Time, limit int) ([]FailedNotification, error) {
+	var rows []FailedNotification
+	err := r.db.WithContext(ctx).
+		Where("resolved_at IS NULL AND next_attempt_at <= ?", now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+// FindByIDs returns the notifications with the given IDs, resolved or not.
+func (r *NotificationRepository) FindByIDs(ctx context.Context, ids []uint) ([]FailedNotification, error) {
+	var rows []FailedNotification
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&rows).Error
+	return rows, err
+}
+
+// Save updates a notification after a retry.
+func (r *NotificationRepository) Save(ctx context.Context, n *FailedNotification) error {
+	return r.db.WithContext(ctx).Save(n).Error
+}
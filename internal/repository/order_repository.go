@@ -0,0 +1,474 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrderStatusPaid is the status of orders that count as sales.
+const OrderStatusPaid = "paid"
+
+// OrderStatusCancelled is the status of orders cancelled after they were
+// placed.
+const OrderStatusCancelled = "cancelled"
+
+// Order is a Mercado Livre order of the authenticated seller. The primary
+// key is Mercado Livre's order ID.
+type Order struct {
+	ID            int64     `gorm:"primaryKey;autoIncrement:false"`
+	SellerID      int64     `gorm:"index;not null"`
+	Status        string    `gorm:"size:32;index;not null"`
+	DateCreated   time.Time `gorm:"index;not null"`
+	DateClosed    *time.Time
+	LastUpdated   time.Time `gorm:"index"`
+	TotalAmount   float64   `gorm:"not null"`
+	PaidAmount    float64
+	Currency      string `gorm:"size:8"`
+	BuyerID       int64
+	BuyerNickname string `gorm:"size:128"`
+	ShippingID    int64  `gorm:"index"`
+	// ShippingCost is what the seller paid for shipping, when known.
+	ShippingCost float64
+	// DispatchAlertedAt is set once the order was reported as close to its
+	// handling deadline.
+	DispatchAlertedAt *time.Time
+	// DispatchLateAt is set once the order was seen still awaiting dispatch
+	// past its handling deadline.
+	DispatchLateAt *time.Time
+	Items          []OrderItem
+	OrgID          uint `gorm:"not null;default:0;index"`
+	Sandbox        bool `gorm:"not null;default:false"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// OrderItem is a line of an order. SaleFee is Mercado Livre's commission per
+// unit. SKU is the seller SKU the listing had when the order was placed;
+// reports prefer the SKU mapped to the listing, see SKUMapping.
+type OrderItem struct {
+	ID          uint    `gorm:"primaryKey"`
+	OrderID     int64   `gorm:"index;not null"`
+	ItemID      string  `gorm:"size:64;index;not null"`
+	VariationID int64   `gorm:"not null;default:0"`
+	Title       string  `gorm:"size:512"`
+	SKU         string  `gorm:"size:128;index"`
+	Quantity    int     `gorm:"not null"`
+	UnitPrice   float64 `gorm:"not null"`
+	SaleFee     float64
+	OrgID       uint `gorm:"not null;default:0;index"`
+	Sandbox     bool `gorm:"not null;default:false"`
+}
+
+// OrderStatusChange is a status transition of an order. FromStatus is empty
+// for the first status seen.
+type OrderStatusChange struct {
+	ID         uint      `gorm:"primaryKey"`
+	OrderID    int64     `gorm:"index;not null"`
+	FromStatus string    `gorm:"size:32"`
+	ToStatus   string    `gorm:"size:32;not null"`
+	ChangedAt  time.Time `gorm:"not null"`
+	OrgID      uint      `gorm:"not null;default:0;index"`
+	Sandbox    bool      `gorm:"not null;default:false"`
+	CreatedAt  time.Time
+}
+
+// SalesPeriod aggregates paid orders within a period.
+type SalesPeriod struct {
+	Period  time.Time
+	Orders  int
+	Units   int
+	Revenue float64
+}
+
+// SKUSales aggregates the paid units of a SKU (or item, when it has none).
+type SKUSales struct {
+	SKU     string
+	ItemID  string
+	Title   string
+	Units   int
+	Revenue float64
+}
+
+// OrderRepository stores the seller's orders. Its report queries read from
+// a replica, see database.Replica.
+type OrderRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderRepository() *OrderRepository {
+	return &OrderRepository{
+		db: database.DB,
+	}
+}
+
+// Upsert inserts or updates an order and replaces its lines. A status
+// change (or the first status of a new order) is recorded in the order's
+// status history. It returns the status the order had before, or "" when it
+// is new.
+func (r *OrderRepository) Upsert(ctx context.Context, order *Order) (string, error) {
+	var previous string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current Order
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("status").
+			Where("id = ?", order.ID).
+			Take(&current).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		previous = current.Status
+
+		items := order.Items
+		order.Items = nil
+		defer func() { order.Items = items }()
+
+		upsert := clause.OnConflict{
+			Columns: []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"status", "date_closed", "last_updated", "total_amount", "paid_amount",
+				"currency", "buyer_id", "buyer_nickname", "shipping_id", "shipping_cost", "updated_at",
+			}),
+		}
+		if err := tx.Clauses(upsert).Create(order).Error; err != nil {
+			return err
+		}
+		if previous != order.Status {
+			change := &OrderStatusChange{OrderID: order.ID, FromStatus: previous, ToStatus: order.Status, ChangedAt: order.LastUpdated}
+			if err := tx.Create(change).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("order_id = ?", order.ID).Delete(&OrderItem{}).Error; err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		for i := range items {
+			items[i].ID = 0
+			items[i].OrderID = order.ID
+		}
+		return tx.Create(&items).Error
+	})
+	return previous, err
+}
+
+// FindByID returns an order with its lines, or nil if it is not stored.
+func (r *OrderRepository) FindByID(ctx context.Context, id int64) (*Order, error) {
+	var order Order
+	err := r.db.WithContext(ctx).Preload("Items").First(&order, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// FindByItem returns a seller's latest stored order with a line of itemID,
+// or nil if there is none.
+func (r *OrderRepository) FindByItem(ctx context.Context, sellerID int64, itemID string) (*Order, error) {
+	var order Order
+	err := r.db.WithContext(ctx).
+		Preload("Items").
+		Where("seller_id = ? AND id IN (?)", sellerID, r.db.WithContext(ctx).Model(&OrderItem{}).Select("order_id").Where("item_id = ?", itemID)).
+		Order("date_created DESC").
+		First(&order).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// OrderFilter narrows List. Zero values match everything.
+type OrderFilter struct {
+	Status string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// List returns a seller's stored orders with their lines, newest first.
+func (r *OrderRepository) List(ctx context.Context, sellerID int64, f OrderFilter) ([]Order, error) {
+	q := r.db.WithContext(ctx).Preload("Items").Where("seller_id = ?", sellerID)
+	if f.Status != "" {
+		q = q.Where("status = ?", f.Status)
+	}
+	if !f.From.IsZero() {
+		q = q.Where("date_created >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		q = q.Where("date_created < ?", f.To)
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	var orders []Order
+	err := q.Order("date_created DESC").Find(&orders).Error
+	return orders, err
+}
+
+// itemTextMatch matches order lines whose title full-text matches the
+// query, or whose title or SKU contains it, or whose item ID is it.
+const itemTextMatch = "to_tsvector('portuguese', title) @@ plainto_tsquery('portuguese', @q) OR title ILIKE @pattern OR sku ILIKE @pattern OR item_id = @q"
+
+// SearchItems searches a seller's listings that appear in stored orders,
+// returning the line of the latest order per listing, newest first.
+func (r *OrderRepository) SearchItems(ctx context.Context, sellerID int64, query string, limit int) ([]OrderItem, error) {
+	args := map[string]interface{}{"q": query, "pattern": containsPattern(query)}
+	latest := r.db.WithContext(ctx).
+		Model(&OrderItem{}).
+		Select("DISTINCT ON (item_id) *").
+		Where(itemTextMatch, args).
+		Where("order_id IN (?)", r.sellerOrderIDs(ctx, sellerID)).
+		Order("item_id, order_id DESC")
+
+	var items []OrderItem
+	err := r.db.WithContext(ctx).
+		Table("(?) AS latest", latest).
+		Order("order_id DESC").
+		Limit(limit).
+		Find(&items).Error
+	return items, err
+}
+
+// Search returns a seller's stored orders, with their lines, whose ID or
+// buyer matches the query or that have a line matching it as in
+// SearchItems, newest first.
+func (r *OrderRepository) Search(ctx context.Context, sellerID int64, query string, limit int) ([]Order, error) {
+	args := map[string]interface{}{"q": query, "pattern": containsPattern(query)}
+	lines := r.db.WithContext(ctx).
+		Model(&OrderItem{}).
+		Select("order_id").
+		Where(itemTextMatch, args)
+
+	cond, vars := "buyer_nickname ILIKE ? OR id IN (?)", []interface{}{containsPattern(query), lines}
+	if id, err := strconv.ParseInt(query, 10, 64); err == nil {
+		cond, vars = cond+" OR id = ?", append(vars, id)
+	}
+
+	var orders []Order
+	err := r.db.WithContext(ctx).Preload("Items").
+		Where("seller_id = ?", sellerID).
+		Where(cond, vars...).
+		Order("date_created DESC").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
+
+// sellerOrderIDs is the subquery of a seller's order IDs, for filtering
+// order lines.
+func (r *OrderRepository) sellerOrderIDs(ctx context.Context, sellerID int64) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&Order{}).Select("id").Where("seller_id = ?", sellerID)
+}
+
+// OrdersInBatches calls fn with a seller's stored orders created in
+// [from, to), with their lines, batchSize orders at a time in order ID
+// order, so exports don't hold every order in memory.
+func (r *OrderRepository) OrdersInBatches(ctx context.Context, sellerID int64, from, to time.Time, batchSize int, fn func([]Order) error) error {
+	var batch []Order
+	return database.Replica(ctx).
+		Preload("Items").
+		Where("seller_id = ? AND date_created >= ? AND date_created < ?", sellerID, from, to).
+		FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// OrdersUpdatedAfter returns up to limit stored orders, with their lines,
+// last stored after (after, afterID) and before a point in time, in
+// (updated_at, id) order. It reads the orders of every seller.
+func (r *OrderRepository) OrdersUpdatedAfter(ctx context.Context, after time.Time, afterID int64, before time.Time, limit int) ([]Order, error) {
+	var orders []Order
+	err := r.db.WithContext(ctx).
+		Preload("Items").
+		Where("(updated_at > ? OR (updated_at = ? AND id > ?)) AND updated_at < ?", after, after, afterID, before).
+		Order("updated_at, id").
+		Limit(limit).
+		Find(&orders).Error
+	return orders, err
+}
+
+// StatusHistory returns the status transitions of an order, oldest first.
+func (r *OrderRepository) StatusHistory(ctx context.Context, orderID int64) ([]OrderStatusChange, error) {
+	var changes []OrderStatusChange
+	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Order("changed_at, id").Find(&changes).Error
+	return changes, err
+}
+
+// LastUpdated returns the most recent update time among a seller's stored
+// orders, or the zero time when there are none.
+func (r *OrderRepository) LastUpdated(ctx context.Context, sellerID int64) (time.Time, error) {
+	var last *time.Time
+	err := r.db.WithContext(ctx).
+		Model(&Order{}).
+		Select("MAX(last_updated)").
+		Where("seller_id = ?", sellerID).
+		Scan(&last).Error
+	if err != nil || last == nil {
+		return time.Time{}, err
+	}
+	return *last, nil
+}
+
+// SalesByPeriod aggregates a seller's paid orders created in [from, to) by
+// period ("day" or "week"), oldest first.
+func (r *OrderRepository) SalesByPeriod(ctx context.Context, sellerID int64, period string, from, to time.Time) ([]SalesPeriod, error) {
+	units := database.Replica(ctx).
+		Model(&OrderItem{}).
+		Select("order_id, SUM(quantity) AS units").
+		Group("order_id")
+
+	var rows []SalesPeriod
+	err := database.Replica(ctx).
+		Model(&Order{}).
+		Select("date_trunc(?, orders.date_created) AS period, COUNT(*) AS orders, COALESCE(SUM(u.units), 0) AS units, SUM(orders.total_amount) AS revenue", period).
+		Joins("LEFT JOIN (?) AS u ON u.order_id = orders.id", units).
+		Where("orders.seller_id = ? AND orders.status = ? AND orders.date_created >= ? AND orders.date_created < ?", sellerID, OrderStatusPaid, from, to).
+		Group("period").
+		Order("period").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// TopSKUs returns a seller's best-selling SKUs by revenue among paid orders
+// created in [from, to). Lines are grouped by their mapped SKU.
+func (r *OrderRepository) TopSKUs(ctx context.Context, sellerID int64, from, to time.Time, limit int) ([]SKUSales, error) {
+	var rows []SKUSales
+	err := database.Replica(ctx).
+		Model(&OrderItem{}).
+		Select(mappedSKU+" AS sku, MIN(order_items.item_id) AS item_id, MIN(order_items.title) AS title, SUM(order_items.quantity) AS units, SUM(order_items.quantity * order_items.unit_price) AS revenue").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Joins(mappedSKUJoins).
+		Where("orders.seller_id = ? AND orders.status = ? AND orders.date_created >= ? AND orders.date_created < ?", sellerID, OrderStatusPaid, from, to).
+		Group(mappedSKU).
+		Order("revenue DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// OrderLine is an order item together with the order-level amounts needed
+// to compute its profit.
+type OrderLine struct {
+	OrderID           int64
+	DateCreated       time.Time
+	SKU               string
+	ItemID            string
+	Title             string
+	Quantity          int
+	UnitPrice         float64
+	SaleFee           float64
+	OrderTotal        float64
+	OrderShippingCost float64
+}
+
+// PaidOrderLines returns the lines of a seller's paid orders created in
+// [from, to), oldest first, with their mapped SKU.
+func (r *OrderRepository) PaidOrderLines(ctx context.Context, sellerID int64, from, to time.Time) ([]OrderLine, error) {
+	var lines []OrderLine
+	err := database.Replica(ctx).
+		Model(&OrderItem{}).
+		Select("orders.id AS order_id, orders.date_created, "+mappedSKU+" AS sku, order_items.item_id, order_items.title, "+
+			"order_items.quantity, order_items.unit_price, order_items.sale_fee, "+
+			"orders.total_amount AS order_total, orders.shipping_cost AS order_shipping_cost").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Joins(mappedSKUJoins).
+		Where("orders.seller_id = ? AND orders.status = ? AND orders.date_created >= ? AND orders.date_created < ?", sellerID, OrderStatusPaid, from, to).
+		Order("orders.date_created").
+		Scan(&lines).Error
+	return lines, err
+}
+
+// ItemSales is the number of paid units of a listing.
+type ItemSales struct {
+	ItemID string
+	SKU    string
+	Title  string
+	Units  int
+}
+
+// UnitsByItem returns the paid units of each of a seller's listings among
+// orders created in [from, to).
+func (r *OrderRepository) UnitsByItem(ctx context.Context, sellerID int64, from, to time.Time) ([]ItemSales, error) {
+	var rows []ItemSales
+	err := r.db.WithContext(ctx).
+		Model(&OrderItem{}).
+		Select("order_items.item_id, MAX(order_items.sku) AS sku, MIN(order_items.title) AS title, SUM(order_items.quantity) AS units").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.seller_id = ? AND orders.status = ? AND orders.date_created >= ? AND orders.date_created < ?", sellerID, OrderStatusPaid, from, to).
+		Group("order_items.item_id").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ItemPerformance is what a listing sold over a range: the paid orders
+// with a line of it, its units and the revenue of those lines.
+type ItemPerformance struct {
+	Orders  int
+	Units   int
+	Revenue float64
+}
+
+// ItemPerformance sums the lines of a listing among a seller's paid orders
+// created in [from, to).
+func (r *OrderRepository) ItemPerformance(ctx context.Context, sellerID int64, itemID string, from, to time.Time) (*ItemPerformance, error) {
+	var p ItemPerformance
+	err := r.db.WithContext(ctx).
+		Model(&OrderItem{}).
+		Select("COUNT(DISTINCT orders.id) AS orders, COALESCE(SUM(order_items.quantity), 0) AS units, COALESCE(SUM(order_items.quantity * order_items.unit_price), 0) AS revenue").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.seller_id = ? AND orders.status = ? AND order_items.item_id = ? AND orders.date_created >= ? AND orders.date_created < ?", sellerID, OrderStatusPaid, itemID, from, to).
+		Scan(&p).Error
+	return &p, err
+}
+
+// AwaitingShipment returns a seller's paid orders with a shipment, created
+// since a point in time, oldest first. Whether they were dispatched has to be
+// checked against the shipment.
+func (r *OrderRepository) AwaitingShipment(ctx context.Context, sellerID int64, since time.Time) ([]Order, error) {
+	var orders []Order
+	err := r.db.WithContext(ctx).
+		Preload("Items").
+		Where("seller_id = ? AND status = ? AND shipping_id <> 0 AND date_created >= ?", sellerID, OrderStatusPaid, since).
+		Order("date_created").
+		Find(&orders).Error
+	return orders, err
+}
+
+// MarkDispatchAlerted records that orders were reported as close to their
+// handling deadline.
+func (r *OrderRepository) MarkDispatchAlerted(ctx context.Context, ids []int64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Model(&Order{}).
+		Where("id IN ?", ids).
+		Update("dispatch_alerted_at", at).Error
+}
+
+// MarkDispatchLate records that orders missed their handling deadline. An
+// order keeps the first time it was seen late.
+func (r *OrderRepository) MarkDispatchLate(ctx context.Context, ids []int64, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Model(&Order{}).
+		Where("id IN ? AND dispatch_late_at IS NULL", ids).
+		Update("dispatch_late_at", at).Error
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Organization is a tenant in multi-tenant mode: the rows of every table
+// with an OrgID belong to one. Its API key is only kept as a SHA-256 hash.
+// SellerID, AccessToken and RefreshToken are the Mercado Livre account it
+// connected, see /api/org/login. The Plan fields are its subscription, kept
+// by the payment provider's webhooks.
+type Organization struct {
+	ID           uint   `gorm:"primaryKey"`
+	Name         string `gorm:"size:128;not null"`
+	KeyHash      string `gorm:"size:64;uniqueIndex;not null"`
+	SellerID     int64  `gorm:"index;not null;default:0"`
+	AccessToken  string `gorm:"type:text"`
+	RefreshToken string `gorm:"type:text"`
+
+	Plan          string `gorm:"size:32;not null;default:''"`
+	PlanStatus    string `gorm:"size:16;not null;default:''"`
+	PlanPeriodEnd *time.Time
+	// GraceUntil is when a past-due plan stops working
+	GraceUntil            *time.Time
+	BillingCustomerID     string `gorm:"size:64;index"`
+	BillingSubscriptionID string `gorm:"size:64"`
+	// BillingEventAt is the time of the last provider event applied, so
+	// events delivered out of order don't undo newer ones
+	BillingEventAt *time.Time
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// OrgLogin is a Mercado Livre login an organization started, kept until its
+// OAuth callback arrives, on whichever instance, or it expires. Expiry
+// comes from the database clock.
+type OrgLogin struct {
+	State     string    `gorm:"primaryKey;size:64"`
+	OrgID     uint      `gorm:"not null;default:0;index"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+}
+
+// Plan statuses of an organization.
+const (
+	PlanStatusActive   = "active"
+	PlanStatusPastDue  = "past_due"
+	PlanStatusInactive = "inactive"
+)
+
+// ScopedKey prefixes a cache key with the organization of ctx in
+// multi-tenant mode, so results computed from one organization's rows are
+// never served to another.
+func ScopedKey(ctx context.Context, key string) string {
+	if id, ok := database.OrgFromContext(ctx); ok {
+		return fmt.Sprintf("org:%d:%s", id, key)
+	}
+	return key
+}
+
+type OrganizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository() *OrganizationRepository {
+	return &OrganizationRepository{
+		db: database.DB,
+	}
+}
+
+// Create persists a new organization.
+func (r *OrganizationRepository) Create(ctx context.Context, org *Organization) error {
+	return r.db.WithContext(ctx).Create(org).Error
+}
+
+// List returns every organization, oldest first.
+func (r *OrganizationRepository) List(ctx context.Context) ([]Organization, error) {
+	var orgs []Organization
+	err := r.db.WithContext(ctx).Order("id").Find(&orgs).Error
+	return orgs, err
+}
+
+// Find returns an organization, or nil if it does not exist.
+func (r *OrganizationRepository) Find(ctx context.Context, id uint) (*Organization, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+// FindByKeyHash returns the organization of an API key hash, or nil.
+func (r *OrganizationRepository) FindByKeyHash(ctx context.Context, hash string) (*Organization, error) {
+	return r.first(ctx, "key_hash = ?", hash)
+}
+
+// FindBySeller returns the organization that connected a Mercado Livre
+// account, or nil.
+func (r *OrganizationRepository) FindBySeller(ctx context.Context, sellerID int64) (*Organization, error) {
+	return r.first(ctx, "seller_id = ?", sellerID)
+}
+
+// FindByBillingCustomer returns the organization of a payment provider
+// customer, or nil.
+func (r *OrganizationRepository) FindByBillingCustomer(ctx context.Context, customerID string) (*Organization, error) {
+	return r.first(ctx, "billing_customer_id = ?", customerID)
+}
+
+func (r *OrganizationRepository) first(ctx context.Context, query string, arg interface{}) (*Organization, error) {
+	var org Organization
+	err := r.db.WithContext(ctx).Where(query, arg).Order("id").First(&org).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// SetKeyHash replaces the API key of an organization. It reports whether
+// the organization exists.
+func (r *OrganizationRepository) SetKeyHash(ctx context.Context, id uint, hash string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&Organization{}).Where("id = ?", id).Update("key_hash", hash)
+	return res.RowsAffected > 0, res.Error
+}
+
+// SetAccount stores the Mercado Livre account an organization connected.
+func (r *OrganizationRepository) SetAccount(ctx context.Context, id uint, sellerID int64, accessToken, refreshToken string) error {
+	return r.db.WithContext(ctx).Model(&Organization{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"seller_id":     sellerID,
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	}).Error
+}
+
+// SetTokens stores a refreshed token pair.
+func (r *OrganizationRepository) SetTokens(ctx context.Context, id uint, accessToken, refreshToken string) error {
+	return r.db.WithContext(ctx).Model(&Organization{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	}).Error
+}
+
+// StartLogin stores a login of an organization that expires after ttl.
+func (r *OrganizationRepository) StartLogin(ctx context.Context, orgID uint, state string, ttl time.Duration) error {
+	return r.db.WithContext(ctx).Model(&OrgLogin{}).Create(map[string]interface{}{
+		"state":      state,
+		"org_id":     orgID,
+		"expires_at": leaseEnd(ttl),
+	}).Error
+}
+
+// PendingLogin reports whether state belongs to a login that has not
+// expired.
+func (r *OrganizationRepository) PendingLogin(ctx context.Context, state string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(database.AllOrgs(ctx)).
+		Model(&OrgLogin{}).
+		Where("state = ? AND expires_at > NOW()", state).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// TakeLogin deletes the login of state and returns its organization; ok is
+// false when there is none or it expired. Only one caller takes a login.
+func (r *OrganizationRepository) TakeLogin(ctx context.Context, state string) (orgID uint, ok bool, err error) {
+	var logins []OrgLogin
+	err = r.db.WithContext(database.AllOrgs(ctx)).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "org_id"}}}).
+		Where("state = ? AND expires_at > NOW()", state).
+		Delete(&logins).Error
+	if err != nil || len(logins) == 0 {
+		return 0, false, err
+	}
+	return logins[0].OrgID, true, nil
+}
+
+// PurgeLogins deletes the logins that expired.
+func (r *OrganizationRepository) PurgeLogins(ctx context.Context) error {
+	return r.db.WithContext(database.AllOrgs(ctx)).
+		Where("expires_at < NOW()").
+		Delete(&OrgLogin{}).Error
+}
+
+// SaveBilling stores the plan and billing fields of an organization.
+func (r *OrganizationRepository) SaveBilling(ctx context.Context, org *Organization) error {
+	return r.db.WithContext(ctx).Model(&Organization{}).Where("id = ?", org.ID).Updates(map[string]interface{}{
+		"plan":                    org.Plan,
+		"plan_status":             org.PlanStatus,
+		"plan_period_end":         org.PlanPeriodEnd,
+		"grace_until":             org.GraceUntil,
+		"billing_customer_id":     org.BillingCustomerID,
+		"billing_subscription_id": org.BillingSubscriptionID,
+		"billing_event_at":        org.BillingEventAt,
+	}).Error
+}
+
+// ListGraceExpired returns the past-due organizations whose grace period
+// ended before now.
+func (r *OrganizationRepository) ListGraceExpired(ctx context.Context, now time.Time) ([]Organization, error) {
+	var orgs []Organization
+	err := r.db.WithContext(ctx).
+		Where("plan_status = ? AND grace_until < ?", PlanStatusPastDue, now).
+		Order("id").Find(&orgs).Error
+	return orgs, err
+}
+
+// Delete removes an organization; its rows are kept. It reports whether the
+// organization existed.
+func (r *OrganizationRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Delete(&Organization{}, id)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Adopt hands the rows that belong to no organization, e.g. those stored
+// before multi-tenant mode was turned on, to an organization. It returns
+// how many rows moved.
+func (r *OrganizationRepository) Adopt(ctx context.Context, id uint) (int64, error) {
+	ctx = database.AllOrgs(ctx)
+	var moved int64
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: r.db}
+		if err := stmt.Parse(model); err != nil {
+			return moved, err
+		}
+		if stmt.Schema.LookUpField("OrgID") == nil {
+			continue
+		}
+		res := r.db.WithContext(ctx).Model(model).Where("org_id = ?", 0).Update("org_id", id)
+		if res.Error != nil {
+			return moved, fmt.Errorf("adopt %s: %w", stmt.Schema.Table, res.Error)
+		}
+		moved += res.RowsAffected
+	}
+	return moved, nil
+}
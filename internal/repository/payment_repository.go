@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Payment is a Mercado Pago payment collected by the seller, with what
+// settling it costs and when its money is released. The primary key is
+// Mercado Pago's payment ID; OrderID is the Mercado Livre order it paid.
+type Payment struct {
+	ID                 int64   `gorm:"primaryKey;autoIncrement:false"`
+	OrderID            int64   `gorm:"index;not null;default:0"`
+	SellerID           int64   `gorm:"index;not null"`
+	Status             string  `gorm:"size:32;index"`
+	StatusDetail       string  `gorm:"size:64"`
+	Currency           string  `gorm:"size:8"`
+	Amount             float64 `gorm:"not null"`
+	RefundedAmount     float64
+	Fees               float64
+	NetAmount          float64
+	DateApproved       *time.Time
+	MoneyReleaseDate   *time.Time `gorm:"index"`
+	MoneyReleaseStatus string     `gorm:"size:32"`
+	LastUpdated        time.Time  `gorm:"index"`
+	OrgID              uint       `gorm:"not null;default:0;index"`
+	Sandbox            bool       `gorm:"not null;default:false"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+type PaymentRepository struct {
+	db *gorm.DB
+}
+
+func NewPaymentRepository() *PaymentRepository {
+	return &PaymentRepository{
+		db: database.DB,
+	}
+}
+
+// Upsert inserts or updates a payment.
+func (r *PaymentRepository) Upsert(ctx context.Context, payment *Payment) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"order_id", "status", "status_detail", "amount", "refunded_amount", "fees", "net_amount",
+				"date_approved", "money_release_date", "money_release_status", "last_updated", "updated_at",
+			}),
+		}).
+		Create(payment).Error
+}
+
+// LastUpdated returns the most recent last_updated among a seller's stored
+// payments, or the zero time if there are none.
+func (r *PaymentRepository) LastUpdated(ctx context.Context, sellerID int64) (time.Time, error) {
+	var last *time.Time
+	err := r.db.WithContext(ctx).
+		Model(&Payment{}).
+		Select("MAX(last_updated)").
+		Where("seller_id = ?", sellerID).
+		Scan(&last).Error
+	if err != nil || last == nil {
+		return time.Time{}, err
+	}
+	return *last, nil
+}
+
+// ForOrders returns the stored payments of the given orders, oldest first.
+func (r *PaymentRepository) ForOrders(ctx context.Context, orderIDs []int64) ([]Payment, error) {
+	var payments []Payment
+	if len(orderIDs) == 0 {
+		return payments, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("order_id IN ?", orderIDs).
+		Order("date_approved, id").
+		Find(&payments).Error
+	return payments, err
+}
+
+// PendingRelease returns a seller's approved payments whose money is not
+// released yet, by release date.
+func (r *PaymentRepository) PendingRelease(ctx context.Context, sellerID int64) ([]Payment, error) {
+	var payments []Payment
+	err := r.db.WithContext(ctx).
+		Where("seller_id = ? AND status = ? AND money_release_status <> ?", sellerID, "approved", "released").
+		Order("money_release_date, id").
+		Find(&payments).Error
+	return payments, err
+}
+
+// RecentlyReleased returns up to limit of a seller's released payments with
+// their approval and release dates, newest first.
+func (r *PaymentRepository) RecentlyReleased(ctx context.Context, sellerID int64, limit int) ([]Payment, error) {
+	var payments []Payment
+	err := r.db.WithContext(ctx).
+		Where("seller_id = ? AND money_release_status = ? AND date_approved IS NOT NULL AND money_release_date IS NOT NULL", sellerID, "released").
+		Order("money_release_date DESC").
+		Limit(limit).
+		Find(&payments).Error
+	return payments, err
+}
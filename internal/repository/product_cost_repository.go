@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProductCost is the seller's unit cost of a SKU (purchase price plus
+// anything else paid per unit before selling it).
+type ProductCost struct {
+	ID        uint    `gorm:"primaryKey"`
+	SKU       string  `gorm:"size:128;uniqueIndex:idx_product_costs_org_sku;not null"`
+	UnitCost  float64 `gorm:"not null"`
+	Currency  string  `gorm:"size:8"`
+	OrgID     uint    `gorm:"not null;default:0;uniqueIndex:idx_product_costs_org_sku,priority:1"`
+	Sandbox   bool    `gorm:"not null;default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ProductCostRepository struct {
+	db *gorm.DB
+}
+
+func NewProductCostRepository() *ProductCostRepository {
+	return &ProductCostRepository{
+		db: database.DB,
+	}
+}
+
+// List returns every recorded cost ordered by SKU.
+func (r *ProductCostRepository) List(ctx context.Context) ([]ProductCost, error) {
+	var costs []ProductCost
+	err := r.db.WithContext(ctx).Order("sku").Find(&costs).Error
+	return costs, err
+}
+
+// Upsert records the unit cost of a SKU, replacing the previous one.
+func (r *ProductCostRepository) Upsert(ctx context.Context, cost *ProductCost) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "org_id"}, {Name: "sku"}},
+			DoUpdates: clause.AssignmentColumns([]string{"unit_cost", "currency", "updated_at"}),
+		}).
+		Create(cost).Error
+}
+
+// Delete removes the cost of a SKU. It reports whether one was recorded.
+func (r *ProductCostRepository) Delete(ctx context.Context, sku string) (bool, error) {
+	res := r.db.WithContext(ctx).Where("sku = ?", sku).Delete(&ProductCost{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// FindBySKU returns the cost of a SKU, or nil if none is recorded.
+func (r *ProductCostRepository) FindBySKU(ctx context.Context, sku string) (*ProductCost, error) {
+	var cost ProductCost
+	err := r.db.WithContext(ctx).Where("sku = ?", sku).First(&cost).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cost, nil
+}
+
+// CostsBySKU returns the unit costs keyed by SKU.
+func (r *ProductCostRepository) CostsBySKU(ctx context.Context) (map[string]float64, error) {
+	costs, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]float64, len(costs))
+	for _, c := range costs {
+		out[c.SKU] = c.UnitCost
+	}
+	return out, nil
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Template match types.
+const (
+	MatchTypeKeywords = "keywords"
+	MatchTypeRegex    = "regex"
+)
+
+// AnswerTemplate is a canned answer for questions matching Pattern. For
+// MatchTypeKeywords Pattern is a comma-separated keyword list, for
+// MatchTypeRegex a regular expression. ItemID and CategoryID restrict the
+// template to one listing or one category; both empty means every listing.
+type AnswerTemplate struct {
+	ID         uint   `gorm:"primaryKey"`
+	Name       string `gorm:"size:128;not null"`
+	MatchType  string `gorm:"size:16;not null"`
+	Pattern    string `gorm:"type:text;not null"`
+	Answer     string `gorm:"type:text;not null"`
+	ItemID     string `gorm:"size:64;index"`
+	CategoryID string `gorm:"size:64;index"`
+	Enabled    bool   `gorm:"not null;default:true"`
+	Version    uint   `gorm:"not null;default:1"`
+	OrgID      uint   `gorm:"not null;default:0;index"`
+	Sandbox    bool   `gorm:"not null;default:false"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+}
+
+// AutoResponderSettings is the single row of auto-responder settings.
+// Answers are only sent when Enabled; matches below MinConfidence wait for
+// review instead.
+type AutoResponderSettings struct {
+	ID            uint    `gorm:"primaryKey"`
+	Enabled       bool    `gorm:"not null;default:false"`
+	MinConfidence float64 `gorm:"not null"`
+	OrgID         uint    `gorm:"not null;default:0;index"`
+	Sandbox       bool    `gorm:"not null;default:false"`
+	UpdatedAt     time.Time
+}
+
+// AutoResponderOptOut excludes a listing from the auto-responder.
+type AutoResponderOptOut struct {
+	ID        uint   `gorm:"primaryKey"`
+	ItemID    string `gorm:"size:64;uniqueIndex:idx_auto_responder_opt_outs_org_item;not null"`
+	OrgID     uint   `gorm:"not null;default:0;uniqueIndex:idx_auto_responder_opt_outs_org_item,priority:1"`
+	Sandbox   bool   `gorm:"not null;default:false"`
+	CreatedAt time.Time
+}
+
+// Question match statuses.
+const (
+	MatchStatusPending      = "pending"
+	MatchStatusAutoAnswered = "auto_answered"
+	MatchStatusAnswered     = "answered"
+	MatchStatusDismissed    = "dismissed"
+)
+
+// QuestionMatch is a question a template matched, either answered right
+// away or waiting for review.
+type QuestionMatch struct {
+	ID              uint    `gorm:"primaryKey"`
+	QuestionID      int64   `gorm:"uniqueIndex:idx_question_matches_org_question;not null"`
+	SellerID        int64   `gorm:"index;not null;default:0"`
+	ItemID          string  `gorm:"size:64;index;not null"`
+	Text            string  `gorm:"type:text;not null"`
+	TemplateID      uint    `gorm:"index"`
+	SuggestedAnswer string  `gorm:"type:text;not null"`
+	Confidence      float64 `gorm:"not null"`
+	Status          string  `gorm:"size:16;index;not null"`
+	AnswerText      string  `gorm:"type:text"`
+	AnsweredAt      *time.Time
+	OrgID           uint `gorm:"not null;default:0;uniqueIndex:idx_question_matches_org_question,priority:1"`
+	Sandbox         bool `gorm:"not null;default:false"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// autoResponderSettingsID is the primary key of the settings row.
+const autoResponderSettingsID = 1
+
+type QuestionRepository struct {
+	db *gorm.DB
+}
+
+func NewQuestionRepository() *QuestionRepository {
+	return &QuestionRepository{
+		db: database.DB,
+	}
+}
+
+// Templates returns every answer template.
+func (r *QuestionRepository) Templates(ctx context.Context) ([]AnswerTemplate, error) {
+	var templates []AnswerTemplate
+	err := r.db.WithContext(ctx).Order("id").Find(&templates).Error
+	return templates, err
+}
+
+// FindTemplate returns a template by ID, or nil if it does not exist.
+func (r *QuestionRepository) FindTemplate(ctx context.Context, id uint) (*AnswerTemplate, error) {
+	var t AnswerTemplate
+	err := r.db.WithContext(ctx).First(&t, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SaveTemplate creates or updates a template.
+func (r *QuestionRepository) SaveTemplate(ctx context.Context, t *AnswerTemplate) error {
+	return r.db.WithContext(ctx).Save(t).Error
+}
+
+// DeleteTemplate moves a template to the trash. It reports whether it
+// existed.
+func (r *QuestionRepository) DeleteTemplate(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Delete(&AnswerTemplate{}, id)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Settings returns the auto-responder settings, or nil if none were saved.
+func (r *QuestionRepository) Settings(ctx context.Context) (*AutoResponderSettings, error) {
+	var s AutoResponderSettings
+	err := r.db.WithContext(ctx).First(&s, autoResponderSettingsID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SaveSettings replaces the auto-responder settings.
+func (r *QuestionRepository) SaveSettings(ctx context.Context, s *AutoResponderSettings) error {
+	s.ID = autoResponderSettingsID
+	return r.db.WithContext(ctx).Save(s).Error
+}
+
+// OptedOutItems returns the IDs of listings excluded from the auto-responder.
+func (r *QuestionRepository) OptedOutItems(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&AutoResponderOptOut{}).Order("item_id").Pluck("item_id", &ids).Error
+	return ids, err
+}
+
+// IsOptedOut reports whether a listing is excluded from the auto-responder.
+func (r *QuestionRepository) IsOptedOut(ctx context.Context, itemID string) (bool, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&AutoResponderOptOut{}).Where("item_id = ?", itemID).Count(&n).Error
+	return n > 0, err
+}
+
+// OptOut excludes a listing from the auto-responder.
+func (r *QuestionRepository) OptOut(ctx context.Context, itemID string) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "org_id"}, {Name: "item_id"}}, DoNothing: true}).
+		Create(&AutoResponderOptOut{ItemID: itemID}).Error
+}
+
+// OptIn includes a listing in the auto-responder again.
+func (r *QuestionRepository) OptIn(ctx context.Context, itemID string) error {
+	return r.db.WithContext(ctx).Where("item_id = ?", itemID).Delete(&AutoResponderOptOut{}).Error
+}
+
+// CreateMatch records a matched question. It reports false when the question
+// was already handled, e.g. on a redelivered notification.
+func (r *QuestionRepository) CreateMatch(ctx context.Context, m *QuestionMatch) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "org_id"}, {Name: "question_id"}}, DoNothing: true}).
+		Create(m)
+	return res.RowsAffected > 0, res.Error
+}
+
+// FindMatch returns a matched question by ID, or nil if it does not exist.
+func (r *QuestionRepository) FindMatch(ctx context.Context, id uint) (*QuestionMatch, error) {
+	var m QuestionMatch
+	err := r.db.WithContext(ctx).First(&m, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SaveMatch updates a matched question.
+func (r *QuestionRepository) SaveMatch(ctx context.Context, m *QuestionMatch) error {
+	return r.db.WithContext(ctx).Save(m).Error
+}
+
+// Matches returns matched questions, newest first, optionally only those in
+// one status.
+func (r *QuestionRepository) Matches(ctx context.Context, status string, limit int) ([]QuestionMatch, error) {
+	q := r.db.WithContext(ctx).Order("created_at DESC").Limit(limit)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var matches []QuestionMatch
+	err := q.Find(&matches).Error
+	return matches, err
+}
+
+// SearchMatches returns the matched questions asked to a seller, newest
+// first, whose text full-text matches the query or contains it, or whose
+// question or item ID is it.
+func (r *QuestionRepository) SearchMatches(ctx context.Context, sellerID int64, query string, limit int) ([]QuestionMatch, error) {
+	cond := "to_tsvector('portuguese', text) @@ plainto_tsquery('portuguese', ?) OR text ILIKE ? OR item_id = ?"
+	vars := []interface{}{query, containsPattern(query), query}
+	if id, err := strconv.ParseInt(query, 10, 64); err == nil {
+		cond, vars = cond+" OR question_id = ?", append(vars, id)
+	}
+
+	var matches []QuestionMatch
+	err := r.db.WithContext(ctx).
+		Where("seller_id = ?", sellerID).
+		Where(cond, vars...).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&matches).Error
+	return matches, err
+}
+
+// CountMatches returns how many matched questions are in a status.
+func (r *QuestionRepository) CountMatches(ctx context.Context, status string) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&QuestionMatch{}).Where("status = ?", status).Count(&n).Error
+	return n, err
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RankTracker is a keyword whose search results are checked for one of the
+// seller's listings. Position and Page are those of the last check, nil
+// when the listing was not found within the tracked depth.
+type RankTracker struct {
+	ID            uint   `gorm:"primaryKey"`
+	Keyword       string `gorm:"size:256;uniqueIndex:idx_rank_trackers_org;not null"`
+	ItemID        string `gorm:"size:64;uniqueIndex:idx_rank_trackers_org;not null"`
+	Position      *int
+	Page          *int
+	LastCheckedAt *time.Time
+	OrgID         uint `gorm:"not null;default:0;uniqueIndex:idx_rank_trackers_org,priority:1"`
+	Sandbox       bool `gorm:"not null;default:false"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// RankSnapshot is the position of a tracked listing at one check. Dropped
+// is set when it lost enough positions to raise an alert.
+type RankSnapshot struct {
+	ID        uint `gorm:"primaryKey"`
+	TrackerID uint `gorm:"index;not null"`
+	Position  *int
+	Page      *int
+	// Total is how many results the search reported.
+	Total     int       `gorm:"not null;default:0"`
+	Dropped   bool      `gorm:"not null;default:false"`
+	OrgID     uint      `gorm:"not null;default:0;index"`
+	Sandbox   bool      `gorm:"not null;default:false"`
+	CheckedAt time.Time `gorm:"index;not null"`
+}
+
+type RankRepository struct {
+	db *gorm.DB
+}
+
+func NewRankRepository() *RankRepository {
+	return &RankRepository{
+		db: database.DB,
+	}
+}
+
+// List returns the rank trackers, by keyword.
+func (r *RankRepository) List(ctx context.Context) ([]RankTracker, error) {
+	var trackers []RankTracker
+	err := r.db.WithContext(ctx).Order("keyword, item_id").Find(&trackers).Error
+	return trackers, err
+}
+
+// Find returns a rank tracker, or nil if there is none with the ID.
+func (r *RankRepository) Find(ctx context.Context, id uint) (*RankTracker, error) {
+	var t RankTracker
+	err := r.db.WithContext(ctx).First(&t, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create stores a tracker, or loads the existing one of the same keyword
+// and listing into t.
+func (r *RankRepository) Create(ctx context.Context, t *RankTracker) error {
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(t).Error
+	if err != nil || t.ID != 0 {
+		return err
+	}
+	return r.db.WithContext(ctx).Where("keyword = ? AND item_id = ?", t.Keyword, t.ItemID).First(t).Error
+}
+
+// Delete removes a tracker and its snapshots. It reports whether the
+// tracker existed.
+func (r *RankRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	var deleted bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&RankTracker{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		deleted = res.RowsAffected > 0
+		return tx.Where("tracker_id = ?", id).Delete(&RankSnapshot{}).Error
+	})
+	return deleted, err
+}
+
+// SaveSnapshot stores a check of a tracker and its new position in one
+// transaction.
+func (r *RankRepository) SaveSnapshot(ctx context.Context, t *RankTracker, snapshot *RankSnapshot) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(t).Error; err != nil {
+			return err
+		}
+		snapshot.TrackerID = t.ID
+		return tx.Create(snapshot).Error
+	})
+}
+
+// History returns the snapshots of a tracker since a point in time, oldest
+// first.
+func (r *RankRepository) History(ctx context.Context, trackerID uint, since time.Time) ([]RankSnapshot, error) {
+	var snapshots []RankSnapshot
+	err := r.db.WithContext(ctx).
+		Where("tracker_id = ? AND checked_at >= ?", trackerID, since).
+		Order("checked_at").
+		Find(&snapshots).Error
+	return snapshots, err
+}
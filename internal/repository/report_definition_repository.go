@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// Report schedules.
+const (
+	ReportScheduleHourly = "hourly"
+	ReportScheduleDaily  = "daily"
+	ReportScheduleWeekly = "weekly"
+)
+
+// Report destinations.
+const (
+	// ReportDestinationStorage uploads the report to the export bucket.
+	ReportDestinationStorage = "storage"
+	// ReportDestinationWebhook POSTs the report to DestinationURL, signed
+	// with Secret.
+	ReportDestinationWebhook = "webhook"
+)
+
+// ReportDefinition is a report a user built: which rows of a data source it
+// reads over its last Days, how it filters and groups them, the metrics it
+// computes and where and how often it is delivered. Filters and Metrics are
+// JSON arrays and GroupBy is a comma-separated list of fields. An empty
+// Schedule only runs on demand.
+type ReportDefinition struct {
+	ID             uint       `gorm:"primaryKey"`
+	Name           string     `gorm:"size:128;not null"`
+	Source         string     `gorm:"size:32;not null"`
+	Filters        string     `gorm:"type:text"`
+	GroupBy        string     `gorm:"type:text"`
+	Metrics        string     `gorm:"type:text;not null"`
+	Days           int        `gorm:"not null;default:30"`
+	Schedule       string     `gorm:"size:16"`
+	Format         string     `gorm:"size:8;not null;default:csv"`
+	Destination    string     `gorm:"size:16;not null;default:storage"`
+	DestinationURL string     `gorm:"size:1024"`
+	Secret         string     `gorm:"size:128"`
+	Enabled        bool       `gorm:"not null;default:true"`
+	NextRunAt      *time.Time `gorm:"index"`
+	LastRunAt      *time.Time
+	LastRows       int
+	LastKey        string `gorm:"size:512"`
+	LastError      string `gorm:"type:text"`
+	OrgID          uint   `gorm:"not null;default:0;index"`
+	Sandbox        bool   `gorm:"not null;default:false"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type ReportDefinitionRepository struct {
+	db *gorm.DB
+}
+
+func NewReportDefinitionRepository() *ReportDefinitionRepository {
+	return &ReportDefinitionRepository{
+		db: database.DB,
+	}
+}
+
+// List returns every report definition.
+func (r *ReportDefinitionRepository) List(ctx context.Context) ([]ReportDefinition, error) {
+	var defs []ReportDefinition
+	err := r.db.WithContext(ctx).Order("id").Find(&defs).Error
+	return defs, err
+}
+
+// Find returns a report definition, or nil if it does not exist.
+func (r *ReportDefinitionRepository) Find(ctx context.Context, id uint) (*ReportDefinition, error) {
+	var def ReportDefinition
+	err := r.db.WithContext(ctx).First(&def, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// Save creates or updates a report definition.
+func (r *ReportDefinitionRepository) Save(ctx context.Context, def *ReportDefinition) error {
+	return r.db.WithContext(ctx).Save(def).Error
+}
+
+// Delete removes a report definition. It reports whether it existed.
+func (r *ReportDefinitionRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Delete(&ReportDefinition{}, id)
+	return res.RowsAffected > 0, res.Error
+}
+
+// Due returns the enabled, scheduled definitions whose next run is at or
+// before now.
+func (r *ReportDefinitionRepository) Due(ctx context.Context, now time.Time) ([]ReportDefinition, error) {
+	var defs []ReportDefinition
+	err := r.db.WithContext(ctx).
+		Where("enabled AND schedule <> '' AND next_run_at <= ?", now).
+		Order("next_run_at").
+		Find(&defs).Error
+	return defs, err
+}
+
+// RecordRun stores the outcome of a run of a definition and when it runs
+// next.
+func (r *ReportDefinitionRepository) RecordRun(ctx context.Context, id uint, at time.Time, next *time.Time, rows int, key, runErr string) error {
+	return r.db.WithContext(ctx).
+		Model(&ReportDefinition{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_run_at": at,
+			"next_run_at": next,
+			"last_rows":   rows,
+			"last_key":    key,
+			"last_error":  runErr,
+		}).Error
+}
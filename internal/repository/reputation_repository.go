@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// ReputationAlert records that one of the seller's reputation metrics got
+// within the alert margin of the limit of its level. It stays open until
+// the metric is back under the margin, so an alert fires once per episode.
+type ReputationAlert struct {
+	ID       uint   `gorm:"primaryKey"`
+	SellerID int64  `gorm:"index;not null"`
+	Metric   string `gorm:"size:32;not null"`
+	Level    string `gorm:"size:32;not null"`
+	// Rate and Limit are percentages of the sales of the period.
+	Rate       float64 `gorm:"not null"`
+	Limit      float64 `gorm:"not null"`
+	Count      int     `gorm:"not null"`
+	Sales      int     `gorm:"not null"`
+	ResolvedAt *time.Time
+	OrgID      uint      `gorm:"not null;default:0;index"`
+	Sandbox    bool      `gorm:"not null;default:false"`
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+type ReputationRepository struct {
+	db *gorm.DB
+}
+
+func NewReputationRepository() *ReputationRepository {
+	return &ReputationRepository{
+		db: database.DB,
+	}
+}
+
+// CountSales counts a seller's orders created in [from, to) that were paid
+// or cancelled.
+func (r *ReputationRepository) CountSales(ctx context.Context, sellerID int64, from, to time.Time) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).
+		Model(&Order{}).
+		Where("seller_id = ? AND status IN ? AND date_created >= ? AND date_created < ?", sellerID, []string{OrderStatusPaid, OrderStatusCancelled}, from, to).
+		Count(&n).Error
+	return n, err
+}
+
+// Sales returns a seller's orders created in [from, to) that were paid or
+// cancelled, with their lines, newest first.
+func (r *ReputationRepository) Sales(ctx context.Context, sellerID int64, from, to time.Time) ([]Order, error) {
+	var orders []Order
+	err := r.db.WithContext(ctx).
+		Preload("Items").
+		Where("seller_id = ? AND status IN ? AND date_created >= ? AND date_created < ?", sellerID, []string{OrderStatusPaid, OrderStatusCancelled}, from, to).
+		Order("date_created DESC").
+		Find(&orders).Error
+	return orders, err
+}
+
+// Claims returns the claims about a seller's orders created in [from, to).
+func (r *ReputationRepository) Claims(ctx context.Context, sellerID int64, from, to time.Time) ([]Claim, error) {
+	var claims []Claim
+	err := r.db.WithContext(ctx).
+		Joins("JOIN orders ON orders.id = claims.order_id").
+		Where("claims.seller_id = ? AND orders.date_created >= ? AND orders.date_created < ?", sellerID, from, to).
+		Order("claims.date_created").
+		Find(&claims).Error
+	return claims, err
+}
+
+// OpenAlerts returns a seller's unresolved reputation alerts keyed by metric.
+func (r *ReputationRepository) OpenAlerts(ctx context.Context, sellerID int64) (map[string]*ReputationAlert, error) {
+	var alerts []ReputationAlert
+	if err := r.db.WithContext(ctx).Where("seller_id = ? AND resolved_at IS NULL", sellerID).Find(&alerts).Error; err != nil {
+		return nil, err
+	}
+	open := make(map[string]*ReputationAlert, len(alerts))
+	for i := range alerts {
+		open[alerts[i].Metric] = &alerts[i]
+	}
+	return open, nil
+}
+
+// CreateAlert stores a new reputation alert.
+func (r *ReputationRepository) CreateAlert(ctx context.Context, alert *ReputationAlert) error {
+	return r.db.WithContext(ctx).Create(alert).Error
+}
+
+// ResolveAlerts closes the given alerts.
+func (r *ReputationRepository) ResolveAlerts(ctx context.Context, ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Model(&ReputationAlert{}).
+		Where("id IN ?", ids).
+		Update("resolved_at", at).Error
+}
+
+// Alerts returns a seller's most recent reputation alerts, open ones only
+// when openOnly is set.
+func (r *ReputationRepository) Alerts(ctx context.Context, sellerID int64, openOnly bool, limit int) ([]ReputationAlert, error) {
+	q := r.db.WithContext(ctx).Where("seller_id = ?", sellerID).Order("created_at DESC").Limit(limit)
+	if openOnly {
+		q = q.Where("resolved_at IS NULL")
+	}
+	var alerts []ReputationAlert
+	err := q.Find(&alerts).Error
+	return alerts, err
+}
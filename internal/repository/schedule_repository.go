@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ScheduleSetting overrides the schedule of a scheduled task, shared by
+// every instance. An empty Cron keeps the task's default interval.
+type ScheduleSetting struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"size:64;not null;uniqueIndex"`
+	Cron      string `gorm:"size:128"`
+	Enabled   bool   `gorm:"not null"`
+	Sandbox   bool   `gorm:"not null;default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ScheduleRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduleRepository() *ScheduleRepository {
+	return &ScheduleRepository{
+		db: database.DB,
+	}
+}
+
+// List returns every stored setting.
+func (r *ScheduleRepository) List(ctx context.Context) ([]ScheduleSetting, error) {
+	var settings []ScheduleSetting
+	err := r.db.WithContext(ctx).Order("name").Find(&settings).Error
+	return settings, err
+}
+
+// Save creates or replaces the setting of a task.
+func (r *ScheduleRepository) Save(ctx context.Context, setting *ScheduleSetting) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"cron", "enabled", "updated_at"}),
+		}).
+		Create(setting).Error
+}
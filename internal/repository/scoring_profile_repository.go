@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// DefaultScoringProfile is the profile used when none is selected.
+const DefaultScoringProfile = "default"
+
+// ScoringProfile weighs the signals of the opportunity score. Weights are
+// relative; PriceBandMin/PriceBandMax, when set, is the price range the
+// profile is interested in.
+type ScoringProfile struct {
+	ID                uint    `gorm:"primaryKey"`
+	Name              string  `gorm:"size:64;uniqueIndex:idx_scoring_profiles_org_name;not null"`
+	WeightSold        float64 `gorm:"not null;default:0"`
+	WeightPrice       float64 `gorm:"not null;default:0"`
+	WeightCompetition float64 `gorm:"not null;default:0"`
+	WeightHealth      float64 `gorm:"not null;default:0"`
+	PriceBandMin      *float64
+	PriceBandMax      *float64
+	OrgID             uint `gorm:"not null;default:0;uniqueIndex:idx_scoring_profiles_org_name,priority:1"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+type ScoringProfileRepository struct {
+	db *gorm.DB
+}
+
+func NewScoringProfileRepository() *ScoringProfileRepository {
+	return &ScoringProfileRepository{
+		db: database.DB,
+	}
+}
+
+// List returns every profile ordered by name.
+func (r *ScoringProfileRepository) List(ctx context.Context) ([]ScoringProfile, error) {
+	var profiles []ScoringProfile
+	err := r.db.WithContext(ctx).Order("name").Find(&profiles).Error
+	return profiles, err
+}
+
+// FindByName returns a profile, or nil if it does not exist.
+func (r *ScoringProfileRepository) FindByName(ctx context.Context, name string) (*ScoringProfile, error) {
+	var p ScoringProfile
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Save creates or updates a profile.
+func (r *ScoringProfileRepository) Save(ctx context.Context, p *ScoringProfile) error {
+	return r.db.WithContext(ctx).Save(p).Error
+}
+
+// Delete removes a profile. It reports whether the profile existed.
+func (r *ScoringProfileRepository) Delete(ctx context.Context, name string) (bool, error) {
+	res := r.db.WithContext(ctx).Where("name = ?", name).Delete(&ScoringProfile{})
+	return res.RowsAffected > 0, res.Error
+}
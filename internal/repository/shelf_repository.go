@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// ShelfSnapshot is a sample of the top search results of a category:
+// Results is how many listings were sampled and Shares how many of them
+// each seller had.
+type ShelfSnapshot struct {
+	ID         uint   `gorm:"primaryKey"`
+	CategoryID string `gorm:"size:32;index;not null"`
+	Results    int    `gorm:"not null"`
+	Shares     []ShelfShare
+	OrgID      uint      `gorm:"not null;default:0;index"`
+	Sandbox    bool      `gorm:"not null;default:false"`
+	TakenAt    time.Time `gorm:"index;not null"`
+}
+
+// ShelfShare is how many listings of a snapshot belonged to a seller.
+type ShelfShare struct {
+	ID              uint  `gorm:"primaryKey"`
+	ShelfSnapshotID uint  `gorm:"index;not null"`
+	SellerID        int64 `gorm:"index;not null"`
+	Listings        int   `gorm:"not null"`
+	OrgID           uint  `gorm:"not null;default:0;index"`
+	Sandbox         bool  `gorm:"not null;default:false"`
+}
+
+type ShelfRepository struct {
+	db *gorm.DB
+}
+
+func NewShelfRepository() *ShelfRepository {
+	return &ShelfRepository{
+		db: database.DB,
+	}
+}
+
+// Save stores a snapshot with its shares.
+func (r *ShelfRepository) Save(ctx context.Context, snapshot *ShelfSnapshot) error {
+	return r.db.WithContext(ctx).Create(snapshot).Error
+}
+
+// Snapshots returns the snapshots taken in [from, to) with their shares,
+// oldest first, of one category when categoryID is set.
+func (r *ShelfRepository) Snapshots(ctx context.Context, categoryID string, from, to time.Time) ([]ShelfSnapshot, error) {
+	q := r.db.WithContext(ctx).
+		Preload("Shares").
+		Where("taken_at >= ? AND taken_at < ?", from, to)
+	if categoryID != "" {
+		q = q.Where("category_id = ?", categoryID)
+	}
+	var snapshots []ShelfSnapshot
+	err := q.Order("taken_at").Find(&snapshots).Error
+	return snapshots, err
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SKUMapping maps one of the seller's own SKUs (e.g. an ERP code) to a
+// listing, or to one variation of it when VariationID is set. A listing or
+// variation has one SKU; a SKU may be sold through several listings.
+type SKUMapping struct {
+	ID          uint   `gorm:"primaryKey"`
+	SKU         string `gorm:"size:128;index;not null"`
+	ItemID      string `gorm:"size:64;not null;uniqueIndex:idx_sku_mappings_org_listing"`
+	VariationID int64  `gorm:"not null;default:0;uniqueIndex:idx_sku_mappings_org_listing"`
+	OrgID       uint   `gorm:"not null;default:0;uniqueIndex:idx_sku_mappings_org_listing,priority:1"`
+	Sandbox     bool   `gorm:"not null;default:false"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// mappedSKUJoins joins order_items with the mapping of their variation (vm)
+// and of their whole listing (im).
+const mappedSKUJoins = "LEFT JOIN sku_mappings AS vm ON vm.org_id = order_items.org_id AND vm.item_id = order_items.item_id AND vm.variation_id = order_items.variation_id AND vm.variation_id <> 0 " +
+	"LEFT JOIN sku_mappings AS im ON im.org_id = order_items.org_id AND im.item_id = order_items.item_id AND im.variation_id = 0"
+
+// mappedSKU is the SKU of an order line: the mapping of its variation, else
+// of its listing, else the SKU on the listing, else the item ID.
+const mappedSKU = "COALESCE(NULLIF(vm.sku, ''), NULLIF(im.sku, ''), NULLIF(order_items.sku, ''), order_items.item_id)"
+
+type SKUMappingRepository struct {
+	db *gorm.DB
+}
+
+func NewSKUMappingRepository() *SKUMappingRepository {
+	return &SKUMappingRepository{
+		db: database.DB,
+	}
+}
+
+// List returns the mappings ordered by SKU, of one SKU when sku is set.
+func (r *SKUMappingRepository) List(ctx context.Context, sku string) ([]SKUMapping, error) {
+	q := r.db.WithContext(ctx)
+	if sku != "" {
+		q = q.Where("sku = ?", sku)
+	}
+	var mappings []SKUMapping
+	err := q.Order("sku, item_id, variation_id").Find(&mappings).Error
+	return mappings, err
+}
+
+// Upsert maps listings or variations to SKUs, replacing their previous
+// SKU.
+func (r *SKUMappingRepository) Upsert(ctx context.Context, mappings []SKUMapping) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "org_id"}, {Name: "item_id"}, {Name: "variation_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"sku", "updated_at"}),
+		}).
+		Create(&mappings).Error
+}
+
+// Delete removes a mapping. It reports whether it existed.
+func (r *SKUMappingRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Delete(&SKUMapping{}, id)
+	return res.RowsAffected > 0, res.Error
+}
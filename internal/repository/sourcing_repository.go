@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// Sourcing signals.
+const (
+	// SourcingSignalBuy is raised when the market price leaves at least
+	// MinMarginPct of margin over the landed cost.
+	SourcingSignalBuy = "buy"
+	// SourcingSignalAbort is raised when the market price no longer covers
+	// the landed cost and fees.
+	SourcingSignalAbort = "abort"
+	// SourcingSignalHold is in between.
+	SourcingSignalHold = "hold"
+)
+
+// SourcingCandidate is a catalog product I'm considering sourcing, with what
+// it would cost me landed. Its catalog best price is tracked and the margin
+// it implies after fees decides the signal; a signal fires once per change.
+type SourcingCandidate struct {
+	ID           uint    `gorm:"primaryKey"`
+	ProductID    string  `gorm:"size:64;uniqueIndex:idx_sourcing_candidates_org_product;not null"`
+	Title        string  `gorm:"size:512"`
+	Permalink    string  `gorm:"size:512"`
+	LandedCost   float64 `gorm:"not null"`
+	MinMarginPct float64 `gorm:"not null"`
+	// ListingType the fees are estimated for
+	ListingType   string `gorm:"size:32;not null"`
+	CategoryID    string `gorm:"size:32"`
+	LastPrice     float64
+	LastFees      float64
+	LastMarginPct float64
+	Signal        string `gorm:"size:16"`
+	LastCheckedAt *time.Time
+	OrgID         uint `gorm:"not null;default:0;uniqueIndex:idx_sourcing_candidates_org_product,priority:1"`
+	Sandbox       bool `gorm:"not null;default:false"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// SourcingSignal is a signal change of a sourcing candidate.
+type SourcingSignal struct {
+	ID           uint    `gorm:"primaryKey"`
+	ProductID    string  `gorm:"size:64;index;not null"`
+	Signal       string  `gorm:"size:16;not null"`
+	Price        float64 `gorm:"not null"`
+	Fees         float64
+	LandedCost   float64
+	MarginPct    float64
+	MinMarginPct float64
+	OrgID        uint      `gorm:"not null;default:0;index"`
+	Sandbox      bool      `gorm:"not null;default:false"`
+	CreatedAt    time.Time `gorm:"index"`
+}
+
+type SourcingRepository struct {
+	db *gorm.DB
+}
+
+func NewSourcingRepository() *SourcingRepository {
+	return &SourcingRepository{
+		db: database.DB,
+	}
+}
+
+// List returns every sourcing candidate.
+func (r *SourcingRepository) List(ctx context.Context) ([]SourcingCandidate, error) {
+	var candidates []SourcingCandidate
+	err := r.db.WithContext(ctx).Order("created_at").Find(&candidates).Error
+	return candidates, err
+}
+
+// FindByProductID returns a sourcing candidate, or nil if it is not
+// registered.
+func (r *SourcingRepository) FindByProductID(ctx context.Context, productID string) (*SourcingCandidate, error) {
+	var c SourcingCandidate
+	err := r.db.WithContext(ctx).Where("product_id = ?", productID).First(&c).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Save creates or updates a sourcing candidate.
+func (r *SourcingRepository) Save(ctx context.Context, c *SourcingCandidate) error {
+	return r.db.WithContext(ctx).Save(c).Error
+}
+
+// Delete removes a sourcing candidate. It reports whether it was registered.
+func (r *SourcingRepository) Delete(ctx context.Context, productID string) (bool, error) {
+	res := r.db.WithContext(ctx).Where("product_id = ?", productID).Delete(&SourcingCandidate{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// SaveWithSignal updates a candidate and records its signal change, if any,
+// in one transaction.
+func (r *SourcingRepository) SaveWithSignal(ctx context.Context, c *SourcingCandidate, signal *SourcingSignal) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(c).Error; err != nil {
+			return err
+		}
+		if signal == nil {
+			return nil
+		}
+		return tx.Create(signal).Error
+	})
+}
+
+// Signals returns the most recent signals, optionally for a single product.
+func (r *SourcingRepository) Signals(ctx context.Context, productID string, limit int) ([]SourcingSignal, error) {
+	q := r.db.WithContext(ctx).Model(&SourcingSignal{})
+	if productID != "" {
+		q = q.Where("product_id = ?", productID)
+	}
+	var signals []SourcingSignal
+	err := q.Order("created_at DESC").Limit(limit).Find(&signals).Error
+	return signals, err
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// rowsAfter pages through an append-only table by primary key: up to limit
+// rows with an ID above afterID, created at or after since (when set), in
+// ID order.
+func rowsAfter[T any](ctx context.Context, db *gorm.DB, afterID uint, since time.Time, limit int) ([]T, error) {
+	var rows []T
+	q := db.WithContext(ctx).Where("id > ?", afterID)
+	if !since.IsZero() {
+		q = q.Where("created_at >= ?", since)
+	}
+	err := q.Order("id").Limit(limit).Find(&rows).Error
+	return rows, err
+}
+
+// SnapshotsAfter returns up to limit product trend snapshots with an ID
+// above afterID, created at or after since, oldest first.
+func (r *TrendRepository) SnapshotsAfter(ctx context.Context, afterID uint, since time.Time, limit int) ([]ProductTrend, error) {
+	return rowsAfter[ProductTrend](ctx, r.db, afterID, since, limit)
+}
+
+// StatsAfter returns up to limit crawl stats with an ID above afterID,
+// created at or after since, oldest first.
+func (r *CategoryStatsRepository) StatsAfter(ctx context.Context, afterID uint, since time.Time, limit int) ([]CategoryStats, error) {
+	return rowsAfter[CategoryStats](ctx, r.db, afterID, since, limit)
+}
+
+// TrendsAfter returns up to limit keyword snapshots with an ID above
+// afterID, created at or after since, oldest first.
+func (r *KeywordTrendRepository) TrendsAfter(ctx context.Context, afterID uint, since time.Time, limit int) ([]KeywordTrend, error) {
+	return rowsAfter[KeywordTrend](ctx, r.db, afterID, since, limit)
+}
+
+// ChangesAfter returns up to limit competitor changes with an ID above
+// afterID, created at or after since, oldest first.
+func (r *CompetitorRepository) ChangesAfter(ctx context.Context, afterID uint, since time.Time, limit int) ([]CompetitorChange, error) {
+	return rowsAfter[CompetitorChange](ctx, r.db, afterID, since, limit)
+}
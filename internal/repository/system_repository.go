@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// SystemRepository exposes database-level information for operators.
+type SystemRepository struct {
+	db *gorm.DB
+}
+
+func NewSystemRepository() *SystemRepository {
+	return &SystemRepository{
+		db: database.DB,
+	}
+}
+
+// DatabaseSize returns the size of the current database in bytes.
+func (r *SystemRepository) DatabaseSize(ctx context.Context) (int64, error) {
+	var size int64
+	err := r.db.WithContext(ctx).Raw("SELECT pg_database_size(current_database())").Scan(&size).Error
+	return size, err
+}
+
+// Ping checks that the database connection is alive.
+func (r *SystemRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TaskLock is the lease of a scheduled task held by one instance, so that
+// replicas do not run the task at the same time. The holder renews it while
+// the task runs and keeps it until the next run is due, so the other
+// replicas skip theirs; an expired lease, e.g. of a crashed instance, is
+// taken over. Times come from the database clock, shared by every
+// instance. In leader mode the "leader" row is the lease of the elected
+// instance.
+type TaskLock struct {
+	Name       string    `gorm:"primaryKey;size:64"`
+	Owner      string    `gorm:"size:128;not null"`
+	AcquiredAt time.Time `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"not null"`
+}
+
+type TaskLockRepository struct {
+	db *gorm.DB
+}
+
+func NewTaskLockRepository() *TaskLockRepository {
+	return &TaskLockRepository{
+		db: database.DB,
+	}
+}
+
+// Acquire takes the lease of a task for ttl when it is free, expired or
+// already held by owner. When it takes over an expired lease of another
+// owner, it returns that owner.
+func (r *TaskLockRepository) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (acquired bool, previous string, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&TaskLock{}).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(map[string]interface{}{
+				"name":        name,
+				"owner":       owner,
+				"acquired_at": gorm.Expr("NOW()"),
+				"expires_at":  leaseEnd(ttl),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 1 {
+			acquired = true
+			return nil
+		}
+
+		var held struct {
+			Owner   string
+			Expired bool
+		}
+		err := tx.Raw("SELECT owner, expires_at < NOW() AS expired FROM task_locks WHERE name = ? FOR UPDATE", name).
+			Scan(&held).Error
+		if err != nil {
+			return err
+		}
+		if held.Owner != owner && !held.Expired {
+			return nil
+		}
+		if held.Owner != owner {
+			previous = held.Owner
+		}
+		acquired = true
+		return tx.Model(&TaskLock{}).
+			Where("name = ?", name).
+			Updates(map[string]interface{}{
+				"owner":       owner,
+				"acquired_at": gorm.Expr("NOW()"),
+				"expires_at":  leaseEnd(ttl),
+			}).Error
+	})
+	if err != nil {
+		return false, "", err
+	}
+	return acquired, previous, nil
+}
+
+// Renew extends the lease of owner by ttl, reporting false when owner no
+// longer holds it.
+func (r *TaskLockRepository) Renew(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Model(&TaskLock{}).
+		Where("name = ? AND owner = ?", name, owner).
+		Update("expires_at", leaseEnd(ttl))
+	return res.RowsAffected == 1, res.Error
+}
+
+// Release frees the lease of owner.
+func (r *TaskLockRepository) Release(ctx context.Context, name, owner string) error {
+	return r.db.WithContext(ctx).
+		Where("name = ? AND owner = ?", name, owner).
+		Delete(&TaskLock{}).Error
+}
+
+// leaseEnd is ttl from now on the database clock.
+func leaseEnd(ttl time.Duration) clause.Expr {
+	return gorm.Expr("NOW() + make_interval(secs => ?)", ttl.Seconds())
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// TestUser is a Mercado Livre test account created in sandbox mode.
+// Credentials are only useful against the sandbox, so they are kept in
+// clear to allow logging in as the test user, but the password is never
+// serialized: only the response creating the user shows it.
+type TestUser struct {
+	ID         uint   `gorm:"primaryKey"`
+	MLUserID   int64  `gorm:"uniqueIndex:idx_test_users_org_user;not null"`
+	Nickname   string `gorm:"size:128;not null"`
+	Email      string `gorm:"size:256"`
+	Password   string `gorm:"size:128" json:"-"`
+	SiteStatus string `gorm:"size:32"`
+	OrgID      uint   `gorm:"not null;default:0;uniqueIndex:idx_test_users_org_user,priority:1"`
+	Sandbox    bool   `gorm:"not null;default:false"`
+	CreatedAt  time.Time
+}
+
+type TestUserRepository struct {
+	db *gorm.DB
+}
+
+func NewTestUserRepository() *TestUserRepository {
+	return &TestUserRepository{
+		db: database.DB,
+	}
+}
+
+// Create persists a test user.
+func (r *TestUserRepository) Create(ctx context.Context, user *TestUser) error {
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+// List returns all known test users, newest first.
+func (r *TestUserRepository) List(ctx context.Context) ([]TestUser, error) {
+	var users []TestUser
+	err := r.db.WithContext(ctx).Order("created_at DESC").Find(&users).Error
+	return users, err
+}
+
+// Exists reports whether the Mercado Livre user is a registered test user.
+func (r *TestUserRepository) Exists(ctx context.Context, mlUserID int64) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&TestUser{}).Where("ml_user_id = ?", mlUserID).Count(&count).Error
+	return count > 0, err
+}
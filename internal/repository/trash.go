@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// trashed returns the soft-deleted rows of a table with a DeletedAt field,
+// last deleted first.
+func trashed[T any](ctx context.Context, db *gorm.DB) ([]T, error) {
+	var rows []T
+	err := db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&rows).Error
+	return rows, err
+}
+
+// restore undeletes the soft-deleted rows of a table with a DeletedAt field
+// that match query. It reports whether there was one.
+func restore[T any](ctx context.Context, db *gorm.DB, query string, args ...interface{}) (bool, error) {
+	res := db.WithContext(ctx).Unscoped().Model(new(T)).
+		Where("deleted_at IS NOT NULL").
+		Where(query, args...).
+		Update("deleted_at", nil)
+	return res.RowsAffected > 0, res.Error
+}
+
+// TrashedWatches returns the products removed from the watchlist, last
+// removed first.
+func (r *WatchlistRepository) TrashedWatches(ctx context.Context) ([]WatchedProduct, error) {
+	return trashed[WatchedProduct](ctx, r.db)
+}
+
+// RestoreWatch puts a removed product back on the watchlist. It reports
+// whether it was removed.
+func (r *WatchlistRepository) RestoreWatch(ctx context.Context, productID string) (bool, error) {
+	return restore[WatchedProduct](ctx, r.db, "product_id = ?", productID)
+}
+
+// TrashedTemplates returns the deleted listing templates, last deleted
+// first.
+func (r *ListingTemplateRepository) TrashedTemplates(ctx context.Context) ([]ListingTemplate, error) {
+	return trashed[ListingTemplate](ctx, r.db)
+}
+
+// RestoreTemplate undeletes a listing template. It reports whether it was
+// deleted.
+func (r *ListingTemplateRepository) RestoreTemplate(ctx context.Context, id uint) (bool, error) {
+	return restore[ListingTemplate](ctx, r.db, "id = ?", id)
+}
+
+// TrashedTemplates returns the deleted answer templates, last deleted
+// first.
+func (r *QuestionRepository) TrashedTemplates(ctx context.Context) ([]AnswerTemplate, error) {
+	return trashed[AnswerTemplate](ctx, r.db)
+}
+
+// RestoreTemplate undeletes an answer template. It reports whether it was
+// deleted.
+func (r *QuestionRepository) RestoreTemplate(ctx context.Context, id uint) (bool, error) {
+	return restore[AnswerTemplate](ctx, r.db, "id = ?", id)
+}
+
+// TrashedRules returns the deleted listing rules, last deleted first.
+func (r *ListingRuleRepository) TrashedRules(ctx context.Context) ([]ListingRule, error) {
+	return trashed[ListingRule](ctx, r.db)
+}
+
+// RestoreRule undeletes a listing rule. It reports whether it was deleted.
+func (r *ListingRuleRepository) RestoreRule(ctx context.Context, id uint) (bool, error) {
+	return restore[ListingRule](ctx, r.db, "id = ?", id)
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"melibot/database"
@@ -20,10 +21,18 @@ type ProductTrend struct {
 	Price        float64 `gorm:"not null"`
 	Thumbnail    string  `gorm:"size:512"`
 	Permalink    string  `gorm:"size:512"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// HighlightCategoryID is the category whose highlights listed the product
+	// at position Rank (CategoryID is the product's own category/domain).
+	HighlightCategoryID string `gorm:"size:64;index"`
+	Rank                int    `gorm:"not null;default:0"` // 0 = unknown
+	OrgID               uint   `gorm:"not null;default:0;index"`
+	Sandbox             bool   `gorm:"not null;default:false"`
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
 }
 
+// TrendRepository stores product snapshots. Its history and report queries
+// read from a replica, see database.Replica.
 type TrendRepository struct {
 	db *gorm.DB
 }
@@ -34,9 +43,43 @@ func NewTrendRepository() *TrendRepository {
 	}
 }
 
+// models lists every table of the schema.
+var models = []interface{}{&ProductTrend{}, &Job{}, &CategoryStats{}, &AuditLog{}, &TestUser{}, &WatchedProduct{}, &PriceAlert{}, &ScoringProfile{}, &KeywordTrend{}, &Order{}, &OrderItem{}, &ProductCost{}, &ReorderAlert{}, &AnswerTemplate{}, &AutoResponderSettings{}, &AutoResponderOptOut{}, &QuestionMatch{}, &OrderStatusChange{}, &WebhookSubscription{}, &Competitor{}, &CompetitorListing{}, &CompetitorChange{}, &APIUsage{}, &FailedNotification{}, &E2ERun{}, &ListingTemplate{}, &ListingRule{}, &ListingAction{}, &SKUMapping{}, &Claim{}, &ReputationAlert{}, &RankTracker{}, &RankSnapshot{}, &ShelfSnapshot{}, &ShelfShare{}, &Experiment{}, &ExperimentPhase{}, &Organization{}, &OrgLogin{}, &Payment{}, &SourcingCandidate{}, &SourcingSignal{}, &ReportDefinition{}, &WarehouseCheckpoint{}, &Credential{}, &ScheduleSetting{}, &TaskLock{}, &LockoutCounter{}}
+
+// globalUniqueIndexes are the unique indexes replaced by per-organization
+// ones when rows became scoped by organization.
+var globalUniqueIndexes = []struct {
+	model interface{}
+	name  string
+}{
+	{&TestUser{}, "idx_test_users_ml_user_id"},
+	{&WatchedProduct{}, "idx_watched_products_product_id"},
+	{&ScoringProfile{}, "idx_scoring_profiles_name"},
+	{&ProductCost{}, "idx_product_costs_sku"},
+	{&AutoResponderOptOut{}, "idx_auto_responder_opt_outs_item_id"},
+	{&QuestionMatch{}, "idx_question_matches_question_id"},
+	{&Competitor{}, "idx_competitors_seller_id"},
+	{&CompetitorListing{}, "idx_competitor_listing"},
+	{&SKUMapping{}, "idx_sku_mappings_listing"},
+	{&RankTracker{}, "idx_rank_tracker"},
+}
+
 // AutoMigrate ensures DB schema is up to date for this repository.
 func AutoMigrate() error {
-	return database.DB.AutoMigrate(&ProductTrend{})
+	db := database.DB.WithContext(database.AllOrgs(context.Background()))
+	if err := db.AutoMigrate(models...); err != nil {
+		return err
+	}
+	migrator := db.Migrator()
+	for _, idx := range globalUniqueIndexes {
+		if !migrator.HasIndex(idx.model, idx.name) {
+			continue
+		}
+		if err := migrator.DropIndex(idx.model, idx.name); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // SaveProductTrends persists a batch of product trend records.
@@ -46,3 +89,299 @@ func (r *TrendRepository) SaveProductTrends(ctx context.Context, items []Product
 	}
 	return r.db.WithContext(ctx).Create(&items).Error
 }
+
+// SearchProductTrends full-text searches stored trend titles (and matches
+// product IDs exactly), returning the latest record per product.
+func (r *TrendRepository) SearchProductTrends(ctx context.Context, query string, limit int) ([]ProductTrend, error) {
+	var trends []ProductTrend
+
+	latest := r.db.WithContext(ctx).
+		Model(&ProductTrend{}).
+		Select("DISTINCT ON (product_id) *").
+		Where("to_tsvector('portuguese', title) @@ plainto_tsquery('portuguese', ?) OR title ILIKE ? OR product_id = ?",
+			query, containsPattern(query), query).
+		Order("product_id, created_at DESC")
+
+	err := r.db.WithContext(ctx).
+		Table("(?) AS latest", latest).
+		Order("sold_quantity DESC").
+		Limit(limit).
+		Find(&trends).Error
+	return trends, err
+}
+
+// likeEscaper escapes the ILIKE wildcards, and the backslash escaping them.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern is the ILIKE pattern matching values that contain query
+// literally, so "50%" or "kit_a" are not read as wildcards.
+func containsPattern(query string) string {
+	return "%" + likeEscaper.Replace(query) + "%"
+}
+
+// LatestProductTrends returns the latest record per product, best sellers
+// first. A non-empty categoryID keeps the products of that category or of
+// its highlights; limit <= 0 returns them all.
+func (r *TrendRepository) LatestProductTrends(ctx context.Context, categoryID string, limit int) ([]ProductTrend, error) {
+	var trends []ProductTrend
+
+	latest := r.db.WithContext(ctx).
+		Model(&ProductTrend{}).
+		Select("DISTINCT ON (product_id) *").
+		Order("product_id, created_at DESC")
+	if categoryID != "" {
+		latest = latest.Where("category_id = ? OR highlight_category_id = ?", categoryID, categoryID)
+	}
+
+	q := r.db.WithContext(ctx).
+		Table("(?) AS latest", latest).
+		Order("sold_quantity DESC")
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+	err := q.Find(&trends).Error
+	return trends, err
+}
+
+// LatestTrendsOf returns the latest record of each of the given products
+// that was ever stored, best sellers first.
+func (r *TrendRepository) LatestTrendsOf(ctx context.Context, productIDs []string) ([]ProductTrend, error) {
+	var trends []ProductTrend
+	if len(productIDs) == 0 {
+		return trends, nil
+	}
+
+	latest := r.db.WithContext(ctx).
+		Model(&ProductTrend{}).
+		Select("DISTINCT ON (product_id) *").
+		Where("product_id IN ?", productIDs).
+		Order("product_id, created_at DESC")
+
+	err := r.db.WithContext(ctx).
+		Table("(?) AS latest", latest).
+		Order("sold_quantity DESC").
+		Find(&trends).Error
+	return trends, err
+}
+
+// SoldSnapshot is a product's sold quantity at the time a trend was stored.
+type SoldSnapshot struct {
+	ProductID    string
+	SoldQuantity int
+	CreatedAt    time.Time
+}
+
+// SoldSnapshots returns the stored sold quantities of the given products
+// since a point in time, oldest first.
+func (r *TrendRepository) SoldSnapshots(ctx context.Context, productIDs []string, since time.Time) ([]SoldSnapshot, error) {
+	var snaps []SoldSnapshot
+	if len(productIDs) == 0 {
+		return snaps, nil
+	}
+	err := r.db.WithContext(ctx).
+		Model(&ProductTrend{}).
+		Select("product_id, sold_quantity, created_at").
+		Where("product_id IN ? AND created_at >= ?", productIDs, since).
+		Order("created_at").
+		Scan(&snaps).Error
+	return snaps, err
+}
+
+// DailyRank is a product's best highlight position on a given day.
+type DailyRank struct {
+	Day      time.Time
+	BestRank int
+	Samples  int
+}
+
+// RankHistory returns a product's best daily position in a category's
+// highlights since a point in time, oldest first. Snapshots stored before
+// ranks were recorded are ignored.
+func (r *TrendRepository) RankHistory(ctx context.Context, productID, categoryID string, since time.Time) ([]DailyRank, error) {
+	var days []DailyRank
+	err := database.Replica(ctx).
+		Model(&ProductTrend{}).
+		Select("date_trunc('day', created_at) AS day, MIN(rank) AS best_rank, COUNT(*) AS samples").
+		Where("product_id = ? AND highlight_category_id = ? AND rank > 0 AND created_at >= ?", productID, categoryID, since).
+		Group("day").
+		Order("day").
+		Scan(&days).Error
+	return days, err
+}
+
+// PeriodSales is how many units the tracked products of a category sold
+// within a period, estimated from the growth of their sold counters.
+type PeriodSales struct {
+	Period    time.Time
+	UnitsSold int
+	Products  int
+}
+
+// SalesByPeriod aggregates the highlight snapshots of a category by period
+// ("week" or "month") since a point in time, oldest period first.
+func (r *TrendRepository) SalesByPeriod(ctx context.Context, categoryID, period string, since time.Time) ([]PeriodSales, error) {
+	perProduct := database.Replica(ctx).
+		Model(&ProductTrend{}).
+		Select("date_trunc(?, created_at) AS period, product_id, MAX(sold_quantity) - MIN(sold_quantity) AS sold", period).
+		Where("highlight_category_id = ? AND created_at >= ?", categoryID, since).
+		Group("period, product_id")
+
+	var rows []PeriodSales
+	err := database.Replica(ctx).
+		Table("(?) AS per_product", perProduct).
+		Select("period, SUM(sold) AS units_sold, COUNT(*) AS products").
+		Group("period").
+		Order("period").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// CategoryMovement is how many units the tracked products of a category sold
+// within a window, estimated from the growth of their sold counters.
+type CategoryMovement struct {
+	CategoryID string
+	UnitsSold  int
+	Products   int
+}
+
+// TopCategoryMovement ranks the highlight categories by units sold since a
+// point in time, dropping those that did not move.
+func (r *TrendRepository) TopCategoryMovement(ctx context.Context, since time.Time, limit int) ([]CategoryMovement, error) {
+	perProduct := database.Replica(ctx).
+		Model(&ProductTrend{}).
+		Select("highlight_category_id AS category_id, product_id, MAX(sold_quantity) - MIN(sold_quantity) AS sold").
+		Where("highlight_category_id <> '' AND created_at >= ?", since).
+		Group("highlight_category_id, product_id")
+
+	var rows []CategoryMovement
+	err := database.Replica(ctx).
+		Table("(?) AS per_product", perProduct).
+		Select("category_id, SUM(sold) AS units_sold, COUNT(*) AS products").
+		Group("category_id").
+		Having("SUM(sold) > 0").
+		Order("units_sold DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// PriceChange is a product's price at its first and latest snapshot within
+// a window.
+type PriceChange struct {
+	ProductID  string
+	Title      string
+	Thumbnail  string
+	Permalink  string
+	FirstPrice float64
+	LastPrice  float64
+}
+
+// PriceDrops returns the products whose price fell the most, relative to
+// their first snapshot since a point in time, biggest drop first.
+func (r *TrendRepository) PriceDrops(ctx context.Context, since time.Time, limit int) ([]PriceChange, error) {
+	const window = "OVER (PARTITION BY product_id ORDER BY created_at ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING)"
+	perProduct := database.Replica(ctx).
+		Model(&ProductTrend{}).
+		Select("DISTINCT ON (product_id) product_id, title, thumbnail, permalink, "+
+			"FIRST_VALUE(price) "+window+" AS first_price, LAST_VALUE(price) "+window+" AS last_price").
+		Where("created_at >= ? AND price > 0", since).
+		Order("product_id, created_at DESC")
+
+	var rows []PriceChange
+	err := database.Replica(ctx).
+		Table("(?) AS per_product", perProduct).
+		Where("last_price < first_price").
+		Order("(first_price - last_price) / first_price DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	return rows, err
+}
+
+// ProductMovement is how a product moved in a category's highlights within
+// a window: its first and latest rank and price, and the units it sold.
+type ProductMovement struct {
+	ProductID  string
+	CategoryID string
+	Title      string
+	Thumbnail  string
+	Permalink  string
+	FirstRank  int
+	LastRank   int
+	FirstPrice float64
+	LastPrice  float64
+	Sold       int
+}
+
+// ProductMovements returns the movement of every product snapshotted in
+// [from, to), per highlight category. Snapshots without rank or price are
+// ignored.
+func (r *TrendRepository) ProductMovements(ctx context.Context, from, to time.Time) ([]ProductMovement, error) {
+	const window = "OVER (PARTITION BY product_id, highlight_category_id ORDER BY created_at ROWS BETWEEN UNBOUNDED PRECEDING AND UNBOUNDED FOLLOWING)"
+	var rows []ProductMovement
+	err := database.Replica(ctx).
+		Model(&ProductTrend{}).
+		Select("DISTINCT ON (product_id, highlight_category_id) product_id, highlight_category_id AS category_id, title, thumbnail, permalink, "+
+			"FIRST_VALUE(rank) "+window+" AS first_rank, LAST_VALUE(rank) "+window+" AS last_rank, "+
+			"FIRST_VALUE(price) "+window+" AS first_price, LAST_VALUE(price) "+window+" AS last_price, "+
+			"MAX(sold_quantity) "+window+" - MIN(sold_quantity) "+window+" AS sold").
+		Where("highlight_category_id <> '' AND rank > 0 AND price > 0 AND created_at >= ? AND created_at < ?", from, to).
+		Order("product_id, highlight_category_id, created_at DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// SnapshotsInBatches calls fn with the stored snapshots created since a
+// point in time (zero for all), batchSize at a time in ID order. A
+// non-empty categoryID keeps the products of that category or of its
+// highlights.
+func (r *TrendRepository) SnapshotsInBatches(ctx context.Context, categoryID string, since time.Time, batchSize int, fn func([]ProductTrend) error) error {
+	q := database.Replica(ctx).Where("created_at >= ?", since)
+	if categoryID != "" {
+		q = q.Where("category_id = ? OR highlight_category_id = ?", categoryID, categoryID)
+	}
+	var batch []ProductTrend
+	return q.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// DailyPrice is the range of a product's prices over a day of snapshots.
+type DailyPrice struct {
+	OrgID     uint
+	ProductID string
+	Day       time.Time
+	MinPrice  float64
+	MaxPrice  float64
+	AvgPrice  float64
+	Snapshots int
+}
+
+// DailyPrices returns the daily prices of the products snapshotted since a
+// point in time (zero for all), by product and day. A non-empty categoryID
+// keeps the products of that category or of its highlights.
+func (r *TrendRepository) DailyPrices(ctx context.Context, categoryID string, since time.Time) ([]DailyPrice, error) {
+	return r.dailyPrices(ctx, categoryID, since, time.Time{})
+}
+
+// DailyPricesBetween returns the daily prices of the products snapshotted
+// in [from, to), by product and day.
+func (r *TrendRepository) DailyPricesBetween(ctx context.Context, from, to time.Time) ([]DailyPrice, error) {
+	return r.dailyPrices(ctx, "", from, to)
+}
+
+func (r *TrendRepository) dailyPrices(ctx context.Context, categoryID string, since, until time.Time) ([]DailyPrice, error) {
+	q := database.Replica(ctx).
+		Model(&ProductTrend{}).
+		Select("org_id, product_id, date_trunc('day', created_at) AS day, MIN(price) AS min_price, MAX(price) AS max_price, "+
+			"AVG(price) AS avg_price, COUNT(*) AS snapshots").
+		Where("price > 0 AND created_at >= ?", since)
+	if !until.IsZero() {
+		q = q.Where("created_at < ?", until)
+	}
+	if categoryID != "" {
+		q = q.Where("category_id = ? OR highlight_category_id = ?", categoryID, categoryID)
+	}
+	var rows []DailyPrice
+	err := q.Group("org_id, product_id, day").Order("product_id, day").Scan(&rows).Error
+	return rows, err
+}
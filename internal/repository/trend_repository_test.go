@@ -0,0 +1,20 @@
+package repository
+
+import "testing"
+
+func TestContainsPattern(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"fone", `%fone%`},
+		{"50%", `%50\%%`},
+		{"kit_a", `%kit\_a%`},
+		{`c:\tmp`, `%c:\\tmp%`},
+	}
+	for _, tt := range tests {
+		if got := containsPattern(tt.query); got != tt.want {
+			t.Errorf("containsPattern(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
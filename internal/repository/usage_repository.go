@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// APIUsage counts the Mercado Livre calls made with one account's token
+// within an hour (UTC). Account 0 holds the calls made without a token.
+type APIUsage struct {
+	ID        uint      `gorm:"primaryKey"`
+	AccountID int64     `gorm:"not null;uniqueIndex:idx_api_usage_account_hour"`
+	Hour      time.Time `gorm:"not null;uniqueIndex:idx_api_usage_account_hour;index"`
+	Requests  int       `gorm:"not null;default:0"`
+	Errors    int       `gorm:"not null;default:0"`
+	Sandbox   bool      `gorm:"not null;default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type UsageRepository struct {
+	db *gorm.DB
+}
+
+func NewUsageRepository() *UsageRepository {
+	return &UsageRepository{
+		db: database.DB,
+	}
+}
+
+// Add adds the counts of each row to the stored ones of its account and
+// hour.
+func (r *UsageRepository) Add(ctx context.Context, rows []APIUsage) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "account_id"}, {Name: "hour"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"requests":   gorm.Expr("api_usages.requests + excluded.requests"),
+				"errors":     gorm.Expr("api_usages.errors + excluded.errors"),
+				"updated_at": gorm.Expr("excluded.updated_at"),
+			}),
+		}).
+		Create(&rows).Error
+}
+
+// HoursSince returns the hourly counts from since on, oldest first.
+func (r *UsageRepository) HoursSince(ctx context.Context, since time.Time) ([]APIUsage, error) {
+	var rows []APIUsage
+	err := r.db.WithContext(ctx).
+		Where("hour >= ?", since).
+		Order("hour, account_id").
+		Find(&rows).Error
+	return rows, err
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// updateVersioned replaces every column of row, an existing row of a table
+// with a Version field, if its stored version is still version, and moves
+// it to the next one. It reports whether the stored version matched; when
+// it did not, row is left unchanged.
+func updateVersioned[T any](ctx context.Context, db *gorm.DB, row *T, version *uint) (bool, error) {
+	expected := *version
+	*version = expected + 1
+	res := db.WithContext(ctx).Model(row).
+		Where("version = ?", expected).
+		Select("*").
+		Omit("created_at").
+		Updates(row)
+	if res.Error != nil || res.RowsAffected == 0 {
+		*version = expected
+		return false, res.Error
+	}
+	return true, nil
+}
+
+// Update replaces a watched product if it is still at p.Version, moving it
+// to the next version. It reports whether it was.
+func (r *WatchlistRepository) Update(ctx context.Context, p *WatchedProduct) (bool, error) {
+	return updateVersioned(ctx, r.db, p, &p.Version)
+}
+
+// Update replaces a template if it is still at t.Version, moving it to the
+// next version. It reports whether it was.
+func (r *ListingTemplateRepository) Update(ctx context.Context, t *ListingTemplate) (bool, error) {
+	return updateVersioned(ctx, r.db, t, &t.Version)
+}
+
+// UpdateTemplate replaces a template if it is still at t.Version, moving it
+// to the next version. It reports whether it was.
+func (r *QuestionRepository) UpdateTemplate(ctx context.Context, t *AnswerTemplate) (bool, error) {
+	return updateVersioned(ctx, r.db, t, &t.Version)
+}
+
+// UpdateRule replaces a rule if it is still at rule.Version, moving it to
+// the next version. It reports whether it was.
+func (r *ListingRuleRepository) UpdateRule(ctx context.Context, rule *ListingRule) (bool, error) {
+	return updateVersioned(ctx, r.db, rule, &rule.Version)
+}
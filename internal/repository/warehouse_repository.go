@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// WarehouseCheckpoint is how far a table was replicated to an analytics
+// warehouse: rows up to Cursor (and, for rows sharing it, up to CursorID)
+// are synced. Rows counts what was sent so far and LastError is set while
+// syncs of the table fail.
+type WarehouseCheckpoint struct {
+	ID         uint      `gorm:"primaryKey"`
+	Target     string    `gorm:"size:255;not null;uniqueIndex:idx_warehouse_checkpoints_org_table"`
+	TableName  string    `gorm:"size:64;not null;uniqueIndex:idx_warehouse_checkpoints_org_table"`
+	Cursor     time.Time `gorm:"not null"`
+	CursorID   int64     `gorm:"not null;default:0"`
+	Rows       int64     `gorm:"not null;default:0"`
+	LastSyncAt *time.Time
+	LastError  string `gorm:"type:text"`
+	OrgID      uint   `gorm:"not null;default:0;uniqueIndex:idx_warehouse_checkpoints_org_table,priority:1"`
+	Sandbox    bool   `gorm:"not null;default:false"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type WarehouseRepository struct {
+	db *gorm.DB
+}
+
+func NewWarehouseRepository() *WarehouseRepository {
+	return &WarehouseRepository{
+		db: database.DB,
+	}
+}
+
+// Checkpoints returns the checkpoints of every replicated table.
+func (r *WarehouseRepository) Checkpoints(ctx context.Context) ([]WarehouseCheckpoint, error) {
+	var cps []WarehouseCheckpoint
+	err := r.db.WithContext(ctx).Order("target, table_name").Find(&cps).Error
+	return cps, err
+}
+
+// Checkpoint returns the checkpoint of a table on a target, or nil if it
+// was never synced.
+func (r *WarehouseRepository) Checkpoint(ctx context.Context, target, table string) (*WarehouseCheckpoint, error) {
+	var cp WarehouseCheckpoint
+	err := r.db.WithContext(ctx).Where("target = ? AND table_name = ?", target, table).First(&cp).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// SaveCheckpoint creates or updates a checkpoint.
+func (r *WarehouseRepository) SaveCheckpoint(ctx context.Context, cp *WarehouseCheckpoint) error {
+	return r.db.WithContext(ctx).Save(cp).Error
+}
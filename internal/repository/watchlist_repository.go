@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// WatchedProduct is a catalog product whose best price is tracked.
+//
+// Thresholds are optional: AlertBelow/AlertAbove fire when the price crosses
+// them, ChangePct when the price moved that many percent from ReferencePrice.
+// BelowActive/AboveActive remember that an alert already fired so it is not
+// repeated until the price crosses back.
+type WatchedProduct struct {
+	ID             uint   `gorm:"primaryKey"`
+	ProductID      string `gorm:"size:64;uniqueIndex:idx_watched_products_org_product;not null"`
+	Title          string `gorm:"size:512"`
+	Permalink      string `gorm:"size:512"`
+	AlertBelow     *float64
+	AlertAbove     *float64
+	ChangePct      *float64
+	LastPrice      float64
+	ReferencePrice float64
+	BelowActive    bool `gorm:"not null;default:false"`
+	AboveActive    bool `gorm:"not null;default:false"`
+	LastCheckedAt  *time.Time
+	Version        uint `gorm:"not null;default:1"`
+	OrgID          uint `gorm:"not null;default:0;uniqueIndex:idx_watched_products_org_product,priority:1"`
+	Sandbox        bool `gorm:"not null;default:false"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+}
+
+// Price alert kinds.
+const (
+	AlertKindBelow  = "below"
+	AlertKindAbove  = "above"
+	AlertKindChange = "change"
+)
+
+// PriceAlert is a threshold crossing detected on a price refresh.
+type PriceAlert struct {
+	ID            uint    `gorm:"primaryKey"`
+	ProductID     string  `gorm:"size:64;index;not null"`
+	Kind          string  `gorm:"size:16;not null"`
+	Price         float64 `gorm:"not null"`
+	PreviousPrice float64
+	Threshold     float64
+	OrgID         uint      `gorm:"not null;default:0;index"`
+	Sandbox       bool      `gorm:"not null;default:false"`
+	CreatedAt     time.Time `gorm:"index"`
+}
+
+type WatchlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWatchlistRepository() *WatchlistRepository {
+	return &WatchlistRepository{
+		db: database.DB,
+	}
+}
+
+// List returns every watched product.
+func (r *WatchlistRepository) List(ctx context.Context) ([]WatchedProduct, error) {
+	var products []WatchedProduct
+	err := r.db.WithContext(ctx).Order("created_at").Find(&products).Error
+	return products, err
+}
+
+// FindByProductID returns a watched product, or nil if it is not watched.
+func (r *WatchlistRepository) FindByProductID(ctx context.Context, productID string) (*WatchedProduct, error) {
+	var p WatchedProduct
+	err := r.db.WithContext(ctx).Where("product_id = ?", productID).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Create adds a watched product. Watching a product again discards its
+// copy in the trash, which holds the same product ID.
+func (r *WatchlistRepository) Create(ctx context.Context, p *WatchedProduct) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Where("product_id = ? AND deleted_at IS NOT NULL", p.ProductID).Delete(&WatchedProduct{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(p).Error
+	})
+}
+
+// Delete stops watching a product, moving it to the trash. It reports
+// whether the product was watched.
+func (r *WatchlistRepository) Delete(ctx context.Context, productID string) (bool, error) {
+	res := r.db.WithContext(ctx).Where("product_id = ?", productID).Delete(&WatchedProduct{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// SaveWithAlerts records a price refresh of a watched product and its new
+// alerts in one transaction, so alert state and alert history never
+// disagree. The alerts were evaluated against the product's thresholds, so
+// nothing is recorded if it was edited meanwhile, i.e. is no longer at
+// p.Version; it reports whether the refresh was recorded. A refresh does
+// not change the version.
+func (r *WatchlistRepository) SaveWithAlerts(ctx context.Context, p *WatchedProduct, alerts []PriceAlert) (bool, error) {
+	saved := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(p).
+			Where("version = ?", p.Version).
+			Select("LastPrice", "ReferencePrice", "BelowActive", "AboveActive", "LastCheckedAt").
+			Updates(p)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		saved = true
+		if len(alerts) == 0 {
+			return nil
+		}
+		return tx.Create(&alerts).Error
+	})
+	return saved && err == nil, err
+}
+
+// Alerts returns the most recent alerts, optionally for a single product.
+func (r *WatchlistRepository) Alerts(ctx context.Context, productID string, limit int) ([]PriceAlert, error) {
+	q := r.db.WithContext(ctx).Model(&PriceAlert{})
+	if productID != "" {
+		q = q.Where("product_id = ?", productID)
+	}
+	var alerts []PriceAlert
+	err := q.Order("created_at DESC").Limit(limit).Find(&alerts).Error
+	return alerts, err
+}
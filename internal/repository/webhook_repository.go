@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"melibot/database"
+
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription is a third-party endpoint that receives internal
+// events. Events is a comma-separated list of event names; empty means all.
+// Payloads are signed with Secret.
+type WebhookSubscription struct {
+	ID             uint   `gorm:"primaryKey"`
+	URL            string `gorm:"size:1024;not null"`
+	Secret         string `gorm:"size:128;not null"`
+	Events         string `gorm:"type:text"`
+	Enabled        bool   `gorm:"not null;default:true"`
+	LastDeliveryAt *time.Time
+	LastStatus     int
+	LastError      string `gorm:"type:text"`
+	OrgID          uint   `gorm:"not null;default:0;index"`
+	Sandbox        bool   `gorm:"not null;default:false"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{
+		db: database.DB,
+	}
+}
+
+// List returns every subscription.
+func (r *WebhookRepository) List(ctx context.Context) ([]WebhookSubscription, error) {
+	var subs []WebhookSubscription
+	err := r.db.WithContext(ctx).Order("id").Find(&subs).Error
+	return subs, err
+}
+
+// ListEnabled returns the subscriptions that receive events.
+func (r *WebhookRepository) ListEnabled(ctx context.Context) ([]WebhookSubscription, error) {
+	var subs []WebhookSubscription
+	err := r.db.WithContext(ctx).Where("enabled").Order("id").Find(&subs).Error
+	return subs, err
+}
+
+// FindByID returns a subscription, or nil if it does not exist.
+func (r *WebhookRepository) FindByID(ctx context.Context, id uint) (*WebhookSubscription, error) {
+	var sub WebhookSubscription
+	err := r.db.WithContext(ctx).First(&sub, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// Save creates or updates a subscription.
+func (r *WebhookRepository) Save(ctx context.Context, sub *WebhookSubscription) error {
+	return r.db.WithContext(ctx).Save(sub).Error
+}
+
+// Delete removes a subscription. It reports whether it existed.
+func (r *WebhookRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Delete(&WebhookSubscription{}, id)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RecordDelivery stores the outcome of the latest delivery attempt.
+func (r *WebhookRepository) RecordDelivery(ctx context.Context, id uint, status int, deliveryErr string, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&WebhookSubscription{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_delivery_at": at, "last_status": status, "last_error": deliveryErr}).Error
+}
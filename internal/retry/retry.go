@@ -0,0 +1,90 @@
+// Package retry classifies the errors of background work, queued jobs and
+// scheduled tasks alike, into retryable and fatal, and turns panics into
+// fatal errors so one bad payload cannot take a worker down.
+package retry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// maxDelay caps Delay.
+const maxDelay = time.Hour
+
+// fatalError marks an error as not worth retrying.
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string { return e.err.Error() }
+func (e *fatalError) Unwrap() error { return e.err }
+
+// Fatal marks err as not worth retrying, e.g. an invalid payload: running
+// the work again would fail the same way. It returns nil for nil.
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &fatalError{err: err}
+}
+
+// PanicError is a recovered panic, with the stack of the goroutine that
+// panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Retryable reports whether work that failed with err may succeed if run
+// again. Errors marked Fatal, panics and payloads that do not decode are
+// not; anything else, e.g. a timeout or an upstream error, is.
+func Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var (
+		fatal     *fatalError
+		panicked  *PanicError
+		syntax    *json.SyntaxError
+		fieldType *json.UnmarshalTypeError
+	)
+	return !errors.As(err, &fatal) && !errors.As(err, &panicked) &&
+		!errors.As(err, &syntax) && !errors.As(err, &fieldType)
+}
+
+// Class names the class of err: "retryable" or "fatal".
+func Class(err error) string {
+	if Retryable(err) {
+		return "retryable"
+	}
+	return "fatal"
+}
+
+// Run calls fn, turning a panic into a *PanicError.
+func Run(fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// Delay is the exponential backoff before attempt+1, after attempt failed
+// attempts: base, 2*base, 4*base... capped at an hour.
+func Delay(base time.Duration, attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 20 {
+		return maxDelay
+	}
+	return min(base<<(attempt-1), maxDelay)
+}
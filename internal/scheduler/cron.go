@@ -0,0 +1,188 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSchedule is returned for schedules Parse does not understand.
+var ErrInvalidSchedule = errors.New("invalid schedule; use a cron expression such as \"*/15 * * * *\" or @every 10m")
+
+// Schedule tells when a task runs next.
+type Schedule interface {
+	// Next returns the first run after t.
+	Next(t time.Time) time.Time
+}
+
+// every runs a task on a fixed interval.
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// Every returns a Schedule running every interval.
+func Every(interval time.Duration) Schedule {
+	return every(interval)
+}
+
+// cron matches times against the five fields of a cron expression, each a
+// bit set of the allowed values.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record a * day field: when both day fields are
+	// restricted a day matching either runs, as in cron(8)
+	domAny, dowAny bool
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	dayNames   = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// Parse reads a schedule: a five-field cron expression (minute, hour, day
+// of month, month, day of week) in local time, with lists, ranges, steps
+// and month and day names, one of @hourly, @daily, @weekly, @monthly and
+// @yearly, or @every followed by a duration such as 10m.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("%w: @every needs a duration of at least 1s", ErrInvalidSchedule)
+		}
+		return every(d), nil
+	}
+	if expr, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = expr
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: expected 5 fields, got %d", ErrInvalidSchedule, len(fields))
+	}
+	var (
+		c   cron
+		err error
+	)
+	if c.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+	// 7 is Sunday too
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domAny = fields[2] == "*" || fields[2] == "?"
+	c.dowAny = fields[4] == "*" || fields[4] == "?"
+	if c.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("%w: %q never matches", ErrInvalidSchedule, spec)
+	}
+	return &c, nil
+}
+
+// parseField reads a comma-separated list of *, values, ranges (a-b) and
+// steps (*/n, a-b/n) between lowest and highest.
+func parseField(field string, lowest, highest int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%w: bad step in %q", ErrInvalidSchedule, field)
+			}
+			step = n
+		}
+		lo, hi := lowest, highest
+		if rangePart != "*" && rangePart != "?" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = fieldValue(from, names); err != nil {
+				return 0, fmt.Errorf("%w: %q", ErrInvalidSchedule, field)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = fieldValue(to, names); err != nil {
+					return 0, fmt.Errorf("%w: %q", ErrInvalidSchedule, field)
+				}
+			} else if hasStep {
+				hi = highest
+			}
+		}
+		if lo < lowest || hi > highest || lo > hi {
+			return 0, fmt.Errorf("%w: %q is out of range %d-%d", ErrInvalidSchedule, field, lowest, highest)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func fieldValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// Next returns the first minute after t matching every field, or the zero
+// time when none does within five years (e.g. February 30).
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
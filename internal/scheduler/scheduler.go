@@ -0,0 +1,450 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"melibot/internal/retry"
+)
+
+const (
+	// maxAttempts bounds the runs of a task per scheduled run when it fails
+	// with a retryable error.
+	maxAttempts    = 3
+	baseRetryDelay = 5 * time.Second
+)
+
+// ErrUnknownTask is returned by Update for names no task is registered
+// under.
+var ErrUnknownTask = errors.New("scheduled task not found")
+
+// TaskFunc is a unit of periodic work.
+type TaskFunc func(ctx context.Context) error
+
+// TaskOption configures a task registered with Every.
+type TaskOption func(*task)
+
+// PerInstance makes a task run on every instance rather than on one at a
+// time under the Locker, for work on the state of the instance itself, e.g.
+// its in-memory cache.
+func PerInstance() TaskOption {
+	return func(t *task) {
+		t.perInstance = true
+	}
+}
+
+// Locker keeps a task from running on several instances at once, see
+// SetLocker.
+type Locker interface {
+	// TryLock takes the lock of the task name, reporting false when another
+	// instance holds it. The task runs with lockCtx, cancelled if the lock
+	// is lost meanwhile, and calls release when done with the time of its
+	// next run. The lock stays held until then, so instances whose own
+	// schedule comes round in between skip the run; zero frees it at once.
+	TryLock(ctx context.Context, name string) (lockCtx context.Context, release func(next time.Time), ok bool, err error)
+}
+
+type task struct {
+	name     string
+	interval time.Duration
+	fn       TaskFunc
+	status   TaskStatus
+
+	// spec is the schedule set at runtime, empty for the default interval
+	spec     string
+	schedule Schedule
+	enabled  bool
+	// next is the time of the next run, zero while disabled
+	next time.Time
+	// changed wakes the loop of the task when its schedule changes
+	changed     chan struct{}
+	perInstance bool
+}
+
+// TaskStatus reports the schedule and last execution of a periodic task.
+type TaskStatus struct {
+	Name      string        `json:"name"`
+	Interval  time.Duration `json:"interval"`
+	LastRun   time.Time     `json:"last_run"`
+	LastError string        `json:"last_error,omitempty"`
+	Runs      int           `json:"runs"`
+	// ErrorClass tells whether LastError was retryable or fatal
+	ErrorClass string `json:"error_class,omitempty"`
+	// Attempts is how many times the last run was tried
+	Attempts int `json:"attempts,omitempty"`
+	// Failures counts the runs that failed in a row
+	Failures    int        `json:"failures,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	// Schedule is the cron expression or @every interval the task runs on,
+	// DefaultSchedule the one it was registered with
+	Schedule        string     `json:"schedule"`
+	DefaultSchedule string     `json:"default_schedule"`
+	Enabled         bool       `json:"enabled"`
+	NextRun         *time.Time `json:"next_run,omitempty"`
+	// PerInstance tasks run on every instance; the others are skipped
+	// while another instance holds their lock, counted in Skipped
+	PerInstance bool `json:"per_instance,omitempty"`
+	Skipped     int  `json:"skipped,omitempty"`
+}
+
+// Scheduler runs registered tasks on their schedule: by default once at
+// start and then on a fixed interval. Schedules can be changed and tasks
+// disabled at runtime, see Update.
+type Scheduler struct {
+	mu     sync.RWMutex
+	tasks  []*task
+	wrap   func(name string, fn TaskFunc) TaskFunc
+	locker Locker
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Every registers fn to run on start and then every interval.
+// It must be called before Start.
+func (s *Scheduler) Every(name string, interval time.Duration, fn TaskFunc, opts ...TaskOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := &task{
+		name:     name,
+		interval: interval,
+		fn:       fn,
+		status:   TaskStatus{Name: name, Interval: interval},
+		schedule: Every(interval),
+		enabled:  true,
+		changed:  make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.status.PerInstance = t.perInstance
+	s.tasks = append(s.tasks, t)
+}
+
+// SetLocker makes tasks, except PerInstance ones, run under l so replicas
+// do not run them at the same time. It must be called before Start.
+func (s *Scheduler) SetLocker(l Locker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locker = l
+}
+
+// Wrap decorates every task with w, e.g. to report their errors. It must be
+// called before Start.
+func (s *Scheduler) Wrap(w func(name string, fn TaskFunc) TaskFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wrap = w
+}
+
+// Start launches one goroutine per task. Tasks on an interval run at once,
+// tasks on a cron expression at its next match. Tasks stop when ctx is
+// cancelled.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, t := range s.tasks {
+		if s.wrap != nil {
+			t.fn = s.wrap(t.name, t.fn)
+		}
+		if _, interval := t.schedule.(every); interval && t.enabled {
+			t.next = now
+		} else if t.enabled {
+			t.next = t.schedule.Next(now)
+		}
+		go s.loop(ctx, t)
+	}
+}
+
+// Status returns the schedule and last-run information of every task.
+func (s *Scheduler) Status() []TaskStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]TaskStatus, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		out = append(out, t.statusLocked())
+	}
+	return out
+}
+
+// Lookup returns the status of the task registered under name.
+func (s *Scheduler) Lookup(name string) (TaskStatus, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if t := s.find(name); t != nil {
+		return t.statusLocked(), true
+	}
+	return TaskStatus{}, false
+}
+
+// Update sets the schedule of a task, a cron expression or @every interval
+// (see Parse; empty for the default interval), and enables or disables it.
+// It takes effect at once, before or after Start.
+func (s *Scheduler) Update(name, spec string, enabled bool) (TaskStatus, error) {
+	var schedule Schedule
+	if spec != "" {
+		var err error
+		if schedule, err = Parse(spec); err != nil {
+			return TaskStatus{}, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.find(name)
+	if t == nil {
+		return TaskStatus{}, ErrUnknownTask
+	}
+	if schedule == nil {
+		schedule = Every(t.interval)
+	}
+	if spec == t.spec && enabled == t.enabled {
+		return t.statusLocked(), nil
+	}
+	log.Printf("[INFO] scheduler: task %s now runs on %q, enabled=%t", name, specOr(spec, t.interval), enabled)
+	t.spec, t.schedule, t.enabled = spec, schedule, enabled
+	t.next = time.Time{}
+	if enabled {
+		t.next = schedule.Next(time.Now())
+	}
+	select {
+	case t.changed <- struct{}{}:
+	default:
+	}
+	return t.statusLocked(), nil
+}
+
+// SetInterval changes the default interval of a task, e.g. on a settings
+// reload. A task running on its default interval next runs one new interval
+// after its last run, or at once when that is past; a schedule set with
+// Update is kept.
+func (s *Scheduler) SetInterval(name string, interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidSchedule
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := s.find(name)
+	if t == nil {
+		return ErrUnknownTask
+	}
+	if interval == t.interval {
+		return nil
+	}
+	t.interval = interval
+	t.status.Interval = interval
+	if t.spec != "" {
+		return nil
+	}
+	log.Printf("[INFO] scheduler: task %s now runs every %s", name, interval)
+	t.schedule = Every(interval)
+	if !t.enabled || t.next.IsZero() {
+		return nil
+	}
+	now := time.Now()
+	t.next = now.Add(interval)
+	if !t.status.LastRun.IsZero() {
+		t.next = t.status.LastRun.Add(interval)
+		if t.next.Before(now) {
+			t.next = now
+		}
+	}
+	select {
+	case t.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *Scheduler) find(name string) *task {
+	for _, t := range s.tasks {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (t *task) statusLocked() TaskStatus {
+	st := t.status
+	st.Schedule = specOr(t.spec, t.interval)
+	st.DefaultSchedule = specOr("", t.interval)
+	st.Enabled = t.enabled
+	if !t.next.IsZero() {
+		next := t.next
+		st.NextRun = &next
+	}
+	return st
+}
+
+// specOr returns spec, or the @every spec of the default interval.
+func specOr(spec string, interval time.Duration) string {
+	if spec != "" {
+		return spec
+	}
+	return "@every " + interval.String()
+}
+
+func (s *Scheduler) loop(ctx context.Context, t *task) {
+	for {
+		s.mu.RLock()
+		next := t.next
+		s.mu.RUnlock()
+
+		// A disabled task, or a cron expression that never matches, waits
+		// for its schedule to change
+		var (
+			timer *time.Timer
+			due   <-chan time.Time
+		)
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+		fired := false
+		select {
+		case <-ctx.Done():
+		case <-t.changed:
+		case <-due:
+			fired = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if !fired {
+			continue
+		}
+
+		s.runLocked(ctx, t)
+	}
+}
+
+// nextRunLocked is the next run of a task that started at start. Runs that
+// took longer than the interval skip the missed ones.
+func (t *task) nextRunLocked(start time.Time) time.Time {
+	if !t.enabled {
+		return time.Time{}
+	}
+	next := t.schedule.Next(start)
+	if now := time.Now(); !next.IsZero() && next.Before(now) {
+		next = t.schedule.Next(now)
+	}
+	return next
+}
+
+// Failed returns the tasks whose last run failed.
+func (s *Scheduler) Failed() []TaskStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []TaskStatus
+	for _, t := range s.tasks {
+		if t.status.LastError != "" {
+			out = append(out, t.statusLocked())
+		}
+	}
+	return out
+}
+
+// runLocked runs a task under its lock, skipping the run when another
+// instance holds it, and schedules its next run. The lock is kept until
+// then: replicas start at different times, so each has its own schedule,
+// and would otherwise run the task again as soon as it is released.
+func (s *Scheduler) runLocked(ctx context.Context, t *task) {
+	start := time.Now()
+	s.mu.RLock()
+	locker := s.locker
+	s.mu.RUnlock()
+
+	var release func(next time.Time)
+	if locker != nil && !t.perInstance {
+		lockCtx, rel, ok, err := locker.TryLock(ctx, t.name)
+		if err != nil {
+			log.Printf("[ERROR] scheduler: task %s skipped, failed to take its lock: %v", t.name, err)
+		}
+		if !ok {
+			s.mu.Lock()
+			t.status.Skipped++
+			t.next = t.nextRunLocked(start)
+			s.mu.Unlock()
+			return
+		}
+		ctx, release = lockCtx, rel
+	}
+
+	s.run(ctx, t)
+
+	s.mu.Lock()
+	t.next = t.nextRunLocked(start)
+	next := t.next
+	s.mu.Unlock()
+	if release != nil {
+		release(next)
+	}
+}
+
+// run runs a task, again with exponential backoff while it fails with a
+// retryable error, up to maxAttempts and before its next scheduled run. Panics are
+// recovered and fail the run, so a bad run cannot stop the loop.
+func (s *Scheduler) run(ctx context.Context, t *task) {
+	start := time.Now()
+	var (
+		err     error
+		attempt int
+	)
+	for attempt = 1; ; attempt++ {
+		err = retry.Run(func() error { return t.fn(ctx) })
+		var panicked *retry.PanicError
+		if errors.As(err, &panicked) {
+			log.Printf("[ERROR] scheduler: task %s panicked: %v\n%s", t.name, panicked.Value, panicked.Stack)
+		}
+		if !retry.Retryable(err) || attempt == maxAttempts {
+			break
+		}
+		delay := retry.Delay(baseRetryDelay, attempt)
+		s.mu.RLock()
+		next := t.schedule.Next(start)
+		s.mu.RUnlock()
+		if !next.IsZero() && !time.Now().Add(delay).Before(next) {
+			break
+		}
+		log.Printf("[WARN] scheduler: task %s failed on attempt %d/%d, retrying in %s: %v", t.name, attempt, maxAttempts, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+
+	s.mu.Lock()
+	t.status.LastRun = start
+	t.status.Runs++
+	t.status.Attempts = attempt
+	t.status.LastError = ""
+	t.status.ErrorClass = ""
+	if err != nil {
+		t.status.LastError = err.Error()
+		t.status.ErrorClass = retry.Class(err)
+		t.status.Failures++
+		now := time.Now()
+		t.status.LastFailure = &now
+	} else {
+		t.status.Failures = 0
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		log.Printf("[ERROR] scheduler: task %s failed (%s): %v", t.name, retry.Class(err), err)
+		return
+	}
+	log.Printf("[INFO] scheduler: task %s finished in %s", t.name, time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,74 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memLocker is a Locker shared by schedulers standing for replicas.
+type memLocker struct {
+	mu    sync.Mutex
+	owner string
+	until time.Time
+}
+
+func (l *memLocker) forOwner(owner string) Locker {
+	return lockerFunc(func(ctx context.Context, name string) (context.Context, func(time.Time), bool, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.owner != "" && l.owner != owner && time.Now().Before(l.until) {
+			return nil, nil, false, nil
+		}
+		l.owner, l.until = owner, time.Now().Add(time.Hour)
+		return ctx, func(next time.Time) {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.until = next
+		}, true, nil
+	})
+}
+
+type lockerFunc func(ctx context.Context, name string) (context.Context, func(time.Time), bool, error)
+
+func (f lockerFunc) TryLock(ctx context.Context, name string) (context.Context, func(time.Time), bool, error) {
+	return f(ctx, name)
+}
+
+func TestLockedTaskRunsOncePerInterval(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var runs atomic.Int32
+	task := func(context.Context) error {
+		runs.Add(1)
+		return nil
+	}
+	locker := &memLocker{}
+	replicas := make([]*Scheduler, 2)
+	for i, owner := range []string{"a", "b"} {
+		s := New()
+		s.Every("snapshot", interval, task)
+		s.SetLocker(locker.forOwner(owner))
+		replicas[i] = s
+	}
+
+	// The replicas start out of step, each running the task at once
+	replicas[0].Start(ctx)
+	time.Sleep(interval / 3)
+	replicas[1].Start(ctx)
+	time.Sleep(4*interval + interval/2)
+	cancel()
+
+	// a runs at 0, 100, 200, 300 and 400ms; b finds the lock held every time
+	if n := runs.Load(); n < 4 || n > 6 {
+		t.Errorf("ran %d times, want 5", n)
+	}
+	st, _ := replicas[1].Lookup("snapshot")
+	if st.Runs != 0 || st.Skipped == 0 {
+		t.Errorf("second replica ran %d times, skipped %d", st.Runs, st.Skipped)
+	}
+}
@@ -0,0 +1,117 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const awsSigningAlgorithm = "AWS4-HMAC-SHA256"
+
+// aws reads secrets from AWS Secrets Manager, signing its requests with
+// Signature Version 4.
+type aws struct {
+	cfg        Config
+	httpClient *http.Client
+}
+
+// getSecretValue returns the current string value of a secret.
+func (a *aws) getSecretValue(ctx context.Context, secretID string) (string, error) {
+	if a.cfg.AWSRegion == "" {
+		return "", errors.New("secrets: AWS_REGION is not set")
+	}
+	if a.cfg.AWSAccessKey == "" || a.cfg.AWSSecretKey == "" {
+		return "", errors.New("secrets: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set")
+	}
+	body, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", err
+	}
+	host := "secretsmanager." + a.cfg.AWSRegion + ".amazonaws.com"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	a.sign(req, host, body, time.Now())
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return "", fmt.Errorf("secrets: Secrets Manager answered %d for %s: %s", resp.StatusCode, secretID, strings.TrimSpace(string(msg)))
+	}
+	var out struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	return out.SecretString, nil
+}
+
+// sign adds the Signature Version 4 Authorization header to a request to
+// the root path of host.
+func (a *aws) sign(req *http.Request, host string, body []byte, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	amzDate := now.Format("20060102T150405Z")
+	scope := strings.Join([]string{date, a.cfg.AWSRegion, "secretsmanager", "aws4_request"}, "/")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.cfg.AWSSessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.cfg.AWSSessionToken)
+	}
+
+	headers := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	if a.cfg.AWSSessionToken != "" {
+		headers = append(headers, "x-amz-security-token")
+	}
+	var canonicalHeaders strings.Builder
+	for _, h := range headers {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{awsSigningAlgorithm, amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+a.cfg.AWSSecretKey), date)
+	key = hmacSHA256(key, a.cfg.AWSRegion)
+	key = hmacSHA256(key, "secretsmanager")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigningAlgorithm, a.cfg.AWSAccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
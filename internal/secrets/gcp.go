@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gcpSecretManagerURL = "https://secretmanager.googleapis.com/v1/"
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// gcp reads secrets from GCP Secret Manager as the service account of the
+// machine (Compute Engine, GKE workload identity, Cloud Run).
+type gcp struct {
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// access returns a version of a secret, the latest unless name ends with
+// /versions/V.
+func (g *gcp) access(ctx context.Context, name string) (string, error) {
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpSecretManagerURL+name+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var resp struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := g.do(req, "Secret Manager", &resp); err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("secrets: Secret Manager sent an invalid payload for %s", name)
+	}
+	return string(data), nil
+}
+
+// accessToken returns a cached access token of the machine's service
+// account, asking the metadata server for a new one when it is about to
+// expire.
+func (g *gcp) accessToken(ctx context.Context) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Now().Add(tokenRefreshMargin).Before(g.tokenExpiry) {
+		return g.token, nil
+	}
+	now := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := g.do(req, "the metadata server", &tok); err != nil {
+		return "", err
+	}
+	g.token = tok.AccessToken
+	g.tokenExpiry = now.Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return g.token, nil
+}
+
+func (g *gcp) do(req *http.Request, what string, out interface{}) error {
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("secrets: %s answered %d: %s", what, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,100 @@
+// Package secrets keeps secrets out of plaintext configuration: it seals
+// credentials with AES-256-GCM under a master key, so the database only
+// ever holds them encrypted, and fetches secrets from Vault and cloud
+// secret managers (see Resolver).
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeySize is the size of a master key: AES-256.
+const KeySize = 32
+
+// ErrWrongKey is returned by Open for values sealed under another master
+// key, or tampered with.
+var ErrWrongKey = errors.New("sealed with another master key or corrupted")
+
+// Box seals and opens values under one master key.
+type Box struct {
+	aead  cipher.AEAD
+	keyID string
+}
+
+// New returns a Box for a KeySize-byte master key.
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key)
+	return &Box{aead: aead, keyID: hex.EncodeToString(sum[:4])}, nil
+}
+
+// ParseKey decodes a master key written as base64 or hex, e.g. by
+// `openssl rand -base64 32`.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if key, err := base64.StdEncoding.DecodeString(s); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(s); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("master key must be %d bytes in base64 or hex", KeySize)
+}
+
+// KeyID identifies the master key without revealing it, so values sealed
+// under a previous key can be told apart.
+func (b *Box) KeyID() string {
+	return b.keyID
+}
+
+// Seal encrypts plaintext, binding it to context (e.g. the name it is
+// stored under) so it cannot be opened under another one. The result is
+// base64 of the nonce followed by the ciphertext.
+func (b *Box) Seal(plaintext, context []byte) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, plaintext, context)), nil
+}
+
+// Open decrypts a value made by Seal with the same context.
+func (b *Box) Open(sealed string, context []byte) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < b.aead.NonceSize() {
+		return nil, ErrWrongKey
+	}
+	nonce, ciphertext := raw[:b.aead.NonceSize()], raw[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, context)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plaintext, nil
+}
+
+// Mask hides a value for display, keeping its last 4 characters when it is
+// long enough for them not to give it away.
+func Mask(value string) string {
+	if len(value) < 16 {
+		return "********"
+	}
+	return "********" + value[len(value)-4:]
+}
@@ -0,0 +1,121 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Secret manager reference schemes, see Resolver.
+const (
+	SchemeVault = "vault"
+	SchemeGCP   = "gcp"
+	SchemeAWS   = "aws"
+)
+
+// tokenRefreshMargin renews cached access tokens this long before they
+// expire.
+const tokenRefreshMargin = time.Minute
+
+// Config is how to reach the secret managers. GCP Secret Manager needs no
+// configuration: it authenticates as the service account of the machine
+// through the metadata server.
+type Config struct {
+	// VaultAddr is the address of the Vault server. It is reached with
+	// VaultToken, or logs in with AppRole when VaultRoleID is set.
+	VaultAddr      string
+	VaultToken     string
+	VaultRoleID    string
+	VaultSecretID  string
+	VaultNamespace string
+
+	AWSRegion       string
+	AWSAccessKey    string
+	AWSSecretKey    string
+	AWSSessionToken string
+}
+
+// Resolver fetches the secrets references point to. A reference is
+//
+//	vault:<path>#<field>      a field of a Vault secret, KV version 1 or 2,
+//	                          e.g. vault:secret/data/melibot#db_password
+//	gcp:<secret>[#<field>]    a GCP Secret Manager secret, projects/P/secrets/S
+//	                          with an optional /versions/V (default latest)
+//	aws:<secret-id>[#<field>] an AWS Secrets Manager secret
+//
+// where the optional field picks a key of a secret holding a JSON object.
+type Resolver struct {
+	vault *vault
+	gcp   *gcp
+	aws   *aws
+}
+
+// NewResolver returns a Resolver for the secret managers of cfg.
+func NewResolver(cfg Config) *Resolver {
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	return &Resolver{
+		vault: &vault{cfg: cfg, httpClient: httpClient},
+		gcp:   &gcp{httpClient: httpClient},
+		aws:   &aws{cfg: cfg, httpClient: httpClient},
+	}
+}
+
+// IsRef reports whether value is a reference to a secret manager rather
+// than a secret.
+func IsRef(value string) bool {
+	scheme, _, ok := strings.Cut(value, ":")
+	return ok && (scheme == SchemeVault || scheme == SchemeGCP || scheme == SchemeAWS)
+}
+
+// Resolve fetches the secret ref points to.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	scheme, rest, _ := strings.Cut(ref, ":")
+	name, field, _ := strings.Cut(rest, "#")
+	if name == "" {
+		return "", fmt.Errorf("secrets: %s reference without a secret", scheme)
+	}
+	var (
+		value string
+		err   error
+	)
+	switch scheme {
+	case SchemeVault:
+		if field == "" {
+			return "", fmt.Errorf("secrets: vault reference %s needs a #field", name)
+		}
+		return r.vault.read(ctx, name, field)
+	case SchemeGCP:
+		value, err = r.gcp.access(ctx, name)
+	case SchemeAWS:
+		value, err = r.aws.getSecretValue(ctx, name)
+	default:
+		return "", fmt.Errorf("secrets: unknown reference scheme %q", scheme)
+	}
+	if err != nil || field == "" {
+		return value, err
+	}
+	return jsonField(value, name, field)
+}
+
+// jsonField returns a field of a secret holding a JSON object.
+func jsonField(value, name, field string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secrets: %s is not a JSON object", name)
+	}
+	return fieldString(fields, name, field)
+}
+
+func fieldString(fields map[string]interface{}, name, field string) (string, error) {
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secrets: %s has no field %q", name, field)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(v), nil
+}
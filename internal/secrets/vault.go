@@ -0,0 +1,113 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vault reads secrets from HashiCorp Vault's HTTP API.
+type vault struct {
+	cfg        Config
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// read returns a field of the secret at path.
+func (v *vault) read(ctx context.Context, path, field string) (string, error) {
+	token, err := v.clientToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, path, token, nil, &resp); err != nil {
+		return "", err
+	}
+	// KV version 2 nests the secret under data.data, next to its metadata
+	data := resp.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+	return fieldString(data, path, field)
+}
+
+// clientToken returns VaultToken, or a cached AppRole token, logging in
+// again when it is about to expire.
+func (v *vault) clientToken(ctx context.Context) (string, error) {
+	if v.cfg.VaultAddr == "" {
+		return "", errors.New("secrets: VAULT_ADDR is not set")
+	}
+	if v.cfg.VaultRoleID == "" {
+		if v.cfg.VaultToken == "" {
+			return "", errors.New("secrets: set VAULT_TOKEN, or VAULT_ROLE_ID and VAULT_SECRET_ID")
+		}
+		return v.cfg.VaultToken, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.token != "" && time.Now().Add(tokenRefreshMargin).Before(v.tokenExpiry) {
+		return v.token, nil
+	}
+	now := time.Now()
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	login := map[string]string{"role_id": v.cfg.VaultRoleID, "secret_id": v.cfg.VaultSecretID}
+	if err := v.do(ctx, http.MethodPost, "auth/approle/login", "", login, &resp); err != nil {
+		return "", err
+	}
+	v.token = resp.Auth.ClientToken
+	v.tokenExpiry = now.Add(time.Duration(resp.Auth.LeaseDuration) * time.Second)
+	return v.token, nil
+}
+
+// do sends a request to the Vault API and decodes its JSON answer into out.
+func (v *vault) do(ctx context.Context, method, path, token string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		buf, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+	endpoint := strings.TrimSuffix(v.cfg.VaultAddr, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.cfg.VaultNamespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.VaultNamespace)
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("secrets: Vault answered %d for %s: %s", resp.StatusCode, path, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
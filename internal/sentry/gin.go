@@ -0,0 +1,150 @@
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/internal/capture"
+	"melibot/internal/middleware"
+)
+
+const (
+	// maxErrorBody caps how much of an error response is read for its
+	// message.
+	maxErrorBody = 1024
+	// panicFlushTimeout bounds how long a panic waits for its report before
+	// going on.
+	panicFlushTimeout = 2 * time.Second
+)
+
+// Middleware reports the panics of handlers, before passing them on to the
+// recovery middleware, and the requests answered with a 5xx status. It must
+// come after gin's Recovery.
+func (t *Tracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if t == nil {
+			c.Next()
+			return
+		}
+		s := &scope{
+			transaction: c.Request.Method + " " + c.Request.URL.Path,
+			request: &request{
+				URL:     capture.RedactURL(c.Request.URL),
+				Method:  c.Request.Method,
+				Headers: requestHeaders(c.Request.Header),
+			},
+			user: map[string]string{"ip_address": c.ClientIP()},
+		}
+		ctx := withScope(c.Request.Context(), s)
+		c.Request = c.Request.WithContext(ctx)
+		rec := &errorRecorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v != http.ErrAbortHandler {
+				setRoute(s, c)
+				t.capturePanic(ctx, v, 2)
+				t.Flush(panicFlushTimeout)
+			}
+			panic(v)
+		}()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < http.StatusInternalServerError {
+			return
+		}
+		route := setRoute(s, c)
+		msg := c.Errors.String()
+		if msg == "" {
+			msg = rec.message()
+		}
+		e := t.newEvent(ctx, "error")
+		e.Exception = []exception{{Type: strconv.Itoa(status) + " " + http.StatusText(status), Value: redact(msg)}}
+		e.Tags["status_code"] = strconv.Itoa(status)
+		// One issue per endpoint and status, whatever the message
+		e.Fingerprint = []string{c.Request.Method, route, strconv.Itoa(status)}
+		t.enqueue(e)
+	}
+}
+
+// setRoute names the transaction after the matched route, e.g.
+// GET /api/items/:id, sets the user the auth middlewares validated, and
+// returns the route.
+func setRoute(s *scope, c *gin.Context) string {
+	route := c.FullPath()
+	if route == "" {
+		route = c.Request.URL.Path
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.transaction = c.Request.Method + " " + route
+	s.user["id"] = middleware.AuditActor(c)
+	s.tags = map[string]string{"route": route}
+	return route
+}
+
+// Task wraps a periodic task so its errors and panics are reported, with
+// breadcrumbs of the upstream calls of the run.
+func (t *Tracker) Task(name string, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	if t == nil {
+		return fn
+	}
+	return func(ctx context.Context) error {
+		s := &scope{transaction: "task " + name, tags: map[string]string{"task": name}}
+		ctx = withScope(ctx, s)
+		defer func() {
+			if v := recover(); v != nil {
+				t.capturePanic(ctx, v, 2)
+				t.Flush(panicFlushTimeout)
+				panic(v)
+			}
+		}()
+		err := fn(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			t.CaptureError(ctx, err)
+		}
+		return err
+	}
+}
+
+// errorRecorder keeps the beginning of the response body, where handlers
+// write the error message.
+type errorRecorder struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (r *errorRecorder) Write(b []byte) (int, error) {
+	if room := maxErrorBody - r.buf.Len(); room > 0 {
+		r.buf.Write(b[:min(room, len(b))])
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *errorRecorder) WriteString(s string) (int, error) {
+	return r.Write([]byte(s))
+}
+
+// message returns the error of a {"error": "..."} body, or the body.
+func (r *errorRecorder) message() string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(r.buf.Bytes(), &body) == nil && body.Error != "" {
+		return body.Error
+	}
+	return r.buf.String()
+}
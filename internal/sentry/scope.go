@@ -0,0 +1,127 @@
+package sentry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"melibot/internal/capture"
+)
+
+// maxBreadcrumbs bounds the breadcrumbs kept per request or task; the
+// oldest are dropped first.
+const maxBreadcrumbs = 50
+
+// Breadcrumb is an event leading up to a report, e.g. an upstream call.
+type Breadcrumb struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Type      string         `json:"type,omitempty"`
+	Category  string         `json:"category,omitempty"`
+	Message   string         `json:"message,omitempty"`
+	Level     string         `json:"level,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// scope is what a request or task adds to its reports.
+type scope struct {
+	mu          sync.Mutex
+	transaction string
+	request     *request
+	user        map[string]string
+	tags        map[string]string
+	breadcrumbs []Breadcrumb
+}
+
+type scopeCtxKey struct{}
+
+func withScope(ctx context.Context, s *scope) context.Context {
+	return context.WithValue(ctx, scopeCtxKey{}, s)
+}
+
+func scopeFrom(ctx context.Context) *scope {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(scopeCtxKey{}).(*scope)
+	return s
+}
+
+// AddBreadcrumb records b in the scope of ctx, if any.
+func AddBreadcrumb(ctx context.Context, b Breadcrumb) {
+	s := scopeFrom(ctx)
+	if s == nil {
+		return
+	}
+	if b.Timestamp.IsZero() {
+		b.Timestamp = time.Now().UTC()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.breadcrumbs) == maxBreadcrumbs {
+		s.breadcrumbs = append(s.breadcrumbs[:0], s.breadcrumbs[1:]...)
+	}
+	s.breadcrumbs = append(s.breadcrumbs, b)
+}
+
+func (s *scope) apply(e *event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.Transaction = s.transaction
+	e.Request = s.request
+	e.User = s.user
+	for k, v := range s.tags {
+		e.Tags[k] = v
+	}
+	e.Breadcrumbs = append([]Breadcrumb(nil), s.breadcrumbs...)
+}
+
+// Transport returns a RoundTripper leaving a breadcrumb of every call made
+// through next in the scope of the request context.
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return &transport{next: next}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if scopeFrom(req.Context()) == nil {
+		return t.next.RoundTrip(req)
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	data := map[string]any{
+		"method":      req.Method,
+		"url":         capture.RedactURL(req.URL),
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	level := "info"
+	if err != nil {
+		data["reason"] = redact(err.Error())
+		level = "error"
+	} else {
+		data["status_code"] = resp.StatusCode
+		if resp.StatusCode >= 400 {
+			level = "warning"
+		}
+	}
+	AddBreadcrumb(req.Context(), Breadcrumb{Timestamp: start.UTC(), Type: "http", Category: "http", Level: level, Data: data})
+	return resp, err
+}
+
+// redact hides tokens and email addresses in report text.
+func redact(s string) string {
+	return capture.RedactText(s)
+}
+
+// requestHeaders flattens the redacted headers of a request.
+func requestHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range capture.RedactHeader(h) {
+		out[k] = strings.Join(v, ", ")
+	}
+	return out
+}
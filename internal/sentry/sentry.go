@@ -0,0 +1,283 @@
+// Package sentry reports panics and errors to Sentry, or any service
+// speaking its protocol (e.g. GlitchTip), through the envelope endpoint of
+// a DSN. Reports carry the request or task they come from and breadcrumbs
+// of the upstream calls made meanwhile, redacted like the debug capture.
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	clientName = "melibot/1.0"
+	// queueSize bounds the reports waiting to be sent; more are dropped
+	queueSize = 100
+)
+
+// Config configures a Tracker.
+type Config struct {
+	// DSN is the project DSN, e.g. https://public@o1.ingest.sentry.io/42
+	DSN         string
+	Environment string
+	Release     string
+}
+
+// Tracker sends reports in the background. A nil Tracker reports nothing,
+// so callers need not check whether error tracking is configured.
+type Tracker struct {
+	endpoint   string
+	auth       string
+	dsn        string
+	cfg        Config
+	serverName string
+	httpClient *http.Client
+
+	queue   chan *event
+	pending sync.WaitGroup
+}
+
+// New returns a Tracker for cfg.DSN, nil when it is empty.
+func New(cfg Config) (*Tracker, error) {
+	if cfg.DSN == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(cfg.DSN)
+	if err != nil || u.User == nil || u.User.Username() == "" || u.Host == "" {
+		return nil, fmt.Errorf("sentry: invalid DSN")
+	}
+	path := strings.Trim(u.Path, "/")
+	project := path
+	prefix := ""
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		prefix, project = "/"+path[:i], path[i+1:]
+	}
+	if project == "" {
+		return nil, fmt.Errorf("sentry: DSN has no project ID")
+	}
+	hostname, _ := os.Hostname()
+	t := &Tracker{
+		endpoint:   fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, prefix, project),
+		auth:       fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", clientName, u.User.Username()),
+		dsn:        cfg.DSN,
+		cfg:        cfg,
+		serverName: hostname,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		queue:      make(chan *event, queueSize),
+	}
+	go t.loop()
+	return t, nil
+}
+
+// event is the subset of the Sentry event payload the Tracker fills in.
+type event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Transaction string            `json:"transaction,omitempty"`
+	Exception   []exception       `json:"exception,omitempty"`
+	Request     *request          `json:"request,omitempty"`
+	User        map[string]string `json:"user,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Fingerprint []string          `json:"fingerprint,omitempty"`
+	Breadcrumbs []Breadcrumb      `json:"breadcrumbs,omitempty"`
+}
+
+type exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *stacktrace `json:"stacktrace,omitempty"`
+}
+
+type stacktrace struct {
+	Frames []frame `json:"frames"`
+}
+
+type frame struct {
+	Function string `json:"function"`
+	Module   string `json:"module,omitempty"`
+	Filename string `json:"filename"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+type request struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// CaptureError reports err with the breadcrumbs of the scope in ctx.
+func (t *Tracker) CaptureError(ctx context.Context, err error) {
+	if t == nil || err == nil {
+		return
+	}
+	e := t.newEvent(ctx, "error")
+	e.Exception = []exception{{Type: fmt.Sprintf("%T", err), Value: redact(err.Error())}}
+	t.enqueue(e)
+}
+
+// capturePanic reports a recovered panic with the stack of the goroutine
+// that panicked; skip drops the frames of the recovering functions.
+func (t *Tracker) capturePanic(ctx context.Context, value any, skip int) {
+	e := t.newEvent(ctx, "fatal")
+	e.Exception = []exception{{
+		Type:       "panic",
+		Value:      redact(fmt.Sprint(value)),
+		Stacktrace: callers(skip + 1),
+	}}
+	t.enqueue(e)
+}
+
+func (t *Tracker) newEvent(ctx context.Context, level string) *event {
+	e := &event{
+		EventID:     newEventID(),
+		Timestamp:   time.Now().UTC(),
+		Level:       level,
+		Platform:    "go",
+		ServerName:  t.serverName,
+		Environment: t.cfg.Environment,
+		Release:     t.cfg.Release,
+		Tags:        map[string]string{},
+	}
+	if s := scopeFrom(ctx); s != nil {
+		s.apply(e)
+	}
+	return e
+}
+
+func (t *Tracker) enqueue(e *event) {
+	t.pending.Add(1)
+	select {
+	case t.queue <- e:
+	default:
+		t.pending.Done()
+		log.Printf("[WARN] sentry: queue full, dropping event %s", e.EventID)
+	}
+}
+
+// Flush waits up to timeout for the queued reports to be sent and reports
+// whether they all were.
+func (t *Tracker) Flush(timeout time.Duration) bool {
+	if t == nil {
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		t.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (t *Tracker) loop() {
+	for e := range t.queue {
+		if err := t.send(e); err != nil {
+			log.Printf("[WARN] sentry: failed to send event %s: %v", e.EventID, err)
+		}
+		t.pending.Done()
+	}
+}
+
+// send posts an event as an envelope: a header line, an item header line
+// and the event itself.
+func (t *Tracker) send(e *event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	header, err := json.Marshal(map[string]any{"event_id": e.EventID, "sent_at": time.Now().UTC(), "dsn": t.dsn})
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	body.Write(header)
+	fmt.Fprintf(&body, "\n{\"type\":\"event\",\"length\":%d}\n", len(payload))
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", t.auth)
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("answered %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// callers returns the stack above skip frames, outermost call first as
+// Sentry expects.
+func callers(skip int) *stacktrace {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var out []frame
+	for {
+		f, more := frames.Next()
+		module, function := splitFunction(f.Function)
+		out = append(out, frame{
+			Function: function,
+			Module:   module,
+			Filename: f.File[strings.LastIndex(f.File, "/")+1:],
+			AbsPath:  f.File,
+			Lineno:   f.Line,
+			InApp:    strings.HasPrefix(module, "melibot") || module == "main",
+		})
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return &stacktrace{Frames: out}
+}
+
+// splitFunction splits e.g. melibot/internal/service.(*X).Y into its
+// package and function.
+func splitFunction(name string) (string, string) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	return name[:slash+1+dot], name[slash+2+dot:]
+}
+
+func newEventID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
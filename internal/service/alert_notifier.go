@@ -0,0 +1,127 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// AlertChannel delivers an alert to the operator.
+type AlertChannel interface {
+	Send(ctx context.Context, subject, text string) error
+	String() string
+}
+
+// AlertNotifier sends the alerts of the event bus (rank drops, listings
+// below cost, competitor moves) to the operator's channels, besides the
+// log. A nil AlertNotifier sends nothing.
+type AlertNotifier struct {
+	channels []AlertChannel
+}
+
+// NewAlertNotifier returns a notifier sending to channels, nil without
+// any.
+func NewAlertNotifier(channels ...AlertChannel) *AlertNotifier {
+	if len(channels) == 0 {
+		return nil
+	}
+	return &AlertNotifier{channels: channels}
+}
+
+// Notify sends an alert to every channel in the background, so publishers
+// are not held up by a slow channel; failures are logged.
+func (n *AlertNotifier) Notify(ctx context.Context, subject, text string) {
+	if n == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, ch := range n.channels {
+		go func(ch AlertChannel) {
+			ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			defer cancel()
+			if err := ch.Send(ctx, subject, text); err != nil {
+				log.Printf("[WARN] alerts: failed to send %q to %s: %v", subject, ch, err)
+			}
+		}(ch)
+	}
+}
+
+// TelegramChannel posts alerts to a Telegram chat through a bot.
+type TelegramChannel struct {
+	token      string
+	chatID     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewTelegramChannel(token, chatID string) *TelegramChannel {
+	return &TelegramChannel{
+		token:      token,
+		chatID:     chatID,
+		baseURL:    "https://api.telegram.org",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *TelegramChannel) Send(ctx context.Context, subject, text string) error {
+	body, err := json.Marshal(map[string]string{"chat_id": t.chatID, "text": subject + "\n\n" + text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/bot"+t.token+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		// The URL holds the token
+		return fmt.Errorf("telegram: %w", errors.Unwrap(err))
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (t *TelegramChannel) String() string { return "telegram chat " + t.chatID }
+
+// SMTPChannel emails alerts.
+type SMTPChannel struct {
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// NewSMTPChannel returns a channel sending through the server at addr
+// (host:port), authenticating when username is set.
+func NewSMTPChannel(addr, username, password, from string, to []string) *SMTPChannel {
+	return &SMTPChannel{addr: addr, username: username, password: password, from: from, to: to}
+}
+
+func (s *SMTPChannel) Send(ctx context.Context, subject, text string) error {
+	var auth smtp.Auth
+	if s.username != "" {
+		host, _, _ := net.SplitHostPort(s.addr)
+		auth = smtp.PlainAuth("", s.username, s.password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		s.from, strings.Join(s.to, ", "), subject, text)
+	// net/smtp takes no context; SendMail upgrades to TLS when offered
+	return smtp.SendMail(s.addr, auth, s.from, s.to, []byte(msg))
+}
+
+func (s *SMTPChannel) String() string { return "email to " + strings.Join(s.to, ", ") }
@@ -0,0 +1,421 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"melibot/database"
+	"melibot/internal/events"
+	"melibot/internal/repository"
+)
+
+// StripeSignatureHeader carries the signature of Stripe webhooks.
+const StripeSignatureHeader = "Stripe-Signature"
+
+// stripeSignatureTolerance is how old a signed Stripe webhook may be, which
+// keeps captured deliveries from being replayed later.
+const stripeSignatureTolerance = 5 * time.Minute
+
+var (
+	ErrBillingSignature = errors.New("invalid billing webhook signature")
+	ErrBillingPayload   = errors.New("invalid billing webhook payload")
+	ErrUnknownPlan      = errors.New("unknown plan")
+)
+
+// Plan is a subscription plan. RPS and Burst cap the API calls of each
+// organization on it, on top of the per-client rate limit.
+type Plan struct {
+	Name  string  `json:"name"`
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst"`
+}
+
+// Plans are the plans organizations can subscribe to, by name.
+var Plans = map[string]Plan{
+	"basic":    {Name: "basic", RPS: 2, Burst: 10},
+	"pro":      {Name: "pro", RPS: 5, Burst: 20},
+	"business": {Name: "business", RPS: 20, Burst: 60},
+}
+
+// BillingConfig configures BillingService.
+type BillingConfig struct {
+	// WebhookSecret is the Stripe endpoint secret that signs its webhooks.
+	WebhookSecret string
+	// Prices maps Stripe price IDs to plan names.
+	Prices map[string]string
+	// GracePeriod is how long a plan keeps working once a payment failed,
+	// or once its period ended without a renewal.
+	GracePeriod time.Duration
+}
+
+// Entitlement is what the plan of an organization allows right now.
+type Entitlement struct {
+	Plan       string     `json:"plan"`
+	Status     string     `json:"status"`
+	Allowed    bool       `json:"allowed"`
+	RPS        float64    `json:"rps,omitempty"`
+	Burst      int        `json:"burst,omitempty"`
+	PeriodEnd  *time.Time `json:"period_end,omitempty"`
+	GraceUntil *time.Time `json:"grace_until,omitempty"`
+}
+
+// BillingService keeps the subscription plans of organizations in multi-
+// tenant mode. Stripe webhooks activate, suspend and cancel them; grace
+// periods are decided here, so a plan never lapses before the server says
+// so, even when the provider's events are late.
+type BillingService struct {
+	repo *repository.OrganizationRepository
+	bus  *events.Bus
+	cfg  BillingConfig
+}
+
+func NewBillingService(repo *repository.OrganizationRepository, bus *events.Bus, cfg BillingConfig) *BillingService {
+	return &BillingService{repo: repo, bus: bus, cfg: cfg}
+}
+
+// Entitlement returns what the plan of the organization of ctx allows.
+func (s *BillingService) Entitlement(ctx context.Context) (*Entitlement, error) {
+	id, ok := database.OrgFromContext(ctx)
+	if !ok {
+		return nil, ErrOrganizationNotFound
+	}
+	org, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, ErrOrganizationNotFound
+	}
+	e := s.entitlement(org, time.Now())
+	return &e, nil
+}
+
+func (s *BillingService) entitlement(org *repository.Organization, now time.Time) Entitlement {
+	e := Entitlement{
+		Plan:       org.Plan,
+		Status:     org.PlanStatus,
+		PeriodEnd:  org.PlanPeriodEnd,
+		GraceUntil: org.GraceUntil,
+	}
+	switch org.PlanStatus {
+	case repository.PlanStatusActive:
+		e.Allowed = org.PlanPeriodEnd == nil || now.Before(org.PlanPeriodEnd.Add(s.cfg.GracePeriod))
+	case repository.PlanStatusPastDue:
+		e.Allowed = org.GraceUntil != nil && now.Before(*org.GraceUntil)
+	}
+	if plan, ok := Plans[org.Plan]; ok && e.Allowed {
+		e.RPS, e.Burst = plan.RPS, plan.Burst
+	}
+	return e
+}
+
+// Entitled keeps the organizations of ids whose plan works, for background
+// work that lapsed plans no longer get.
+func (s *BillingService) Entitled(ctx context.Context, ids []uint) ([]uint, error) {
+	rows, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	allowed := make(map[uint]bool, len(rows))
+	for i := range rows {
+		allowed[rows[i].ID] = s.entitlement(&rows[i], now).Allowed
+	}
+	var kept []uint
+	for _, id := range ids {
+		if allowed[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept, nil
+}
+
+// SetPlan puts an organization on a plan by hand, e.g. for a trial or an
+// invoice paid outside the provider, until periodEnd when set. An empty
+// plan deactivates it.
+func (s *BillingService) SetPlan(ctx context.Context, id uint, plan string, periodEnd *time.Time) (*Entitlement, error) {
+	if _, ok := Plans[plan]; plan != "" && !ok {
+		return nil, ErrUnknownPlan
+	}
+	org, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if org == nil {
+		return nil, ErrOrganizationNotFound
+	}
+	fromPlan, from := org.Plan, org.PlanStatus
+	if plan == "" {
+		org.PlanStatus = repository.PlanStatusInactive
+	} else {
+		org.Plan = plan
+		org.PlanStatus = repository.PlanStatusActive
+		org.PlanPeriodEnd = periodEnd
+	}
+	org.GraceUntil = nil
+	if err := s.repo.SaveBilling(ctx, org); err != nil {
+		return nil, err
+	}
+	s.publishChange(ctx, org, fromPlan, from)
+	e := s.entitlement(org, time.Now())
+	return &e, nil
+}
+
+// ExpireGrace deactivates the past-due plans whose grace period ended.
+// Entitlement already refuses them; this records it and tells subscribers.
+func (s *BillingService) ExpireGrace(ctx context.Context) error {
+	rows, err := s.repo.ListGraceExpired(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range rows {
+		org := &rows[i]
+		org.PlanStatus = repository.PlanStatusInactive
+		if err := s.repo.SaveBilling(ctx, org); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.Printf("[WARN] Grace period of organization %d ended; its %s plan is inactive", org.ID, org.Plan)
+		s.publishChange(ctx, org, org.Plan, repository.PlanStatusPastDue)
+	}
+	return errors.Join(errs...)
+}
+
+// stripeEvent is the envelope of a Stripe webhook.
+type stripeEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+type stripeSubscription struct {
+	ID               string            `json:"id"`
+	Customer         string            `json:"customer"`
+	Status           string            `json:"status"`
+	CurrentPeriodEnd int64             `json:"current_period_end"`
+	Metadata         map[string]string `json:"metadata"`
+	Items            struct {
+		Data []struct {
+			CurrentPeriodEnd int64 `json:"current_period_end"`
+			Price            struct {
+				ID string `json:"id"`
+			} `json:"price"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+type stripeInvoice struct {
+	ID           string `json:"id"`
+	Customer     string `json:"customer"`
+	Subscription string `json:"subscription"`
+}
+
+// periodEnd returns the end of the paid period; newer API versions moved it
+// from the subscription to its items.
+func (sub stripeSubscription) periodEnd() *time.Time {
+	end := sub.CurrentPeriodEnd
+	if end == 0 && len(sub.Items.Data) > 0 {
+		end = sub.Items.Data[0].CurrentPeriodEnd
+	}
+	if end == 0 {
+		return nil
+	}
+	t := time.Unix(end, 0)
+	return &t
+}
+
+// HandleStripe applies a Stripe webhook after checking its signature.
+// Subscriptions name their organization in metadata.org_id (set it in
+// subscription_data.metadata when creating the checkout session); invoices
+// are matched by customer. Events for unknown organizations are ignored.
+func (s *BillingService) HandleStripe(ctx context.Context, payload []byte, signature string) error {
+	if err := verifyStripeSignature(signature, payload, s.cfg.WebhookSecret, time.Now()); err != nil {
+		return err
+	}
+	var ev stripeEvent
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return ErrBillingPayload
+	}
+	at := time.Unix(ev.Created, 0)
+
+	switch ev.Type {
+	case "customer.subscription.created", "customer.subscription.updated", "customer.subscription.deleted":
+		var sub stripeSubscription
+		if err := json.Unmarshal(ev.Data.Object, &sub); err != nil {
+			return ErrBillingPayload
+		}
+		org, err := s.subscriptionOrg(ctx, sub)
+		if err != nil || org == nil {
+			return err
+		}
+		status := sub.Status
+		if ev.Type == "customer.subscription.deleted" {
+			status = "canceled"
+		}
+		return s.apply(ctx, ev, org, at, func(org *repository.Organization) {
+			org.BillingCustomerID = sub.Customer
+			org.BillingSubscriptionID = sub.ID
+			if len(sub.Items.Data) > 0 {
+				price := sub.Items.Data[0].Price.ID
+				if plan, ok := s.cfg.Prices[price]; ok {
+					org.Plan = plan
+				} else {
+					log.Printf("[WARN] Stripe price %s of subscription %s maps to no plan; see BILLING_PRICES", price, sub.ID)
+				}
+			}
+			if end := sub.periodEnd(); end != nil {
+				org.PlanPeriodEnd = end
+			}
+			switch status {
+			case "active", "trialing":
+				activatePlan(org)
+			case "past_due", "unpaid":
+				s.startGrace(org)
+			case "canceled", "incomplete_expired", "paused":
+				org.PlanStatus = repository.PlanStatusInactive
+				org.GraceUntil = nil
+			}
+		})
+	case "invoice.paid", "invoice.payment_succeeded", "invoice.payment_failed":
+		var inv stripeInvoice
+		if err := json.Unmarshal(ev.Data.Object, &inv); err != nil {
+			return ErrBillingPayload
+		}
+		org, err := s.repo.FindByBillingCustomer(ctx, inv.Customer)
+		if err != nil {
+			return err
+		}
+		if org == nil {
+			log.Printf("[WARN] Stripe event %s: no organization has customer %s", ev.ID, inv.Customer)
+			return nil
+		}
+		if inv.Subscription != "" && org.BillingSubscriptionID != "" && inv.Subscription != org.BillingSubscriptionID {
+			return nil
+		}
+		return s.apply(ctx, ev, org, at, func(org *repository.Organization) {
+			if ev.Type == "invoice.payment_failed" {
+				s.startGrace(org)
+			} else {
+				activatePlan(org)
+			}
+		})
+	}
+	return nil
+}
+
+func (s *BillingService) subscriptionOrg(ctx context.Context, sub stripeSubscription) (*repository.Organization, error) {
+	if raw := sub.Metadata["org_id"]; raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err == nil {
+			org, err := s.repo.Find(ctx, uint(id))
+			if err != nil || org != nil {
+				return org, err
+			}
+		}
+	}
+	org, err := s.repo.FindByBillingCustomer(ctx, sub.Customer)
+	if err == nil && org == nil {
+		log.Printf("[WARN] Stripe subscription %s names no known organization", sub.ID)
+	}
+	return org, err
+}
+
+// apply changes an organization with an event, unless a newer event was
+// already applied.
+func (s *BillingService) apply(ctx context.Context, ev stripeEvent, org *repository.Organization, at time.Time, change func(*repository.Organization)) error {
+	if org.BillingEventAt != nil && at.Before(*org.BillingEventAt) {
+		log.Printf("[INFO] Stripe event %s is older than the last one applied to organization %d; skipped", ev.ID, org.ID)
+		return nil
+	}
+	fromPlan, from := org.Plan, org.PlanStatus
+	change(org)
+	org.BillingEventAt = &at
+	if err := s.repo.SaveBilling(ctx, org); err != nil {
+		return err
+	}
+	s.publishChange(ctx, org, fromPlan, from)
+	return nil
+}
+
+func activatePlan(org *repository.Organization) {
+	org.PlanStatus = repository.PlanStatusActive
+	org.GraceUntil = nil
+}
+
+// startGrace marks a plan past due. Its grace period starts with the first
+// failure and is not extended by the provider's retries.
+func (s *BillingService) startGrace(org *repository.Organization) {
+	if org.PlanStatus == repository.PlanStatusPastDue && org.GraceUntil != nil {
+		return
+	}
+	if org.PlanStatus == repository.PlanStatusInactive {
+		return
+	}
+	until := time.Now().Add(s.cfg.GracePeriod)
+	org.PlanStatus = repository.PlanStatusPastDue
+	org.GraceUntil = &until
+}
+
+func (s *BillingService) publishChange(ctx context.Context, org *repository.Organization, fromPlan, from string) {
+	if org.Plan == fromPlan && org.PlanStatus == from {
+		return
+	}
+	log.Printf("[INFO] Plan of organization %d: %s %s -> %s %s", org.ID, fromPlan, from, org.Plan, org.PlanStatus)
+	s.bus.Publish(database.WithOrg(ctx, org.ID), events.PlanChanged{
+		OrgID:      org.ID,
+		Plan:       org.Plan,
+		From:       from,
+		To:         org.PlanStatus,
+		GraceUntil: org.GraceUntil,
+	})
+}
+
+// verifyStripeSignature checks a Stripe-Signature header, "t=<unix>,v1=<hex>"
+// with one v1 per active secret, against the payload.
+func verifyStripeSignature(header string, payload []byte, secret string, now time.Time) error {
+	var ts string
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			if sig, err := hex.DecodeString(v); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 || secret == "" {
+		return ErrBillingSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > stripeSignatureTolerance || age < -stripeSignatureTolerance {
+		return ErrBillingSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(payload)
+	want := mac.Sum(nil)
+	for _, sig := range sigs {
+		if hmac.Equal(sig, want) {
+			return nil
+		}
+	}
+	return ErrBillingSignature
+}
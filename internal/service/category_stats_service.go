@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+const (
+	// The public search refuses offsets beyond 1000 results.
+	maxCrawlListings = 1000
+	crawlPageSize    = 50
+	// crawlPageDelay paces requests so a crawl does not eat the shared quota.
+	crawlPageDelay = 500 * time.Millisecond
+	// topSellerSample is how many of the best-selling listings the health
+	// average covers: one multiget call.
+	topSellerSample = 20
+)
+
+// CategoryStatsService crawls categories and serves their distribution stats.
+type CategoryStatsService struct {
+	meliClient *meli.MeliClient
+	statsRepo  *repository.CategoryStatsRepository
+}
+
+func NewCategoryStatsService(meliClient *meli.MeliClient, statsRepo *repository.CategoryStatsRepository) *CategoryStatsService {
+	return &CategoryStatsService{
+		meliClient: meliClient,
+		statsRepo:  statsRepo,
+	}
+}
+
+// CrawlListings pages through the site search for a category, up to the
+// 1000 listings it exposes. It returns them with the category's total.
+func (s *CategoryStatsService) CrawlListings(ctx context.Context, categoryID string) ([]meli.CategorySearchResult, int, error) {
+	return s.sampleListings(ctx, categoryID, maxCrawlListings)
+}
+
+// CrawlCategory crawls a category, computes price/sold percentiles and the
+// free-shipping share, and stores the result.
+func (s *CategoryStatsService) CrawlCategory(ctx context.Context, categoryID string) (*repository.CategoryStats, error) {
+	listings, total, err := s.CrawlListings(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		prices       []float64
+		sold         []float64
+		soldTotal    int
+		freeShipping int
+	)
+	for _, r := range listings {
+		if r.Price > 0 {
+			prices = append(prices, r.Price)
+		}
+		sold = append(sold, float64(r.SoldQuantity))
+		soldTotal += r.SoldQuantity
+		if r.Shipping.FreeShipping {
+			freeShipping++
+		}
+	}
+
+	sampled := len(sold)
+	log.Printf("[INFO] Crawled category %s: %d of %d listings", categoryID, sampled, total)
+
+	sort.Float64s(prices)
+	sort.Float64s(sold)
+
+	stats := &repository.CategoryStats{
+		CategoryID:      categoryID,
+		TotalListings:   total,
+		SampledListings: sampled,
+		PriceMin:        percentile(prices, 0),
+		PriceP25:        percentile(prices, 25),
+		PriceP50:        percentile(prices, 50),
+		PriceP75:        percentile(prices, 75),
+		PriceMax:        percentile(prices, 100),
+		SoldTotal:       soldTotal,
+		SoldP50:         percentile(sold, 50),
+		CrawledAt:       time.Now(),
+	}
+	if sampled > 0 {
+		stats.FreeShippingShare = float64(freeShipping) / float64(sampled)
+	}
+	stats.TopSellerHealth = s.topSellerHealth(ctx, listings)
+
+	if err := s.statsRepo.Save(ctx, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// topSellerHealth averages the health score of the best-selling listings,
+// a proxy for how professional the competition is. It is nil when none of
+// them has been rated or their details could not be fetched.
+func (s *CategoryStatsService) topSellerHealth(ctx context.Context, listings []meli.CategorySearchResult) *float64 {
+	sort.SliceStable(listings, func(i, j int) bool {
+		return listings[i].SoldQuantity > listings[j].SoldQuantity
+	})
+	ids := make([]string, 0, topSellerSample)
+	for _, l := range listings[:min(topSellerSample, len(listings))] {
+		ids = append(ids, l.ID)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	items, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil {
+		log.Printf("[WARN] Failed to fetch the top sellers of the crawl: %v", err)
+		return nil
+	}
+	var sum float64
+	var rated int
+	for _, it := range items {
+		if it.Health != nil {
+			sum += *it.Health
+			rated++
+		}
+	}
+	if rated == 0 {
+		return nil
+	}
+	avg := sum / float64(rated)
+	return &avg
+}
+
+// LatestStats returns the most recent crawl stats, or nil if none exist.
+func (s *CategoryStatsService) LatestStats(ctx context.Context, categoryID string) (*repository.CategoryStats, error) {
+	return s.statsRepo.Latest(ctx, categoryID)
+}
+
+// percentile returns the p-th percentile of sorted values using linear
+// interpolation between closest ranks.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo == hi {
+		return sorted[lo]
+	}
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+// sampleListings pages through the category's search results, up to limit,
+// pausing between pages. It returns them with the category's total.
+func (s *CategoryStatsService) sampleListings(ctx context.Context, categoryID string, limit int) ([]meli.CategorySearchResult, int, error) {
+	var (
+		total    int
+		listings []meli.CategorySearchResult
+	)
+	for offset := 0; offset < limit; offset += crawlPageSize {
+		if offset > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, 0, ctx.Err()
+			case <-time.After(crawlPageDelay):
+			}
+		}
+
+		page, err := s.meliClient.SearchCategoryPage(ctx, categoryID, offset, crawlPageSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		total = page.Paging.Total
+		listings = append(listings, page.Results...)
+
+		if len(page.Results) < crawlPageSize || offset+crawlPageSize >= total {
+			break
+		}
+	}
+	return listings, total, nil
+}
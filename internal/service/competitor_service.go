@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"melibot/internal/events"
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// ErrNotCompetitor is returned for operations on a seller that is not watched.
+var ErrNotCompetitor = errors.New("seller is not a watched competitor")
+
+// CompetitorService snapshots the listings of watched competitors and
+// records what changed between snapshots. Price drops and new listings are
+// published as events, which the notifier and outbound webhooks pick up.
+type CompetitorService struct {
+	meliClient *meli.MeliClient
+	repo       *repository.CompetitorRepository
+	bus        *events.Bus
+}
+
+func NewCompetitorService(meliClient *meli.MeliClient, repo *repository.CompetitorRepository, bus *events.Bus) *CompetitorService {
+	return &CompetitorService{
+		meliClient: meliClient,
+		repo:       repo,
+		bus:        bus,
+	}
+}
+
+// List returns the watched competitors.
+func (s *CompetitorService) List(ctx context.Context) ([]repository.Competitor, error) {
+	return s.repo.List(ctx)
+}
+
+// Watch starts tracking a seller and takes the first snapshot of their
+// listings, the baseline later changes are measured against. Watching a
+// seller twice is a no-op.
+func (s *CompetitorService) Watch(ctx context.Context, sellerID int64) (*repository.Competitor, error) {
+	c, err := s.repo.Find(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		return c, nil
+	}
+
+	user, err := s.meliClient.GetUser(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	c = &repository.Competitor{SellerID: sellerID, Nickname: user.Nickname}
+	if _, err := s.snapshot(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Unwatch stops tracking a seller. Their change feed is kept.
+func (s *CompetitorService) Unwatch(ctx context.Context, sellerID int64) error {
+	deleted, err := s.repo.Delete(ctx, sellerID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotCompetitor
+	}
+	return nil
+}
+
+// Changes returns a competitor's recent changes, newest first, optionally
+// of one kind.
+func (s *CompetitorService) Changes(ctx context.Context, sellerID int64, kind string, since time.Time, limit int) ([]repository.CompetitorChange, error) {
+	c, err := s.repo.Find(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrNotCompetitor
+	}
+	return s.repo.Changes(ctx, sellerID, kind, since, limit)
+}
+
+// RefreshAll snapshots every watched competitor. Competitors that fail are
+// logged and skipped.
+func (s *CompetitorService) RefreshAll(ctx context.Context) error {
+	competitors, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i := range competitors {
+		c := &competitors[i]
+		changes, err := s.snapshot(ctx, c)
+		if err != nil {
+			log.Printf("[WARN] Competitor snapshot failed for seller %d: %v", c.SellerID, err)
+			failed++
+			continue
+		}
+		if len(changes) > 0 {
+			log.Printf("[INFO] Competitor %s (%d): %d changes", c.Nickname, c.SellerID, len(changes))
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("competitor refresh: %d of %d sellers failed", failed, len(competitors))
+	}
+	return nil
+}
+
+// snapshot fetches the competitor's listings, stores their new state and
+// the changes against the previous snapshot, and publishes the alerts. The
+// first snapshot only sets the baseline.
+func (s *CompetitorService) snapshot(ctx context.Context, c *repository.Competitor) ([]repository.CompetitorChange, error) {
+	current, err := s.meliClient.GetSellerItems(ctx, c.SellerID)
+	if err != nil {
+		return nil, err
+	}
+	known, err := s.repo.Listings(ctx, c.SellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	baseline := c.LastSnapshotAt == nil
+	// Past the deepest search page, missing listings may just not be listed
+	complete := len(current.Items) >= current.Total
+	listings, changes := diffListings(c.SellerID, known, current.Items, complete, now)
+	if baseline {
+		changes = nil
+	}
+	c.Listings = len(current.Items)
+	c.LastSnapshotAt = &now
+	if err := s.repo.SaveSnapshot(ctx, c, listings, changes); err != nil {
+		return nil, err
+	}
+
+	for _, ch := range changes {
+		switch ch.Kind {
+		case repository.ChangeKindPriceDrop:
+			s.bus.Publish(ctx, events.CompetitorPriceDropped{SellerID: c.SellerID, Nickname: c.Nickname, ItemID: ch.ItemID, Title: ch.Title, OldPrice: ch.OldValue, NewPrice: ch.NewValue})
+		case repository.ChangeKindNewListing:
+			s.bus.Publish(ctx, events.CompetitorListingLaunched{SellerID: c.SellerID, Nickname: c.Nickname, ItemID: ch.ItemID, Title: ch.Title, Price: ch.NewValue})
+		}
+	}
+	return changes, nil
+}
+
+// diffListings compares the stored listings of a seller with the ones the
+// search returned now. It returns the listings to store, known ones updated
+// in place, and the changes found. When current is complete, listings
+// missing from it are marked inactive; when they come back they count as
+// new again.
+func diffListings(sellerID int64, known []repository.CompetitorListing, current []meli.CategorySearchResult, complete bool, now time.Time) ([]repository.CompetitorListing, []repository.CompetitorChange) {
+	byID := make(map[string]*repository.CompetitorListing, len(known))
+	for i := range known {
+		byID[known[i].ItemID] = &known[i]
+	}
+
+	var changes []repository.CompetitorChange
+	change := func(l *repository.CompetitorListing, kind string, oldValue, newValue float64) {
+		changes = append(changes, repository.CompetitorChange{
+			SellerID: sellerID,
+			ItemID:   l.ItemID,
+			Title:    l.Title,
+			Kind:     kind,
+			OldValue: oldValue,
+			NewValue: newValue,
+		})
+	}
+
+	var added []repository.CompetitorListing
+	seen := make(map[string]bool, len(current))
+	for _, it := range current {
+		if seen[it.ID] {
+			continue
+		}
+		seen[it.ID] = true
+
+		l, ok := byID[it.ID]
+		if !ok {
+			added = append(added, repository.CompetitorListing{
+				SellerID:          sellerID,
+				ItemID:            it.ID,
+				Title:             it.Title,
+				CatalogProductID:  it.CatalogProductID,
+				Price:             it.Price,
+				AvailableQuantity: it.AvailableQuantity,
+				SoldQuantity:      it.SoldQuantity,
+				Active:            true,
+				FirstSeenAt:       now,
+				LastSeenAt:        now,
+			})
+			change(&added[len(added)-1], repository.ChangeKindNewListing, 0, it.Price)
+			continue
+		}
+
+		l.Title = it.Title
+		switch {
+		case !l.Active:
+			change(l, repository.ChangeKindNewListing, l.Price, it.Price)
+		case it.Price < l.Price:
+			change(l, repository.ChangeKindPriceDrop, l.Price, it.Price)
+		case it.Price > l.Price:
+			change(l, repository.ChangeKindPriceIncrease, l.Price, it.Price)
+		}
+		if l.Active && it.AvailableQuantity != l.AvailableQuantity {
+			change(l, repository.ChangeKindStock, float64(l.AvailableQuantity), float64(it.AvailableQuantity))
+		}
+		l.CatalogProductID = it.CatalogProductID
+		l.Price = it.Price
+		l.AvailableQuantity = it.AvailableQuantity
+		l.SoldQuantity = it.SoldQuantity
+		l.Active = true
+		l.LastSeenAt = now
+	}
+
+	for i := range known {
+		l := &known[i]
+		if complete && l.Active && !seen[l.ItemID] {
+			change(l, repository.ChangeKindRemoved, l.Price, 0)
+			l.Active = false
+		}
+	}
+	return append(known, added...), changes
+}
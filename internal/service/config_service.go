@@ -0,0 +1,626 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"melibot/database"
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// configVersion is the version of the config document format.
+const configVersion = 1
+
+var (
+	ErrConfigNoOrganization = errors.New("config export and import need an organization key")
+	ErrConfigVersion        = errors.New("unsupported config version")
+	ErrConfigDuplicate      = errors.New("duplicate entry")
+)
+
+// ConfigDocument is the automation config of an organization as exported to
+// YAML: what the seller set up, without IDs, timestamps or state, so it can
+// be kept in git and loaded into another environment.
+type ConfigDocument struct {
+	Version          int                     `json:"version"`
+	Watchlist        []WatchConfig           `json:"watchlist"`
+	ListingRules     []ListingRuleConfig     `json:"listing_rules"`
+	ListingTemplates []ListingTemplateConfig `json:"listing_templates"`
+	AnswerTemplates  []AnswerTemplateConfig  `json:"answer_templates"`
+	ScoringProfiles  []ScoringProfileConfig  `json:"scoring_profiles"`
+}
+
+// WatchConfig is a watched product and its alert thresholds.
+type WatchConfig struct {
+	ProductID string `json:"product_id"`
+	Title     string `json:"title,omitempty"`
+	Permalink string `json:"permalink,omitempty"`
+	Thresholds
+}
+
+// ListingRuleConfig is a listing rule, identified by its listing, trigger
+// and pause time.
+type ListingRuleConfig struct {
+	ItemID   string     `json:"item_id"`
+	Trigger  string     `json:"trigger"`
+	PauseAt  *time.Time `json:"pause_at,omitempty"`
+	ResumeAt *time.Time `json:"resume_at,omitempty"`
+	Enabled  bool       `json:"enabled"`
+}
+
+// ListingTemplateConfig is a listing template, identified by its name.
+type ListingTemplateConfig struct {
+	Name              string                `json:"name"`
+	CategoryID        string                `json:"category_id"`
+	ListingType       string                `json:"listing_type_id,omitempty"`
+	Condition         string                `json:"condition,omitempty"`
+	CurrencyID        string                `json:"currency_id,omitempty"`
+	BuyingMode        string                `json:"buying_mode,omitempty"`
+	Attributes        []meli.Attribute      `json:"attributes,omitempty"`
+	DescriptionBlocks []string              `json:"description_blocks,omitempty"`
+	Shipping          *meli.NewItemShipping `json:"shipping,omitempty"`
+}
+
+// AnswerTemplateConfig is an answer template, identified by its name.
+type AnswerTemplateConfig struct {
+	Name       string `json:"name"`
+	MatchType  string `json:"match_type"`
+	Pattern    string `json:"pattern"`
+	Answer     string `json:"answer"`
+	ItemID     string `json:"item_id,omitempty"`
+	CategoryID string `json:"category_id,omitempty"`
+	Enabled    bool   `json:"enabled"`
+}
+
+// ScoringProfileConfig is a scoring profile, identified by its name.
+type ScoringProfileConfig struct {
+	Name              string   `json:"name"`
+	WeightSold        float64  `json:"weight_sold"`
+	WeightPrice       float64  `json:"weight_price"`
+	WeightCompetition float64  `json:"weight_competition"`
+	WeightHealth      float64  `json:"weight_health"`
+	PriceBandMin      *float64 `json:"price_band_min,omitempty"`
+	PriceBandMax      *float64 `json:"price_band_max,omitempty"`
+}
+
+// ConfigError is an invalid entry of an imported document.
+type ConfigError struct {
+	Section string
+	Index   int
+	Err     error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s[%d]: %v", e.Section, e.Index, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigCounts is what an import changed in one section.
+type ConfigCounts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Deleted int `json:"deleted"`
+}
+
+// ConfigImport is the outcome of an import, by section.
+type ConfigImport struct {
+	Watchlist        ConfigCounts `json:"watchlist"`
+	ListingRules     ConfigCounts `json:"listing_rules"`
+	ListingTemplates ConfigCounts `json:"listing_templates"`
+	AnswerTemplates  ConfigCounts `json:"answer_templates"`
+	ScoringProfiles  ConfigCounts `json:"scoring_profiles"`
+}
+
+// ConfigService exports the automation config as YAML and imports it back,
+// to promote it between environments.
+type ConfigService struct {
+	watchRepo    *repository.WatchlistRepository
+	ruleRepo     *repository.ListingRuleRepository
+	templateRepo *repository.ListingTemplateRepository
+	questionRepo *repository.QuestionRepository
+	profileRepo  *repository.ScoringProfileRepository
+}
+
+func NewConfigService(watchRepo *repository.WatchlistRepository, ruleRepo *repository.ListingRuleRepository, templateRepo *repository.ListingTemplateRepository, questionRepo *repository.QuestionRepository, profileRepo *repository.ScoringProfileRepository) *ConfigService {
+	return &ConfigService{
+		watchRepo:    watchRepo,
+		ruleRepo:     ruleRepo,
+		templateRepo: templateRepo,
+		questionRepo: questionRepo,
+		profileRepo:  profileRepo,
+	}
+}
+
+// checkOrg refuses to work across organizations, which would mix their
+// config in one document.
+func checkOrg(ctx context.Context) error {
+	if _, ok := database.OrgFromContext(ctx); database.MultiTenant() && !ok {
+		return ErrConfigNoOrganization
+	}
+	return nil
+}
+
+// Export returns the automation config.
+func (s *ConfigService) Export(ctx context.Context) (*ConfigDocument, error) {
+	if err := checkOrg(ctx); err != nil {
+		return nil, err
+	}
+	doc := &ConfigDocument{
+		Version:          configVersion,
+		Watchlist:        []WatchConfig{},
+		ListingRules:     []ListingRuleConfig{},
+		ListingTemplates: []ListingTemplateConfig{},
+		AnswerTemplates:  []AnswerTemplateConfig{},
+		ScoringProfiles:  []ScoringProfileConfig{},
+	}
+
+	watches, err := s.watchRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range watches {
+		doc.Watchlist = append(doc.Watchlist, WatchConfig{
+			ProductID:  p.ProductID,
+			Title:      p.Title,
+			Permalink:  p.Permalink,
+			Thresholds: Thresholds{AlertBelow: p.AlertBelow, AlertAbove: p.AlertAbove, ChangePct: p.ChangePct},
+		})
+	}
+
+	rules, err := s.ruleRepo.Rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		doc.ListingRules = append(doc.ListingRules, ListingRuleConfig{
+			ItemID: r.ItemID, Trigger: r.Trigger, PauseAt: r.PauseAt, ResumeAt: r.ResumeAt, Enabled: r.Enabled,
+		})
+	}
+
+	rows, err := s.templateRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		t, err := listingTemplateFromRow(&rows[i])
+		if err != nil {
+			return nil, err
+		}
+		doc.ListingTemplates = append(doc.ListingTemplates, ListingTemplateConfig{
+			Name:              t.Name,
+			CategoryID:        t.CategoryID,
+			ListingType:       t.ListingType,
+			Condition:         t.Condition,
+			CurrencyID:        t.CurrencyID,
+			BuyingMode:        t.BuyingMode,
+			Attributes:        t.Attributes,
+			DescriptionBlocks: t.DescriptionBlocks,
+			Shipping:          t.Shipping,
+		})
+	}
+
+	answers, err := s.questionRepo.Templates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range answers {
+		doc.AnswerTemplates = append(doc.AnswerTemplates, AnswerTemplateConfig{
+			Name: t.Name, MatchType: t.MatchType, Pattern: t.Pattern, Answer: t.Answer,
+			ItemID: t.ItemID, CategoryID: t.CategoryID, Enabled: t.Enabled,
+		})
+	}
+
+	profiles, err := s.profileRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		doc.ScoringProfiles = append(doc.ScoringProfiles, ScoringProfileConfig{
+			Name:              p.Name,
+			WeightSold:        p.WeightSold,
+			WeightPrice:       p.WeightPrice,
+			WeightCompetition: p.WeightCompetition,
+			WeightHealth:      p.WeightHealth,
+			PriceBandMin:      p.PriceBandMin,
+			PriceBandMax:      p.PriceBandMax,
+		})
+	}
+	return doc, nil
+}
+
+// MarshalConfig encodes a document as YAML. It goes through JSON so the
+// YAML keys are the JSON field names, in a stable order that diffs well.
+func MarshalConfig(doc *ConfigDocument) ([]byte, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(generic)
+}
+
+// UnmarshalConfig decodes a YAML document written by MarshalConfig.
+func UnmarshalConfig(data []byte) (*ConfigDocument, error) {
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(generic)
+	if err != nil {
+		return nil, err
+	}
+	var doc ConfigDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Validate checks a whole document before anything is imported, so an
+// invalid entry never leaves the config half imported.
+func (doc *ConfigDocument) Validate() error {
+	if doc.Version != configVersion {
+		return ErrConfigVersion
+	}
+	seen := map[string]bool{}
+	unique := func(section string, i int, key string) error {
+		key = section + "\x00" + key
+		if seen[key] {
+			return &ConfigError{Section: section, Index: i, Err: ErrConfigDuplicate}
+		}
+		seen[key] = true
+		return nil
+	}
+
+	for i, w := range doc.Watchlist {
+		if strings.TrimSpace(w.ProductID) == "" {
+			return &ConfigError{Section: "watchlist", Index: i, Err: errors.New("product_id is required")}
+		}
+		if err := w.Validate(); err != nil {
+			return &ConfigError{Section: "watchlist", Index: i, Err: err}
+		}
+		if err := unique("watchlist", i, w.ProductID); err != nil {
+			return err
+		}
+	}
+	for i, r := range doc.ListingRules {
+		rule := r.rule()
+		if err := ValidateListingRule(rule); err != nil {
+			return &ConfigError{Section: "listing_rules", Index: i, Err: err}
+		}
+		if err := unique("listing_rules", i, ruleKey(rule)); err != nil {
+			return err
+		}
+	}
+	for i, t := range doc.ListingTemplates {
+		if err := ValidateListingTemplate(t.template()); err != nil {
+			return &ConfigError{Section: "listing_templates", Index: i, Err: err}
+		}
+		if err := unique("listing_templates", i, strings.TrimSpace(t.Name)); err != nil {
+			return err
+		}
+	}
+	for i, t := range doc.AnswerTemplates {
+		if err := ValidateTemplate(t.template()); err != nil {
+			return &ConfigError{Section: "answer_templates", Index: i, Err: err}
+		}
+		if err := unique("answer_templates", i, t.Name); err != nil {
+			return err
+		}
+	}
+	for i, p := range doc.ScoringProfiles {
+		if strings.TrimSpace(p.Name) == "" {
+			return &ConfigError{Section: "scoring_profiles", Index: i, Err: errors.New("name is required")}
+		}
+		if err := ValidateProfile(p.profile()); err != nil {
+			return &ConfigError{Section: "scoring_profiles", Index: i, Err: err}
+		}
+		if err := unique("scoring_profiles", i, p.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r ListingRuleConfig) rule() *repository.ListingRule {
+	return &repository.ListingRule{ItemID: r.ItemID, Trigger: r.Trigger, PauseAt: r.PauseAt, ResumeAt: r.ResumeAt, Enabled: r.Enabled}
+}
+
+// ruleKey identifies a rule across environments, where IDs differ.
+func ruleKey(r *repository.ListingRule) string {
+	key := r.ItemID + "\x00" + r.Trigger
+	if r.PauseAt != nil {
+		key += "\x00" + r.PauseAt.UTC().Format(time.RFC3339)
+	}
+	return key
+}
+
+func (t ListingTemplateConfig) template() *ListingTemplate {
+	return &ListingTemplate{
+		Name:              t.Name,
+		CategoryID:        t.CategoryID,
+		ListingType:       t.ListingType,
+		Condition:         t.Condition,
+		CurrencyID:        t.CurrencyID,
+		BuyingMode:        t.BuyingMode,
+		Attributes:        t.Attributes,
+		DescriptionBlocks: t.DescriptionBlocks,
+		Shipping:          t.Shipping,
+	}
+}
+
+func (t AnswerTemplateConfig) template() *repository.AnswerTemplate {
+	return &repository.AnswerTemplate{
+		Name: t.Name, MatchType: t.MatchType, Pattern: t.Pattern, Answer: t.Answer,
+		ItemID: t.ItemID, CategoryID: t.CategoryID, Enabled: t.Enabled,
+	}
+}
+
+func (p ScoringProfileConfig) profile() repository.ScoringProfile {
+	return repository.ScoringProfile{
+		Name:              p.Name,
+		WeightSold:        p.WeightSold,
+		WeightPrice:       p.WeightPrice,
+		WeightCompetition: p.WeightCompetition,
+		WeightHealth:      p.WeightHealth,
+		PriceBandMin:      p.PriceBandMin,
+		PriceBandMax:      p.PriceBandMax,
+	}
+}
+
+// Import creates or updates the entries of a document, matching them to
+// the stored ones by product ID, name, or for listing rules by listing,
+// trigger and pause time. With prune, stored entries missing from the
+// document are deleted too; watches, rules and templates go to the trash.
+func (s *ConfigService) Import(ctx context.Context, doc *ConfigDocument, prune bool) (*ConfigImport, error) {
+	if err := checkOrg(ctx); err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	res := &ConfigImport{}
+	steps := []func(context.Context, *ConfigDocument, bool, *ConfigImport) error{
+		s.importWatchlist, s.importListingRules, s.importListingTemplates, s.importAnswerTemplates, s.importScoringProfiles,
+	}
+	for _, step := range steps {
+		if err := step(ctx, doc, prune, res); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func (s *ConfigService) importWatchlist(ctx context.Context, doc *ConfigDocument, prune bool, res *ConfigImport) error {
+	stored, err := s.watchRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	byProduct := make(map[string]*repository.WatchedProduct, len(stored))
+	for i := range stored {
+		byProduct[stored[i].ProductID] = &stored[i]
+	}
+
+	for _, w := range doc.Watchlist {
+		p := byProduct[w.ProductID]
+		delete(byProduct, w.ProductID)
+		if p == nil {
+			// The next price refresh records the price alerts start from
+			p = &repository.WatchedProduct{ProductID: w.ProductID, Title: w.Title, Permalink: w.Permalink}
+			applyThresholds(p, w.Thresholds)
+			if err := s.watchRepo.Create(ctx, p); err != nil {
+				return err
+			}
+			res.Watchlist.Created++
+			continue
+		}
+		applyThresholds(p, w.Thresholds)
+		updated, err := s.watchRepo.Update(ctx, p)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrVersionConflict
+		}
+		res.Watchlist.Updated++
+	}
+
+	if !prune {
+		return nil
+	}
+	for productID := range byProduct {
+		if _, err := s.watchRepo.Delete(ctx, productID); err != nil {
+			return err
+		}
+		res.Watchlist.Deleted++
+	}
+	return nil
+}
+
+func (s *ConfigService) importListingRules(ctx context.Context, doc *ConfigDocument, prune bool, res *ConfigImport) error {
+	stored, err := s.ruleRepo.Rules(ctx)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]*repository.ListingRule, len(stored))
+	for i := range stored {
+		byKey[ruleKey(&stored[i])] = &stored[i]
+	}
+
+	for _, r := range doc.ListingRules {
+		rule := r.rule()
+		key := ruleKey(rule)
+		existing := byKey[key]
+		delete(byKey, key)
+		if existing == nil {
+			if err := s.ruleRepo.SaveRule(ctx, rule); err != nil {
+				return err
+			}
+			res.ListingRules.Created++
+			continue
+		}
+		// A replaced rule keeps holding the listing it paused
+		rule.ID, rule.CreatedAt, rule.Holding, rule.Version = existing.ID, existing.CreatedAt, existing.Holding, existing.Version
+		updated, err := s.ruleRepo.UpdateRule(ctx, rule)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrVersionConflict
+		}
+		res.ListingRules.Updated++
+	}
+
+	if !prune {
+		return nil
+	}
+	for _, rule := range byKey {
+		if _, err := s.ruleRepo.DeleteRule(ctx, rule.ID); err != nil {
+			return err
+		}
+		res.ListingRules.Deleted++
+	}
+	return nil
+}
+
+func (s *ConfigService) importListingTemplates(ctx context.Context, doc *ConfigDocument, prune bool, res *ConfigImport) error {
+	stored, err := s.templateRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*repository.ListingTemplate, len(stored))
+	for i := range stored {
+		byName[stored[i].Name] = &stored[i]
+	}
+
+	for _, t := range doc.ListingTemplates {
+		row, err := listingTemplateRow(t.template())
+		if err != nil {
+			return err
+		}
+		existing := byName[row.Name]
+		delete(byName, row.Name)
+		if existing == nil {
+			if err := s.templateRepo.Save(ctx, row); err != nil {
+				return err
+			}
+			res.ListingTemplates.Created++
+			continue
+		}
+		row.ID, row.CreatedAt, row.Version = existing.ID, existing.CreatedAt, existing.Version
+		updated, err := s.templateRepo.Update(ctx, row)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrVersionConflict
+		}
+		res.ListingTemplates.Updated++
+	}
+
+	if !prune {
+		return nil
+	}
+	for _, row := range byName {
+		if _, err := s.templateRepo.Delete(ctx, row.ID); err != nil {
+			return err
+		}
+		res.ListingTemplates.Deleted++
+	}
+	return nil
+}
+
+func (s *ConfigService) importAnswerTemplates(ctx context.Context, doc *ConfigDocument, prune bool, res *ConfigImport) error {
+	stored, err := s.questionRepo.Templates(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*repository.AnswerTemplate, len(stored))
+	for i := range stored {
+		byName[stored[i].Name] = &stored[i]
+	}
+
+	for _, a := range doc.AnswerTemplates {
+		t := a.template()
+		existing := byName[t.Name]
+		delete(byName, t.Name)
+		if existing == nil {
+			if err := s.questionRepo.SaveTemplate(ctx, t); err != nil {
+				return err
+			}
+			res.AnswerTemplates.Created++
+			continue
+		}
+		t.ID, t.CreatedAt, t.Version = existing.ID, existing.CreatedAt, existing.Version
+		updated, err := s.questionRepo.UpdateTemplate(ctx, t)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrVersionConflict
+		}
+		res.AnswerTemplates.Updated++
+	}
+
+	if !prune {
+		return nil
+	}
+	for _, t := range byName {
+		if _, err := s.questionRepo.DeleteTemplate(ctx, t.ID); err != nil {
+			return err
+		}
+		res.AnswerTemplates.Deleted++
+	}
+	return nil
+}
+
+func (s *ConfigService) importScoringProfiles(ctx context.Context, doc *ConfigDocument, prune bool, res *ConfigImport) error {
+	stored, err := s.profileRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*repository.ScoringProfile, len(stored))
+	for i := range stored {
+		byName[stored[i].Name] = &stored[i]
+	}
+
+	for _, cfg := range doc.ScoringProfiles {
+		p := cfg.profile()
+		existing := byName[p.Name]
+		delete(byName, p.Name)
+		if existing != nil {
+			p.ID, p.CreatedAt = existing.ID, existing.CreatedAt
+		}
+		if err := s.profileRepo.Save(ctx, &p); err != nil {
+			return err
+		}
+		if existing == nil {
+			res.ScoringProfiles.Created++
+		} else {
+			res.ScoringProfiles.Updated++
+		}
+	}
+
+	if !prune {
+		return nil
+	}
+	for name := range byName {
+		if _, err := s.profileRepo.Delete(ctx, name); err != nil {
+			return err
+		}
+		res.ScoringProfiles.Deleted++
+	}
+	return nil
+}
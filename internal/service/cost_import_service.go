@@ -0,0 +1,435 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"melibot/internal/events"
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+const (
+	// MaxCostRows caps the rows of a supplier price list.
+	MaxCostRows = 5000
+	// maxEmailSize bounds the inbound emails read, attachments included.
+	maxEmailSize = 20 << 20
+	// maxMIMEDepth bounds how deep nested multiparts are followed.
+	maxMIMEDepth = 5
+)
+
+var (
+	ErrNoCostAttachments = errors.New("the email has no CSV attachment")
+	ErrSenderNotAllowed  = errors.New("the sender is not an allowed supplier")
+)
+
+// costColumns maps accepted header names to columns.
+var costColumns = map[string]string{
+	"sku": "sku", "codigo": "sku", "código": "sku", "referencia": "sku", "referência": "sku", "ref": "sku",
+	"cost": "cost", "unit_cost": "cost", "custo": "cost", "preco": "cost", "preço": "cost", "price": "cost", "valor": "cost",
+	"currency": "currency", "moeda": "currency",
+}
+
+// csvContentTypes are the attachment types read as CSV, besides any file
+// named *.csv.
+var csvContentTypes = []string{"text/csv", "application/csv", "text/comma-separated-values", "application/vnd.ms-excel"}
+
+// CostRow is one SKU of a supplier price list. Line is the row's position
+// in its file.
+type CostRow struct {
+	Line     int     `json:"line"`
+	SKU      string  `json:"sku"`
+	UnitCost float64 `json:"unit_cost"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// ParseCostCSV reads a supplier price list. The header row is required and
+// names a sku column and a cost (custo, preço) column, optionally a
+// currency one. Semicolon-separated files and Brazilian decimals
+// ("1.234,56") are accepted; rows without a SKU are skipped.
+func ParseCostCSV(r io.Reader) ([]CostRow, error) {
+	records, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{}
+	for i, name := range records[0] {
+		if field, ok := costColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			cols[field] = i
+		}
+	}
+	_, hasSKU := cols["sku"]
+	_, hasCost := cols["cost"]
+	if !hasSKU || !hasCost {
+		return nil, errors.New("the CSV needs a header with a sku column and a cost column")
+	}
+
+	var rows []CostRow
+	for i, rec := range records[1:] {
+		line := i + 2
+		field := func(name string) string {
+			if idx, ok := cols[name]; ok && idx < len(rec) {
+				return strings.TrimSpace(rec[idx])
+			}
+			return ""
+		}
+		row := CostRow{Line: line, SKU: field("sku"), Currency: strings.ToUpper(field("currency"))}
+		if row.SKU == "" {
+			continue
+		}
+		v := strings.TrimSpace(strings.TrimPrefix(field("cost"), "R$"))
+		cost, err := parseDecimal(v)
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("line %d: invalid cost %q", line, field("cost"))
+		}
+		row.UnitCost = cost
+		rows = append(rows, row)
+		if len(rows) > MaxCostRows {
+			return nil, fmt.Errorf("the CSV has more than %d rows", MaxCostRows)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("the CSV has no rows")
+	}
+	return rows, nil
+}
+
+// CostAttachment is a CSV attached to an inbound email.
+type CostAttachment struct {
+	Name string
+	Data []byte
+}
+
+// CostEmail is an inbound supplier email: its sender address, subject and
+// CSV attachments.
+type CostEmail struct {
+	From        string
+	Subject     string
+	Attachments []CostAttachment
+}
+
+// ParseCostEmail reads a raw MIME message, as forwarded by an inbound email
+// service, and collects its CSV attachments.
+func ParseCostEmail(r io.Reader) (*CostEmail, error) {
+	msg, err := mail.ReadMessage(io.LimitReader(r, maxEmailSize))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email: %w", err)
+	}
+	email := &CostEmail{}
+	if from, err := mail.ParseAddress(msg.Header.Get("From")); err == nil {
+		email.From = from.Address
+	}
+	dec := new(mime.WordDecoder)
+	if subject, err := dec.DecodeHeader(msg.Header.Get("Subject")); err == nil {
+		email.Subject = subject
+	}
+	part := mimePart{
+		contentType: msg.Header.Get("Content-Type"),
+		disposition: msg.Header.Get("Content-Disposition"),
+		encoding:    msg.Header.Get("Content-Transfer-Encoding"),
+		body:        msg.Body,
+	}
+	if err := email.collect(part, 0); err != nil {
+		return nil, err
+	}
+	return email, nil
+}
+
+// mimePart is the part of a message collect walks through.
+type mimePart struct {
+	contentType, disposition, encoding string
+	body                               io.Reader
+}
+
+// collect adds the part to the attachments when it is a CSV file, or
+// walks its subparts when it is a multipart.
+func (e *CostEmail) collect(p mimePart, depth int) error {
+	mediaType, params, _ := mime.ParseMediaType(p.contentType)
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth {
+			return nil
+		}
+		mr := multipart.NewReader(p.body, params["boundary"])
+		for {
+			sub, err := mr.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid email: %w", err)
+			}
+			err = e.collect(mimePart{
+				contentType: sub.Header.Get("Content-Type"),
+				disposition: sub.Header.Get("Content-Disposition"),
+				encoding:    sub.Header.Get("Content-Transfer-Encoding"),
+				body:        sub,
+			}, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	name := params["name"]
+	if _, dparams, err := mime.ParseMediaType(p.disposition); err == nil && dparams["filename"] != "" {
+		name = dparams["filename"]
+	}
+	isCSV := strings.EqualFold(path.Ext(name), ".csv") ||
+		(name != "" && slices.Contains(csvContentTypes, mediaType))
+	if !isCSV {
+		return nil
+	}
+
+	var body io.Reader = p.body
+	switch strings.ToLower(strings.TrimSpace(p.encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, p.body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(p.body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("invalid attachment %s: %w", name, err)
+	}
+	e.Attachments = append(e.Attachments, CostAttachment{Name: name, Data: data})
+	return nil
+}
+
+// CostChange is a SKU whose unit cost the import changed. Previous is nil
+// for SKUs without a recorded cost.
+type CostChange struct {
+	SKU       string   `json:"sku"`
+	Previous  *float64 `json:"previous"`
+	UnitCost  float64  `json:"unit_cost"`
+	ChangePct *float64 `json:"change_pct,omitempty"`
+}
+
+// ListingMargin is what one of my listings of a changed SKU makes per sale
+// at its current price, after Mercado Livre's fees and the new cost.
+type ListingMargin struct {
+	ItemID    string  `json:"item_id"`
+	SKU       string  `json:"sku"`
+	Title     string  `json:"title"`
+	Price     float64 `json:"price"`
+	Fees      float64 `json:"fees"`
+	UnitCost  float64 `json:"unit_cost"`
+	Margin    float64 `json:"margin"`
+	MarginPct float64 `json:"margin_pct"`
+}
+
+// CostImportReport summarizes an import: the SKUs whose cost changed and
+// the margins of the listings that sell them. LossMaking lists the
+// listings that now sell below cost.
+type CostImportReport struct {
+	Source     string          `json:"source"`
+	Rows       int             `json:"rows"`
+	Updated    int             `json:"updated"`
+	Unchanged  int             `json:"unchanged"`
+	Changes    []CostChange    `json:"changes"`
+	Listings   []ListingMargin `json:"listings"`
+	LossMaking []string        `json:"loss_making"`
+}
+
+// CostImportService records supplier price lists as the unit costs of the
+// seller's SKUs and recomputes the margin of the listings they change.
+type CostImportService struct {
+	meliClient *meli.MeliClient
+	costRepo   *repository.ProductCostRepository
+	skuRepo    *repository.SKUMappingRepository
+	bus        *events.Bus
+	// senders are the addresses price emails are accepted from; any when
+	// empty.
+	senders []string
+}
+
+func NewCostImportService(meliClient *meli.MeliClient, costRepo *repository.ProductCostRepository, skuRepo *repository.SKUMappingRepository, bus *events.Bus, senders []string) *CostImportService {
+	return &CostImportService{
+		meliClient: meliClient,
+		costRepo:   costRepo,
+		skuRepo:    skuRepo,
+		bus:        bus,
+		senders:    senders,
+	}
+}
+
+// ImportEmail imports the CSV attachments of a supplier email. Emails from
+// senders outside the allowed list are rejected.
+func (s *CostImportService) ImportEmail(ctx context.Context, email *CostEmail) (*CostImportReport, error) {
+	if len(s.senders) > 0 && !slices.ContainsFunc(s.senders, func(a string) bool { return strings.EqualFold(a, email.From) }) {
+		return nil, ErrSenderNotAllowed
+	}
+	if len(email.Attachments) == 0 {
+		return nil, ErrNoCostAttachments
+	}
+	var rows []CostRow
+	for _, a := range email.Attachments {
+		parsed, err := ParseCostCSV(bytes.NewReader(a.Data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.Name, err)
+		}
+		rows = append(rows, parsed...)
+	}
+	source := "email from " + email.From
+	if email.Subject != "" {
+		source += ": " + email.Subject
+	}
+	return s.Import(ctx, source, rows)
+}
+
+// Import records the unit cost of each row, later rows of a SKU winning,
+// recomputes the margin of my listings of the SKUs whose cost changed and
+// publishes CostsUpdated. Margins are best-effort: listings whose fees
+// cannot be read are left out.
+func (s *CostImportService) Import(ctx context.Context, source string, rows []CostRow) (*CostImportReport, error) {
+	existing, err := s.costRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[string]repository.ProductCost, len(existing))
+	for _, c := range existing {
+		previous[c.SKU] = c
+	}
+	latest := map[string]CostRow{}
+	var skus []string
+	for _, row := range rows {
+		if _, ok := latest[row.SKU]; !ok {
+			skus = append(skus, row.SKU)
+		}
+		latest[row.SKU] = row
+	}
+
+	report := &CostImportReport{
+		Source:     source,
+		Rows:       len(rows),
+		Changes:    []CostChange{},
+		Listings:   []ListingMargin{},
+		LossMaking: []string{},
+	}
+	changed := map[string]float64{}
+	for _, sku := range skus {
+		row := latest[sku]
+		cost := roundCents(row.UnitCost)
+		old, known := previous[sku]
+		currency := row.Currency
+		if currency == "" && known {
+			currency = old.Currency
+		}
+		if known && old.UnitCost == cost && old.Currency == currency {
+			report.Unchanged++
+			continue
+		}
+		if err := s.costRepo.Upsert(ctx, &repository.ProductCost{SKU: sku, UnitCost: cost, Currency: currency}); err != nil {
+			return nil, err
+		}
+		change := CostChange{SKU: sku, UnitCost: cost}
+		if known {
+			change.Previous = &old.UnitCost
+			if old.UnitCost > 0 {
+				pct := roundCents((cost - old.UnitCost) / old.UnitCost * 100)
+				change.ChangePct = &pct
+			}
+		}
+		report.Changes = append(report.Changes, change)
+		changed[sku] = cost
+		report.Updated++
+	}
+	if len(changed) == 0 {
+		return report, nil
+	}
+
+	if err := s.margins(ctx, report, changed); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Supplier costs from %s: %d SKUs changed, %d listings now sell below cost", source, report.Updated, len(report.LossMaking))
+	updated := make([]string, 0, len(report.Changes))
+	for _, c := range report.Changes {
+		updated = append(updated, c.SKU)
+	}
+	s.bus.Publish(ctx, events.CostsUpdated{Source: source, SKUs: updated, LossMaking: report.LossMaking})
+	return report, nil
+}
+
+// margins fills in the margin of my active listings of the changed SKUs:
+// those mapped to them, or else carrying them as their seller SKU.
+func (s *CostImportService) margins(ctx context.Context, report *CostImportReport, costs map[string]float64) error {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return err
+	}
+	mappings, err := s.skuRepo.List(ctx, "")
+	if err != nil {
+		return err
+	}
+	mapped := map[string]string{}
+	for _, m := range mappings {
+		if _, ok := costs[m.SKU]; ok {
+			mapped[m.ItemID] = m.SKU
+		}
+	}
+	ids, err := s.meliClient.UserItemIDs(ctx, me.ID)
+	if err != nil {
+		return err
+	}
+	items, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		it := &items[i]
+		if it.Status != meli.ItemStatusActive || int64(it.SellerID) != me.ID {
+			continue
+		}
+		sku, ok := mapped[it.ID]
+		if !ok {
+			sku = it.SKU()
+		}
+		cost, ok := costs[sku]
+		if !ok {
+			continue
+		}
+		prices, err := s.meliClient.ListingPrices(ctx, it.CategoryID, it.ListingType, it.Price)
+		if err != nil {
+			log.Printf("[WARN] Fees of %s unavailable for the cost update: %v", it.ID, err)
+			continue
+		}
+		fees := prices.ListingFeeAmount + prices.SaleFeeAmount
+		m := ListingMargin{
+			ItemID:   it.ID,
+			SKU:      sku,
+			Title:    it.Title,
+			Price:    it.Price,
+			Fees:     roundCents(fees),
+			UnitCost: cost,
+			Margin:   roundCents(it.Price - fees - cost),
+		}
+		if it.Price > 0 {
+			m.MarginPct = roundCents(m.Margin / it.Price * 100)
+		}
+		report.Listings = append(report.Listings, m)
+		if m.Margin < 0 {
+			report.LossMaking = append(report.LossMaking, it.ID)
+		}
+	}
+	sort.Slice(report.Listings, func(i, j int) bool {
+		a, b := report.Listings[i], report.Listings[j]
+		if a.MarginPct != b.MarginPct {
+			return a.MarginPct < b.MarginPct
+		}
+		return a.ItemID < b.ItemID
+	})
+	return nil
+}
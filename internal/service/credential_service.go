@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"melibot/database"
+	"melibot/internal/repository"
+	"melibot/internal/secrets"
+)
+
+// Credential kinds.
+const (
+	CredentialKindSMTP                 = "smtp"
+	CredentialKindTelegram             = "telegram"
+	CredentialKindS3                   = "s3"
+	CredentialKindGoogleServiceAccount = "google_service_account"
+	CredentialKindGeneric              = "generic"
+)
+
+var (
+	ErrCredentialNotFound       = errors.New("credential not found")
+	ErrCredentialName           = errors.New("credential name must be 1-128 letters, digits, dots, dashes or underscores")
+	ErrCredentialKind           = errors.New("kind must be smtp, telegram, s3, google_service_account or generic")
+	ErrCredentialValue          = errors.New("value is required")
+	ErrCredentialServiceAccount = errors.New("a google_service_account value must be the JSON key of the service account")
+	ErrCredentialS3             = errors.New("an s3 value must be a JSON object with access_key_id and secret_access_key")
+	ErrCredentialWrongKey       = errors.New("credential was sealed under another master key; save it again")
+)
+
+var credentialName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// Credential is a stored credential as shown to operators: its value is
+// masked.
+type Credential struct {
+	Name        string    `json:"name"`
+	Kind        string    `json:"kind"`
+	Description string    `json:"description"`
+	Masked      string    `json:"masked_value"`
+	KeyID       string    `json:"key_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CredentialService keeps the credentials of third-party integrations
+// encrypted in the database.
+type CredentialService struct {
+	box  *secrets.Box
+	repo *repository.CredentialRepository
+}
+
+func NewCredentialService(box *secrets.Box, repo *repository.CredentialRepository) *CredentialService {
+	return &CredentialService{box: box, repo: repo}
+}
+
+// sealContext binds a sealed value to the organization and name it is
+// stored under, so it cannot be copied to another row.
+func sealContext(orgID uint, name string) []byte {
+	return []byte(fmt.Sprintf("%d:%s", orgID, name))
+}
+
+// Credentials lists the credentials, masked.
+func (s *CredentialService) Credentials(ctx context.Context) ([]Credential, error) {
+	rows, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Credential, 0, len(rows))
+	for i := range rows {
+		out = append(out, s.masked(&rows[i]))
+	}
+	return out, nil
+}
+
+// Credential returns a credential, masked.
+func (s *CredentialService) Credential(ctx context.Context, name string) (*Credential, error) {
+	row, err := s.repo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, ErrCredentialNotFound
+	}
+	c := s.masked(row)
+	return &c, nil
+}
+
+// masked describes a row, with its value masked. A value that cannot be
+// opened shows as empty.
+func (s *CredentialService) masked(row *repository.Credential) Credential {
+	c := Credential{
+		Name:        row.Name,
+		Kind:        row.Kind,
+		Description: row.Description,
+		KeyID:       row.KeyID,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+	}
+	if value, err := s.box.Open(row.Value, sealContext(row.OrgID, row.Name)); err == nil {
+		c.Masked = secrets.Mask(string(value))
+	}
+	return c
+}
+
+// SaveCredential creates or replaces a named credential. An empty value
+// keeps the stored one, so the kind and description can be edited without
+// sending the secret again. It reports whether the credential was created.
+func (s *CredentialService) SaveCredential(ctx context.Context, name, kind, description, value string) (*Credential, bool, error) {
+	if !credentialName.MatchString(name) {
+		return nil, false, ErrCredentialName
+	}
+	if err := validateCredential(kind, value); err != nil {
+		return nil, false, err
+	}
+	row, err := s.repo.FindByName(ctx, name)
+	if err != nil {
+		return nil, false, err
+	}
+	created := row == nil
+	if created {
+		if value == "" {
+			return nil, false, ErrCredentialValue
+		}
+		orgID, _ := database.OrgFromContext(ctx)
+		row = &repository.Credential{Name: name, OrgID: orgID}
+	}
+	row.Kind, row.Description = kind, strings.TrimSpace(description)
+	if value != "" {
+		if row.Value, err = s.box.Seal([]byte(value), sealContext(row.OrgID, row.Name)); err != nil {
+			return nil, false, err
+		}
+		row.KeyID = s.box.KeyID()
+	}
+	if err := s.repo.Save(ctx, row); err != nil {
+		return nil, false, err
+	}
+	c := s.masked(row)
+	return &c, created, nil
+}
+
+func validateCredential(kind, value string) error {
+	switch kind {
+	case CredentialKindSMTP, CredentialKindTelegram, CredentialKindGeneric:
+	case CredentialKindS3:
+		if value == "" {
+			return nil
+		}
+		if _, _, err := parseS3Keys(value); err != nil {
+			return err
+		}
+	case CredentialKindGoogleServiceAccount:
+		if value == "" {
+			return nil
+		}
+		var key struct {
+			Type        string `json:"type"`
+			ClientEmail string `json:"client_email"`
+			PrivateKey  string `json:"private_key"`
+		}
+		if json.Unmarshal([]byte(value), &key) != nil || key.ClientEmail == "" || key.PrivateKey == "" {
+			return ErrCredentialServiceAccount
+		}
+	default:
+		return ErrCredentialKind
+	}
+	return nil
+}
+
+// DeleteCredential removes a named credential.
+func (s *CredentialService) DeleteCredential(ctx context.Context, name string) error {
+	deleted, err := s.repo.Delete(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrCredentialNotFound
+	}
+	return nil
+}
+
+// Secret returns the value of a named credential, for the integrations
+// that use it.
+func (s *CredentialService) Secret(ctx context.Context, name string) (string, error) {
+	row, err := s.repo.FindByName(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if row == nil {
+		return "", ErrCredentialNotFound
+	}
+	value, err := s.box.Open(row.Value, sealContext(row.OrgID, row.Name))
+	if err != nil {
+		return "", ErrCredentialWrongKey
+	}
+	return string(value), nil
+}
+
+// S3Keys returns the access keys of a named s3 credential.
+func (s *CredentialService) S3Keys(ctx context.Context, name string) (accessKey, secretKey string, err error) {
+	value, err := s.Secret(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+	return parseS3Keys(value)
+}
+
+func parseS3Keys(value string) (accessKey, secretKey string, err error) {
+	var keys struct {
+		AccessKeyID     string `json:"access_key_id"`
+		SecretAccessKey string `json:"secret_access_key"`
+	}
+	if json.Unmarshal([]byte(value), &keys) != nil || keys.AccessKeyID == "" || keys.SecretAccessKey == "" {
+		return "", "", ErrCredentialS3
+	}
+	return keys.AccessKeyID, keys.SecretAccessKey, nil
+}
@@ -0,0 +1,836 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/internal/storage"
+	"melibot/pkg/meli"
+)
+
+// Custom report data sources.
+const (
+	// ReportSourceOrders reads one row per order line of my synced orders.
+	ReportSourceOrders = "orders"
+	// ReportSourceSnapshots reads the stored highlight snapshots.
+	ReportSourceSnapshots = "trend_snapshots"
+)
+
+// Custom report metric functions.
+const (
+	ReportMetricCount         = "count"
+	ReportMetricCountDistinct = "count_distinct"
+	ReportMetricSum           = "sum"
+	ReportMetricAvg           = "avg"
+	ReportMetricMin           = "min"
+	ReportMetricMax           = "max"
+)
+
+const (
+	// customReportKeyPrefix is where custom reports are stored in the
+	// bucket.
+	customReportKeyPrefix = "reports/"
+	// maxCustomReportDays bounds how far back a custom report reads.
+	maxCustomReportDays = 366
+	// maxCustomReportGroups bounds the rows of a custom report.
+	maxCustomReportGroups = 50000
+	// customReportBatch is how many source rows a run loads at a time.
+	customReportBatch = 2000
+	// CustomReportHeader names the report a webhook delivery carries.
+	CustomReportHeader = "X-Melibot-Report"
+)
+
+var (
+	ErrCustomReportNotFound   = errors.New("report not found")
+	ErrCustomReportName       = errors.New("name is required")
+	ErrCustomReportSource     = errors.New("source must be orders or trend_snapshots")
+	ErrCustomReportMetrics    = errors.New("at least one metric is required")
+	ErrCustomReportDays       = errors.New("days must be between 1 and 366")
+	ErrCustomReportSchedule   = errors.New("schedule must be hourly, daily, weekly or empty")
+	ErrCustomReportFormat     = errors.New("format must be csv or json")
+	ErrCustomReportDest       = errors.New("destination must be storage or webhook")
+	ErrCustomReportWebhookURL = errors.New("destination_url must be an absolute http or https URL")
+)
+
+// reportSource lists the fields a data source offers: dimensions to filter,
+// group and count by, and numeric measures to aggregate.
+type reportSource struct {
+	dimensions []string
+	measures   []string
+}
+
+var reportSources = map[string]reportSource{
+	ReportSourceOrders: {
+		dimensions: []string{"status", "currency", "buyer_nickname", "item_id", "title", "sku"},
+		measures:   []string{"quantity", "unit_price", "revenue", "sale_fee", "shipping_cost"},
+	},
+	ReportSourceSnapshots: {
+		dimensions: []string{"product_id", "title", "category_id", "highlight_category_id", "health"},
+		measures:   []string{"rank", "price", "sold_quantity"},
+	},
+}
+
+// reportTimeBuckets are the dimensions every source derives from the date
+// of its rows: the order's creation or the snapshot's capture.
+var reportTimeBuckets = []string{"day", "week", "month"}
+
+var reportFilterOps = []string{"eq", "ne", "gt", "gte", "lt", "lte", "in", "contains"}
+
+// ReportSourceInfo describes a data source for report builders.
+type ReportSourceInfo struct {
+	Source      string   `json:"source"`
+	Dimensions  []string `json:"dimensions"`
+	Measures    []string `json:"measures"`
+	TimeBuckets []string `json:"time_buckets"`
+}
+
+// ReportSources lists the data sources custom reports can read.
+func ReportSources() []ReportSourceInfo {
+	out := make([]ReportSourceInfo, 0, len(reportSources))
+	for name, src := range reportSources {
+		out = append(out, ReportSourceInfo{Source: name, Dimensions: src.dimensions, Measures: src.measures, TimeBuckets: reportTimeBuckets})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+	return out
+}
+
+// ReportFilter keeps the rows whose field compares to Value with Op: eq,
+// ne, gt, gte, lt, lte, contains, or in with a list of values. Measures
+// compare as numbers, dimensions as text.
+type ReportFilter struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// ReportMetric is a column of a custom report: Func over Field, named As
+// (by default func_field). count needs no field and count_distinct takes
+// any field; the others take a measure.
+type ReportMetric struct {
+	Func  string `json:"func"`
+	Field string `json:"field,omitempty"`
+	As    string `json:"as,omitempty"`
+}
+
+func (m ReportMetric) column() string {
+	switch {
+	case m.As != "":
+		return m.As
+	case m.Field == "":
+		return m.Func
+	}
+	return m.Func + "_" + m.Field
+}
+
+// ReportSpec is what a custom report is made of, as users write it.
+type ReportSpec struct {
+	Name           string         `json:"name"`
+	Source         string         `json:"source"`
+	Filters        []ReportFilter `json:"filters"`
+	GroupBy        []string       `json:"group_by"`
+	Metrics        []ReportMetric `json:"metrics"`
+	Days           int            `json:"days"`
+	Schedule       string         `json:"schedule"`
+	Format         string         `json:"format"`
+	Destination    string         `json:"destination"`
+	DestinationURL string         `json:"destination_url,omitempty"`
+	Enabled        *bool          `json:"enabled,omitempty"`
+}
+
+// Validate checks the spec against its data source and fills in its
+// defaults: the last 30 days, as CSV, to object storage.
+func (s *ReportSpec) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return ErrCustomReportName
+	}
+	src, ok := reportSources[s.Source]
+	if !ok {
+		return ErrCustomReportSource
+	}
+	if s.Days == 0 {
+		s.Days = 30
+	}
+	if s.Days < 0 || s.Days > maxCustomReportDays {
+		return ErrCustomReportDays
+	}
+	if s.Format == "" {
+		s.Format = "csv"
+	}
+	if s.Format != "csv" && s.Format != "json" {
+		return ErrCustomReportFormat
+	}
+	if s.Destination == "" {
+		s.Destination = repository.ReportDestinationStorage
+	}
+	switch s.Destination {
+	case repository.ReportDestinationStorage:
+	case repository.ReportDestinationWebhook:
+		u, err := url.Parse(s.DestinationURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return ErrCustomReportWebhookURL
+		}
+		if err := checkDestinationHost(u.Hostname()); err != nil {
+			return err
+		}
+	default:
+		return ErrCustomReportDest
+	}
+	if _, ok := reportScheduleInterval(s.Schedule); !ok {
+		return ErrCustomReportSchedule
+	}
+
+	for _, f := range s.Filters {
+		if !src.dimension(f.Field) && !src.measure(f.Field) {
+			return fmt.Errorf("unknown field %q in filters", f.Field)
+		}
+		if !slices.Contains(reportFilterOps, f.Op) {
+			return fmt.Errorf("unknown filter op %q; valid ops are %s", f.Op, strings.Join(reportFilterOps, ", "))
+		}
+		if _, isList := f.Value.([]interface{}); isList != (f.Op == "in") {
+			return fmt.Errorf("filter on %q: only the in op takes a list of values", f.Field)
+		}
+	}
+	for _, g := range s.GroupBy {
+		if !src.dimension(g) {
+			return fmt.Errorf("cannot group by %q", g)
+		}
+	}
+	if len(s.Metrics) == 0 {
+		return ErrCustomReportMetrics
+	}
+	columns := map[string]bool{}
+	for _, g := range s.GroupBy {
+		columns[g] = true
+	}
+	for _, m := range s.Metrics {
+		switch m.Func {
+		case ReportMetricCount:
+		case ReportMetricCountDistinct:
+			if !src.dimension(m.Field) && !src.measure(m.Field) {
+				return fmt.Errorf("unknown field %q in metrics", m.Field)
+			}
+		case ReportMetricSum, ReportMetricAvg, ReportMetricMin, ReportMetricMax:
+			if !src.measure(m.Field) {
+				return fmt.Errorf("%s needs a measure, not %q", m.Func, m.Field)
+			}
+		default:
+			return fmt.Errorf("unknown metric %q", m.Func)
+		}
+		if columns[m.column()] {
+			return fmt.Errorf("duplicate column %q", m.column())
+		}
+		columns[m.column()] = true
+	}
+	return nil
+}
+
+func (src reportSource) dimension(field string) bool {
+	return slices.Contains(src.dimensions, field) || slices.Contains(reportTimeBuckets, field)
+}
+
+func (src reportSource) measure(field string) bool {
+	return slices.Contains(src.measures, field)
+}
+
+// reportScheduleInterval returns how often a schedule runs; ok is false for
+// unknown schedules and the interval is zero for on-demand reports.
+func reportScheduleInterval(schedule string) (time.Duration, bool) {
+	switch schedule {
+	case "":
+		return 0, true
+	case repository.ReportScheduleHourly:
+		return time.Hour, true
+	case repository.ReportScheduleDaily:
+		return 24 * time.Hour, true
+	case repository.ReportScheduleWeekly:
+		return 7 * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// nextReportRun is when a scheduled report runs after now, nil for
+// on-demand reports.
+func nextReportRun(schedule string, now time.Time) *time.Time {
+	interval, _ := reportScheduleInterval(schedule)
+	if interval == 0 {
+		return nil
+	}
+	next := now.Add(interval)
+	return &next
+}
+
+// DefinitionSpec decodes the spec of a stored definition.
+func DefinitionSpec(def *repository.ReportDefinition) (ReportSpec, error) {
+	enabled := def.Enabled
+	spec := ReportSpec{
+		Name:           def.Name,
+		Source:         def.Source,
+		Filters:        []ReportFilter{},
+		GroupBy:        []string{},
+		Metrics:        []ReportMetric{},
+		Days:           def.Days,
+		Schedule:       def.Schedule,
+		Format:         def.Format,
+		Destination:    def.Destination,
+		DestinationURL: def.DestinationURL,
+		Enabled:        &enabled,
+	}
+	if def.Filters != "" {
+		if err := json.Unmarshal([]byte(def.Filters), &spec.Filters); err != nil {
+			return spec, fmt.Errorf("report %d filters: %w", def.ID, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(def.Metrics), &spec.Metrics); err != nil {
+		return spec, fmt.Errorf("report %d metrics: %w", def.ID, err)
+	}
+	for _, g := range strings.Split(def.GroupBy, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			spec.GroupBy = append(spec.GroupBy, g)
+		}
+	}
+	return spec, nil
+}
+
+// ReportTable is the output of a custom report: its group-by fields then
+// its metrics, one row per group.
+type ReportTable struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// WriteReportTable writes a table as CSV (with a header) or as a JSON array
+// of objects keyed by column.
+func WriteReportTable(w io.Writer, format string, t *ReportTable) error {
+	switch format {
+	case "json":
+		out := make([]map[string]interface{}, 0, len(t.Rows))
+		for _, row := range t.Rows {
+			obj := make(map[string]interface{}, len(row))
+			for i, v := range row {
+				obj[t.Columns[i]] = v
+			}
+			out = append(out, obj)
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(t.Columns); err != nil {
+			return err
+		}
+		for _, row := range t.Rows {
+			rec := make([]string, len(row))
+			for i, v := range row {
+				rec[i] = fmt.Sprint(v)
+			}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return ErrCustomReportFormat
+}
+
+// CustomReportRun is the outcome of a run of a custom report. URL and
+// ExpiresAt are set for reports uploaded to object storage.
+type CustomReportRun struct {
+	ReportID    uint       `json:"report_id"`
+	Rows        int        `json:"rows"`
+	Format      string     `json:"format"`
+	Destination string     `json:"destination"`
+	Key         string     `json:"key,omitempty"`
+	URL         string     `json:"url,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RanAt       time.Time  `json:"ran_at"`
+}
+
+// CustomReportService stores the reports users build and runs them:
+// it reads the rows of their data source, filters, groups and aggregates
+// them and delivers the table to object storage or a webhook, on demand or
+// on their schedule.
+type CustomReportService struct {
+	meliClient *meli.MeliClient
+	repo       *repository.ReportDefinitionRepository
+	trendRepo  *repository.TrendRepository
+	orderRepo  *repository.OrderRepository
+	bucket     *storage.Bucket
+	urlTTL     time.Duration
+	httpClient *http.Client
+}
+
+func NewCustomReportService(meliClient *meli.MeliClient, repo *repository.ReportDefinitionRepository, trendRepo *repository.TrendRepository, orderRepo *repository.OrderRepository, bucket *storage.Bucket, urlTTL time.Duration, httpClient *http.Client) *CustomReportService {
+	if httpClient == nil {
+		httpClient = newPublicHTTPClient(30 * time.Second)
+	}
+	return &CustomReportService{
+		meliClient: meliClient,
+		repo:       repo,
+		trendRepo:  trendRepo,
+		orderRepo:  orderRepo,
+		bucket:     bucket,
+		urlTTL:     urlTTL,
+		httpClient: httpClient,
+	}
+}
+
+// List returns the report definitions.
+func (s *CustomReportService) List(ctx context.Context) ([]repository.ReportDefinition, error) {
+	return s.repo.List(ctx)
+}
+
+// Get returns a report definition.
+func (s *CustomReportService) Get(ctx context.Context, id uint) (*repository.ReportDefinition, error) {
+	def, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if def == nil {
+		return nil, ErrCustomReportNotFound
+	}
+	return def, nil
+}
+
+// Save validates spec and creates a definition from it, or replaces the
+// one with id. Webhook destinations get a random signing secret, kept
+// across updates. A new or changed schedule first runs one interval from
+// now.
+func (s *CustomReportService) Save(ctx context.Context, id uint, spec ReportSpec) (*repository.ReportDefinition, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	filters, err := json.Marshal(spec.Filters)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := json.Marshal(spec.Metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	def := &repository.ReportDefinition{}
+	if id != 0 {
+		if def, err = s.Get(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	if def.ID == 0 || def.Schedule != spec.Schedule {
+		def.NextRunAt = nextReportRun(spec.Schedule, time.Now())
+	}
+	def.Name = strings.TrimSpace(spec.Name)
+	def.Source = spec.Source
+	def.Filters = string(filters)
+	def.GroupBy = strings.Join(spec.GroupBy, ",")
+	def.Metrics = string(metrics)
+	def.Days = spec.Days
+	def.Schedule = spec.Schedule
+	def.Format = spec.Format
+	def.Destination = spec.Destination
+	def.DestinationURL = spec.DestinationURL
+	def.Enabled = spec.Enabled == nil || *spec.Enabled
+	if def.Destination == repository.ReportDestinationWebhook && def.Secret == "" {
+		if def.Secret, err = randomHex(32); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.Save(ctx, def); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// Delete removes a report definition.
+func (s *CustomReportService) Delete(ctx context.Context, id uint) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrCustomReportNotFound
+	}
+	return nil
+}
+
+// Run runs a report now and delivers it to its destination.
+func (s *CustomReportService) Run(ctx context.Context, id uint) (*CustomReportRun, error) {
+	def, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx, def)
+}
+
+// RunDue runs the scheduled reports whose next run is due. Reports that
+// fail are logged and skipped; they run again on their next interval.
+func (s *CustomReportService) RunDue(ctx context.Context) error {
+	defs, err := s.repo.Due(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for i := range defs {
+		if _, err := s.run(ctx, &defs[i]); err != nil {
+			log.Printf("[WARN] Custom report %d (%s) failed: %v", defs[i].ID, defs[i].Name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("custom reports: %d of %d reports failed", failed, len(defs))
+	}
+	return nil
+}
+
+// run executes def, delivers it and records the outcome on the definition.
+func (s *CustomReportService) run(ctx context.Context, def *repository.ReportDefinition) (*CustomReportRun, error) {
+	now := time.Now()
+	result, err := s.deliver(ctx, def, now)
+	rows, key, msg := 0, "", ""
+	if result != nil {
+		rows, key = result.Rows, result.Key
+	}
+	if err != nil {
+		msg = err.Error()
+	}
+	if recErr := s.repo.RecordRun(ctx, def.ID, now, nextReportRun(def.Schedule, now), rows, key, msg); recErr != nil && err == nil {
+		return nil, recErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Custom report %d (%s): %d rows to %s", def.ID, def.Name, rows, def.Destination)
+	return result, nil
+}
+
+func (s *CustomReportService) deliver(ctx context.Context, def *repository.ReportDefinition, now time.Time) (*CustomReportRun, error) {
+	if def.Destination == repository.ReportDestinationStorage && s.bucket == nil {
+		return nil, ErrExportStorageDisabled
+	}
+	table, err := s.Execute(ctx, def)
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	if err := WriteReportTable(&body, def.Format, table); err != nil {
+		return nil, err
+	}
+
+	result := &CustomReportRun{
+		ReportID:    def.ID,
+		Rows:        len(table.Rows),
+		Format:      def.Format,
+		Destination: def.Destination,
+		RanAt:       now,
+	}
+	switch def.Destination {
+	case repository.ReportDestinationStorage:
+		result.Key = fmt.Sprintf("%s%d/report-%d-%s.%s", customReportKeyPrefix, def.ID, def.ID, now.UTC().Format("20060102T150405Z"), def.Format)
+		size := int64(body.Len())
+		if err := s.bucket.Upload(ctx, result.Key, exportContentType(def.Format), &body, size); err != nil {
+			return nil, err
+		}
+		url, expiresAt, err := s.bucket.SignedURL(result.Key, s.urlTTL)
+		if err != nil {
+			return nil, err
+		}
+		result.URL, result.ExpiresAt = url, &expiresAt
+	case repository.ReportDestinationWebhook:
+		if err := s.post(ctx, def, body.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// post sends a report to its webhook, signed like the outbound event
+// webhooks. A non-2xx answer is an error.
+func (s *CustomReportService) post(ctx context.Context, def *repository.ReportDefinition, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, def.DestinationURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", exportContentType(def.Format))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(def.Secret, body))
+	req.Header.Set(CustomReportHeader, strconv.FormatUint(uint64(def.ID), 10))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("report %d webhook answered %d", def.ID, resp.StatusCode)
+	}
+	return nil
+}
+
+// reportRow is a row of a data source: its dimensions as text, its
+// measures and its date.
+type reportRow struct {
+	dims     map[string]string
+	measures map[string]float64
+	at       time.Time
+}
+
+func (r reportRow) dimension(field string) string {
+	switch field {
+	case "day":
+		return r.at.Format(time.DateOnly)
+	case "week":
+		year, week := r.at.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case "month":
+		return r.at.Format("2006-01")
+	}
+	if v, ok := r.measures[field]; ok {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return r.dims[field]
+}
+
+// reportGroup accumulates the metrics of one group of rows.
+type reportGroup struct {
+	values   []interface{}
+	count    []int
+	sum      []float64
+	min      []float64
+	max      []float64
+	distinct []map[string]bool
+}
+
+// Execute reads the rows of a definition's source over its last days and
+// returns the table it makes, sorted by its group-by fields.
+func (s *CustomReportService) Execute(ctx context.Context, def *repository.ReportDefinition) (*ReportTable, error) {
+	spec, err := DefinitionSpec(def)
+	if err != nil {
+		return nil, err
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	groups := map[string]*reportGroup{}
+	var keys []string
+	add := func(r reportRow) error {
+		for _, f := range spec.Filters {
+			if !matchReportFilter(r, f) {
+				return nil
+			}
+		}
+		values := make([]interface{}, len(spec.GroupBy))
+		parts := make([]string, len(spec.GroupBy))
+		for i, g := range spec.GroupBy {
+			parts[i] = r.dimension(g)
+			values[i] = parts[i]
+		}
+		key := strings.Join(parts, "\x00")
+		g, ok := groups[key]
+		if !ok {
+			if len(groups) >= maxCustomReportGroups {
+				return fmt.Errorf("report has more than %d groups; group by fewer fields", maxCustomReportGroups)
+			}
+			n := len(spec.Metrics)
+			g = &reportGroup{values: values, count: make([]int, n), sum: make([]float64, n), min: make([]float64, n), max: make([]float64, n), distinct: make([]map[string]bool, n)}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		for i, m := range spec.Metrics {
+			switch m.Func {
+			case ReportMetricCountDistinct:
+				if g.distinct[i] == nil {
+					g.distinct[i] = map[string]bool{}
+				}
+				g.distinct[i][r.dimension(m.Field)] = true
+			case ReportMetricCount:
+				g.count[i]++
+			default:
+				v := r.measures[m.Field]
+				if g.count[i] == 0 || v < g.min[i] {
+					g.min[i] = v
+				}
+				if g.count[i] == 0 || v > g.max[i] {
+					g.max[i] = v
+				}
+				g.sum[i] += v
+				g.count[i]++
+			}
+		}
+		return nil
+	}
+	if err := s.readSource(ctx, spec, add); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	table := &ReportTable{Columns: append([]string{}, spec.GroupBy...), Rows: make([][]interface{}, 0, len(keys))}
+	for _, m := range spec.Metrics {
+		table.Columns = append(table.Columns, m.column())
+	}
+	for _, key := range keys {
+		g := groups[key]
+		row := append([]interface{}{}, g.values...)
+		for i, m := range spec.Metrics {
+			switch m.Func {
+			case ReportMetricCount:
+				row = append(row, g.count[i])
+			case ReportMetricCountDistinct:
+				row = append(row, len(g.distinct[i]))
+			case ReportMetricSum:
+				row = append(row, roundCents(g.sum[i]))
+			case ReportMetricAvg:
+				avg := 0.0
+				if g.count[i] > 0 {
+					avg = roundCents(g.sum[i] / float64(g.count[i]))
+				}
+				row = append(row, avg)
+			case ReportMetricMin:
+				row = append(row, g.min[i])
+			case ReportMetricMax:
+				row = append(row, g.max[i])
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+// readSource calls fn with every row of the spec's source since its first
+// day.
+func (s *CustomReportService) readSource(ctx context.Context, spec ReportSpec, fn func(reportRow) error) error {
+	now := time.Now()
+	since := now.AddDate(0, 0, -spec.Days)
+	switch spec.Source {
+	case ReportSourceOrders:
+		me, err := s.meliClient.Me(ctx)
+		if err != nil {
+			return err
+		}
+		return s.orderRepo.OrdersInBatches(ctx, me.ID, since, now, customReportBatch, func(orders []repository.Order) error {
+			for _, l := range orderLines(orders) {
+				err := fn(reportRow{
+					dims: map[string]string{
+						"status":         l.Status,
+						"currency":       l.Currency,
+						"buyer_nickname": l.BuyerNickname,
+						"item_id":        l.ItemID,
+						"title":          l.Title,
+						"sku":            l.SKU,
+					},
+					measures: map[string]float64{
+						"quantity":      float64(l.Quantity),
+						"unit_price":    l.UnitPrice,
+						"revenue":       l.UnitPrice * float64(l.Quantity),
+						"sale_fee":      l.SaleFee * float64(l.Quantity),
+						"shipping_cost": l.ShippingCost,
+					},
+					at: l.DateCreated,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	case ReportSourceSnapshots:
+		return s.trendRepo.SnapshotsInBatches(ctx, "", since, customReportBatch, func(trends []repository.ProductTrend) error {
+			for _, t := range trends {
+				err := fn(reportRow{
+					dims: map[string]string{
+						"product_id":            t.ProductID,
+						"title":                 t.Title,
+						"category_id":           t.CategoryID,
+						"highlight_category_id": t.HighlightCategoryID,
+						"health":                t.Health,
+					},
+					measures: map[string]float64{
+						"rank":          float64(t.Rank),
+						"price":         t.Price,
+						"sold_quantity": float64(t.SoldQuantity),
+					},
+					at: t.CreatedAt,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	return ErrCustomReportSource
+}
+
+// matchReportFilter reports whether a row passes a filter. Measures compare
+// as numbers, everything else as text.
+func matchReportFilter(r reportRow, f ReportFilter) bool {
+	if f.Op == "in" {
+		v := r.dimension(f.Field)
+		for _, item := range f.Value.([]interface{}) {
+			if v == filterText(item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	want := filterText(f.Value)
+	if measure, ok := r.measures[f.Field]; ok && f.Op != "contains" {
+		n, err := strconv.ParseFloat(want, 64)
+		if err != nil {
+			return false
+		}
+		return compareReportValues(f.Op, measure-n)
+	}
+	v := r.dimension(f.Field)
+	if f.Op == "contains" {
+		return strings.Contains(strings.ToLower(v), strings.ToLower(want))
+	}
+	return compareReportValues(f.Op, float64(strings.Compare(v, want)))
+}
+
+// compareReportValues applies a comparison op to the sign of a difference.
+func compareReportValues(op string, diff float64) bool {
+	switch op {
+	case "eq":
+		return diff == 0
+	case "ne":
+		return diff != 0
+	case "gt":
+		return diff > 0
+	case "gte":
+		return diff >= 0
+	case "lt":
+		return diff < 0
+	case "lte":
+		return diff <= 0
+	}
+	return false
+}
+
+func filterText(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
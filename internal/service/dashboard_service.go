@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// Dashboard widget names, the keys of DashboardSummary.Errors.
+const (
+	WidgetTopCategories = "top_categories"
+	WidgetPriceDrops    = "price_drops"
+	WidgetSales         = "sales"
+	WidgetQuestions     = "questions"
+)
+
+// CategoryMovement is a category ranked by the units its tracked products
+// sold since the start of the day.
+type CategoryMovement struct {
+	CategoryID string `json:"category_id"`
+	Name       string `json:"name,omitempty"`
+	UnitsSold  int    `json:"units_sold"`
+	Products   int    `json:"products"`
+}
+
+// PriceDrop is a tracked product that got cheaper since the start of the
+// day.
+type PriceDrop struct {
+	ProductID  string  `json:"product_id"`
+	Title      string  `json:"title"`
+	Thumbnail  string  `json:"thumbnail"`
+	Permalink  string  `json:"permalink"`
+	FirstPrice float64 `json:"first_price"`
+	Price      float64 `json:"price"`
+	DropPct    float64 `json:"drop_pct"`
+}
+
+// SalesComparison is the seller's paid sales today against all of
+// yesterday. ChangePct is nil when yesterday had no revenue.
+type SalesComparison struct {
+	Today     SalesTotals `json:"today"`
+	Yesterday SalesTotals `json:"yesterday"`
+	ChangePct *float64    `json:"change_pct"`
+}
+
+// QuestionCounts are the questions waiting for the seller: unanswered on
+// Mercado Livre, and suggested answers waiting in the review queue.
+type QuestionCounts struct {
+	Open           int   `json:"open"`
+	AwaitingReview int64 `json:"awaiting_review"`
+}
+
+// DashboardSummary holds the widgets of the index page. A widget that could
+// not be computed is null and its error is reported in Errors, so one slow
+// or failing source doesn't blank the whole page.
+type DashboardSummary struct {
+	GeneratedAt   time.Time          `json:"generated_at"`
+	TopCategories []CategoryMovement `json:"top_categories"`
+	PriceDrops    []PriceDrop        `json:"price_drops"`
+	Sales         *SalesComparison   `json:"sales"`
+	Questions     *QuestionCounts    `json:"questions"`
+	Errors        map[string]string  `json:"errors,omitempty"`
+}
+
+// DashboardService aggregates stored snapshots, orders and questions into
+// the widgets of the index page.
+type DashboardService struct {
+	meliClient   *meli.MeliClient
+	marketing    *MarketingService
+	trendRepo    *repository.TrendRepository
+	orderRepo    *repository.OrderRepository
+	questionRepo *repository.QuestionRepository
+}
+
+func NewDashboardService(meliClient *meli.MeliClient, marketing *MarketingService, trendRepo *repository.TrendRepository, orderRepo *repository.OrderRepository, questionRepo *repository.QuestionRepository) *DashboardService {
+	return &DashboardService{
+		meliClient:   meliClient,
+		marketing:    marketing,
+		trendRepo:    trendRepo,
+		orderRepo:    orderRepo,
+		questionRepo: questionRepo,
+	}
+}
+
+// Summary computes every widget concurrently. limit bounds the category and
+// price drop lists. "Today" starts at local midnight.
+func (s *DashboardService) Summary(ctx context.Context, limit int) *DashboardSummary {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	out := &DashboardSummary{GeneratedAt: now}
+
+	// Sales and questions both need the seller ID
+	me := sync.OnceValues(func() (*meli.User, error) {
+		return s.meliClient.Me(ctx)
+	})
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	run := func(widget string, fn func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(); err != nil {
+				log.Printf("[WARN] dashboard %s: %v", widget, err)
+				mu.Lock()
+				if out.Errors == nil {
+					out.Errors = make(map[string]string)
+				}
+				out.Errors[widget] = err.Error()
+				mu.Unlock()
+			}
+		}()
+	}
+
+	run(WidgetTopCategories, func() error {
+		categories, err := s.topCategories(ctx, today, limit)
+		out.TopCategories = categories
+		return err
+	})
+	run(WidgetPriceDrops, func() error {
+		drops, err := s.priceDrops(ctx, today, limit)
+		out.PriceDrops = drops
+		return err
+	})
+	run(WidgetSales, func() error {
+		user, err := me()
+		if err != nil {
+			return err
+		}
+		sales, err := s.salesComparison(ctx, user.ID, today, now)
+		out.Sales = sales
+		return err
+	})
+	run(WidgetQuestions, func() error {
+		user, err := me()
+		if err != nil {
+			return err
+		}
+		questions, err := s.questionCounts(ctx, user.ID)
+		out.Questions = questions
+		return err
+	})
+	wg.Wait()
+	return out
+}
+
+func (s *DashboardService) topCategories(ctx context.Context, since time.Time, limit int) ([]CategoryMovement, error) {
+	rows, err := s.trendRepo.TopCategoryMovement(ctx, since, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Names are best effort: highlights may list subcategories, and the
+	// widget is still useful with IDs only
+	names := map[string]string{}
+	if cats, err := s.marketing.RootCategories(ctx); err == nil {
+		for _, c := range cats {
+			names[c.ID] = c.Name
+		}
+	}
+
+	out := make([]CategoryMovement, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, CategoryMovement{CategoryID: r.CategoryID, Name: names[r.CategoryID], UnitsSold: r.UnitsSold, Products: r.Products})
+	}
+	return out, nil
+}
+
+func (s *DashboardService) priceDrops(ctx context.Context, since time.Time, limit int) ([]PriceDrop, error) {
+	rows, err := s.trendRepo.PriceDrops(ctx, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PriceDrop, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, PriceDrop{
+			ProductID:  r.ProductID,
+			Title:      r.Title,
+			Thumbnail:  r.Thumbnail,
+			Permalink:  r.Permalink,
+			FirstPrice: r.FirstPrice,
+			Price:      r.LastPrice,
+			DropPct:    math.Round((r.FirstPrice-r.LastPrice)/r.FirstPrice*1000) / 10,
+		})
+	}
+	return out, nil
+}
+
+func (s *DashboardService) salesComparison(ctx context.Context, sellerID int64, today, now time.Time) (*SalesComparison, error) {
+	todayTotals, err := s.salesBetween(ctx, sellerID, today, now)
+	if err != nil {
+		return nil, err
+	}
+	yesterdayTotals, err := s.salesBetween(ctx, sellerID, today.AddDate(0, 0, -1), today)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &SalesComparison{Today: todayTotals, Yesterday: yesterdayTotals}
+	if yesterdayTotals.Revenue > 0 {
+		pct := math.Round((todayTotals.Revenue-yesterdayTotals.Revenue)/yesterdayTotals.Revenue*1000) / 10
+		out.ChangePct = &pct
+	}
+	return out, nil
+}
+
+// salesBetween totals the seller's paid orders created in [from, to). The
+// range may span two database days, depending on the time zones involved.
+func (s *DashboardService) salesBetween(ctx context.Context, sellerID int64, from, to time.Time) (SalesTotals, error) {
+	periods, err := s.orderRepo.SalesByPeriod(ctx, sellerID, "day", from, to)
+	if err != nil {
+		return SalesTotals{}, err
+	}
+	var orders, units int
+	var revenue float64
+	for _, p := range periods {
+		orders += p.Orders
+		units += p.Units
+		revenue += p.Revenue
+	}
+	return salesTotals(orders, units, revenue), nil
+}
+
+func (s *DashboardService) questionCounts(ctx context.Context, sellerID int64) (*QuestionCounts, error) {
+	open, err := s.meliClient.CountQuestions(ctx, sellerID, meli.QuestionStatusUnanswered)
+	if err != nil {
+		return nil, err
+	}
+	review, err := s.questionRepo.CountMatches(ctx, repository.MatchStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	return &QuestionCounts{Open: open, AwaitingReview: review}, nil
+}
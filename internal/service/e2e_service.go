@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"melibot/internal/events"
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// End-to-end check steps, in the order they run.
+const (
+	E2EStepSellerAccount = "seller_account"
+	E2EStepBuyer         = "buyer_test_user"
+	E2EStepListing       = "test_listing"
+	E2EStepOrder         = "test_order"
+	E2EStepWebhook       = "webhook"
+	E2EStepOrderSync     = "order_sync"
+	E2EStepAnalytics     = "analytics"
+	E2EStepCleanup       = "cleanup"
+)
+
+// Step and run statuses.
+const (
+	E2EStatusOK      = "ok"
+	E2EStatusFailed  = "failed"
+	E2EStatusPending = "pending"
+
+	E2ERunWaiting = "waiting_order"
+	E2ERunPassed  = "passed"
+	E2ERunFailed  = "failed"
+)
+
+const (
+	// e2eListingTitle is the title Mercado Livre asks test listings to use.
+	e2eListingTitle    = "Item de Teste - Por favor, NÃO OFERTAR!"
+	defaultE2EPrice    = 10
+	defaultE2ECurrency = "BRL"
+)
+
+var (
+	ErrE2ERunNotFound  = errors.New("end-to-end run not found")
+	ErrE2EInvalidPrice = errors.New("price must not be negative")
+)
+
+// E2ERequest configures the test listing of an end-to-end check.
+// CurrencyID defaults to BRL, the currency of the default site.
+type E2ERequest struct {
+	CategoryID string  `json:"category_id"`
+	Price      float64 `json:"price"`
+	CurrencyID string  `json:"currency_id"`
+}
+
+// E2EStep is the result of one step of an end-to-end check.
+type E2EStep struct {
+	Name   string    `json:"name"`
+	Status string    `json:"status"`
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// E2EBuyer is the test user that buys the test listing.
+type E2EBuyer struct {
+	ID       int64  `json:"id"`
+	Nickname string `json:"nickname"`
+	Password string `json:"password"`
+}
+
+// E2EReport is the state of an end-to-end check.
+type E2EReport struct {
+	ID        uint      `json:"id"`
+	Status    string    `json:"status"`
+	SellerID  int64     `json:"seller_id"`
+	Buyer     *E2EBuyer `json:"buyer,omitempty"`
+	ItemID    string    `json:"item_id,omitempty"`
+	Permalink string    `json:"permalink,omitempty"`
+	OrderID   int64     `json:"order_id,omitempty"`
+	Steps     []E2EStep `json:"steps"`
+	// NextAction tells the operator what to do while the run waits.
+	NextAction string    `json:"next_action,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// E2EService validates a sandbox deployment end to end: a buyer test user
+// buys a test listing of the logged-in test seller, and the order is
+// followed through the webhook, the order sync and the sales analytics.
+// Mercado Livre has no API to buy, so Start publishes the listing and the
+// operator buys it as the buyer; Verify then checks the pipeline.
+type E2EService struct {
+	meliClient   *meli.MeliClient
+	orderRepo    *repository.OrderRepository
+	testUserRepo *repository.TestUserRepository
+	runRepo      *repository.E2ERunRepository
+	bus          *events.Bus
+	enabled      bool
+}
+
+func NewE2EService(meliClient *meli.MeliClient, orderRepo *repository.OrderRepository, testUserRepo *repository.TestUserRepository, runRepo *repository.E2ERunRepository, bus *events.Bus, enabled bool) *E2EService {
+	return &E2EService{
+		meliClient:   meliClient,
+		orderRepo:    orderRepo,
+		testUserRepo: testUserRepo,
+		runRepo:      runRepo,
+		bus:          bus,
+		enabled:      enabled,
+	}
+}
+
+// Start checks the seller is a test user, creates a buyer test user and
+// publishes a test listing. The run is stored whatever the outcome, with
+// the result of each step.
+func (s *E2EService) Start(ctx context.Context, req E2ERequest) (*E2EReport, error) {
+	if !s.enabled {
+		return nil, ErrSandboxDisabled
+	}
+	if req.Price < 0 {
+		return nil, ErrE2EInvalidPrice
+	}
+	if req.Price == 0 {
+		req.Price = defaultE2EPrice
+	}
+	if req.CurrencyID == "" {
+		req.CurrencyID = defaultE2ECurrency
+	}
+
+	run := &repository.E2ERun{Status: E2ERunFailed}
+	var steps []E2EStep
+	finish := func() (*E2EReport, error) {
+		if err := s.save(ctx, run, steps); err != nil {
+			return nil, err
+		}
+		return s.report(run, steps), nil
+	}
+
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		steps = setE2EStep(steps, E2EStepSellerAccount, E2EStatusFailed, err.Error())
+		return finish()
+	}
+	run.SellerID = me.ID
+	isTest, err := s.testUserRepo.Exists(ctx, me.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !isTest {
+		steps = setE2EStep(steps, E2EStepSellerAccount, E2EStatusFailed, fmt.Sprintf("%s is not a test user; log in with a seller test user", me.Nickname))
+		return finish()
+	}
+	steps = setE2EStep(steps, E2EStepSellerAccount, E2EStatusOK, me.Nickname)
+
+	buyer, err := s.meliClient.CreateTestUser(ctx)
+	if err != nil {
+		steps = setE2EStep(steps, E2EStepBuyer, E2EStatusFailed, err.Error())
+		return finish()
+	}
+	if err := s.testUserRepo.Create(ctx, &repository.TestUser{
+		MLUserID:   buyer.ID,
+		Nickname:   buyer.Nickname,
+		Email:      buyer.Email,
+		Password:   buyer.Password,
+		SiteStatus: buyer.SiteStatus,
+	}); err != nil {
+		return nil, err
+	}
+	run.BuyerID, run.BuyerNickname, run.BuyerPassword = buyer.ID, buyer.Nickname, buyer.Password
+	steps = setE2EStep(steps, E2EStepBuyer, E2EStatusOK, buyer.Nickname)
+
+	item, err := s.meliClient.CreateItem(ctx, meli.NewItem{
+		Title:        e2eListingTitle,
+		CategoryID:   req.CategoryID,
+		Price:        req.Price,
+		CurrencyID:   req.CurrencyID,
+		AvailableQty: 1,
+		BuyingMode:   "buy_it_now",
+		ListingType:  "free",
+		Condition:    "new",
+	})
+	if err != nil {
+		steps = setE2EStep(steps, E2EStepListing, E2EStatusFailed, err.Error())
+		return finish()
+	}
+	run.ItemID, run.Permalink = item.ID, item.Permalink
+	steps = setE2EStep(steps, E2EStepListing, E2EStatusOK, item.ID)
+	steps = setE2EStep(steps, E2EStepOrder, E2EStatusPending, "")
+	run.Status = E2ERunWaiting
+	return finish()
+}
+
+// Verify follows the order of the test listing through the pipeline: it is
+// expected in the database through the webhook first, then the order sync
+// must agree with Mercado Livre and the analytics must count it. Once every
+// step passed, the test listing is closed.
+func (s *E2EService) Verify(ctx context.Context, id uint) (*E2EReport, error) {
+	run, err := s.runRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run == nil {
+		return nil, ErrE2ERunNotFound
+	}
+	var steps []E2EStep
+	if err := json.Unmarshal([]byte(run.Steps), &steps); err != nil {
+		return nil, err
+	}
+	if run.Status != E2ERunWaiting {
+		return s.report(run, steps), nil
+	}
+	if !s.enabled {
+		return nil, ErrSandboxDisabled
+	}
+
+	// An order already stored came from the webhook, unless the periodic
+	// sync ran since it was placed.
+	stored, err := s.orderRepo.FindByItem(ctx, run.SellerID, run.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		orders, err := s.meliClient.SearchOrders(ctx, run.SellerID, run.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		i := slices.IndexFunc(orders, func(o meli.Order) bool {
+			return slices.ContainsFunc(o.OrderItems, func(line meli.OrderItem) bool { return line.Item.ID == run.ItemID })
+		})
+		if i < 0 {
+			return s.report(run, steps), nil
+		}
+		run.OrderID = orders[i].ID
+		steps = setE2EStep(steps, E2EStepOrder, E2EStatusOK, fmt.Sprintf("order %d", run.OrderID))
+		steps = setE2EStep(steps, E2EStepWebhook, E2EStatusFailed,
+			"the order was not received through the notification; check the application's callback URL and /api/admin/webhooks/failed")
+	} else {
+		run.OrderID = stored.ID
+		steps = setE2EStep(steps, E2EStepOrder, E2EStatusOK, fmt.Sprintf("order %d", run.OrderID))
+		steps = setE2EStep(steps, E2EStepWebhook, E2EStatusOK, "")
+	}
+
+	orders := NewOrderService(s.meliClient, s.orderRepo, s.bus)
+	steps = s.verifySync(ctx, orders, run, steps)
+	steps = s.verifyAnalytics(ctx, orders, run, steps)
+
+	failed := slices.ContainsFunc(steps, func(st E2EStep) bool { return st.Status == E2EStatusFailed })
+	pending := slices.ContainsFunc(steps, func(st E2EStep) bool { return st.Status == E2EStatusPending })
+	switch {
+	case failed:
+		run.Status = E2ERunFailed
+	case !pending:
+		if err := s.meliClient.CloseItem(ctx, run.ItemID); err != nil {
+			steps = setE2EStep(steps, E2EStepCleanup, E2EStatusFailed, err.Error())
+		} else {
+			steps = setE2EStep(steps, E2EStepCleanup, E2EStatusOK, "test listing closed")
+		}
+		run.Status = E2ERunPassed
+	}
+
+	if err := s.save(ctx, run, steps); err != nil {
+		return nil, err
+	}
+	return s.report(run, steps), nil
+}
+
+// verifySync runs an order sync and checks the stored order matches
+// Mercado Livre's.
+func (s *E2EService) verifySync(ctx context.Context, orders *OrderService, run *repository.E2ERun, steps []E2EStep) []E2EStep {
+	if _, err := orders.SyncOrders(ctx); err != nil {
+		return setE2EStep(steps, E2EStepOrderSync, E2EStatusFailed, err.Error())
+	}
+	upstream, err := s.meliClient.GetOrder(ctx, run.OrderID)
+	if err != nil {
+		return setE2EStep(steps, E2EStepOrderSync, E2EStatusFailed, err.Error())
+	}
+	stored, err := s.orderRepo.FindByID(ctx, run.OrderID)
+	switch {
+	case err != nil:
+		return setE2EStep(steps, E2EStepOrderSync, E2EStatusFailed, err.Error())
+	case stored == nil:
+		return setE2EStep(steps, E2EStepOrderSync, E2EStatusFailed, "the sync did not store the order")
+	case stored.Status != upstream.Status:
+		return setE2EStep(steps, E2EStepOrderSync, E2EStatusFailed, fmt.Sprintf("stored status %s, Mercado Livre has %s", stored.Status, upstream.Status))
+	}
+	return setE2EStep(steps, E2EStepOrderSync, E2EStatusOK, stored.Status)
+}
+
+// verifyAnalytics checks the sales analytics count the order once it is
+// paid; until then the step stays pending.
+func (s *E2EService) verifyAnalytics(ctx context.Context, orders *OrderService, run *repository.E2ERun, steps []E2EStep) []E2EStep {
+	stored, err := s.orderRepo.FindByID(ctx, run.OrderID)
+	if err != nil || stored == nil {
+		return setE2EStep(steps, E2EStepAnalytics, E2EStatusPending, "waiting for the order to be stored")
+	}
+	if stored.Status != repository.OrderStatusPaid {
+		return setE2EStep(steps, E2EStepAnalytics, E2EStatusPending, fmt.Sprintf("the order is %s; sales analytics only count paid orders", stored.Status))
+	}
+
+	day := stored.DateCreated.Truncate(24 * time.Hour)
+	analytics, err := orders.SalesAnalytics(ctx, "day", day, day.Add(24*time.Hour))
+	if err != nil {
+		return setE2EStep(steps, E2EStepAnalytics, E2EStatusFailed, err.Error())
+	}
+	if !slices.ContainsFunc(analytics.TopSKUs, func(sku TopSKU) bool { return sku.ItemID == run.ItemID }) {
+		return setE2EStep(steps, E2EStepAnalytics, E2EStatusFailed, "the sales analytics do not include the test listing")
+	}
+	return setE2EStep(steps, E2EStepAnalytics, E2EStatusOK, fmt.Sprintf("%d orders on %s", analytics.Totals.Orders, day.Format(time.DateOnly)))
+}
+
+func (s *E2EService) save(ctx context.Context, run *repository.E2ERun, steps []E2EStep) error {
+	body, err := json.Marshal(steps)
+	if err != nil {
+		return err
+	}
+	run.Steps = string(body)
+	return s.runRepo.Save(ctx, run)
+}
+
+func (s *E2EService) report(run *repository.E2ERun, steps []E2EStep) *E2EReport {
+	r := &E2EReport{
+		ID:        run.ID,
+		Status:    run.Status,
+		SellerID:  run.SellerID,
+		ItemID:    run.ItemID,
+		Permalink: run.Permalink,
+		OrderID:   run.OrderID,
+		Steps:     steps,
+		CreatedAt: run.CreatedAt,
+	}
+	if run.BuyerID != 0 {
+		r.Buyer = &E2EBuyer{ID: run.BuyerID, Nickname: run.BuyerNickname, Password: run.BuyerPassword}
+	}
+	if run.Status == E2ERunWaiting {
+		if run.OrderID == 0 {
+			r.NextAction = fmt.Sprintf("log in to Mercado Livre as %s, buy %s and verify the run again", run.BuyerNickname, run.Permalink)
+		} else {
+			r.NextAction = "pay the order as the buyer with a test card and verify the run again"
+		}
+	}
+	return r
+}
+
+// setE2EStep records the result of a step, replacing an earlier one.
+func setE2EStep(steps []E2EStep, name, status, detail string) []E2EStep {
+	step := E2EStep{Name: name, Status: status, Detail: detail, At: time.Now()}
+	if i := slices.IndexFunc(steps, func(st E2EStep) bool { return st.Name == name }); i >= 0 {
+		steps[i] = step
+		return steps
+	}
+	return append(steps, step)
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"melibot/pkg/meli"
+)
+
+const (
+	// entryReportListings is how many of the category's top search results
+	// the entry report samples.
+	entryReportListings = 200
+	// entryTopSellers is how many sellers the concentration is measured on.
+	entryTopSellers = 5
+	// logisticFulfillment is the logistic type of listings shipped by
+	// Mercado Livre's FULL warehouses.
+	logisticFulfillment = "fulfillment"
+)
+
+// certificationKeywords flag the attributes that carry a certification or
+// registration, e.g. ANATEL homologation or INMETRO and ANVISA numbers.
+var certificationKeywords = []string{"ANATEL", "INMETRO", "ANVISA", "CERTIF", "HOMOLOG", "REGIST"}
+
+// EntryAttribute is an attribute new listings of a category must, or may
+// have to, fill in.
+type EntryAttribute struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+}
+
+// ListingTypeShare is how many of the top-selling listings use a listing
+// type.
+type ListingTypeShare struct {
+	ListingType string  `json:"listing_type"`
+	Listings    int     `json:"listings"`
+	Share       float64 `json:"share"`
+}
+
+// EntryReport answers how hard it is to enter a category, from its listing
+// rules and a sample of its top search results. Shares are 0-1.
+// TopSellerShare is the part of the sample's sales made by the
+// entryTopSellers biggest sellers; HHI is the Herfindahl index of sales by
+// seller (0-10000). Difficulty is 0-100, see entryDifficulty.
+type EntryReport struct {
+	CategoryID           string             `json:"category_id"`
+	Name                 string             `json:"name"`
+	ListingAllowed       bool               `json:"listing_allowed"`
+	CatalogRequired      bool               `json:"catalog_required"`
+	ItemConditions       []string           `json:"item_conditions"`
+	MinimumPrice         float64            `json:"minimum_price,omitempty"`
+	ListingType          string             `json:"listing_type"`
+	ListingTypes         []ListingTypeShare `json:"listing_types"`
+	RequiredAttributes   []EntryAttribute   `json:"required_attributes"`
+	Certifications       []EntryAttribute   `json:"certifications"`
+	SampledListings      int                `json:"sampled_listings"`
+	TotalListings        int                `json:"total_listings"`
+	MedianPrice          float64            `json:"median_price"`
+	Sellers              int                `json:"sellers"`
+	TopSellerShare       float64            `json:"top_seller_share"`
+	HHI                  float64            `json:"hhi"`
+	FullShare            float64            `json:"full_share"`
+	TopListingsFullShare float64            `json:"top_listings_full_share"`
+	TopSellerHealth      *float64           `json:"top_seller_health"`
+	Difficulty           float64            `json:"difficulty"`
+	DifficultyLevel      string             `json:"difficulty_level"`
+	GeneratedAt          time.Time          `json:"generated_at"`
+}
+
+// EntryReport builds the entry report of a category: its listing rules and
+// required attributes, and the price, seller concentration, FULL share and
+// listing quality of its top search results.
+func (s *CategoryStatsService) EntryReport(ctx context.Context, categoryID string) (*EntryReport, error) {
+	category, err := s.meliClient.GetCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := s.meliClient.GetCategoryAttributes(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	listings, total, err := s.sampleListings(ctx, categoryID, entryReportListings)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &EntryReport{
+		CategoryID:         category.ID,
+		Name:               category.Name,
+		ListingAllowed:     category.Settings.ListingAllowed,
+		CatalogRequired:    category.CatalogRequired(),
+		ItemConditions:     category.Settings.ItemConditions,
+		MinimumPrice:       category.Settings.MinimumPrice,
+		ListingTypes:       []ListingTypeShare{},
+		RequiredAttributes: []EntryAttribute{},
+		Certifications:     []EntryAttribute{},
+		SampledListings:    len(listings),
+		TotalListings:      total,
+		GeneratedAt:        time.Now(),
+	}
+	for _, a := range attrs {
+		if a.Tags.Hidden || a.Tags.ReadOnly {
+			continue
+		}
+		required := a.Tags.Required || a.Tags.CatalogRequired
+		attr := EntryAttribute{ID: a.ID, Name: a.Name, Required: required}
+		if isCertification(a) {
+			attr.Required = required || a.Tags.ConditionalRequired
+			r.Certifications = append(r.Certifications, attr)
+		} else if required {
+			r.RequiredAttributes = append(r.RequiredAttributes, attr)
+		}
+	}
+
+	var prices []float64
+	soldBySeller := map[int64]int{}
+	soldTotal, full := 0, 0
+	for _, l := range listings {
+		if l.Price > 0 {
+			prices = append(prices, l.Price)
+		}
+		soldBySeller[l.Seller.ID] += l.SoldQuantity
+		soldTotal += l.SoldQuantity
+		if l.Shipping.LogisticType == logisticFulfillment {
+			full++
+		}
+	}
+	sort.Float64s(prices)
+	r.MedianPrice = roundCents(percentile(prices, 50))
+	r.Sellers = len(soldBySeller)
+	if len(listings) > 0 {
+		r.FullShare = roundShare(float64(full) / float64(len(listings)))
+	}
+	r.TopSellerShare, r.HHI = concentration(soldBySeller, soldTotal)
+
+	s.describeTopListings(ctx, r, listings)
+	r.Difficulty = entryDifficulty(r)
+	r.DifficultyLevel = difficultyLevel(r.Difficulty)
+	return r, nil
+}
+
+// describeTopListings fills what the best-selling listings of the sample
+// show: their listing types, the most used one being the one to match,
+// their FULL share and their average health. Failing to fetch them leaves
+// those fields empty.
+func (s *CategoryStatsService) describeTopListings(ctx context.Context, r *EntryReport, listings []meli.CategorySearchResult) {
+	top := append([]meli.CategorySearchResult(nil), listings...)
+	sort.SliceStable(top, func(i, j int) bool { return top[i].SoldQuantity > top[j].SoldQuantity })
+	top = top[:min(topSellerSample, len(top))]
+	if len(top) == 0 {
+		return
+	}
+
+	full := 0
+	ids := make([]string, 0, len(top))
+	for _, l := range top {
+		ids = append(ids, l.ID)
+		if l.Shipping.LogisticType == logisticFulfillment {
+			full++
+		}
+	}
+	r.TopListingsFullShare = roundShare(float64(full) / float64(len(top)))
+
+	items, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil || len(items) == 0 {
+		return
+	}
+	types := map[string]int{}
+	var health float64
+	rated := 0
+	for _, it := range items {
+		if it.ListingType != "" {
+			types[it.ListingType]++
+		}
+		if it.Health != nil {
+			health += *it.Health
+			rated++
+		}
+	}
+	for t, n := range types {
+		r.ListingTypes = append(r.ListingTypes, ListingTypeShare{ListingType: t, Listings: n, Share: roundShare(float64(n) / float64(len(items)))})
+	}
+	sort.Slice(r.ListingTypes, func(i, j int) bool {
+		a, b := r.ListingTypes[i], r.ListingTypes[j]
+		if a.Listings != b.Listings {
+			return a.Listings > b.Listings
+		}
+		return a.ListingType < b.ListingType
+	})
+	if len(r.ListingTypes) > 0 {
+		r.ListingType = r.ListingTypes[0].ListingType
+	}
+	if rated > 0 {
+		avg := roundShare(health / float64(rated))
+		r.TopSellerHealth = &avg
+	}
+}
+
+func isCertification(a meli.CategoryAttribute) bool {
+	id, name := strings.ToUpper(a.ID), strings.ToUpper(a.Name)
+	for _, kw := range certificationKeywords {
+		if strings.Contains(id, kw) || strings.Contains(name, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// concentration returns the share of sales made by the entryTopSellers
+// biggest sellers and the Herfindahl index of sales by seller.
+func concentration(soldBySeller map[int64]int, soldTotal int) (topShare, hhi float64) {
+	if soldTotal == 0 {
+		return 0, 0
+	}
+	sold := make([]int, 0, len(soldBySeller))
+	for _, n := range soldBySeller {
+		sold = append(sold, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sold)))
+	top := 0
+	for i, n := range sold {
+		share := float64(n) / float64(soldTotal) * 100
+		hhi += share * share
+		if i < entryTopSellers {
+			top += n
+		}
+	}
+	return roundShare(float64(top) / float64(soldTotal)), math.Round(hhi)
+}
+
+// entryDifficulty scores how hard a category is to enter, 0-100: seller
+// concentration weighs 30, FULL among the top listings 20, the health of
+// the top listings 20, certifications 20 and a required catalog 10.
+func entryDifficulty(r *EntryReport) float64 {
+	score := r.TopSellerShare*30 + r.TopListingsFullShare*20
+	if r.TopSellerHealth != nil {
+		score += *r.TopSellerHealth * 20
+	}
+	for _, c := range r.Certifications {
+		if c.Required {
+			score += 20
+			break
+		}
+	}
+	if r.CatalogRequired {
+		score += 10
+	}
+	return math.Round(score)
+}
+
+func difficultyLevel(score float64) string {
+	switch {
+	case score < 35:
+		return "low"
+	case score < 65:
+		return "medium"
+	default:
+		return "high"
+	}
+}
+
+func roundShare(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
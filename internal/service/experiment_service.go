@@ -0,0 +1,602 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+const (
+	maxExperimentVariants = 6
+	maxExperimentCycles   = 4
+	maxPhaseDays          = 30
+	defaultPhaseDays      = 7
+	// titleExperimentVariants is how many titles an A/B test rotates.
+	titleExperimentVariants = 2
+	// minSignificanceOrders is how many orders each variant compared needs
+	// before the normal approximation of the tests is trusted.
+	minSignificanceOrders = 5
+	// significanceLevel is the p-value under which a difference counts.
+	significanceLevel = 0.05
+	// maxPriceStepPct bounds the price steps of an experiment, in percent
+	// of the current price.
+	maxPriceStepPct = 50
+)
+
+var (
+	ErrExperimentNotFound    = errors.New("experiment not found")
+	ErrExperimentRunning     = errors.New("the listing already has a running experiment")
+	ErrExperimentNotRunning  = errors.New("the experiment is not running")
+	ErrExperimentInactive    = errors.New("the listing must be active to run an experiment")
+	ErrExperimentVariations  = errors.New("price experiments do not support listings with variations")
+	ErrExperimentTitleLocked = errors.New("the title of a listing with sales cannot be changed")
+)
+
+// ExperimentVariant is what the listing shows during the phases of a
+// variant: a price or a title, depending on the kind of experiment.
+type ExperimentVariant struct {
+	Price float64 `json:"price,omitempty"`
+	Title string  `json:"title,omitempty"`
+}
+
+// NewExperiment is a request to start an experiment. Price experiments take
+// either absolute prices or steps in percent of the current price, e.g.
+// [-5, 0, 5]; title experiments take the two titles to A/B test. Each
+// variant is live for PhaseDays, Cycles times over.
+type NewExperiment struct {
+	ItemID        string    `json:"item_id"`
+	Kind          string    `json:"kind"`
+	Prices        []float64 `json:"prices"`
+	PriceStepsPct []float64 `json:"price_steps_pct"`
+	Titles        []string  `json:"titles"`
+	PhaseDays     int       `json:"phase_days"`
+	Cycles        int       `json:"cycles"`
+}
+
+// ValidateExperiment checks an experiment request and fills in its
+// defaults.
+func ValidateExperiment(e *NewExperiment) error {
+	if e.ItemID == "" {
+		return errors.New("item_id is required")
+	}
+	if e.Kind == "" {
+		e.Kind = repository.ExperimentKindPrice
+	}
+	switch e.Kind {
+	case repository.ExperimentKindPrice:
+		if err := validatePriceVariants(e); err != nil {
+			return err
+		}
+	case repository.ExperimentKindTitle:
+		if err := validateTitleVariants(e); err != nil {
+			return err
+		}
+	default:
+		return errors.New("kind must be price or title")
+	}
+	if e.PhaseDays == 0 {
+		e.PhaseDays = defaultPhaseDays
+	}
+	if e.PhaseDays < 1 || e.PhaseDays > maxPhaseDays {
+		return fmt.Errorf("phase_days must be between 1 and %d", maxPhaseDays)
+	}
+	if e.Cycles == 0 {
+		e.Cycles = 1
+	}
+	if e.Cycles < 1 || e.Cycles > maxExperimentCycles {
+		return fmt.Errorf("cycles must be between 1 and %d", maxExperimentCycles)
+	}
+	return nil
+}
+
+func validatePriceVariants(e *NewExperiment) error {
+	if len(e.Titles) > 0 {
+		return errors.New("titles are only for title experiments")
+	}
+	if (len(e.Prices) == 0) == (len(e.PriceStepsPct) == 0) {
+		return errors.New("set either prices or price_steps_pct")
+	}
+	if n := len(e.Prices) + len(e.PriceStepsPct); n < 2 || n > maxExperimentVariants {
+		return fmt.Errorf("an experiment needs between 2 and %d variants", maxExperimentVariants)
+	}
+	for _, p := range e.Prices {
+		if p <= 0 {
+			return errors.New("prices must be positive")
+		}
+	}
+	for _, step := range e.PriceStepsPct {
+		if math.Abs(step) > maxPriceStepPct {
+			return fmt.Errorf("price steps must be between -%d%% and %d%%", maxPriceStepPct, maxPriceStepPct)
+		}
+	}
+	return nil
+}
+
+func validateTitleVariants(e *NewExperiment) error {
+	if len(e.Prices) > 0 || len(e.PriceStepsPct) > 0 {
+		return errors.New("prices are only for price experiments")
+	}
+	if len(e.Titles) != titleExperimentVariants {
+		return fmt.Errorf("a title experiment needs %d titles", titleExperimentVariants)
+	}
+	for i, title := range e.Titles {
+		title = strings.TrimSpace(title)
+		if title == "" {
+			return errors.New("titles must not be empty")
+		}
+		if utf8.RuneCountInString(title) > maxTitleLength {
+			return fmt.Errorf("titles must be at most %d characters", maxTitleLength)
+		}
+		e.Titles[i] = title
+	}
+	if strings.EqualFold(e.Titles[0], e.Titles[1]) {
+		return errors.New("the titles must differ")
+	}
+	return nil
+}
+
+// Experiment is an experiment as the API shows it. NextChangeAt is when the
+// running phase ends.
+type Experiment struct {
+	ID             uint                `json:"id"`
+	ItemID         string              `json:"item_id"`
+	Kind           string              `json:"kind"`
+	Status         string              `json:"status"`
+	Variants       []ExperimentVariant `json:"variants"`
+	Original       ExperimentVariant   `json:"original"`
+	PhaseDays      float64             `json:"phase_days"`
+	Cycles         int                 `json:"cycles"`
+	Phase          int                 `json:"phase"`
+	Phases         int                 `json:"phases"`
+	CurrentVariant *int                `json:"current_variant"`
+	NextChangeAt   *time.Time          `json:"next_change_at"`
+	Error          string              `json:"error,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	EndedAt        *time.Time          `json:"ended_at"`
+}
+
+// VariantResult is what a variant achieved over its phases. Rates are
+// percentages; VisitsKnown is false when Mercado Livre did not report the
+// visits of some phase.
+type VariantResult struct {
+	Variant         int               `json:"variant"`
+	Value           ExperimentVariant `json:"value"`
+	Days            float64           `json:"days"`
+	Visits          int               `json:"visits"`
+	VisitsKnown     bool              `json:"visits_known"`
+	Orders          int               `json:"orders"`
+	Units           int               `json:"units"`
+	Revenue         float64           `json:"revenue"`
+	ConversionPct   float64           `json:"conversion_pct"`
+	RevenuePerDay   float64           `json:"revenue_per_day"`
+	RevenuePerVisit float64           `json:"revenue_per_visit"`
+}
+
+// SignificanceTest compares the best variant with another on a metric:
+// orders_per_day (a test of Poisson rates) or conversion (a test of two
+// proportions, when the visits are known). Significant needs
+// minSignificanceOrders orders on both sides and a two-sided p-value under
+// significanceLevel.
+type SignificanceTest struct {
+	Metric      string  `json:"metric"`
+	Against     int     `json:"against"`
+	ZScore      float64 `json:"z_score"`
+	PValue      float64 `json:"p_value"`
+	Significant bool    `json:"significant"`
+}
+
+// ExperimentReport compares the variants of an experiment. Best is the
+// variant with the most revenue per day, and Significance tests it against
+// the runner-up. Elasticity is the arc elasticity of units per day between
+// the lowest and highest price, for price experiments with sales at both.
+type ExperimentReport struct {
+	Experiment
+	Results      []VariantResult    `json:"results"`
+	Best         *int               `json:"best"`
+	Significance []SignificanceTest `json:"significance"`
+	Elasticity   *float64           `json:"elasticity,omitempty"`
+}
+
+// ExperimentService runs experiments on the seller's listings: it rotates
+// the variants on schedule, restores the listing afterwards and reports
+// visits and sales per variant from the visits API and the synced orders.
+type ExperimentService struct {
+	meliClient *meli.MeliClient
+	repo       *repository.ExperimentRepository
+	orderRepo  *repository.OrderRepository
+}
+
+func NewExperimentService(meliClient *meli.MeliClient, repo *repository.ExperimentRepository, orderRepo *repository.OrderRepository) *ExperimentService {
+	return &ExperimentService{
+		meliClient: meliClient,
+		repo:       repo,
+		orderRepo:  orderRepo,
+	}
+}
+
+// Experiments lists the experiments, of one listing when itemID is set.
+func (s *ExperimentService) Experiments(ctx context.Context, itemID string) ([]Experiment, error) {
+	rows, err := s.repo.List(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Experiment, 0, len(rows))
+	for i := range rows {
+		e, err := experimentFromRow(&rows[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *e)
+	}
+	return out, nil
+}
+
+// Start puts the first variant live on one of the seller's active listings
+// and starts its first phase.
+func (s *ExperimentService) Start(ctx context.Context, req *NewExperiment) (*Experiment, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.meliClient.GetItem(ctx, req.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	if int64(item.SellerID) != me.ID {
+		return nil, ErrNotOwnItem
+	}
+	if item.Status != meli.ItemStatusActive {
+		return nil, ErrExperimentInactive
+	}
+	running, err := s.repo.RunningFor(ctx, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if running {
+		return nil, ErrExperimentRunning
+	}
+
+	var variants []ExperimentVariant
+	var original ExperimentVariant
+	switch req.Kind {
+	case repository.ExperimentKindPrice:
+		if len(item.Variations) > 0 {
+			return nil, ErrExperimentVariations
+		}
+		original.Price = item.Price
+		for _, p := range req.Prices {
+			variants = append(variants, ExperimentVariant{Price: roundCents(p)})
+		}
+		for _, step := range req.PriceStepsPct {
+			variants = append(variants, ExperimentVariant{Price: roundCents(item.Price * (1 + step/100))})
+		}
+	case repository.ExperimentKindTitle:
+		if item.SoldQty > 0 {
+			return nil, ErrExperimentTitleLocked
+		}
+		original.Title = item.Title
+		for _, title := range req.Titles {
+			variants = append(variants, ExperimentVariant{Title: title})
+		}
+	}
+	variantsJSON, err := json.Marshal(variants)
+	if err != nil {
+		return nil, err
+	}
+	originalJSON, err := json.Marshal(original)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.apply(ctx, req.Kind, item.ID, variants[0]); err != nil {
+		return nil, err
+	}
+	row := &repository.Experiment{
+		ItemID:        item.ID,
+		Kind:          req.Kind,
+		Status:        repository.ExperimentStatusRunning,
+		Variants:      string(variantsJSON),
+		Original:      string(originalJSON),
+		PhaseDuration: time.Duration(req.PhaseDays) * 24 * time.Hour,
+		Cycles:        req.Cycles,
+		Phases:        []repository.ExperimentPhase{{Variant: 0, StartedAt: time.Now()}},
+	}
+	if err := s.repo.Save(ctx, row); err != nil {
+		return nil, err
+	}
+	return experimentFromRow(row)
+}
+
+// Stop ends a running experiment and restores the listing.
+func (s *ExperimentService) Stop(ctx context.Context, id uint) (*Experiment, error) {
+	row, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, ErrExperimentNotFound
+	}
+	if row.Status != repository.ExperimentStatusRunning {
+		return nil, ErrExperimentNotRunning
+	}
+	if err := s.finish(ctx, row, repository.ExperimentStatusStopped, time.Now()); err != nil {
+		return nil, err
+	}
+	return experimentFromRow(row)
+}
+
+// Advance moves the running experiments whose phase is over to their next
+// variant, and restores the listings of those that went through all of
+// them. Listings that cannot be changed are retried on the next run.
+func (s *ExperimentService) Advance(ctx context.Context) error {
+	rows, err := s.repo.Running(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	failed := 0
+	for i := range rows {
+		if err := s.advance(ctx, &rows[i], now); err != nil {
+			log.Printf("[WARN] Experiment %d on %s failed to advance: %v", rows[i].ID, rows[i].ItemID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("experiments: %d of %d failed to advance", failed, len(rows))
+	}
+	return nil
+}
+
+func (s *ExperimentService) advance(ctx context.Context, row *repository.Experiment, now time.Time) error {
+	phase := currentPhase(row)
+	if phase == nil || now.Before(phase.StartedAt.Add(row.PhaseDuration)) {
+		return nil
+	}
+	var variants []ExperimentVariant
+	if err := json.Unmarshal([]byte(row.Variants), &variants); err != nil {
+		return err
+	}
+	if row.Phase+1 >= len(variants)*row.Cycles {
+		return s.finish(ctx, row, repository.ExperimentStatusCompleted, now)
+	}
+
+	next := (row.Phase + 1) % len(variants)
+	if err := s.apply(ctx, row.Kind, row.ItemID, variants[next]); err != nil {
+		row.Error = err.Error()
+		if saveErr := s.repo.Save(ctx, row); saveErr != nil {
+			log.Printf("[ERROR] Failed to record the error of experiment %d: %v", row.ID, saveErr)
+		}
+		return err
+	}
+	phase.EndedAt = &now
+	row.Phase++
+	row.Error = ""
+	row.Phases = append(row.Phases, repository.ExperimentPhase{ExperimentID: row.ID, Variant: next, StartedAt: now})
+	return s.repo.Save(ctx, row)
+}
+
+// finish restores the listing and closes the experiment.
+func (s *ExperimentService) finish(ctx context.Context, row *repository.Experiment, status string, now time.Time) error {
+	var original ExperimentVariant
+	if err := json.Unmarshal([]byte(row.Original), &original); err != nil {
+		return err
+	}
+	if err := s.apply(ctx, row.Kind, row.ItemID, original); err != nil {
+		return err
+	}
+	if phase := currentPhase(row); phase != nil {
+		phase.EndedAt = &now
+	}
+	row.Status, row.EndedAt, row.Error = status, &now, ""
+	return s.repo.Save(ctx, row)
+}
+
+// apply puts a variant live on the listing.
+func (s *ExperimentService) apply(ctx context.Context, kind, itemID string, v ExperimentVariant) error {
+	switch kind {
+	case repository.ExperimentKindPrice:
+		return s.meliClient.SetItemPrice(ctx, itemID, v.Price)
+	case repository.ExperimentKindTitle:
+		return s.meliClient.SetItemTitle(ctx, itemID, v.Title)
+	}
+	return fmt.Errorf("unknown experiment kind %q", kind)
+}
+
+// Report compares the variants of an experiment over the phases run so
+// far, the running one up to now.
+func (s *ExperimentService) Report(ctx context.Context, id uint) (*ExperimentReport, error) {
+	row, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, ErrExperimentNotFound
+	}
+	e, err := experimentFromRow(row)
+	if err != nil {
+		return nil, err
+	}
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]VariantResult, len(e.Variants))
+	for i, v := range e.Variants {
+		results[i] = VariantResult{Variant: i, Value: v, VisitsKnown: true}
+	}
+	now := time.Now()
+	for _, phase := range row.Phases {
+		if phase.Variant >= len(results) {
+			continue
+		}
+		r := &results[phase.Variant]
+		end := now
+		if phase.EndedAt != nil {
+			end = *phase.EndedAt
+		}
+		r.Days += end.Sub(phase.StartedAt).Hours() / 24
+
+		perf, err := s.orderRepo.ItemPerformance(ctx, me.ID, row.ItemID, phase.StartedAt, end)
+		if err != nil {
+			return nil, err
+		}
+		r.Orders += perf.Orders
+		r.Units += perf.Units
+		r.Revenue += perf.Revenue
+
+		visits, err := s.meliClient.GetItemVisits(ctx, row.ItemID, phase.StartedAt, end)
+		if err != nil {
+			log.Printf("[WARN] Visits of %s for experiment %d unavailable: %v", row.ItemID, row.ID, err)
+			r.VisitsKnown = false
+			continue
+		}
+		r.Visits += visits
+	}
+
+	report := &ExperimentReport{Experiment: *e, Results: results}
+	for i := range results {
+		r := &results[i]
+		r.Days = math.Round(r.Days*10) / 10
+		r.Revenue = roundCents(r.Revenue)
+		if r.Visits > 0 {
+			r.ConversionPct = roundPct(float64(r.Orders) / float64(r.Visits))
+			r.RevenuePerVisit = roundCents(r.Revenue / float64(r.Visits))
+		}
+		if r.Days > 0 {
+			r.RevenuePerDay = roundCents(r.Revenue / r.Days)
+			if report.Best == nil || r.RevenuePerDay > results[*report.Best].RevenuePerDay {
+				report.Best = &r.Variant
+			}
+		}
+	}
+	report.Significance = significance(results, report.Best)
+	if row.Kind == repository.ExperimentKindPrice {
+		report.Elasticity = priceElasticity(results)
+	}
+	return report, nil
+}
+
+// significance tests the best variant against the runner-up by revenue per
+// day.
+func significance(results []VariantResult, best *int) []SignificanceTest {
+	tests := []SignificanceTest{}
+	if best == nil {
+		return tests
+	}
+	var other *VariantResult
+	for i := range results {
+		r := &results[i]
+		if r.Variant == *best || r.Days <= 0 {
+			continue
+		}
+		if other == nil || r.RevenuePerDay > other.RevenuePerDay {
+			other = r
+		}
+	}
+	if other == nil {
+		return tests
+	}
+	a, b := &results[*best], other
+	enough := a.Orders >= minSignificanceOrders && b.Orders >= minSignificanceOrders
+
+	// Orders per day, as Poisson rates over each variant's days.
+	rateA, rateB := float64(a.Orders)/a.Days, float64(b.Orders)/b.Days
+	if se := math.Sqrt(rateA/a.Days + rateB/b.Days); se > 0 {
+		tests = append(tests, significanceTest("orders_per_day", b.Variant, (rateA-rateB)/se, enough))
+	}
+	// Conversion, as two proportions of the visits.
+	if a.VisitsKnown && b.VisitsKnown && a.Visits > 0 && b.Visits > 0 {
+		pA, pB := float64(a.Orders)/float64(a.Visits), float64(b.Orders)/float64(b.Visits)
+		pooled := float64(a.Orders+b.Orders) / float64(a.Visits+b.Visits)
+		if se := math.Sqrt(pooled * (1 - pooled) * (1/float64(a.Visits) + 1/float64(b.Visits))); se > 0 {
+			tests = append(tests, significanceTest("conversion", b.Variant, (pA-pB)/se, enough))
+		}
+	}
+	return tests
+}
+
+func significanceTest(metric string, against int, z float64, enough bool) SignificanceTest {
+	p := math.Erfc(math.Abs(z) / math.Sqrt2)
+	return SignificanceTest{
+		Metric:      metric,
+		Against:     against,
+		ZScore:      math.Round(z*100) / 100,
+		PValue:      math.Round(p*10000) / 10000,
+		Significant: enough && p < significanceLevel,
+	}
+}
+
+// priceElasticity is the arc elasticity of units per day between the
+// lowest and the highest price tried, nil without sales at both.
+func priceElasticity(results []VariantResult) *float64 {
+	var low, high *VariantResult
+	for i := range results {
+		r := &results[i]
+		if r.Days <= 0 {
+			continue
+		}
+		if low == nil || r.Value.Price < low.Value.Price {
+			low = r
+		}
+		if high == nil || r.Value.Price > high.Value.Price {
+			high = r
+		}
+	}
+	if low == nil || high == nil || low.Value.Price == high.Value.Price || low.Units == 0 || high.Units == 0 {
+		return nil
+	}
+	qLow, qHigh := float64(low.Units)/low.Days, float64(high.Units)/high.Days
+	dq := (qHigh - qLow) / ((qHigh + qLow) / 2)
+	dp := (high.Value.Price - low.Value.Price) / ((high.Value.Price + low.Value.Price) / 2)
+	e := math.Round(dq/dp*100) / 100
+	return &e
+}
+
+// currentPhase is the running phase of an experiment, nil once it ended.
+func currentPhase(row *repository.Experiment) *repository.ExperimentPhase {
+	for i := len(row.Phases) - 1; i >= 0; i-- {
+		if row.Phases[i].EndedAt == nil {
+			return &row.Phases[i]
+		}
+	}
+	return nil
+}
+
+func experimentFromRow(row *repository.Experiment) (*Experiment, error) {
+	e := &Experiment{
+		ID:        row.ID,
+		ItemID:    row.ItemID,
+		Kind:      row.Kind,
+		Status:    row.Status,
+		PhaseDays: row.PhaseDuration.Hours() / 24,
+		Cycles:    row.Cycles,
+		Phase:     row.Phase,
+		Error:     row.Error,
+		CreatedAt: row.CreatedAt,
+		EndedAt:   row.EndedAt,
+	}
+	if err := json.Unmarshal([]byte(row.Variants), &e.Variants); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(row.Original), &e.Original); err != nil {
+		return nil, err
+	}
+	e.Phases = len(e.Variants) * row.Cycles
+	if phase := currentPhase(row); phase != nil {
+		variant := phase.Variant
+		next := phase.StartedAt.Add(row.PhaseDuration)
+		e.CurrentVariant, e.NextChangeAt = &variant, &next
+	}
+	return e, nil
+}
@@ -0,0 +1,578 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"melibot/internal/parquet"
+	"melibot/internal/repository"
+	"melibot/internal/storage"
+	"melibot/pkg/meli"
+)
+
+// Export kinds.
+const (
+	ExportProductTrends    = "product_trends"
+	ExportOrders           = "orders"
+	ExportCategoryListings = "category_listings"
+	ExportTrendSnapshots   = "trend_snapshots"
+	ExportPriceHistory     = "price_history"
+)
+
+const (
+	// exportKeyPrefix is where exports are stored in the bucket.
+	exportKeyPrefix = "exports/"
+	// exportOrderBatch is how many orders an export loads at a time.
+	exportOrderBatch = 500
+	// exportSnapshotBatch is how many trend snapshots an export loads at a
+	// time.
+	exportSnapshotBatch = 5000
+	// firstExportYear bounds ?year= of order exports.
+	firstExportYear = 2000
+)
+
+var (
+	// ErrUnsupportedExportFormat is returned for formats other than csv,
+	// json and parquet.
+	ErrUnsupportedExportFormat = errors.New("format must be csv, json or parquet")
+	ErrUnsupportedExportKind   = errors.New("kind must be product_trends, trend_snapshots, price_history, orders or category_listings")
+	ErrExportCategoryRequired  = errors.New("category_id is required")
+	ErrInvalidExportYear       = errors.New("year must be between 2000 and the current year")
+	ErrInvalidExportDays       = errors.New("days must not be negative")
+	// ErrExportStorageDisabled is returned when no bucket is configured.
+	ErrExportStorageDisabled = errors.New("export storage is not configured")
+)
+
+// ExportedProduct is a stored product trend as written by WriteProductTrends.
+type ExportedProduct struct {
+	ProductID           string    `json:"product_id"`
+	Title               string    `json:"title"`
+	CategoryID          string    `json:"category_id"`
+	HighlightCategoryID string    `json:"highlight_category_id"`
+	Rank                int       `json:"rank"`
+	SoldQuantity        int       `json:"sold_quantity"`
+	Price               float64   `json:"price"`
+	Health              string    `json:"health"`
+	Permalink           string    `json:"permalink"`
+	CapturedAt          time.Time `json:"captured_at"`
+}
+
+// WriteProductTrends writes trends as CSV (with a header), as a JSON array
+// or as Parquet.
+func WriteProductTrends(w io.Writer, format string, trends []repository.ProductTrend) error {
+	products := exportedProducts(trends)
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(products)
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(productHeader); err != nil {
+			return err
+		}
+		for _, p := range products {
+			if err := cw.Write(productRecord(p)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "parquet":
+		return writeParquet(w, products)
+	}
+	return ErrUnsupportedExportFormat
+}
+
+func exportedProducts(trends []repository.ProductTrend) []ExportedProduct {
+	products := make([]ExportedProduct, 0, len(trends))
+	for _, t := range trends {
+		products = append(products, ExportedProduct{
+			ProductID:           t.ProductID,
+			Title:               t.Title,
+			CategoryID:          t.CategoryID,
+			HighlightCategoryID: t.HighlightCategoryID,
+			Rank:                t.Rank,
+			SoldQuantity:        t.SoldQuantity,
+			Price:               t.Price,
+			Health:              t.Health,
+			Permalink:           t.Permalink,
+			CapturedAt:          t.CreatedAt,
+		})
+	}
+	return products
+}
+
+// ExportedListing is a category listing as written by category exports.
+type ExportedListing struct {
+	ID                string  `json:"id"`
+	Title             string  `json:"title"`
+	CatalogProductID  string  `json:"catalog_product_id"`
+	SellerID          int64   `json:"seller_id"`
+	Price             float64 `json:"price"`
+	SoldQuantity      int     `json:"sold_quantity"`
+	AvailableQuantity int     `json:"available_quantity"`
+	FreeShipping      bool    `json:"free_shipping"`
+	LogisticType      string  `json:"logistic_type"`
+}
+
+// WriteListings writes crawled listings as CSV (with a header), as a JSON
+// array or as Parquet.
+func WriteListings(w io.Writer, format string, listings []meli.CategorySearchResult) error {
+	out := make([]ExportedListing, 0, len(listings))
+	for _, l := range listings {
+		out = append(out, ExportedListing{
+			ID:                l.ID,
+			Title:             l.Title,
+			CatalogProductID:  l.CatalogProductID,
+			SellerID:          l.Seller.ID,
+			Price:             l.Price,
+			SoldQuantity:      l.SoldQuantity,
+			AvailableQuantity: l.AvailableQuantity,
+			FreeShipping:      l.Shipping.FreeShipping,
+			LogisticType:      l.Shipping.LogisticType,
+		})
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case "csv":
+		cw := csv.NewWriter(w)
+		header := []string{"id", "title", "catalog_product_id", "seller_id", "price", "sold_quantity",
+			"available_quantity", "free_shipping", "logistic_type"}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, l := range out {
+			rec := []string{l.ID, l.Title, l.CatalogProductID, strconv.FormatInt(l.SellerID, 10),
+				strconv.FormatFloat(l.Price, 'f', 2, 64), strconv.Itoa(l.SoldQuantity), strconv.Itoa(l.AvailableQuantity),
+				strconv.FormatBool(l.FreeShipping), l.LogisticType}
+			if err := cw.Write(rec); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "parquet":
+		return writeParquet(w, out)
+	}
+	return ErrUnsupportedExportFormat
+}
+
+// ExportedOrderLine is an order line as written by order exports, with the
+// fields of its order.
+type ExportedOrderLine struct {
+	OrderID       int64     `json:"order_id"`
+	DateCreated   time.Time `json:"date_created"`
+	Status        string    `json:"status"`
+	BuyerNickname string    `json:"buyer_nickname"`
+	Currency      string    `json:"currency"`
+	TotalAmount   float64   `json:"total_amount"`
+	ShippingCost  float64   `json:"shipping_cost"`
+	ItemID        string    `json:"item_id"`
+	Title         string    `json:"title"`
+	SKU           string    `json:"sku"`
+	Quantity      int       `json:"quantity"`
+	UnitPrice     float64   `json:"unit_price"`
+	SaleFee       float64   `json:"sale_fee"`
+}
+
+// orderLines flattens orders into one ExportedOrderLine per line.
+func orderLines(orders []repository.Order) []ExportedOrderLine {
+	var lines []ExportedOrderLine
+	for _, o := range orders {
+		for _, it := range o.Items {
+			lines = append(lines, ExportedOrderLine{
+				OrderID:       o.ID,
+				DateCreated:   o.DateCreated,
+				Status:        o.Status,
+				BuyerNickname: o.BuyerNickname,
+				Currency:      o.Currency,
+				TotalAmount:   o.TotalAmount,
+				ShippingCost:  o.ShippingCost,
+				ItemID:        it.ItemID,
+				Title:         it.Title,
+				SKU:           it.SKU,
+				Quantity:      it.Quantity,
+				UnitPrice:     it.UnitPrice,
+				SaleFee:       it.SaleFee,
+			})
+		}
+	}
+	return lines
+}
+
+var orderLineHeader = []string{"order_id", "date_created", "status", "buyer_nickname", "currency", "total_amount",
+	"shipping_cost", "item_id", "title", "sku", "quantity", "unit_price", "sale_fee"}
+
+func orderLineRecord(l ExportedOrderLine) []string {
+	return []string{strconv.FormatInt(l.OrderID, 10), l.DateCreated.UTC().Format(time.RFC3339), l.Status,
+		l.BuyerNickname, l.Currency, strconv.FormatFloat(l.TotalAmount, 'f', 2, 64),
+		strconv.FormatFloat(l.ShippingCost, 'f', 2, 64), l.ItemID, l.Title, l.SKU, strconv.Itoa(l.Quantity),
+		strconv.FormatFloat(l.UnitPrice, 'f', 2, 64), strconv.FormatFloat(l.SaleFee, 'f', 2, 64)}
+}
+
+var productHeader = []string{"product_id", "title", "category_id", "highlight_category_id", "rank",
+	"sold_quantity", "price", "health", "permalink", "captured_at"}
+
+func productRecord(p ExportedProduct) []string {
+	return []string{p.ProductID, p.Title, p.CategoryID, p.HighlightCategoryID, strconv.Itoa(p.Rank),
+		strconv.Itoa(p.SoldQuantity), strconv.FormatFloat(p.Price, 'f', 2, 64), p.Health, p.Permalink,
+		p.CapturedAt.UTC().Format(time.RFC3339)}
+}
+
+// ExportedDailyPrice is a product's price over a day of snapshots, as
+// written by price history exports.
+type ExportedDailyPrice struct {
+	ProductID string    `json:"product_id"`
+	Day       time.Time `json:"day"`
+	MinPrice  float64   `json:"min_price"`
+	MaxPrice  float64   `json:"max_price"`
+	AvgPrice  float64   `json:"avg_price"`
+	Snapshots int       `json:"snapshots"`
+}
+
+var dailyPriceHeader = []string{"product_id", "day", "min_price", "max_price", "avg_price", "snapshots"}
+
+func dailyPriceRecord(p ExportedDailyPrice) []string {
+	return []string{p.ProductID, p.Day.Format(time.DateOnly), strconv.FormatFloat(p.MinPrice, 'f', 2, 64),
+		strconv.FormatFloat(p.MaxPrice, 'f', 2, 64), strconv.FormatFloat(p.AvgPrice, 'f', 2, 64), strconv.Itoa(p.Snapshots)}
+}
+
+// rowWriter writes rows as CSV, a JSON array or Parquet as batches of
+// them come in, so large exports don't hold every row in memory. Close
+// finishes the output.
+type rowWriter[T any] struct {
+	w       io.Writer
+	csv     *csv.Writer
+	record  func(T) []string
+	parquet *parquet.Writer[T]
+	rows    int
+}
+
+func newRowWriter[T any](w io.Writer, format string, header []string, record func(T) []string) (*rowWriter[T], error) {
+	rw := &rowWriter[T]{w: w, record: record}
+	switch format {
+	case "json":
+		_, err := io.WriteString(w, "[")
+		return rw, err
+	case "csv":
+		rw.csv = csv.NewWriter(w)
+		return rw, rw.csv.Write(header)
+	case "parquet":
+		pw, err := parquet.NewWriter[T](w)
+		rw.parquet = pw
+		return rw, err
+	}
+	return nil, ErrUnsupportedExportFormat
+}
+
+func (rw *rowWriter[T]) Write(rows ...T) error {
+	for _, r := range rows {
+		if err := rw.write(r); err != nil {
+			return err
+		}
+		rw.rows++
+	}
+	return nil
+}
+
+func (rw *rowWriter[T]) write(r T) error {
+	switch {
+	case rw.csv != nil:
+		return rw.csv.Write(rw.record(r))
+	case rw.parquet != nil:
+		return rw.parquet.Write(r)
+	}
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	sep := ",\n  "
+	if rw.rows == 0 {
+		sep = "\n  "
+	}
+	_, err = io.WriteString(rw.w, sep+string(body))
+	return err
+}
+
+func (rw *rowWriter[T]) Close() error {
+	switch {
+	case rw.csv != nil:
+		rw.csv.Flush()
+		return rw.csv.Error()
+	case rw.parquet != nil:
+		return rw.parquet.Close()
+	}
+	_, err := io.WriteString(rw.w, "\n]\n")
+	return err
+}
+
+// writeParquet writes rows as a Parquet file.
+func writeParquet[T any](w io.Writer, rows []T) error {
+	pw, err := parquet.NewWriter[T](w)
+	if err != nil {
+		return err
+	}
+	if err := pw.Write(rows...); err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// ExportRequest describes an export job. Year applies to orders, whose
+// export covers one calendar year; Days limits trend snapshots and price
+// history to the last days (0 = all); CategoryID is required for category
+// listings and narrows the trend kinds; Limit caps product trends.
+type ExportRequest struct {
+	Kind       string `json:"kind"`
+	Format     string `json:"format"`
+	CategoryID string `json:"category_id,omitempty"`
+	Year       int    `json:"year,omitempty"`
+	Days       int    `json:"days,omitempty"`
+	Limit      int    `json:"limit,omitempty"`
+}
+
+// Validate checks the request and fills in its defaults: CSV, and the
+// current year for orders.
+func (r *ExportRequest) Validate() error {
+	if r.Format == "" {
+		r.Format = "csv"
+	}
+	if !IsExportFormat(r.Format) {
+		return ErrUnsupportedExportFormat
+	}
+	if r.Days < 0 {
+		return ErrInvalidExportDays
+	}
+	switch r.Kind {
+	case ExportProductTrends, ExportTrendSnapshots, ExportPriceHistory:
+	case ExportOrders:
+		if r.Year == 0 {
+			r.Year = time.Now().Year()
+		}
+		if r.Year < firstExportYear || r.Year > time.Now().Year() {
+			return ErrInvalidExportYear
+		}
+	case ExportCategoryListings:
+		if r.CategoryID == "" {
+			return ErrExportCategoryRequired
+		}
+	default:
+		return ErrUnsupportedExportKind
+	}
+	return nil
+}
+
+// IsExportFormat reports whether format is csv, json or parquet.
+func IsExportFormat(format string) bool {
+	return format == "csv" || format == "json" || format == "parquet"
+}
+
+// since is where the trend kinds of req start, zero for all history.
+func (r *ExportRequest) since() time.Time {
+	if r.Days == 0 {
+		return time.Time{}
+	}
+	return time.Now().AddDate(0, 0, -r.Days)
+}
+
+// ExportResult is where an export was uploaded. URL downloads it until
+// ExpiresAt; a fresh one can be signed from Key.
+type ExportResult struct {
+	Kind      string    `json:"kind"`
+	Format    string    `json:"format"`
+	Bucket    string    `json:"bucket"`
+	Key       string    `json:"key"`
+	Rows      int       `json:"rows"`
+	Bytes     int64     `json:"bytes"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ExportService writes large exports to a file and uploads it to object
+// storage, so they are downloaded from the bucket instead of streamed
+// through the API.
+type ExportService struct {
+	meliClient *meli.MeliClient
+	trendRepo  *repository.TrendRepository
+	orderRepo  *repository.OrderRepository
+	bucket     *storage.Bucket
+	urlTTL     time.Duration
+}
+
+func NewExportService(meliClient *meli.MeliClient, trendRepo *repository.TrendRepository, orderRepo *repository.OrderRepository, bucket *storage.Bucket, urlTTL time.Duration) *ExportService {
+	return &ExportService{
+		meliClient: meliClient,
+		trendRepo:  trendRepo,
+		orderRepo:  orderRepo,
+		bucket:     bucket,
+		urlTTL:     urlTTL,
+	}
+}
+
+// Export runs req, uploads the file and returns a signed URL to it.
+func (s *ExportService) Export(ctx context.Context, req ExportRequest) (*ExportResult, error) {
+	if s.bucket == nil {
+		return nil, ErrExportStorageDisabled
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", "melibot-export-*."+req.Format)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	rows, err := s.write(ctx, w, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	size, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	key := exportKey(req, time.Now())
+	if err := s.bucket.Upload(ctx, key, exportContentType(req.Format), f, size); err != nil {
+		return nil, err
+	}
+	log.Printf("[INFO] Exported %d %s rows (%d bytes) to %s/%s", rows, req.Kind, size, s.bucket, key)
+
+	url, expiresAt, err := s.bucket.SignedURL(key, s.urlTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportResult{
+		Kind:      req.Kind,
+		Format:    req.Format,
+		Bucket:    s.bucket.String(),
+		Key:       key,
+		Rows:      rows,
+		Bytes:     size,
+		URL:       url,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// write writes the rows of req to w and returns how many it wrote.
+func (s *ExportService) write(ctx context.Context, w io.Writer, req ExportRequest) (int, error) {
+	switch req.Kind {
+	case ExportProductTrends:
+		trends, err := s.trendRepo.LatestProductTrends(ctx, req.CategoryID, req.Limit)
+		if err != nil {
+			return 0, err
+		}
+		return len(trends), WriteProductTrends(w, req.Format, trends)
+
+	case ExportCategoryListings:
+		// Crawls page through slow search results; see the category crawl job.
+		listings, _, err := NewCategoryStatsService(s.meliClient, nil).CrawlListings(ctx, req.CategoryID)
+		if err != nil {
+			return 0, err
+		}
+		return len(listings), WriteListings(w, req.Format, listings)
+
+	case ExportOrders:
+		me, err := s.meliClient.Me(ctx)
+		if err != nil {
+			return 0, err
+		}
+		rw, err := newRowWriter(w, req.Format, orderLineHeader, orderLineRecord)
+		if err != nil {
+			return 0, err
+		}
+		from := time.Date(req.Year, time.January, 1, 0, 0, 0, 0, time.Local)
+		err = s.orderRepo.OrdersInBatches(ctx, me.ID, from, from.AddDate(1, 0, 0), exportOrderBatch, func(orders []repository.Order) error {
+			return rw.Write(orderLines(orders)...)
+		})
+		if err != nil {
+			return 0, err
+		}
+		return rw.rows, rw.Close()
+
+	case ExportTrendSnapshots:
+		rw, err := newRowWriter(w, req.Format, productHeader, productRecord)
+		if err != nil {
+			return 0, err
+		}
+		err = s.trendRepo.SnapshotsInBatches(ctx, req.CategoryID, req.since(), exportSnapshotBatch, func(trends []repository.ProductTrend) error {
+			return rw.Write(exportedProducts(trends)...)
+		})
+		if err != nil {
+			return 0, err
+		}
+		return rw.rows, rw.Close()
+
+	case ExportPriceHistory:
+		prices, err := s.trendRepo.DailyPrices(ctx, req.CategoryID, req.since())
+		if err != nil {
+			return 0, err
+		}
+		rw, err := newRowWriter(w, req.Format, dailyPriceHeader, dailyPriceRecord)
+		if err != nil {
+			return 0, err
+		}
+		for _, p := range prices {
+			err := rw.Write(ExportedDailyPrice{
+				ProductID: p.ProductID,
+				Day:       p.Day,
+				MinPrice:  p.MinPrice,
+				MaxPrice:  p.MaxPrice,
+				AvgPrice:  roundCents(p.AvgPrice),
+				Snapshots: p.Snapshots,
+			})
+			if err != nil {
+				return 0, err
+			}
+		}
+		return rw.rows, rw.Close()
+	}
+	return 0, ErrUnsupportedExportKind
+}
+
+// exportKey names an export in the bucket, e.g.
+// exports/orders/orders-2025-20260101T120000Z.csv.
+func exportKey(req ExportRequest, now time.Time) string {
+	name := req.Kind
+	switch {
+	case req.Kind == ExportOrders:
+		name += "-" + strconv.Itoa(req.Year)
+	case req.CategoryID != "":
+		name += "-" + req.CategoryID
+	}
+	return fmt.Sprintf("%s%s/%s-%s.%s", exportKeyPrefix, req.Kind, name, now.UTC().Format("20060102T150405Z"), req.Format)
+}
+
+func exportContentType(format string) string {
+	switch format {
+	case "json":
+		return "application/json"
+	case "parquet":
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv; charset=utf-8"
+}
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"slices"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// Settlement states of an order in the reconciliation.
+const (
+	// SettlementReleased orders were paid out: the money of their approved
+	// payments is available to the seller.
+	SettlementReleased = "released"
+	// SettlementPending orders were paid, but Mercado Pago still holds the
+	// money until ReleaseDate.
+	SettlementPending = "pending"
+	// SettlementUnpaid orders have no approved payment synced.
+	SettlementUnpaid = "unpaid"
+	// SettlementRefunded orders had their payments refunded or charged back.
+	SettlementRefunded = "refunded"
+)
+
+// reconciliationTolerance is the difference between an order's total and
+// its payments below which they are considered equal.
+const reconciliationTolerance = 0.01
+
+var ErrInvalidSettlement = errors.New("settlement must be released, pending, unpaid or refunded")
+
+// ReconciledOrder is an order with the payments that settled it. Difference
+// is the order total minus the approved payments, when they don't match.
+type ReconciledOrder struct {
+	OrderID     int64      `json:"order_id"`
+	DateCreated time.Time  `json:"date_created"`
+	OrderStatus string     `json:"order_status"`
+	Currency    string     `json:"currency"`
+	TotalAmount float64    `json:"total_amount"`
+	PaymentIDs  []int64    `json:"payment_ids"`
+	PaidAmount  float64    `json:"paid_amount"`
+	Refunded    float64    `json:"refunded_amount"`
+	Fees        float64    `json:"fees"`
+	NetAmount   float64    `json:"net_amount"`
+	ReleaseDate *time.Time `json:"release_date,omitempty"`
+	Settlement  string     `json:"settlement"`
+	Difference  float64    `json:"difference,omitempty"`
+}
+
+// SettlementTotals counts the reconciled orders by settlement. Released and
+// pending amounts are net of fees.
+type SettlementTotals struct {
+	Orders         int     `json:"orders"`
+	Released       int     `json:"released"`
+	Pending        int     `json:"pending"`
+	Unpaid         int     `json:"unpaid"`
+	Refunded       int     `json:"refunded"`
+	Mismatched     int     `json:"mismatched"`
+	ReleasedAmount float64 `json:"released_amount"`
+	PendingAmount  float64 `json:"pending_amount"`
+	Fees           float64 `json:"fees"`
+}
+
+// ReconciliationReport matches the orders created over a range against
+// their Mercado Pago payments.
+type ReconciliationReport struct {
+	From   time.Time         `json:"from"`
+	To     time.Time         `json:"to"`
+	Totals SettlementTotals  `json:"totals"`
+	Orders []ReconciledOrder `json:"orders"`
+}
+
+// FinanceService syncs the seller's Mercado Pago payments and reconciles
+// them against the synced orders.
+type FinanceService struct {
+	meliClient  *meli.MeliClient
+	orderRepo   *repository.OrderRepository
+	paymentRepo *repository.PaymentRepository
+}
+
+func NewFinanceService(meliClient *meli.MeliClient, orderRepo *repository.OrderRepository, paymentRepo *repository.PaymentRepository) *FinanceService {
+	return &FinanceService{
+		meliClient:  meliClient,
+		orderRepo:   orderRepo,
+		paymentRepo: paymentRepo,
+	}
+}
+
+// SyncPayments polls the payments updated since the last sync and upserts
+// them. It returns how many payments were stored.
+func (s *FinanceService) SyncPayments(ctx context.Context) (int, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return 0, err
+	}
+	since, err := s.paymentRepo.LastUpdated(ctx, me.ID)
+	if err != nil {
+		return 0, err
+	}
+	if since.IsZero() {
+		since = time.Now().Add(-initialOrderSyncWindow)
+	} else {
+		since = since.Add(-orderSyncOverlap)
+	}
+
+	payments, err := s.meliClient.SearchPayments(ctx, me.ID, since)
+	if err != nil {
+		return 0, err
+	}
+	for i := range payments {
+		if err := s.paymentRepo.Upsert(ctx, paymentFromAPI(me.ID, &payments[i])); err != nil {
+			return i, err
+		}
+	}
+	if len(payments) > 0 {
+		log.Printf("[INFO] Synced %d payments for seller %d", len(payments), me.ID)
+	}
+	return len(payments), nil
+}
+
+func paymentFromAPI(sellerID int64, p *meli.Payment) *repository.Payment {
+	return &repository.Payment{
+		ID:                 p.ID,
+		OrderID:            p.Order.ID,
+		SellerID:           sellerID,
+		Status:             p.Status,
+		StatusDetail:       p.StatusDetail,
+		Currency:           p.CurrencyID,
+		Amount:             p.TransactionAmount,
+		RefundedAmount:     p.AmountRefunded,
+		Fees:               p.SellerFees(),
+		NetAmount:          p.TransactionDetails.NetReceivedAmount,
+		DateApproved:       p.DateApproved,
+		MoneyReleaseDate:   p.MoneyReleaseDate,
+		MoneyReleaseStatus: p.MoneyReleaseStatus,
+		LastUpdated:        p.DateLastUpdated,
+	}
+}
+
+// Reconciliation matches my orders created in [from, to) against their
+// synced payments, keeping only the given settlement when set. Orders that
+// were never paid and are no longer payable, e.g. cancelled before payment,
+// are left out.
+func (s *FinanceService) Reconciliation(ctx context.Context, from, to time.Time, settlement string) (*ReconciliationReport, error) {
+	switch settlement {
+	case "", SettlementReleased, SettlementPending, SettlementUnpaid, SettlementRefunded:
+	default:
+		return nil, ErrInvalidSettlement
+	}
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.orderRepo.List(ctx, me.ID, repository.OrderFilter{From: from, To: to})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(orders))
+	for _, o := range orders {
+		ids = append(ids, o.ID)
+	}
+	payments, err := s.paymentRepo.ForOrders(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byOrder := make(map[int64][]repository.Payment)
+	for _, p := range payments {
+		byOrder[p.OrderID] = append(byOrder[p.OrderID], p)
+	}
+
+	report := &ReconciliationReport{From: from, To: to, Orders: []ReconciledOrder{}}
+	now := time.Now()
+	for _, o := range orders {
+		paid := byOrder[o.ID]
+		if len(paid) == 0 && o.Status != repository.OrderStatusPaid {
+			continue
+		}
+		line := reconcile(o, paid, now)
+		if settlement != "" && line.Settlement != settlement {
+			continue
+		}
+		report.Totals.add(line)
+		report.Orders = append(report.Orders, line)
+	}
+	return report, nil
+}
+
+// reconcile settles an order with its payments.
+func reconcile(o repository.Order, payments []repository.Payment, now time.Time) ReconciledOrder {
+	line := ReconciledOrder{
+		OrderID:     o.ID,
+		DateCreated: o.DateCreated,
+		OrderStatus: o.Status,
+		Currency:    o.Currency,
+		TotalAmount: o.TotalAmount,
+		PaymentIDs:  []int64{},
+	}
+	approved, refunded, released := 0, 0, 0
+	for _, p := range payments {
+		line.PaymentIDs = append(line.PaymentIDs, p.ID)
+		line.Refunded += p.RefundedAmount
+		switch p.Status {
+		case meli.PaymentStatusApproved:
+			approved++
+			line.PaidAmount += p.Amount
+			line.Fees += p.Fees
+			line.NetAmount += p.NetAmount
+			if p.MoneyReleaseDate != nil && (line.ReleaseDate == nil || p.MoneyReleaseDate.After(*line.ReleaseDate)) {
+				line.ReleaseDate = p.MoneyReleaseDate
+			}
+			if p.MoneyReleaseStatus == meli.MoneyReleaseStatusReleased || (p.MoneyReleaseDate != nil && !p.MoneyReleaseDate.After(now)) {
+				released++
+			}
+		case meli.PaymentStatusRefunded, meli.PaymentStatusChargedBack:
+			refunded++
+		}
+	}
+
+	switch {
+	case approved == 0 && refunded > 0:
+		line.Settlement = SettlementRefunded
+	case approved == 0:
+		line.Settlement = SettlementUnpaid
+	case released == approved:
+		line.Settlement = SettlementReleased
+	default:
+		line.Settlement = SettlementPending
+	}
+	if approved > 0 {
+		if diff := line.TotalAmount - line.PaidAmount; math.Abs(diff) >= reconciliationTolerance {
+			line.Difference = math.Round(diff*100) / 100
+		}
+	}
+	return line
+}
+
+func (t *SettlementTotals) add(line ReconciledOrder) {
+	t.Orders++
+	t.Fees += line.Fees
+	switch line.Settlement {
+	case SettlementReleased:
+		t.Released++
+		t.ReleasedAmount += line.NetAmount
+	case SettlementPending:
+		t.Pending++
+		t.PendingAmount += line.NetAmount
+	case SettlementUnpaid:
+		t.Unpaid++
+	case SettlementRefunded:
+		t.Refunded++
+	}
+	if line.Difference != 0 {
+		t.Mismatched++
+	}
+}
+
+const (
+	// DefaultProjectionDays is how far ahead Projection looks by default.
+	DefaultProjectionDays = 30
+	// MaxProjectionDays bounds Projection.
+	MaxProjectionDays = 90
+	// releaseLagSample is how many released payments the usual release lag
+	// is measured on.
+	releaseLagSample = 200
+	// unsyncedWindow is how far back Projection looks for paid orders
+	// without a synced payment.
+	unsyncedWindow = 30 * 24 * time.Hour
+)
+
+var ErrInvalidProjectionDays = errors.New("days must be between 1 and 90")
+
+// CashflowDay is the money expected to be released on a day. Estimated is
+// the part whose release date was estimated from the usual release lag.
+type CashflowDay struct {
+	Date      string  `json:"date"`
+	Amount    float64 `json:"amount"`
+	Estimated float64 `json:"estimated"`
+	Payments  int     `json:"payments"`
+}
+
+// CashflowProjection is the money Mercado Pago will release to the seller
+// per day, net of fees. Overdue is held past its release date and counted
+// on the first day. Later is scheduled after the projected days. Unsynced
+// counts the paid orders of the last 30 days without a synced payment,
+// which the projection misses.
+type CashflowProjection struct {
+	From           string        `json:"from"`
+	Days           []CashflowDay `json:"days"`
+	Total          float64       `json:"total"`
+	Overdue        float64       `json:"overdue"`
+	Later          float64       `json:"later"`
+	ReleaseLagDays float64       `json:"release_lag_days"`
+	UnsyncedOrders int           `json:"unsynced_orders"`
+	UnsyncedAmount float64       `json:"unsynced_amount"`
+}
+
+// Projection projects the cash released to me per day over the next days,
+// from the approved payments not released yet. Payments without a release
+// date are expected the usual release lag after their approval, measured
+// on recently released payments.
+func (s *FinanceService) Projection(ctx context.Context, days int) (*CashflowProjection, error) {
+	if days < 1 || days > MaxProjectionDays {
+		return nil, ErrInvalidProjectionDays
+	}
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.paymentRepo.PendingRelease(ctx, me.ID)
+	if err != nil {
+		return nil, err
+	}
+	released, err := s.paymentRepo.RecentlyReleased(ctx, me.ID, releaseLagSample)
+	if err != nil {
+		return nil, err
+	}
+	lag := medianReleaseLag(released)
+
+	now := time.Now()
+	today := now.UTC().Truncate(24 * time.Hour)
+	p := &CashflowProjection{
+		From:           today.Format(time.DateOnly),
+		Days:           make([]CashflowDay, days),
+		ReleaseLagDays: math.Round(lag.Hours()/24*10) / 10,
+	}
+	for i := range p.Days {
+		p.Days[i].Date = today.AddDate(0, 0, i).Format(time.DateOnly)
+	}
+	for _, pay := range pending {
+		var release time.Time
+		estimated := false
+		switch {
+		case pay.MoneyReleaseDate != nil:
+			release = *pay.MoneyReleaseDate
+		case pay.DateApproved != nil:
+			release = pay.DateApproved.Add(lag)
+			estimated = true
+		default:
+			release = pay.LastUpdated.Add(lag)
+			estimated = true
+		}
+		amount := pay.NetAmount
+		p.Total += amount
+
+		day := int(release.UTC().Truncate(24*time.Hour).Sub(today) / (24 * time.Hour))
+		switch {
+		case day >= days:
+			p.Later += amount
+			continue
+		case day < 0:
+			p.Overdue += amount
+			day = 0
+		}
+		p.Days[day].Amount += amount
+		p.Days[day].Payments++
+		if estimated {
+			p.Days[day].Estimated += amount
+		}
+	}
+
+	unpaid, err := s.Reconciliation(ctx, now.Add(-unsyncedWindow), now, SettlementUnpaid)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range unpaid.Orders {
+		p.UnsyncedOrders++
+		p.UnsyncedAmount += o.TotalAmount
+	}
+	return p, nil
+}
+
+// medianReleaseLag is the median time from approval to release of the
+// given payments, or Mercado Livre's usual 28 days without any.
+func medianReleaseLag(released []repository.Payment) time.Duration {
+	lags := make([]time.Duration, 0, len(released))
+	for _, p := range released {
+		if p.DateApproved != nil && p.MoneyReleaseDate != nil && p.MoneyReleaseDate.After(*p.DateApproved) {
+			lags = append(lags, p.MoneyReleaseDate.Sub(*p.DateApproved))
+		}
+	}
+	if len(lags) == 0 {
+		return 28 * 24 * time.Hour
+	}
+	slices.Sort(lags)
+	return lags[len(lags)/2]
+}
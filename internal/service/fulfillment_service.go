@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// fulfillmentVelocityWindow is how many days of sales define the days of
+// cover of FULL stock.
+const fulfillmentVelocityWindow = 30
+
+// FulfillmentService compares stock held in Mercado Livre's warehouses
+// (FULL) with the seller's own.
+type FulfillmentService struct {
+	meliClient *meli.MeliClient
+	orderRepo  *repository.OrderRepository
+}
+
+func NewFulfillmentService(meliClient *meli.MeliClient, orderRepo *repository.OrderRepository) *FulfillmentService {
+	return &FulfillmentService{
+		meliClient: meliClient,
+		orderRepo:  orderRepo,
+	}
+}
+
+// SKUStock is the stock of a SKU split by where it is held.
+type SKUStock struct {
+	SKU              string         `json:"sku"`
+	Title            string         `json:"title"`
+	FullItemIDs      []string       `json:"full_item_ids"`
+	SellerItemIDs    []string       `json:"seller_item_ids"`
+	FullAvailable    int            `json:"full_available"`
+	FullInbound      int            `json:"full_inbound"`
+	FullNotAvailable int            `json:"full_not_available"`
+	NotAvailable     map[string]int `json:"not_available_detail"`
+	SellerStock      int            `json:"seller_stock"`
+	DailyVelocity    float64        `json:"daily_velocity"`
+	// FullDaysOfCover is nil for SKUs without FULL listings or sales.
+	FullDaysOfCover *float64 `json:"full_days_of_cover"`
+}
+
+type FulfillmentStockReport struct {
+	SKUs []SKUStock `json:"skus"`
+	// Errors lists inventories whose FULL stock could not be read.
+	Errors []string `json:"errors"`
+}
+
+// StockReport lists, per SKU of the seller's listings, the units available
+// in FULL, inbound to it and unavailable there, next to the stock the seller
+// ships. SKUs with the least FULL cover come first.
+func (s *FulfillmentService) StockReport(ctx context.Context) (*FulfillmentStockReport, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.meliClient.UserItemIDs(ctx, me.ID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sales, err := s.orderRepo.UnitsByItem(ctx, me.ID, now.AddDate(0, 0, -fulfillmentVelocityWindow), now)
+	if err != nil {
+		return nil, err
+	}
+	unitsByItem := make(map[string]int, len(sales))
+	for _, sale := range sales {
+		unitsByItem[sale.ItemID] = sale.Units
+	}
+
+	report := &FulfillmentStockReport{SKUs: []SKUStock{}, Errors: []string{}}
+	bySKU := map[string]*SKUStock{}
+	fullUnitsSold := map[string]int{}
+	// Variations of a listing share an inventory; read each one once.
+	seenInventories := map[string]bool{}
+	for i := range items {
+		it := &items[i]
+		sku := it.SKU()
+		if sku == "" {
+			sku = it.ID
+		}
+		st := bySKU[sku]
+		if st == nil {
+			st = &SKUStock{SKU: sku, Title: it.Title, FullItemIDs: []string{}, SellerItemIDs: []string{}, NotAvailable: map[string]int{}}
+			bySKU[sku] = st
+		}
+
+		if !it.Fulfilled() {
+			st.SellerItemIDs = append(st.SellerItemIDs, it.ID)
+			st.SellerStock += it.AvailableQty
+			continue
+		}
+		st.FullItemIDs = append(st.FullItemIDs, it.ID)
+		fullUnitsSold[sku] += unitsByItem[it.ID]
+		if seenInventories[it.InventoryID] {
+			continue
+		}
+		seenInventories[it.InventoryID] = true
+
+		stock, err := s.meliClient.GetFulfillmentStock(ctx, it.InventoryID)
+		if err != nil {
+			log.Printf("[WARN] FULL stock lookup failed for inventory %s (%s): %v", it.InventoryID, it.ID, err)
+			report.Errors = append(report.Errors, it.InventoryID)
+			continue
+		}
+		st.FullAvailable += stock.AvailableQuantity
+		st.FullNotAvailable += stock.NotAvailableQuantity
+		for _, d := range stock.NotAvailableDetail {
+			st.NotAvailable[d.Status] += d.Quantity
+			if d.Status == meli.FulfillmentStatusTransfer {
+				st.FullInbound += d.Quantity
+			}
+		}
+	}
+
+	for sku, st := range bySKU {
+		velocity := float64(fullUnitsSold[sku]) / fulfillmentVelocityWindow
+		st.DailyVelocity = math.Round(velocity*100) / 100
+		if len(st.FullItemIDs) > 0 && velocity > 0 {
+			days := math.Round(float64(st.FullAvailable+st.FullInbound)/velocity*10) / 10
+			st.FullDaysOfCover = &days
+		}
+		report.SKUs = append(report.SKUs, *st)
+	}
+	sort.Slice(report.SKUs, func(i, j int) bool {
+		a, b := report.SKUs[i].FullDaysOfCover, report.SKUs[j].FullDaysOfCover
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		if a != nil && *a != *b {
+			return *a < *b
+		}
+		return report.SKUs[i].SKU < report.SKUs[j].SKU
+	})
+	return report, nil
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// Upper bounds of the GraphQL list arguments.
+const (
+	maxGraphTrends      = 50
+	maxGraphProducts    = 100
+	maxGraphCompetitors = 50
+	maxGraphOrders      = 200
+	maxGraphAlerts      = 100
+	maxGraphDays        = 365
+)
+
+// GraphService serves the GraphQL schema (see internal/graph): products,
+// trends, snapshots, orders and the watchlist, so the dashboard can fetch
+// nested data (trend → item → competitors) in a single request. A
+// GraphService lives for one request: item lookups and the seller are
+// memoized.
+type GraphService struct {
+	meliClient    *meli.MeliClient
+	marketing     *MarketingService
+	trendRepo     *repository.TrendRepository
+	orderRepo     *repository.OrderRepository
+	watchlistRepo *repository.WatchlistRepository
+
+	sellerOnce sync.Once
+	sellerID   int64
+	sellerErr  error
+
+	itemsMu sync.Mutex
+	items   map[string]*itemLookup
+}
+
+type itemLookup struct {
+	once sync.Once
+	item *meli.Item
+	err  error
+}
+
+func NewGraphService(meliClient *meli.MeliClient, marketing *MarketingService, trendRepo *repository.TrendRepository, orderRepo *repository.OrderRepository, watchlistRepo *repository.WatchlistRepository) *GraphService {
+	return &GraphService{
+		meliClient:    meliClient,
+		marketing:     marketing,
+		trendRepo:     trendRepo,
+		orderRepo:     orderRepo,
+		watchlistRepo: watchlistRepo,
+		items:         make(map[string]*itemLookup),
+	}
+}
+
+func (s *GraphService) seller(ctx context.Context) (int64, error) {
+	s.sellerOnce.Do(func() {
+		me, err := s.meliClient.Me(ctx)
+		if err != nil {
+			s.sellerErr = err
+			return
+		}
+		s.sellerID = me.ID
+	})
+	return s.sellerID, s.sellerErr
+}
+
+// Item returns a listing by ID. Catalog product IDs (as found in trends and
+// the watchlist) resolve to the product's best offer. Unknown IDs give nil.
+func (s *GraphService) Item(ctx context.Context, id string) (*meli.Item, error) {
+	s.itemsMu.Lock()
+	l, ok := s.items[id]
+	if !ok {
+		l = &itemLookup{}
+		s.items[id] = l
+	}
+	s.itemsMu.Unlock()
+
+	l.once.Do(func() {
+		l.item, l.err = s.meliClient.GetItem(ctx, id)
+		if !errors.Is(l.err, meli.ErrNotFound) {
+			return
+		}
+		offer, err := s.meliClient.GetProductBestPriceWithLink(ctx, id)
+		if err != nil || offer.ItemID == "" {
+			l.item, l.err = nil, nil
+			return
+		}
+		l.item, l.err = s.meliClient.GetItem(ctx, offer.ItemID)
+		if errors.Is(l.err, meli.ErrNotFound) {
+			l.item, l.err = nil, nil
+		}
+	})
+	return l.item, l.err
+}
+
+// Competitors are other listings matching an item's title.
+type Competitors struct {
+	// Total counts the matching listings, excluding the item itself
+	Total    int
+	Listings []meli.CategorySearchResult
+}
+
+// checkLimit checks a list argument is within [1, max].
+func checkLimit(name string, n, max int) error {
+	if n < 1 || n > max {
+		return fmt.Errorf("%s must be between 1 and %d", name, max)
+	}
+	return nil
+}
+
+// Trends returns the top sold products of a category.
+func (s *GraphService) Trends(ctx context.Context, categoryID string, limit int) (*TrendsResult, error) {
+	if err := checkLimit("limit", limit, maxGraphTrends); err != nil {
+		return nil, err
+	}
+	return s.marketing.TopTrendsByCategory(ctx, categoryID, limit)
+}
+
+// Products returns the stored products whose title or ID matches query.
+func (s *GraphService) Products(ctx context.Context, query string, limit int) ([]repository.ProductTrend, error) {
+	if err := checkLimit("limit", limit, maxGraphProducts); err != nil {
+		return nil, err
+	}
+	return s.trendRepo.SearchProductTrends(ctx, query, limit)
+}
+
+// Competitors returns other listings matching an item's title.
+func (s *GraphService) Competitors(ctx context.Context, item *meli.Item, limit int) (*Competitors, error) {
+	if err := checkLimit("limit", limit, maxGraphCompetitors); err != nil {
+		return nil, err
+	}
+	page, err := s.meliClient.SearchListings(ctx, item.Title, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	total := page.Paging.Total
+	listings := make([]meli.CategorySearchResult, 0, limit)
+	for _, r := range page.Results {
+		if r.ID == item.ID {
+			total--
+			continue
+		}
+		if len(listings) < limit {
+			listings = append(listings, r)
+		}
+	}
+	return &Competitors{Total: max(total, 0), Listings: listings}, nil
+}
+
+// Snapshots returns the stored sales snapshots of a product over the last
+// days, oldest first.
+func (s *GraphService) Snapshots(ctx context.Context, productID string, days int) ([]repository.SoldSnapshot, error) {
+	if err := checkLimit("days", days, maxGraphDays); err != nil {
+		return nil, err
+	}
+	return s.trendRepo.SoldSnapshots(ctx, []string{productID}, time.Now().AddDate(0, 0, -days))
+}
+
+// Orders returns the stored orders of the authenticated seller matching f,
+// newest first.
+func (s *GraphService) Orders(ctx context.Context, f repository.OrderFilter) ([]repository.Order, error) {
+	if err := checkLimit("limit", f.Limit, maxGraphOrders); err != nil {
+		return nil, err
+	}
+	sellerID, err := s.seller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.orderRepo.List(ctx, sellerID, f)
+}
+
+// Order returns a stored order, or nil.
+func (s *GraphService) Order(ctx context.Context, id string) (*repository.Order, error) {
+	orderID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, errors.New("id must be a numeric order ID")
+	}
+	return s.orderRepo.FindByID(ctx, orderID)
+}
+
+// OrderHistory returns the status changes of an order.
+func (s *GraphService) OrderHistory(ctx context.Context, orderID int64) ([]repository.OrderStatusChange, error) {
+	return s.orderRepo.StatusHistory(ctx, orderID)
+}
+
+// Watchlist returns the watched catalog products.
+func (s *GraphService) Watchlist(ctx context.Context) ([]repository.WatchedProduct, error) {
+	return s.watchlistRepo.List(ctx)
+}
+
+// Alerts returns the latest price alerts of a watched product.
+func (s *GraphService) Alerts(ctx context.Context, productID string, limit int) ([]repository.PriceAlert, error) {
+	if err := checkLimit("limit", limit, maxGraphAlerts); err != nil {
+		return nil, err
+	}
+	return s.watchlistRepo.Alerts(ctx, productID, limit)
+}
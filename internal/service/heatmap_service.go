@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"melibot/internal/cache"
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+const (
+	// heatmapLookupConcurrency bounds parallel category lookups when
+	// rolling highlight categories up to their level-2 ancestors.
+	heatmapLookupConcurrency = 4
+	// categoryPathTTL is how long a category's path from the root is
+	// cached; the category tree rarely changes.
+	categoryPathTTL = 24 * time.Hour
+)
+
+// HeatmapNode is a category of the heatmap tree. Value sizes the node (units
+// sold in the window) and the change percentages compare it with the
+// previous window of the same length; DemandChangePct is nil when that
+// window sold none. On level-2 nodes the intensities scale both changes to
+// -1..1 against the largest change of the report, for coloring.
+type HeatmapNode struct {
+	ID               string        `json:"id"`
+	Name             string        `json:"name"`
+	Value            int           `json:"value"`
+	PreviousValue    int           `json:"previous_value"`
+	Products         int           `json:"products"`
+	DemandChangePct  *float64      `json:"demand_change_pct"`
+	PriceChangePct   float64       `json:"price_change_pct"`
+	DemandIntensity  float64       `json:"demand_intensity"`
+	PriceIntensity   float64       `json:"price_intensity"`
+	Children         []HeatmapNode `json:"children,omitempty"`
+	priceChangeTotal float64
+}
+
+// Heatmap is the marketplace as a tree of level-1 categories holding their
+// level-2 categories, shaped for a treemap. Unresolved counts the
+// snapshotted categories whose place in the tree could not be looked up;
+// they are left out.
+type Heatmap struct {
+	Window      string        `json:"window"`
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Categories  []HeatmapNode `json:"categories"`
+	Unresolved  int           `json:"unresolved"`
+}
+
+// HeatmapService aggregates the stored snapshots by level-2 category. It
+// calls Mercado Livre only to place the snapshotted categories in the
+// category tree.
+type HeatmapService struct {
+	meliClient *meli.MeliClient
+	trendRepo  *repository.TrendRepository
+	cache      *cache.Cache
+}
+
+func NewHeatmapService(meliClient *meli.MeliClient, trendRepo *repository.TrendRepository, cache *cache.Cache) *HeatmapService {
+	return &HeatmapService{
+		meliClient: meliClient,
+		trendRepo:  trendRepo,
+		cache:      cache,
+	}
+}
+
+func heatmapCacheKey(ctx context.Context, window string) string {
+	return repository.ScopedKey(ctx, "reports:heatmap:"+window)
+}
+
+func categoryPathCacheKey(categoryID string) string {
+	return "categories:path:" + categoryID
+}
+
+// Heatmap reports the demand and price movement of every level-2 category
+// in the last window, name being how the window was asked for (e.g. "7d").
+// Reports are served from the response cache when fresh.
+func (s *HeatmapService) Heatmap(ctx context.Context, name string, window time.Duration) (*Heatmap, error) {
+	key := heatmapCacheKey(ctx, name)
+	if cached, ok := s.cache.Get(key); ok {
+		return cached.(*Heatmap), nil
+	}
+
+	now := time.Now()
+	from := now.Add(-window)
+	current, err := s.trendRepo.ProductMovements(ctx, from, now)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.trendRepo.ProductMovements(ctx, from.Add(-window), from)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]bool{}
+	for _, m := range current {
+		ids[m.CategoryID] = true
+	}
+	for _, m := range previous {
+		ids[m.CategoryID] = true
+	}
+	paths := s.categoryPaths(ctx, ids)
+
+	// Level-2 nodes by ID, and the level-1 node each belongs to
+	leaves := map[string]*HeatmapNode{}
+	parents := map[string]meli.Category{}
+	unresolved := map[string]bool{}
+	leafOf := func(categoryID string) *HeatmapNode {
+		path, ok := paths[categoryID]
+		if !ok || len(path) == 0 {
+			unresolved[categoryID] = true
+			return nil
+		}
+		top, level2 := path[0], path[0]
+		if len(path) > 1 {
+			level2 = path[1]
+		}
+		n, ok := leaves[level2.ID]
+		if !ok {
+			n = &HeatmapNode{ID: level2.ID, Name: level2.Name}
+			leaves[level2.ID] = n
+			parents[level2.ID] = top
+		}
+		return n
+	}
+	for _, m := range current {
+		if n := leafOf(m.CategoryID); n != nil {
+			n.Value += m.Sold
+			n.Products++
+			n.priceChangeTotal += (m.LastPrice - m.FirstPrice) / m.FirstPrice
+		}
+	}
+	for _, m := range previous {
+		if n := leafOf(m.CategoryID); n != nil {
+			n.PreviousValue += m.Sold
+		}
+	}
+
+	var maxDemand, maxPrice float64
+	for _, n := range leaves {
+		n.finish()
+		if n.DemandChangePct != nil {
+			maxDemand = math.Max(maxDemand, math.Abs(*n.DemandChangePct))
+		}
+		maxPrice = math.Max(maxPrice, math.Abs(n.PriceChangePct))
+	}
+
+	tops := map[string]*HeatmapNode{}
+	for id, leaf := range leaves {
+		if leaf.Products == 0 {
+			// Moved only in the previous window
+			continue
+		}
+		if maxDemand > 0 && leaf.DemandChangePct != nil {
+			leaf.DemandIntensity = math.Round(*leaf.DemandChangePct/maxDemand*1000) / 1000
+		}
+		if maxPrice > 0 {
+			leaf.PriceIntensity = math.Round(leaf.PriceChangePct/maxPrice*1000) / 1000
+		}
+		parent := parents[id]
+		top, ok := tops[parent.ID]
+		if !ok {
+			top = &HeatmapNode{ID: parent.ID, Name: parent.Name}
+			tops[parent.ID] = top
+		}
+		top.Value += leaf.Value
+		top.PreviousValue += leaf.PreviousValue
+		top.Products += leaf.Products
+		top.priceChangeTotal += leaf.priceChangeTotal
+		top.Children = append(top.Children, *leaf)
+	}
+
+	report := &Heatmap{
+		Window:      name,
+		From:        from,
+		To:          now,
+		GeneratedAt: now,
+		Categories:  make([]HeatmapNode, 0, len(tops)),
+		Unresolved:  len(unresolved),
+	}
+	for _, top := range tops {
+		top.finish()
+		sortHeatmapNodes(top.Children)
+		report.Categories = append(report.Categories, *top)
+	}
+	sortHeatmapNodes(report.Categories)
+	s.cache.Set(key, report)
+	return report, nil
+}
+
+// finish derives the change percentages of a node from its totals.
+func (n *HeatmapNode) finish() {
+	if n.PreviousValue > 0 {
+		pct := roundPct(float64(n.Value-n.PreviousValue) / float64(n.PreviousValue))
+		n.DemandChangePct = &pct
+	}
+	if n.Products > 0 {
+		n.PriceChangePct = roundPct(n.priceChangeTotal / float64(n.Products))
+	}
+}
+
+// sortHeatmapNodes orders nodes biggest first, as treemaps lay them out.
+func sortHeatmapNodes(nodes []HeatmapNode) {
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Value != nodes[j].Value {
+			return nodes[i].Value > nodes[j].Value
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+}
+
+// categoryPaths looks up the path from the root of each category with
+// bounded concurrency, from the response cache when fresh. Categories that
+// fail are logged and left out.
+func (s *HeatmapService) categoryPaths(ctx context.Context, ids map[string]bool) map[string][]meli.Category {
+	paths := make(map[string][]meli.Category, len(ids))
+	var mu sync.Mutex
+	sem := make(chan struct{}, heatmapLookupConcurrency)
+	var wg sync.WaitGroup
+
+	for id := range ids {
+		if cached, ok := s.cache.Get(categoryPathCacheKey(id)); ok {
+			mu.Lock()
+			paths[id] = cached.([]meli.Category)
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			cat, err := s.meliClient.GetCategory(ctx, id)
+			if err != nil {
+				log.Printf("[WARN] Failed to look up category %s for the heatmap: %v", id, err)
+				return
+			}
+			s.cache.SetWithTTL(categoryPathCacheKey(id), cat.PathFromRoot, categoryPathTTL)
+			mu.Lock()
+			paths[id] = cat.PathFromRoot
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return paths
+}
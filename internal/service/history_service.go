@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"melibot/internal/repository"
+)
+
+// HistoryService analyses the trend snapshots accumulated by /api/trends
+// and the cache warmer.
+type HistoryService struct {
+	trendRepo   *repository.TrendRepository
+	keywordRepo *repository.KeywordTrendRepository
+}
+
+func NewHistoryService(trendRepo *repository.TrendRepository, keywordRepo *repository.KeywordTrendRepository) *HistoryService {
+	return &HistoryService{
+		trendRepo:   trendRepo,
+		keywordRepo: keywordRepo,
+	}
+}
+
+// ChartSeries is a time series shaped for charting libraries: one label per
+// bucket and a dataset whose values line up with the labels. Buckets without
+// data are nil so charts show a gap instead of a drop to zero.
+type ChartSeries struct {
+	Labels   []string       `json:"labels"`
+	Datasets []ChartDataset `json:"datasets"`
+}
+
+type ChartDataset struct {
+	Label string     `json:"label"`
+	Data  []*float64 `json:"data"`
+}
+
+// RankHistory returns a product's best daily position in a category's
+// highlights over the last days, one label per day.
+func (s *HistoryService) RankHistory(ctx context.Context, productID, categoryID string, days int) (*ChartSeries, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from := today.AddDate(0, 0, -(days - 1))
+
+	ranks, err := s.trendRepo.RankHistory(ctx, productID, categoryID, from)
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[string]float64, len(ranks))
+	for _, r := range ranks {
+		byDay[r.Day.UTC().Format(time.DateOnly)] = float64(r.BestRank)
+	}
+
+	series := &ChartSeries{
+		Labels:   make([]string, 0, days),
+		Datasets: []ChartDataset{{Label: "rank", Data: make([]*float64, 0, days)}},
+	}
+	for d := from; !d.After(today); d = d.AddDate(0, 0, 1) {
+		label := d.Format(time.DateOnly)
+		series.Labels = append(series.Labels, label)
+		var v *float64
+		if rank, ok := byDay[label]; ok {
+			v = &rank
+		}
+		series.Datasets[0].Data = append(series.Datasets[0].Data, v)
+	}
+	return series, nil
+}
+
+const (
+	// seasonalPeakIndex marks periods selling at least 25% above average.
+	seasonalPeakIndex = 1.25
+	// seasonalTopKeywords is how many keywords are listed per period.
+	seasonalTopKeywords = 5
+)
+
+// ErrInvalidGroupBy is returned for a seasonality grouping other than week/month.
+var ErrInvalidGroupBy = errors.New("group_by must be week or month")
+
+// Seasonality is a category's demand per period.
+type Seasonality struct {
+	CategoryID string              `json:"category_id"`
+	GroupBy    string              `json:"group_by"`
+	Periods    []SeasonalityPeriod `json:"periods"`
+	// Peaks are the periods whose index is at least seasonalPeakIndex.
+	Peaks []string `json:"peaks"`
+}
+
+// SeasonalityPeriod is the activity of one week or month. Index is the
+// units sold relative to the average period (1 = average).
+type SeasonalityPeriod struct {
+	Period      string         `json:"period"`
+	UnitsSold   int            `json:"units_sold"`
+	Products    int            `json:"products"`
+	Index       float64        `json:"index"`
+	TopKeywords []KeywordCount `json:"top_keywords"`
+}
+
+type KeywordCount struct {
+	Keyword     string `json:"keyword"`
+	Appearances int    `json:"appearances"`
+}
+
+// Seasonality aggregates a category's sold growth and trending keywords by
+// week or month over the last months.
+func (s *HistoryService) Seasonality(ctx context.Context, categoryID, groupBy string, months int) (*Seasonality, error) {
+	layout := map[string]string{"week": time.DateOnly, "month": "2006-01"}[groupBy]
+	if layout == "" {
+		return nil, ErrInvalidGroupBy
+	}
+	since := time.Now().AddDate(0, -months, 0)
+
+	sales, err := s.trendRepo.SalesByPeriod(ctx, categoryID, groupBy, since)
+	if err != nil {
+		return nil, err
+	}
+	activity, err := s.keywordRepo.ActivityByPeriod(ctx, categoryID, groupBy, since)
+	if err != nil {
+		return nil, err
+	}
+
+	keywords := map[string][]KeywordCount{}
+	for _, a := range activity {
+		key := a.Period.Format(layout)
+		if len(keywords[key]) < seasonalTopKeywords {
+			keywords[key] = append(keywords[key], KeywordCount{Keyword: a.Keyword, Appearances: a.Appearances})
+		}
+	}
+
+	total := 0
+	for _, p := range sales {
+		total += p.UnitsSold
+	}
+	avg := 0.0
+	if len(sales) > 0 {
+		avg = float64(total) / float64(len(sales))
+	}
+
+	out := &Seasonality{CategoryID: categoryID, GroupBy: groupBy, Periods: []SeasonalityPeriod{}, Peaks: []string{}}
+	seen := map[string]bool{}
+	for _, p := range sales {
+		key := p.Period.Format(layout)
+		seen[key] = true
+		period := SeasonalityPeriod{
+			Period:      key,
+			UnitsSold:   p.UnitsSold,
+			Products:    p.Products,
+			TopKeywords: keywords[key],
+		}
+		if avg > 0 {
+			period.Index = math.Round(float64(p.UnitsSold)/avg*100) / 100
+		}
+		if period.Index >= seasonalPeakIndex {
+			out.Peaks = append(out.Peaks, key)
+		}
+		out.Periods = append(out.Periods, period)
+	}
+	// Periods with keyword data but no sales snapshots still show what was
+	// being searched.
+	for _, a := range activity {
+		key := a.Period.Format(layout)
+		if !seen[key] {
+			seen[key] = true
+			out.Periods = append(out.Periods, SeasonalityPeriod{Period: key, TopKeywords: keywords[key]})
+		}
+	}
+	sort.Slice(out.Periods, func(i, j int) bool { return out.Periods[i].Period < out.Periods[j].Period })
+	return out, nil
+}
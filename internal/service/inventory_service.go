@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// ForecastSettings drive the inventory forecast.
+type ForecastSettings struct {
+	// WindowDays is how many days of sales define the velocity.
+	WindowDays int `json:"window_days"`
+	// LeadTimeDays is how long a reorder takes to arrive.
+	LeadTimeDays int `json:"lead_time_days"`
+	// SafetyDays of sales are kept in stock on top of the lead time.
+	SafetyDays int `json:"safety_days"`
+	// CoverageDays of sales a reorder should cover once it arrives.
+	CoverageDays int `json:"coverage_days"`
+}
+
+// DefaultForecastSettings are used when none are configured.
+var DefaultForecastSettings = ForecastSettings{WindowDays: 30, LeadTimeDays: 14, SafetyDays: 7, CoverageDays: 30}
+
+func (f ForecastSettings) Validate() error {
+	if f.WindowDays <= 0 || f.WindowDays > 365 {
+		return errors.New("window_days must be between 1 and 365")
+	}
+	if f.LeadTimeDays < 0 || f.SafetyDays < 0 || f.CoverageDays < 0 {
+		return errors.New("lead_time_days, safety_days and coverage_days must not be negative")
+	}
+	return nil
+}
+
+// InventoryService forecasts stockouts from order velocity and listing stock.
+type InventoryService struct {
+	meliClient *meli.MeliClient
+	orderRepo  *repository.OrderRepository
+	invRepo    *repository.InventoryRepository
+	settings   ForecastSettings
+}
+
+func NewInventoryService(meliClient *meli.MeliClient, orderRepo *repository.OrderRepository, invRepo *repository.InventoryRepository, settings ForecastSettings) *InventoryService {
+	return &InventoryService{
+		meliClient: meliClient,
+		orderRepo:  orderRepo,
+		invRepo:    invRepo,
+		settings:   settings,
+	}
+}
+
+// Settings returns the configured forecast settings.
+func (s *InventoryService) Settings() ForecastSettings {
+	return s.settings
+}
+
+// SKUForecast is the stock outlook of a SKU across its listings.
+type SKUForecast struct {
+	SKU           string   `json:"sku"`
+	Title         string   `json:"title"`
+	ItemIDs       []string `json:"item_ids"`
+	Stock         int      `json:"stock"`
+	UnitsSold     int      `json:"units_sold"`
+	DailyVelocity float64  `json:"daily_velocity"`
+	// DaysOfStock is nil when the SKU did not sell in the window.
+	DaysOfStock  *float64 `json:"days_of_stock"`
+	StockoutDate *string  `json:"stockout_date"`
+	ReorderPoint int      `json:"reorder_point"`
+	ReorderQty   int      `json:"reorder_qty"`
+	Reorder      bool     `json:"reorder"`
+}
+
+type InventoryForecast struct {
+	Settings    ForecastSettings `json:"settings"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	SKUs        []SKUForecast    `json:"skus"`
+}
+
+// Forecast estimates days until stockout and a reorder quantity for every
+// SKU of the seller's active listings, most urgent first.
+func (s *InventoryService) Forecast(ctx context.Context, settings ForecastSettings) (*InventoryForecast, error) {
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.meliClient.UserItemIDs(ctx, me.ID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sales, err := s.orderRepo.UnitsByItem(ctx, me.ID, now.AddDate(0, 0, -settings.WindowDays), now)
+	if err != nil {
+		return nil, err
+	}
+	return buildForecast(items, sales, settings, now), nil
+}
+
+// buildForecast groups listings by SKU (the item ID when a listing has none)
+// and projects their stock against the velocity of the window.
+func buildForecast(items []meli.Item, sales []repository.ItemSales, settings ForecastSettings, now time.Time) *InventoryForecast {
+	bySKU := map[string]*SKUForecast{}
+	skuOfItem := map[string]string{}
+	for i := range items {
+		it := &items[i]
+		if it.Status != "active" && it.Status != "paused" {
+			continue
+		}
+		sku := it.SKU()
+		if sku == "" {
+			sku = it.ID
+		}
+		f := bySKU[sku]
+		if f == nil {
+			f = &SKUForecast{SKU: sku, Title: it.Title}
+			bySKU[sku] = f
+		}
+		f.ItemIDs = append(f.ItemIDs, it.ID)
+		f.Stock += it.AvailableQty
+		skuOfItem[it.ID] = sku
+	}
+	for _, sale := range sales {
+		if sku, ok := skuOfItem[sale.ItemID]; ok {
+			bySKU[sku].UnitsSold += sale.Units
+		}
+	}
+
+	forecast := &InventoryForecast{Settings: settings, GeneratedAt: now, SKUs: make([]SKUForecast, 0, len(bySKU))}
+	for _, f := range bySKU {
+		velocity := float64(f.UnitsSold) / float64(settings.WindowDays)
+		f.DailyVelocity = math.Round(velocity*100) / 100
+		if velocity > 0 {
+			days := math.Round(float64(f.Stock)/velocity*10) / 10
+			f.DaysOfStock = &days
+			date := now.Add(time.Duration(days * float64(24*time.Hour))).Format(time.DateOnly)
+			f.StockoutDate = &date
+
+			f.ReorderPoint = int(math.Ceil(velocity * float64(settings.LeadTimeDays+settings.SafetyDays)))
+			target := int(math.Ceil(velocity * float64(settings.LeadTimeDays+settings.SafetyDays+settings.CoverageDays)))
+			f.ReorderQty = max(target-f.Stock, 0)
+			f.Reorder = f.Stock <= f.ReorderPoint
+		}
+		forecast.SKUs = append(forecast.SKUs, *f)
+	}
+	sort.Slice(forecast.SKUs, func(i, j int) bool {
+		a, b := forecast.SKUs[i].DaysOfStock, forecast.SKUs[j].DaysOfStock
+		if (a == nil) != (b == nil) {
+			return a != nil
+		}
+		if a != nil && *a != *b {
+			return *a < *b
+		}
+		return forecast.SKUs[i].SKU < forecast.SKUs[j].SKU
+	})
+	return forecast
+}
+
+// CheckReorderPoints runs the forecast with the configured settings and
+// opens an alert for each SKU that reached its reorder point, resolving
+// alerts of SKUs that were restocked.
+func (s *InventoryService) CheckReorderPoints(ctx context.Context) error {
+	forecast, err := s.Forecast(ctx, s.settings)
+	if err != nil {
+		return err
+	}
+	open, err := s.invRepo.OpenAlerts(ctx)
+	if err != nil {
+		return err
+	}
+
+	var resolved []uint
+	for _, f := range forecast.SKUs {
+		alert, isOpen := open[f.SKU]
+		delete(open, f.SKU)
+		switch {
+		case f.Reorder && !isOpen:
+			alert = &repository.ReorderAlert{
+				SKU:           f.SKU,
+				Title:         f.Title,
+				Stock:         f.Stock,
+				DailyVelocity: f.DailyVelocity,
+				ReorderPoint:  f.ReorderPoint,
+				ReorderQty:    f.ReorderQty,
+			}
+			if err := s.invRepo.CreateAlert(ctx, alert); err != nil {
+				return err
+			}
+			log.Printf("[INFO] Reorder alert for %s: %d in stock, reorder point %d, suggested qty %d", f.SKU, f.Stock, f.ReorderPoint, f.ReorderQty)
+		case !f.Reorder && isOpen:
+			resolved = append(resolved, alert.ID)
+		}
+	}
+	// SKUs that are no longer listed cannot be restocked through us.
+	for _, alert := range open {
+		resolved = append(resolved, alert.ID)
+	}
+	return s.invRepo.ResolveAlerts(ctx, resolved, time.Now())
+}
+
+// Alerts returns recent reorder alerts.
+func (s *InventoryService) Alerts(ctx context.Context, openOnly bool, limit int) ([]repository.ReorderAlert, error) {
+	return s.invRepo.Alerts(ctx, openOnly, limit)
+}
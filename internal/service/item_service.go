@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+
+	"melibot/pkg/meli"
+)
+
+// BuyBox is the cheapest active listing of the catalog product an item
+// belongs to.
+type BuyBox struct {
+	ItemID    string  `json:"item_id"`
+	Price     float64 `json:"price"`
+	Permalink string  `json:"permalink,omitempty"`
+	Winning   bool    `json:"winning"`
+}
+
+// ItemDetail is a listing with the buy box of its catalog product. Errors
+// lists the enrichment that failed; the listing itself is still returned.
+type ItemDetail struct {
+	meli.Item
+	BuyBox *BuyBox  `json:"buy_box,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ItemService looks up single listings.
+type ItemService struct {
+	meliClient *meli.MeliClient
+}
+
+func NewItemService(meliClient *meli.MeliClient) *ItemService {
+	return &ItemService{
+		meliClient: meliClient,
+	}
+}
+
+// Item fetches a listing. withBuyBox also looks up the best offer of its
+// catalog product, one more call per item in the catalog.
+func (s *ItemService) Item(ctx context.Context, itemID string, withBuyBox bool) (*ItemDetail, error) {
+	item, err := s.meliClient.GetItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	out := &ItemDetail{Item: *item}
+	if !withBuyBox || item.CatalogID == "" {
+		return out, nil
+	}
+
+	best, err := s.meliClient.GetProductBestPriceWithLink(ctx, item.CatalogID)
+	if err != nil {
+		out.Errors = append(out.Errors, "buy_box: "+err.Error())
+		return out, nil
+	}
+	out.BuyBox = &BuyBox{
+		ItemID:    best.ItemID,
+		Price:     best.Price,
+		Permalink: best.Permalink,
+		Winning:   best.ItemID == item.ID,
+	}
+	return out, nil
+}
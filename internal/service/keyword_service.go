@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// KeywordService snapshots trending search terms so their activity can be
+// analysed over time.
+type KeywordService struct {
+	meliClient  *meli.MeliClient
+	keywordRepo *repository.KeywordTrendRepository
+}
+
+func NewKeywordService(meliClient *meli.MeliClient, keywordRepo *repository.KeywordTrendRepository) *KeywordService {
+	return &KeywordService{
+		meliClient:  meliClient,
+		keywordRepo: keywordRepo,
+	}
+}
+
+// SnapshotKeywords stores the current trending keywords of each category.
+// Failures for individual categories are logged and do not stop the others.
+func (s *KeywordService) SnapshotKeywords(ctx context.Context, categories []string) error {
+	failed := 0
+	for _, categoryID := range categories {
+		keywords, err := s.meliClient.TrendingKeywords(ctx, categoryID)
+		if err != nil {
+			log.Printf("[WARN] Keyword snapshot failed for category %s: %v", categoryID, err)
+			failed++
+			continue
+		}
+
+		trends := make([]repository.KeywordTrend, 0, len(keywords))
+		for i, k := range keywords {
+			trends = append(trends, repository.KeywordTrend{CategoryID: categoryID, Keyword: k.Keyword, Position: i + 1})
+		}
+		if err := s.keywordRepo.SaveKeywordTrends(ctx, trends); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("keyword snapshot: %d of %d categories failed", failed, len(categories))
+	}
+	return nil
+}
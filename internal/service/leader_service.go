@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"melibot/internal/repository"
+)
+
+// leaderLease is the lease the instances compete for, stored with the task
+// leases.
+const leaderLease = "leader"
+
+// LeaderStats reports the leadership of this instance.
+type LeaderStats struct {
+	Owner     string     `json:"owner"`
+	Leader    bool       `json:"leader"`
+	Since     *time.Time `json:"since,omitempty"`
+	Elections int64      `json:"elections"`
+	Lost      int64      `json:"lost"`
+}
+
+// LeaderElector elects one instance to run the background subsystems
+// (scheduler, queue workers) while every instance serves HTTP. The leader
+// holds a lease in the database and renews it; when it dies the lease
+// expires and another instance takes over. It implements scheduler.Locker,
+// letting tasks run on the leader only.
+type LeaderElector struct {
+	repo  *repository.TaskLockRepository
+	owner string
+	ttl   time.Duration
+
+	mu sync.Mutex
+	// leaderCtx is cancelled when leadership is lost, nil when not leader
+	leaderCtx context.Context
+	since     time.Time
+
+	elections, lost atomic.Int64
+}
+
+// NewLeaderElector returns an elector whose lease lasts ttl.
+func NewLeaderElector(repo *repository.TaskLockRepository, ttl time.Duration) *LeaderElector {
+	if ttl <= 0 {
+		ttl = DefaultTaskLockTTL
+	}
+	return &LeaderElector{repo: repo, owner: InstanceID(), ttl: ttl}
+}
+
+// Run campaigns for leadership until ctx is cancelled, calling onElected
+// with a context cancelled when leadership is lost each time this instance
+// is elected. The lease is renewed every third of its TTL, each attempt
+// bounded by a third of it; when renewals fail for half of it, the instance
+// steps down before the lease expires, so two leaders never overlap.
+func (e *LeaderElector) Run(ctx context.Context, onElected func(ctx context.Context)) {
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	var (
+		resign  context.CancelFunc
+		renewed time.Time
+	)
+	for {
+		if resign == nil {
+			campaignCtx, cancel := context.WithTimeout(ctx, e.ttl/3)
+			ok, previous, err := e.repo.Acquire(campaignCtx, leaderLease, e.owner, e.ttl)
+			cancel()
+			if err != nil {
+				log.Printf("[WARN] leader: failed to campaign: %v", err)
+			} else if ok {
+				var leaderCtx context.Context
+				leaderCtx, resign = context.WithCancel(ctx)
+				renewed = time.Now()
+				e.setLeader(leaderCtx)
+				e.elections.Add(1)
+				if previous != "" {
+					log.Printf("[INFO] leader: %s elected, taking over from %s", e.owner, previous)
+				} else {
+					log.Printf("[INFO] leader: %s elected", e.owner)
+				}
+				go onElected(leaderCtx)
+			}
+		} else {
+			// Bounded, so a hung database cannot keep this instance leading
+			// past the lease
+			renewCtx, cancel := context.WithTimeout(ctx, e.ttl/3)
+			ok, err := e.repo.Renew(renewCtx, leaderLease, e.owner, e.ttl)
+			cancel()
+			switch {
+			case err == nil && ok:
+				renewed = time.Now()
+			case err != nil && time.Since(renewed) < e.ttl/2:
+				log.Printf("[WARN] leader: failed to renew the lease: %v", err)
+			default:
+				log.Printf("[ERROR] leader: %s lost leadership, stopping background work", e.owner)
+				e.lost.Add(1)
+				e.setLeader(nil)
+				resign()
+				resign = nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if resign != nil {
+				e.setLeader(nil)
+				resign()
+				releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+				if err := e.repo.Release(releaseCtx, leaderLease, e.owner); err != nil {
+					log.Printf("[WARN] leader: failed to release the lease: %v", err)
+				}
+				cancel()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *LeaderElector) setLeader(leaderCtx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leaderCtx = leaderCtx
+	e.since = time.Now()
+}
+
+// IsLeader reports whether this instance is the leader.
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.leaderCtx != nil
+}
+
+// TryLock lets a task run while this instance is the leader, stopping it
+// when leadership is lost.
+func (e *LeaderElector) TryLock(ctx context.Context, name string) (context.Context, func(time.Time), bool, error) {
+	e.mu.Lock()
+	leaderCtx := e.leaderCtx
+	e.mu.Unlock()
+	if leaderCtx == nil {
+		return nil, nil, false, nil
+	}
+	lockCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(leaderCtx, cancel)
+	return lockCtx, func(time.Time) {
+		stop()
+		cancel()
+	}, true, nil
+}
+
+// Stats returns the leadership of this instance.
+func (e *LeaderElector) Stats() LeaderStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	st := LeaderStats{
+		Owner:     e.owner,
+		Leader:    e.leaderCtx != nil,
+		Elections: e.elections.Load(),
+		Lost:      e.lost.Load(),
+	}
+	if st.Leader {
+		since := e.since
+		st.Since = &since
+	}
+	return st
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// listingActionsLimit is how many actions the action log returns.
+const listingActionsLimit = 200
+
+var ErrListingRuleNotFound = errors.New("listing rule not found")
+
+// ListingEvaluation summarizes a pass over the listing rules.
+type ListingEvaluation struct {
+	Items       int `json:"items"`
+	Paused      int `json:"paused"`
+	Reactivated int `json:"reactivated"`
+	// Errors lists the listings that could not be evaluated or changed.
+	Errors []string `json:"errors"`
+}
+
+// ListingAutomationService pauses the seller's listings while their rules
+// say they cannot be fulfilled, e.g. with no FULL stock left or during a
+// scheduled break, and reactivates them afterwards. Every change is logged.
+type ListingAutomationService struct {
+	meliClient *meli.MeliClient
+	repo       *repository.ListingRuleRepository
+}
+
+func NewListingAutomationService(meliClient *meli.MeliClient, repo *repository.ListingRuleRepository) *ListingAutomationService {
+	return &ListingAutomationService{
+		meliClient: meliClient,
+		repo:       repo,
+	}
+}
+
+// ValidateListingRule checks that a rule can be evaluated.
+func ValidateListingRule(r *repository.ListingRule) error {
+	if r.ItemID == "" {
+		return errors.New("item_id is required")
+	}
+	switch r.Trigger {
+	case repository.RuleTriggerSchedule:
+		if r.PauseAt == nil {
+			return errors.New("pause_at is required for schedule rules")
+		}
+		if r.ResumeAt != nil && !r.ResumeAt.After(*r.PauseAt) {
+			return errors.New("resume_at must be after pause_at")
+		}
+	case repository.RuleTriggerFullStock:
+		r.PauseAt, r.ResumeAt = nil, nil
+	default:
+		return errors.New("trigger must be schedule or full_stock")
+	}
+	return nil
+}
+
+// Rules lists the listing rules.
+func (s *ListingAutomationService) Rules(ctx context.Context) ([]repository.ListingRule, error) {
+	return s.repo.Rules(ctx)
+}
+
+// SaveRule creates a rule, or replaces the one with r.ID unless it changed
+// since r.Version (0 for the stored one). A replaced rule keeps holding the
+// listing it paused.
+func (s *ListingAutomationService) SaveRule(ctx context.Context, r *repository.ListingRule) error {
+	if r.ID == 0 {
+		r.Version = 0
+		return s.repo.SaveRule(ctx, r)
+	}
+	existing, err := s.repo.FindRule(ctx, r.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrListingRuleNotFound
+	}
+	if err := checkVersion(r.Version, existing.Version); err != nil {
+		return err
+	}
+	r.CreatedAt, r.Holding, r.Version = existing.CreatedAt, existing.Holding, existing.Version
+	updated, err := s.repo.UpdateRule(ctx, r)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// DeleteRule moves a rule to the trash. A listing it paused stays paused.
+func (s *ListingAutomationService) DeleteRule(ctx context.Context, id uint) error {
+	deleted, err := s.repo.DeleteRule(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrListingRuleNotFound
+	}
+	return nil
+}
+
+// TrashedRules lists the deleted rules, last deleted first.
+func (s *ListingAutomationService) TrashedRules(ctx context.Context) ([]repository.ListingRule, error) {
+	return s.repo.TrashedRules(ctx)
+}
+
+// RestoreRule takes a rule out of the trash. It keeps holding the listing
+// it paused before being deleted, so the next evaluation can reactivate it.
+func (s *ListingAutomationService) RestoreRule(ctx context.Context, id uint) (*repository.ListingRule, error) {
+	restored, err := s.repo.RestoreRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrListingRuleNotFound
+	}
+	rule, err := s.repo.FindRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return nil, ErrListingRuleNotFound
+	}
+	return rule, nil
+}
+
+// Actions returns the latest pauses and reactivations, of one listing when
+// itemID is set.
+func (s *ListingAutomationService) Actions(ctx context.Context, itemID string) ([]repository.ListingAction, error) {
+	return s.repo.Actions(ctx, itemID, listingActionsLimit)
+}
+
+// Evaluate applies every enabled rule.
+func (s *ListingAutomationService) Evaluate(ctx context.Context) (*ListingEvaluation, error) {
+	rules, err := s.repo.EnabledRules(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.evaluate(ctx, rules), nil
+}
+
+// EvaluateAll is Evaluate for the scheduler.
+func (s *ListingAutomationService) EvaluateAll(ctx context.Context) error {
+	report, err := s.Evaluate(ctx)
+	if err != nil {
+		return err
+	}
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("listing rules failed for %d of %d listings", len(report.Errors), report.Items)
+	}
+	return nil
+}
+
+// HandleItemNotification applies the rules of a listing Mercado Livre
+// reported a change of, e.g. a restock.
+func (s *ListingAutomationService) HandleItemNotification(ctx context.Context, n meli.Notification) error {
+	itemID := n.ResourceKey()
+	if itemID == "" {
+		return nil
+	}
+	rules, err := s.repo.EnabledRules(ctx, itemID)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+	report := s.evaluate(ctx, rules)
+	if len(report.Errors) > 0 {
+		return fmt.Errorf("listing rules failed for %s", itemID)
+	}
+	return nil
+}
+
+func (s *ListingAutomationService) evaluate(ctx context.Context, rules []repository.ListingRule) *ListingEvaluation {
+	report := &ListingEvaluation{Errors: []string{}}
+	var order []string
+	byItem := map[string][]*repository.ListingRule{}
+	for i := range rules {
+		id := rules[i].ItemID
+		if _, ok := byItem[id]; !ok {
+			order = append(order, id)
+		}
+		byItem[id] = append(byItem[id], &rules[i])
+	}
+
+	now := time.Now()
+	for _, itemID := range order {
+		report.Items++
+		action, err := s.evaluateItem(ctx, itemID, byItem[itemID], now)
+		if err != nil {
+			log.Printf("[WARN] Listing rules for %s failed: %v", itemID, err)
+			report.Errors = append(report.Errors, itemID)
+		}
+		switch action {
+		case repository.ListingActionPause:
+			report.Paused++
+		case repository.ListingActionActivate:
+			report.Reactivated++
+		}
+	}
+	return report
+}
+
+// evaluateItem pauses a listing when any of its rules says so, and
+// reactivates it once none does and a rule was holding it. It returns the
+// action taken, if any.
+func (s *ListingAutomationService) evaluateItem(ctx context.Context, itemID string, rules []*repository.ListingRule, now time.Time) (string, error) {
+	item, err := s.meliClient.GetItem(ctx, itemID)
+	if err != nil {
+		return "", err
+	}
+
+	var pausing []*repository.ListingRule
+	var reason string
+	for _, rule := range rules {
+		pause, why, err := s.rulePauses(ctx, rule, item, now)
+		if err != nil {
+			return "", err
+		}
+		if pause {
+			pausing = append(pausing, rule)
+			if reason == "" {
+				reason = why
+			}
+		}
+	}
+
+	if len(pausing) > 0 {
+		if item.Status != meli.ItemStatusActive {
+			// Already paused (or closed): only take over a pause we made.
+			return "", s.setHolding(ctx, pausing, item.Status == meli.ItemStatusPaused && len(holdingRules(rules)) > 0)
+		}
+		if err := s.apply(ctx, pausing[0], itemID, repository.ListingActionPause, reason); err != nil {
+			return "", err
+		}
+		return repository.ListingActionPause, s.setHolding(ctx, pausing, true)
+	}
+
+	holding := holdingRules(rules)
+	if len(holding) == 0 {
+		return "", nil
+	}
+	if item.Status != meli.ItemStatusPaused {
+		// Reactivated or closed by the seller meanwhile.
+		return "", s.setHolding(ctx, holding, false)
+	}
+	if err := s.apply(ctx, holding[0], itemID, repository.ListingActionActivate, "no rule pauses the listing anymore"); err != nil {
+		return "", err
+	}
+	return repository.ListingActionActivate, s.setHolding(ctx, holding, false)
+}
+
+// rulePauses reports whether a rule wants the listing paused now, and why.
+func (s *ListingAutomationService) rulePauses(ctx context.Context, rule *repository.ListingRule, item *meli.Item, now time.Time) (bool, string, error) {
+	switch rule.Trigger {
+	case repository.RuleTriggerSchedule:
+		if now.Before(*rule.PauseAt) || (rule.ResumeAt != nil && !now.Before(*rule.ResumeAt)) {
+			return false, "", nil
+		}
+		if rule.ResumeAt == nil {
+			return true, "scheduled pause", nil
+		}
+		return true, "scheduled pause until " + rule.ResumeAt.Format(time.RFC3339), nil
+	case repository.RuleTriggerFullStock:
+		if !item.Fulfilled() {
+			return false, "", nil
+		}
+		stock, err := s.meliClient.GetFulfillmentStock(ctx, item.InventoryID)
+		if err != nil {
+			return false, "", err
+		}
+		return stock.AvailableQuantity <= 0, "no stock left in FULL", nil
+	}
+	return false, "", nil
+}
+
+// apply changes the listing's status and logs the action, failed or not.
+func (s *ListingAutomationService) apply(ctx context.Context, rule *repository.ListingRule, itemID, action, reason string) error {
+	status := meli.ItemStatusPaused
+	if action == repository.ListingActionActivate {
+		status = meli.ItemStatusActive
+	}
+	err := s.meliClient.SetItemStatus(ctx, itemID, status)
+
+	entry := &repository.ListingAction{RuleID: rule.ID, ItemID: itemID, Action: action, Reason: reason}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if logErr := s.repo.LogAction(context.WithoutCancel(ctx), entry); logErr != nil {
+		log.Printf("[ERROR] Failed to log %s of %s: %v", action, itemID, logErr)
+	}
+	return err
+}
+
+func (s *ListingAutomationService) setHolding(ctx context.Context, rules []*repository.ListingRule, holding bool) error {
+	for _, rule := range rules {
+		if rule.Holding == holding {
+			continue
+		}
+		rule.Holding = holding
+		if err := s.repo.SetHolding(ctx, rule.ID, holding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func holdingRules(rules []*repository.ListingRule) []*repository.ListingRule {
+	var out []*repository.ListingRule
+	for _, rule := range rules {
+		if rule.Holding {
+			out = append(out, rule)
+		}
+	}
+	return out
+}
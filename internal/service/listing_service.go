@@ -0,0 +1,712 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// Validation checks, as reported in ListingProblem.Check.
+const (
+	CheckRequired       = "required_fields"
+	CheckCategory       = "category"
+	CheckAttributes     = "attributes"
+	CheckTitle          = "title"
+	CheckForbiddenWords = "forbidden_words"
+	CheckPrice          = "price"
+	CheckPictures       = "pictures"
+	CheckMercadoLivre   = "mercado_livre"
+)
+
+// Problem severities: errors make Mercado Livre reject the listing,
+// warnings hurt it.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+const (
+	// minTitleLength is below what titles rank well with.
+	minTitleLength = 20
+	// minPictureSide and recommendedPictureSide are Mercado Livre's minimum
+	// and recommended size of the smaller side of a picture, in pixels.
+	minPictureSide         = 500
+	recommendedPictureSide = 1200
+	// maxPictureBytes is the largest picture Mercado Livre accepts.
+	maxPictureBytes = 10 << 20
+	// defaultMaxPictures applies when the category has no setting.
+	defaultMaxPictures = 12
+	pictureTimeout     = 10 * time.Second
+)
+
+// forbiddenTitleTerms are not allowed in titles: shipping, price and
+// promotion claims belong in the listing's settings, and counterfeits are
+// banned. Matched without accents.
+var forbiddenTitleTerms = []string{
+	"frete gratis", "envio gratis", "promocao", "oferta", "desconto", "liquidacao",
+	"queima de estoque", "melhor preco", "mais barato", "replica", "falsificado",
+	"pirata", "similar", "whatsapp",
+}
+
+var (
+	titleContactPattern = regexp.MustCompile(`(?i)https?://|www\.|\.com\b|@[a-z0-9-]+\.|\d{8,}`)
+	// friendlyCauses rewords the Mercado Livre validation causes sellers
+	// hit most.
+	friendlyCauses = map[string]string{
+		"item.attributes.missing_required": "Mercado Livre requires more attributes for this category",
+		"item.title.length.invalid":        "the title is too long for this category",
+		"item.price.invalid":               "the price is outside what this category allows",
+		"item.pictures.max":                "there are more pictures than this category allows",
+		"item.category_id.invalid":         "the category does not accept listings; pick a more specific one",
+		"item.listing_type_id.invalid":     "the listing type is not available for this category or account",
+		"item.available_quantity.invalid":  "the available quantity is not allowed for this listing type",
+		"item.condition.invalid":           "the condition is not accepted in this category",
+		"item.currency_id.invalid":         "the currency is not accepted on this site",
+	}
+)
+
+// ListingProblem is one problem found in a listing draft. Code is Mercado
+// Livre's cause code for problems its own validation found.
+type ListingProblem struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+	Code     string `json:"code,omitempty"`
+}
+
+// ListingFees is what Mercado Livre charges for a draft at its price.
+// Margin is left after fees and the SKU's recorded unit cost, when known.
+type ListingFees struct {
+	ListingFee float64  `json:"listing_fee"`
+	SaleFee    float64  `json:"sale_fee"`
+	Net        float64  `json:"net"`
+	UnitCost   *float64 `json:"unit_cost,omitempty"`
+	Margin     *float64 `json:"margin,omitempty"`
+}
+
+// ListingValidation is the preflight report of a listing draft. Valid means
+// no check found an error; warnings may remain.
+type ListingValidation struct {
+	Valid    bool             `json:"valid"`
+	Problems []ListingProblem `json:"problems"`
+	Fees     *ListingFees     `json:"fees,omitempty"`
+}
+
+func (v *ListingValidation) add(check, severity, field, format string, args ...interface{}) {
+	v.Problems = append(v.Problems, ListingProblem{Check: check, Severity: severity, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+var (
+	ErrListingTemplateNotFound = errors.New("listing template not found")
+	ErrListingInvalid          = errors.New("the listing has problems; fix them and publish again")
+)
+
+// ListingTemplate is a listing template as edited by the seller. Drafts
+// made from it take its category, settings, attributes, description and
+// shipping; the description is its blocks, one paragraph each.
+type ListingTemplate struct {
+	ID                uint                  `json:"id"`
+	Name              string                `json:"name"`
+	CategoryID        string                `json:"category_id"`
+	ListingType       string                `json:"listing_type_id,omitempty"`
+	Condition         string                `json:"condition,omitempty"`
+	CurrencyID        string                `json:"currency_id,omitempty"`
+	BuyingMode        string                `json:"buying_mode,omitempty"`
+	Attributes        []meli.Attribute      `json:"attributes"`
+	DescriptionBlocks []string              `json:"description_blocks"`
+	Shipping          *meli.NewItemShipping `json:"shipping,omitempty"`
+	CreatedAt         time.Time             `json:"created_at"`
+	UpdatedAt         time.Time             `json:"updated_at"`
+	DeletedAt         *time.Time            `json:"deleted_at,omitempty"`
+	// Version counts the edits; an edit sent with the version it was based
+	// on fails with ErrVersionConflict if someone else saved one since.
+	Version uint `json:"version"`
+}
+
+// ListingOverrides is what sets one listing apart from its template. Empty
+// fields keep the template's value; Attributes replace the template's
+// attributes with the same ID and add the others, and Description comes
+// before the template's blocks.
+type ListingOverrides struct {
+	Title        string                `json:"title"`
+	Price        float64               `json:"price"`
+	AvailableQty int                   `json:"available_quantity"`
+	CurrencyID   string                `json:"currency_id"`
+	ListingType  string                `json:"listing_type_id"`
+	Condition    string                `json:"condition"`
+	Description  string                `json:"description"`
+	Pictures     []meli.NewItemPicture `json:"pictures"`
+	Attributes   []meli.Attribute      `json:"attributes"`
+	Shipping     *meli.NewItemShipping `json:"shipping"`
+}
+
+// ListingService prepares the seller's new listings.
+type ListingService struct {
+	meliClient   *meli.MeliClient
+	costRepo     *repository.ProductCostRepository
+	templateRepo *repository.ListingTemplateRepository
+	// pictures downloads draft pictures to check their size.
+	pictures *http.Client
+}
+
+func NewListingService(meliClient *meli.MeliClient, costRepo *repository.ProductCostRepository, templateRepo *repository.ListingTemplateRepository) *ListingService {
+	return &ListingService{
+		meliClient:   meliClient,
+		costRepo:     costRepo,
+		templateRepo: templateRepo,
+		pictures:     &http.Client{Timeout: pictureTimeout},
+	}
+}
+
+// Validate runs a listing draft through every preflight check before it is
+// published: required fields, the category's rules and attributes, title
+// length and forbidden words, price against the fees and the SKU's cost,
+// pictures, and finally Mercado Livre's own validation, reworded.
+func (s *ListingService) Validate(ctx context.Context, draft meli.NewItem) (*ListingValidation, error) {
+	v := &ListingValidation{Problems: []ListingProblem{}}
+	checkRequiredFields(v, draft)
+	if draft.CategoryID != "" {
+		if err := s.checkCategory(ctx, v, draft); err != nil {
+			return nil, err
+		}
+	}
+	checkTitleWords(v, draft.Title)
+	if err := s.checkFees(ctx, v, draft); err != nil {
+		return nil, err
+	}
+	s.checkPictures(ctx, v, draft.Pictures)
+
+	causes, err := s.meliClient.ValidateItem(ctx, draft)
+	if err != nil {
+		return nil, err
+	}
+	for _, cause := range causes {
+		message := cause.Message
+		if friendly, ok := friendlyCauses[cause.Code]; ok {
+			message = friendly + " (" + cause.Message + ")"
+		}
+		severity := SeverityError
+		if cause.Type == SeverityWarning {
+			severity = SeverityWarning
+		}
+		v.Problems = append(v.Problems, ListingProblem{
+			Check:    CheckMercadoLivre,
+			Severity: severity,
+			Field:    strings.Join(cause.References, ","),
+			Message:  message,
+			Code:     cause.Code,
+		})
+	}
+
+	v.Valid = !slices.ContainsFunc(v.Problems, func(p ListingProblem) bool { return p.Severity == SeverityError })
+	return v, nil
+}
+
+func checkRequiredFields(v *ListingValidation, draft meli.NewItem) {
+	missing := func(field string) {
+		v.add(CheckRequired, SeverityError, field, "%s is required", field)
+	}
+	if strings.TrimSpace(draft.Title) == "" {
+		missing("title")
+	}
+	if draft.CategoryID == "" {
+		missing("category_id")
+	}
+	if draft.Price <= 0 {
+		v.add(CheckRequired, SeverityError, "price", "price must be positive")
+	}
+	if draft.CurrencyID == "" {
+		missing("currency_id")
+	}
+	if draft.AvailableQty <= 0 {
+		v.add(CheckRequired, SeverityError, "available_quantity", "available_quantity must be positive")
+	}
+	if draft.BuyingMode == "" {
+		missing("buying_mode")
+	}
+	if draft.ListingType == "" {
+		missing("listing_type_id")
+	}
+	if draft.Condition == "" {
+		missing("condition")
+	}
+}
+
+// checkCategory checks the draft against its category's settings and
+// attributes.
+func (s *ListingService) checkCategory(ctx context.Context, v *ListingValidation, draft meli.NewItem) error {
+	category, err := s.meliClient.GetCategory(ctx, draft.CategoryID)
+	if errors.Is(err, meli.ErrNotFound) {
+		v.add(CheckCategory, SeverityError, "category_id", "category %s does not exist", draft.CategoryID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	settings := category.Settings
+	if !settings.ListingAllowed {
+		v.add(CheckCategory, SeverityError, "category_id", "%s does not accept listings; pick one of its subcategories", category.Name)
+	}
+	if category.CatalogRequired() {
+		v.add(CheckCategory, SeverityWarning, "category_id", "%s requires listings to join a catalog product", category.Name)
+	}
+	if draft.Condition != "" && len(settings.ItemConditions) > 0 && !slices.Contains(settings.ItemConditions, draft.Condition) {
+		v.add(CheckCategory, SeverityError, "condition", "condition must be one of %s in this category", strings.Join(settings.ItemConditions, ", "))
+	}
+	if draft.CurrencyID != "" && len(settings.Currencies) > 0 && !slices.Contains(settings.Currencies, draft.CurrencyID) {
+		v.add(CheckCategory, SeverityError, "currency_id", "currency must be one of %s", strings.Join(settings.Currencies, ", "))
+	}
+	if settings.MinimumPrice > 0 && draft.Price > 0 && draft.Price < settings.MinimumPrice {
+		v.add(CheckPrice, SeverityError, "price", "the minimum price in this category is %.2f", settings.MinimumPrice)
+	}
+	if settings.MaximumPrice > 0 && draft.Price > settings.MaximumPrice {
+		v.add(CheckPrice, SeverityError, "price", "the maximum price in this category is %.2f", settings.MaximumPrice)
+	}
+
+	maxLength := settings.MaxTitleLength
+	if maxLength == 0 {
+		maxLength = maxTitleLength
+	}
+	if n := len([]rune(draft.Title)); n > maxLength {
+		v.add(CheckTitle, SeverityError, "title", "the title has %d characters; this category allows %d", n, maxLength)
+	} else if n > 0 && n < minTitleLength {
+		v.add(CheckTitle, SeverityWarning, "title", "the title has only %d characters; state the product, brand, model and key attributes", n)
+	}
+	maxPictures := settings.MaxPicturesPerItem
+	if maxPictures == 0 {
+		maxPictures = defaultMaxPictures
+	}
+	if len(draft.Pictures) > maxPictures {
+		v.add(CheckPictures, SeverityError, "pictures", "there are %d pictures; this category allows %d", len(draft.Pictures), maxPictures)
+	}
+
+	attrs, err := s.meliClient.GetCategoryAttributes(ctx, draft.CategoryID)
+	if err != nil {
+		return err
+	}
+	given := make(map[string]bool, len(draft.Attributes))
+	for _, a := range draft.Attributes {
+		if a.ValueID != "" || strings.TrimSpace(a.ValueName) != "" {
+			given[a.ID] = true
+		}
+	}
+	var required, catalog []string
+	for _, a := range attrs {
+		switch {
+		case a.Tags.ReadOnly && given[a.ID]:
+			v.add(CheckAttributes, SeverityWarning, a.ID, "%s is set by Mercado Livre and will be ignored", a.Name)
+		case a.Tags.Required && !given[a.ID]:
+			required = append(required, a.Name+" ("+a.ID+")")
+		case a.Tags.CatalogRequired && !given[a.ID]:
+			catalog = append(catalog, a.Name+" ("+a.ID+")")
+		}
+	}
+	if len(required) > 0 {
+		v.add(CheckAttributes, SeverityError, "attributes", "missing required attributes: %s", strings.Join(required, ", "))
+	}
+	if len(catalog) > 0 {
+		v.add(CheckAttributes, SeverityWarning, "attributes", "missing attributes needed to join the catalog: %s", strings.Join(catalog, ", "))
+	}
+	return nil
+}
+
+// checkTitleWords flags promotion claims, counterfeit terms, contact
+// details and shouting in the title.
+func checkTitleWords(v *ListingValidation, title string) {
+	folded := " " + accentFolder.Replace(strings.ToLower(title)) + " "
+	for _, term := range forbiddenTitleTerms {
+		if strings.Contains(folded, " "+term+" ") {
+			v.add(CheckForbiddenWords, SeverityError, "title", "remove %q from the title; Mercado Livre does not allow it", term)
+		}
+	}
+	if titleContactPattern.MatchString(title) {
+		v.add(CheckForbiddenWords, SeverityError, "title", "remove links, e-mails and phone numbers from the title")
+	}
+	letters, upper := 0, 0
+	for _, r := range title {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= 10 && upper == letters {
+		v.add(CheckTitle, SeverityWarning, "title", "the title is all capitals; write it in normal case")
+	}
+}
+
+// checkFees compares the price with Mercado Livre's fees and the SKU's
+// recorded unit cost.
+func (s *ListingService) checkFees(ctx context.Context, v *ListingValidation, draft meli.NewItem) error {
+	if draft.Price <= 0 || draft.CategoryID == "" || draft.ListingType == "" {
+		return nil
+	}
+	prices, err := s.meliClient.ListingPrices(ctx, draft.CategoryID, draft.ListingType, draft.Price)
+	if errors.Is(err, meli.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fees := &ListingFees{
+		ListingFee: prices.ListingFeeAmount,
+		SaleFee:    prices.SaleFeeAmount,
+		Net:        roundCents(draft.Price - prices.ListingFeeAmount - prices.SaleFeeAmount),
+	}
+	v.Fees = fees
+	if fees.Net <= 0 {
+		v.add(CheckPrice, SeverityError, "price", "the fees (%.2f) take the whole price", prices.ListingFeeAmount+prices.SaleFeeAmount)
+		return nil
+	}
+
+	sku := draft.SKU()
+	if sku == "" || s.costRepo == nil {
+		return nil
+	}
+	cost, err := s.costRepo.FindBySKU(ctx, sku)
+	if err != nil || cost == nil {
+		return err
+	}
+	margin := roundCents(fees.Net - cost.UnitCost)
+	fees.UnitCost, fees.Margin = &cost.UnitCost, &margin
+	if margin < 0 {
+		v.add(CheckPrice, SeverityWarning, "price", "after %.2f in fees and a unit cost of %.2f, each sale loses %.2f", prices.ListingFeeAmount+prices.SaleFeeAmount, cost.UnitCost, -margin)
+	}
+	return nil
+}
+
+// checkPictures requires at least one picture and downloads each to check
+// its size.
+func (s *ListingService) checkPictures(ctx context.Context, v *ListingValidation, pictures []meli.NewItemPicture) {
+	if len(pictures) == 0 {
+		v.add(CheckPictures, SeverityError, "pictures", "add at least one picture")
+		return
+	}
+
+	problems := make([][]ListingProblem, len(pictures))
+	var wg sync.WaitGroup
+	for i, p := range pictures {
+		wg.Add(1)
+		go func(i int, source string) {
+			defer wg.Done()
+			pv := &ListingValidation{}
+			s.checkPicture(ctx, pv, fmt.Sprintf("pictures[%d]", i), source)
+			problems[i] = pv.Problems
+		}(i, p.Source)
+	}
+	wg.Wait()
+	for _, p := range problems {
+		v.Problems = append(v.Problems, p...)
+	}
+}
+
+func (s *ListingService) checkPicture(ctx context.Context, v *ListingValidation, field, source string) {
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add(CheckPictures, SeverityError, field, "the picture source must be an http or https URL")
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		v.add(CheckPictures, SeverityError, field, "the picture could not be downloaded: %v", err)
+		return
+	}
+	resp, err := s.pictures.Do(req)
+	if err != nil {
+		v.add(CheckPictures, SeverityError, field, "the picture could not be downloaded: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		v.add(CheckPictures, SeverityError, field, "the picture could not be downloaded: %s", resp.Status)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPictureBytes+1))
+	if err != nil {
+		v.add(CheckPictures, SeverityError, field, "the picture could not be downloaded: %v", err)
+		return
+	}
+	if len(body) > maxPictureBytes {
+		v.add(CheckPictures, SeverityError, field, "the picture is larger than %d MB", maxPictureBytes>>20)
+		return
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		v.add(CheckPictures, SeverityWarning, field, "the picture size could not be read; use JPG or PNG")
+		return
+	}
+	side := min(cfg.Width, cfg.Height)
+	switch {
+	case side < minPictureSide:
+		v.add(CheckPictures, SeverityError, field, "the picture is %dx%d px; its smaller side must be at least %d px", cfg.Width, cfg.Height, minPictureSide)
+	case side < recommendedPictureSide:
+		v.add(CheckPictures, SeverityWarning, field, "the picture is %dx%d px; %d px or more enables zoom", cfg.Width, cfg.Height, recommendedPictureSide)
+	}
+}
+
+// ValidateListingTemplate checks that drafts can be made from a template.
+func ValidateListingTemplate(t *ListingTemplate) error {
+	if strings.TrimSpace(t.Name) == "" || strings.TrimSpace(t.CategoryID) == "" {
+		return errors.New("name and category_id are required")
+	}
+	for _, a := range t.Attributes {
+		if a.ID == "" {
+			return errors.New("every attribute needs an id")
+		}
+	}
+	return nil
+}
+
+// Templates lists the listing templates.
+func (s *ListingService) Templates(ctx context.Context) ([]ListingTemplate, error) {
+	rows, err := s.templateRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return listingTemplatesFromRows(rows)
+}
+
+// TrashedTemplates lists the deleted listing templates, last deleted first.
+func (s *ListingService) TrashedTemplates(ctx context.Context) ([]ListingTemplate, error) {
+	rows, err := s.templateRepo.TrashedTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return listingTemplatesFromRows(rows)
+}
+
+func listingTemplatesFromRows(rows []repository.ListingTemplate) ([]ListingTemplate, error) {
+	out := make([]ListingTemplate, 0, len(rows))
+	for i := range rows {
+		t, err := listingTemplateFromRow(&rows[i])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, nil
+}
+
+// SaveTemplate creates a template, or replaces the one with t.ID unless it
+// changed since t.Version (0 for the stored one).
+func (s *ListingService) SaveTemplate(ctx context.Context, t *ListingTemplate) error {
+	row, err := listingTemplateRow(t)
+	if err != nil {
+		return err
+	}
+	if t.ID != 0 {
+		existing, err := s.templateRepo.FindByID(ctx, t.ID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return ErrListingTemplateNotFound
+		}
+		if err := checkVersion(t.Version, existing.Version); err != nil {
+			return err
+		}
+		row.CreatedAt, row.Version = existing.CreatedAt, existing.Version
+	}
+
+	if row.ID == 0 {
+		if err := s.templateRepo.Save(ctx, row); err != nil {
+			return err
+		}
+	} else {
+		updated, err := s.templateRepo.Update(ctx, row)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrVersionConflict
+		}
+	}
+	t.ID, t.CreatedAt, t.UpdatedAt, t.Version = row.ID, row.CreatedAt, row.UpdatedAt, row.Version
+	return nil
+}
+
+// DeleteTemplate moves a template to the trash.
+func (s *ListingService) DeleteTemplate(ctx context.Context, id uint) error {
+	deleted, err := s.templateRepo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrListingTemplateNotFound
+	}
+	return nil
+}
+
+// RestoreTemplate takes a template out of the trash.
+func (s *ListingService) RestoreTemplate(ctx context.Context, id uint) (*ListingTemplate, error) {
+	restored, err := s.templateRepo.RestoreTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrListingTemplateNotFound
+	}
+	row, err := s.templateRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, ErrListingTemplateNotFound
+	}
+	return listingTemplateFromRow(row)
+}
+
+// DraftFromTemplate merges a template with one listing's overrides into a
+// draft ready to publish.
+func (s *ListingService) DraftFromTemplate(ctx context.Context, templateID uint, o ListingOverrides) (*meli.NewItem, error) {
+	row, err := s.templateRepo.FindByID(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, ErrListingTemplateNotFound
+	}
+	t, err := listingTemplateFromRow(row)
+	if err != nil {
+		return nil, err
+	}
+
+	draft := &meli.NewItem{
+		Title:        strings.TrimSpace(o.Title),
+		CategoryID:   t.CategoryID,
+		Price:        o.Price,
+		CurrencyID:   cmp.Or(o.CurrencyID, t.CurrencyID),
+		AvailableQty: o.AvailableQty,
+		BuyingMode:   cmp.Or(t.BuyingMode, "buy_it_now"),
+		ListingType:  cmp.Or(o.ListingType, t.ListingType),
+		Condition:    cmp.Or(o.Condition, t.Condition),
+		Pictures:     o.Pictures,
+		Shipping:     t.Shipping,
+	}
+	if o.Shipping != nil {
+		draft.Shipping = o.Shipping
+	}
+
+	draft.Attributes = make([]meli.Attribute, 0, len(t.Attributes)+len(o.Attributes))
+	for _, a := range t.Attributes {
+		if !slices.ContainsFunc(o.Attributes, func(b meli.Attribute) bool { return b.ID == a.ID }) {
+			draft.Attributes = append(draft.Attributes, a)
+		}
+	}
+	draft.Attributes = append(draft.Attributes, o.Attributes...)
+
+	var paragraphs []string
+	for _, block := range append([]string{o.Description}, t.DescriptionBlocks...) {
+		if block = strings.TrimSpace(block); block != "" {
+			paragraphs = append(paragraphs, block)
+		}
+	}
+	if len(paragraphs) > 0 {
+		draft.Description = &meli.NewItemText{PlainText: strings.Join(paragraphs, "\n\n")}
+	}
+	return draft, nil
+}
+
+// PublishFromTemplate publishes a listing made from a template and the
+// overrides. The draft goes through Validate first and is not published
+// when it has errors: the validation is returned with ErrListingInvalid.
+func (s *ListingService) PublishFromTemplate(ctx context.Context, templateID uint, o ListingOverrides) (*meli.Item, *ListingValidation, error) {
+	draft, err := s.DraftFromTemplate(ctx, templateID, o)
+	if err != nil {
+		return nil, nil, err
+	}
+	validation, err := s.Validate(ctx, *draft)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !validation.Valid {
+		return nil, validation, ErrListingInvalid
+	}
+	item, err := s.meliClient.CreateItem(ctx, *draft)
+	if err != nil {
+		return nil, validation, err
+	}
+	return item, validation, nil
+}
+
+// listingTemplateRow returns the row storing t, with its ID.
+func listingTemplateRow(t *ListingTemplate) (*repository.ListingTemplate, error) {
+	row := &repository.ListingTemplate{
+		ID:          t.ID,
+		Name:        strings.TrimSpace(t.Name),
+		CategoryID:  t.CategoryID,
+		ListingType: t.ListingType,
+		Condition:   t.Condition,
+		CurrencyID:  t.CurrencyID,
+		BuyingMode:  t.BuyingMode,
+	}
+	if t.Attributes == nil {
+		t.Attributes = []meli.Attribute{}
+	}
+	if t.DescriptionBlocks == nil {
+		t.DescriptionBlocks = []string{}
+	}
+	attrs, err := json.Marshal(t.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := json.Marshal(t.DescriptionBlocks)
+	if err != nil {
+		return nil, err
+	}
+	row.Attributes, row.DescriptionBlocks = string(attrs), string(blocks)
+	if t.Shipping != nil {
+		row.ShippingMode, row.FreeShipping, row.LocalPickUp = t.Shipping.Mode, t.Shipping.FreeShipping, t.Shipping.LocalPickUp
+	}
+	return row, nil
+}
+
+func listingTemplateFromRow(row *repository.ListingTemplate) (*ListingTemplate, error) {
+	t := &ListingTemplate{
+		ID:          row.ID,
+		Name:        row.Name,
+		CategoryID:  row.CategoryID,
+		ListingType: row.ListingType,
+		Condition:   row.Condition,
+		CurrencyID:  row.CurrencyID,
+		BuyingMode:  row.BuyingMode,
+		CreatedAt:   row.CreatedAt,
+		UpdatedAt:   row.UpdatedAt,
+		Version:     row.Version,
+	}
+	if row.DeletedAt.Valid {
+		t.DeletedAt = &row.DeletedAt.Time
+	}
+	if err := json.Unmarshal([]byte(row.Attributes), &t.Attributes); err != nil {
+		return nil, fmt.Errorf("listing template %d attributes: %w", row.ID, err)
+	}
+	if err := json.Unmarshal([]byte(row.DescriptionBlocks), &t.DescriptionBlocks); err != nil {
+		return nil, fmt.Errorf("listing template %d description: %w", row.ID, err)
+	}
+	if row.ShippingMode != "" || row.FreeShipping || row.LocalPickUp {
+		t.Shipping = &meli.NewItemShipping{Mode: row.ShippingMode, FreeShipping: row.FreeShipping, LocalPickUp: row.LocalPickUp}
+	}
+	return t, nil
+}
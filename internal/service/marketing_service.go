@@ -2,75 +2,395 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
 
-	"melibot/internal/api"
+	"melibot/internal/cache"
+	"melibot/internal/events"
 	"melibot/internal/repository"
+	"melibot/pkg/meli"
 )
 
+const rootCategoriesCacheKey = "categories:root"
+
 // MarketingService encapsulates business logic for marketing/sales analysis.
 type MarketingService struct {
-	meliClient *api.MeliClient
+	meliClient *meli.MeliClient
 	trendRepo  *repository.TrendRepository
+	cache      *cache.Cache
+	bus        *events.Bus
 }
 
-func NewMarketingService(meliClient *api.MeliClient, trendRepo *repository.TrendRepository) *MarketingService {
+func NewMarketingService(meliClient *meli.MeliClient, trendRepo *repository.TrendRepository, cache *cache.Cache, bus *events.Bus) *MarketingService {
 	return &MarketingService{
 		meliClient: meliClient,
 		trendRepo:  trendRepo,
+		cache:      cache,
+		bus:        bus,
+	}
+}
+
+// trendsCacheKey is per organization: each one stores its own snapshots.
+func trendsCacheKey(ctx context.Context, categoryID string, limit int) string {
+	return repository.ScopedKey(ctx, fmt.Sprintf("trends:%s:%d", categoryID, limit))
+}
+
+// TrendsResult is the top sold products of a category. Partial is set when
+// some products could not be fully fetched (see each item's errors) or the
+// deadline budget ran out.
+type TrendsResult struct {
+	Items   []meli.SearchItem `json:"items"`
+	Total   int               `json:"total"`
+	Partial bool              `json:"partial"`
+}
+
+// TopTrendsByCategory returns the top N sold products for a category,
+// served from the response cache when fresh. A deadline on ctx acts as the
+// budget for the whole fan-out.
+func (s *MarketingService) TopTrendsByCategory(ctx context.Context, categoryID string, limit int) (*TrendsResult, error) {
+	if cached, ok := s.cache.Get(trendsCacheKey(ctx, categoryID, limit)); ok {
+		return cached.(*TrendsResult), nil
 	}
+	return s.RefreshTopTrends(ctx, categoryID, limit)
 }
 
-// TopTrendsByCategory returns the top N sold products for a category
-// and stores their metrics for trend analysis.
-func (s *MarketingService) TopTrendsByCategory(ctx context.Context, categoryID string, limit int) ([]api.SearchItem, error) {
-	ids, err := s.meliClient.TopSoldByCategory(ctx, categoryID, limit)
+// RefreshTopTrends fetches the top N sold products for a category from
+// Mercado Livre, stores their metrics for trend analysis and updates the cache.
+// Partial results are returned but never cached.
+func (s *MarketingService) RefreshTopTrends(ctx context.Context, categoryID string, limit int) (*TrendsResult, error) {
+	top, err := s.meliClient.TopSoldByCategory(ctx, categoryID, limit)
 	if err != nil {
 		return nil, err
 	}
-	items := make([]api.SearchItem, 0, len(ids))
-
-	for _, id := range ids {
-		items = append(items, api.SearchItem{
-			ID:           id.ID,
-			Title:        id.Title, // preencher depois com dados do /items/{id}
-			Price:        id.Price, // idem
-			Thumbnail:    id.Thumbnail,
-			SoldQuantity: id.SoldQuantity,
-			Health:       id.Health,
-			CategoryID:   id.CategoryID, // cuidado: aqui não é o mesmo que ProductID
-			Permalink:    id.Permalink,
-		})
-	}
+	items := trendItems(top)
 
-	/*trends := make([]repository.ProductTrend, 0, len(items))
-	for _, it := range items {
+	trends := make([]repository.ProductTrend, 0, len(items))
+	for i, it := range items {
+		if len(it.Errors) > 0 {
+			// Incomplete records would skew trend analysis.
+			continue
+		}
 		trends = append(trends, repository.ProductTrend{
-			ProductID:    it.ID,
-			Title:        it.Title,
-			CategoryID:   it.CategoryID,
-			SoldQuantity: it.SoldQuantity,
-			Health:       it.Health,
-			Price:        it.Price,
-			Thumbnail:    it.Thumbnail,
-			Permalink:    it.Permalink,
+			ProductID:           it.ID,
+			Title:               it.Title,
+			CategoryID:          it.CategoryID,
+			SoldQuantity:        it.SoldQuantity,
+			Health:              it.Health,
+			Price:               it.Price,
+			Thumbnail:           it.Thumbnail,
+			Permalink:           it.Permalink,
+			HighlightCategoryID: categoryID,
+			Rank:                i + 1,
 		})
-	}*/
+	}
+
+	// Persist trend data (best-effort; it feeds the local search but must not
+	// break the live response). The budget may already be spent, so don't let
+	// its deadline cancel the insert.
+	if err := s.trendRepo.SaveProductTrends(context.WithoutCancel(ctx), trends); err != nil {
+		log.Printf("[ERROR] Failed to persist trends for category %s: %v", categoryID, err)
+	}
+
+	result := &TrendsResult{Items: items, Total: top.Total, Partial: top.Partial}
+	s.bus.Publish(context.WithoutCancel(ctx), events.TrendSnapshotCompleted{CategoryID: categoryID, Products: len(trends), Partial: result.Partial})
+	if !result.Partial {
+		s.cache.Set(trendsCacheKey(ctx, categoryID, limit), result)
+	}
+	return result, nil
+}
 
-	/*// Persist trend data (best-effort; surface error to caller).
-	if err := s.trendRepo.SaveProductTrends(ctx, trends); err != nil {
+// TopTrendsWithoutPrices returns the top N sold products of a category
+// without looking up the best price of catalog products, for callers that
+// don't show prices. A fresh cached result is reused as is; a new fetch is
+// neither cached nor stored, since snapshots without prices would skew the
+// price history.
+func (s *MarketingService) TopTrendsWithoutPrices(ctx context.Context, categoryID string, limit int) (*TrendsResult, error) {
+	if cached, ok := s.cache.Get(trendsCacheKey(ctx, categoryID, limit)); ok {
+		return cached.(*TrendsResult), nil
+	}
+	top, err := s.meliClient.TopSoldByCategoryWith(ctx, categoryID, limit, meli.TopSoldOptions{SkipBestPrice: true})
+	if err != nil {
 		return nil, err
-	}*/
+	}
+	return &TrendsResult{Items: trendItems(top), Total: top.Total, Partial: top.Partial}, nil
+}
+
+// trendItems copies the fields of the highlights a TrendsResult exposes,
+// with listings of the same catalog product merged.
+func trendItems(top *meli.TopSoldResult) []meli.SearchItem {
+	items := make([]meli.SearchItem, 0, len(top.Items))
+	for _, id := range top.Items {
+		items = append(items, meli.SearchItem{
+			ID:               id.ID,
+			Title:            id.Title, // preencher depois com dados do /items/{id}
+			Price:            id.Price, // idem
+			Thumbnail:        id.Thumbnail,
+			SoldQuantity:     id.SoldQuantity,
+			Health:           id.Health,
+			CategoryID:       id.CategoryID, // cuidado: aqui não é o mesmo que ProductID
+			Permalink:        id.Permalink,
+			Errors:           id.Errors,
+			CatalogProductID: id.CatalogProductID,
+		})
+	}
+	items = dedupeTrends(items)
+	for i := range items {
+		if h, ok := meli.ParseHealth(items[i].Health); ok {
+			items[i].HealthScore = &h
+		}
+	}
+	return items
+}
 
-	return items, nil
+// WithMinHealth returns a copy of r without the items whose health score is
+// below minHealth or unknown. r itself may be cached, so it is left untouched.
+func (r *TrendsResult) WithMinHealth(minHealth float64) *TrendsResult {
+	out := *r
+	out.Items = make([]meli.SearchItem, 0, len(r.Items))
+	for _, it := range r.Items {
+		if it.HealthScore != nil && *it.HealthScore >= minHealth {
+			out.Items = append(out.Items, it)
+		}
+	}
+	return &out
+}
+
+// dedupeTrends merges highlights that are the same catalog product, e.g.
+// a PRODUCT and one of its listings ranked as an ITEM. The merged entry
+// takes the better rank and is the catalog product when it is in the list;
+// data it lacks is taken from the others, whose IDs go to MergedIDs.
+func dedupeTrends(items []meli.SearchItem) []meli.SearchItem {
+	out := make([]meli.SearchItem, 0, len(items))
+	byCatalog := make(map[string]int)
+	for _, it := range items {
+		key := it.CatalogProductID
+		if key == "" {
+			out = append(out, it)
+			continue
+		}
+		i, seen := byCatalog[key]
+		if !seen {
+			byCatalog[key] = len(out)
+			out = append(out, it)
+			continue
+		}
+
+		kept, other := out[i], it
+		if other.ID == key {
+			// The catalog product represents the group
+			kept, other = other, kept
+		}
+		kept.MergedIDs = append(append(kept.MergedIDs, other.ID), other.MergedIDs...)
+		kept.SoldQuantity = max(kept.SoldQuantity, other.SoldQuantity)
+		if kept.Price == 0 && other.Price > 0 {
+			kept.Price = other.Price
+			kept.Errors = slices.DeleteFunc(kept.Errors, func(e string) bool {
+				return strings.HasPrefix(e, "price: ")
+			})
+		}
+		if kept.Title == "" {
+			kept.Title = other.Title
+		}
+		if kept.Thumbnail == "" {
+			kept.Thumbnail = other.Thumbnail
+		}
+		if kept.Permalink == "" {
+			kept.Permalink = other.Permalink
+		}
+		if kept.Health == "" {
+			kept.Health = other.Health
+		}
+		if len(kept.Errors) == 0 {
+			kept.Errors = nil
+		}
+		out[i] = kept
+	}
+	return out
 }
 
 // RootCategories lists the main Mercado Livre categories for MLB.
-func (s *MarketingService) RootCategories(ctx context.Context) ([]api.Category, error) {
-	return s.meliClient.RootCategories(ctx)
+func (s *MarketingService) RootCategories(ctx context.Context) ([]meli.Category, error) {
+	if cached, ok := s.cache.Get(rootCategoriesCacheKey); ok {
+		return cached.([]meli.Category), nil
+	}
+	return s.RefreshRootCategories(ctx)
+}
+
+// RefreshRootCategories fetches the root categories and updates the cache.
+func (s *MarketingService) RefreshRootCategories(ctx context.Context) ([]meli.Category, error) {
+	cats, err := s.meliClient.RootCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Set(rootCategoriesCacheKey, cats)
+	return cats, nil
+}
+
+// AttributeRef names an attribute of a category.
+type AttributeRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PredictionDetail is a category predictor suggestion with what listing in
+// the category takes. Error is set when the category could not be looked
+// up; the prediction itself is still returned.
+type PredictionDetail struct {
+	meli.CategoryPrediction
+	PathFromRoot       []meli.Category `json:"path_from_root"`
+	CatalogRequired    bool            `json:"catalog_required"`
+	RequiredAttributes []AttributeRef  `json:"required_attributes"`
+	CatalogAttributes  []AttributeRef  `json:"catalog_attributes"`
+	Error              string          `json:"error,omitempty"`
+}
+
+// categoryRules is what PredictionDetail adds to a prediction, cached per
+// category.
+type categoryRules struct {
+	path               []meli.Category
+	catalogRequired    bool
+	requiredAttributes []AttributeRef
+	catalogAttributes  []AttributeRef
+}
+
+func categoryRulesCacheKey(categoryID string) string {
+	return "categories:rules:" + categoryID
 }
 
 // SuggestCategories uses the Mercado Livre category predictor to suggest
-// categories based on a free-text query.
-func (s *MarketingService) SuggestCategories(ctx context.Context, query string) ([]api.CategoryPrediction, error) {
-	return s.meliClient.PredictCategory(ctx, query)
+// categories based on a free-text query, each with its path from the root
+// category and whether listing in it takes a catalog product or specific
+// attributes.
+func (s *MarketingService) SuggestCategories(ctx context.Context, query string) ([]PredictionDetail, error) {
+	preds, err := s.meliClient.PredictCategory(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]PredictionDetail, len(preds))
+	var wg sync.WaitGroup
+	for i, p := range preds {
+		out[i] = PredictionDetail{CategoryPrediction: p}
+		wg.Add(1)
+		go func(d *PredictionDetail) {
+			defer wg.Done()
+			rules, err := s.categoryRules(ctx, d.ID)
+			if err != nil {
+				log.Printf("[WARN] Failed to look up category %s: %v", d.ID, err)
+				d.Error = err.Error()
+				return
+			}
+			d.PathFromRoot = rules.path
+			d.CatalogRequired = rules.catalogRequired
+			d.RequiredAttributes = rules.requiredAttributes
+			d.CatalogAttributes = rules.catalogAttributes
+		}(&out[i])
+	}
+	wg.Wait()
+	return out, nil
+}
+
+// categoryRules fetches a category and its attributes, served from the
+// response cache when fresh.
+func (s *MarketingService) categoryRules(ctx context.Context, categoryID string) (*categoryRules, error) {
+	if cached, ok := s.cache.Get(categoryRulesCacheKey(categoryID)); ok {
+		return cached.(*categoryRules), nil
+	}
+
+	cat, err := s.meliClient.GetCategory(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := s.meliClient.GetCategoryAttributes(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &categoryRules{
+		path:               cat.PathFromRoot,
+		catalogRequired:    cat.CatalogRequired(),
+		requiredAttributes: []AttributeRef{},
+		catalogAttributes:  []AttributeRef{},
+	}
+	for _, a := range attrs {
+		// Hidden and read-only attributes are filled by Mercado Livre
+		if a.Tags.Hidden || a.Tags.ReadOnly {
+			continue
+		}
+		ref := AttributeRef{ID: a.ID, Name: a.Name}
+		if a.Tags.Required {
+			rules.requiredAttributes = append(rules.requiredAttributes, ref)
+		}
+		if a.Tags.CatalogRequired {
+			rules.catalogAttributes = append(rules.catalogAttributes, ref)
+		}
+	}
+	s.cache.Set(categoryRulesCacheKey(categoryID), rules)
+	return rules, nil
+}
+
+// predictorConcurrency bounds parallel calls to the category predictor in
+// batch suggestions.
+const predictorConcurrency = 4
+
+// CategorySuggestion holds the predictions for one title of a batch.
+type CategorySuggestion struct {
+	Title       string                    `json:"title"`
+	Predictions []meli.CategoryPrediction `json:"predictions"`
+	Error       string                    `json:"error,omitempty"`
+}
+
+// SuggestCategoriesBatch runs the category predictor over many titles with
+// bounded concurrency. Results keep the order of titles; a failed title
+// carries its error instead of failing the batch.
+func (s *MarketingService) SuggestCategoriesBatch(ctx context.Context, titles []string) []CategorySuggestion {
+	results := make([]CategorySuggestion, len(titles))
+	sem := make(chan struct{}, predictorConcurrency)
+	var wg sync.WaitGroup
+
+	for i, title := range titles {
+		results[i] = CategorySuggestion{Title: title, Predictions: []meli.CategoryPrediction{}}
+		if strings.TrimSpace(title) == "" {
+			results[i].Error = "empty title"
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, title string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			preds, err := s.meliClient.PredictCategory(ctx, title)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Predictions = append(results[i].Predictions, preds...)
+		}(i, title)
+	}
+	wg.Wait()
+	return results
+}
+
+// WarmCache pre-fetches root categories and the trends of the given hot
+// categories so the first dashboard load is served from cache. Failures for
+// individual categories are logged and do not stop the others.
+func (s *MarketingService) WarmCache(ctx context.Context, hotCategories []string, limit int) error {
+	if _, err := s.RefreshRootCategories(ctx); err != nil {
+		return fmt.Errorf("warm root categories: %w", err)
+	}
+
+	failed := 0
+	for _, categoryID := range hotCategories {
+		if _, err := s.RefreshTopTrends(ctx, categoryID, limit); err != nil {
+			log.Printf("[WARN] Cache warm failed for category %s: %v", categoryID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("warm trends: %d of %d categories failed", failed, len(hotCategories))
+	}
+	return nil
 }
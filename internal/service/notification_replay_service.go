@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+const (
+	// notificationRetryBase is the delay before the first retry of a failed
+	// notification; it doubles with every attempt up to notificationRetryMax.
+	notificationRetryBase = time.Minute
+	notificationRetryMax  = 6 * time.Hour
+	// notificationRetryTimeout bounds the processing of one retry.
+	notificationRetryTimeout = time.Minute
+	// notificationReplayBatch is how many notifications a retry pass or a
+	// replay handles at most.
+	notificationReplayBatch = 100
+)
+
+// FailedNotificationView is a failed notification as listed to operators.
+type FailedNotificationView struct {
+	ID            uint       `json:"id"`
+	Topic         string     `json:"topic"`
+	Resource      string     `json:"resource"`
+	UserID        int64      `json:"user_id"`
+	LastError     string     `json:"last_error"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// NotificationReplay is the outcome of replaying one notification.
+type NotificationReplay struct {
+	ID       uint   `json:"id"`
+	Topic    string `json:"topic"`
+	Resource string `json:"resource"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ReplayReport summarizes a replay.
+type ReplayReport struct {
+	Replayed  int                  `json:"replayed"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Results   []NotificationReplay `json:"results"`
+}
+
+// NotificationReplayService keeps Mercado Livre notifications whose
+// processing failed and retries them, on a backoff and on demand, until
+// they succeed.
+type NotificationReplayService struct {
+	repo *repository.NotificationRepository
+	// process dispatches a notification to the processor of its topic.
+	process func(ctx context.Context, n meli.Notification) error
+}
+
+func NewNotificationReplayService(repo *repository.NotificationRepository, process func(ctx context.Context, n meli.Notification) error) *NotificationReplayService {
+	return &NotificationReplayService{repo: repo, process: process}
+}
+
+// Record stores a notification whose processing failed with cause, for
+// retrying.
+func (s *NotificationReplayService) Record(ctx context.Context, n meli.Notification, cause error) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	key := n.ID
+	if key == "" {
+		key = n.Topic + ":" + n.Resource
+	}
+	return s.repo.RecordFailure(ctx, &repository.FailedNotification{
+		Key:           key,
+		Topic:         n.Topic,
+		Resource:      n.Resource,
+		UserID:        n.UserID,
+		Payload:       string(payload),
+		LastError:     cause.Error(),
+		Attempts:      1,
+		NextAttemptAt: time.Now().Add(notificationRetryBase),
+	})
+}
+
+// Failed lists the notifications still waiting for a successful retry.
+func (s *NotificationReplayService) Failed(ctx context.Context) ([]FailedNotificationView, error) {
+	rows, err := s.repo.Pending(ctx, notificationReplayBatch)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]FailedNotificationView, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, FailedNotificationView{
+			ID:            row.ID,
+			Topic:         row.Topic,
+			Resource:      row.Resource,
+			UserID:        row.UserID,
+			LastError:     row.LastError,
+			Attempts:      row.Attempts,
+			NextAttemptAt: row.NextAttemptAt,
+			ResolvedAt:    row.ResolvedAt,
+			CreatedAt:     row.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// RetryDue retries the failed notifications whose backoff has elapsed.
+func (s *NotificationReplayService) RetryDue(ctx context.Context) error {
+	rows, err := s.repo.Due(ctx, time.Now(), notificationReplayBatch)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		if r := s.retry(ctx, &rows[i]); !r.OK {
+			log.Printf("[WARN] Retry %d of %s notification %s failed: %s", rows[i].Attempts-1, r.Topic, r.Resource, r.Error)
+		}
+	}
+	return nil
+}
+
+// Replay retries the notifications with the given IDs right away, resolved
+// ones included, or every pending one when ids is empty.
+func (s *NotificationReplayService) Replay(ctx context.Context, ids []uint) (*ReplayReport, error) {
+	var rows []repository.FailedNotification
+	var err error
+	if len(ids) == 0 {
+		rows, err = s.repo.Pending(ctx, notificationReplayBatch)
+	} else {
+		rows, err = s.repo.FindByIDs(ctx, ids)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ReplayReport{Results: make([]NotificationReplay, 0, len(rows))}
+	for i := range rows {
+		r := s.retry(ctx, &rows[i])
+		report.Replayed++
+		if r.OK {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, r)
+	}
+	return report, nil
+}
+
+// retry processes a stored notification again and records the outcome:
+// resolved on success, the next attempt pushed back on failure.
+func (s *NotificationReplayService) retry(ctx context.Context, row *repository.FailedNotification) NotificationReplay {
+	r := NotificationReplay{ID: row.ID, Topic: row.Topic, Resource: row.Resource}
+
+	var n meli.Notification
+	err := json.Unmarshal([]byte(row.Payload), &n)
+	if err == nil {
+		retryCtx, cancel := context.WithTimeout(ctx, notificationRetryTimeout)
+		err = s.process(retryCtx, n)
+		cancel()
+	}
+
+	now := time.Now()
+	if err != nil {
+		row.Attempts++
+		row.LastError = err.Error()
+		row.NextAttemptAt = now.Add(notificationBackoff(row.Attempts))
+		r.Error = err.Error()
+	} else {
+		row.ResolvedAt = &now
+		r.OK = true
+	}
+	// Use a fresh context so the outcome is recorded even during shutdown.
+	if err := s.repo.Save(context.WithoutCancel(ctx), row); err != nil {
+		log.Printf("[ERROR] Failed to save failed notification %d: %v", row.ID, err)
+	}
+	return r
+}
+
+// notificationBackoff is the delay before the next retry of a notification
+// that failed attempts times.
+func notificationBackoff(attempts int) time.Duration {
+	if attempts > 10 {
+		return notificationRetryMax
+	}
+	return min(notificationRetryBase<<(attempts-1), notificationRetryMax)
+}
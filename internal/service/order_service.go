@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"time"
+
+	"melibot/internal/events"
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+const (
+	// initialOrderSyncWindow is how far back the first sync looks.
+	initialOrderSyncWindow = 60 * 24 * time.Hour
+	// orderSyncOverlap re-reads recent updates in case some were missed.
+	orderSyncOverlap = time.Hour
+	topSKULimit      = 10
+)
+
+// ErrOrderNotFound is returned for orders that were not synced.
+var ErrOrderNotFound = errors.New("order not found")
+
+// ErrInvalidSalesGrouping is returned for a group_by other than day/week.
+var ErrInvalidSalesGrouping = errors.New("group_by must be day or week")
+
+// OrderService syncs the seller's orders into the database and analyses them.
+// Stored orders are announced on the event bus.
+type OrderService struct {
+	meliClient *meli.MeliClient
+	orderRepo  *repository.OrderRepository
+	bus        *events.Bus
+}
+
+func NewOrderService(meliClient *meli.MeliClient, orderRepo *repository.OrderRepository, bus *events.Bus) *OrderService {
+	return &OrderService{
+		meliClient: meliClient,
+		orderRepo:  orderRepo,
+		bus:        bus,
+	}
+}
+
+// SyncOrders polls the orders updated since the last sync and upserts them.
+// It returns how many orders were stored.
+func (s *OrderService) SyncOrders(ctx context.Context) (int, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	since, err := s.orderRepo.LastUpdated(ctx, me.ID)
+	if err != nil {
+		return 0, err
+	}
+	if since.IsZero() {
+		since = time.Now().Add(-initialOrderSyncWindow)
+	} else {
+		since = since.Add(-orderSyncOverlap)
+	}
+
+	orders, err := s.meliClient.SearchOrders(ctx, me.ID, since)
+	if err != nil {
+		return 0, err
+	}
+	for i := range orders {
+		if err := s.store(ctx, &orders[i]); err != nil {
+			return i, err
+		}
+	}
+	if len(orders) > 0 {
+		log.Printf("[INFO] Synced %d orders for seller %d", len(orders), me.ID)
+	}
+	return len(orders), nil
+}
+
+// HandleOrderNotification processes an "orders_v2" notification: the order
+// is fetched from Mercado Livre and stored, usually well before the next
+// poll would have seen it.
+func (s *OrderService) HandleOrderNotification(ctx context.Context, n meli.Notification) error {
+	o, err := s.meliClient.GetOrder(ctx, n.ResourceID())
+	if err != nil {
+		return err
+	}
+	return s.store(ctx, o)
+}
+
+// store upserts an order and publishes OrderCreated or OrderStatusChanged.
+func (s *OrderService) store(ctx context.Context, o *meli.Order) error {
+	order := OrderFromAPI(o)
+	s.fillShippingCost(ctx, order)
+	previous, err := s.orderRepo.Upsert(ctx, order)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case previous == "":
+		s.bus.Publish(ctx, events.OrderCreated{Order: order})
+	case previous != order.Status:
+		s.bus.Publish(ctx, events.OrderStatusChanged{Order: order, From: previous, To: order.Status})
+	}
+	return nil
+}
+
+// OrderHistory returns a stored order with its status transitions.
+func (s *OrderService) OrderHistory(ctx context.Context, orderID int64) (*repository.Order, []repository.OrderStatusChange, error) {
+	order, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if order == nil {
+		return nil, nil, ErrOrderNotFound
+	}
+	changes, err := s.orderRepo.StatusHistory(ctx, orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, changes, nil
+}
+
+// fillShippingCost looks up what the seller paid to ship an order. It is
+// best-effort: profit reports treat unknown shipping as zero.
+func (s *OrderService) fillShippingCost(ctx context.Context, order *repository.Order) {
+	if order.ShippingID == 0 {
+		return
+	}
+	costs, err := s.meliClient.GetShipmentCosts(ctx, order.ShippingID)
+	if err != nil {
+		log.Printf("[WARN] Shipping cost lookup failed for order %d: %v", order.ID, err)
+		return
+	}
+	order.ShippingCost = costs.SenderCost()
+}
+
+// OrderFromAPI maps a Mercado Livre order to its stored form.
+func OrderFromAPI(o *meli.Order) *repository.Order {
+	order := &repository.Order{
+		ID:            o.ID,
+		SellerID:      o.Seller.ID,
+		Status:        o.Status,
+		DateCreated:   o.DateCreated,
+		DateClosed:    o.DateClosed,
+		LastUpdated:   o.LastUpdated,
+		TotalAmount:   o.TotalAmount,
+		PaidAmount:    o.PaidAmount,
+		Currency:      o.CurrencyID,
+		BuyerID:       o.Buyer.ID,
+		BuyerNickname: o.Buyer.Nickname,
+		ShippingID:    o.Shipping.ID,
+	}
+	if order.LastUpdated.IsZero() {
+		order.LastUpdated = order.DateCreated
+	}
+	for _, it := range o.OrderItems {
+		order.Items = append(order.Items, repository.OrderItem{
+			ItemID:      it.Item.ID,
+			VariationID: it.Item.VariationID,
+			Title:       it.Item.Title,
+			SKU:         it.Item.SellerSKU,
+			Quantity:    it.Quantity,
+			UnitPrice:   it.UnitPrice,
+			SaleFee:     it.SaleFee,
+		})
+	}
+	return order
+}
+
+// SalesTotals are revenue, units and average order value over a range.
+type SalesTotals struct {
+	Orders            int     `json:"orders"`
+	Units             int     `json:"units"`
+	Revenue           float64 `json:"revenue"`
+	AverageOrderValue float64 `json:"average_order_value"`
+}
+
+// SalesPeriod is SalesTotals for one day or week.
+type SalesPeriod struct {
+	Period string `json:"period"`
+	SalesTotals
+}
+
+type TopSKU struct {
+	SKU     string  `json:"sku"`
+	ItemID  string  `json:"item_id"`
+	Title   string  `json:"title"`
+	Units   int     `json:"units"`
+	Revenue float64 `json:"revenue"`
+}
+
+// SalesAnalytics is the seller's paid sales over a range.
+type SalesAnalytics struct {
+	GroupBy string        `json:"group_by"`
+	From    time.Time     `json:"from"`
+	To      time.Time     `json:"to"`
+	Totals  SalesTotals   `json:"totals"`
+	Periods []SalesPeriod `json:"periods"`
+	TopSKUs []TopSKU      `json:"top_skus"`
+}
+
+// SalesAnalytics aggregates the stored paid orders created in [from, to) by
+// day or week, with the top SKUs of the range.
+func (s *OrderService) SalesAnalytics(ctx context.Context, groupBy string, from, to time.Time) (*SalesAnalytics, error) {
+	if groupBy != "day" && groupBy != "week" {
+		return nil, ErrInvalidSalesGrouping
+	}
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	periods, err := s.orderRepo.SalesByPeriod(ctx, me.ID, groupBy, from, to)
+	if err != nil {
+		return nil, err
+	}
+	skus, err := s.orderRepo.TopSKUs(ctx, me.ID, from, to, topSKULimit)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &SalesAnalytics{GroupBy: groupBy, From: from, To: to, Periods: []SalesPeriod{}, TopSKUs: []TopSKU{}}
+	for _, p := range periods {
+		out.Periods = append(out.Periods, SalesPeriod{
+			Period:      p.Period.Format(time.DateOnly),
+			SalesTotals: salesTotals(p.Orders, p.Units, p.Revenue),
+		})
+		out.Totals.Orders += p.Orders
+		out.Totals.Units += p.Units
+		out.Totals.Revenue += p.Revenue
+	}
+	out.Totals = salesTotals(out.Totals.Orders, out.Totals.Units, out.Totals.Revenue)
+	for _, sku := range skus {
+		out.TopSKUs = append(out.TopSKUs, TopSKU{SKU: sku.SKU, ItemID: sku.ItemID, Title: sku.Title, Units: sku.Units, Revenue: roundCents(sku.Revenue)})
+	}
+	return out, nil
+}
+
+func salesTotals(orders, units int, revenue float64) SalesTotals {
+	t := SalesTotals{Orders: orders, Units: units, Revenue: roundCents(revenue)}
+	if orders > 0 {
+		t.AverageOrderValue = roundCents(revenue / float64(orders))
+	}
+	return t
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"melibot/database"
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// orgLoginTTL is how long an organization has to finish a Mercado Livre
+// login it started.
+const orgLoginTTL = 10 * time.Minute
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrOrganizationName     = errors.New("organization name is required")
+	ErrOAuthNotConfigured   = errors.New("OAuth not configured")
+	ErrOrgLoginExpired      = errors.New("login expired or unknown; start it again")
+)
+
+// Organization is a tenant as operators see it; its API key is only shown
+// when created or rotated, see OrganizationKey.
+type Organization struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	SellerID  int64  `json:"seller_id,omitempty"`
+	Connected bool   `json:"connected"`
+	// Plan and PlanStatus are its subscription, see BillingService
+	Plan       string    `json:"plan,omitempty"`
+	PlanStatus string    `json:"plan_status,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OrganizationKey is an organization with its new API key.
+type OrganizationKey struct {
+	Organization
+	Key string `json:"key"`
+}
+
+// OrgTokens is the Mercado Livre token pair of an organization. Refreshed
+// pairs are saved, since refresh tokens are single-use, and other replicas
+// pick them up from the database when their own pair stops working.
+type OrgTokens struct {
+	*meli.TokenManager
+	orgID uint
+	repo  *repository.OrganizationRepository
+
+	// mu keeps a reload from undoing a refresh not yet saved
+	mu sync.Mutex
+}
+
+// Refresh implements meli.TokenRefresher. The stored pair is read first,
+// in case another replica refreshed it already, and again when the refresh
+// fails, in case one did meanwhile.
+func (t *OrgTokens) Refresh(ctx context.Context, stale string) (string, error) {
+	if t.repo == nil {
+		return t.TokenManager.Refresh(ctx, stale)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reload(ctx)
+	before := t.RefreshToken()
+	token, err := t.TokenManager.Refresh(ctx, stale)
+	if err != nil {
+		if t.reload(ctx) {
+			if access := t.AccessToken(); access != stale {
+				return access, nil
+			}
+		}
+		return token, err
+	}
+	if refresh := t.RefreshToken(); refresh != before {
+		if err := t.repo.SetTokens(context.WithoutCancel(ctx), t.orgID, token, refresh); err != nil {
+			log.Printf("[ERROR] Failed to save the refreshed tokens of organization %d: %v", t.orgID, err)
+		}
+	}
+	return token, nil
+}
+
+// reload replaces the pair with the stored one when they differ. It reports
+// whether it did.
+func (t *OrgTokens) reload(ctx context.Context) bool {
+	row, err := t.repo.Find(ctx, t.orgID)
+	if err != nil {
+		log.Printf("[WARN] Failed to reload the tokens of organization %d: %v", t.orgID, err)
+		return false
+	}
+	if row == nil || row.RefreshToken == "" {
+		return false
+	}
+	if row.AccessToken == t.AccessToken() && row.RefreshToken == t.RefreshToken() {
+		return false
+	}
+	t.Set(row.AccessToken, row.RefreshToken)
+	return true
+}
+
+// OrganizationService manages the tenants of multi-tenant mode: their API
+// keys and the Mercado Livre account each one connects.
+type OrganizationService struct {
+	repo  *repository.OrganizationRepository
+	oauth *meli.OAuthClient
+
+	mu     sync.Mutex
+	tokens map[uint]*OrgTokens
+}
+
+// NewOrganizationService returns the service; oauth may be nil when OAuth
+// is not configured, leaving organizations unable to log in.
+func NewOrganizationService(repo *repository.OrganizationRepository, oauth *meli.OAuthClient) *OrganizationService {
+	return &OrganizationService{
+		repo:   repo,
+		oauth:  oauth,
+		tokens: make(map[uint]*OrgTokens),
+	}
+}
+
+func hashOrgKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func newOrgKey() (key, hash string, err error) {
+	random, err := randomHex(24)
+	if err != nil {
+		return "", "", err
+	}
+	key = "org_" + random
+	return key, hashOrgKey(key), nil
+}
+
+func organizationFromRow(row repository.Organization) Organization {
+	return Organization{
+		ID:         row.ID,
+		Name:       row.Name,
+		SellerID:   row.SellerID,
+		Connected:  row.RefreshToken != "",
+		Plan:       row.Plan,
+		PlanStatus: row.PlanStatus,
+		CreatedAt:  row.CreatedAt,
+	}
+}
+
+// Organizations lists every organization.
+func (s *OrganizationService) Organizations(ctx context.Context) ([]Organization, error) {
+	rows, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orgs := make([]Organization, 0, len(rows))
+	for _, row := range rows {
+		orgs = append(orgs, organizationFromRow(row))
+	}
+	return orgs, nil
+}
+
+// ConnectedIDs returns the organizations that connected a Mercado Livre
+// account, for background work done for each one.
+func (s *OrganizationService) ConnectedIDs(ctx context.Context) ([]uint, error) {
+	rows, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uint
+	for _, row := range rows {
+		if row.RefreshToken != "" {
+			ids = append(ids, row.ID)
+		}
+	}
+	return ids, nil
+}
+
+// Create adds an organization and returns it with its API key.
+func (s *OrganizationService) Create(ctx context.Context, name string) (*OrganizationKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrOrganizationName
+	}
+	key, hash, err := newOrgKey()
+	if err != nil {
+		return nil, err
+	}
+	row := &repository.Organization{Name: name, KeyHash: hash}
+	if err := s.repo.Create(ctx, row); err != nil {
+		return nil, err
+	}
+	return &OrganizationKey{Organization: organizationFromRow(*row), Key: key}, nil
+}
+
+// RotateKey replaces the API key of an organization; the old one stops
+// working at once.
+func (s *OrganizationService) RotateKey(ctx context.Context, id uint) (*OrganizationKey, error) {
+	row, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, ErrOrganizationNotFound
+	}
+	key, hash, err := newOrgKey()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.repo.SetKeyHash(ctx, id, hash); err != nil {
+		return nil, err
+	}
+	return &OrganizationKey{Organization: organizationFromRow(*row), Key: key}, nil
+}
+
+// Delete removes an organization. Its rows are kept but no key reaches them
+// anymore.
+func (s *OrganizationService) Delete(ctx context.Context, id uint) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrOrganizationNotFound
+	}
+	s.mu.Lock()
+	delete(s.tokens, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// Current returns the organization of ctx.
+func (s *OrganizationService) Current(ctx context.Context) (*Organization, error) {
+	id, ok := database.OrgFromContext(ctx)
+	if !ok {
+		return nil, ErrOrganizationNotFound
+	}
+	row, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, ErrOrganizationNotFound
+	}
+	org := organizationFromRow(*row)
+	return &org, nil
+}
+
+// Resolve returns the organization of an API key; ok is false for unknown
+// keys.
+func (s *OrganizationService) Resolve(ctx context.Context, key string) (uint, bool, error) {
+	row, err := s.repo.FindByKeyHash(ctx, hashOrgKey(key))
+	if err != nil || row == nil {
+		return 0, false, err
+	}
+	return row.ID, true, nil
+}
+
+// ForSeller returns the organization that connected a Mercado Livre
+// account, for notifications, which carry the seller but no key.
+func (s *OrganizationService) ForSeller(ctx context.Context, sellerID int64) (uint, bool, error) {
+	row, err := s.repo.FindBySeller(ctx, sellerID)
+	if err != nil || row == nil {
+		return 0, false, err
+	}
+	return row.ID, true, nil
+}
+
+// Tokens returns the token pair of the organization of ctx, loaded once and
+// then kept in memory; it is read again from the database when refreshed,
+// see OrgTokens.Refresh. Without an organization, or when it cannot be
+// loaded, the pair is empty.
+func (s *OrganizationService) Tokens(ctx context.Context) (*OrgTokens, error) {
+	id, ok := database.OrgFromContext(ctx)
+	if !ok {
+		return &OrgTokens{TokenManager: meli.NewTokenManager(nil)}, ErrOrganizationNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.tokens[id]; ok {
+		return t, nil
+	}
+	t := &OrgTokens{TokenManager: meli.NewTokenManager(s.oauth), orgID: id, repo: s.repo}
+	row, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return t, err
+	}
+	if row != nil {
+		t.Set(row.AccessToken, row.RefreshToken)
+	}
+	s.tokens[id] = t
+	return t, nil
+}
+
+// AccessToken returns the access token of the organization of ctx, empty
+// when it has not logged in.
+func (s *OrganizationService) AccessToken(ctx context.Context) string {
+	t, err := s.Tokens(ctx)
+	if err != nil {
+		log.Printf("[ERROR] Failed to load organization tokens: %v", err)
+	}
+	return t.AccessToken()
+}
+
+// LoginURL starts a Mercado Livre login for the organization of ctx and
+// returns the authorization URL to send the user to; the callback finishes
+// it with CompleteLogin. The login is stored, so the callback may land on
+// any instance.
+func (s *OrganizationService) LoginURL(ctx context.Context) (string, error) {
+	if s.oauth == nil {
+		return "", ErrOAuthNotConfigured
+	}
+	id, ok := database.OrgFromContext(ctx)
+	if !ok {
+		return "", ErrOrganizationNotFound
+	}
+	state, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.StartLogin(ctx, id, state, orgLoginTTL); err != nil {
+		return "", err
+	}
+	return s.oauth.AuthorizationURL(state), nil
+}
+
+// PendingLogin reports whether state belongs to a login started by
+// LoginURL that has not expired.
+func (s *OrganizationService) PendingLogin(ctx context.Context, state string) bool {
+	ok, err := s.repo.PendingLogin(ctx, state)
+	if err != nil {
+		log.Printf("[ERROR] Failed to look up organization login: %v", err)
+	}
+	return ok
+}
+
+// PurgeLogins deletes the logins that expired before their callback.
+func (s *OrganizationService) PurgeLogins(ctx context.Context) error {
+	return s.repo.PurgeLogins(ctx)
+}
+
+// CompleteLogin exchanges the code of a login started by LoginURL and
+// connects the account to the organization. It returns the organization.
+func (s *OrganizationService) CompleteLogin(ctx context.Context, state, code string) (*Organization, error) {
+	if s.oauth == nil {
+		return nil, ErrOAuthNotConfigured
+	}
+	orgID, ok, err := s.repo.TakeLogin(ctx, state)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrOrgLoginExpired
+	}
+
+	tok, err := s.oauth.ExchangeCodeForToken(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	sellerID := int64(tok.UserID)
+	if err := s.repo.SetAccount(ctx, orgID, sellerID, tok.AccessToken, tok.RefreshToken); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if t, ok := s.tokens[orgID]; ok {
+		t.Set(tok.AccessToken, tok.RefreshToken)
+	}
+	s.mu.Unlock()
+
+	row, err := s.repo.Find(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, ErrOrganizationNotFound
+	}
+	org := organizationFromRow(*row)
+	return &org, nil
+}
+
+// Bootstrap prepares multi-tenant mode on first start: when there is no
+// organization yet it creates one, logs its API key and hands it the rows
+// stored so far. It does nothing once an organization exists.
+func (s *OrganizationService) Bootstrap(ctx context.Context) error {
+	rows, err := s.repo.List(ctx)
+	if err != nil || len(rows) > 0 {
+		return err
+	}
+	org, err := s.Create(ctx, "default")
+	if err != nil {
+		return err
+	}
+	moved, err := s.repo.Adopt(ctx, org.ID)
+	if err != nil {
+		return err
+	}
+	log.Printf("[WARN] Created organization %d (%s) with %d existing rows; its API key, shown only once, is %s", org.ID, org.Name, moved, org.Key)
+	return nil
+}
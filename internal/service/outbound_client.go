@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateDestination is returned when an outbound delivery would reach a
+// private, loopback or link-local address.
+var ErrPrivateDestination = errors.New("destination resolves to a private, loopback or link-local address")
+
+// newPublicHTTPClient returns a client for URLs given by users, such as
+// webhook subscriptions and report destinations, that refuses to connect to
+// the server's own network. The address is checked when dialing, after DNS
+// resolution and on every redirect, so a public name resolving to an
+// internal address is refused too. Proxies are ignored, as they would dial
+// on the client's behalf.
+func newPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(addr.Addr()) {
+				return fmt.Errorf("%w: %s", ErrPrivateDestination, addr.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// publicAddr reports whether addr may be reached by outbound deliveries.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, internal to providers
+// and some cloud networks.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// checkDestinationHost rejects hosts that are private addresses, so the
+// mistake is reported when saving rather than on the first delivery. Names
+// are only checked when dialing.
+func checkDestinationHost(host string) error {
+	if host == "localhost" {
+		return ErrPrivateDestination
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		return ErrPrivateDestination
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// ErrCostNotFound is returned when no cost is recorded for a SKU.
+var ErrCostNotFound = errors.New("no cost recorded for this SKU")
+
+// ErrInvalidProfitGrouping is returned for a group_by other than day/week/month.
+var ErrInvalidProfitGrouping = errors.New("group_by must be day, week or month")
+
+// ProfitService combines orders, Mercado Livre fees, shipping charges and
+// the seller's unit costs into margins.
+type ProfitService struct {
+	meliClient *meli.MeliClient
+	orderRepo  *repository.OrderRepository
+	costRepo   *repository.ProductCostRepository
+}
+
+func NewProfitService(meliClient *meli.MeliClient, orderRepo *repository.OrderRepository, costRepo *repository.ProductCostRepository) *ProfitService {
+	return &ProfitService{
+		meliClient: meliClient,
+		orderRepo:  orderRepo,
+		costRepo:   costRepo,
+	}
+}
+
+// Costs lists the recorded unit costs.
+func (s *ProfitService) Costs(ctx context.Context) ([]repository.ProductCost, error) {
+	return s.costRepo.List(ctx)
+}
+
+// SetCost records the unit cost of a SKU.
+func (s *ProfitService) SetCost(ctx context.Context, sku string, unitCost float64, currency string) (*repository.ProductCost, error) {
+	cost := &repository.ProductCost{SKU: sku, UnitCost: unitCost, Currency: currency}
+	if err := s.costRepo.Upsert(ctx, cost); err != nil {
+		return nil, err
+	}
+	return cost, nil
+}
+
+// DeleteCost forgets the unit cost of a SKU.
+func (s *ProfitService) DeleteCost(ctx context.Context, sku string) error {
+	deleted, err := s.costRepo.Delete(ctx, sku)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrCostNotFound
+	}
+	return nil
+}
+
+// Profit is revenue minus fees, shipping and cost of goods.
+type Profit struct {
+	Units       int     `json:"units"`
+	Revenue     float64 `json:"revenue"`
+	Fees        float64 `json:"fees"`
+	Shipping    float64 `json:"shipping"`
+	Cost        float64 `json:"cost"`
+	Margin      float64 `json:"margin"`
+	MarginPct   float64 `json:"margin_pct"`
+	MissingCost bool    `json:"missing_cost,omitempty"`
+}
+
+func (p *Profit) add(units int, revenue, fees, shipping, cost float64, missingCost bool) {
+	p.Units += units
+	p.Revenue += revenue
+	p.Fees += fees
+	p.Shipping += shipping
+	p.Cost += cost
+	p.MissingCost = p.MissingCost || missingCost
+}
+
+func (p Profit) rounded() Profit {
+	p.Margin = roundCents(p.Revenue - p.Fees - p.Shipping - p.Cost)
+	if p.Revenue > 0 {
+		p.MarginPct = roundCents(p.Margin / p.Revenue * 100)
+	}
+	p.Revenue, p.Fees, p.Shipping, p.Cost = roundCents(p.Revenue), roundCents(p.Fees), roundCents(p.Shipping), roundCents(p.Cost)
+	return p
+}
+
+type SKUProfit struct {
+	SKU    string `json:"sku"`
+	ItemID string `json:"item_id"`
+	Title  string `json:"title"`
+	Profit
+}
+
+type PeriodProfit struct {
+	Period string `json:"period"`
+	Profit
+}
+
+// ProfitReport is the margin per SKU and per period over a range. SKUs
+// without a recorded cost are flagged with missing_cost and counted at zero
+// cost, so their margin is overstated.
+type ProfitReport struct {
+	GroupBy     string         `json:"group_by"`
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	Totals      Profit         `json:"totals"`
+	SKUs        []SKUProfit    `json:"skus"`
+	Periods     []PeriodProfit `json:"periods"`
+	MissingSKUs []string       `json:"missing_cost_skus"`
+}
+
+// ProfitReport computes margins of the paid orders created in [from, to).
+// An order's shipping charge is split across its lines by revenue.
+func (s *ProfitService) ProfitReport(ctx context.Context, groupBy string, from, to time.Time) (*ProfitReport, error) {
+	periodStart, ok := periodTruncators[groupBy]
+	if !ok {
+		return nil, ErrInvalidProfitGrouping
+	}
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.orderRepo.PaidOrderLines(ctx, me.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	costs, err := s.costRepo.CostsBySKU(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ProfitReport{GroupBy: groupBy, From: from, To: to, SKUs: []SKUProfit{}, Periods: []PeriodProfit{}, MissingSKUs: []string{}}
+	bySKU := map[string]*SKUProfit{}
+	byPeriod := map[string]*PeriodProfit{}
+	var totals Profit
+
+	for _, l := range lines {
+		sku := l.SKU
+		if sku == "" {
+			sku = l.ItemID
+		}
+		revenue := float64(l.Quantity) * l.UnitPrice
+		fees := float64(l.Quantity) * l.SaleFee
+		shipping := 0.0
+		if l.OrderTotal > 0 {
+			shipping = l.OrderShippingCost * revenue / l.OrderTotal
+		}
+		unitCost, hasCost := costs[sku]
+		cost := float64(l.Quantity) * unitCost
+
+		sp := bySKU[sku]
+		if sp == nil {
+			sp = &SKUProfit{SKU: sku, ItemID: l.ItemID, Title: l.Title}
+			bySKU[sku] = sp
+		}
+		sp.add(l.Quantity, revenue, fees, shipping, cost, !hasCost)
+
+		key := periodStart(l.DateCreated).Format(time.DateOnly)
+		pp := byPeriod[key]
+		if pp == nil {
+			pp = &PeriodProfit{Period: key}
+			byPeriod[key] = pp
+		}
+		pp.add(l.Quantity, revenue, fees, shipping, cost, !hasCost)
+
+		totals.add(l.Quantity, revenue, fees, shipping, cost, !hasCost)
+	}
+
+	report.Totals = totals.rounded()
+	for _, sp := range bySKU {
+		sp.Profit = sp.Profit.rounded()
+		report.SKUs = append(report.SKUs, *sp)
+		if sp.MissingCost {
+			report.MissingSKUs = append(report.MissingSKUs, sp.SKU)
+		}
+	}
+	sort.Slice(report.SKUs, func(i, j int) bool { return report.SKUs[i].Margin > report.SKUs[j].Margin })
+	sort.Strings(report.MissingSKUs)
+	for _, pp := range byPeriod {
+		pp.Profit = pp.Profit.rounded()
+		report.Periods = append(report.Periods, *pp)
+	}
+	sort.Slice(report.Periods, func(i, j int) bool { return report.Periods[i].Period < report.Periods[j].Period })
+	return report, nil
+}
+
+// periodTruncators map a grouping to the start of the period containing t.
+var periodTruncators = map[string]func(t time.Time) time.Time{
+	"day": func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	},
+	"week": func(t time.Time) time.Time {
+		// Weeks start on Monday, like Postgres date_trunc.
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+	},
+	"month": func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	},
+}
@@ -0,0 +1,414 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// defaultMinConfidence is the confidence from which matches are answered
+// without review, until the seller configures one.
+const defaultMinConfidence = 0.8
+
+var (
+	ErrTemplateNotFound = errors.New("answer template not found")
+	ErrMatchNotFound    = errors.New("question not in the review queue")
+	ErrMatchHandled     = errors.New("question was already answered or dismissed")
+)
+
+// QuestionService answers buyer questions from templates. It is opt-in:
+// nothing is sent until the auto-responder is enabled.
+type QuestionService struct {
+	meliClient *meli.MeliClient
+	repo       *repository.QuestionRepository
+}
+
+func NewQuestionService(meliClient *meli.MeliClient, repo *repository.QuestionRepository) *QuestionService {
+	return &QuestionService{
+		meliClient: meliClient,
+		repo:       repo,
+	}
+}
+
+// ValidateTemplate checks that a template can be matched.
+func ValidateTemplate(t *repository.AnswerTemplate) error {
+	if strings.TrimSpace(t.Name) == "" || strings.TrimSpace(t.Answer) == "" {
+		return errors.New("name and answer are required")
+	}
+	if t.ItemID != "" && t.CategoryID != "" {
+		return errors.New("a template is scoped to an item or a category, not both")
+	}
+	switch t.MatchType {
+	case repository.MatchTypeKeywords:
+		if len(templateKeywords(t.Pattern)) == 0 {
+			return errors.New("pattern must list at least one keyword")
+		}
+	case repository.MatchTypeRegex:
+		if _, err := regexp.Compile("(?i)" + t.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	default:
+		return errors.New("match_type must be keywords or regex")
+	}
+	return nil
+}
+
+// Templates lists the answer templates.
+func (s *QuestionService) Templates(ctx context.Context) ([]repository.AnswerTemplate, error) {
+	return s.repo.Templates(ctx)
+}
+
+// SaveTemplate creates a template, or replaces the one with t.ID unless it
+// changed since t.Version (0 for the stored one).
+func (s *QuestionService) SaveTemplate(ctx context.Context, t *repository.AnswerTemplate) error {
+	if t.ID == 0 {
+		t.Version = 0
+		return s.repo.SaveTemplate(ctx, t)
+	}
+	existing, err := s.repo.FindTemplate(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrTemplateNotFound
+	}
+	if err := checkVersion(t.Version, existing.Version); err != nil {
+		return err
+	}
+	t.CreatedAt, t.Version = existing.CreatedAt, existing.Version
+	updated, err := s.repo.UpdateTemplate(ctx, t)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// DeleteTemplate moves a template to the trash.
+func (s *QuestionService) DeleteTemplate(ctx context.Context, id uint) error {
+	deleted, err := s.repo.DeleteTemplate(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+// TrashedTemplates lists the deleted answer templates, last deleted first.
+func (s *QuestionService) TrashedTemplates(ctx context.Context) ([]repository.AnswerTemplate, error) {
+	return s.repo.TrashedTemplates(ctx)
+}
+
+// RestoreTemplate takes a template out of the trash.
+func (s *QuestionService) RestoreTemplate(ctx context.Context, id uint) (*repository.AnswerTemplate, error) {
+	restored, err := s.repo.RestoreTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrTemplateNotFound
+	}
+	t, err := s.repo.FindTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTemplateNotFound
+	}
+	return t, nil
+}
+
+// AutoResponderStatus is the auto-responder configuration.
+type AutoResponderStatus struct {
+	Enabled        bool     `json:"enabled"`
+	MinConfidence  float64  `json:"min_confidence"`
+	OptedOutItems  []string `json:"opted_out_items"`
+	PendingReviews int64    `json:"pending_reviews"`
+}
+
+// Settings returns the auto-responder settings, defaulting to disabled.
+func (s *QuestionService) Settings(ctx context.Context) (*repository.AutoResponderSettings, error) {
+	settings, err := s.repo.Settings(ctx)
+	if err != nil || settings != nil {
+		return settings, err
+	}
+	return &repository.AutoResponderSettings{MinConfidence: defaultMinConfidence}, nil
+}
+
+// Status returns the settings together with the opted-out listings.
+func (s *QuestionService) Status(ctx context.Context) (*AutoResponderStatus, error) {
+	settings, err := s.Settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.OptedOutItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := s.repo.CountMatches(ctx, repository.MatchStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	return &AutoResponderStatus{
+		Enabled:        settings.Enabled,
+		MinConfidence:  settings.MinConfidence,
+		OptedOutItems:  append([]string{}, items...),
+		PendingReviews: pending,
+	}, nil
+}
+
+// Configure turns the auto-responder on or off and sets its confidence
+// threshold; a nil minConfidence keeps the current one.
+func (s *QuestionService) Configure(ctx context.Context, enabled bool, minConfidence *float64) (*AutoResponderStatus, error) {
+	settings, err := s.Settings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings.Enabled = enabled
+	if minConfidence != nil {
+		if *minConfidence <= 0 || *minConfidence > 1 {
+			return nil, errors.New("min_confidence must be in (0, 1]")
+		}
+		settings.MinConfidence = *minConfidence
+	}
+	if err := s.repo.SaveSettings(ctx, settings); err != nil {
+		return nil, err
+	}
+	return s.Status(ctx)
+}
+
+// OptOut excludes a listing from the auto-responder.
+func (s *QuestionService) OptOut(ctx context.Context, itemID string) error {
+	return s.repo.OptOut(ctx, itemID)
+}
+
+// OptIn includes a listing in the auto-responder again.
+func (s *QuestionService) OptIn(ctx context.Context, itemID string) error {
+	return s.repo.OptIn(ctx, itemID)
+}
+
+// maxReviewQueue caps how many pending questions are listed.
+const maxReviewQueue = 500
+
+// ReviewQueue lists questions waiting for the seller to confirm the
+// suggested answer.
+func (s *QuestionService) ReviewQueue(ctx context.Context) ([]repository.QuestionMatch, error) {
+	return s.repo.Matches(ctx, repository.MatchStatusPending, maxReviewQueue)
+}
+
+// Approve answers a queued question with the suggested answer, or with
+// text when it is not empty.
+func (s *QuestionService) Approve(ctx context.Context, id uint, text string) (*repository.QuestionMatch, error) {
+	m, err := s.pendingMatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		text = m.SuggestedAnswer
+	}
+	if err := s.meliClient.AnswerQuestion(ctx, m.QuestionID, text); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	m.Status, m.AnswerText, m.AnsweredAt = repository.MatchStatusAnswered, text, &now
+	if err := s.repo.SaveMatch(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Dismiss drops a queued question; the seller answers it on Mercado Livre.
+func (s *QuestionService) Dismiss(ctx context.Context, id uint) error {
+	m, err := s.pendingMatch(ctx, id)
+	if err != nil {
+		return err
+	}
+	m.Status = repository.MatchStatusDismissed
+	return s.repo.SaveMatch(ctx, m)
+}
+
+func (s *QuestionService) pendingMatch(ctx context.Context, id uint) (*repository.QuestionMatch, error) {
+	m, err := s.repo.FindMatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return nil, ErrMatchNotFound
+	}
+	if m.Status != repository.MatchStatusPending {
+		return nil, ErrMatchHandled
+	}
+	return m, nil
+}
+
+// HandleQuestion processes a newly asked question: it is matched against
+// the templates in scope for its listing, answered when the best match is
+// confident enough, and queued for review otherwise. Questions that match
+// nothing are left to the seller.
+func (s *QuestionService) HandleQuestion(ctx context.Context, questionID int64) error {
+	settings, err := s.Settings(ctx)
+	if err != nil || !settings.Enabled {
+		return err
+	}
+
+	q, err := s.meliClient.GetQuestion(ctx, questionID)
+	if err != nil {
+		return err
+	}
+	if q.Status != meli.QuestionStatusUnanswered {
+		return nil
+	}
+	optedOut, err := s.repo.IsOptedOut(ctx, q.ItemID)
+	if err != nil || optedOut {
+		return err
+	}
+
+	templates, err := s.repo.Templates(ctx)
+	if err != nil {
+		return err
+	}
+	categoryID := ""
+	if needsCategory(templates) {
+		item, err := s.meliClient.GetItem(ctx, q.ItemID)
+		if err != nil {
+			return err
+		}
+		categoryID = item.CategoryID
+	}
+
+	tpl, confidence := matchTemplate(templates, q.Text, q.ItemID, categoryID)
+	if tpl == nil {
+		return nil
+	}
+	m := &repository.QuestionMatch{
+		QuestionID:      q.ID,
+		SellerID:        q.SellerID,
+		ItemID:          q.ItemID,
+		Text:            q.Text,
+		TemplateID:      tpl.ID,
+		SuggestedAnswer: tpl.Answer,
+		Confidence:      confidence,
+		Status:          repository.MatchStatusPending,
+	}
+	autoAnswer := confidence >= settings.MinConfidence
+	if autoAnswer {
+		m.Status = repository.MatchStatusAutoAnswered
+	}
+	created, err := s.repo.CreateMatch(ctx, m)
+	if err != nil || !created {
+		return err
+	}
+	if !autoAnswer {
+		log.Printf("[INFO] Question %d on %s queued for review (template %q, confidence %.2f)", q.ID, q.ItemID, tpl.Name, confidence)
+		return nil
+	}
+
+	if err := s.meliClient.AnswerQuestion(ctx, q.ID, tpl.Answer); err != nil {
+		// Leave it for the seller to approve by hand.
+		m.Status = repository.MatchStatusPending
+		if saveErr := s.repo.SaveMatch(ctx, m); saveErr != nil {
+			log.Printf("[ERROR] Could not queue question %d after failed answer: %v", q.ID, saveErr)
+		}
+		return err
+	}
+	now := time.Now()
+	m.AnswerText, m.AnsweredAt = tpl.Answer, &now
+	if err := s.repo.SaveMatch(ctx, m); err != nil {
+		return err
+	}
+	log.Printf("[INFO] Question %d on %s answered with template %q (confidence %.2f)", q.ID, q.ItemID, tpl.Name, confidence)
+	return nil
+}
+
+func needsCategory(templates []repository.AnswerTemplate) bool {
+	for _, t := range templates {
+		if t.Enabled && t.CategoryID != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTemplate returns the enabled template in scope that best matches the
+// question, with its confidence. A regex match has confidence 1; a keyword
+// template scores the share of its keywords found in the text. Ties go to
+// the narrower scope: item, then category, then global.
+func matchTemplate(templates []repository.AnswerTemplate, text, itemID, categoryID string) (*repository.AnswerTemplate, float64) {
+	folded := accentFolder.Replace(strings.ToLower(text))
+
+	var best *repository.AnswerTemplate
+	bestScore, bestScope := 0.0, 0
+	for i := range templates {
+		t := &templates[i]
+		if !t.Enabled {
+			continue
+		}
+		scope := 1
+		switch {
+		case t.ItemID != "":
+			if t.ItemID != itemID {
+				continue
+			}
+			scope = 3
+		case t.CategoryID != "":
+			if t.CategoryID != categoryID {
+				continue
+			}
+			scope = 2
+		}
+
+		score := templateScore(t, text, folded)
+		if score == 0 {
+			continue
+		}
+		if score > bestScore || (score == bestScore && scope > bestScope) {
+			best, bestScore, bestScope = t, score, scope
+		}
+	}
+	return best, bestScore
+}
+
+func templateScore(t *repository.AnswerTemplate, text, folded string) float64 {
+	if t.MatchType == repository.MatchTypeRegex {
+		re, err := regexp.Compile("(?i)" + t.Pattern)
+		if err != nil || !re.MatchString(text) {
+			return 0
+		}
+		return 1
+	}
+
+	keywords := templateKeywords(t.Pattern)
+	if len(keywords) == 0 {
+		return 0
+	}
+	found := 0
+	for _, kw := range keywords {
+		if strings.Contains(folded, kw) {
+			found++
+		}
+	}
+	return float64(found) / float64(len(keywords))
+}
+
+// templateKeywords splits a keyword pattern into lower-case, accent-folded
+// keywords.
+func templateKeywords(pattern string) []string {
+	var out []string
+	for _, kw := range strings.Split(pattern, ",") {
+		if kw = strings.TrimSpace(accentFolder.Replace(strings.ToLower(kw))); kw != "" {
+			out = append(out, kw)
+		}
+	}
+	return out
+}
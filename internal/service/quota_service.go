@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// quotaHistoryHours is how many hours of usage the quota report lists.
+const quotaHistoryHours = 24
+
+var ErrBackgroundBudgetExhausted = errors.New("background work is paused: its share of the Mercado Livre request budget is used up")
+
+// QuotaBudget bounds the Mercado Livre calls of each linked account. Zero
+// budgets are unlimited. Once BackgroundShare of either budget is used,
+// non-essential background work is refused so the rest stays available to
+// interactive use.
+type QuotaBudget struct {
+	Hourly          int
+	Daily           int
+	BackgroundShare float64
+}
+
+func (b QuotaBudget) enabled() bool {
+	return b.Hourly > 0 || b.Daily > 0
+}
+
+// QuotaReport is the request budget accounting served by /api/admin/quota.
+type QuotaReport struct {
+	HourlyBudget    int            `json:"hourly_budget"`
+	DailyBudget     int            `json:"daily_budget"`
+	BackgroundShare float64        `json:"background_share"`
+	Hour            time.Time      `json:"hour"`
+	Day             time.Time      `json:"day"`
+	Accounts        []AccountQuota `json:"accounts"`
+}
+
+// AccountQuota is one account's calls in the current hour and day (UTC),
+// the percentage of each budget they use and its hourly history.
+type AccountQuota struct {
+	AccountID         int64       `json:"account_id"`
+	HourRequests      int         `json:"hour_requests"`
+	HourErrors        int         `json:"hour_errors"`
+	DayRequests       int         `json:"day_requests"`
+	DayErrors         int         `json:"day_errors"`
+	HourlyUsedPct     float64     `json:"hourly_used_pct,omitempty"`
+	DailyUsedPct      float64     `json:"daily_used_pct,omitempty"`
+	BackgroundAllowed bool        `json:"background_allowed"`
+	Hours             []UsageHour `json:"hours"`
+}
+
+// UsageHour is an account's calls within one hour.
+type UsageHour struct {
+	Hour     time.Time `json:"hour"`
+	Requests int       `json:"requests"`
+	Errors   int       `json:"errors"`
+}
+
+// QuotaService persists the per-account call counts of the Mercado Livre
+// clients and checks them against the request budget.
+type QuotaService struct {
+	usageRepo *repository.UsageRepository
+	budget    QuotaBudget
+
+	mu sync.Mutex
+	// unsaved holds counts a failed Flush could not store yet.
+	unsaved []repository.APIUsage
+}
+
+func NewQuotaService(usageRepo *repository.UsageRepository, budget QuotaBudget) *QuotaService {
+	return &QuotaService{usageRepo: usageRepo, budget: budget}
+}
+
+// Flush adds the calls counted since the previous flush to the stored
+// hourly totals. Counts that fail to store are retried on the next flush.
+func (s *QuotaService) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// One row per account and hour: an upsert cannot touch a row twice.
+	for _, u := range meli.TakeAccountUsage() {
+		i := slices.IndexFunc(s.unsaved, func(row repository.APIUsage) bool {
+			return row.AccountID == u.AccountID && row.Hour.Equal(u.Hour)
+		})
+		if i < 0 {
+			s.unsaved = append(s.unsaved, repository.APIUsage{AccountID: u.AccountID, Hour: u.Hour})
+			i = len(s.unsaved) - 1
+		}
+		s.unsaved[i].Requests += u.Requests
+		s.unsaved[i].Errors += u.Errors
+	}
+	if err := s.usageRepo.Add(ctx, s.unsaved); err != nil {
+		return err
+	}
+	s.unsaved = nil
+	return nil
+}
+
+// Report returns every account's usage over the last quotaHistoryHours.
+func (s *QuotaService) Report(ctx context.Context) (*QuotaReport, error) {
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	hour, day := usagePeriods(time.Now())
+	rows, err := s.usageRepo.HoursSince(ctx, hour.Add(-(quotaHistoryHours-1)*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	byAccount := make(map[int64]*AccountQuota)
+	for _, row := range rows {
+		a, ok := byAccount[row.AccountID]
+		if !ok {
+			a = &AccountQuota{AccountID: row.AccountID, Hours: []UsageHour{}}
+			byAccount[row.AccountID] = a
+		}
+		a.Hours = append(a.Hours, UsageHour{Hour: row.Hour.UTC(), Requests: row.Requests, Errors: row.Errors})
+		if !row.Hour.Before(day) {
+			a.DayRequests += row.Requests
+			a.DayErrors += row.Errors
+		}
+		if !row.Hour.Before(hour) {
+			a.HourRequests += row.Requests
+			a.HourErrors += row.Errors
+		}
+	}
+
+	report := &QuotaReport{
+		HourlyBudget:    s.budget.Hourly,
+		DailyBudget:     s.budget.Daily,
+		BackgroundShare: s.budget.BackgroundShare,
+		Hour:            hour,
+		Day:             day,
+		Accounts:        make([]AccountQuota, 0, len(byAccount)),
+	}
+	for _, a := range byAccount {
+		if s.budget.Hourly > 0 {
+			a.HourlyUsedPct = roundPct(float64(a.HourRequests) / float64(s.budget.Hourly))
+		}
+		if s.budget.Daily > 0 {
+			a.DailyUsedPct = roundPct(float64(a.DayRequests) / float64(s.budget.Daily))
+		}
+		a.BackgroundAllowed = s.backgroundAllowed(a.HourRequests, a.DayRequests)
+		report.Accounts = append(report.Accounts, *a)
+	}
+	sort.Slice(report.Accounts, func(i, j int) bool {
+		return report.Accounts[i].AccountID < report.Accounts[j].AccountID
+	})
+	return report, nil
+}
+
+// CheckBackground returns ErrBackgroundBudgetExhausted when the account of
+// client has used the background share of its hourly or daily budget.
+// Non-essential background work (crawls, cache warming, snapshots) calls it
+// before starting; essential work such as order syncing does not.
+func (s *QuotaService) CheckBackground(ctx context.Context, client *meli.MeliClient) error {
+	if !s.budget.enabled() {
+		return nil
+	}
+	if err := s.Flush(ctx); err != nil {
+		return err
+	}
+	accountID, _ := client.AccountID()
+	hour, day := usagePeriods(time.Now())
+	rows, err := s.usageRepo.HoursSince(ctx, day)
+	if err != nil {
+		return err
+	}
+	var hourRequests, dayRequests int
+	for _, row := range rows {
+		if row.AccountID != accountID {
+			continue
+		}
+		dayRequests += row.Requests
+		if !row.Hour.Before(hour) {
+			hourRequests += row.Requests
+		}
+	}
+	if !s.backgroundAllowed(hourRequests, dayRequests) {
+		return ErrBackgroundBudgetExhausted
+	}
+	return nil
+}
+
+func (s *QuotaService) backgroundAllowed(hourRequests, dayRequests int) bool {
+	if s.budget.Hourly > 0 && float64(hourRequests) >= s.budget.BackgroundShare*float64(s.budget.Hourly) {
+		return false
+	}
+	if s.budget.Daily > 0 && float64(dayRequests) >= s.budget.BackgroundShare*float64(s.budget.Daily) {
+		return false
+	}
+	return true
+}
+
+// usagePeriods returns the start of the current hour and day in UTC, the
+// periods budgets apply to.
+func usagePeriods(now time.Time) (hour, day time.Time) {
+	now = now.UTC()
+	return now.Truncate(time.Hour), time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
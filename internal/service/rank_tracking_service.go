@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"melibot/internal/events"
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+const (
+	// rankPageSize is how many results a search page shows buyers.
+	rankPageSize = 50
+	// rankMaxDepth is how deep the search is followed looking for a listing.
+	rankMaxDepth = 200
+	// maxKeywordLength bounds tracked keywords, like the search box does.
+	maxKeywordLength = 256
+)
+
+var ErrRankTrackerNotFound = errors.New("rank tracker not found")
+
+// RankTrackingService checks where the seller's listings rank in the search
+// for chosen keywords, keeps their history and publishes RankDropped when a
+// listing loses at least dropThreshold positions, or falls out of reach.
+type RankTrackingService struct {
+	meliClient    *meli.MeliClient
+	repo          *repository.RankRepository
+	bus           *events.Bus
+	dropThreshold int
+}
+
+func NewRankTrackingService(meliClient *meli.MeliClient, repo *repository.RankRepository, bus *events.Bus, dropThreshold int) *RankTrackingService {
+	return &RankTrackingService{
+		meliClient:    meliClient,
+		repo:          repo,
+		bus:           bus,
+		dropThreshold: dropThreshold,
+	}
+}
+
+// ValidateRankTracker checks a keyword and listing to track.
+func ValidateRankTracker(keyword, itemID string) error {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return errors.New("keyword is required")
+	}
+	if len(keyword) > maxKeywordLength {
+		return fmt.Errorf("keyword must be at most %d characters", maxKeywordLength)
+	}
+	if strings.TrimSpace(itemID) == "" {
+		return errors.New("item_id is required")
+	}
+	return nil
+}
+
+// Trackers lists the tracked keywords with their last positions.
+func (s *RankTrackingService) Trackers(ctx context.Context) ([]repository.RankTracker, error) {
+	return s.repo.List(ctx)
+}
+
+// Track starts tracking one of the seller's listings on a keyword and
+// takes the first position. Tracking a pair twice returns the existing
+// tracker.
+func (s *RankTrackingService) Track(ctx context.Context, keyword, itemID string) (*repository.RankTracker, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.meliClient.GetItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if int64(item.SellerID) != me.ID {
+		return nil, ErrNotOwnItem
+	}
+
+	t := &repository.RankTracker{Keyword: strings.TrimSpace(keyword), ItemID: item.ID}
+	if err := s.repo.Create(ctx, t); err != nil {
+		return nil, err
+	}
+	if t.LastCheckedAt == nil {
+		if err := s.check(ctx, t); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Untrack stops tracking a keyword and drops its history.
+func (s *RankTrackingService) Untrack(ctx context.Context, id uint) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrRankTrackerNotFound
+	}
+	return nil
+}
+
+// History returns a tracker with its positions since a point in time.
+func (s *RankTrackingService) History(ctx context.Context, id uint, since time.Time) (*repository.RankTracker, []repository.RankSnapshot, error) {
+	t, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t == nil {
+		return nil, nil, ErrRankTrackerNotFound
+	}
+	snapshots, err := s.repo.History(ctx, id, since)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, snapshots, nil
+}
+
+// CheckAll checks every tracker. Trackers that fail are logged and skipped.
+func (s *RankTrackingService) CheckAll(ctx context.Context) error {
+	trackers, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i := range trackers {
+		if err := s.check(ctx, &trackers[i]); err != nil {
+			log.Printf("[WARN] Rank check failed for %q / %s: %v", trackers[i].Keyword, trackers[i].ItemID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("rank tracking: %d of %d keywords failed", failed, len(trackers))
+	}
+	return nil
+}
+
+// check searches the keyword for the tracked listing, stores its position
+// and publishes RankDropped when it dropped.
+func (s *RankTrackingService) check(ctx context.Context, t *repository.RankTracker) error {
+	position, total, err := s.position(ctx, t.Keyword, t.ItemID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	previous := t.Position
+	snapshot := &repository.RankSnapshot{Position: position, Total: total, CheckedAt: now}
+	if position != nil {
+		page := (*position-1)/rankPageSize + 1
+		snapshot.Page = &page
+	}
+	snapshot.Dropped = previous != nil && (position == nil || *position-*previous >= s.dropThreshold)
+	t.Position, t.Page, t.LastCheckedAt = snapshot.Position, snapshot.Page, &now
+	if err := s.repo.SaveSnapshot(ctx, t, snapshot); err != nil {
+		return err
+	}
+
+	if snapshot.Dropped {
+		e := events.RankDropped{Keyword: t.Keyword, ItemID: t.ItemID, OldPosition: *previous}
+		if position != nil {
+			e.NewPosition = *position
+		}
+		s.bus.Publish(ctx, e)
+	}
+	return nil
+}
+
+// position follows the search results of a keyword page by page until the
+// listing shows up, returning its 1-based position (nil when it is not
+// within rankMaxDepth) and the total of results.
+func (s *RankTrackingService) position(ctx context.Context, keyword, itemID string) (*int, int, error) {
+	total := 0
+	for offset := 0; offset < rankMaxDepth; offset += rankPageSize {
+		page, err := s.meliClient.SearchQueryPage(ctx, keyword, offset, rankPageSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		total = page.Paging.Total
+		for i, r := range page.Results {
+			if r.ID == itemID {
+				pos := offset + i + 1
+				return &pos, total, nil
+			}
+		}
+		if len(page.Results) < rankPageSize {
+			break
+		}
+	}
+	return nil, total, nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"melibot/internal/cache"
+	"melibot/internal/repository"
+)
+
+// Mover is a product that moved in a category's highlights within the
+// report window. Changes are measured from its first to its latest snapshot
+// in the window; demand compares the units sold with the previous window of
+// the same length, and DemandChangePct is nil when that window sold none.
+type Mover struct {
+	ProductID         string   `json:"product_id"`
+	CategoryID        string   `json:"category_id"`
+	Title             string   `json:"title"`
+	Thumbnail         string   `json:"thumbnail"`
+	Permalink         string   `json:"permalink"`
+	FirstRank         int      `json:"first_rank"`
+	Rank              int      `json:"rank"`
+	RankChange        int      `json:"rank_change"` // positive when it climbed
+	FirstPrice        float64  `json:"first_price"`
+	Price             float64  `json:"price"`
+	PriceChangePct    float64  `json:"price_change_pct"`
+	UnitsSold         int      `json:"units_sold"`
+	PreviousUnitsSold int      `json:"previous_units_sold"`
+	DemandChangePct   *float64 `json:"demand_change_pct"`
+}
+
+// MoversReport lists the biggest winners and losers across every
+// snapshotted category within a window.
+type MoversReport struct {
+	Window       string    `json:"window"`
+	From         time.Time `json:"from"`
+	To           time.Time `json:"to"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	RankGainers  []Mover   `json:"rank_gainers"`
+	RankLosers   []Mover   `json:"rank_losers"`
+	PriceDrops   []Mover   `json:"price_drops"`
+	DemandSpikes []Mover   `json:"demand_spikes"`
+}
+
+// ReportService builds reports from the stored snapshots only, without
+// calling Mercado Livre.
+type ReportService struct {
+	trendRepo *repository.TrendRepository
+	cache     *cache.Cache
+}
+
+func NewReportService(trendRepo *repository.TrendRepository, cache *cache.Cache) *ReportService {
+	return &ReportService{
+		trendRepo: trendRepo,
+		cache:     cache,
+	}
+}
+
+func moversCacheKey(ctx context.Context, window string, limit int) string {
+	return repository.ScopedKey(ctx, fmt.Sprintf("reports:movers:%s:%d", window, limit))
+}
+
+// Movers reports the products that moved the most in the last window:
+// name is how the window was asked for (e.g. "7d"), for the report and its
+// cache key. Each list holds up to limit products. Reports are served from
+// the response cache when fresh.
+func (s *ReportService) Movers(ctx context.Context, name string, window time.Duration, limit int) (*MoversReport, error) {
+	key := moversCacheKey(ctx, name, limit)
+	if cached, ok := s.cache.Get(key); ok {
+		return cached.(*MoversReport), nil
+	}
+
+	now := time.Now()
+	from := now.Add(-window)
+	current, err := s.trendRepo.ProductMovements(ctx, from, now)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.trendRepo.ProductMovements(ctx, from.Add(-window), from)
+	if err != nil {
+		return nil, err
+	}
+	previousSold := make(map[[2]string]int, len(previous))
+	for _, p := range previous {
+		previousSold[[2]string{p.ProductID, p.CategoryID}] = p.Sold
+	}
+
+	movers := make([]Mover, 0, len(current))
+	for _, m := range current {
+		mover := Mover{
+			ProductID:         m.ProductID,
+			CategoryID:        m.CategoryID,
+			Title:             m.Title,
+			Thumbnail:         m.Thumbnail,
+			Permalink:         m.Permalink,
+			FirstRank:         m.FirstRank,
+			Rank:              m.LastRank,
+			RankChange:        m.FirstRank - m.LastRank,
+			FirstPrice:        m.FirstPrice,
+			Price:             m.LastPrice,
+			PriceChangePct:    roundPct((m.LastPrice - m.FirstPrice) / m.FirstPrice),
+			UnitsSold:         m.Sold,
+			PreviousUnitsSold: previousSold[[2]string{m.ProductID, m.CategoryID}],
+		}
+		if mover.PreviousUnitsSold > 0 {
+			pct := roundPct(float64(mover.UnitsSold-mover.PreviousUnitsSold) / float64(mover.PreviousUnitsSold))
+			mover.DemandChangePct = &pct
+		}
+		movers = append(movers, mover)
+	}
+
+	report := &MoversReport{
+		Window:      name,
+		From:        from,
+		To:          now,
+		GeneratedAt: now,
+		RankGainers: topMovers(movers, limit, func(m Mover) float64 { return float64(m.RankChange) }),
+		RankLosers:  topMovers(movers, limit, func(m Mover) float64 { return float64(-m.RankChange) }),
+		PriceDrops:  topMovers(movers, limit, func(m Mover) float64 { return -m.PriceChangePct }),
+		DemandSpikes: topMovers(movers, limit, func(m Mover) float64 {
+			return float64(m.UnitsSold - m.PreviousUnitsSold)
+		}),
+	}
+	s.cache.Set(key, report)
+	return report, nil
+}
+
+// topMovers returns up to limit movers with a positive score, best first.
+func topMovers(movers []Mover, limit int, score func(Mover) float64) []Mover {
+	out := make([]Mover, 0, limit)
+	for _, m := range movers {
+		if score(m) > 0 {
+			out = append(out, m)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return score(out[i]) > score(out[j])
+	})
+	return out[:min(limit, len(out))]
+}
+
+// roundPct turns a ratio into a percentage with one decimal.
+func roundPct(ratio float64) float64 {
+	return math.Round(ratio*1000) / 10
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// Reputation metrics, as Mercado Livre measures them.
+const (
+	ReputationClaims          = "claims"
+	ReputationDelayedHandling = "delayed_handling"
+	ReputationCancellations   = "cancellations"
+)
+
+const (
+	// Mercado Livre measures the reputation over the last 60 days, or the
+	// last 365 days when the seller made fewer than 60 sales in 60 days.
+	reputationShortWindow = 60 * 24 * time.Hour
+	reputationLongWindow  = 365 * 24 * time.Hour
+	reputationMinSales    = 60
+	// reputationOrdersLimit is how many orders are listed per metric.
+	reputationOrdersLimit = 50
+	reputationLevelRed    = "1_red"
+)
+
+// reputationLevel holds the highest share of the sales each metric may
+// reach to keep a level.
+type reputationLevel struct {
+	ID     string
+	Limits map[string]float64
+}
+
+// reputationLevels are Mercado Livre Brasil's published limits, best level
+// first; below the last one a seller is red.
+var reputationLevels = []reputationLevel{
+	{ID: "5_green", Limits: map[string]float64{ReputationClaims: 0.01, ReputationDelayedHandling: 0.06, ReputationCancellations: 0.005}},
+	{ID: "4_light_green", Limits: map[string]float64{ReputationClaims: 0.02, ReputationDelayedHandling: 0.10, ReputationCancellations: 0.01}},
+	{ID: "3_yellow", Limits: map[string]float64{ReputationClaims: 0.04, ReputationDelayedHandling: 0.15, ReputationCancellations: 0.02}},
+	{ID: "2_orange", Limits: map[string]float64{ReputationClaims: 0.07, ReputationDelayedHandling: 0.20, ReputationCancellations: 0.04}},
+}
+
+var reputationMetrics = []string{ReputationClaims, ReputationDelayedHandling, ReputationCancellations}
+
+// ReputationOrder is an order that counts against a reputation metric.
+type ReputationOrder struct {
+	OrderID       int64     `json:"order_id"`
+	DateCreated   time.Time `json:"date_created"`
+	Status        string    `json:"status"`
+	BuyerNickname string    `json:"buyer_nickname"`
+	Items         []string  `json:"items"`
+	Detail        string    `json:"detail,omitempty"`
+}
+
+// ReputationMetric is where a metric stands: its level, the limit of that
+// level and how many more orders would drop it to the next one. AtRisk is
+// set when the rate is within the alert margin of the limit.
+type ReputationMetric struct {
+	Metric       string            `json:"metric"`
+	Count        int               `json:"count"`
+	RatePct      float64           `json:"rate_pct"`
+	Level        string            `json:"level"`
+	LimitPct     *float64          `json:"limit_pct"`
+	OrdersToDrop *int              `json:"orders_to_drop"`
+	AtRisk       bool              `json:"at_risk"`
+	Orders       []ReputationOrder `json:"orders"`
+}
+
+// ReputationStanding is the seller's reputation computed from the synced
+// orders and claims. Level is the worst level of the metrics; MeliLevel is
+// the one Mercado Livre currently shows, which lags behind.
+type ReputationStanding struct {
+	Level     string             `json:"level"`
+	MeliLevel string             `json:"meli_level,omitempty"`
+	From      time.Time          `json:"from"`
+	To        time.Time          `json:"to"`
+	Sales     int                `json:"sales"`
+	MarginPct float64            `json:"margin_pct"`
+	AtRisk    bool               `json:"at_risk"`
+	Metrics   []ReputationMetric `json:"metrics"`
+
+	sellerID int64
+}
+
+// ReputationService watches the seller's claim, delayed dispatch and
+// cancellation rates against Mercado Livre's reputation levels.
+type ReputationService struct {
+	meliClient *meli.MeliClient
+	repo       *repository.ReputationRepository
+	// margin is how close to a limit, as a share of it, a metric is at risk.
+	margin float64
+}
+
+func NewReputationService(meliClient *meli.MeliClient, repo *repository.ReputationRepository, margin float64) *ReputationService {
+	return &ReputationService{
+		meliClient: meliClient,
+		repo:       repo,
+		margin:     margin,
+	}
+}
+
+// Standing computes the seller's reputation metrics over Mercado Livre's
+// window. Claims count every claim but cancellations; cancellations count
+// cancelled orders the buyer did not ask to cancel; delayed dispatches
+// count the orders the dispatch check saw past their handling deadline.
+func (s *ReputationService) Standing(ctx context.Context) (*ReputationStanding, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	to := time.Now()
+	from := to.Add(-reputationShortWindow)
+	n, err := s.repo.CountSales(ctx, me.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if n < reputationMinSales {
+		from = to.Add(-reputationLongWindow)
+	}
+
+	orders, err := s.repo.Sales(ctx, me.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.repo.Claims(ctx, me.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	claimed := map[int64]string{}
+	buyerCancelled := map[int64]bool{}
+	for _, c := range claims {
+		switch c.Type {
+		case meli.ClaimTypeCancelPurchase:
+			buyerCancelled[c.OrderID] = true
+		case meli.ClaimTypeCancelSale:
+		default:
+			if _, ok := claimed[c.OrderID]; !ok {
+				claimed[c.OrderID] = c.ReasonID
+			}
+		}
+	}
+
+	standing := &ReputationStanding{
+		From:      from,
+		To:        to,
+		Sales:     len(orders),
+		MarginPct: roundPct(s.margin),
+		Metrics:   make([]ReputationMetric, 0, len(reputationMetrics)),
+		sellerID:  me.ID,
+	}
+	if me.SellerReputation != nil {
+		standing.MeliLevel = me.SellerReputation.LevelID
+	}
+	counted := map[string][]ReputationOrder{}
+	counts := map[string]int{}
+	count := func(metric string, o *repository.Order, detail string) {
+		counts[metric]++
+		if len(counted[metric]) < reputationOrdersLimit {
+			counted[metric] = append(counted[metric], reputationOrder(o, detail))
+		}
+	}
+	for i := range orders {
+		o := &orders[i]
+		if reason, ok := claimed[o.ID]; ok {
+			count(ReputationClaims, o, reason)
+		}
+		if o.DispatchLateAt != nil {
+			count(ReputationDelayedHandling, o, "late since "+o.DispatchLateAt.Format(time.RFC3339))
+		}
+		if o.Status == repository.OrderStatusCancelled && !buyerCancelled[o.ID] {
+			count(ReputationCancellations, o, "")
+		}
+	}
+
+	worst := 0
+	for _, metric := range reputationMetrics {
+		m := s.metric(metric, counts[metric], standing.Sales)
+		m.Orders = counted[metric]
+		if m.Orders == nil {
+			m.Orders = []ReputationOrder{}
+		}
+		worst = max(worst, levelIndex(m.Level))
+		standing.AtRisk = standing.AtRisk || m.AtRisk
+		standing.Metrics = append(standing.Metrics, m)
+	}
+	standing.Level = levelID(worst)
+	return standing, nil
+}
+
+// metric places a metric's count among the reputation levels.
+func (s *ReputationService) metric(metric string, count, sales int) ReputationMetric {
+	m := ReputationMetric{Metric: metric, Count: count, Level: reputationLevelRed}
+	rate := 0.0
+	if sales > 0 {
+		rate = float64(count) / float64(sales)
+	}
+	m.RatePct = roundPct(rate)
+	for _, level := range reputationLevels {
+		limit := level.Limits[metric]
+		if rate > limit {
+			continue
+		}
+		m.Level = level.ID
+		limitPct := roundPct(limit)
+		m.LimitPct = &limitPct
+		// The count may reach limit*sales; one more order drops the level.
+		toDrop := int(math.Floor(limit*float64(sales))) - count + 1
+		m.OrdersToDrop = &toDrop
+		m.AtRisk = sales > 0 && rate >= limit*(1-s.margin)
+		break
+	}
+	return m
+}
+
+// CheckGuardrails opens an alert for each metric within the margin of the
+// limit of its level, and resolves the alerts of metrics back under it.
+func (s *ReputationService) CheckGuardrails(ctx context.Context) error {
+	standing, err := s.Standing(ctx)
+	if err != nil {
+		return err
+	}
+	open, err := s.repo.OpenAlerts(ctx, standing.sellerID)
+	if err != nil {
+		return err
+	}
+
+	var resolved []uint
+	for _, m := range standing.Metrics {
+		alert, isOpen := open[m.Metric]
+		switch {
+		case m.AtRisk && !isOpen:
+			alert = &repository.ReputationAlert{
+				SellerID: standing.sellerID,
+				Metric:   m.Metric,
+				Level:    m.Level,
+				Rate:     m.RatePct,
+				Limit:    *m.LimitPct,
+				Count:    m.Count,
+				Sales:    standing.Sales,
+			}
+			if err := s.repo.CreateAlert(ctx, alert); err != nil {
+				return err
+			}
+			log.Printf("[WARN] Reputation at risk: %s at %.1f%% of sales, %s limit %.1f%% (%d more orders drop the level)",
+				m.Metric, m.RatePct, m.Level, *m.LimitPct, *m.OrdersToDrop)
+		case !m.AtRisk && isOpen:
+			resolved = append(resolved, alert.ID)
+		}
+	}
+	return s.repo.ResolveAlerts(ctx, resolved, time.Now())
+}
+
+// Alerts returns the seller's recent reputation alerts.
+func (s *ReputationService) Alerts(ctx context.Context, openOnly bool, limit int) ([]repository.ReputationAlert, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.Alerts(ctx, me.ID, openOnly, limit)
+}
+
+func reputationOrder(o *repository.Order, detail string) ReputationOrder {
+	r := ReputationOrder{
+		OrderID:       o.ID,
+		DateCreated:   o.DateCreated,
+		Status:        o.Status,
+		BuyerNickname: o.BuyerNickname,
+		Items:         make([]string, 0, len(o.Items)),
+		Detail:        detail,
+	}
+	for _, it := range o.Items {
+		r.Items = append(r.Items, it.Title)
+	}
+	return r
+}
+
+// levelIndex is a level's position in reputationLevels; red comes last.
+func levelIndex(id string) int {
+	for i, level := range reputationLevels {
+		if level.ID == id {
+			return i
+		}
+	}
+	return len(reputationLevels)
+}
+
+func levelID(i int) string {
+	if i < len(reputationLevels) {
+		return reputationLevels[i].ID
+	}
+	return reputationLevelRed
+}
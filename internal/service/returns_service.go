@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+const (
+	// minReturnsOrders is how many orders a SKU needs before its claim rate
+	// can be called abnormal.
+	minReturnsOrders = 5
+	// abnormalClaimFactor and abnormalClaimMargin flag SKUs whose claim rate
+	// is at least this many times the seller's, and this many points above.
+	abnormalClaimFactor = 2.0
+	abnormalClaimMargin = 0.05
+	// topReasonsLimit is how many claim reasons are listed per SKU.
+	topReasonsLimit = 3
+)
+
+// cancellationClaimTypes are claims that cancel an order rather than
+// complain about the product; they are left out of the returns analytics.
+var cancellationClaimTypes = []string{meli.ClaimTypeCancelPurchase, meli.ClaimTypeCancelSale}
+
+var ErrInvalidReturnsGrouping = errors.New("group_by must be day, week or month")
+
+// ReturnRates are the orders, claimed orders and returned orders of a SKU,
+// category or period. Rates are percentages of the orders.
+type ReturnRates struct {
+	Orders        int     `json:"orders"`
+	Claims        int     `json:"claims"`
+	Returns       int     `json:"returns"`
+	ClaimRatePct  float64 `json:"claim_rate_pct"`
+	ReturnRatePct float64 `json:"return_rate_pct"`
+}
+
+// ReasonCount is how many claims gave a reason.
+type ReasonCount struct {
+	ReasonID string `json:"reason_id"`
+	Claims   int    `json:"claims"`
+}
+
+// SKUReturns are the return rates of a SKU. Abnormal is set when its claim
+// rate stands out from the seller's.
+type SKUReturns struct {
+	SKU        string        `json:"sku"`
+	ItemID     string        `json:"item_id"`
+	Title      string        `json:"title"`
+	CategoryID string        `json:"category_id,omitempty"`
+	TopReasons []ReasonCount `json:"top_reasons"`
+	Abnormal   bool          `json:"abnormal"`
+	ReturnRates
+}
+
+// CategoryReturns are the return rates of a category.
+type CategoryReturns struct {
+	CategoryID string `json:"category_id"`
+	ReturnRates
+}
+
+// PeriodReturns are the return rates of the orders created in a period.
+type PeriodReturns struct {
+	Period string `json:"period"`
+	ReturnRates
+}
+
+// ReturnsReport are the claims and returns of the orders created over a
+// range, per SKU, category and period. Abnormal lists the SKUs whose claim
+// rate stands out, worst first.
+type ReturnsReport struct {
+	GroupBy    string            `json:"group_by"`
+	From       time.Time         `json:"from"`
+	To         time.Time         `json:"to"`
+	Totals     ReturnRates       `json:"totals"`
+	SKUs       []SKUReturns      `json:"skus"`
+	Categories []CategoryReturns `json:"categories"`
+	Periods    []PeriodReturns   `json:"periods"`
+	Abnormal   []string          `json:"abnormal_skus"`
+}
+
+// returnCounts collects the distinct orders, claimed and returned orders
+// of a group.
+type returnCounts struct {
+	orders, claimed, returned map[int64]bool
+}
+
+func newReturnCounts() *returnCounts {
+	return &returnCounts{orders: map[int64]bool{}, claimed: map[int64]bool{}, returned: map[int64]bool{}}
+}
+
+func (c *returnCounts) add(l *repository.ClaimLine) {
+	c.orders[l.OrderID] = true
+	if l.ClaimID != nil {
+		c.claimed[l.OrderID] = true
+	}
+	if l.Returned {
+		c.returned[l.OrderID] = true
+	}
+}
+
+func (c *returnCounts) rates() ReturnRates {
+	r := ReturnRates{Orders: len(c.orders), Claims: len(c.claimed), Returns: len(c.returned)}
+	if r.Orders > 0 {
+		r.ClaimRatePct = roundPct(float64(r.Claims) / float64(r.Orders))
+		r.ReturnRatePct = roundPct(float64(r.Returns) / float64(r.Orders))
+	}
+	return r
+}
+
+// ReturnsService syncs the claims against the seller and reports claim
+// and return rates per SKU and category.
+type ReturnsService struct {
+	meliClient *meli.MeliClient
+	claimRepo  *repository.ClaimRepository
+}
+
+func NewReturnsService(meliClient *meli.MeliClient, claimRepo *repository.ClaimRepository) *ReturnsService {
+	return &ReturnsService{
+		meliClient: meliClient,
+		claimRepo:  claimRepo,
+	}
+}
+
+// SyncClaims polls the claims updated since the last sync and upserts them.
+// It returns how many claims were stored.
+func (s *ReturnsService) SyncClaims(ctx context.Context) (int, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return 0, err
+	}
+	since, err := s.claimRepo.LastUpdated(ctx, me.ID)
+	if err != nil {
+		return 0, err
+	}
+	if since.IsZero() {
+		since = time.Now().Add(-initialOrderSyncWindow)
+	} else {
+		since = since.Add(-orderSyncOverlap)
+	}
+
+	claims, err := s.meliClient.SearchClaims(ctx, me.ID, since)
+	if err != nil {
+		return 0, err
+	}
+	stored := 0
+	for i := range claims {
+		ok, err := s.store(ctx, me.ID, &claims[i])
+		if err != nil {
+			return stored, err
+		}
+		if ok {
+			stored++
+		}
+	}
+	if stored > 0 {
+		log.Printf("[INFO] Synced %d claims for seller %d", stored, me.ID)
+	}
+	return stored, nil
+}
+
+// HandleClaimNotification processes a "claims" notification: the claim is
+// fetched from Mercado Livre and stored.
+func (s *ReturnsService) HandleClaimNotification(ctx context.Context, n meli.Notification) error {
+	claim, err := s.meliClient.GetClaim(ctx, n.ResourceID())
+	if err != nil {
+		return err
+	}
+	_, err = s.store(ctx, n.UserID, claim)
+	return err
+}
+
+// store upserts a claim about an order; claims about other resources are
+// skipped. It reports whether the claim was stored.
+func (s *ReturnsService) store(ctx context.Context, sellerID int64, c *meli.Claim) (bool, error) {
+	if c.Resource != "order" || c.ResourceID == 0 {
+		return false, nil
+	}
+	err := s.claimRepo.Upsert(ctx, &repository.Claim{
+		ID:          c.ID,
+		OrderID:     c.ResourceID,
+		SellerID:    sellerID,
+		Type:        c.Type,
+		Stage:       c.Stage,
+		Status:      c.Status,
+		ReasonID:    c.ReasonID,
+		Returned:    c.Returned(),
+		DateCreated: c.DateCreated,
+		LastUpdated: c.LastUpdated,
+	})
+	return err == nil, err
+}
+
+// ReturnsReport computes the claim and return rates of the orders created
+// in [from, to), by SKU, category and period (day, week or month). A claim
+// counts towards every SKU of its order; cancellations are left out.
+func (s *ReturnsService) ReturnsReport(ctx context.Context, groupBy string, from, to time.Time) (*ReturnsReport, error) {
+	periodStart, ok := periodTruncators[groupBy]
+	if !ok {
+		return nil, ErrInvalidReturnsGrouping
+	}
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.claimRepo.ClaimLines(ctx, me.ID, from, to, cancellationClaimTypes)
+	if err != nil {
+		return nil, err
+	}
+	categories := s.itemCategories(ctx, lines)
+
+	type skuGroup struct {
+		returns SKUReturns
+		counts  *returnCounts
+		reasons map[string]map[int64]bool
+	}
+	totals := newReturnCounts()
+	bySKU := map[string]*skuGroup{}
+	byCategory := map[string]*returnCounts{}
+	byPeriod := map[string]*returnCounts{}
+	for i := range lines {
+		l := &lines[i]
+		totals.add(l)
+
+		g := bySKU[l.SKU]
+		if g == nil {
+			g = &skuGroup{
+				returns: SKUReturns{SKU: l.SKU, ItemID: l.ItemID, Title: l.Title, CategoryID: categories[l.ItemID]},
+				counts:  newReturnCounts(),
+				reasons: map[string]map[int64]bool{},
+			}
+			bySKU[l.SKU] = g
+		}
+		g.counts.add(l)
+		if l.ClaimID != nil && l.ReasonID != "" {
+			if g.reasons[l.ReasonID] == nil {
+				g.reasons[l.ReasonID] = map[int64]bool{}
+			}
+			g.reasons[l.ReasonID][*l.ClaimID] = true
+		}
+
+		if category := categories[l.ItemID]; category != "" {
+			if byCategory[category] == nil {
+				byCategory[category] = newReturnCounts()
+			}
+			byCategory[category].add(l)
+		}
+
+		key := periodStart(l.DateCreated).Format(time.DateOnly)
+		if byPeriod[key] == nil {
+			byPeriod[key] = newReturnCounts()
+		}
+		byPeriod[key].add(l)
+	}
+
+	report := &ReturnsReport{
+		GroupBy:    groupBy,
+		From:       from,
+		To:         to,
+		Totals:     totals.rates(),
+		SKUs:       []SKUReturns{},
+		Categories: []CategoryReturns{},
+		Periods:    []PeriodReturns{},
+		Abnormal:   []string{},
+	}
+	overall := 0.0
+	if report.Totals.Orders > 0 {
+		overall = float64(report.Totals.Claims) / float64(report.Totals.Orders)
+	}
+	threshold := max(overall*abnormalClaimFactor, overall+abnormalClaimMargin)
+	for _, g := range bySKU {
+		r := g.returns
+		r.ReturnRates = g.counts.rates()
+		r.TopReasons = topReasons(g.reasons)
+		r.Abnormal = r.Orders >= minReturnsOrders && r.Claims > 0 && float64(r.Claims)/float64(r.Orders) >= threshold
+		report.SKUs = append(report.SKUs, r)
+	}
+	sort.Slice(report.SKUs, func(i, j int) bool {
+		a, b := report.SKUs[i], report.SKUs[j]
+		if a.ClaimRatePct != b.ClaimRatePct {
+			return a.ClaimRatePct > b.ClaimRatePct
+		}
+		if a.Orders != b.Orders {
+			return a.Orders > b.Orders
+		}
+		return a.SKU < b.SKU
+	})
+	for _, r := range report.SKUs {
+		if r.Abnormal {
+			report.Abnormal = append(report.Abnormal, r.SKU)
+		}
+	}
+	for id, c := range byCategory {
+		report.Categories = append(report.Categories, CategoryReturns{CategoryID: id, ReturnRates: c.rates()})
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		a, b := report.Categories[i], report.Categories[j]
+		if a.ClaimRatePct != b.ClaimRatePct {
+			return a.ClaimRatePct > b.ClaimRatePct
+		}
+		return a.CategoryID < b.CategoryID
+	})
+	for key, c := range byPeriod {
+		report.Periods = append(report.Periods, PeriodReturns{Period: key, ReturnRates: c.rates()})
+	}
+	sort.Slice(report.Periods, func(i, j int) bool { return report.Periods[i].Period < report.Periods[j].Period })
+	return report, nil
+}
+
+// itemCategories returns the category of each listing of the lines. It is
+// best-effort: listings that cannot be read are left out of the category
+// breakdown.
+func (s *ReturnsService) itemCategories(ctx context.Context, lines []repository.ClaimLine) map[string]string {
+	var ids []string
+	seen := map[string]bool{}
+	for _, l := range lines {
+		if !seen[l.ItemID] {
+			seen[l.ItemID] = true
+			ids = append(ids, l.ItemID)
+		}
+	}
+	categories := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return categories
+	}
+	items, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil {
+		log.Printf("[WARN] Failed to read the categories of %d listings: %v", len(ids), err)
+		return categories
+	}
+	for _, it := range items {
+		categories[it.ID] = it.CategoryID
+	}
+	return categories
+}
+
+// topReasons returns the most given claim reasons, by distinct claims.
+func topReasons(reasons map[string]map[int64]bool) []ReasonCount {
+	out := make([]ReasonCount, 0, len(reasons))
+	for id, claims := range reasons {
+		out = append(out, ReasonCount{ReasonID: id, Claims: len(claims)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Claims != out[j].Claims {
+			return out[i].Claims > out[j].Claims
+		}
+		return out[i].ReasonID < out[j].ReasonID
+	})
+	return out[:min(topReasonsLimit, len(out))]
+}
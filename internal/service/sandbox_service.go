@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// ErrSandboxDisabled is returned by sandbox operations outside sandbox mode.
+var ErrSandboxDisabled = errors.New("sandbox mode is disabled; set ML_ENVIRONMENT=sandbox")
+
+// SandboxService manages Mercado Livre test users for ML_ENVIRONMENT=sandbox.
+type SandboxService struct {
+	meliClient   *meli.MeliClient
+	testUserRepo *repository.TestUserRepository
+	enabled      bool
+}
+
+func NewSandboxService(meliClient *meli.MeliClient, testUserRepo *repository.TestUserRepository, enabled bool) *SandboxService {
+	return &SandboxService{
+		meliClient:   meliClient,
+		testUserRepo: testUserRepo,
+		enabled:      enabled,
+	}
+}
+
+// CreateTestUser creates a test user on Mercado Livre and stores it.
+func (s *SandboxService) CreateTestUser(ctx context.Context) (*repository.TestUser, error) {
+	if !s.enabled {
+		return nil, ErrSandboxDisabled
+	}
+
+	created, err := s.meliClient.CreateTestUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &repository.TestUser{
+		MLUserID:   created.ID,
+		Nickname:   created.Nickname,
+		Email:      created.Email,
+		Password:   created.Password,
+		SiteStatus: created.SiteStatus,
+	}
+	if err := s.testUserRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// ListTestUsers returns the stored test users.
+func (s *SandboxService) ListTestUsers(ctx context.Context) ([]repository.TestUser, error) {
+	if !s.enabled {
+		return nil, ErrSandboxDisabled
+	}
+	return s.testUserRepo.List(ctx)
+}
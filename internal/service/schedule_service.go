@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"melibot/internal/repository"
+	"melibot/internal/scheduler"
+)
+
+// ScheduleUpdate changes the schedule of a task; fields left out are kept.
+type ScheduleUpdate struct {
+	// Schedule is a cron expression or @every interval, empty for the
+	// task's default interval
+	Schedule *string `json:"schedule"`
+	Enabled  *bool   `json:"enabled"`
+}
+
+// ScheduleService edits the schedules of the scheduled tasks at runtime and
+// persists them, so they survive restarts and reach every instance.
+type ScheduleService struct {
+	sched *scheduler.Scheduler
+	repo  *repository.ScheduleRepository
+}
+
+func NewScheduleService(sched *scheduler.Scheduler, repo *repository.ScheduleRepository) *ScheduleService {
+	return &ScheduleService{sched: sched, repo: repo}
+}
+
+// Schedules returns every task with its schedule, last and next run.
+func (s *ScheduleService) Schedules() []scheduler.TaskStatus {
+	return s.sched.Status()
+}
+
+// Schedule returns a single task.
+func (s *ScheduleService) Schedule(name string) (*scheduler.TaskStatus, error) {
+	st, ok := s.sched.Lookup(name)
+	if !ok {
+		return nil, scheduler.ErrUnknownTask
+	}
+	return &st, nil
+}
+
+// UpdateSchedule stores and applies a new schedule for a task.
+func (s *ScheduleService) UpdateSchedule(ctx context.Context, name string, upd ScheduleUpdate) (*scheduler.TaskStatus, error) {
+	current, ok := s.sched.Lookup(name)
+	if !ok {
+		return nil, scheduler.ErrUnknownTask
+	}
+	spec, enabled := current.Schedule, current.Enabled
+	if upd.Schedule != nil {
+		spec = strings.TrimSpace(*upd.Schedule)
+	}
+	if upd.Enabled != nil {
+		enabled = *upd.Enabled
+	}
+	if spec == current.DefaultSchedule {
+		spec = ""
+	}
+	if spec != "" {
+		if _, err := scheduler.Parse(spec); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Save(ctx, &repository.ScheduleSetting{Name: name, Cron: spec, Enabled: enabled}); err != nil {
+		return nil, err
+	}
+	st, err := s.sched.Update(name, spec, enabled)
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// Restore applies the stored schedules, at start and then periodically to
+// pick up the changes made on other instances. Settings of tasks that no
+// longer exist, or that no longer parse, are skipped.
+func (s *ScheduleService) Restore(ctx context.Context) error {
+	settings, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, setting := range settings {
+		_, err := s.sched.Update(setting.Name, setting.Cron, setting.Enabled)
+		if errors.Is(err, scheduler.ErrUnknownTask) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[WARN] scheduler: ignoring stored schedule of %s: %v", setting.Name, err)
+		}
+	}
+	return nil
+}
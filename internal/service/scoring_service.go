@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// velocityWindow is how far back stored snapshots are used to estimate how
+// fast a product sells.
+const velocityWindow = 30 * 24 * time.Hour
+
+// ErrProfileNotFound is returned when a scoring profile does not exist.
+var ErrProfileNotFound = errors.New("scoring profile not found")
+
+// defaultProfile is used for ?profile=default until one is stored.
+var defaultProfile = repository.ScoringProfile{
+	Name:              repository.DefaultScoringProfile,
+	WeightSold:        0.4,
+	WeightPrice:       0.2,
+	WeightCompetition: 0.2,
+	WeightHealth:      0.2,
+}
+
+// ScoredItem is a trend item with its opportunity score (0-100) and the
+// 0-1 value of each signal that went into it.
+type ScoredItem struct {
+	meli.SearchItem
+	Score     float64            `json:"score"`
+	Breakdown map[string]float64 `json:"score_breakdown"`
+}
+
+// ScoredTrends is a TrendsResult ranked by a scoring profile.
+type ScoredTrends struct {
+	Items   []ScoredItem `json:"items"`
+	Total   int          `json:"total"`
+	Partial bool         `json:"partial"`
+	Profile string       `json:"profile"`
+}
+
+// ScoringService ranks trends by named, user-defined scoring profiles.
+type ScoringService struct {
+	profileRepo *repository.ScoringProfileRepository
+	trendRepo   *repository.TrendRepository
+	statsRepo   *repository.CategoryStatsRepository
+}
+
+func NewScoringService(profileRepo *repository.ScoringProfileRepository, trendRepo *repository.TrendRepository, statsRepo *repository.CategoryStatsRepository) *ScoringService {
+	return &ScoringService{
+		profileRepo: profileRepo,
+		trendRepo:   trendRepo,
+		statsRepo:   statsRepo,
+	}
+}
+
+// Profiles lists the stored profiles.
+func (s *ScoringService) Profiles(ctx context.Context) ([]repository.ScoringProfile, error) {
+	return s.profileRepo.List(ctx)
+}
+
+// Profile returns a profile by name, falling back to the built-in default.
+func (s *ScoringService) Profile(ctx context.Context, name string) (*repository.ScoringProfile, error) {
+	p, err := s.profileRepo.FindByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		if name == repository.DefaultScoringProfile {
+			def := defaultProfile
+			return &def, nil
+		}
+		return nil, ErrProfileNotFound
+	}
+	return p, nil
+}
+
+// SaveProfile creates or replaces a named profile.
+func (s *ScoringService) SaveProfile(ctx context.Context, p repository.ScoringProfile) (*repository.ScoringProfile, error) {
+	if err := ValidateProfile(p); err != nil {
+		return nil, err
+	}
+	existing, err := s.profileRepo.FindByName(ctx, p.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		p.ID, p.CreatedAt = existing.ID, existing.CreatedAt
+	}
+	if err := s.profileRepo.Save(ctx, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// DeleteProfile removes a named profile.
+func (s *ScoringService) DeleteProfile(ctx context.Context, name string) error {
+	deleted, err := s.profileRepo.Delete(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrProfileNotFound
+	}
+	return nil
+}
+
+// ValidateProfile rejects negative or all-zero weights and an inverted price
+// band.
+func ValidateProfile(p repository.ScoringProfile) error {
+	weights := []float64{p.WeightSold, p.WeightPrice, p.WeightCompetition, p.WeightHealth}
+	sum := 0.0
+	for _, w := range weights {
+		if w < 0 {
+			return errors.New("weights must not be negative")
+		}
+		sum += w
+	}
+	if sum == 0 {
+		return errors.New("at least one weight must be positive")
+	}
+	if p.PriceBandMin != nil && p.PriceBandMax != nil && *p.PriceBandMin > *p.PriceBandMax {
+		return errors.New("price_band_min must not exceed price_band_max")
+	}
+	return nil
+}
+
+// Rank scores the trends of a category with the named profile and sorts
+// them best first.
+func (s *ScoringService) Rank(ctx context.Context, profileName, categoryID string, trends *TrendsResult) (*ScoredTrends, error) {
+	profile, err := s.Profile(ctx, profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.statsRepo.Latest(ctx, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("load category stats: %w", err)
+	}
+
+	ids := make([]string, 0, len(trends.Items))
+	for _, it := range trends.Items {
+		ids = append(ids, it.ID)
+	}
+	snaps, err := s.trendRepo.SoldSnapshots(ctx, ids, time.Now().Add(-velocityWindow))
+	if err != nil {
+		return nil, fmt.Errorf("load sold history: %w", err)
+	}
+
+	scored := scoreItems(trends.Items, profile, stats, soldVelocity(snaps))
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+
+	return &ScoredTrends{Items: scored, Total: trends.Total, Partial: trends.Partial, Profile: profile.Name}, nil
+}
+
+// soldVelocity returns units sold per day for products with at least two
+// snapshots a day apart.
+func soldVelocity(snaps []repository.SoldSnapshot) map[string]float64 {
+	first := map[string]repository.SoldSnapshot{}
+	last := map[string]repository.SoldSnapshot{}
+	for _, s := range snaps {
+		if _, ok := first[s.ProductID]; !ok {
+			first[s.ProductID] = s
+		}
+		last[s.ProductID] = s
+	}
+
+	velocity := make(map[string]float64, len(first))
+	for id, f := range first {
+		l := last[id]
+		days := l.CreatedAt.Sub(f.CreatedAt).Hours() / 24
+		if days < 1 || l.SoldQuantity < f.SoldQuantity {
+			continue
+		}
+		velocity[id] = float64(l.SoldQuantity-f.SoldQuantity) / days
+	}
+	return velocity
+}
+
+func scoreItems(items []meli.SearchItem, p *repository.ScoringProfile, stats *repository.CategoryStats, velocity map[string]float64) []ScoredItem {
+	// Products without history fall back to their total sold, so the sold
+	// signal is normalized separately for each source.
+	var maxVelocity, maxSold float64
+	for _, it := range items {
+		maxVelocity = math.Max(maxVelocity, velocity[it.ID])
+		maxSold = math.Max(maxSold, float64(it.SoldQuantity))
+	}
+
+	competition := competitionSignal(stats)
+	totalWeight := p.WeightSold + p.WeightPrice + p.WeightCompetition + p.WeightHealth
+
+	out := make([]ScoredItem, 0, len(items))
+	for _, it := range items {
+		sold := 0.0
+		if v, ok := velocity[it.ID]; ok && maxVelocity > 0 {
+			sold = v / maxVelocity
+		} else if maxSold > 0 {
+			sold = float64(it.SoldQuantity) / maxSold
+		}
+
+		breakdown := map[string]float64{
+			"sold":        sold,
+			"price":       priceSignal(it.Price, p, stats),
+			"competition": competition,
+			"health":      healthSignal(it.Health),
+		}
+		score := (p.WeightSold*breakdown["sold"] +
+			p.WeightPrice*breakdown["price"] +
+			p.WeightCompetition*breakdown["competition"] +
+			p.WeightHealth*breakdown["health"]) / totalWeight
+
+		out = append(out, ScoredItem{SearchItem: it, Score: math.Round(score*1000) / 10, Breakdown: breakdown})
+	}
+	return out
+}
+
+// priceSignal is 1 inside the profile's price band (or the category's
+// interquartile range when the profile has none) and decays with the
+// distance outside it.
+func priceSignal(price float64, p *repository.ScoringProfile, stats *repository.CategoryStats) float64 {
+	if price <= 0 {
+		return 0
+	}
+	lo, hi := 0.0, math.Inf(1)
+	switch {
+	case p.PriceBandMin != nil || p.PriceBandMax != nil:
+		if p.PriceBandMin != nil {
+			lo = *p.PriceBandMin
+		}
+		if p.PriceBandMax != nil {
+			hi = *p.PriceBandMax
+		}
+	case stats != nil && stats.PriceP75 > 0:
+		lo, hi = stats.PriceP25, stats.PriceP75
+	default:
+		return 0.5
+	}
+
+	switch {
+	case price < lo:
+		return price / lo
+	case price > hi:
+		return hi / price
+	}
+	return 1
+}
+
+// competitionSignal favours categories with fewer listings, on a log scale
+// where a million listings scores 0. Unknown categories are neutral.
+func competitionSignal(stats *repository.CategoryStats) float64 {
+	if stats == nil || stats.TotalListings <= 0 {
+		return 0.5
+	}
+	return math.Max(0, 1-math.Log10(float64(stats.TotalListings))/6)
+}
+
+// healthSignal reads Mercado Livre's 0-1 listing health; missing values are
+// neutral.
+func healthSignal(health string) float64 {
+	h, ok := meli.ParseHealth(health)
+	if !ok {
+		return 0.5
+	}
+	return h
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"melibot/pkg/meli"
+)
+
+const (
+	// MaxSupplierRows caps the rows of an imported supplier catalog.
+	MaxSupplierRows = 500
+	// screeningConcurrency bounds the rows screened in parallel.
+	screeningConcurrency = 4
+	// screeningSearchLimit is how many listings define the market of a row.
+	screeningSearchLimit = 20
+	// worthListingMargin is the gross margin from which a product with demand
+	// is worth listing.
+	worthListingMargin = 30.0
+)
+
+// SupplierRow is a product of a supplier price list.
+type SupplierRow struct {
+	Line  int     `json:"line"`
+	Title string  `json:"title"`
+	Cost  float64 `json:"cost"`
+	EAN   string  `json:"ean,omitempty"`
+}
+
+// supplierColumns maps accepted header names to columns.
+var supplierColumns = map[string]string{
+	"title": "title", "titulo": "title", "título": "title", "produto": "title", "descricao": "title", "descrição": "title",
+	"cost": "cost", "custo": "cost", "preco": "cost", "preço": "cost",
+	"ean": "ean", "gtin": "ean", "codigo de barras": "ean", "código de barras": "ean",
+}
+
+// ParseSupplierCSV reads a title,cost,EAN catalog. The header row is
+// optional; with one, columns may come in any order. Semicolon-separated
+// files and Brazilian decimals ("1.234,56") are accepted.
+func ParseSupplierCSV(r io.Reader) ([]SupplierRow, error) {
+	records, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cols := map[string]int{"title": 0, "cost": 1, "ean": 2}
+	start := 0
+	if header := headerColumns(records[0]); header != nil {
+		cols, start = header, 1
+	}
+
+	var rows []SupplierRow
+	for i, rec := range records[start:] {
+		line := start + i + 1
+		field := func(name string) string {
+			if idx, ok := cols[name]; ok && idx < len(rec) {
+				return strings.TrimSpace(rec[idx])
+			}
+			return ""
+		}
+		title := field("title")
+		if title == "" {
+			continue
+		}
+		cost, err := parseDecimal(field("cost"))
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("line %d: invalid cost %q", line, field("cost"))
+		}
+		rows = append(rows, SupplierRow{Line: line, Title: title, Cost: cost, EAN: field("ean")})
+		if len(rows) > MaxSupplierRows {
+			return nil, fmt.Errorf("the CSV has more than %d products", MaxSupplierRows)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("the CSV has no products")
+	}
+	return rows, nil
+}
+
+// readCSV reads an uploaded spreadsheet of up to 5 MB, comma- or
+// semicolon-separated, with or without a byte order mark.
+func readCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, 5<<20))
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+
+	reader := csv.NewReader(strings.NewReader(text))
+	firstLine, _, _ := strings.Cut(text, "\n")
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("the CSV is empty")
+	}
+	return records, nil
+}
+
+// headerColumns returns the column of each known field when rec is a header
+// row, or nil.
+func headerColumns(rec []string) map[string]int {
+	cols := map[string]int{}
+	for i, name := range rec {
+		if field, ok := supplierColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			cols[field] = i
+		}
+	}
+	if _, ok := cols["title"]; !ok {
+		return nil
+	}
+	return cols
+}
+
+// parseDecimal accepts "1234.56", "1234,56" and "1.234,56".
+func parseDecimal(v string) (float64, error) {
+	v = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(v), "R$"))
+	if strings.Contains(v, ",") {
+		v = strings.ReplaceAll(v, ".", "")
+		v = strings.ReplaceAll(v, ",", ".")
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// ScreenedProduct is the market outlook of a supplier product.
+type ScreenedProduct struct {
+	SupplierRow
+	CategoryID      string  `json:"category_id,omitempty"`
+	CategoryName    string  `json:"category_name,omitempty"`
+	CategoryProb    float64 `json:"category_probability,omitempty"`
+	MatchedByEAN    bool    `json:"matched_by_ean"`
+	SampledListings int     `json:"sampled_listings"`
+	Competitors     int     `json:"competitors"`
+	MarketPrice     float64 `json:"market_price"`
+	UnitsSold       int     `json:"units_sold"`
+	GrossMarginPct  float64 `json:"gross_margin_pct"`
+	Score           float64 `json:"score"`
+	WorthListing    bool    `json:"worth_listing"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// ScreeningReport ranks the products of a supplier catalog, most promising
+// first.
+type ScreeningReport struct {
+	Total        int               `json:"total"`
+	WorthListing int               `json:"worth_listing"`
+	Products     []ScreenedProduct `json:"products"`
+}
+
+// ScreeningService evaluates supplier products against the marketplace.
+type ScreeningService struct {
+	meliClient *meli.MeliClient
+}
+
+func NewScreeningService(meliClient *meli.MeliClient) *ScreeningService {
+	return &ScreeningService{
+		meliClient: meliClient,
+	}
+}
+
+// ScreenSupplierCatalog predicts the category of every row and looks up its
+// market price (median of matching listings), competition and demand. The
+// score weighs the gross margin against cost by demand; products with demand
+// and at least a 30% margin are flagged as worth listing. Rows that fail are
+// reported with their error at the end.
+func (s *ScreeningService) ScreenSupplierCatalog(ctx context.Context, rows []SupplierRow) (*ScreeningReport, error) {
+	products := make([]ScreenedProduct, len(rows))
+	sem := make(chan struct{}, screeningConcurrency)
+	var wg sync.WaitGroup
+
+	for i, row := range rows {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, row SupplierRow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			products[i] = s.screen(ctx, row)
+		}(i, row)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &ScreeningReport{Total: len(products), Products: products}
+	for _, p := range products {
+		if p.WorthListing {
+			report.WorthListing++
+		}
+	}
+	sort.SliceStable(report.Products, func(i, j int) bool {
+		a, b := report.Products[i], report.Products[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		return a.Score > b.Score
+	})
+	return report, nil
+}
+
+func (s *ScreeningService) screen(ctx context.Context, row SupplierRow) ScreenedProduct {
+	p := ScreenedProduct{SupplierRow: row}
+
+	preds, err := s.meliClient.PredictCategory(ctx, row.Title)
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	if len(preds) > 0 {
+		p.CategoryID, p.CategoryName, p.CategoryProb = preds[0].ID, preds[0].Name, preds[0].Prob
+	}
+
+	var page *meli.CategorySearchPage
+	if row.EAN != "" {
+		page, err = s.meliClient.SearchListings(ctx, row.EAN, screeningSearchLimit)
+		p.MatchedByEAN = err == nil && len(page.Results) > 0
+	}
+	if !p.MatchedByEAN {
+		page, err = s.meliClient.SearchListings(ctx, row.Title, screeningSearchLimit)
+	}
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+
+	p.Competitors = page.Paging.Total
+	p.SampledListings = len(page.Results)
+	prices := make([]float64, 0, len(page.Results))
+	for _, r := range page.Results {
+		prices = append(prices, r.Price)
+		p.UnitsSold += r.SoldQuantity
+	}
+	p.MarketPrice = roundCents(median(prices))
+	if p.MarketPrice > 0 {
+		p.GrossMarginPct = roundCents((p.MarketPrice - row.Cost) / p.MarketPrice * 100)
+	}
+	p.Score = roundCents(math.Max(p.GrossMarginPct, 0) / 100 * math.Log1p(float64(p.UnitsSold)))
+	p.WorthListing = p.UnitsSold > 0 && p.GrossMarginPct >= worthListingMargin
+	return p
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// WriteCSV writes the report as a spreadsheet-friendly CSV.
+func (r *ScreeningReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"line", "title", "ean", "cost", "category_id", "category_name", "market_price",
+		"gross_margin_pct", "units_sold", "competitors", "score", "worth_listing", "error"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	for _, p := range r.Products {
+		rec := []string{strconv.Itoa(p.Line), p.Title, p.EAN, f(p.Cost), p.CategoryID, p.CategoryName, f(p.MarketPrice),
+			f(p.GrossMarginPct), strconv.Itoa(p.UnitsSold), strconv.Itoa(p.Competitors), f(p.Score), strconv.FormatBool(p.WorthListing), p.Error}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// SearchResult is a single hit from the local search, tagged with its source.
+type SearchResult struct {
+	Type       string  `json:"type"`
+	ID         string  `json:"id"`
+	Title      string  `json:"title"`
+	CategoryID string  `json:"category_id,omitempty"`
+	Price      float64 `json:"price,omitempty"`
+	Thumbnail  string  `json:"thumbnail,omitempty"`
+	Permalink  string  `json:"permalink,omitempty"`
+}
+
+// Search result types.
+const (
+	SearchTypeTrend    = "trend"
+	SearchTypeItem     = "item"
+	SearchTypeOrder    = "order"
+	SearchTypeQuestion = "question"
+)
+
+// SearchService searches data already persisted in the database; Mercado
+// Livre is only asked who the authenticated seller is.
+type SearchService struct {
+	meliClient   *meli.MeliClient
+	trendRepo    *repository.TrendRepository
+	orderRepo    *repository.OrderRepository
+	questionRepo *repository.QuestionRepository
+}
+
+func NewSearchService(meliClient *meli.MeliClient, trendRepo *repository.TrendRepository, orderRepo *repository.OrderRepository, questionRepo *repository.QuestionRepository) *SearchService {
+	return &SearchService{
+		meliClient:   meliClient,
+		trendRepo:    trendRepo,
+		orderRepo:    orderRepo,
+		questionRepo: questionRepo,
+	}
+}
+
+// SearchLocal returns up to limit results matching query across stored
+// trends and the authenticated seller's listings (as seen in their orders),
+// orders and matched questions. Results alternate between the sources, so a source
+// with many hits does not hide the others.
+func (s *SearchService) SearchLocal(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return []SearchResult{}, nil
+	}
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trends, err := s.trendRepo.SearchProductTrends(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	trendResults := make([]SearchResult, 0, len(trends))
+	for _, t := range trends {
+		trendResults = append(trendResults, SearchResult{
+			Type:       SearchTypeTrend,
+			ID:         t.ProductID,
+			Title:      t.Title,
+			CategoryID: t.CategoryID,
+			Price:      t.Price,
+			Thumbnail:  t.Thumbnail,
+			Permalink:  t.Permalink,
+		})
+	}
+
+	items, err := s.orderRepo.SearchItems(ctx, me.ID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	itemResults := make([]SearchResult, 0, len(items))
+	for _, it := range items {
+		itemResults = append(itemResults, SearchResult{
+			Type:  SearchTypeItem,
+			ID:    it.ItemID,
+			Title: it.Title,
+			Price: it.UnitPrice,
+		})
+	}
+
+	orders, err := s.orderRepo.Search(ctx, me.ID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	orderResults := make([]SearchResult, 0, len(orders))
+	for _, o := range orders {
+		orderResults = append(orderResults, SearchResult{
+			Type:  SearchTypeOrder,
+			ID:    strconv.FormatInt(o.ID, 10),
+			Title: orderTitle(o),
+			Price: o.TotalAmount,
+		})
+	}
+
+	questions, err := s.questionRepo.SearchMatches(ctx, me.ID, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	questionResults := make([]SearchResult, 0, len(questions))
+	for _, q := range questions {
+		questionResults = append(questionResults, SearchResult{
+			Type:  SearchTypeQuestion,
+			ID:    strconv.FormatInt(q.QuestionID, 10),
+			Title: q.Text,
+		})
+	}
+
+	return interleave(limit, trendResults, itemResults, orderResults, questionResults), nil
+}
+
+// orderTitle describes an order by its lines, e.g. "Fone Bluetooth +1".
+func orderTitle(o repository.Order) string {
+	switch len(o.Items) {
+	case 0:
+		return o.BuyerNickname
+	case 1:
+		return o.Items[0].Title
+	}
+	return fmt.Sprintf("%s +%d", o.Items[0].Title, len(o.Items)-1)
+}
+
+// interleave takes one result of each source in turn, up to limit.
+func interleave(limit int, sources ...[]SearchResult) []SearchResult {
+	out := make([]SearchResult, 0, limit)
+	for i := 0; len(out) < limit; i++ {
+		taken := false
+		for _, src := range sources {
+			if i < len(src) && len(out) < limit {
+				out = append(out, src[i])
+				taken = true
+			}
+		}
+		if !taken {
+			break
+		}
+	}
+	return out
+}
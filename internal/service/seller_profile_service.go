@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// topSellerItems is how many of a seller's best-selling listings a profile
+// lists.
+const topSellerItems = 20
+
+// TrendingListing is a seller's listing found in the stored highlights,
+// directly or through its catalog product.
+type TrendingListing struct {
+	ItemID              string    `json:"item_id"`
+	Title               string    `json:"title"`
+	Price               float64   `json:"price"`
+	SoldQuantity        int       `json:"sold_quantity"`
+	ProductID           string    `json:"product_id"` // the highlighted ID: the item or its catalog product
+	HighlightCategoryID string    `json:"highlight_category_id"`
+	Rank                int       `json:"rank,omitempty"`
+	SeenAt              time.Time `json:"seen_at"`
+}
+
+// SellerProfile sums up another seller: reputation, catalog size, best
+// sellers, and which of their listings rank in the highlights we track.
+type SellerProfile struct {
+	Seller        *meli.User                  `json:"seller"`
+	ItemCount     int                         `json:"item_count"`
+	SoldTotal     int                         `json:"sold_total"` // over the listings the search returned
+	TopItems      []meli.CategorySearchResult `json:"top_items"`
+	TrendingItems []TrendingListing           `json:"trending_items"`
+}
+
+// SellerProfileService looks up other sellers, e.g. one dominating a
+// category's highlights.
+type SellerProfileService struct {
+	meliClient *meli.MeliClient
+	trendRepo  *repository.TrendRepository
+}
+
+func NewSellerProfileService(meliClient *meli.MeliClient, trendRepo *repository.TrendRepository) *SellerProfileService {
+	return &SellerProfileService{
+		meliClient: meliClient,
+		trendRepo:  trendRepo,
+	}
+}
+
+// Profile fetches a seller's reputation and listings and matches them, and
+// their catalog products, against the stored trend snapshots.
+func (s *SellerProfileService) Profile(ctx context.Context, sellerID int64) (*SellerProfile, error) {
+	user, err := s.meliClient.GetUser(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	listings, err := s.meliClient.GetSellerItems(ctx, sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := listings.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].SoldQuantity > items[j].SoldQuantity
+	})
+	profile := &SellerProfile{
+		Seller:        user,
+		ItemCount:     listings.Total,
+		TopItems:      items[:min(topSellerItems, len(items))],
+		TrendingItems: []TrendingListing{},
+	}
+
+	// Highlights list either the listing or its catalog product
+	byProduct := make(map[string][]meli.CategorySearchResult)
+	for _, it := range items {
+		profile.SoldTotal += it.SoldQuantity
+		byProduct[it.ID] = append(byProduct[it.ID], it)
+		if it.CatalogProductID != "" {
+			byProduct[it.CatalogProductID] = append(byProduct[it.CatalogProductID], it)
+		}
+	}
+	ids := make([]string, 0, len(byProduct))
+	for id := range byProduct {
+		ids = append(ids, id)
+	}
+	trends, err := s.trendRepo.LatestTrendsOf(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range trends {
+		for _, it := range byProduct[t.ProductID] {
+			profile.TrendingItems = append(profile.TrendingItems, TrendingListing{
+				ItemID:              it.ID,
+				Title:               it.Title,
+				Price:               it.Price,
+				SoldQuantity:        it.SoldQuantity,
+				ProductID:           t.ProductID,
+				HighlightCategoryID: t.HighlightCategoryID,
+				Rank:                t.Rank,
+				SeenAt:              t.CreatedAt,
+			})
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"sync"
+
+	"melibot/pkg/meli"
+)
+
+// eligibilityConcurrency bounds parallel calls to the eligibility endpoint.
+const eligibilityConcurrency = 5
+
+// SellerService encapsulates operations on the authenticated seller's own listings.
+type SellerService struct {
+	meliClient *meli.MeliClient
+}
+
+func NewSellerService(meliClient *meli.MeliClient) *SellerService {
+	return &SellerService{
+		meliClient: meliClient,
+	}
+}
+
+// ItemEligibility is the catalog eligibility verdict for a single listing.
+type ItemEligibility struct {
+	ItemID         string `json:"item_id"`
+	Status         string `json:"status"`
+	BuyBoxEligible bool   `json:"buy_box_eligible"`
+	Eligible       bool   `json:"eligible"`
+	Reason         string `json:"reason,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// EligibilityReport summarizes catalog eligibility across all listings.
+type EligibilityReport struct {
+	Total      int               `json:"total"`
+	Eligible   int               `json:"eligible"`
+	Ineligible int               `json:"ineligible"`
+	ByStatus   map[string]int    `json:"by_status"`
+	Items      []ItemEligibility `json:"items"`
+}
+
+// eligibilityReasons translates ML eligibility statuses into actionable text.
+var eligibilityReasons = map[string]string{
+	"NOT_ELIGIBLE":     "item does not match any catalog product; review title, GTIN and attributes",
+	"PRODUCT_INACTIVE": "the matching catalog product is inactive",
+	"CLOSED":           "listing is closed",
+	"PAUSED":           "listing is paused",
+	"UNDER_REVIEW":     "listing is under review by Mercado Livre",
+}
+
+// CatalogEligibilityReport checks every listing of the authenticated seller
+// against the catalog eligibility endpoint. Ineligible items come first so
+// the caller can prioritize fixes.
+func (s *SellerService) CatalogEligibilityReport(ctx context.Context) (*EligibilityReport, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := s.meliClient.UserItemIDs(ctx, me.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ItemEligibility, len(ids))
+	sem := make(chan struct{}, eligibilityConcurrency)
+	var wg sync.WaitGroup
+
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = s.itemEligibility(ctx, id)
+		}(i, id)
+	}
+	wg.Wait()
+
+	report := &EligibilityReport{
+		Total:    len(results),
+		ByStatus: make(map[string]int),
+		Items:    results,
+	}
+	for _, r := range results {
+		report.ByStatus[r.Status]++
+		if r.Eligible {
+			report.Eligible++
+		} else {
+			report.Ineligible++
+		}
+	}
+
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		return !report.Items[i].Eligible && report.Items[j].Eligible
+	})
+
+	return report, nil
+}
+
+func (s *SellerService) itemEligibility(ctx context.Context, itemID string) ItemEligibility {
+	elig, err := s.meliClient.CatalogEligibility(ctx, itemID)
+	if errors.Is(err, meli.ErrNotFound) {
+		// Closed or deleted listings have no eligibility resource.
+		return ItemEligibility{ItemID: itemID, Status: "NOT_FOUND", Error: err.Error()}
+	}
+	if err != nil {
+		return ItemEligibility{ItemID: itemID, Status: "ERROR", Error: err.Error()}
+	}
+
+	out := ItemEligibility{
+		ItemID:         itemID,
+		Status:         elig.Status,
+		BuyBoxEligible: elig.BuyBoxEligible,
+		Eligible:       elig.Status == "READY_FOR_OPTIN" || elig.Status == "ALREADY_OPTED_IN",
+		Reason:         elig.Reason,
+	}
+	if out.Reason == "" && !out.Eligible {
+		out.Reason = eligibilityReasons[elig.Status]
+	}
+	// Some listings are only eligible through one of their variations.
+	if !out.Eligible {
+		for _, v := range elig.Variations {
+			if v.Status == "READY_FOR_OPTIN" {
+				out.Reason = "only some variations are eligible; opt them in individually"
+				break
+			}
+		}
+	}
+	return out
+}
+
+// similarTitleThreshold is the token overlap (Jaccard) above which two
+// listings of the same category are considered duplicates.
+const similarTitleThreshold = 0.7
+
+// OverlapGroup is a set of my listings competing with each other.
+type OverlapGroup struct {
+	Reason           string   `json:"reason"`
+	CatalogProductID string   `json:"catalog_product_id,omitempty"`
+	CategoryID       string   `json:"category_id"`
+	Similarity       float64  `json:"similarity,omitempty"`
+	ItemIDs          []string `json:"item_ids"`
+	Titles           []string `json:"titles"`
+}
+
+// OverlapReport lists my listings that cannibalize each other.
+type OverlapReport struct {
+	CheckedItems int            `json:"checked_items"`
+	Groups       []OverlapGroup `json:"groups"`
+}
+
+// OverlapReport finds the authenticated seller's active or paused listings
+// that compete with each other: several listings in the same catalog product,
+// or listings in the same category with near-identical titles.
+func (s *SellerService) OverlapReport(ctx context.Context) (*OverlapReport, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.meliClient.UserItemIDs(ctx, me.ID)
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]meli.Item, 0, len(all))
+	for _, it := range all {
+		if it.Status == "active" || it.Status == "paused" {
+			items = append(items, it)
+		}
+	}
+	return findOverlaps(items), nil
+}
+
+func findOverlaps(items []meli.Item) *OverlapReport {
+	report := &OverlapReport{CheckedItems: len(items), Groups: []OverlapGroup{}}
+
+	// Same catalog product.
+	byCatalog := map[string][]meli.Item{}
+	var catalogIDs []string
+	for _, it := range items {
+		if it.CatalogID == "" {
+			continue
+		}
+		if _, ok := byCatalog[it.CatalogID]; !ok {
+			catalogIDs = append(catalogIDs, it.CatalogID)
+		}
+		byCatalog[it.CatalogID] = append(byCatalog[it.CatalogID], it)
+	}
+	sameCatalog := map[[2]string]bool{}
+	for _, id := range catalogIDs {
+		group := byCatalog[id]
+		if len(group) < 2 {
+			continue
+		}
+		g := OverlapGroup{Reason: "same_catalog_product", CatalogProductID: id, CategoryID: group[0].CategoryID}
+		for i, it := range group {
+			g.ItemIDs = append(g.ItemIDs, it.ID)
+			g.Titles = append(g.Titles, it.Title)
+			for _, other := range group[i+1:] {
+				sameCatalog[[2]string{it.ID, other.ID}] = true
+			}
+		}
+		report.Groups = append(report.Groups, g)
+	}
+
+	// Similar titles in the same category, not already reported above.
+	tokens := make([]map[string]bool, len(items))
+	for i, it := range items {
+		tokens[i] = map[string]bool{}
+		for _, t := range titleTokens(it.Title) {
+			tokens[i][t] = true
+		}
+	}
+	for i := range items {
+		for j := i + 1; j < len(items); j++ {
+			a, b := items[i], items[j]
+			if a.CategoryID != b.CategoryID || sameCatalog[[2]string{a.ID, b.ID}] {
+				continue
+			}
+			sim := jaccard(tokens[i], tokens[j])
+			if sim < similarTitleThreshold {
+				continue
+			}
+			report.Groups = append(report.Groups, OverlapGroup{
+				Reason:     "similar_title",
+				CategoryID: a.CategoryID,
+				Similarity: math.Round(sim*100) / 100,
+				ItemIDs:    []string{a.ID, b.ID},
+				Titles:     []string{a.Title, b.Title},
+			})
+		}
+	}
+	return report
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for t := range a {
+		if b[t] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+const (
+	// DefaultAuditConfidence is the predictor probability above which a
+	// listing in another category is reported as miscategorized.
+	DefaultAuditConfidence = 0.8
+	// categoryAuditConcurrency bounds parallel calls to the predictor.
+	categoryAuditConcurrency = 4
+)
+
+// CategoryMismatch is a listing the category predictor places elsewhere.
+// CurrentProbability is the predictor's probability for the listing's own
+// category, zero when it is not among the predictions.
+type CategoryMismatch struct {
+	ItemID                string  `json:"item_id"`
+	Title                 string  `json:"title"`
+	CategoryID            string  `json:"category_id"`
+	CategoryName          string  `json:"category_name,omitempty"`
+	PredictedCategoryID   string  `json:"predicted_category_id"`
+	PredictedCategoryName string  `json:"predicted_category_name"`
+	Confidence            float64 `json:"confidence"`
+	CurrentProbability    float64 `json:"current_probability"`
+}
+
+// CategoryAuditReport lists the listings whose category disagrees with the
+// predictor. Failed lists the listings whose title could not be predicted.
+type CategoryAuditReport struct {
+	CheckedItems  int                `json:"checked_items"`
+	MinConfidence float64            `json:"min_confidence"`
+	Mismatches    []CategoryMismatch `json:"mismatches"`
+	Failed        []string           `json:"failed"`
+}
+
+// CategoryAudit runs the category predictor on the titles of the seller's
+// active or paused listings and reports those whose top prediction is
+// another category with at least minConfidence, most confident first.
+// Miscategorized listings rank poorly in search.
+func (s *SellerService) CategoryAudit(ctx context.Context, minConfidence float64) (*CategoryAuditReport, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.meliClient.UserItemIDs(ctx, me.ID)
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]meli.Item, 0, len(all))
+	for _, it := range all {
+		if it.Status == meli.ItemStatusActive || it.Status == meli.ItemStatusPaused {
+			items = append(items, it)
+		}
+	}
+
+	mismatches := make([]*CategoryMismatch, len(items))
+	failed := make([]bool, len(items))
+	sem := make(chan struct{}, categoryAuditConcurrency)
+	var wg sync.WaitGroup
+	for i := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			m, err := s.categoryMismatch(ctx, &items[i], minConfidence)
+			if err != nil {
+				log.Printf("[WARN] Category prediction failed for %s: %v", items[i].ID, err)
+				failed[i] = true
+				return
+			}
+			mismatches[i] = m
+		}(i)
+	}
+	wg.Wait()
+
+	report := &CategoryAuditReport{
+		CheckedItems:  len(items),
+		MinConfidence: minConfidence,
+		Mismatches:    []CategoryMismatch{},
+		Failed:        []string{},
+	}
+	for i, m := range mismatches {
+		switch {
+		case failed[i]:
+			report.Failed = append(report.Failed, items[i].ID)
+		case m != nil:
+			report.Mismatches = append(report.Mismatches, *m)
+		}
+	}
+	s.fillCategoryNames(ctx, report.Mismatches)
+	sort.SliceStable(report.Mismatches, func(i, j int) bool {
+		return report.Mismatches[i].Confidence > report.Mismatches[j].Confidence
+	})
+	return report, nil
+}
+
+// categoryMismatch predicts the category of a listing's title, returning
+// nil when the listing is where the predictor would put it or the
+// prediction is not confident enough.
+func (s *SellerService) categoryMismatch(ctx context.Context, it *meli.Item, minConfidence float64) (*CategoryMismatch, error) {
+	preds, err := s.meliClient.PredictCategory(ctx, it.Title)
+	if err != nil {
+		return nil, err
+	}
+	if len(preds) == 0 || preds[0].ID == it.CategoryID || preds[0].Prob < minConfidence {
+		return nil, nil
+	}
+	m := &CategoryMismatch{
+		ItemID:                it.ID,
+		Title:                 it.Title,
+		CategoryID:            it.CategoryID,
+		PredictedCategoryID:   preds[0].ID,
+		PredictedCategoryName: preds[0].Name,
+		Confidence:            math.Round(preds[0].Prob*100) / 100,
+	}
+	for _, p := range preds[1:] {
+		if p.ID == it.CategoryID {
+			m.CurrentProbability = math.Round(p.Prob*100) / 100
+			break
+		}
+	}
+	return m, nil
+}
+
+// fillCategoryNames names the current categories of the mismatches. It is
+// best-effort: categories that cannot be read stay unnamed.
+func (s *SellerService) fillCategoryNames(ctx context.Context, mismatches []CategoryMismatch) {
+	names := map[string]string{}
+	for i := range mismatches {
+		id := mismatches[i].CategoryID
+		if _, ok := names[id]; !ok {
+			names[id] = ""
+			cat, err := s.meliClient.GetCategory(ctx, id)
+			if err != nil {
+				log.Printf("[WARN] Category lookup failed for %s: %v", id, err)
+			} else {
+				names[id] = cat.Name
+			}
+		}
+		mismatches[i].CategoryName = names[id]
+	}
+}
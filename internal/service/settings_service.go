@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// SettingChange is a setting changed by a reload; an empty value is unset.
+type SettingChange struct {
+	Key string `json:"key"`
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// ReloadResult reports what a reload changed.
+type ReloadResult struct {
+	At      time.Time       `json:"at"`
+	Changed []SettingChange `json:"changed"`
+	// Ignored lists the keys changed in the file that a reload does not
+	// apply: secrets and what the server is wired with, which need a
+	// restart, and settings set in the process environment
+	Ignored []string `json:"ignored,omitempty"`
+}
+
+// Settings reports the reloadable settings and their current values, empty
+// for the default.
+type Settings struct {
+	File   string            `json:"file"`
+	Values map[string]string `json:"values"`
+	// Pinned settings come from the process environment, which takes
+	// precedence over the file, so reloads leave them alone
+	Pinned     []string      `json:"pinned,omitempty"`
+	LastReload *ReloadResult `json:"last_reload,omitempty"`
+}
+
+type settingWatch struct {
+	keys  []string
+	apply func()
+}
+
+// SettingsService reloads the tuning settings (cache TTLs, rate limits, log
+// level, task intervals, alert thresholds) from the .env file without a
+// restart. Only the keys registered with Watch are reloadable; each change
+// is written to the environment and the watchers of the changed keys are
+// called to apply it. A reload only affects the instance it runs on.
+type SettingsService struct {
+	file string
+
+	mu      sync.Mutex
+	watches []settingWatch
+	keys    map[string]bool
+	pinned  map[string]bool
+	// loaded is the content of the file at the last load
+	loaded map[string]string
+	last   *ReloadResult
+}
+
+// NewSettingsService returns a service reloading file, which main loaded at
+// start.
+func NewSettingsService(file string) *SettingsService {
+	loaded, err := readEnvFile(file)
+	if err != nil {
+		log.Printf("[WARN] settings: failed to read %s: %v", file, err)
+	}
+	return &SettingsService{file: file, keys: map[string]bool{}, pinned: map[string]bool{}, loaded: loaded}
+}
+
+// Watch makes keys reloadable, calling apply (if not nil) after a reload
+// changed any of them. Settings read on every use need no apply.
+func (s *SettingsService) Watch(apply func(), keys ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		s.keys[key] = true
+		// A value the file did not set came from the process environment
+		if v, ok := os.LookupEnv(key); ok {
+			if fileValue, inFile := s.loaded[key]; !inFile || fileValue != v {
+				s.pinned[key] = true
+			}
+		}
+	}
+	s.watches = append(s.watches, settingWatch{keys: keys, apply: apply})
+}
+
+// Reload reads the file again and applies the changed settings. Settings
+// removed from the file go back to their default.
+func (s *SettingsService) Reload() (*ReloadResult, error) {
+	file, err := readEnvFile(s.file)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := &ReloadResult{At: time.Now(), Changed: []SettingChange{}}
+	changed := map[string]bool{}
+	for key := range s.keys {
+		if s.pinned[key] {
+			continue
+		}
+		old := os.Getenv(key)
+		value, ok := file[key]
+		if old == value {
+			continue
+		}
+		if ok {
+			os.Setenv(key, value)
+		} else {
+			os.Unsetenv(key)
+		}
+		changed[key] = true
+		result.Changed = append(result.Changed, SettingChange{Key: key, Old: old, New: value})
+		log.Printf("[INFO] settings: %s changed from %q to %q", key, old, value)
+	}
+	for key, value := range file {
+		if old, ok := s.loaded[key]; (!ok || old != value) && (!s.keys[key] || s.pinned[key]) {
+			result.Ignored = append(result.Ignored, key)
+		}
+	}
+	for key := range s.loaded {
+		if _, ok := file[key]; !ok && (!s.keys[key] || s.pinned[key]) {
+			result.Ignored = append(result.Ignored, key)
+		}
+	}
+	sort.Slice(result.Changed, func(i, j int) bool { return result.Changed[i].Key < result.Changed[j].Key })
+	sort.Strings(result.Ignored)
+	if len(result.Ignored) > 0 {
+		log.Printf("[WARN] settings: changes to %v are not applied by a reload", result.Ignored)
+	}
+
+	for _, w := range s.watches {
+		if w.apply == nil {
+			continue
+		}
+		for _, key := range w.keys {
+			if changed[key] {
+				w.apply()
+				break
+			}
+		}
+	}
+	s.loaded = file
+	s.last = result
+	return result, nil
+}
+
+// Settings returns the reloadable settings and the last reload.
+func (s *SettingsService) Settings() Settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := Settings{File: s.file, Values: make(map[string]string, len(s.keys)), LastReload: s.last}
+	for key := range s.keys {
+		st.Values[key] = os.Getenv(key)
+		if s.pinned[key] {
+			st.Pinned = append(st.Pinned, key)
+		}
+	}
+	sort.Strings(st.Pinned)
+	return st
+}
+
+// readEnvFile reads a .env file, empty when it does not exist.
+func readEnvFile(file string) (map[string]string, error) {
+	values, err := godotenv.Read(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	return values, err
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+const (
+	// shelfDepth is how many top search results of a category are sampled.
+	shelfDepth = 50
+	// shelfMaxCategories bounds the categories sampled per snapshot, those
+	// with the most listings of mine first.
+	shelfMaxCategories = 20
+	// shelfTopSellers is how many other sellers the report breaks down.
+	shelfTopSellers = 10
+)
+
+var ErrInvalidShelfGrouping = errors.New("group_by must be day, week or month")
+
+// SellerShelf is a seller's share of the sampled results. Nickname is only
+// known for watched competitors.
+type SellerShelf struct {
+	SellerID int64   `json:"seller_id"`
+	Nickname string  `json:"nickname,omitempty"`
+	Mine     bool    `json:"mine"`
+	Listings int     `json:"listings"`
+	SharePct float64 `json:"share_pct"`
+}
+
+// ShelfPeriod is the share of each seller over the snapshots of a period.
+type ShelfPeriod struct {
+	Period     string        `json:"period"`
+	Snapshots  int           `json:"snapshots"`
+	MySharePct float64       `json:"my_share_pct"`
+	Sellers    []SellerShelf `json:"sellers"`
+}
+
+// CategoryShelf is the share of shelf in one category: overall across the
+// range, at the latest snapshot and per period. Sellers are the top
+// sellers over the range, mine always included.
+type CategoryShelf struct {
+	CategoryID       string        `json:"category_id"`
+	Snapshots        int           `json:"snapshots"`
+	MySharePct       float64       `json:"my_share_pct"`
+	LatestMySharePct float64       `json:"latest_my_share_pct"`
+	Sellers          []SellerShelf `json:"sellers"`
+	Periods          []ShelfPeriod `json:"periods"`
+}
+
+// ShareOfShelfReport is what share of the top search results of my
+// categories belongs to my listings and to each other seller over time.
+type ShareOfShelfReport struct {
+	GroupBy    string          `json:"group_by"`
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	Categories []CategoryShelf `json:"categories"`
+}
+
+// ShareOfShelfService samples the top search results of the categories
+// the seller lists in and reports who owns them.
+type ShareOfShelfService struct {
+	meliClient     *meli.MeliClient
+	repo           *repository.ShelfRepository
+	competitorRepo *repository.CompetitorRepository
+}
+
+func NewShareOfShelfService(meliClient *meli.MeliClient, repo *repository.ShelfRepository, competitorRepo *repository.CompetitorRepository) *ShareOfShelfService {
+	return &ShareOfShelfService{
+		meliClient:     meliClient,
+		repo:           repo,
+		competitorRepo: competitorRepo,
+	}
+}
+
+// Snapshot samples the top results of each category the seller has active
+// listings in. Categories that fail are logged and skipped. It returns how
+// many categories were sampled.
+func (s *ShareOfShelfService) Snapshot(ctx context.Context) (int, error) {
+	categories, err := s.myCategories(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	failed := 0
+	for _, categoryID := range categories {
+		page, err := s.meliClient.SearchCategoryPage(ctx, categoryID, 0, shelfDepth)
+		if err != nil {
+			log.Printf("[WARN] Share of shelf sample failed for %s: %v", categoryID, err)
+			failed++
+			continue
+		}
+		snapshot := &repository.ShelfSnapshot{CategoryID: categoryID, Results: len(page.Results), TakenAt: now}
+		bySeller := map[int64]int{}
+		var sellers []int64
+		for _, r := range page.Results {
+			if bySeller[r.Seller.ID] == 0 {
+				sellers = append(sellers, r.Seller.ID)
+			}
+			bySeller[r.Seller.ID]++
+		}
+		for _, id := range sellers {
+			snapshot.Shares = append(snapshot.Shares, repository.ShelfShare{SellerID: id, Listings: bySeller[id]})
+		}
+		if err := s.repo.Save(ctx, snapshot); err != nil {
+			return len(categories) - failed, err
+		}
+	}
+	if failed > 0 {
+		return len(categories) - failed, fmt.Errorf("share of shelf: %d of %d categories failed", failed, len(categories))
+	}
+	return len(categories), nil
+}
+
+// SnapshotAll is Snapshot for the scheduler.
+func (s *ShareOfShelfService) SnapshotAll(ctx context.Context) error {
+	_, err := s.Snapshot(ctx)
+	return err
+}
+
+// myCategories returns the categories of the seller's active listings,
+// those with the most listings first, up to shelfMaxCategories.
+func (s *ShareOfShelfService) myCategories(ctx context.Context) ([]string, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.meliClient.UserItemIDs(ctx, me.ID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	var categories []string
+	for _, it := range items {
+		if it.Status != meli.ItemStatusActive || it.CategoryID == "" {
+			continue
+		}
+		if counts[it.CategoryID] == 0 {
+			categories = append(categories, it.CategoryID)
+		}
+		counts[it.CategoryID]++
+	}
+	sort.SliceStable(categories, func(i, j int) bool { return counts[categories[i]] > counts[categories[j]] })
+	return categories[:min(shelfMaxCategories, len(categories))], nil
+}
+
+// shelfCounts sums the sampled results and each seller's listings over a
+// set of snapshots.
+type shelfCounts struct {
+	snapshots, results int
+	bySeller           map[int64]int
+}
+
+func (c *shelfCounts) add(snapshot *repository.ShelfSnapshot) {
+	if c.bySeller == nil {
+		c.bySeller = map[int64]int{}
+	}
+	c.snapshots++
+	c.results += snapshot.Results
+	for _, share := range snapshot.Shares {
+		c.bySeller[share.SellerID] += share.Listings
+	}
+}
+
+func (c *shelfCounts) share(sellerID int64) float64 {
+	if c.results == 0 {
+		return 0
+	}
+	return roundPct(float64(c.bySeller[sellerID]) / float64(c.results))
+}
+
+// Report computes the share of shelf of the snapshots taken in [from, to),
+// per category and period (day, week or month), of one category when
+// categoryID is set.
+func (s *ShareOfShelfService) Report(ctx context.Context, groupBy, categoryID string, from, to time.Time) (*ShareOfShelfReport, error) {
+	periodStart, ok := periodTruncators[groupBy]
+	if !ok {
+		return nil, ErrInvalidShelfGrouping
+	}
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.repo.Snapshots(ctx, categoryID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	competitors, err := s.competitorRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nicknames := make(map[int64]string, len(competitors))
+	for _, c := range competitors {
+		nicknames[c.SellerID] = c.Nickname
+	}
+
+	type categoryCounts struct {
+		total   shelfCounts
+		latest  *repository.ShelfSnapshot
+		periods map[string]*shelfCounts
+		keys    []string
+	}
+	var order []string
+	byCategory := map[string]*categoryCounts{}
+	for i := range snapshots {
+		snap := &snapshots[i]
+		cc := byCategory[snap.CategoryID]
+		if cc == nil {
+			cc = &categoryCounts{periods: map[string]*shelfCounts{}}
+			byCategory[snap.CategoryID] = cc
+			order = append(order, snap.CategoryID)
+		}
+		cc.total.add(snap)
+		cc.latest = snap
+		key := periodStart(snap.TakenAt).Format(time.DateOnly)
+		if cc.periods[key] == nil {
+			cc.periods[key] = &shelfCounts{}
+			cc.keys = append(cc.keys, key)
+		}
+		cc.periods[key].add(snap)
+	}
+
+	report := &ShareOfShelfReport{GroupBy: groupBy, From: from, To: to, Categories: []CategoryShelf{}}
+	for _, id := range order {
+		cc := byCategory[id]
+		sellers := topShelfSellers(&cc.total, me.ID)
+		seller := func(counts *shelfCounts, sellerID int64) SellerShelf {
+			return SellerShelf{
+				SellerID: sellerID,
+				Nickname: nicknames[sellerID],
+				Mine:     sellerID == me.ID,
+				Listings: counts.bySeller[sellerID],
+				SharePct: counts.share(sellerID),
+			}
+		}
+
+		var latest shelfCounts
+		latest.add(cc.latest)
+		category := CategoryShelf{
+			CategoryID:       id,
+			Snapshots:        cc.total.snapshots,
+			MySharePct:       cc.total.share(me.ID),
+			LatestMySharePct: latest.share(me.ID),
+			Sellers:          make([]SellerShelf, 0, len(sellers)),
+			Periods:          make([]ShelfPeriod, 0, len(cc.keys)),
+		}
+		for _, sellerID := range sellers {
+			category.Sellers = append(category.Sellers, seller(&cc.total, sellerID))
+		}
+		for _, key := range cc.keys {
+			counts := cc.periods[key]
+			period := ShelfPeriod{
+				Period:     key,
+				Snapshots:  counts.snapshots,
+				MySharePct: counts.share(me.ID),
+				Sellers:    make([]SellerShelf, 0, len(sellers)),
+			}
+			for _, sellerID := range sellers {
+				period.Sellers = append(period.Sellers, seller(counts, sellerID))
+			}
+			category.Periods = append(category.Periods, period)
+		}
+		report.Categories = append(report.Categories, category)
+	}
+	sort.SliceStable(report.Categories, func(i, j int) bool {
+		return report.Categories[i].MySharePct > report.Categories[j].MySharePct
+	})
+	return report, nil
+}
+
+// topShelfSellers returns the sellers with the most sampled listings, by
+// listings, and the seller themself whether or not they made the cut.
+func topShelfSellers(counts *shelfCounts, me int64) []int64 {
+	sellers := make([]int64, 0, len(counts.bySeller))
+	for id := range counts.bySeller {
+		if id != me {
+			sellers = append(sellers, id)
+		}
+	}
+	sort.Slice(sellers, func(i, j int) bool {
+		a, b := counts.bySeller[sellers[i]], counts.bySeller[sellers[j]]
+		if a != b {
+			return a > b
+		}
+		return sellers[i] < sellers[j]
+	})
+	sellers = sellers[:min(shelfTopSellers, len(sellers))]
+	return append([]int64{me}, sellers...)
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// dispatchLookback is how old an order can be and still await dispatch;
+// handling times are a few business days.
+const dispatchLookback = 15 * 24 * time.Hour
+
+// Dispatch urgencies, from most to least urgent.
+const (
+	UrgencyOverdue  = "overdue"
+	UrgencyCritical = "critical"
+	UrgencyOK       = "ok"
+	UrgencyUnknown  = "unknown"
+)
+
+var urgencyRank = map[string]int{UrgencyOverdue: 0, UrgencyCritical: 1, UrgencyOK: 2, UrgencyUnknown: 3}
+
+// ShipmentService tracks orders the seller still has to dispatch.
+type ShipmentService struct {
+	meliClient *meli.MeliClient
+	orderRepo  *repository.OrderRepository
+	// warnWithin is how close to the handling deadline an order becomes critical.
+	warnWithin time.Duration
+}
+
+func NewShipmentService(meliClient *meli.MeliClient, orderRepo *repository.OrderRepository, warnWithin time.Duration) *ShipmentService {
+	return &ShipmentService{
+		meliClient: meliClient,
+		orderRepo:  orderRepo,
+		warnWithin: warnWithin,
+	}
+}
+
+// PendingShipment is an order awaiting dispatch.
+type PendingShipment struct {
+	OrderID       int64      `json:"order_id"`
+	ShipmentID    int64      `json:"shipment_id"`
+	Status        string     `json:"status"`
+	Substatus     string     `json:"substatus"`
+	LogisticType  string     `json:"logistic_type"`
+	BuyerNickname string     `json:"buyer_nickname"`
+	Items         []string   `json:"items"`
+	DateCreated   time.Time  `json:"date_created"`
+	Deadline      *time.Time `json:"handling_deadline"`
+	HoursLeft     *float64   `json:"hours_left"`
+	Urgency       string     `json:"urgency"`
+
+	alerted bool
+	late    bool
+}
+
+type PendingShipments struct {
+	WarnWithinHours float64           `json:"warn_within_hours"`
+	Overdue         int               `json:"overdue"`
+	Critical        int               `json:"critical"`
+	Shipments       []PendingShipment `json:"shipments"`
+}
+
+// PendingShipments lists the synced orders whose shipment has not left yet,
+// most urgent first. Orders whose shipment lookup fails are logged and left
+// out.
+func (s *ShipmentService) PendingShipments(ctx context.Context) (*PendingShipments, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.orderRepo.AwaitingShipment(ctx, me.ID, time.Now().Add(-dispatchLookback))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	pending := &PendingShipments{WarnWithinHours: s.warnWithin.Hours(), Shipments: []PendingShipment{}}
+	for i := range orders {
+		o := &orders[i]
+		shipment, err := s.meliClient.GetShipment(ctx, o.ShippingID)
+		if err != nil {
+			log.Printf("[WARN] Shipment lookup failed for order %d: %v", o.ID, err)
+			continue
+		}
+		if !shipment.AwaitingDispatch() {
+			continue
+		}
+		p := pendingShipment(o, shipment, now, s.warnWithin)
+		switch p.Urgency {
+		case UrgencyOverdue:
+			pending.Overdue++
+		case UrgencyCritical:
+			pending.Critical++
+		}
+		pending.Shipments = append(pending.Shipments, p)
+	}
+
+	sort.SliceStable(pending.Shipments, func(i, j int) bool {
+		a, b := pending.Shipments[i], pending.Shipments[j]
+		if urgencyRank[a.Urgency] != urgencyRank[b.Urgency] {
+			return urgencyRank[a.Urgency] < urgencyRank[b.Urgency]
+		}
+		if a.Deadline != nil && b.Deadline != nil {
+			return a.Deadline.Before(*b.Deadline)
+		}
+		return false
+	})
+	return pending, nil
+}
+
+func pendingShipment(o *repository.Order, shipment *meli.Shipment, now time.Time, warnWithin time.Duration) PendingShipment {
+	p := PendingShipment{
+		OrderID:       o.ID,
+		ShipmentID:    shipment.ID,
+		Status:        shipment.Status,
+		Substatus:     shipment.Substatus,
+		LogisticType:  shipment.LogisticType,
+		BuyerNickname: o.BuyerNickname,
+		Items:         make([]string, 0, len(o.Items)),
+		DateCreated:   o.DateCreated,
+		Deadline:      shipment.HandlingDeadline(),
+		Urgency:       UrgencyUnknown,
+		alerted:       o.DispatchAlertedAt != nil,
+		late:          o.DispatchLateAt != nil,
+	}
+	for _, it := range o.Items {
+		p.Items = append(p.Items, it.Title)
+	}
+	if p.Deadline == nil {
+		return p
+	}
+
+	left := p.Deadline.Sub(now)
+	hours := math.Round(left.Hours()*10) / 10
+	p.HoursLeft = &hours
+	switch {
+	case left < 0:
+		p.Urgency = UrgencyOverdue
+	case left <= warnWithin:
+		p.Urgency = UrgencyCritical
+	default:
+		p.Urgency = UrgencyOK
+	}
+	return p
+}
+
+// CheckDispatchDeadlines warns once about each order that is overdue or
+// close to its handling deadline; late dispatches hurt the seller's
+// reputation. Overdue orders the seller ships themselves are recorded as
+// late for the reputation guardrails; FULL orders are Mercado Livre's to
+// dispatch.
+func (s *ShipmentService) CheckDispatchDeadlines(ctx context.Context) error {
+	pending, err := s.PendingShipments(ctx)
+	if err != nil {
+		return err
+	}
+
+	var alerted, late []int64
+	for _, p := range pending.Shipments {
+		if p.Urgency == UrgencyOverdue && !p.late && p.LogisticType != meli.LogisticTypeFulfillment {
+			late = append(late, p.OrderID)
+		}
+		if p.alerted || (p.Urgency != UrgencyOverdue && p.Urgency != UrgencyCritical) {
+			continue
+		}
+		log.Printf("[WARN] Order %d must be dispatched by %s (%.1fh left)", p.OrderID, p.Deadline.Format(time.RFC3339), *p.HoursLeft)
+		alerted = append(alerted, p.OrderID)
+	}
+	now := time.Now()
+	if err := s.orderRepo.MarkDispatchLate(ctx, late, now); err != nil {
+		return err
+	}
+	return s.orderRepo.MarkDispatchAlerted(ctx, alerted, now)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// MaxSKUMappings caps the mappings saved at once.
+const MaxSKUMappings = 500
+
+var (
+	ErrSKUMappingNotFound = errors.New("SKU mapping not found")
+	ErrUnknownVariation   = errors.New("the listing has no such variation")
+)
+
+// SKUMapping maps one of the seller's SKUs to a listing or a variation.
+type SKUMapping struct {
+	ID          uint   `json:"id"`
+	SKU         string `json:"sku"`
+	ItemID      string `json:"item_id"`
+	VariationID int64  `json:"variation_id,omitempty"`
+}
+
+// ValidateSKUMappings checks that every mapping names a SKU and a listing,
+// and maps each listing or variation once.
+func ValidateSKUMappings(mappings []SKUMapping) error {
+	if len(mappings) == 0 {
+		return errors.New("no SKU mappings")
+	}
+	if len(mappings) > MaxSKUMappings {
+		return fmt.Errorf("at most %d SKU mappings are accepted at once", MaxSKUMappings)
+	}
+	seen := map[stockRef]bool{}
+	for i, m := range mappings {
+		if strings.TrimSpace(m.SKU) == "" || m.ItemID == "" {
+			return fmt.Errorf("mapping %d: sku and item_id are required", i+1)
+		}
+		ref := stockRef{itemID: m.ItemID, variationID: m.VariationID}
+		if seen[ref] {
+			return fmt.Errorf("mapping %d: %s is mapped twice", i+1, m.ItemID)
+		}
+		seen[ref] = true
+	}
+	return nil
+}
+
+// SKUMappingService maps the seller's own SKUs, e.g. ERP codes, to their
+// listings and variations. Stock adjustments accept mapped SKUs, and sales
+// analytics and profit group orders by them.
+type SKUMappingService struct {
+	meliClient *meli.MeliClient
+	repo       *repository.SKUMappingRepository
+}
+
+func NewSKUMappingService(meliClient *meli.MeliClient, repo *repository.SKUMappingRepository) *SKUMappingService {
+	return &SKUMappingService{
+		meliClient: meliClient,
+		repo:       repo,
+	}
+}
+
+// Mappings lists the mappings, of one SKU when sku is set.
+func (s *SKUMappingService) Mappings(ctx context.Context, sku string) ([]SKUMapping, error) {
+	rows, err := s.repo.List(ctx, sku)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SKUMapping, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, SKUMapping{ID: row.ID, SKU: row.SKU, ItemID: row.ItemID, VariationID: row.VariationID})
+	}
+	return out, nil
+}
+
+// Save maps listings and variations to SKUs, replacing the SKU they were
+// mapped to. The listings must be the seller's and the variations must
+// exist.
+func (s *SKUMappingService) Save(ctx context.Context, mappings []SKUMapping) ([]SKUMapping, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, m := range mappings {
+		if !slices.Contains(ids, m.ItemID) {
+			ids = append(ids, m.ItemID)
+		}
+	}
+	items, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*meli.Item, len(items))
+	for i := range items {
+		byID[items[i].ID] = &items[i]
+	}
+
+	rows := make([]repository.SKUMapping, 0, len(mappings))
+	for _, m := range mappings {
+		it := byID[m.ItemID]
+		if it == nil || int64(it.SellerID) != me.ID {
+			return nil, fmt.Errorf("%s: %w", m.ItemID, ErrNotOwnItem)
+		}
+		if m.VariationID != 0 && !slices.ContainsFunc(it.Variations, func(v meli.Variation) bool { return v.ID == m.VariationID }) {
+			return nil, fmt.Errorf("%s variation %d: %w", m.ItemID, m.VariationID, ErrUnknownVariation)
+		}
+		rows = append(rows, repository.SKUMapping{SKU: strings.TrimSpace(m.SKU), ItemID: m.ItemID, VariationID: m.VariationID})
+	}
+	if err := s.repo.Upsert(ctx, rows); err != nil {
+		return nil, err
+	}
+
+	out := make([]SKUMapping, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, SKUMapping{ID: row.ID, SKU: row.SKU, ItemID: row.ItemID, VariationID: row.VariationID})
+	}
+	return out, nil
+}
+
+// Delete removes a mapping.
+func (s *SKUMappingService) Delete(ctx context.Context, id uint) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSKUMappingNotFound
+	}
+	return nil
+}
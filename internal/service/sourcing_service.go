@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"melibot/internal/events"
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// defaultSourcingListingType is the listing type fees are estimated for
+// when the candidate names none ("Clássico").
+const defaultSourcingListingType = "gold_special"
+
+// ErrNotSourcing is returned for operations on a product that is not a
+// sourcing candidate.
+var ErrNotSourcing = errors.New("product is not a sourcing candidate")
+
+// SourcingTerms are what a sourcing candidate would cost me and the margin
+// that makes it worth buying.
+type SourcingTerms struct {
+	LandedCost   float64 `json:"landed_cost"`
+	MinMarginPct float64 `json:"min_margin_pct"`
+	ListingType  string  `json:"listing_type"`
+}
+
+// Validate rejects a non-positive landed cost and a margin threshold outside
+// (0, 100).
+func (t SourcingTerms) Validate() error {
+	if t.LandedCost <= 0 {
+		return errors.New("landed_cost must be positive")
+	}
+	if t.MinMarginPct <= 0 || t.MinMarginPct >= 100 {
+		return errors.New("min_margin_pct must be between 0 and 100")
+	}
+	return nil
+}
+
+// SourcingService tracks the catalog best price of products I'm considering
+// sourcing and signals when the margin it implies after fees makes them a
+// buy, or drops below break-even. Signal changes are published as
+// SourcingSignal events.
+type SourcingService struct {
+	meliClient *meli.MeliClient
+	repo       *repository.SourcingRepository
+	bus        *events.Bus
+}
+
+func NewSourcingService(meliClient *meli.MeliClient, repo *repository.SourcingRepository, bus *events.Bus) *SourcingService {
+	return &SourcingService{
+		meliClient: meliClient,
+		repo:       repo,
+		bus:        bus,
+	}
+}
+
+// List returns the sourcing candidates.
+func (s *SourcingService) List(ctx context.Context) ([]repository.SourcingCandidate, error) {
+	return s.repo.List(ctx)
+}
+
+// Track registers a product as a sourcing candidate (or updates its terms)
+// and evaluates it at the current best price. The first evaluation sets
+// the signal without firing it.
+func (s *SourcingService) Track(ctx context.Context, productID string, t SourcingTerms) (*repository.SourcingCandidate, error) {
+	c, err := s.repo.FindByProductID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		c = &repository.SourcingCandidate{ProductID: productID}
+	}
+	applySourcingTerms(c, t)
+	if err := s.evaluate(ctx, c, false); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// UpdateTerms replaces the terms of a sourcing candidate and re-evaluates it.
+func (s *SourcingService) UpdateTerms(ctx context.Context, productID string, t SourcingTerms) (*repository.SourcingCandidate, error) {
+	c, err := s.repo.FindByProductID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, ErrNotSourcing
+	}
+	applySourcingTerms(c, t)
+	if err := s.evaluate(ctx, c, false); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func applySourcingTerms(c *repository.SourcingCandidate, t SourcingTerms) {
+	c.LandedCost, c.MinMarginPct, c.ListingType = t.LandedCost, t.MinMarginPct, t.ListingType
+	if c.ListingType == "" {
+		c.ListingType = defaultSourcingListingType
+	}
+}
+
+// Untrack removes a sourcing candidate.
+func (s *SourcingService) Untrack(ctx context.Context, productID string) error {
+	deleted, err := s.repo.Delete(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotSourcing
+	}
+	return nil
+}
+
+// Signals returns recent signal changes, optionally for one product.
+func (s *SourcingService) Signals(ctx context.Context, productID string, limit int) ([]repository.SourcingSignal, error) {
+	return s.repo.Signals(ctx, productID, limit)
+}
+
+// RefreshPrices re-evaluates every sourcing candidate at its current best
+// price. Candidates that fail are logged and skipped.
+func (s *SourcingService) RefreshPrices(ctx context.Context) error {
+	candidates, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for i := range candidates {
+		if err := s.evaluate(ctx, &candidates[i], true); err != nil {
+			log.Printf("[WARN] Sourcing price refresh failed for %s: %v", candidates[i].ProductID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("sourcing refresh: %d of %d products failed", failed, len(candidates))
+	}
+	return nil
+}
+
+// evaluate prices a candidate at the catalog best price with the fees of
+// its listing type, stores it and, when fire is set, records and publishes
+// a change to a buy or abort signal.
+func (s *SourcingService) evaluate(ctx context.Context, c *repository.SourcingCandidate, fire bool) error {
+	best, err := s.meliClient.GetProductBestPriceWithLink(ctx, c.ProductID)
+	if err != nil {
+		return err
+	}
+	if c.CategoryID == "" && best.ItemID != "" {
+		item, err := s.meliClient.GetItem(ctx, best.ItemID)
+		if err != nil {
+			return err
+		}
+		c.CategoryID = item.CategoryID
+	}
+	prices, err := s.meliClient.ListingPrices(ctx, c.CategoryID, c.ListingType, best.Price)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	previous := c.Signal
+	c.Title, c.Permalink = best.Title, best.Permalink
+	c.LastPrice = best.Price
+	c.LastFees = roundCents(prices.ListingFeeAmount + prices.SaleFeeAmount)
+	c.LastMarginPct = 0
+	if best.Price > 0 {
+		c.LastMarginPct = roundCents((best.Price - c.LastFees - c.LandedCost) / best.Price * 100)
+	}
+	c.Signal = sourcingSignal(best.Price-c.LastFees-c.LandedCost, c.LastMarginPct, c.MinMarginPct)
+	c.LastCheckedAt = &now
+
+	var signal *repository.SourcingSignal
+	if fire && c.Signal != previous && c.Signal != repository.SourcingSignalHold {
+		signal = &repository.SourcingSignal{
+			ProductID:    c.ProductID,
+			Signal:       c.Signal,
+			Price:        c.LastPrice,
+			Fees:         c.LastFees,
+			LandedCost:   c.LandedCost,
+			MarginPct:    c.LastMarginPct,
+			MinMarginPct: c.MinMarginPct,
+		}
+	}
+	if err := s.repo.SaveWithSignal(ctx, c, signal); err != nil {
+		return err
+	}
+	if signal != nil {
+		log.Printf("[INFO] Sourcing %s signal for %s: %.2f leaves %.2f%% margin over a %.2f landed cost", c.Signal, c.ProductID, c.LastPrice, c.LastMarginPct, c.LandedCost)
+		s.bus.Publish(ctx, events.SourcingSignal{
+			ProductID:    c.ProductID,
+			Title:        c.Title,
+			Signal:       c.Signal,
+			Price:        c.LastPrice,
+			LandedCost:   c.LandedCost,
+			MarginPct:    c.LastMarginPct,
+			MinMarginPct: c.MinMarginPct,
+		})
+	}
+	return nil
+}
+
+// sourcingSignal decides the signal of a margin: buy at or above the
+// threshold, abort below break-even, hold in between.
+func sourcingSignal(margin, marginPct, minMarginPct float64) string {
+	switch {
+	case margin < 0:
+		return repository.SourcingSignalAbort
+	case marginPct >= minMarginPct:
+		return repository.SourcingSignalBuy
+	default:
+		return repository.SourcingSignalHold
+	}
+}
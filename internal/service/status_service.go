@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"melibot/internal/cache"
+	"melibot/internal/repository"
+	"melibot/internal/scheduler"
+	"melibot/pkg/meli"
+)
+
+// SystemStatus is the operator overview served by /api/admin/status.
+type SystemStatus struct {
+	Upstream       meli.UpstreamStats     `json:"mercado_livre"`
+	Outbound       meli.LimiterStats      `json:"outbound"`
+	LinkedAccounts int                    `json:"linked_accounts"`
+	Scheduler      []scheduler.TaskStatus `json:"scheduler"`
+	TaskLocks      *TaskLockStats         `json:"task_locks,omitempty"`
+	Leader         *LeaderStats           `json:"leader,omitempty"`
+	Queue          map[string]int64       `json:"queue"`
+	Cache          cache.Stats            `json:"cache"`
+	Database       DatabaseStatus         `json:"database"`
+}
+
+// DatabaseStatus reports database reachability and size.
+type DatabaseStatus struct {
+	OK        bool   `json:"ok"`
+	SizeBytes int64  `json:"size_bytes"`
+	Error     string `json:"error,omitempty"`
+}
+
+// FailedJobs is the background work that failed, served by
+// /api/admin/jobs/failed: queued jobs that failed for good and scheduled
+// tasks whose last run failed.
+type FailedJobs struct {
+	Jobs  []FailedJob            `json:"jobs"`
+	Tasks []scheduler.TaskStatus `json:"tasks"`
+}
+
+// FailedJob is a queued job that failed for good, with the payload that
+// made it fail.
+type FailedJob struct {
+	ID          uint            `json:"id"`
+	Type        string          `json:"type"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Error       string          `json:"error"`
+	ErrorClass  string          `json:"error_class,omitempty"`
+	PanicStack  string          `json:"panic_stack,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+}
+
+// StatusService aggregates health information from every subsystem.
+type StatusService struct {
+	jobRepo    *repository.JobRepository
+	systemRepo *repository.SystemRepository
+	cache      *cache.Cache
+	scheduler  *scheduler.Scheduler
+	taskLocks  *TaskLocker
+	leader     *LeaderElector
+	limiter    *meli.Limiter
+	// linkedAccounts reports how many Mercado Livre accounts hold a token.
+	linkedAccounts func() int
+}
+
+func NewStatusService(jobRepo *repository.JobRepository, systemRepo *repository.SystemRepository, cache *cache.Cache, scheduler *scheduler.Scheduler, taskLocks *TaskLocker, leader *LeaderElector, limiter *meli.Limiter, linkedAccounts func() int) *StatusService {
+	return &StatusService{
+		jobRepo:        jobRepo,
+		systemRepo:     systemRepo,
+		cache:          cache,
+		scheduler:      scheduler,
+		taskLocks:      taskLocks,
+		leader:         leader,
+		limiter:        limiter,
+		linkedAccounts: linkedAccounts,
+	}
+}
+
+// Status collects the current system status. Subsystem failures are reported
+// inside the status rather than failing the whole call.
+func (s *StatusService) Status(ctx context.Context) SystemStatus {
+	st := SystemStatus{
+		Upstream:       meli.GetUpstreamStats(),
+		Outbound:       s.limiter.Stats(),
+		LinkedAccounts: s.linkedAccounts(),
+		Scheduler:      s.scheduler.Status(),
+		Cache:          s.cache.Stats(),
+		Queue:          map[string]int64{},
+	}
+
+	if s.taskLocks != nil {
+		locks := s.taskLocks.Stats()
+		st.TaskLocks = &locks
+	}
+	if s.leader != nil {
+		leader := s.leader.Stats()
+		st.Leader = &leader
+	}
+
+	if counts, err := s.jobRepo.CountByStatus(ctx); err == nil {
+		st.Queue = counts
+	}
+
+	if err := s.systemRepo.Ping(ctx); err != nil {
+		st.Database.Error = err.Error()
+	} else if size, err := s.systemRepo.DatabaseSize(ctx); err != nil {
+		st.Database.Error = err.Error()
+	} else {
+		st.Database.OK = true
+		st.Database.SizeBytes = size
+	}
+
+	return st
+}
+
+// FailedJobs lists the failed jobs, most recent first and optionally of one
+// type, and the failing scheduled tasks.
+func (s *StatusService) FailedJobs(ctx context.Context, jobType string, limit, offset int) (*FailedJobs, error) {
+	jobs, err := s.jobRepo.ListFailed(ctx, jobType, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	out := &FailedJobs{Jobs: make([]FailedJob, 0, len(jobs)), Tasks: s.scheduler.Failed()}
+	if out.Tasks == nil {
+		out.Tasks = []scheduler.TaskStatus{}
+	}
+	for _, job := range jobs {
+		failed := FailedJob{
+			ID:          job.ID,
+			Type:        job.Type,
+			Attempts:    job.Attempts,
+			MaxAttempts: job.MaxAttempts,
+			Error:       job.LastError,
+			ErrorClass:  job.ErrorClass,
+			PanicStack:  job.PanicStack,
+			CreatedAt:   job.CreatedAt,
+			FinishedAt:  job.FinishedAt,
+		}
+		if json.Valid([]byte(job.Payload)) {
+			failed.Payload = json.RawMessage(job.Payload)
+		}
+		out.Jobs = append(out.Jobs, failed)
+	}
+	return out, nil
+}
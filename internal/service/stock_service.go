@@ -0,0 +1,427 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+const (
+	// MaxStockRows caps the rows of a bulk stock adjustment.
+	MaxStockRows = 500
+	// stockUpdateConcurrency bounds the listings updated in parallel.
+	stockUpdateConcurrency = 4
+)
+
+// StockAdjustment is one row of a bulk stock adjustment: a listing, by
+// item_id or by SKU, and either a delta to add to its stock or the
+// absolute stock to set. Line is the row's position in the input.
+type StockAdjustment struct {
+	Line     int    `json:"line"`
+	ItemID   string `json:"item_id"`
+	SKU      string `json:"sku"`
+	Delta    *int   `json:"delta"`
+	Absolute *int   `json:"absolute"`
+	// VariationID picks a variation of ItemID.
+	VariationID int64 `json:"variation_id"`
+}
+
+// StockResult is the outcome of a row for one listing. A SKU row has a
+// result per listing with that SKU; an invalid row has one without an item.
+type StockResult struct {
+	Line        int    `json:"line"`
+	ItemID      string `json:"item_id,omitempty"`
+	VariationID int64  `json:"variation_id,omitempty"`
+	SKU         string `json:"sku,omitempty"`
+	Previous    *int   `json:"previous,omitempty"`
+	Quantity    *int   `json:"quantity,omitempty"`
+	OK          bool   `json:"ok"`
+	Error       string `json:"error,omitempty"`
+}
+
+// StockAdjustmentReport summarizes a bulk stock adjustment.
+type StockAdjustmentReport struct {
+	Rows    int           `json:"rows"`
+	Updated int           `json:"updated"`
+	Failed  int           `json:"failed"`
+	Results []StockResult `json:"results"`
+}
+
+// stockColumns maps accepted header names to columns.
+var stockColumns = map[string]string{
+	"item_id": "item_id", "item": "item_id", "anuncio": "item_id", "anúncio": "item_id", "mlb": "item_id",
+	"variation_id": "variation_id", "variation": "variation_id", "variacao": "variation_id", "variação": "variation_id",
+	"sku": "sku", "codigo": "sku", "código": "sku",
+	"delta": "delta", "ajuste": "delta",
+	"absolute": "absolute", "quantity": "absolute", "stock": "absolute", "quantidade": "absolute", "estoque": "absolute",
+}
+
+// ParseStockCSV reads a stock adjustment spreadsheet. The header row is
+// required and names an item_id or sku column and a delta or absolute
+// (quantity, stock) column, optionally a variation_id one; empty cells are
+// skipped.
+func ParseStockCSV(r io.Reader) ([]StockAdjustment, error) {
+	records, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{}
+	for i, name := range records[0] {
+		if field, ok := stockColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+			cols[field] = i
+		}
+	}
+	_, hasItem := cols["item_id"]
+	_, hasSKU := cols["sku"]
+	_, hasDelta := cols["delta"]
+	_, hasAbsolute := cols["absolute"]
+	if !(hasItem || hasSKU) || !(hasDelta || hasAbsolute) {
+		return nil, errors.New("the CSV needs a header with an item_id or sku column and a delta or absolute column")
+	}
+
+	var rows []StockAdjustment
+	for i, rec := range records[1:] {
+		line := i + 2
+		field := func(name string) string {
+			if idx, ok := cols[name]; ok && idx < len(rec) {
+				return strings.TrimSpace(rec[idx])
+			}
+			return ""
+		}
+		row := StockAdjustment{Line: line, ItemID: field("item_id"), SKU: field("sku")}
+		if row.ItemID == "" && row.SKU == "" {
+			continue
+		}
+		for name, dst := range map[string]**int{"delta": &row.Delta, "absolute": &row.Absolute} {
+			v := field(name)
+			if v == "" {
+				continue
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, name, v)
+			}
+			*dst = &n
+		}
+		if v := field("variation_id"); v != "" {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid variation_id %q", line, v)
+			}
+			row.VariationID = id
+		}
+		rows = append(rows, row)
+		if len(rows) > MaxStockRows {
+			return nil, fmt.Errorf("the CSV has more than %d rows", MaxStockRows)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("the CSV has no rows")
+	}
+	return rows, nil
+}
+
+// ValidateStockAdjustments checks the size of a bulk stock adjustment; its
+// rows are checked one by one when it is applied.
+func ValidateStockAdjustments(rows []StockAdjustment) error {
+	if len(rows) == 0 {
+		return errors.New("no stock adjustments")
+	}
+	if len(rows) > MaxStockRows {
+		return fmt.Errorf("at most %d stock adjustments are accepted at once", MaxStockRows)
+	}
+	return nil
+}
+
+// StockService adjusts the stock of the seller's listings in bulk.
+type StockService struct {
+	meliClient *meli.MeliClient
+	skuRepo    *repository.SKUMappingRepository
+}
+
+func NewStockService(meliClient *meli.MeliClient, skuRepo *repository.SKUMappingRepository) *StockService {
+	return &StockService{
+		meliClient: meliClient,
+		skuRepo:    skuRepo,
+	}
+}
+
+// stockTarget is a listing, or a variation of it, whose stock a bulk
+// adjustment changes, with the results of the rows that changed it.
+type stockTarget struct {
+	item        *meli.Item
+	variationID int64
+	current     int
+	quantity    int
+	results     []int
+}
+
+// stockRef is a listing or variation a row refers to.
+type stockRef struct {
+	itemID      string
+	variationID int64
+}
+
+// Adjust applies the rows in order: rows for the same listing or variation
+// add up and each listing is updated once, with its final stock. A SKU
+// applies to every listing and variation mapped to it, or else to the
+// listings carrying it as their seller SKU. A row that cannot be applied
+// fails alone.
+func (s *StockService) Adjust(ctx context.Context, rows []StockAdjustment) (*StockAdjustmentReport, error) {
+	for i := range rows {
+		if rows[i].Line == 0 {
+			rows[i].Line = i + 1
+		}
+	}
+	mapped, err := s.mappedRefs(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.stockItems(ctx, rows, mapped)
+	if err != nil {
+		return nil, err
+	}
+	bySKU := map[string][]stockRef{}
+	byID := make(map[string]*meli.Item, len(items))
+	for i := range items {
+		byID[items[i].ID] = &items[i]
+		if sku := items[i].SKU(); sku != "" {
+			bySKU[sku] = append(bySKU[sku], stockRef{itemID: items[i].ID})
+		}
+	}
+
+	report := &StockAdjustmentReport{Rows: len(rows), Results: []StockResult{}}
+	fail := func(row StockAdjustment, itemID, format string, args ...interface{}) {
+		report.Results = append(report.Results, StockResult{Line: row.Line, ItemID: itemID, SKU: row.SKU, Error: fmt.Sprintf(format, args...)})
+	}
+	var order []*stockTarget
+	targets := map[stockRef]*stockTarget{}
+	for _, row := range rows {
+		if (row.ItemID == "") == (row.SKU == "") {
+			fail(row, row.ItemID, "set either item_id or sku")
+			continue
+		}
+		if (row.Delta == nil) == (row.Absolute == nil) {
+			fail(row, row.ItemID, "set either delta or absolute")
+			continue
+		}
+		if row.Absolute != nil && *row.Absolute < 0 {
+			fail(row, row.ItemID, "absolute must not be negative")
+			continue
+		}
+
+		refs := []stockRef{{itemID: row.ItemID, variationID: row.VariationID}}
+		if row.SKU != "" {
+			refs = mapped[row.SKU]
+			if len(refs) == 0 {
+				refs = bySKU[row.SKU]
+			}
+		}
+		if len(refs) == 0 {
+			fail(row, row.ItemID, "no listing of yours matches")
+			continue
+		}
+
+		for _, ref := range refs {
+			it := byID[ref.itemID]
+			if it == nil {
+				fail(row, ref.itemID, "no listing of yours matches")
+				continue
+			}
+			current, err := stockOf(it, ref.variationID)
+			if err != nil {
+				fail(row, it.ID, "%s", err)
+				continue
+			}
+			t := targets[ref]
+			if t == nil {
+				t = &stockTarget{item: it, variationID: ref.variationID, current: current, quantity: current}
+				targets[ref] = t
+				order = append(order, t)
+			}
+			next := t.quantity
+			if row.Absolute != nil {
+				next = *row.Absolute
+			} else {
+				next += *row.Delta
+			}
+			if next < 0 {
+				fail(row, it.ID, "the stock would drop below zero (%d %+d)", t.quantity, *row.Delta)
+				continue
+			}
+			previous, quantity := t.quantity, next
+			t.quantity = next
+			t.results = append(t.results, len(report.Results))
+			report.Results = append(report.Results, StockResult{Line: row.Line, ItemID: it.ID, VariationID: ref.variationID, SKU: row.SKU, Previous: &previous, Quantity: &quantity, OK: true})
+		}
+	}
+
+	for _, failure := range s.applyStock(ctx, order) {
+		for _, i := range failure.results {
+			report.Results[i].OK, report.Results[i].Error = false, failure.err
+		}
+	}
+	for _, r := range report.Results {
+		if r.OK {
+			report.Updated++
+		} else {
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// stockOf returns the stock of a listing, or of one of its variations,
+// when it can be set through the API.
+func stockOf(it *meli.Item, variationID int64) (int, error) {
+	switch {
+	case it.Fulfilled():
+		return 0, errors.New("the listing ships from FULL; its stock is managed by Mercado Livre")
+	case it.Status == meli.ItemStatusClosed:
+		return 0, errors.New("the listing is closed")
+	case variationID == 0 && len(it.Variations) > 0:
+		return 0, errors.New("the listing has variations; set variation_id or map each variation to a SKU")
+	case variationID == 0:
+		return it.AvailableQty, nil
+	}
+	for _, v := range it.Variations {
+		if v.ID == variationID {
+			return v.AvailableQty, nil
+		}
+	}
+	return 0, fmt.Errorf("the listing has no variation %d", variationID)
+}
+
+// mappedRefs returns the listings and variations mapped to the SKUs of the
+// rows.
+func (s *StockService) mappedRefs(ctx context.Context, rows []StockAdjustment) (map[string][]stockRef, error) {
+	refs := map[string][]stockRef{}
+	for _, row := range rows {
+		if row.SKU == "" {
+			continue
+		}
+		if _, done := refs[row.SKU]; done {
+			continue
+		}
+		mappings, err := s.skuRepo.List(ctx, row.SKU)
+		if err != nil {
+			return nil, err
+		}
+		refs[row.SKU] = []stockRef{}
+		for _, m := range mappings {
+			refs[row.SKU] = append(refs[row.SKU], stockRef{itemID: m.ItemID, variationID: m.VariationID})
+		}
+	}
+	return refs, nil
+}
+
+// stockItems fetches the seller's listings the rows refer to: the named
+// and mapped ones, or all of them when a SKU is not mapped and has to be
+// matched against the listings' seller SKU.
+func (s *StockService) stockItems(ctx context.Context, rows []StockAdjustment, mapped map[string][]stockRef) ([]meli.Item, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	seen := map[string]bool{}
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	unmapped := false
+	for _, row := range rows {
+		add(row.ItemID)
+		if row.SKU != "" && len(mapped[row.SKU]) == 0 {
+			unmapped = true
+		}
+		for _, ref := range mapped[row.SKU] {
+			add(ref.itemID)
+		}
+	}
+	if unmapped {
+		if ids, err = s.meliClient.UserItemIDs(ctx, me.ID); err != nil {
+			return nil, err
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	items, err := s.meliClient.GetItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(items, func(it meli.Item) bool { return int64(it.SellerID) != me.ID }), nil
+}
+
+// stockFailure is a listing whose update failed, with the results of the
+// rows that changed it.
+type stockFailure struct {
+	results []int
+	err     string
+}
+
+// applyStock updates the listings whose stock changed, a few at a time,
+// with one call per listing for all its variations.
+func (s *StockService) applyStock(ctx context.Context, targets []*stockTarget) []stockFailure {
+	var itemOrder []*meli.Item
+	byItem := map[string][]*stockTarget{}
+	for _, t := range targets {
+		if _, ok := byItem[t.item.ID]; !ok {
+			itemOrder = append(itemOrder, t.item)
+		}
+		byItem[t.item.ID] = append(byItem[t.item.ID], t)
+	}
+
+	var (
+		mu       sync.Mutex
+		failures []stockFailure
+		wg       sync.WaitGroup
+	)
+	sem := make(chan struct{}, stockUpdateConcurrency)
+	for _, it := range itemOrder {
+		changed := slices.ContainsFunc(byItem[it.ID], func(t *stockTarget) bool { return t.quantity != t.current })
+		if !changed {
+			continue
+		}
+		wg.Add(1)
+		go func(it *meli.Item, targets []*stockTarget) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var err error
+			if targets[0].variationID == 0 {
+				err = s.meliClient.SetItemStock(ctx, it.ID, targets[0].quantity)
+			} else {
+				quantities := make(map[int64]int, len(targets))
+				for _, t := range targets {
+					quantities[t.variationID] = t.quantity
+				}
+				err = s.meliClient.SetVariationStock(ctx, it, quantities)
+			}
+			if err == nil {
+				return
+			}
+			failure := stockFailure{err: err.Error()}
+			for _, t := range targets {
+				failure.results = append(failure.results, t.results...)
+			}
+			mu.Lock()
+			failures = append(failures, failure)
+			mu.Unlock()
+		}(it, byItem[it.ID])
+	}
+	wg.Wait()
+	return failures
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"melibot/internal/repository"
+)
+
+// Stream entities: append-only tables a data pipeline can pull
+// incrementally.
+const (
+	StreamTrendSnapshots    = "trend_snapshots"
+	StreamCategoryStats     = "category_stats"
+	StreamKeywordTrends     = "keyword_trends"
+	StreamCompetitorChanges = "competitor_changes"
+)
+
+// streamPageSize is how many rows a stream loads, and flushes, at a time.
+const streamPageSize = 1000
+
+var (
+	ErrUnknownStreamEntity = errors.New("entity must be trend_snapshots, category_stats, keyword_trends or competitor_changes")
+	ErrInvalidStreamCursor = errors.New("cursor must be the id of the last record received")
+)
+
+// StreamedTrendSnapshot is a product trend snapshot as streamed.
+type StreamedTrendSnapshot struct {
+	ID                  uint      `json:"id"`
+	ProductID           string    `json:"product_id"`
+	Title               string    `json:"title"`
+	CategoryID          string    `json:"category_id"`
+	HighlightCategoryID string    `json:"highlight_category_id"`
+	Rank                int       `json:"rank"`
+	SoldQuantity        int       `json:"sold_quantity"`
+	Price               float64   `json:"price"`
+	Health              string    `json:"health"`
+	Thumbnail           string    `json:"thumbnail"`
+	Permalink           string    `json:"permalink"`
+	CapturedAt          time.Time `json:"captured_at"`
+}
+
+// StreamedCategoryStats is a category crawl as streamed.
+type StreamedCategoryStats struct {
+	ID                uint      `json:"id"`
+	CategoryID        string    `json:"category_id"`
+	TotalListings     int       `json:"total_listings"`
+	SampledListings   int       `json:"sampled_listings"`
+	PriceMin          float64   `json:"price_min"`
+	PriceP25          float64   `json:"price_p25"`
+	PriceP50          float64   `json:"price_p50"`
+	PriceP75          float64   `json:"price_p75"`
+	PriceMax          float64   `json:"price_max"`
+	SoldTotal         int       `json:"sold_total"`
+	SoldP50           float64   `json:"sold_p50"`
+	FreeShippingShare float64   `json:"free_shipping_share"`
+	TopSellerHealth   *float64  `json:"top_seller_health"`
+	CrawledAt         time.Time `json:"crawled_at"`
+}
+
+// StreamedKeywordTrend is a trending keyword snapshot as streamed.
+type StreamedKeywordTrend struct {
+	ID         uint      `json:"id"`
+	CategoryID string    `json:"category_id"`
+	Keyword    string    `json:"keyword"`
+	Position   int       `json:"position"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+// StreamedCompetitorChange is a competitor change as streamed.
+type StreamedCompetitorChange struct {
+	ID        uint      `json:"id"`
+	SellerID  int64     `json:"seller_id"`
+	ItemID    string    `json:"item_id"`
+	Title     string    `json:"title"`
+	Kind      string    `json:"kind"`
+	OldValue  float64   `json:"old_value"`
+	NewValue  float64   `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// StreamResult summarizes a stream: NextCursor resumes after its last
+// record, and fewer records than the limit means the stream caught up.
+type StreamResult struct {
+	Records    int
+	NextCursor string
+}
+
+// streamPager loads a page of an entity: its records and the ID of the
+// last one.
+type streamPager func(ctx context.Context, afterID uint, since time.Time, limit int) ([]interface{}, uint, error)
+
+// StreamService streams stored records as newline-delimited JSON for data
+// warehouse ingestion.
+type StreamService struct {
+	trendRepo      *repository.TrendRepository
+	statsRepo      *repository.CategoryStatsRepository
+	keywordRepo    *repository.KeywordTrendRepository
+	competitorRepo *repository.CompetitorRepository
+}
+
+func NewStreamService(trendRepo *repository.TrendRepository, statsRepo *repository.CategoryStatsRepository, keywordRepo *repository.KeywordTrendRepository, competitorRepo *repository.CompetitorRepository) *StreamService {
+	return &StreamService{
+		trendRepo:      trendRepo,
+		statsRepo:      statsRepo,
+		keywordRepo:    keywordRepo,
+		competitorRepo: competitorRepo,
+	}
+}
+
+// ParseStreamCursor validates an entity and a cursor, the id of the last
+// record a previous stream returned; empty starts from the beginning.
+func (s *StreamService) ParseStreamCursor(entity, cursor string) (uint, error) {
+	if s.pager(entity) == nil {
+		return 0, ErrUnknownStreamEntity
+	}
+	if cursor == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidStreamCursor
+	}
+	return uint(id), nil
+}
+
+// Stream writes up to limit records of entity with an ID above afterID and
+// created at or after since to w, one JSON object per line, oldest first.
+// flush is called after each page so records reach the client as they are
+// read.
+func (s *StreamService) Stream(ctx context.Context, w io.Writer, flush func(), entity string, afterID uint, since time.Time, limit int) (*StreamResult, error) {
+	page := s.pager(entity)
+	if page == nil {
+		return nil, ErrUnknownStreamEntity
+	}
+
+	enc := json.NewEncoder(w)
+	result := &StreamResult{NextCursor: strconv.FormatUint(uint64(afterID), 10)}
+	for result.Records < limit {
+		records, lastID, err := page(ctx, afterID, since, min(streamPageSize, limit-result.Records))
+		if err != nil {
+			return result, err
+		}
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return result, err
+			}
+		}
+		if len(records) == 0 {
+			break
+		}
+		afterID = lastID
+		result.Records += len(records)
+		result.NextCursor = strconv.FormatUint(uint64(lastID), 10)
+		flush()
+	}
+	return result, nil
+}
+
+func (s *StreamService) pager(entity string) streamPager {
+	switch entity {
+	case StreamTrendSnapshots:
+		return pageOf(s.trendRepo.SnapshotsAfter, func(t repository.ProductTrend) (uint, interface{}) {
+			return t.ID, StreamedTrendSnapshot{
+				ID:                  t.ID,
+				ProductID:           t.ProductID,
+				Title:               t.Title,
+				CategoryID:          t.CategoryID,
+				HighlightCategoryID: t.HighlightCategoryID,
+				Rank:                t.Rank,
+				SoldQuantity:        t.SoldQuantity,
+				Price:               t.Price,
+				Health:              t.Health,
+				Thumbnail:           t.Thumbnail,
+				Permalink:           t.Permalink,
+				CapturedAt:          t.CreatedAt,
+			}
+		})
+	case StreamCategoryStats:
+		return pageOf(s.statsRepo.StatsAfter, func(st repository.CategoryStats) (uint, interface{}) {
+			return st.ID, StreamedCategoryStats{
+				ID:                st.ID,
+				CategoryID:        st.CategoryID,
+				TotalListings:     st.TotalListings,
+				SampledListings:   st.SampledListings,
+				PriceMin:          st.PriceMin,
+				PriceP25:          st.PriceP25,
+				PriceP50:          st.PriceP50,
+				PriceP75:          st.PriceP75,
+				PriceMax:          st.PriceMax,
+				SoldTotal:         st.SoldTotal,
+				SoldP50:           st.SoldP50,
+				FreeShippingShare: st.FreeShippingShare,
+				TopSellerHealth:   st.TopSellerHealth,
+				CrawledAt:         st.CrawledAt,
+			}
+		})
+	case StreamKeywordTrends:
+		return pageOf(s.keywordRepo.TrendsAfter, func(k repository.KeywordTrend) (uint, interface{}) {
+			return k.ID, StreamedKeywordTrend{
+				ID:         k.ID,
+				CategoryID: k.CategoryID,
+				Keyword:    k.Keyword,
+				Position:   k.Position,
+				CapturedAt: k.CreatedAt,
+			}
+		})
+	case StreamCompetitorChanges:
+		return pageOf(s.competitorRepo.ChangesAfter, func(ch repository.CompetitorChange) (uint, interface{}) {
+			return ch.ID, StreamedCompetitorChange{
+				ID:        ch.ID,
+				SellerID:  ch.SellerID,
+				ItemID:    ch.ItemID,
+				Title:     ch.Title,
+				Kind:      ch.Kind,
+				OldValue:  ch.OldValue,
+				NewValue:  ch.NewValue,
+				CreatedAt: ch.CreatedAt,
+			}
+		})
+	}
+	return nil
+}
+
+// pageOf adapts a repository's XAfter method to a streamPager, converting
+// each row with record.
+func pageOf[T any](fetch func(context.Context, uint, time.Time, int) ([]T, error), record func(T) (uint, interface{})) streamPager {
+	return func(ctx context.Context, afterID uint, since time.Time, limit int) ([]interface{}, uint, error) {
+		rows, err := fetch(ctx, afterID, since, limit)
+		if err != nil {
+			return nil, afterID, err
+		}
+		out := make([]interface{}, 0, len(rows))
+		lastID := afterID
+		for _, row := range rows {
+			id, r := record(row)
+			out = append(out, r)
+			lastID = id
+		}
+		return out, lastID, nil
+	}
+}
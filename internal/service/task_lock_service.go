@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"melibot/internal/repository"
+)
+
+// DefaultTaskLockTTL is how long a task lease lasts without being renewed.
+const DefaultTaskLockTTL = 2 * time.Minute
+
+// TaskLockStats counts what happened to the task leases of this instance.
+type TaskLockStats struct {
+	Owner     string `json:"owner"`
+	Acquired  int64  `json:"acquired"`
+	Contended int64  `json:"contended"`
+	TakenOver int64  `json:"taken_over"`
+	Lost      int64  `json:"lost"`
+	Errors    int64  `json:"errors"`
+	Held      int64  `json:"held"`
+}
+
+// TaskLocker keeps scheduled tasks from running on several instances at
+// once with leases in the database (see repository.TaskLock), renewed while
+// the task runs and kept until its next run. It implements
+// scheduler.Locker.
+type TaskLocker struct {
+	repo  *repository.TaskLockRepository
+	owner string
+	ttl   time.Duration
+
+	acquired, contended, takenOver, lost, errors, held atomic.Int64
+}
+
+func NewTaskLocker(repo *repository.TaskLockRepository, ttl time.Duration) *TaskLocker {
+	if ttl <= 0 {
+		ttl = DefaultTaskLockTTL
+	}
+	return &TaskLocker{repo: repo, owner: InstanceID(), ttl: ttl}
+}
+
+// InstanceID names this instance in the leases it holds: host, process and
+// a random suffix, as containers often share host names and PIDs.
+func InstanceID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 4)
+	rand.Read(b)
+	return fmt.Sprintf("%s/%d/%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// TryLock takes the lease of a task. While the task runs the lease is
+// renewed every third of its TTL; when it has not been renewed for half of
+// it, lockCtx is cancelled, leaving the task time to stop before the lease
+// expires and another instance may take it over.
+func (l *TaskLocker) TryLock(ctx context.Context, name string) (context.Context, func(time.Time), bool, error) {
+	ok, previous, err := l.repo.Acquire(ctx, name, l.owner, l.ttl)
+	if err != nil {
+		l.errors.Add(1)
+		return nil, nil, false, err
+	}
+	if !ok {
+		l.contended.Add(1)
+		return nil, nil, false, nil
+	}
+	l.acquired.Add(1)
+	l.held.Add(1)
+	if previous != "" {
+		l.takenOver.Add(1)
+		log.Printf("[WARN] scheduler: took over the expired lock of task %s from %s", name, previous)
+	}
+
+	lockCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go l.renew(lockCtx, cancel, done, name)
+
+	// The lease is kept until the next run of the task, so other instances
+	// skip their runs in between
+	release := func(next time.Time) {
+		close(done)
+		cancel()
+		l.held.Add(-1)
+		releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancelRelease()
+		var err error
+		if until := time.Until(next); until > 0 {
+			_, err = l.repo.Renew(releaseCtx, name, l.owner, until)
+		} else {
+			err = l.repo.Release(releaseCtx, name, l.owner)
+		}
+		if err != nil {
+			// The lease expires on its own
+			log.Printf("[WARN] scheduler: failed to release the lock of task %s: %v", name, err)
+		}
+	}
+	return lockCtx, release, true, nil
+}
+
+func (l *TaskLocker) renew(ctx context.Context, cancel context.CancelFunc, done <-chan struct{}, name string) {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		renewCtx, cancelRenew := context.WithTimeout(context.WithoutCancel(ctx), l.ttl/3)
+		ok, err := l.repo.Renew(renewCtx, name, l.owner, l.ttl)
+		cancelRenew()
+		select {
+		case <-done:
+			// Released meanwhile
+			return
+		default:
+		}
+		switch {
+		case err != nil && time.Since(renewed) < l.ttl/2:
+			l.errors.Add(1)
+			log.Printf("[WARN] scheduler: failed to renew the lock of task %s: %v", name, err)
+		case err != nil || !ok:
+			l.lost.Add(1)
+			log.Printf("[ERROR] scheduler: lost the lock of task %s, stopping it", name)
+			cancel()
+			return
+		default:
+			renewed = time.Now()
+		}
+	}
+}
+
+// Stats returns the lease counters of this instance.
+func (l *TaskLocker) Stats() TaskLockStats {
+	return TaskLockStats{
+		Owner:     l.owner,
+		Acquired:  l.acquired.Load(),
+		Contended: l.contended.Load(),
+		TakenOver: l.takenOver.Load(),
+		Lost:      l.lost.Load(),
+		Errors:    l.errors.Load(),
+		Held:      l.held.Load(),
+	}
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"melibot/pkg/meli"
+)
+
+const (
+	// maxTitleLength is Mercado Livre's limit for most categories.
+	maxTitleLength = 60
+	// titleSampleSize is how many top sellers the title is compared against.
+	titleSampleSize = 20
+	// commonTokenShare is the share of top titles a term must appear in to
+	// be suggested.
+	commonTokenShare = 0.3
+)
+
+// ErrNotOwnItem is returned when a listing belongs to another seller.
+var ErrNotOwnItem = errors.New("item does not belong to the authenticated seller")
+
+// titleAttributes are the attributes buyers search for and that are worth
+// stating in the title.
+var titleAttributes = []string{"BRAND", "MODEL", "LINE", "COLOR", "SIZE", "CAPACITY", "VOLTAGE", "GENDER", "MATERIAL"}
+
+// titleStopwords are ignored when comparing titles.
+var titleStopwords = map[string]bool{
+	"a": true, "o": true, "as": true, "os": true, "e": true, "de": true, "da": true, "do": true,
+	"das": true, "dos": true, "em": true, "para": true, "com": true, "sem": true, "por": true,
+	"p": true, "c": true, "un": true, "novo": true, "original": true, "promocao": true, "oferta": true,
+}
+
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a",
+	"é", "e", "ê", "e", "è", "e",
+	"í", "i", "î", "i",
+	"ó", "o", "ô", "o", "õ", "o", "ö", "o",
+	"ú", "u", "ü", "u",
+	"ç", "c",
+)
+
+// TitleSuggestion is one concrete change to a listing title.
+type TitleSuggestion struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Term    string `json:"term,omitempty"`
+}
+
+// TitleReport compares a listing title with the top sellers of its category.
+type TitleReport struct {
+	ItemID             string            `json:"item_id"`
+	Title              string            `json:"title"`
+	Length             int               `json:"length"`
+	TopMedianLength    int               `json:"top_median_length"`
+	ComparedListings   int               `json:"compared_listings"`
+	AttributeCoverage  float64           `json:"attribute_coverage"`
+	MissingAttributes  []string          `json:"missing_attributes"`
+	CommonMissingTerms []string          `json:"common_missing_terms"`
+	Suggestions        []TitleSuggestion `json:"suggestions"`
+}
+
+// TitleService suggests title improvements for the seller's own listings.
+type TitleService struct {
+	meliClient *meli.MeliClient
+}
+
+func NewTitleService(meliClient *meli.MeliClient) *TitleService {
+	return &TitleService{
+		meliClient: meliClient,
+	}
+}
+
+// TitleSuggestions compares the title of one of the authenticated seller's
+// listings against the best sellers of its category: term frequency,
+// coverage of key attributes and length.
+func (s *TitleService) TitleSuggestions(ctx context.Context, itemID string) (*TitleReport, error) {
+	me, err := s.meliClient.Me(ctx)
+	if err != nil {
+		return nil, err
+	}
+	item, err := s.meliClient.GetItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if int64(item.SellerID) != me.ID {
+		return nil, ErrNotOwnItem
+	}
+
+	page, err := s.meliClient.SearchCategoryPage(ctx, item.CategoryID, 0, 50)
+	if err != nil {
+		return nil, err
+	}
+	top := make([]meli.CategorySearchResult, 0, len(page.Results))
+	for _, r := range page.Results {
+		if r.ID != item.ID && r.Seller.ID != me.ID {
+			top = append(top, r)
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool { return top[i].SoldQuantity > top[j].SoldQuantity })
+	if len(top) > titleSampleSize {
+		top = top[:titleSampleSize]
+	}
+
+	return analyzeTitle(item, top), nil
+}
+
+func analyzeTitle(item *meli.Item, top []meli.CategorySearchResult) *TitleReport {
+	report := &TitleReport{
+		ItemID:             item.ID,
+		Title:              item.Title,
+		Length:             len([]rune(item.Title)),
+		ComparedListings:   len(top),
+		MissingAttributes:  []string{},
+		CommonMissingTerms: []string{},
+		Suggestions:        []TitleSuggestion{},
+	}
+	suggest := func(typ, term, format string, args ...interface{}) {
+		report.Suggestions = append(report.Suggestions, TitleSuggestion{Type: typ, Term: term, Message: fmt.Sprintf(format, args...)})
+	}
+
+	own := map[string]bool{}
+	for _, t := range titleTokens(item.Title) {
+		own[t] = true
+	}
+
+	// Terms most top sellers use that the title lacks.
+	docFreq := map[string]int{}
+	lengths := make([]int, 0, len(top))
+	for _, r := range top {
+		lengths = append(lengths, len([]rune(r.Title)))
+		seen := map[string]bool{}
+		for _, t := range titleTokens(r.Title) {
+			if !seen[t] {
+				seen[t] = true
+				docFreq[t]++
+			}
+		}
+	}
+	type termShare struct {
+		term  string
+		share float64
+	}
+	var missing []termShare
+	for t, n := range docFreq {
+		share := float64(n) / float64(len(top))
+		if share >= commonTokenShare && !own[t] {
+			missing = append(missing, termShare{t, share})
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].share != missing[j].share {
+			return missing[i].share > missing[j].share
+		}
+		return missing[i].term < missing[j].term
+	})
+	for _, m := range missing {
+		report.CommonMissingTerms = append(report.CommonMissingTerms, m.term)
+		suggest("missing_term", m.term, "add %q: it appears in %.0f%% of the top-selling titles", m.term, m.share*100)
+	}
+
+	// Key attributes whose value is not in the title.
+	checked := 0
+	for _, id := range titleAttributes {
+		for _, a := range item.Attributes {
+			if a.ID != id || a.ValueName == "" {
+				continue
+			}
+			checked++
+			if !containsAllTokens(own, titleTokens(a.ValueName)) {
+				report.MissingAttributes = append(report.MissingAttributes, a.Name)
+				suggest("missing_attribute", a.ValueName, "mention the %s (%s) in the title", strings.ToLower(a.Name), a.ValueName)
+			}
+		}
+	}
+	if checked > 0 {
+		report.AttributeCoverage = float64(checked-len(report.MissingAttributes)) / float64(checked)
+	} else {
+		report.AttributeCoverage = 1
+	}
+
+	// Length against the limit and the top sellers.
+	if len(lengths) > 0 {
+		sort.Ints(lengths)
+		report.TopMedianLength = lengths[len(lengths)/2]
+	}
+	switch {
+	case report.Length > maxTitleLength:
+		suggest("too_long", "", "shorten the title to %d characters; it has %d", maxTitleLength, report.Length)
+	case report.TopMedianLength > 0 && report.Length < report.TopMedianLength*2/3:
+		suggest("too_short", "", "the title has %d characters while top sellers use around %d; use the room for searched terms", report.Length, report.TopMedianLength)
+	}
+
+	// Repeated words waste characters.
+	counts := map[string]int{}
+	for _, t := range titleTokens(item.Title) {
+		counts[t]++
+		if counts[t] == 2 {
+			suggest("repeated_term", t, "%q appears more than once", t)
+		}
+	}
+	if isShouting(item.Title) {
+		suggest("all_caps", "", "avoid writing the title in capital letters")
+	}
+
+	return report
+}
+
+// titleTokens lowercases, folds accents and splits a title into searchable
+// terms, dropping stopwords.
+func titleTokens(title string) []string {
+	folded := accentFolder.Replace(strings.ToLower(title))
+	fields := strings.FieldsFunc(folded, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, f := range fields {
+		if len(f) > 1 && !titleStopwords[f] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func containsAllTokens(set map[string]bool, tokens []string) bool {
+	for _, t := range tokens {
+		if !set[t] {
+			return false
+		}
+	}
+	return true
+}
+
+func isShouting(title string) bool {
+	letters, upper := 0, 0
+	for _, r := range title {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= 10 && upper*10 >= letters*8
+}
@@ -0,0 +1,17 @@
+package service
+
+import "errors"
+
+// ErrVersionConflict is returned when an edit of a rule or template was
+// based on a version someone else has changed since.
+var ErrVersionConflict = errors.New("it was changed by someone else since you loaded it; reload it and try again")
+
+// checkVersion returns ErrVersionConflict when an edit based on version
+// would overwrite a newer stored one. Edits that carry no version (0) are
+// based on the stored one.
+func checkVersion(version, stored uint) error {
+	if version != 0 && version != stored {
+		return ErrVersionConflict
+	}
+	return nil
+}
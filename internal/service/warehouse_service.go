@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"melibot/database"
+	"melibot/internal/repository"
+	"melibot/internal/warehouse"
+)
+
+const (
+	// warehouseBatch is how many rows a sync reads and inserts at a time.
+	warehouseBatch = 1000
+	// warehouseMaxBatches bounds the batches of a table per sync, so the
+	// first sync of a long history spreads over several runs.
+	warehouseMaxBatches = 50
+	// warehouseSyncLag leaves out the rows written in the last minute,
+	// which transactions still in flight may add behind the cursor.
+	warehouseSyncLag = time.Minute
+	// warehousePriceDays is how many days of daily prices a batch covers.
+	warehousePriceDays = 7
+)
+
+// ErrWarehouseSyncRunning is returned when a sync starts while another one
+// is still running.
+var ErrWarehouseSyncRunning = errors.New("a warehouse sync is already running")
+
+// Replicated tables. Every row carries synced_at, the version that picks
+// the latest copy of a row synced more than once.
+var (
+	warehouseSnapshots = warehouse.Table{
+		Name: "product_trends",
+		Columns: []warehouse.Column{
+			{Name: "id", Type: warehouse.TypeInt},
+			{Name: "org_id", Type: warehouse.TypeInt},
+			{Name: "product_id", Type: warehouse.TypeString},
+			{Name: "title", Type: warehouse.TypeString},
+			{Name: "category_id", Type: warehouse.TypeString},
+			{Name: "highlight_category_id", Type: warehouse.TypeString},
+			{Name: "rank", Type: warehouse.TypeInt},
+			{Name: "sold_quantity", Type: warehouse.TypeInt},
+			{Name: "price", Type: warehouse.TypeFloat},
+			{Name: "health", Type: warehouse.TypeString},
+			{Name: "permalink", Type: warehouse.TypeString},
+			{Name: "created_at", Type: warehouse.TypeTimestamp},
+			{Name: "synced_at", Type: warehouse.TypeTimestamp},
+		},
+		Key:     []string{"id"},
+		Version: "synced_at",
+	}
+	warehouseOrders = warehouse.Table{
+		Name: "orders",
+		Columns: []warehouse.Column{
+			{Name: "id", Type: warehouse.TypeInt},
+			{Name: "org_id", Type: warehouse.TypeInt},
+			{Name: "seller_id", Type: warehouse.TypeInt},
+			{Name: "status", Type: warehouse.TypeString},
+			{Name: "date_created", Type: warehouse.TypeTimestamp},
+			{Name: "date_closed", Type: warehouse.TypeTimestamp},
+			{Name: "total_amount", Type: warehouse.TypeFloat},
+			{Name: "paid_amount", Type: warehouse.TypeFloat},
+			{Name: "currency", Type: warehouse.TypeString},
+			{Name: "shipping_cost", Type: warehouse.TypeFloat},
+			{Name: "updated_at", Type: warehouse.TypeTimestamp},
+			{Name: "synced_at", Type: warehouse.TypeTimestamp},
+		},
+		Key:     []string{"id"},
+		Version: "synced_at",
+	}
+	warehouseOrderItems = warehouse.Table{
+		Name: "order_items",
+		Columns: []warehouse.Column{
+			{Name: "order_id", Type: warehouse.TypeInt},
+			{Name: "item_id", Type: warehouse.TypeString},
+			{Name: "variation_id", Type: warehouse.TypeInt},
+			{Name: "org_id", Type: warehouse.TypeInt},
+			{Name: "title", Type: warehouse.TypeString},
+			{Name: "sku", Type: warehouse.TypeString},
+			{Name: "quantity", Type: warehouse.TypeInt},
+			{Name: "unit_price", Type: warehouse.TypeFloat},
+			{Name: "sale_fee", Type: warehouse.TypeFloat},
+			{Name: "date_created", Type: warehouse.TypeTimestamp},
+			{Name: "synced_at", Type: warehouse.TypeTimestamp},
+		},
+		Key:     []string{"order_id", "item_id", "variation_id"},
+		Version: "synced_at",
+	}
+	warehouseDailyPrices = warehouse.Table{
+		Name: "daily_prices",
+		Columns: []warehouse.Column{
+			{Name: "org_id", Type: warehouse.TypeInt},
+			{Name: "product_id", Type: warehouse.TypeString},
+			{Name: "day", Type: warehouse.TypeDate},
+			{Name: "min_price", Type: warehouse.TypeFloat},
+			{Name: "max_price", Type: warehouse.TypeFloat},
+			{Name: "avg_price", Type: warehouse.TypeFloat},
+			{Name: "snapshots", Type: warehouse.TypeInt},
+			{Name: "synced_at", Type: warehouse.TypeTimestamp},
+		},
+		Key:     []string{"org_id", "product_id", "day"},
+		Version: "synced_at",
+	}
+)
+
+// WarehouseService incrementally replicates the snapshots, orders and daily
+// price history of every organization to an analytics warehouse. Each
+// table resumes from its checkpoint, saved after every batch, so a failed
+// sync picks up where it stopped.
+type WarehouseService struct {
+	target    warehouse.Target
+	repo      *repository.WarehouseRepository
+	trendRepo *repository.TrendRepository
+	orderRepo *repository.OrderRepository
+
+	running sync.Mutex
+}
+
+func NewWarehouseService(target warehouse.Target, repo *repository.WarehouseRepository, trendRepo *repository.TrendRepository, orderRepo *repository.OrderRepository) *WarehouseService {
+	return &WarehouseService{
+		target:    target,
+		repo:      repo,
+		trendRepo: trendRepo,
+		orderRepo: orderRepo,
+	}
+}
+
+// Checkpoints returns how far each table was replicated.
+func (s *WarehouseService) Checkpoints(ctx context.Context) ([]repository.WarehouseCheckpoint, error) {
+	return s.repo.Checkpoints(database.AllOrgs(ctx))
+}
+
+// Sync replicates what changed since the last sync, creating the tables
+// on the first one. A table that fails does not stop the others.
+func (s *WarehouseService) Sync(ctx context.Context) error {
+	if !s.running.TryLock() {
+		return ErrWarehouseSyncRunning
+	}
+	defer s.running.Unlock()
+
+	ctx = database.AllOrgs(ctx)
+	for _, t := range []warehouse.Table{warehouseSnapshots, warehouseOrders, warehouseOrderItems, warehouseDailyPrices} {
+		if err := s.target.EnsureTable(ctx, t); err != nil {
+			return err
+		}
+	}
+
+	before := time.Now().Add(-warehouseSyncLag)
+	var errs []error
+	for _, table := range []struct {
+		name string
+		fn   func(context.Context, *repository.WarehouseCheckpoint, time.Time) (int, error)
+	}{
+		{warehouseSnapshots.Name, s.syncSnapshots},
+		{warehouseOrders.Name, s.syncOrders},
+		{warehouseDailyPrices.Name, s.syncDailyPrices},
+	} {
+		if err := s.syncTable(ctx, table.name, before, table.fn); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", table.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncTable runs the sync of a table from its checkpoint and records the
+// outcome on it.
+func (s *WarehouseService) syncTable(ctx context.Context, table string, before time.Time, fn func(context.Context, *repository.WarehouseCheckpoint, time.Time) (int, error)) error {
+	cp, err := s.repo.Checkpoint(ctx, s.target.String(), table)
+	if err != nil {
+		return err
+	}
+	if cp == nil {
+		cp = &repository.WarehouseCheckpoint{Target: s.target.String(), TableName: table}
+	}
+
+	synced, err := fn(ctx, cp, before)
+	now := time.Now()
+	cp.LastSyncAt = &now
+	cp.LastError = ""
+	if err != nil {
+		cp.LastError = err.Error()
+	}
+	if saveErr := s.repo.SaveCheckpoint(ctx, cp); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	if synced > 0 {
+		log.Printf("[INFO] Warehouse: synced %d %s rows to %s", synced, table, s.target)
+	}
+	return err
+}
+
+// advance moves a checkpoint past a synced batch and saves it.
+func (s *WarehouseService) advance(ctx context.Context, cp *repository.WarehouseCheckpoint, cursor time.Time, cursorID int64, rows int) error {
+	cp.Cursor, cp.CursorID = cursor, cursorID
+	cp.Rows += int64(rows)
+	return s.repo.SaveCheckpoint(ctx, cp)
+}
+
+// syncSnapshots appends the snapshots taken since the last sync, by ID.
+func (s *WarehouseService) syncSnapshots(ctx context.Context, cp *repository.WarehouseCheckpoint, before time.Time) (int, error) {
+	synced := 0
+	for range warehouseMaxBatches {
+		trends, err := s.trendRepo.SnapshotsAfter(ctx, uint(cp.CursorID), time.Time{}, warehouseBatch)
+		if err != nil {
+			return synced, err
+		}
+		now := time.Now()
+		rows := make([]warehouse.Row, 0, len(trends))
+		for _, t := range trends {
+			if !t.CreatedAt.Before(before) {
+				break
+			}
+			rows = append(rows, warehouse.Row{
+				"id":                    int64(t.ID),
+				"org_id":                int64(t.OrgID),
+				"product_id":            t.ProductID,
+				"title":                 t.Title,
+				"category_id":           t.CategoryID,
+				"highlight_category_id": t.HighlightCategoryID,
+				"rank":                  t.Rank,
+				"sold_quantity":         t.SoldQuantity,
+				"price":                 t.Price,
+				"health":                t.Health,
+				"permalink":             t.Permalink,
+				"created_at":            t.CreatedAt,
+				"synced_at":             now,
+			})
+		}
+		if len(rows) == 0 {
+			return synced, nil
+		}
+		if err := s.target.Insert(ctx, warehouseSnapshots, rows); err != nil {
+			return synced, err
+		}
+		last := trends[len(rows)-1]
+		if err := s.advance(ctx, cp, last.CreatedAt, int64(last.ID), len(rows)); err != nil {
+			return synced, err
+		}
+		synced += len(rows)
+		if len(rows) < warehouseBatch {
+			return synced, nil
+		}
+	}
+	return synced, nil
+}
+
+// syncOrders sends the orders stored or updated since the last sync, with
+// their lines, as new copies of them.
+func (s *WarehouseService) syncOrders(ctx context.Context, cp *repository.WarehouseCheckpoint, before time.Time) (int, error) {
+	synced := 0
+	for range warehouseMaxBatches {
+		orders, err := s.orderRepo.OrdersUpdatedAfter(ctx, cp.Cursor, cp.CursorID, before, warehouseBatch)
+		if err != nil {
+			return synced, err
+		}
+		if len(orders) == 0 {
+			return synced, nil
+		}
+		now := time.Now()
+		rows := make([]warehouse.Row, 0, len(orders))
+		var lines []warehouse.Row
+		for _, o := range orders {
+			rows = append(rows, warehouse.Row{
+				"id":            o.ID,
+				"org_id":        int64(o.OrgID),
+				"seller_id":     o.SellerID,
+				"status":        o.Status,
+				"date_created":  o.DateCreated,
+				"date_closed":   o.DateClosed,
+				"total_amount":  o.TotalAmount,
+				"paid_amount":   o.PaidAmount,
+				"currency":      o.Currency,
+				"shipping_cost": o.ShippingCost,
+				"updated_at":    o.UpdatedAt,
+				"synced_at":     now,
+			})
+			for _, it := range o.Items {
+				lines = append(lines, warehouse.Row{
+					"order_id":     o.ID,
+					"item_id":      it.ItemID,
+					"variation_id": it.VariationID,
+					"org_id":       int64(o.OrgID),
+					"title":        it.Title,
+					"sku":          it.SKU,
+					"quantity":     it.Quantity,
+					"unit_price":   it.UnitPrice,
+					"sale_fee":     it.SaleFee,
+					"date_created": o.DateCreated,
+					"synced_at":    now,
+				})
+			}
+		}
+		if err := s.target.Insert(ctx, warehouseOrders, rows); err != nil {
+			return synced, err
+		}
+		if err := s.target.Insert(ctx, warehouseOrderItems, lines); err != nil {
+			return synced, err
+		}
+		last := orders[len(orders)-1]
+		if err := s.advance(ctx, cp, last.UpdatedAt, last.ID, len(rows)); err != nil {
+			return synced, err
+		}
+		synced += len(rows)
+		if len(orders) < warehouseBatch {
+			return synced, nil
+		}
+	}
+	return synced, nil
+}
+
+// syncDailyPrices sends the daily prices of the days since the last sync,
+// warehousePriceDays at a time. The day the sync stops in may still get
+// snapshots, so the next sync sends it again. Days are cut in the server's
+// time zone, which should be the database's.
+func (s *WarehouseService) syncDailyPrices(ctx context.Context, cp *repository.WarehouseCheckpoint, before time.Time) (int, error) {
+	from := cp.Cursor
+	if from.IsZero() {
+		first, err := s.trendRepo.SnapshotsAfter(ctx, 0, time.Time{}, 1)
+		if err != nil || len(first) == 0 {
+			return 0, err
+		}
+		from = startOfDay(first[0].CreatedAt)
+	}
+
+	synced := 0
+	for i := 0; i < warehouseMaxBatches && from.Before(before); i++ {
+		to := from.AddDate(0, 0, warehousePriceDays)
+		if to.After(before) {
+			to = before
+		}
+		prices, err := s.trendRepo.DailyPricesBetween(ctx, from, to)
+		if err != nil {
+			return synced, err
+		}
+		now := time.Now()
+		rows := make([]warehouse.Row, 0, len(prices))
+		for _, p := range prices {
+			rows = append(rows, warehouse.Row{
+				"org_id":     int64(p.OrgID),
+				"product_id": p.ProductID,
+				"day":        p.Day,
+				"min_price":  p.MinPrice,
+				"max_price":  p.MaxPrice,
+				"avg_price":  roundCents(p.AvgPrice),
+				"snapshots":  p.Snapshots,
+				"synced_at":  now,
+			})
+		}
+		if err := s.target.Insert(ctx, warehouseDailyPrices, rows); err != nil {
+			return synced, err
+		}
+		if err := s.advance(ctx, cp, startOfDay(to), 0, len(rows)); err != nil {
+			return synced, err
+		}
+		synced += len(rows)
+		from = to
+	}
+	return synced, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"melibot/internal/events"
+	"melibot/internal/repository"
+	"melibot/pkg/meli"
+)
+
+// ErrNotWatched is returned for operations on a product that is not watched.
+var ErrNotWatched = errors.New("product is not on the watchlist")
+
+// ErrNotTrashed is returned when restoring a product that was not removed
+// from the watchlist.
+var ErrNotTrashed = errors.New("product is not in the watchlist trash")
+
+// Thresholds are the alert settings of a watched product. Nil fields are
+// disabled.
+type Thresholds struct {
+	AlertBelow *float64 `json:"alert_below"`
+	AlertAbove *float64 `json:"alert_above"`
+	ChangePct  *float64 `json:"change_pct"`
+}
+
+// Validate rejects non-positive values and an inverted below/above pair.
+func (t Thresholds) Validate() error {
+	for name, v := range map[string]*float64{"alert_below": t.AlertBelow, "alert_above": t.AlertAbove, "change_pct": t.ChangePct} {
+		if v != nil && *v <= 0 {
+			return fmt.Errorf("%s must be positive", name)
+		}
+	}
+	if t.AlertBelow != nil && t.AlertAbove != nil && *t.AlertBelow >= *t.AlertAbove {
+		return errors.New("alert_below must be lower than alert_above")
+	}
+	return nil
+}
+
+// WatchlistService tracks the best price of watched products and raises
+// alerts when it crosses their thresholds. Price moves are published as
+// PriceChanged events.
+type WatchlistService struct {
+	meliClient *meli.MeliClient
+	repo       *repository.WatchlistRepository
+	bus        *events.Bus
+}
+
+func NewWatchlistService(meliClient *meli.MeliClient, repo *repository.WatchlistRepository, bus *events.Bus) *WatchlistService {
+	return &WatchlistService{
+		meliClient: meliClient,
+		repo:       repo,
+		bus:        bus,
+	}
+}
+
+// List returns the watched products.
+func (s *WatchlistService) List(ctx context.Context) ([]repository.WatchedProduct, error) {
+	return s.repo.List(ctx)
+}
+
+// Watch adds a product to the watchlist (or updates its thresholds) and
+// records its current best price as the reference for change alerts.
+func (s *WatchlistService) Watch(ctx context.Context, productID string, t Thresholds) (*repository.WatchedProduct, error) {
+	p, err := s.repo.FindByProductID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil {
+		return s.updateThresholds(ctx, p, t)
+	}
+
+	best, err := s.meliClient.GetProductBestPriceWithLink(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	p = &repository.WatchedProduct{
+		ProductID:      productID,
+		Title:          best.Title,
+		Permalink:      best.Permalink,
+		LastPrice:      best.Price,
+		ReferencePrice: best.Price,
+		LastCheckedAt:  &now,
+	}
+	applyThresholds(p, t)
+	if err := s.repo.Create(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// UpdateThresholds replaces the thresholds of a watched product, unless
+// they changed since version (0 for the stored one).
+func (s *WatchlistService) UpdateThresholds(ctx context.Context, productID string, t Thresholds, version uint) (*repository.WatchedProduct, error) {
+	p, err := s.repo.FindByProductID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrNotWatched
+	}
+	if err := checkVersion(version, p.Version); err != nil {
+		return nil, err
+	}
+	return s.updateThresholds(ctx, p, t)
+}
+
+func (s *WatchlistService) updateThresholds(ctx context.Context, p *repository.WatchedProduct, t Thresholds) (*repository.WatchedProduct, error) {
+	applyThresholds(p, t)
+	updated, err := s.repo.Update(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, ErrVersionConflict
+	}
+	return p, nil
+}
+
+// applyThresholds sets new thresholds and re-arms their alerts against the
+// last known price.
+func applyThresholds(p *repository.WatchedProduct, t Thresholds) {
+	p.AlertBelow, p.AlertAbove, p.ChangePct = t.AlertBelow, t.AlertAbove, t.ChangePct
+	p.BelowActive = p.AlertBelow != nil && p.LastPrice > 0 && p.LastPrice < *p.AlertBelow
+	p.AboveActive = p.AlertAbove != nil && p.LastPrice > *p.AlertAbove
+	if p.LastPrice > 0 {
+		p.ReferencePrice = p.LastPrice
+	}
+}
+
+// Unwatch moves a product from the watchlist to its trash.
+func (s *WatchlistService) Unwatch(ctx context.Context, productID string) error {
+	deleted, err := s.repo.Delete(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotWatched
+	}
+	return nil
+}
+
+// Trash returns the products removed from the watchlist, last removed
+// first.
+func (s *WatchlistService) Trash(ctx context.Context) ([]repository.WatchedProduct, error) {
+	return s.repo.TrashedWatches(ctx)
+}
+
+// Restore puts a removed product back on the watchlist with its
+// thresholds. Its price is refreshed by the next scheduled check.
+func (s *WatchlistService) Restore(ctx context.Context, productID string) (*repository.WatchedProduct, error) {
+	restored, err := s.repo.RestoreWatch(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrNotTrashed
+	}
+	p, err := s.repo.FindByProductID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrNotWatched
+	}
+	return p, nil
+}
+
+// Alerts returns recent alerts, optionally for one product.
+func (s *WatchlistService) Alerts(ctx context.Context, productID string, limit int) ([]repository.PriceAlert, error) {
+	return s.repo.Alerts(ctx, productID, limit)
+}
+
+// RefreshPrices fetches the best price of every watched product and
+// evaluates its thresholds. Products that fail to refresh are logged and
+// skipped.
+func (s *WatchlistService) RefreshPrices(ctx context.Context) error {
+	products, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i := range products {
+		p := &products[i]
+		best, err := s.meliClient.GetProductBestPriceWithLink(ctx, p.ProductID)
+		if err != nil {
+			log.Printf("[WARN] Watchlist price refresh failed for %s: %v", p.ProductID, err)
+			failed++
+			continue
+		}
+
+		previous := p.LastPrice
+		alerts := evaluatePrice(p, best.Price, time.Now())
+		saved, err := s.repo.SaveWithAlerts(ctx, p, alerts)
+		if err != nil {
+			return err
+		}
+		if !saved {
+			// Edited or removed meanwhile: the next refresh evaluates its
+			// new thresholds
+			continue
+		}
+		if previous > 0 && previous != best.Price {
+			changed := events.PriceChanged{ProductID: p.ProductID, Title: p.Title, OldPrice: previous, NewPrice: best.Price}
+			for _, a := range alerts {
+				changed.Alerts = append(changed.Alerts, a.Kind)
+			}
+			s.bus.Publish(ctx, changed)
+		}
+		for _, a := range alerts {
+			log.Printf("[INFO] Price alert %s for %s: %.2f -> %.2f (threshold %.2f)", a.Kind, a.ProductID, a.PreviousPrice, a.Price, a.Threshold)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("watchlist refresh: %d of %d products failed", failed, len(products))
+	}
+	return nil
+}
+
+// evaluatePrice records a new price on p and returns the alerts it triggers.
+// Below/above alerts fire once per crossing; the change alert fires when the
+// price moved ChangePct percent from the reference, which then moves to the
+// new price.
+func evaluatePrice(p *repository.WatchedProduct, price float64, now time.Time) []repository.PriceAlert {
+	var alerts []repository.PriceAlert
+	alert := func(kind string, threshold float64) {
+		alerts = append(alerts, repository.PriceAlert{
+			ProductID:     p.ProductID,
+			Kind:          kind,
+			Price:         price,
+			PreviousPrice: p.LastPrice,
+			Threshold:     threshold,
+		})
+	}
+
+	if p.AlertBelow != nil {
+		below := price < *p.AlertBelow
+		if below && !p.BelowActive {
+			alert(repository.AlertKindBelow, *p.AlertBelow)
+		}
+		p.BelowActive = below
+	}
+	if p.AlertAbove != nil {
+		above := price > *p.AlertAbove
+		if above && !p.AboveActive {
+			alert(repository.AlertKindAbove, *p.AlertAbove)
+		}
+		p.AboveActive = above
+	}
+	if p.ChangePct != nil && p.ReferencePrice > 0 {
+		change := math.Abs(price-p.ReferencePrice) / p.ReferencePrice * 100
+		if change >= *p.ChangePct {
+			alert(repository.AlertKindChange, *p.ChangePct)
+			p.ReferencePrice = price
+		}
+	}
+	if p.ReferencePrice == 0 {
+		p.ReferencePrice = price
+	}
+
+	p.LastPrice = price
+	p.LastCheckedAt = &now
+	return alerts
+}
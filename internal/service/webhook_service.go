@@ -0,0 +1,328 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"melibot/internal/events"
+	"melibot/internal/repository"
+)
+
+// Headers sent with every outbound webhook delivery.
+const (
+	WebhookSignatureHeader = "X-Melibot-Signature"
+	WebhookEventHeader     = "X-Melibot-Event"
+	WebhookDeliveryHeader  = "X-Melibot-Delivery"
+)
+
+// WebhookTestEvent is the event name of deliveries sent by TestDelivery.
+const WebhookTestEvent = "webhook.test"
+
+var ErrWebhookNotFound = errors.New("webhook subscription not found")
+
+// WebhookPayload is the JSON body POSTed to subscribers.
+type WebhookPayload struct {
+	ID         string      `json:"id"`
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// WebhookDelivery is one payload to send to one subscription.
+type WebhookDelivery struct {
+	SubscriptionID uint            `json:"subscription_id"`
+	DeliveryID     string          `json:"delivery_id"`
+	Event          string          `json:"event"`
+	Body           json.RawMessage `json:"body"`
+}
+
+// OutboundWebhookService forwards internal events to third-party URLs,
+// signing each body with the subscription's secret. Private addresses are
+// never reached, unless a client given to NewOutboundWebhookService allows
+// it.
+type OutboundWebhookService struct {
+	repo       *repository.WebhookRepository
+	httpClient *http.Client
+}
+
+func NewOutboundWebhookService(repo *repository.WebhookRepository, httpClient *http.Client) *OutboundWebhookService {
+	if httpClient == nil {
+		httpClient = newPublicHTTPClient(10 * time.Second)
+	}
+	return &OutboundWebhookService{
+		repo:       repo,
+		httpClient: httpClient,
+	}
+}
+
+// ValidateWebhook checks the URL and event filter of a subscription.
+func ValidateWebhook(sub *repository.WebhookSubscription) error {
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if err := checkDestinationHost(u.Hostname()); err != nil {
+		return err
+	}
+	for _, name := range WebhookEvents(sub) {
+		if !knownEvent(name) {
+			return fmt.Errorf("unknown event %q; valid events are %s", name, strings.Join(events.Names, ", "))
+		}
+	}
+	return nil
+}
+
+// WebhookEvents returns the event filter of sub; empty means every event.
+func WebhookEvents(sub *repository.WebhookSubscription) []string {
+	var names []string
+	for _, name := range strings.Split(sub.Events, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func knownEvent(name string) bool {
+	for _, n := range events.Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func wantsEvent(sub *repository.WebhookSubscription, name string) bool {
+	names := WebhookEvents(sub)
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscriptions lists the webhook subscriptions.
+func (s *OutboundWebhookService) Subscriptions(ctx context.Context) ([]repository.WebhookSubscription, error) {
+	return s.repo.List(ctx)
+}
+
+// SaveSubscription creates a subscription, or replaces the one with sub.ID.
+// A random secret is generated when none is given; an update without a
+// secret keeps the current one.
+func (s *OutboundWebhookService) SaveSubscription(ctx context.Context, sub *repository.WebhookSubscription) error {
+	if err := ValidateWebhook(sub); err != nil {
+		return err
+	}
+	if sub.ID != 0 {
+		existing, err := s.repo.FindByID(ctx, sub.ID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return ErrWebhookNotFound
+		}
+		sub.CreatedAt = existing.CreatedAt
+		sub.LastDeliveryAt = existing.LastDeliveryAt
+		sub.LastStatus = existing.LastStatus
+		sub.LastError = existing.LastError
+		if sub.Secret == "" {
+			sub.Secret = existing.Secret
+		}
+	}
+	if sub.Secret == "" {
+		secret, err := randomHex(32)
+		if err != nil {
+			return err
+		}
+		sub.Secret = secret
+	}
+	return s.repo.Save(ctx, sub)
+}
+
+// DeleteSubscription removes a subscription.
+func (s *OutboundWebhookService) DeleteSubscription(ctx context.Context, id uint) error {
+	deleted, err := s.repo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Prepare builds the deliveries of e, one per enabled subscription that
+// listens to it.
+func (s *OutboundWebhookService) Prepare(ctx context.Context, e events.Event) ([]WebhookDelivery, error) {
+	subs, err := s.repo.ListEnabled(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []WebhookDelivery
+	for i := range subs {
+		if !wantsEvent(&subs[i], e.EventName()) {
+			continue
+		}
+		d, err := newDelivery(subs[i].ID, e.EventName(), webhookData(e))
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, nil
+}
+
+// TestDelivery builds a WebhookTestEvent delivery for a subscription, so the
+// receiver can check its signature verification.
+func (s *OutboundWebhookService) TestDelivery(ctx context.Context, id uint) (*WebhookDelivery, error) {
+	sub, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return newDelivery(sub.ID, WebhookTestEvent, map[string]interface{}{"message": "webhook test"})
+}
+
+func newDelivery(subscriptionID uint, event string, data interface{}) (*WebhookDelivery, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(WebhookPayload{
+		ID:         id,
+		Event:      event,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookDelivery{SubscriptionID: subscriptionID, DeliveryID: id, Event: event, Body: body}, nil
+}
+
+// Deliver POSTs d to its subscription and records the outcome. A non-2xx
+// answer is an error so the caller can retry. Deliveries to subscriptions
+// that were since deleted or disabled are dropped.
+func (s *OutboundWebhookService) Deliver(ctx context.Context, d WebhookDelivery) error {
+	sub, err := s.repo.FindByID(ctx, d.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	if sub == nil || (!sub.Enabled && d.Event != WebhookTestEvent) {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(sub.Secret, d.Body))
+	req.Header.Set(WebhookEventHeader, d.Event)
+	req.Header.Set(WebhookDeliveryHeader, d.DeliveryID)
+
+	status := 0
+	resp, err := s.httpClient.Do(req)
+	if err == nil {
+		status = resp.StatusCode
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+		if status < 200 || status > 299 {
+			err = fmt.Errorf("webhook %d answered %d", sub.ID, status)
+		}
+	}
+
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	if recErr := s.repo.RecordDelivery(ctx, sub.ID, status, msg, time.Now()); recErr != nil && err == nil {
+		return recErr
+	}
+	return err
+}
+
+// SignWebhook returns the signature header value of body: "sha256=" followed
+// by the hex HMAC-SHA256 of body keyed with secret.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// webhookData maps an event to its JSON representation.
+func webhookData(e events.Event) interface{} {
+	switch ev := e.(type) {
+	case events.OrderCreated:
+		return webhookOrder(ev.Order)
+	case events.OrderStatusChanged:
+		return map[string]interface{}{
+			"order": webhookOrder(ev.Order),
+			"from":  ev.From,
+			"to":    ev.To,
+		}
+	case events.PriceChanged:
+		return map[string]interface{}{
+			"product_id": ev.ProductID,
+			"title":      ev.Title,
+			"old_price":  ev.OldPrice,
+			"new_price":  ev.NewPrice,
+			"alerts":     append([]string{}, ev.Alerts...),
+		}
+	case events.TrendSnapshotCompleted:
+		return map[string]interface{}{
+			"category_id": ev.CategoryID,
+			"products":    ev.Products,
+			"partial":     ev.Partial,
+		}
+	case events.QuestionReceived:
+		return map[string]interface{}{
+			"question_id": ev.QuestionID,
+			"seller_id":   ev.SellerID,
+		}
+	}
+	return e
+}
+
+func webhookOrder(o *repository.Order) map[string]interface{} {
+	if o == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"id":             o.ID,
+		"status":         o.Status,
+		"date_created":   o.DateCreated,
+		"total_amount":   o.TotalAmount,
+		"paid_amount":    o.PaidAmount,
+		"currency":       o.Currency,
+		"buyer_id":       o.BuyerID,
+		"buyer_nickname": o.BuyerNickname,
+		"shipping_id":    o.ShippingID,
+	}
+}
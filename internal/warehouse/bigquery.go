@@ -0,0 +1,293 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	bigQueryEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+	bigQueryScope    = "https://www.googleapis.com/auth/bigquery"
+	googleTokenURL   = "https://oauth2.googleapis.com/token"
+	// tokenRefreshMargin renews access tokens this long before they expire.
+	tokenRefreshMargin = time.Minute
+)
+
+var bigQueryTypes = map[string]string{
+	TypeString:    "STRING",
+	TypeInt:       "INT64",
+	TypeFloat:     "FLOAT64",
+	TypeBool:      "BOOL",
+	TypeTimestamp: "TIMESTAMP",
+	TypeDate:      "DATE",
+}
+
+// serviceAccount is the part of a Google service account key file used to
+// sign token requests.
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// bigQuery writes to BigQuery with streaming inserts, authenticated as a
+// service account. Each row carries an insert ID built from its key and
+// version, so retried batches are not duplicated.
+type bigQuery struct {
+	project    string
+	dataset    string
+	account    serviceAccount
+	key        *rsa.PrivateKey
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+func newBigQuery(cfg Config) (*bigQuery, error) {
+	if cfg.Project == "" || cfg.Dataset == "" {
+		return nil, errors.New("warehouse: BigQuery project and dataset are required")
+	}
+	var account serviceAccount
+	if err := json.Unmarshal(cfg.CredentialsJSON, &account); err != nil {
+		return nil, fmt.Errorf("warehouse: BigQuery credentials: %w", err)
+	}
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if account.ClientEmail == "" || block == nil {
+		return nil, errors.New("warehouse: BigQuery credentials must be a service account key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("warehouse: BigQuery private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("warehouse: BigQuery private key must be RSA")
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = googleTokenURL
+	}
+	return &bigQuery{
+		project:    cfg.Project,
+		dataset:    cfg.Dataset,
+		account:    account,
+		key:        key,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (b *bigQuery) String() string {
+	return ProviderBigQuery + ":" + b.project + "." + b.dataset
+}
+
+func (b *bigQuery) tablesURL() string {
+	return fmt.Sprintf("%s/projects/%s/datasets/%s/tables", bigQueryEndpoint, url.PathEscape(b.project), url.PathEscape(b.dataset))
+}
+
+func (b *bigQuery) tableURL(table string) string {
+	return b.tablesURL() + "/" + url.PathEscape(table)
+}
+
+func (b *bigQuery) EnsureTable(ctx context.Context, t Table) error {
+	status, _, err := b.do(ctx, http.MethodGet, b.tableURL(t.Name), nil)
+	if err != nil || status == http.StatusOK {
+		return err
+	}
+	if status != http.StatusNotFound {
+		return fmt.Errorf("warehouse: BigQuery answered %d looking up table %s", status, t.Name)
+	}
+
+	fields := make([]map[string]string, 0, len(t.Columns))
+	for _, col := range t.Columns {
+		typ := bigQueryTypes[col.Type]
+		if typ == "" {
+			return fmt.Errorf("warehouse: column %s.%s has unknown type %q", t.Name, col.Name, col.Type)
+		}
+		fields = append(fields, map[string]string{"name": col.Name, "type": typ, "mode": "NULLABLE"})
+	}
+	def := map[string]interface{}{
+		"tableReference": map[string]string{"projectId": b.project, "datasetId": b.dataset, "tableId": t.Name},
+		"schema":         map[string]interface{}{"fields": fields},
+		"clustering":     map[string]interface{}{"fields": t.Key[:min(len(t.Key), 4)]},
+	}
+	status, body, err := b.do(ctx, http.MethodPost, b.tablesURL(), def)
+	if err != nil {
+		return err
+	}
+	// Conflict: created concurrently
+	if status != http.StatusOK && status != http.StatusConflict {
+		return fmt.Errorf("warehouse: BigQuery answered %d creating table %s: %s", status, t.Name, body)
+	}
+	return nil
+}
+
+func (b *bigQuery) Insert(ctx context.Context, t Table, rows []Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	type insertRow struct {
+		InsertID string                 `json:"insertId"`
+		JSON     map[string]interface{} `json:"json"`
+	}
+	req := struct {
+		Rows []insertRow `json:"rows"`
+	}{Rows: make([]insertRow, 0, len(rows))}
+	for _, r := range rows {
+		out := make(map[string]interface{}, len(t.Columns))
+		for _, col := range t.Columns {
+			out[col.Name] = encodeValue(r[col.Name], col.Type, time.RFC3339Nano)
+		}
+		id := make([]string, 0, len(t.Key)+1)
+		for _, k := range slices.Concat(t.Key, []string{t.Version}) {
+			id = append(id, fmt.Sprint(out[k]))
+		}
+		req.Rows = append(req.Rows, insertRow{InsertID: strings.Join(id, "|"), JSON: out})
+	}
+
+	status, body, err := b.do(ctx, http.MethodPost, b.tableURL(t.Name)+"/insertAll", req)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("warehouse: BigQuery answered %d inserting into %s: %s", status, t.Name, body)
+	}
+	var resp struct {
+		InsertErrors []struct {
+			Index  int `json:"index"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"insertErrors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+	if len(resp.InsertErrors) > 0 {
+		e := resp.InsertErrors[0]
+		msg := ""
+		if len(e.Errors) > 0 {
+			msg = e.Errors[0].Message
+		}
+		return fmt.Errorf("warehouse: BigQuery rejected %d rows of %s, row %d: %s", len(resp.InsertErrors), t.Name, e.Index, msg)
+	}
+	return nil
+}
+
+// do sends an authenticated request with payload as its JSON body and
+// returns the status and body of the answer.
+func (b *bigQuery) do(ctx context.Context, method, endpoint string, payload interface{}) (int, []byte, error) {
+	token, err := b.accessToken(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	var body io.Reader
+	if payload != nil {
+		buf, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, err
+		}
+		body = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	return resp.StatusCode, respBody, err
+}
+
+// accessToken returns a cached OAuth access token, exchanging a signed JWT
+// for a new one when it is about to expire.
+func (b *bigQuery) accessToken(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token != "" && time.Now().Add(tokenRefreshMargin).Before(b.tokenExpiry) {
+		return b.token, nil
+	}
+
+	now := time.Now()
+	assertion, err := b.signJWT(now)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return "", fmt.Errorf("warehouse: Google token endpoint answered %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", err
+	}
+	b.token = tok.AccessToken
+	b.tokenExpiry = now.Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return b.token, nil
+}
+
+// signJWT signs the assertion of a service account token request (RS256).
+func (b *bigQuery) signJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   b.account.ClientEmail,
+		"scope": bigQueryScope,
+		"aud":   b.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(nil, b.key, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + enc.EncodeToString(sig), nil
+}
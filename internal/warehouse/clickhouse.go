@@ -0,0 +1,133 @@
+package warehouse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// clickHouseTimeLayout is how DateTime64(3) values are written.
+const clickHouseTimeLayout = "2006-01-02 15:04:05.000"
+
+var clickHouseTypes = map[string]string{
+	TypeString:    "String",
+	TypeInt:       "Int64",
+	TypeFloat:     "Float64",
+	TypeBool:      "Bool",
+	TypeTimestamp: "DateTime64(3, 'UTC')",
+	TypeDate:      "Date",
+}
+
+// clickHouse writes to ClickHouse over its HTTP interface. Tables use the
+// ReplacingMergeTree engine, so copies of a row collapse into the one with
+// the greatest version.
+type clickHouse struct {
+	base       *url.URL
+	database   string
+	user       string
+	password   string
+	httpClient *http.Client
+}
+
+func newClickHouse(cfg Config) (*clickHouse, error) {
+	base, err := url.Parse(strings.TrimSuffix(cfg.URL, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("warehouse: invalid ClickHouse URL %q", cfg.URL)
+	}
+	if cfg.Database == "" {
+		cfg.Database = "default"
+	}
+	return &clickHouse{
+		base:       base,
+		database:   cfg.Database,
+		user:       cfg.User,
+		password:   cfg.Password,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+func (c *clickHouse) String() string {
+	return ProviderClickHouse + ":" + c.database
+}
+
+func (c *clickHouse) EnsureTable(ctx context.Context, t Table) error {
+	cols := make([]string, 0, len(t.Columns))
+	for _, col := range t.Columns {
+		typ := clickHouseTypes[col.Type]
+		if typ == "" {
+			return fmt.Errorf("warehouse: column %s.%s has unknown type %q", t.Name, col.Name, col.Type)
+		}
+		if !slices.Contains(t.Key, col.Name) && col.Name != t.Version {
+			typ = "Nullable(" + typ + ")"
+		}
+		cols = append(cols, quoteClickHouse(col.Name)+" "+typ)
+	}
+	key := make([]string, 0, len(t.Key))
+	for _, k := range t.Key {
+		key = append(key, quoteClickHouse(k))
+	}
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s) ENGINE = ReplacingMergeTree(%s) ORDER BY (%s)",
+		quoteClickHouse(t.Name), strings.Join(cols, ", "), quoteClickHouse(t.Version), strings.Join(key, ", "))
+	return c.exec(ctx, query, nil)
+}
+
+func (c *clickHouse) Insert(ctx context.Context, t Table, rows []Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, r := range rows {
+		out := make(map[string]interface{}, len(t.Columns))
+		for _, col := range t.Columns {
+			out[col.Name] = encodeValue(r[col.Name], col.Type, clickHouseTimeLayout)
+		}
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+	return c.exec(ctx, fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", quoteClickHouse(t.Name)), &body)
+}
+
+// exec runs query, with body as its input data when set.
+func (c *clickHouse) exec(ctx context.Context, query string, body io.Reader) error {
+	u := *c.base
+	q := url.Values{"database": {c.database}}
+	if body == nil {
+		body = strings.NewReader(query)
+	} else {
+		q.Set("query", query)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), body)
+	if err != nil {
+		return err
+	}
+	if c.user != "" {
+		req.Header.Set("X-ClickHouse-User", c.user)
+		req.Header.Set("X-ClickHouse-Key", c.password)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("warehouse: ClickHouse answered %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+func quoteClickHouse(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
@@ -0,0 +1,100 @@
+// Package warehouse replicates rows to an external analytics database, so
+// heavy analytical queries run there instead of on the operational one. It
+// speaks ClickHouse's HTTP interface and BigQuery's REST API.
+package warehouse
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Providers.
+const (
+	ProviderClickHouse = "clickhouse"
+	ProviderBigQuery   = "bigquery"
+)
+
+// Column types.
+const (
+	TypeString    = "string"
+	TypeInt       = "int"
+	TypeFloat     = "float"
+	TypeBool      = "bool"
+	TypeTimestamp = "timestamp"
+	TypeDate      = "date"
+)
+
+// Column is a column of a replicated table.
+type Column struct {
+	Name string
+	Type string
+}
+
+// Table is a replicated table. Rows are only ever appended: a row synced
+// again is a new copy, Key identifies the copies of a row and the one with
+// the greatest Version column is current. ClickHouse collapses them in the
+// background; BigQuery queries pick the latest Version per Key.
+type Table struct {
+	Name    string
+	Columns []Column
+	Key     []string
+	Version string
+}
+
+// Row is a row to insert, by column name. Timestamps and dates are
+// time.Time.
+type Row map[string]interface{}
+
+// Target is an analytics database rows are replicated to.
+type Target interface {
+	// EnsureTable creates the table when it does not exist.
+	EnsureTable(ctx context.Context, t Table) error
+	// Insert appends rows to the table.
+	Insert(ctx context.Context, t Table, rows []Row) error
+	// String names the target, e.g. clickhouse:analytics.
+	String() string
+}
+
+// Config selects a target. URL, Database, User and Password address
+// ClickHouse; Project, Dataset and the service account key in
+// CredentialsJSON address BigQuery.
+type Config struct {
+	Provider        string
+	URL             string
+	Database        string
+	User            string
+	Password        string
+	Project         string
+	Dataset         string
+	CredentialsJSON []byte
+}
+
+// New validates cfg and returns its target.
+func New(cfg Config) (Target, error) {
+	switch cfg.Provider {
+	case ProviderClickHouse:
+		return newClickHouse(cfg)
+	case ProviderBigQuery:
+		return newBigQuery(cfg)
+	}
+	return nil, fmt.Errorf("warehouse: unknown provider %q, want %s or %s", cfg.Provider, ProviderClickHouse, ProviderBigQuery)
+}
+
+// encodeValue turns a row value into what the target's JSON expects for a
+// column type: timestamps in layout, dates as YYYY-MM-DD.
+func encodeValue(v interface{}, typ, layout string) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		if typ == TypeDate {
+			return v.Format(time.DateOnly)
+		}
+		return v.UTC().Format(layout)
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return encodeValue(*v, typ, layout)
+	}
+	return v
+}
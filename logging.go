@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// logLevels ranks the level tags log lines start with, e.g. "[WARN] ...";
+// lines without one count as INFO.
+var logLevels = map[string]int32{"DEBUG": 0, "INFO": 1, "WARN": 2, "ERROR": 3}
+
+// levelWriter drops the log lines below its level.
+type levelWriter struct {
+	out   io.Writer
+	level atomic.Int32
+}
+
+var (
+	logOutput     *levelWriter
+	logOutputOnce sync.Once
+)
+
+// setLogLevel shows the log lines of level and above, e.g. LOG_LEVEL=warn;
+// empty shows everything, DEBUG included.
+func setLogLevel(level string) {
+	logOutputOnce.Do(func() {
+		logOutput = &levelWriter{out: os.Stderr}
+		log.SetOutput(logOutput)
+	})
+	if level == "" {
+		level = "debug"
+	}
+	rank, ok := logLevels[strings.ToUpper(level)]
+	if !ok {
+		log.Printf("[WARN] invalid LOG_LEVEL=%q, keeping %s", level, levelName(logOutput.level.Load()))
+		return
+	}
+	logOutput.level.Store(rank)
+}
+
+func (w *levelWriter) Write(p []byte) (int, error) {
+	if lineLevel(p) < w.level.Load() {
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
+
+// lineLevel reads the level tag following the date and time of a log line.
+func lineLevel(line []byte) int32 {
+	start := bytes.IndexByte(line, '[')
+	// Tags sit right after the date and time
+	if start < 0 || start > 32 {
+		return logLevels["INFO"]
+	}
+	end := bytes.IndexByte(line[start:], ']')
+	if end < 0 {
+		return logLevels["INFO"]
+	}
+	if rank, ok := logLevels[string(line[start+1:start+end])]; ok {
+		return rank
+	}
+	return logLevels["INFO"]
+}
+
+func levelName(rank int32) string {
+	for name, r := range logLevels {
+		if r == rank {
+			return name
+		}
+	}
+	return "DEBUG"
+}
@@ -1,114 +1,140 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
-
-	"melibot/database"
-	"melibot/internal/api"
-	"melibot/internal/handlers"
-	"melibot/internal/repository"
-	"melibot/internal/service"
 )
 
+// command is a melibot subcommand.
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"serve", "[--port N] [--tls-cert FILE --tls-key FILE | --autocert-domains D] [--redirect-http :80]", "run the web server, job queue and scheduler (default)", runServe},
+	{"snapshot", "--category ID[,ID...] [--limit N] [--org ID]", "fetch and store the top sellers of categories", runSnapshot},
+	{"export", "[--format csv|json|parquet] [--category ID] [--output FILE] [--org ID]", "write the latest stored product trends", runExport},
+	{"migrate", "", "create or update the database schema", runMigrate},
+	{"token", "refresh [--format env|json]", "exchange ML_REFRESH_TOKEN for a new token pair", runToken},
+}
+
 func main() {
 	// Load .env file (if present)
 	if err := godotenv.Load(); err != nil {
 		log.Println("no .env file found or error loading .env, continuing with existing environment variables")
 	}
+	setLogLevel(os.Getenv("LOG_LEVEL"))
 
-	// Initialize OAuth client with loaded environment variables
-	handlers.InitializeOAuth()
-
-	// Initialize database connection
-	database.Connect()
-
-	// Run repository migrations
-	if err := repository.AutoMigrate(); err != nil {
-		log.Fatalf("failed to run repository migrations: %v", err)
+	// Without a subcommand (or with flags only) melibot serves, as before
+	// subcommands existed
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		usage(os.Stdout)
+		return
 	}
 
-	// Wire dependencies
-	meliClientID := os.Getenv("ML_CLIENT_ID")
-	trendRepo := repository.NewTrendRepository()
-
-	// Setup Gin router
-	router := gin.Default()
-
-	// Simple health check route
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-		})
-	})
-
-	// OAuth routes (must be registered before API routes)
-	handlers.RegisterOAuthRoutes(router)
-
-	// Create middleware to validate token for protected routes
-	requireAuth := func(c *gin.Context) {
-		token := handlers.GetTokenFromContext(c)
-		if token == "" {
-			c.JSON(401, gin.H{"error": "Autenticação necessária. Por favor, faça login primeiro."})
-			c.Abort()
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args)
+		if errors.Is(err, flag.ErrHelp) {
 			return
 		}
-		c.Next()
+		if err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+		return
 	}
 
-	// Create a function to get fresh client/service/handler with current token
-	getMarketingHandler := func(c *gin.Context) *handlers.MarketingHandler {
-		meliAccessToken := handlers.GetTokenFromContext(c)
-		if meliAccessToken == "" {
-			meliAccessToken = os.Getenv("ML_ACCESS_TOKEN") // fallback to env
-			if meliAccessToken == "" {
-				log.Println("[DEBUG] Warning: No token found in context or .env for API request")
-			}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+	usage(os.Stderr)
+	os.Exit(2)
+}
+
+func usage(w *os.File) {
+	fmt.Fprintln(w, "usage: melibot <command> [flags]")
+	fmt.Fprintln(w)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", cmd.name, cmd.summary)
+		if cmd.args != "" {
+			fmt.Fprintf(w, "  %-9s   melibot %s %s\n", "", cmd.name, cmd.args)
 		}
-		log.Printf("[DEBUG] Creating handler with token (first 20 chars): %s...", meliAccessToken[:20])
-		meliClient := api.NewMeliClient(meliAccessToken, meliClientID)
-		marketingService := service.NewMarketingService(meliClient, trendRepo)
-		return handlers.NewMarketingHandler(marketingService)
 	}
+}
 
-	// API routes with dynamic token refresh
-	apiGroup := router.Group("/api")
-	{
-		// Categories - can work without auth for public data
-		apiGroup.GET("/categories", func(c *gin.Context) {
-			getMarketingHandler(c).GetCategories(c)
-		})
-		// Trends - requires authentication
-		apiGroup.GET("/trends", requireAuth, func(c *gin.Context) {
-			getMarketingHandler(c).GetTopTrends(c)
-		})
-		// Category suggest - requires authentication
-		apiGroup.GET("/category_suggest", requireAuth, func(c *gin.Context) {
-			getMarketingHandler(c).SuggestCategory(c)
-		})
+// envString returns an env var, or def when unset.
+func envString(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
+	return def
+}
 
-	// Static dashboard
-	router.Static("/static", "./web")
-	router.GET("/", func(c *gin.Context) {
-		c.File("./web/index.html")
-	})
-	router.GET("/oauth-help", func(c *gin.Context) {
-		c.File("./web/oauth_help.html")
-	})
+// envDuration parses a duration env var (e.g. "10m"), returning def when unset
+// or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("[WARN] invalid %s=%q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
 
-	// Determine server port from env, default 8080
-	port := os.Getenv("SERVER_PORT")
-	if port == "" {
-		port = "8080"
+// envInt parses a positive integer env var, returning def when unset or invalid.
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
 	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("[WARN] invalid %s=%q, using %d", key, v, def)
+		return def
+	}
+	return n
+}
 
-	log.Println("Servidor iniciado na porta", port)
+// envFloat parses a positive float env var, returning def when unset or invalid.
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 {
+		log.Printf("[WARN] invalid %s=%q, using %g", key, v, def)
+		return def
+	}
+	return f
+}
 
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("failed to start server: %v", err)
+// splitList splits a comma-separated env value, dropping empty entries.
+func splitList(v string) []string {
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
 	}
+	return out
 }
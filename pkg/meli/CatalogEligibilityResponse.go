@@ -0,0 +1,20 @@
+package meli
+
+// CatalogEligibility is the response of `/items/{id}/catalog_listing_eligibility`.
+type CatalogEligibility struct {
+	ID             string                        `json:"id"`
+	SiteID         string                        `json:"site_id"`
+	DomainID       string                        `json:"domain_id"`
+	BuyBoxEligible bool                          `json:"buy_box_eligible"`
+	Status         string                        `json:"status"`
+	Reason         string                        `json:"reason"`
+	Variations     []CatalogEligibilityVariation `json:"variations"`
+}
+
+// CatalogEligibilityVariation is the eligibility of one variation.
+type CatalogEligibilityVariation struct {
+	ID             int64  `json:"id"`
+	BuyBoxEligible bool   `json:"buy_box_eligible"`
+	Status         string `json:"status"`
+	Reason         string `json:"reason"`
+}
@@ -0,0 +1,47 @@
+package meli
+
+// CategoryDetail is a category as returned by `/categories/{id}`.
+type CategoryDetail struct {
+	ID           string           `json:"id"`
+	Name         string           `json:"name"`
+	PathFromRoot []Category       `json:"path_from_root"`
+	Settings     CategorySettings `json:"settings"`
+}
+
+// CategorySettings are the listing rules of a category.
+type CategorySettings struct {
+	CatalogDomain      string   `json:"catalog_domain"`
+	ListingAllowed     bool     `json:"listing_allowed"`
+	ListingStrategy    string   `json:"listing_strategy"` // "catalog_required" when listings must join a catalog product
+	MaxTitleLength     int      `json:"max_title_length"`
+	MaxPicturesPerItem int      `json:"max_pictures_per_item"`
+	MinimumPrice       float64  `json:"minimum_price"`
+	MaximumPrice       float64  `json:"maximum_price"`
+	ItemConditions     []string `json:"item_conditions"`
+	Currencies         []string `json:"currencies"`
+}
+
+// CatalogRequired reports whether new listings of the category must be
+// linked to a catalog product.
+func (c *CategoryDetail) CatalogRequired() bool {
+	return c.Settings.ListingStrategy == "catalog_required"
+}
+
+// CategoryAttribute is one attribute listings of a category can carry, as
+// returned by `/categories/{id}/attributes`.
+type CategoryAttribute struct {
+	ID   string                `json:"id"`
+	Name string                `json:"name"`
+	Tags CategoryAttributeTags `json:"tags"`
+}
+
+// CategoryAttributeTags flags how an attribute is used.
+type CategoryAttributeTags struct {
+	Required        bool `json:"required"`
+	CatalogRequired bool `json:"catalog_required"`
+	// ConditionalRequired attributes are required for some listings, e.g.
+	// certifications of some product lines
+	ConditionalRequired bool `json:"conditional_required"`
+	Hidden              bool `json:"hidden"`
+	ReadOnly            bool `json:"read_only"`
+}
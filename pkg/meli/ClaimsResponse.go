@@ -0,0 +1,39 @@
+package meli
+
+import (
+	"slices"
+	"time"
+)
+
+// Claim types that cancel the order instead of disputing the product.
+const (
+	ClaimTypeCancelPurchase = "cancel_purchase"
+	ClaimTypeCancelSale     = "cancel_sale"
+)
+
+// Claim is a buyer's claim about an order, a subset of
+// `/post-purchase/v1/claims/{id}`. ResourceID is the order ID when
+// Resource is "order". A claim that led to a return lists "return" among
+// its related entities.
+type Claim struct {
+	ID              int64     `json:"id"`
+	ResourceID      int64     `json:"resource_id"`
+	Resource        string    `json:"resource"`
+	Status          string    `json:"status"`
+	Type            string    `json:"type"`
+	Stage           string    `json:"stage"`
+	ReasonID        string    `json:"reason_id"`
+	DateCreated     time.Time `json:"date_created"`
+	LastUpdated     time.Time `json:"last_updated"`
+	RelatedEntities []string  `json:"related_entities"`
+}
+
+// Returned reports whether the claim led to the product being returned.
+func (c *Claim) Returned() bool {
+	return slices.Contains(c.RelatedEntities, "return")
+}
+
+type claimsSearchResponse struct {
+	Data   []Claim `json:"data"`
+	Paging paging  `json:"paging"`
+}
@@ -1,5 +1,6 @@
-package api
+package meli
 
+// HighlightResponse is the ranking of best sellers of a category.
 type HighlightResponse struct {
 	QueryData struct {
 		HighlightType string `json:"highlight_type"`
@@ -0,0 +1,160 @@
+package meli
+
+// Item is a listing, as returned by /items.
+type Item struct {
+	ID           string        `json:"id"`
+	Title        string        `json:"title"`
+	CategoryID   string        `json:"category_id"`
+	Price        float64       `json:"price"`
+	CurrencyID   string        `json:"currency_id"`
+	AvailableQty int           `json:"available_quantity"`
+	SoldQty      int           `json:"sold_quantity"`
+	Condition    string        `json:"condition"`
+	Permalink    string        `json:"permalink"`
+	Thumbnail    string        `json:"thumbnail"`
+	Pictures     []ItemPicture `json:"pictures"`
+	SellerID     int           `json:"seller_id"`
+	CatalogID    string        `json:"catalog_product_id"`
+	SellerCustom string        `json:"seller_custom_field"`
+	InventoryID  string        `json:"inventory_id"`
+	Shipping     ItemShipping  `json:"shipping"`
+	Status       string        `json:"status"`
+	ListingType  string        `json:"listing_type_id"`
+	Attributes   []Attribute   `json:"attributes"`
+	Variations   []Variation   `json:"variations"`
+	Health       *float64      `json:"health"` // 0-1, null until Mercado Livre rates the listing
+}
+
+// SKU returns the seller's SKU for the listing: the SELLER_SKU attribute,
+// falling back to seller_custom_field.
+func (i *Item) SKU() string {
+	for _, a := range i.Attributes {
+		if a.ID == "SELLER_SKU" && a.ValueName != "" {
+			return a.ValueName
+		}
+	}
+	return i.SellerCustom
+}
+
+// Listing statuses that can be set with SetItemStatus.
+const (
+	ItemStatusActive = "active"
+	ItemStatusPaused = "paused"
+	ItemStatusClosed = "closed"
+)
+
+// LogisticTypeFulfillment marks listings stocked in Mercado Livre's
+// warehouses (FULL).
+const LogisticTypeFulfillment = "fulfillment"
+
+// ItemShipping is how a listing is shipped.
+type ItemShipping struct {
+	Mode         string `json:"mode"`
+	LogisticType string `json:"logistic_type"`
+}
+
+// Fulfilled reports whether the listing ships from Mercado Livre's
+// warehouses.
+func (i *Item) Fulfilled() bool {
+	return i.Shipping.LogisticType == LogisticTypeFulfillment && i.InventoryID != ""
+}
+
+// ItemPicture is a picture of a listing.
+type ItemPicture struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// Variation is one variant of a listing, e.g. a color or size, with its
+// own stock.
+type Variation struct {
+	ID           int64       `json:"id"`
+	AvailableQty int         `json:"available_quantity"`
+	SoldQty      int         `json:"sold_quantity"`
+	Attributes   []Attribute `json:"attributes"`
+}
+
+// ItemVisits is an entry of `/items/visits`: the visits of a listing over
+// a date range.
+type ItemVisits struct {
+	ItemID      string `json:"item_id"`
+	TotalVisits int    `json:"total_visits"`
+}
+
+// Attribute is a technical attribute of a listing, e.g. BRAND.
+type Attribute struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	ValueID   string `json:"value_id"`
+	ValueName string `json:"value_name"`
+}
+
+// NewItem is a listing to publish with CreateItem.
+type NewItem struct {
+	Title        string           `json:"title"`
+	CategoryID   string           `json:"category_id"`
+	Price        float64          `json:"price"`
+	CurrencyID   string           `json:"currency_id"`
+	AvailableQty int              `json:"available_quantity"`
+	BuyingMode   string           `json:"buying_mode"`
+	ListingType  string           `json:"listing_type_id"`
+	Condition    string           `json:"condition"`
+	Description  *NewItemText     `json:"description,omitempty"`
+	Pictures     []NewItemPicture `json:"pictures,omitempty"`
+	Attributes   []Attribute      `json:"attributes,omitempty"`
+	Shipping     *NewItemShipping `json:"shipping,omitempty"`
+}
+
+// NewItemText is the description of a NewItem.
+type NewItemText struct {
+	PlainText string `json:"plain_text"`
+}
+
+// NewItemShipping is how a NewItem is shipped. Mode is e.g. "me2"
+// (Mercado Envios) or "not_specified".
+type NewItemShipping struct {
+	Mode         string `json:"mode,omitempty"`
+	LocalPickUp  bool   `json:"local_pick_up"`
+	FreeShipping bool   `json:"free_shipping"`
+}
+
+// SKU returns the seller's SKU of the listing, its SELLER_SKU attribute.
+func (i *NewItem) SKU() string {
+	for _, a := range i.Attributes {
+		if a.ID == "SELLER_SKU" && a.ValueName != "" {
+			return a.ValueName
+		}
+	}
+	return ""
+}
+
+// NewItemPicture is a picture of a NewItem, downloaded by Mercado Livre
+// from Source.
+type NewItemPicture struct {
+	Source string `json:"source"`
+}
+
+// ListingPrice is what Mercado Livre charges to list and sell an item at a
+// price, as returned by `/sites/{site}/listing_prices`.
+type ListingPrice struct {
+	ListingTypeID    string  `json:"listing_type_id"`
+	ListingTypeName  string  `json:"listing_type_name"`
+	CurrencyID       string  `json:"currency_id"`
+	ListingFeeAmount float64 `json:"listing_fee_amount"`
+	SaleFeeAmount    float64 `json:"sale_fee_amount"`
+}
+
+// ValidationCause is one problem `/items/validate` found in a listing.
+// Type is "error" or "warning".
+type ValidationCause struct {
+	Code       string   `json:"code"`
+	Type       string   `json:"type"`
+	Message    string   `json:"message"`
+	References []string `json:"references"`
+}
+
+// multigetItem is one entry of `/items?ids=...`.
+type multigetItem struct {
+	Code int  `json:"code"`
+	Body Item `json:"body"`
+}
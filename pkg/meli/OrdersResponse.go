@@ -0,0 +1,52 @@
+package meli
+
+import "time"
+
+// Order is a subset of `/orders/{id}` (and each result of `/orders/search`).
+type Order struct {
+	ID          int64       `json:"id"`
+	Status      string      `json:"status"`
+	DateCreated time.Time   `json:"date_created"`
+	DateClosed  *time.Time  `json:"date_closed"`
+	LastUpdated time.Time   `json:"last_updated"`
+	TotalAmount float64     `json:"total_amount"`
+	PaidAmount  float64     `json:"paid_amount"`
+	CurrencyID  string      `json:"currency_id"`
+	Buyer       OrderBuyer  `json:"buyer"`
+	Seller      OrderSeller `json:"seller"`
+	OrderItems  []OrderItem `json:"order_items"`
+	Shipping    struct {
+		ID int64 `json:"id"`
+	} `json:"shipping"`
+	Tags []string `json:"tags"`
+}
+
+// OrderBuyer is the buyer of an order.
+type OrderBuyer struct {
+	ID       int64  `json:"id"`
+	Nickname string `json:"nickname"`
+}
+
+// OrderSeller is the seller of an order.
+type OrderSeller struct {
+	ID int64 `json:"id"`
+}
+
+// OrderItem is a line of an order. SaleFee is Mercado Livre's commission
+// per unit.
+type OrderItem struct {
+	Item struct {
+		ID          string `json:"id"`
+		Title       string `json:"title"`
+		SellerSKU   string `json:"seller_sku"`
+		VariationID int64  `json:"variation_id"`
+	} `json:"item"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+	SaleFee   float64 `json:"sale_fee"`
+}
+
+type ordersSearchResponse struct {
+	Results []Order `json:"results"`
+	Paging  paging  `json:"paging"`
+}
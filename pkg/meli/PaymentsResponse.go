@@ -0,0 +1,62 @@
+package meli
+
+import "time"
+
+// Payment statuses that matter for settlement.
+const (
+	PaymentStatusApproved    = "approved"
+	PaymentStatusRefunded    = "refunded"
+	PaymentStatusChargedBack = "charged_back"
+)
+
+// MoneyReleaseStatusReleased is the money release status of a payment whose
+// money is available to the seller.
+const MoneyReleaseStatusReleased = "released"
+
+// Payment is a Mercado Pago payment collected by the seller, a subset of
+// `/v1/payments/{id}`. Order is the Mercado Livre order it paid, when it
+// came from one.
+type Payment struct {
+	ID           int64  `json:"id"`
+	Status       string `json:"status"`
+	StatusDetail string `json:"status_detail"`
+	Order        struct {
+		ID   int64  `json:"id"`
+		Type string `json:"type"`
+	} `json:"order"`
+	CurrencyID         string       `json:"currency_id"`
+	TransactionAmount  float64      `json:"transaction_amount"`
+	AmountRefunded     float64      `json:"transaction_amount_refunded"`
+	FeeDetails         []PaymentFee `json:"fee_details"`
+	TransactionDetails struct {
+		NetReceivedAmount float64 `json:"net_received_amount"`
+	} `json:"transaction_details"`
+	DateApproved       *time.Time `json:"date_approved"`
+	DateLastUpdated    time.Time  `json:"date_last_updated"`
+	MoneyReleaseDate   *time.Time `json:"money_release_date"`
+	MoneyReleaseStatus string     `json:"money_release_status"`
+}
+
+// PaymentFee is a fee charged on a payment; FeePayer is "collector" for
+// the fees the seller pays.
+type PaymentFee struct {
+	Type     string  `json:"type"`
+	Amount   float64 `json:"amount"`
+	FeePayer string  `json:"fee_payer"`
+}
+
+// SellerFees returns the fees of the payment paid by the seller.
+func (p *Payment) SellerFees() float64 {
+	var total float64
+	for _, f := range p.FeeDetails {
+		if f.FeePayer == "collector" {
+			total += f.Amount
+		}
+	}
+	return total
+}
+
+type paymentsSearchResponse struct {
+	Results []Payment `json:"results"`
+	Paging  paging    `json:"paging"`
+}
@@ -1,7 +1,8 @@
-package api
+package meli
 
 import "encoding/json"
 
+// Product is a catalog product, as returned by /products.
 type Product struct {
 	ID               string `json:"id"`
 	CatalogProductID string `json:"catalog_product_id"`
@@ -25,6 +26,7 @@ type Product struct {
 	UpdatedAt string `json:"last_updated"`
 }
 
+// ProductPicture is a picture of a catalog product.
 type ProductPicture struct {
 	ID        string `json:"id"`
 	URL       string `json:"url"`
@@ -0,0 +1,30 @@
+package meli
+
+import "time"
+
+// Question statuses.
+const (
+	QuestionStatusUnanswered = "UNANSWERED"
+	QuestionStatusAnswered   = "ANSWERED"
+)
+
+// Question is a subset of `/questions/{id}`.
+type Question struct {
+	ID          int64     `json:"id"`
+	ItemID      string    `json:"item_id"`
+	SellerID    int64     `json:"seller_id"`
+	Text        string    `json:"text"`
+	Status      string    `json:"status"`
+	DateCreated time.Time `json:"date_created"`
+	From        struct {
+		ID int64 `json:"id"`
+	} `json:"from"`
+	Answer *QuestionAnswer `json:"answer"`
+}
+
+// QuestionAnswer is the seller's answer to a question.
+type QuestionAnswer struct {
+	Text        string    `json:"text"`
+	Status      string    `json:"status"`
+	DateCreated time.Time `json:"date_created"`
+}
@@ -0,0 +1,29 @@
+package meli
+
+// CategorySearchResult is a listing as returned by `/sites/{site}/search`.
+type CategorySearchResult struct {
+	ID                string  `json:"id"`
+	Title             string  `json:"title"`
+	Price             float64 `json:"price"`
+	SoldQuantity      int     `json:"sold_quantity"`
+	AvailableQuantity int     `json:"available_quantity"`
+	CatalogProductID  string  `json:"catalog_product_id"`
+	Shipping          struct {
+		FreeShipping bool   `json:"free_shipping"`
+		LogisticType string `json:"logistic_type"`
+	} `json:"shipping"`
+	Seller struct {
+		ID int64 `json:"id"`
+	} `json:"seller"`
+}
+
+// CategorySearchPage is one page of `/sites/{site}/search?category=`.
+type CategorySearchPage struct {
+	Paging struct {
+		Total          int `json:"total"`
+		PrimaryResults int `json:"primary_results"`
+		Offset         int `json:"offset"`
+		Limit          int `json:"limit"`
+	} `json:"paging"`
+	Results []CategorySearchResult `json:"results"`
+}
@@ -0,0 +1,82 @@
+package meli
+
+import "time"
+
+// ShipmentCosts is a subset of `/shipments/{id}/costs`: what the buyer paid
+// and what each sender (the seller) was charged.
+type ShipmentCosts struct {
+	GrossAmount float64 `json:"gross_amount"`
+	Receiver    struct {
+		Cost float64 `json:"cost"`
+	} `json:"receiver"`
+	Senders []struct {
+		UserID int64   `json:"user_id"`
+		Cost   float64 `json:"cost"`
+	} `json:"senders"`
+}
+
+// SenderCost is the total shipping charged to the seller.
+func (s *ShipmentCosts) SenderCost() float64 {
+	total := 0.0
+	for _, sender := range s.Senders {
+		total += sender.Cost
+	}
+	return total
+}
+
+// Shipment statuses before the package leaves the seller.
+const (
+	ShipmentStatusPending     = "pending"
+	ShipmentStatusHandling    = "handling"
+	ShipmentStatusReadyToShip = "ready_to_ship"
+)
+
+// Shipment is a subset of `/shipments/{id}`.
+type Shipment struct {
+	ID             int64     `json:"id"`
+	OrderID        int64     `json:"order_id"`
+	Status         string    `json:"status"`
+	Substatus      string    `json:"substatus"`
+	LogisticType   string    `json:"logistic_type"`
+	DateCreated    time.Time `json:"date_created"`
+	ShippingOption struct {
+		Name                   string `json:"name"`
+		EstimatedHandlingLimit *struct {
+			Date time.Time `json:"date"`
+		} `json:"estimated_handling_limit"`
+	} `json:"shipping_option"`
+}
+
+// HandlingDeadline is when the seller must dispatch the package, if known.
+func (s *Shipment) HandlingDeadline() *time.Time {
+	if s.ShippingOption.EstimatedHandlingLimit == nil {
+		return nil
+	}
+	return &s.ShippingOption.EstimatedHandlingLimit.Date
+}
+
+// AwaitingDispatch reports whether the seller still has to ship the package.
+func (s *Shipment) AwaitingDispatch() bool {
+	switch s.Status {
+	case ShipmentStatusPending, ShipmentStatusHandling, ShipmentStatusReadyToShip:
+		return true
+	}
+	return false
+}
+
+// FulfillmentStock is `/inventories/{id}/stock/fulfillment`: the units of an
+// inventory held in Mercado Livre's warehouses.
+type FulfillmentStock struct {
+	InventoryID          string `json:"inventory_id"`
+	Total                int    `json:"total"`
+	AvailableQuantity    int    `json:"available_quantity"`
+	NotAvailableQuantity int    `json:"not_available_quantity"`
+	NotAvailableDetail   []struct {
+		Status   string `json:"status"`
+		Quantity int    `json:"quantity"`
+	} `json:"not_available_detail"`
+}
+
+// FulfillmentStatusTransfer is the not-available status of units on their
+// way into (or between) Mercado Livre's warehouses.
+const FulfillmentStatusTransfer = "transfer"
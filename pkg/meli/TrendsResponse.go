@@ -0,0 +1,8 @@
+package meli
+
+// TrendKeyword is a search term trending on Mercado Livre, as returned by
+// `/trends/{site}/{category}`. The list is ordered by popularity.
+type TrendKeyword struct {
+	Keyword string `json:"keyword"`
+	URL     string `json:"url"`
+}
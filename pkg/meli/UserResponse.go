@@ -0,0 +1,51 @@
+package meli
+
+// User is a subset of the `/users/{id}` and `/users/me` responses.
+type User struct {
+	ID               int64             `json:"id"`
+	Nickname         string            `json:"nickname"`
+	SiteID           string            `json:"site_id"`
+	Permalink        string            `json:"permalink"`
+	RegistrationDate string            `json:"registration_date,omitempty"`
+	SellerReputation *SellerReputation `json:"seller_reputation,omitempty"`
+}
+
+// SellerReputation is a seller's standing: level_id runs from "1_red" to
+// "5_green", and power_seller_status is "silver", "gold", "platinum" or
+// empty.
+type SellerReputation struct {
+	LevelID           string             `json:"level_id"`
+	PowerSellerStatus string             `json:"power_seller_status"`
+	Transactions      ReputationActivity `json:"transactions"`
+}
+
+// ReputationActivity counts a seller's sales and how buyers rated them.
+type ReputationActivity struct {
+	Period    string  `json:"period"`
+	Total     int     `json:"total"`
+	Completed int     `json:"completed"`
+	Canceled  int     `json:"canceled"`
+	Ratings   Ratings `json:"ratings"`
+}
+
+// Ratings are the shares (0-1) of positive, neutral and negative ratings.
+type Ratings struct {
+	Positive float64 `json:"positive"`
+	Neutral  float64 `json:"neutral"`
+	Negative float64 `json:"negative"`
+}
+
+type userItemsSearchResponse struct {
+	Results []string `json:"results"`
+	Paging  paging   `json:"paging"`
+}
+
+// TestUser is the response of `POST /users/test_user`. The password is only
+// returned once, at creation time.
+type TestUser struct {
+	ID         int64  `json:"id"`
+	Nickname   string `json:"nickname"`
+	Password   string `json:"password"`
+	SiteStatus string `json:"site_status"`
+	Email      string `json:"email"`
+}
@@ -0,0 +1,186 @@
+// Package apitest provides a fake Mercado Livre API for tests and local
+// development. It serves canned fixtures for the endpoints MeliClient and
+// OAuthClient use, and can be told to fail specific routes with 429 or 500.
+package apitest
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Response is a canned reply for a route.
+type Response struct {
+	Status int
+	Body   []byte
+	Header http.Header
+}
+
+// Request is a request received by the fake server.
+type Request struct {
+	Method        string
+	Path          string
+	Query         string
+	Authorization string
+}
+
+// Server is an httptest-based fake of api.mercadolibre.com.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	routes    map[string]Response
+	failures  map[string][]Response
+	requests  []Request
+	wantToken string
+}
+
+// defaultRoutes maps "METHOD /path" to the fixture file served for it.
+var defaultRoutes = map[string]string{
+	"GET /categories/MLB1055":                      "category_MLB1055.json",
+	"GET /categories/MLB1055/attributes":           "category_attributes_MLB1055.json",
+	"GET /highlights/MLB/category/MLB1055":         "highlights_MLB1055.json",
+	"GET /inventories/LCQI05831/stock/fulfillment": "fulfillment_stock_LCQI05831.json",
+	"GET /items":               "items_multiget.json",
+	"GET /items/MLB3456789012": "item_MLB3456789012.json",
+	"GET /items/MLB3456789012/catalog_listing_eligibility": "catalog_eligibility_MLB3456789012.json",
+	"GET /orders/2000001234567890":                         "order_2000001234567890.json",
+	"GET /orders/search":                                   "orders_search.json",
+	"GET /products/MLB19615317":                            "product_MLB19615317.json",
+	"GET /products/MLB19615317/items":                      "product_items_MLB19615317.json",
+	"GET /questions/9876543210":                            "question_9876543210.json",
+	"GET /questions/search":                                "questions_search.json",
+	"GET /shipments/41234567890":                           "shipment_41234567890.json",
+	"GET /shipments/41234567890/costs":                     "shipment_costs_41234567890.json",
+	"GET /sites/MLB/categories":                            "categories.json",
+	"GET /sites/MLB/category_predictor/predict":            "category_predictor.json",
+	"GET /sites/MLB/search":                                "search_MLB1055.json",
+	"GET /trends/MLB/MLB1055":                              "trends_MLB1055.json",
+	"GET /users/123456789":                                 "user_123456789.json",
+	"GET /users/123456789/items/search":                    "user_items_123456789.json",
+	"GET /users/me":                                        "users_me.json",
+	"POST /answers":                                        "answers.json",
+	"POST /oauth/token":                                    "oauth_token.json",
+	"POST /users/test_user":                                "test_user.json",
+}
+
+// NewServer starts a fake server preloaded with the default fixtures.
+// Callers must Close it.
+func NewServer() *Server {
+	s := &Server{
+		routes:   make(map[string]Response),
+		failures: make(map[string][]Response),
+	}
+
+	for route, file := range defaultRoutes {
+		body, err := fixtures.ReadFile("fixtures/" + file)
+		if err != nil {
+			panic(fmt.Sprintf("apitest: missing fixture %s: %v", file, err))
+		}
+		s.routes[route] = Response{Status: http.StatusOK, Body: body}
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// TokenURL is the fake OAuth token endpoint, for api.WithTokenURL.
+func (s *Server) TokenURL() string {
+	return s.URL + "/oauth/token"
+}
+
+// RequireToken makes every route except the OAuth token endpoint answer 401
+// unless the request carries "Bearer <token>".
+func (s *Server) RequireToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.wantToken = token
+}
+
+// Handle sets the canned response for a route, e.g. Handle("GET", "/items/MLB1", 200, body).
+func (s *Server) Handle(method, path string, status int, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[method+" "+path] = Response{Status: status, Body: body}
+}
+
+// FailNext makes the next n calls to the route answer with status before the
+// canned response is served again. 429 replies carry Retry-After: 1.
+func (s *Server) FailNext(method, path string, status, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := Response{
+		Status: status,
+		Body:   []byte(fmt.Sprintf(`{"message":"fake failure","error":%q,"status":%d}`, http.StatusText(status), status)),
+		Header: http.Header{},
+	}
+	if status == http.StatusTooManyRequests {
+		resp.Header.Set("Retry-After", "1")
+	}
+
+	key := method + " " + path
+	for i := 0; i < n; i++ {
+		s.failures[key] = append(s.failures[key], resp)
+	}
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Request, len(s.requests))
+	copy(out, s.requests)
+	return out
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method:        r.Method,
+		Path:          r.URL.Path,
+		Query:         r.URL.RawQuery,
+		Authorization: r.Header.Get("Authorization"),
+	})
+
+	key := r.Method + " " + r.URL.Path
+	resp, found := s.routes[key]
+	if queued := s.failures[key]; len(queued) > 0 {
+		resp, found = queued[0], true
+		s.failures[key] = queued[1:]
+	}
+	wantToken := s.wantToken
+	s.mu.Unlock()
+
+	if wantToken != "" && r.URL.Path != "/oauth/token" && r.Header.Get("Authorization") != "Bearer "+wantToken {
+		writeJSON(w, Response{
+			Status: http.StatusUnauthorized,
+			Body:   []byte(`{"message":"invalid access token","error":"unauthorized","status":401}`),
+		})
+		return
+	}
+
+	if !found {
+		writeJSON(w, Response{
+			Status: http.StatusNotFound,
+			Body:   []byte(fmt.Sprintf(`{"message":"resource %s not found","error":"not_found","status":404}`, strings.TrimPrefix(r.URL.Path, "/"))),
+		})
+		return
+	}
+	writeJSON(w, resp)
+}
+
+func writeJSON(w http.ResponseWriter, resp Response) {
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
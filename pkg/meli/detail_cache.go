@@ -0,0 +1,189 @@
+package meli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDetailTTL is how long a client trusts a cached listing or catalog
+// product unless WithDetailCache gives it a shared cache.
+const DefaultDetailTTL = 10 * time.Minute
+
+const (
+	// maxDetailEntries bounds the cache; past it, entries that can no
+	// longer be revalidated are dropped.
+	maxDetailEntries = 5000
+	// detailMaxAge is how long a stale listing is kept for revalidation.
+	detailMaxAge = 24 * time.Hour
+)
+
+// DetailCache is a read-through cache of `/items/{id}` and `/products/{id}`
+// bodies, so the same trend entries seen across snapshots are not fetched
+// again. Past its TTL, a listing is revalidated against its last_updated
+// with a light multiget and only fetched again when it changed; catalog
+// products have no such field and are simply fetched again. Share one
+// between clients with WithDetailCache.
+type DetailCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]detailEntry
+}
+
+type detailEntry struct {
+	body        []byte
+	lastUpdated string
+	checkedAt   time.Time
+}
+
+// NewDetailCache returns a cache whose entries are trusted for ttl.
+func NewDetailCache(ttl time.Duration) *DetailCache {
+	return &DetailCache{ttl: ttl, entries: make(map[string]detailEntry)}
+}
+
+// SetTTL changes how long entries are trusted, cached ones included.
+func (c *DetailCache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// get returns the cached body under key and whether it is still fresh.
+func (c *DetailCache) get(key string) (detailEntry, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return detailEntry{}, false, false
+	}
+	return e, true, time.Since(e.checkedAt) < c.ttl
+}
+
+func (c *DetailCache) set(key string, body []byte) {
+	var meta struct {
+		LastUpdated string `json:"last_updated"`
+	}
+	_ = json.Unmarshal(body, &meta)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= maxDetailEntries {
+		for k, e := range c.entries {
+			if now.Sub(e.checkedAt) > c.ttl && (e.lastUpdated == "" || now.Sub(e.checkedAt) > detailMaxAge) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = detailEntry{body: body, lastUpdated: meta.LastUpdated, checkedAt: now}
+}
+
+// touch marks a revalidated entry as fresh again.
+func (c *DetailCache) touch(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok {
+		e.checkedAt = time.Now()
+		c.entries[key] = e
+	}
+}
+
+func (c *DetailCache) drop(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// WithDetailCache shares a listing and catalog product cache between
+// clients, e.g. the per-request clients of the server.
+func WithDetailCache(cache *DetailCache) Option {
+	return func(c *MeliClient) {
+		c.details = cache
+	}
+}
+
+func detailKey(resource, id string) string {
+	return resource + "/" + id
+}
+
+// detailBody returns the body of `/{resource}/{id}` ("items" or "products")
+// from the cache, revalidating a stale listing first, or fetches it.
+func (c *MeliClient) detailBody(ctx context.Context, resource, id, what string) ([]byte, error) {
+	key := detailKey(resource, id)
+	if e, ok, fresh := c.details.get(key); ok {
+		if fresh {
+			return e.body, nil
+		}
+		if resource == "items" && e.lastUpdated != "" {
+			c.revalidateItems(ctx, []string{id})
+			if e, ok, fresh := c.details.get(key); ok && fresh {
+				return e.body, nil
+			}
+		}
+	}
+
+	body, err := c.getBody(ctx, fmt.Sprintf("%s/%s/%s", c.baseURL, resource, id), what)
+	if err != nil {
+		return nil, err
+	}
+	c.details.set(key, body)
+	return body, nil
+}
+
+// revalidateItems checks the stale cached listings among itemIDs against
+// their current last_updated, one multiget call per 20 listings: unchanged
+// ones are fresh again, changed or missing ones are dropped so the next
+// read fetches them. Failures leave the entries stale.
+func (c *MeliClient) revalidateItems(ctx context.Context, itemIDs []string) {
+	known := make(map[string]string)
+	var stale []string
+	for _, id := range itemIDs {
+		if e, ok, fresh := c.details.get(detailKey("items", id)); ok && !fresh && e.lastUpdated != "" {
+			if _, seen := known[id]; !seen {
+				stale = append(stale, id)
+			}
+			known[id] = e.lastUpdated
+		}
+	}
+
+	for start := 0; start < len(stale); start += multigetMaxIDs {
+		batch := stale[start:min(start+multigetMaxIDs, len(stale))]
+		endpoint := fmt.Sprintf("%s/items?ids=%s&attributes=id,last_updated", c.baseURL, url.QueryEscape(strings.Join(batch, ",")))
+		body, err := c.getBody(ctx, endpoint, "items revalidation")
+		if err != nil {
+			log.Printf("[WARN] Failed to revalidate %d cached listings: %v", len(batch), err)
+			return
+		}
+		var entries []struct {
+			Code int `json:"code"`
+			Body struct {
+				ID          string `json:"id"`
+				LastUpdated string `json:"last_updated"`
+			} `json:"body"`
+		}
+		if err := json.Unmarshal(body, &entries); err != nil {
+			log.Printf("[WARN] Failed to decode the revalidation of %d cached listings: %v", len(batch), err)
+			return
+		}
+		current := make(map[string]string, len(entries))
+		for _, e := range entries {
+			if e.Code == http.StatusOK {
+				current[e.Body.ID] = e.Body.LastUpdated
+			}
+		}
+		for _, id := range batch {
+			key := detailKey("items", id)
+			if lastUpdated, ok := current[id]; ok && lastUpdated == known[id] {
+				c.details.touch(key)
+			} else {
+				c.details.drop(key)
+			}
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package meli is a client for the Mercado Livre APIs: category rankings,
+// listings and catalog products, orders, shipments, questions, Full stock
+// and OAuth. It depends only on the standard library.
+//
+// A client for public endpoints needs no token:
+//
+//	c := meli.NewMeliClient("", "", meli.WithSiteID("MLA"))
+//	top, err := c.TopSoldByCategory(ctx, "MLA1055", 10)
+//
+// Seller endpoints need an access token. A TokenManager keeps the token pair
+// and, passed with WithTokenRefresher, refreshes it and retries once when a
+// call is answered with 401:
+//
+//	oauth := meli.NewOAuthClient(clientID, clientSecret, redirectURI)
+//	tokens := meli.NewTokenManager(oauth)
+//	tokens.Set(accessToken, refreshToken)
+//	c := meli.NewMeliClient(tokens.AccessToken(), clientID, meli.WithTokenRefresher(tokens))
+//
+// Clients sharing a Limiter (WithLimiter) bound their calls in flight and
+// per second together; calls of clients built WithPriority(PriorityLow)
+// wait behind interactive ones.
+//
+// Non-success responses are returned as *ErrUpstream, or *ErrRateLimited
+// for 429. Match ErrNotFound and ErrUnauthorized with errors.Is and read the
+// status or Retry-After with errors.As.
+//
+// Subpackage apitest serves canned responses for tests and vcr records and
+// replays real traffic.
+package meli
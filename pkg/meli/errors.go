@@ -0,0 +1,59 @@
+package meli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Sentinel errors for common upstream statuses. Match them with errors.Is;
+// the concrete error is an *ErrUpstream carrying the status and body.
+var (
+	ErrNotFound     = errors.New("meli: not found")
+	ErrUnauthorized = errors.New("meli: unauthorized")
+)
+
+// ErrUpstream is a non-success response from Mercado Livre.
+type ErrUpstream struct {
+	Op     string
+	Status int
+	Body   string
+}
+
+func (e *ErrUpstream) Error() string {
+	return fmt.Sprintf("meli %s: status=%d - %s", e.Op, e.Status, e.Body)
+}
+
+// Unwrap lets errors.Is match ErrNotFound and ErrUnauthorized.
+func (e *ErrUpstream) Unwrap() error {
+	switch e.Status {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// ErrRateLimited is a 429 response. RetryAfter is how long Mercado Livre
+// asked us to wait, or zero when it did not say.
+type ErrRateLimited struct {
+	Op         string
+	RetryAfter time.Duration
+	Body       string
+}
+
+func (e *ErrRateLimited) Error() string {
+	return fmt.Sprintf("meli %s: rate limited (retry after %s) - %s", e.Op, e.RetryAfter, e.Body)
+}
+
+// statusError reads resp's body and turns the response into a typed error.
+func statusError(resp *http.Response, op string) error {
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return &ErrRateLimited{Op: op, RetryAfter: retryAfter(resp.Header.Get("Retry-After"), 0), Body: string(body)}
+	}
+	return &ErrUpstream{Op: op, Status: resp.StatusCode, Body: string(body)}
+}
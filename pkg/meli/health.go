@@ -0,0 +1,35 @@
+package meli
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParseHealth reads a listing health into a 0-1 score. Mercado Livre
+// reports it as a fraction ("0.85"); percentages ("85%" or "85") are
+// accepted too. ok is false for missing or unreadable values.
+func ParseHealth(health string) (score float64, ok bool) {
+	s := strings.TrimSpace(health)
+	percent := strings.HasSuffix(s, "%")
+	s = strings.TrimSuffix(s, "%")
+	h, err := strconv.ParseFloat(s, 64)
+	if err != nil || h < 0 {
+		return 0, false
+	}
+	if percent || h > 1 {
+		h /= 100
+	}
+	if h > 1 {
+		return 0, false
+	}
+	return h, true
+}
+
+// formatHealth is the inverse of ParseHealth for the numeric health of
+// `/items/{id}`.
+func formatHealth(h *float64) string {
+	if h == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*h, 'f', -1, 64)
+}
@@ -0,0 +1,103 @@
+package meli
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultItemStatusTTL is how long a client remembers listing statuses
+// unless WithItemStatusCache gives it a shared cache.
+const DefaultItemStatusTTL = time.Minute
+
+// ItemStatusCache remembers the status of listings for a short time, so
+// best-price lookups don't validate the same winning listing on every
+// request. Share one between clients with WithItemStatusCache.
+type ItemStatusCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]itemStatus
+}
+
+type itemStatus struct {
+	status    string
+	expiresAt time.Time
+}
+
+// NewItemStatusCache returns a cache whose entries expire after ttl.
+func NewItemStatusCache(ttl time.Duration) *ItemStatusCache {
+	return &ItemStatusCache{ttl: ttl, entries: make(map[string]itemStatus)}
+}
+
+// SetTTL changes the TTL of the statuses stored from now on.
+func (c *ItemStatusCache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+func (c *ItemStatusCache) get(itemID string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[itemID]
+	if !ok || time.Now().After(e.expiresAt) {
+		delete(c.entries, itemID)
+		return "", false
+	}
+	return e.status, true
+}
+
+func (c *ItemStatusCache) set(itemID, status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	// Drop expired entries now and then so the map stays small
+	if len(c.entries) >= 1000 {
+		for id, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, id)
+			}
+		}
+	}
+	c.entries[itemID] = itemStatus{status: status, expiresAt: now.Add(c.ttl)}
+}
+
+// WithItemStatusCache shares a listing status cache between clients, e.g.
+// the per-request clients of the server.
+func WithItemStatusCache(cache *ItemStatusCache) Option {
+	return func(c *MeliClient) {
+		c.itemStatuses = cache
+	}
+}
+
+// itemStatusesOf returns the status of each listing, from the cache or one
+// multiget call per 20 listings. Listings that could not be fetched are
+// left out; callers treat them as unknown.
+func (c *MeliClient) itemStatusesOf(ctx context.Context, itemIDs []string) map[string]string {
+	statuses := make(map[string]string, len(itemIDs))
+	var missing []string
+	for _, id := range itemIDs {
+		if status, ok := c.itemStatuses.get(id); ok {
+			statuses[id] = status
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return statuses
+	}
+
+	ctx, cancel := c.endpointContext(ctx, EndpointDetail)
+	defer cancel()
+
+	items, err := c.GetItems(ctx, missing)
+	if err != nil {
+		log.Printf("[WARN] Failed to fetch the status of %d listings: %v", len(missing), err)
+	}
+	for _, item := range items {
+		statuses[item.ID] = item.Status
+		c.itemStatuses.set(item.ID, item.Status)
+	}
+	return statuses
+}
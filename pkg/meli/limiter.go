@@ -0,0 +1,212 @@
+package meli
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Priority orders outbound calls waiting on a shared Limiter.
+type Priority int
+
+const (
+	// PriorityHigh is for interactive requests, the default.
+	PriorityHigh Priority = iota
+	// PriorityLow is for scheduled tasks, crawls and other background work.
+	PriorityLow
+)
+
+// LimiterStats reports the calls a Limiter holds.
+type LimiterStats struct {
+	InFlight    int `json:"in_flight"`
+	WaitingHigh int `json:"waiting_high"`
+	WaitingLow  int `json:"waiting_low"`
+}
+
+// Limiter bounds the outbound calls of every client sharing it: at most
+// concurrency in flight and, with a positive rate, that many started per
+// second. Waiting calls are served high priority first, in arrival order
+// within a priority, so background work never delays interactive requests
+// by more than the calls already in flight. Share one with WithLimiter.
+type Limiter struct {
+	interval    time.Duration
+	concurrency int
+
+	mu       sync.Mutex
+	next     time.Time
+	inFlight int
+	waiting  [PriorityLow + 1][]*limiterWaiter
+	timer    *time.Timer
+}
+
+type limiterWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewLimiter returns a limiter allowing concurrency calls in flight (zero
+// is unbounded) started at up to rate per second (zero is unbounded).
+func NewLimiter(rate float64, concurrency int) *Limiter {
+	l := &Limiter{}
+	l.setLimits(rate, concurrency)
+	return l
+}
+
+// SetLimits changes the rate and concurrency, as in NewLimiter. Calls in
+// flight are not interrupted; waiting calls are served under the new limits.
+func (l *Limiter) SetLimits(rate float64, concurrency int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.setLimits(rate, concurrency)
+	l.dispatch()
+}
+
+func (l *Limiter) setLimits(rate float64, concurrency int) {
+	l.concurrency = concurrency
+	l.interval = 0
+	if rate > 0 {
+		l.interval = time.Duration(float64(time.Second) / rate)
+	}
+}
+
+// Stats returns the calls currently in flight and waiting.
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return LimiterStats{
+		InFlight:    l.inFlight,
+		WaitingHigh: len(l.waiting[PriorityHigh]),
+		WaitingLow:  len(l.waiting[PriorityLow]),
+	}
+}
+
+// acquire waits for a slot for a call of priority p, or until ctx is done.
+// Each successful acquire must be followed by a release.
+func (l *Limiter) acquire(ctx context.Context, p Priority) error {
+	l.mu.Lock()
+	if l.queued(p) == 0 && l.available(time.Now()) {
+		l.grant(time.Now())
+		l.mu.Unlock()
+		return nil
+	}
+	w := &limiterWaiter{ready: make(chan struct{})}
+	l.waiting[p] = append(l.waiting[p], w)
+	l.dispatch()
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if w.granted {
+			l.inFlight--
+			l.dispatch()
+			return ctx.Err()
+		}
+		for i, other := range l.waiting[p] {
+			if other == w {
+				l.waiting[p] = append(l.waiting[p][:i], l.waiting[p][i+1:]...)
+				break
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	l.dispatch()
+}
+
+// queued counts the calls waiting with priority p or higher.
+func (l *Limiter) queued(p Priority) int {
+	n := 0
+	for q := PriorityHigh; q <= p; q++ {
+		n += len(l.waiting[q])
+	}
+	return n
+}
+
+// available reports whether a call may start now. l.mu must be held.
+func (l *Limiter) available(now time.Time) bool {
+	if l.concurrency > 0 && l.inFlight >= l.concurrency {
+		return false
+	}
+	return !now.Before(l.next)
+}
+
+// grant takes a slot. l.mu must be held.
+func (l *Limiter) grant(now time.Time) {
+	l.inFlight++
+	if l.interval > 0 {
+		l.next = now.Add(l.interval)
+	}
+}
+
+// dispatch hands free slots to waiting calls, highest priority first, and
+// arms a timer when the rate is what holds them back. l.mu must be held.
+func (l *Limiter) dispatch() {
+	for {
+		p := PriorityHigh
+		for p <= PriorityLow && len(l.waiting[p]) == 0 {
+			p++
+		}
+		if p > PriorityLow {
+			return
+		}
+		now := time.Now()
+		if !l.available(now) {
+			if (l.concurrency <= 0 || l.inFlight < l.concurrency) && l.timer == nil {
+				l.timer = time.AfterFunc(l.next.Sub(now), func() {
+					l.mu.Lock()
+					defer l.mu.Unlock()
+					l.timer = nil
+					l.dispatch()
+				})
+			}
+			return
+		}
+		w := l.waiting[p][0]
+		l.waiting[p] = l.waiting[p][1:]
+		l.grant(now)
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+// WithLimiter makes the client's calls wait for a slot of a limiter shared
+// with other clients.
+func WithLimiter(l *Limiter) Option {
+	return func(c *MeliClient) {
+		c.limiter = l
+	}
+}
+
+// WithPriority sets the priority of the client's calls on its limiter;
+// clients default to PriorityHigh.
+func WithPriority(p Priority) Option {
+	return func(c *MeliClient) {
+		c.priority = p
+	}
+}
+
+// limitedTransport holds every round trip until its limiter has a slot for
+// it. The slot is released once the response headers arrive.
+type limitedTransport struct {
+	next     http.RoundTripper
+	limiter  *Limiter
+	priority Priority
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.acquire(req.Context(), t.priority); err != nil {
+		return nil, err
+	}
+	defer t.limiter.release()
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,1429 @@
+package meli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBaseURL     = "https://api.mercadolibre.com"
+	defaultPaymentsURL = "https://api.mercadopago.com"
+	defaultSiteID      = "MLB"
+	defaultHTTPTimeout = 10 * time.Second
+)
+
+// MeliClient is a small HTTP client to talk to Mercado Livre public APIs.
+type MeliClient struct {
+	httpClient  *http.Client
+	baseURL     string
+	paymentsURL string
+	siteID      string
+	accessToken string
+	clientID    string
+	// endpointTimeouts bound individual calls below the client-wide timeout.
+	endpointTimeouts map[string]time.Duration
+	refresher        TokenRefresher
+	itemStatuses     *ItemStatusCache
+	details          *DetailCache
+	limiter          *Limiter
+	priority         Priority
+}
+
+// Option customizes a MeliClient.
+type Option func(*MeliClient)
+
+// WithBaseURL points the client at another API host, e.g. a fake server in tests.
+func WithBaseURL(baseURL string) Option {
+	return func(c *MeliClient) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithPaymentsBaseURL points the Mercado Pago calls at another API host.
+func WithPaymentsBaseURL(baseURL string) Option {
+	return func(c *MeliClient) {
+		c.paymentsURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithSiteID selects the Mercado Livre site (country), e.g. "MLA" or "MLM".
+func WithSiteID(siteID string) Option {
+	return func(c *MeliClient) {
+		c.siteID = siteID
+	}
+}
+
+// WithTimeout sets the overall timeout of each outbound request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *MeliClient) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithHTTPClient uses a copy of hc for outbound requests. Its transport is
+// wrapped so upstream metrics keep being collected.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *MeliClient) {
+		clone := *hc
+		next := clone.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		clone.Transport = &instrumentedTransport{next: next}
+		c.httpClient = &clone
+	}
+}
+
+// WithEndpointTimeout bounds calls to one endpoint group (see the Endpoint
+// constants). A zero duration removes the bound.
+func WithEndpointTimeout(endpoint string, timeout time.Duration) Option {
+	return func(c *MeliClient) {
+		if timeout <= 0 {
+			delete(c.endpointTimeouts, endpoint)
+			return
+		}
+		c.endpointTimeouts[endpoint] = timeout
+	}
+}
+
+// WithTransport sets the transport used for outbound requests (e.g. a VCR
+// recorder or a proxy). Upstream metrics keep being collected.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *MeliClient) {
+		c.httpClient.Transport = &instrumentedTransport{next: rt}
+	}
+}
+
+// WithTokenRefresher makes the client refresh its token and retry once when
+// a call is answered with 401.
+func WithTokenRefresher(r TokenRefresher) Option {
+	return func(c *MeliClient) {
+		c.refresher = r
+	}
+}
+
+// NewMeliClient returns a client authenticated with accessToken. An empty
+// token is fine for the public endpoints.
+func NewMeliClient(accessToken string, clientID string, opts ...Option) *MeliClient {
+	c := &MeliClient{
+		httpClient: &http.Client{
+			Timeout:   defaultHTTPTimeout,
+			Transport: &instrumentedTransport{next: http.DefaultTransport},
+		},
+		baseURL:     defaultBaseURL,
+		paymentsURL: defaultPaymentsURL,
+		siteID:      defaultSiteID,
+		accessToken: accessToken,
+		clientID:    clientID,
+		endpointTimeouts: map[string]time.Duration{
+			EndpointDetail:       4 * time.Second,
+			EndpointProductItems: 6 * time.Second,
+		},
+		itemStatuses: NewItemStatusCache(DefaultItemStatusTTL),
+		details:      NewDetailCache(DefaultDetailTTL),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.limiter != nil {
+		hc := *c.httpClient
+		hc.Transport = &limitedTransport{next: hc.Transport, limiter: c.limiter, priority: c.priority}
+		c.httpClient = &hc
+	}
+	if c.refresher != nil {
+		hc := *c.httpClient
+		hc.Transport = &refreshTransport{next: hc.Transport, refresher: c.refresher}
+		c.httpClient = &hc
+	}
+	return c
+}
+
+// SearchItem represents a subset of fields from the search API.
+type SearchItem struct {
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	Price        float64  `json:"price"`
+	Thumbnail    string   `json:"thumbnail"`
+	SoldQuantity int      `json:"sold_quantity"`
+	Health       string   `json:"health"`
+	HealthScore  *float64 `json:"health_score"` // Health as a 0-1 number, see ParseHealth
+	CategoryID   string   `json:"category_id"`
+	Permalink    string   `json:"permalink"`
+	Status       string   `json:"status"`
+	Errors       []string `json:"errors,omitempty"`     // falhas ao enriquecer este item (detalhe, preço)
+	LinkVenda    string   `json:"link_venda,omitempty"` // campo extra para link de venda (pode ser o mesmo que Permalink ou diferente se quisermos usar um link de afiliado)
+	// CatalogProductID is the catalog product a listing belongs to, or the
+	// ID itself for catalog products.
+	CatalogProductID string `json:"catalog_product_id,omitempty"`
+	// MergedIDs lists the highlights folded into this one because they are
+	// the same catalog product.
+	MergedIDs []string `json:"merged_ids,omitempty"`
+}
+
+type searchResponse struct {
+	Results []SearchItem `json:"results"`
+}
+
+// ProductPrice holds the best price and details for a product item.
+type ProductPrice struct {
+	Price     float64
+	ItemID    string
+	Title     string
+	Permalink string // May be empty; use ItemID to search for item page
+}
+
+// Category represents a Mercado Livre category.
+type Category struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// CategoryPrediction is a simplified view of category predictor output.
+type CategoryPrediction struct {
+	ID   string  `json:"id"`
+	Name string  `json:"name"`
+	Prob float64 `json:"prediction_probability"`
+}
+
+type categoryPredictorResponse struct {
+	Predictions []CategoryPrediction `json:"predictions"`
+}
+
+// TopSoldResult is the outcome of TopSoldByCategory.
+type TopSoldResult struct {
+	Items []SearchItem
+	// Total is the number of highlights Mercado Livre returned.
+	Total int
+	// Partial is set when some highlight could not be fully enriched (see
+	// each item's Errors) or the caller's deadline ran out before every
+	// highlight was processed.
+	Partial bool
+}
+
+// TopSoldOptions trims the enrichment done by TopSoldByCategoryWith.
+type TopSoldOptions struct {
+	// SkipBestPrice leaves catalog products without Price and LinkVenda,
+	// saving one best-price lookup per product.
+	SkipBestPrice bool
+}
+
+// TopSoldByCategory fetches the top N sold products for a given category.
+// This endpoint now requires authentication due to PolicyAgent restrictions.
+// If ctx carries a deadline, enrichment stops when it is reached and the
+// items collected so far are returned as a partial result.
+func (c *MeliClient) TopSoldByCategory(ctx context.Context, categoryID string, limit int) (*TopSoldResult, error) {
+	return c.TopSoldByCategoryWith(ctx, categoryID, limit, TopSoldOptions{})
+}
+
+// TopSoldByCategoryWith is TopSoldByCategory with some enrichment skipped.
+func (c *MeliClient) TopSoldByCategoryWith(ctx context.Context, categoryID string, limit int, opts TopSoldOptions) (*TopSoldResult, error) {
+	endpoint := fmt.Sprintf("%s/highlights/%s/category/%s", c.baseURL, c.siteID, categoryID)
+
+	reqCtx, cancel := c.endpointContext(ctx, EndpointHighlights)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Debug: log token status
+	if c.accessToken == "" {
+		log.Println("[DEBUG] Warning: accessToken is empty for TopSoldByCategory")
+	} else {
+		// Set headers
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+		req.Header.Set("Referer", "https://www.mercadolivre.com.br/")
+
+		// Add Authorization header if token is available
+		if c.accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+c.accessToken)
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, "search")
+	}
+
+	var highlights HighlightResponse
+	if err := json.NewDecoder(resp.Body).Decode(&highlights); err != nil {
+		return nil, err
+	}
+
+	result := &TopSoldResult{
+		Items: make([]SearchItem, 0, len(highlights.Content)),
+		Total: len(highlights.Content),
+	}
+
+	// Stale cached listings are revalidated in batches instead of one by one
+	var listingIDs []string
+	for _, highlight := range highlights.Content {
+		if highlight.Type != "PRODUCT" {
+			listingIDs = append(listingIDs, highlight.ID)
+		}
+	}
+	c.revalidateItems(ctx, listingIDs)
+
+	// Indexes of the catalog products in result.Items, priced in one batch
+	var products []int
+	for i, highlight := range highlights.Content {
+		if ctx.Err() != nil {
+			log.Printf("[WARN] Deadline budget exhausted for category %s after %d of %d highlights", categoryID, i, len(highlights.Content))
+			result.Partial = true
+			break
+		}
+
+		item, err := c.GetHighlightDetail(ctx, highlight.ID, highlight.Type)
+		if err != nil {
+			log.Printf("[ERROR] Failed to get detail for highlight %s: %v", highlight.ID, err)
+			result.Items = append(result.Items, SearchItem{
+				ID:     highlight.ID,
+				Errors: []string{"detail: " + err.Error()},
+			})
+			result.Partial = true
+			continue
+		}
+
+		if highlight.Type == "PRODUCT" {
+			if !opts.SkipBestPrice {
+				products = append(products, len(result.Items))
+			}
+		} else if item.Status != "" {
+			// Listings ranked next to a product are often its best offer
+			c.itemStatuses.set(item.ID, item.Status)
+		}
+		result.Items = append(result.Items, *item)
+	}
+
+	// Catalog products have no price of their own: use the cheapest active
+	// listing. Individual items already carry their price.
+	if len(products) == 0 {
+		return result, nil
+	}
+	ids := make([]string, len(products))
+	for i, idx := range products {
+		ids[i] = result.Items[idx].ID
+	}
+	prices := c.GetProductBestPrices(ctx, ids)
+	for _, idx := range products {
+		item := &result.Items[idx]
+		r := prices[item.ID]
+		if r.Err != nil {
+			log.Printf("[ERROR] Failed to get best price for item %s: %v", item.ID, r.Err)
+			item.Errors = append(item.Errors, "price: "+r.Err.Error())
+			result.Partial = true
+			continue
+		}
+		item.Price = r.Price.Price
+		item.LinkVenda = r.Price.Permalink
+	}
+
+	return result, nil
+}
+
+// GetHighlightDetail fetches one entry of a highlights list, a catalog
+// product when highlightType is "PRODUCT" and a listing otherwise, read
+// through the detail cache.
+func (c *MeliClient) GetHighlightDetail(ctx context.Context, highlightID string, highlightType string) (*SearchItem, error) {
+	resource := "items"
+	if highlightType == "PRODUCT" {
+		resource = "products"
+	}
+
+	ctx, cancel := c.endpointContext(ctx, EndpointDetail)
+	defer cancel()
+
+	bodyBytes, err := c.detailBody(ctx, resource, highlightID, highlightType)
+	if err != nil {
+		return nil, err
+	}
+
+	if highlightType == "PRODUCT" {
+		var product Product
+		if err := json.Unmarshal(bodyBytes, &product); err != nil {
+			return nil, fmt.Errorf("json decode product: %w - body: %s", err, string(bodyBytes))
+		}
+		return mapProductToSearchItem(product), nil
+	} else {
+		var item Item
+		if err := json.Unmarshal(bodyBytes, &item); err != nil {
+			return nil, fmt.Errorf("json decode item: %w - body: %s", err, string(bodyBytes))
+		}
+		return mapItemToSearchItem(item), nil
+	}
+}
+
+func (c *MeliClient) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Headers básicos (sempre presentes)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+	req.Header.Set("Referer", "https://www.mercadolivre.com.br/")
+
+	// Se tiver token, adiciona Authorization
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	}
+
+	return req, nil
+}
+
+// RootCategories returns the main categories for the site.
+// This endpoint now requires authentication due to PolicyAgent restrictions.
+func (c *MeliClient) RootCategories(ctx context.Context) ([]Category, error) {
+	endpoint := fmt.Sprintf("%s/sites/%s/categories", c.baseURL, c.siteID)
+
+	ctx, cancel := c.endpointContext(ctx, EndpointCategories)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set headers
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+	req.Header.Set("Referer", "https://www.mercadolivre.com.br/")
+
+	// Add Authorization header if token is available
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, "categories")
+	}
+
+	var cats []Category
+	if err := json.NewDecoder(resp.Body).Decode(&cats); err != nil {
+		return nil, err
+	}
+	return cats, nil
+}
+
+// GetCategory returns a category with its path from the root category and
+// its listing settings.
+func (c *MeliClient) GetCategory(ctx context.Context, categoryID string) (*CategoryDetail, error) {
+	endpoint := fmt.Sprintf("%s/categories/%s", c.baseURL, categoryID)
+
+	ctx, cancel := c.endpointContext(ctx, EndpointCategories)
+	defer cancel()
+
+	body, err := c.getBody(ctx, endpoint, "category")
+	if err != nil {
+		return nil, err
+	}
+	var cat CategoryDetail
+	if err := json.Unmarshal(body, &cat); err != nil {
+		return nil, err
+	}
+	return &cat, nil
+}
+
+// GetCategoryAttributes returns the attributes listings of a category can
+// carry, required ones included.
+func (c *MeliClient) GetCategoryAttributes(ctx context.Context, categoryID string) ([]CategoryAttribute, error) {
+	endpoint := fmt.Sprintf("%s/categories/%s/attributes", c.baseURL, categoryID)
+
+	ctx, cancel := c.endpointContext(ctx, EndpointCategories)
+	defer cancel()
+
+	body, err := c.getBody(ctx, endpoint, "category attributes")
+	if err != nil {
+		return nil, err
+	}
+	var attrs []CategoryAttribute
+	if err := json.Unmarshal(body, &attrs); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// PredictCategory suggests categories for a free-text query using Mercado Livre's
+// category predictor API. This endpoint may require authentication.
+func (c *MeliClient) PredictCategory(ctx context.Context, query string) ([]CategoryPrediction, error) {
+	endpoint := fmt.Sprintf("%s/sites/%s/category_predictor/predict", c.baseURL, c.siteID)
+
+	q := url.Values{}
+	q.Set("q", query)
+
+	ctx, cancel := c.endpointContext(ctx, EndpointPredictor)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set headers
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Language", "pt-BR,pt;q=0.9")
+	req.Header.Set("Referer", "https://www.mercadolivre.com.br/")
+
+	// Add Authorization header if token is available
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, "category predictor")
+	}
+
+	var pr categoryPredictorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&pr); err != nil {
+		return nil, err
+	}
+	return pr.Predictions, nil
+}
+
+func (c *MeliClient) applyAuth(req *http.Request) {
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	}
+}
+
+func mapProductToSearchItem(p Product) *SearchItem {
+	return &SearchItem{
+		ID:               p.ID,
+		Title:            p.Name,
+		CategoryID:       p.DomainID,
+		Price:            0, // precisa buscar em /products/{id}/items
+		Thumbnail:        firstProductPicture(p.Pictures),
+		Permalink:        p.Permalink,
+		Status:           p.Status,
+		CatalogProductID: p.ID,
+	}
+}
+
+func mapItemToSearchItem(i Item) *SearchItem {
+	return &SearchItem{
+		ID:               i.ID,
+		Title:            i.Title,
+		CategoryID:       i.CategoryID,
+		Price:            i.Price,
+		Thumbnail:        i.Thumbnail,
+		Permalink:        i.Permalink,
+		Status:           i.Status,
+		Health:           formatHealth(i.Health),
+		CatalogProductID: i.CatalogID,
+	}
+}
+
+func firstProductPicture(pics []ProductPicture) string {
+	if len(pics) > 0 {
+		return pics[0].URL
+	}
+	return ""
+}
+
+// GetProductBestPrice fetches `/products/{id}/items` and returns the lowest
+// item price for the given product.
+func (c *MeliClient) GetProductBestPrice(ctx context.Context, productID string) (float64, error) {
+	tr := startTrace(ctx, productID)
+	defer tr.finish()
+
+	best, err := c.bestProductOffer(withTracer(ctx, tr), productID)
+	if err != nil {
+		return 0, err
+	}
+	return best.Price, nil
+}
+
+// bestProductOffer walks every page of `/products/{id}/items` and returns the
+// cheapest active offer.
+func (c *MeliClient) bestProductOffer(ctx context.Context, productID string) (*listedItem, error) {
+	endpoint := fmt.Sprintf("%s/products/%s/items", c.baseURL, productID)
+	tr := tracerFrom(ctx)
+
+	ctx, cancel := c.endpointContext(ctx, EndpointProductItems)
+	defer cancel()
+
+	var best *listedItem
+	err := c.fetchPages(ctx, endpoint, "product items", func(body []byte) (paging, error) {
+		items, p, err := decodeProductItems(body)
+		if err != nil {
+			return paging{}, err
+		}
+		if p.Limit > 0 {
+			tr.Logf("Paged response: total=%d, offset=%d, limit=%d, results=%d", p.Total, p.Offset, p.Limit, len(items))
+		}
+		for i := range items {
+			it := items[i]
+			if it.Price <= 0 || !it.Active {
+				tr.Logf("Skipping item %s: price=%.2f, active=%t", it.ID, it.Price, it.Active)
+				continue
+			}
+			tr.Logf("Found item: ID=%s, Price=%.2f, Condition=%s", it.ID, it.Price, it.Condition)
+			if best == nil || it.Price < best.Price {
+				best = &it
+				tr.Logf("New best price: %.2f from item %s", it.Price, it.ID)
+			}
+		}
+		return p, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no active items with price for product %s", productID)
+	}
+	return best, nil
+}
+
+// GetProductBestPriceWithLink fetches `/products/{id}/items` and returns the
+// lowest price item with its link/URL, after checking the item is still active.
+func (c *MeliClient) GetProductBestPriceWithLink(ctx context.Context, productID string) (*ProductPrice, error) {
+	r := c.GetProductBestPrices(ctx, []string{productID})[productID]
+	return r.Price, r.Err
+}
+
+// BestPriceResult is the outcome of one product of GetProductBestPrices.
+type BestPriceResult struct {
+	Price *ProductPrice
+	Err   error
+}
+
+// GetProductBestPrices finds the cheapest active offer of each product, like
+// GetProductBestPriceWithLink, but checks that the winning listings are
+// still active with one multiget call per 20 products instead of one call
+// each. Recently seen statuses are not fetched again.
+func (c *MeliClient) GetProductBestPrices(ctx context.Context, productIDs []string) map[string]BestPriceResult {
+	out := make(map[string]BestPriceResult, len(productIDs))
+	tracers := make(map[string]*tracer, len(productIDs))
+	defer func() {
+		for _, tr := range tracers {
+			tr.finish()
+		}
+	}()
+
+	winners := make([]string, 0, len(productIDs))
+	for _, productID := range productIDs {
+		if err := ctx.Err(); err != nil {
+			out[productID] = BestPriceResult{Err: err}
+			continue
+		}
+		tr := startTrace(ctx, productID)
+		tracers[productID] = tr
+
+		best, err := c.bestProductOffer(withTracer(ctx, tr), productID)
+		if err != nil {
+			out[productID] = BestPriceResult{Err: err}
+			continue
+		}
+		out[productID] = BestPriceResult{Price: &ProductPrice{
+			Price:     best.Price,
+			ItemID:    best.ID,
+			Title:     best.Title,
+			Permalink: best.Permalink,
+		}}
+		tr.Logf("Before validation: Price=%.2f, ItemID=%s", best.Price, best.ID)
+		if best.ID != "" {
+			winners = append(winners, best.ID)
+		}
+	}
+	if len(winners) == 0 {
+		return out
+	}
+
+	// Validate that the best price items are actually active on Mercado
+	// Livre. A listing whose status could not be fetched is given the
+	// benefit of the doubt.
+	statuses := c.itemStatusesOf(ctx, winners)
+	for productID, r := range out {
+		if r.Price == nil {
+			continue
+		}
+		tr := tracers[productID]
+		itemID := r.Price.ItemID
+		if status, ok := statuses[itemID]; ok {
+			if status != "active" {
+				tr.Logf("Item %s is NOT active (status=%s), rejecting", itemID, status)
+				// Item is not active, return error - we don't have a valid backup
+				out[productID] = BestPriceResult{Err: fmt.Errorf("best price item %s is not active (status=%s)", itemID, status)}
+				continue
+			}
+			tr.Logf("Item %s validated as ACTIVE", itemID)
+		}
+		tr.Logf("FINAL RESULT: Price=%.2f, ItemID=%s", r.Price.Price, itemID)
+	}
+	return out
+}
+
+// GetItem returns a listing with its attributes, read through the detail
+// cache.
+func (c *MeliClient) GetItem(ctx context.Context, itemID string) (*Item, error) {
+	ctx, cancel := c.endpointContext(ctx, EndpointDetail)
+	defer cancel()
+
+	body, err := c.detailBody(ctx, "items", itemID, "item")
+	if err != nil {
+		return nil, err
+	}
+	var item Item
+	if err := json.Unmarshal(body, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// multigetMaxIDs is the most IDs `/items?ids=` accepts per call.
+const multigetMaxIDs = 20
+
+// GetItems fetches listings in batches through the multiget endpoint.
+// Listings that no longer exist are left out.
+func (c *MeliClient) GetItems(ctx context.Context, itemIDs []string) ([]Item, error) {
+	items := make([]Item, 0, len(itemIDs))
+	for start := 0; start < len(itemIDs); start += multigetMaxIDs {
+		batch := itemIDs[start:min(start+multigetMaxIDs, len(itemIDs))]
+		endpoint := fmt.Sprintf("%s/items?ids=%s", c.baseURL, url.QueryEscape(strings.Join(batch, ",")))
+
+		body, err := c.getBody(ctx, endpoint, "items multiget")
+		if err != nil {
+			return nil, err
+		}
+		var entries []multigetItem
+		if err := json.Unmarshal(body, &entries); err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.Code == http.StatusOK {
+				items = append(items, e.Body)
+			}
+		}
+	}
+	return items, nil
+}
+
+// SearchOrders lists a seller's orders updated since a point in time,
+// following `/orders/search` pagination.
+func (c *MeliClient) SearchOrders(ctx context.Context, sellerID int64, updatedSince time.Time) ([]Order, error) {
+	q := url.Values{}
+	q.Set("seller", strconv.FormatInt(sellerID, 10))
+	q.Set("order.date_last_updated.from", updatedSince.UTC().Format("2006-01-02T15:04:05.000-07:00"))
+	q.Set("sort", "date_asc")
+	q.Set("offset", "0")
+	q.Set("limit", "50")
+	endpoint := fmt.Sprintf("%s/orders/search?%s", c.baseURL, q.Encode())
+
+	orders := make([]Order, 0)
+	err := c.fetchPages(ctx, endpoint, "orders search", func(body []byte) (paging, error) {
+		var page ordersSearchResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return paging{}, err
+		}
+		orders = append(orders, page.Results...)
+		if len(page.Results) == 0 {
+			return paging{}, nil
+		}
+		return page.Paging, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// SearchClaims lists the claims against a seller updated since a point in
+// time, following `/post-purchase/v1/claims/search` pagination.
+func (c *MeliClient) SearchClaims(ctx context.Context, sellerID int64, updatedSince time.Time) ([]Claim, error) {
+	q := url.Values{}
+	q.Set("player_role", "respondent")
+	q.Set("player_user_id", strconv.FormatInt(sellerID, 10))
+	q.Set("range", "last_updated:after:"+updatedSince.UTC().Format("2006-01-02T15:04:05.000-07:00"))
+	q.Set("sort", "last_updated:asc")
+	q.Set("offset", "0")
+	q.Set("limit", "30")
+	endpoint := fmt.Sprintf("%s/post-purchase/v1/claims/search?%s", c.baseURL, q.Encode())
+
+	claims := make([]Claim, 0)
+	err := c.fetchPages(ctx, endpoint, "claims search", func(body []byte) (paging, error) {
+		var page claimsSearchResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return paging{}, err
+		}
+		claims = append(claims, page.Data...)
+		if len(page.Data) == 0 {
+			return paging{}, nil
+		}
+		return page.Paging, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// SearchPayments lists the Mercado Pago payments collected by a seller
+// updated since a point in time, following `/v1/payments/search`
+// pagination. Mercado Livre tokens are accepted by Mercado Pago.
+func (c *MeliClient) SearchPayments(ctx context.Context, collectorID int64, updatedSince time.Time) ([]Payment, error) {
+	q := url.Values{}
+	q.Set("collector.id", strconv.FormatInt(collectorID, 10))
+	q.Set("range", "date_last_updated")
+	q.Set("begin_date", updatedSince.UTC().Format("2006-01-02T15:04:05.000Z"))
+	q.Set("end_date", "NOW")
+	q.Set("sort", "date_last_updated")
+	q.Set("criteria", "asc")
+	q.Set("offset", "0")
+	q.Set("limit", "50")
+	endpoint := fmt.Sprintf("%s/v1/payments/search?%s", c.paymentsURL, q.Encode())
+
+	payments := make([]Payment, 0)
+	err := c.fetchPages(ctx, endpoint, "payments search", func(body []byte) (paging, error) {
+		var page paymentsSearchResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return paging{}, err
+		}
+		payments = append(payments, page.Results...)
+		if len(page.Results) == 0 {
+			return paging{}, nil
+		}
+		return page.Paging, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// GetClaim returns a claim against the seller.
+func (c *MeliClient) GetClaim(ctx context.Context, claimID int64) (*Claim, error) {
+	endpoint := fmt.Sprintf("%s/post-purchase/v1/claims/%d", c.baseURL, claimID)
+
+	ctx, cancel := c.endpointContext(ctx, EndpointDetail)
+	defer cancel()
+
+	body, err := c.getBody(ctx, endpoint, "claim")
+	if err != nil {
+		return nil, err
+	}
+	var claim Claim
+	if err := json.Unmarshal(body, &claim); err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+// GetItemVisits returns the visits a listing had in [from, to). Mercado
+// Livre counts visits per day, and the last day may still be incomplete.
+func (c *MeliClient) GetItemVisits(ctx context.Context, itemID string, from, to time.Time) (int, error) {
+	q := url.Values{}
+	q.Set("ids", itemID)
+	q.Set("date_from", from.UTC().Format("2006-01-02T15:04:05.000-07:00"))
+	q.Set("date_to", to.UTC().Format("2006-01-02T15:04:05.000-07:00"))
+	endpoint := fmt.Sprintf("%s/items/visits?%s", c.baseURL, q.Encode())
+
+	ctx, cancel := c.endpointContext(ctx, EndpointDetail)
+	defer cancel()
+
+	body, err := c.getBody(ctx, endpoint, "item visits")
+	if err != nil {
+		return 0, err
+	}
+	var visits []ItemVisits
+	if err := json.Unmarshal(body, &visits); err != nil {
+		return 0, err
+	}
+	for _, v := range visits {
+		if v.ItemID == itemID {
+			return v.TotalVisits, nil
+		}
+	}
+	return 0, nil
+}
+
+// GetShipmentCosts returns the cost split of a shipment.
+func (c *MeliClient) GetShipmentCosts(ctx context.Context, shipmentID int64) (*ShipmentCosts, error) {
+	endpoint := fmt.Sprintf("%s/shipments/%d/costs", c.baseURL, shipmentID)
+
+	body, err := c.getBody(ctx, endpoint, "shipment costs")
+	if err != nil {
+		return nil, err
+	}
+	var costs ShipmentCosts
+	if err := json.Unmarshal(body, &costs); err != nil {
+		return nil, err
+	}
+	return &costs, nil
+}
+
+// TrendingKeywords returns the most searched terms of a category.
+func (c *MeliClient) TrendingKeywords(ctx context.Context, categoryID string) ([]TrendKeyword, error) {
+	endpoint := fmt.Sprintf("%s/trends/%s/%s", c.baseURL, c.siteID, categoryID)
+
+	body, err := c.getBody(ctx, endpoint, "trends")
+	if err != nil {
+		return nil, err
+	}
+	var keywords []TrendKeyword
+	if err := json.Unmarshal(body, &keywords); err != nil {
+		return nil, err
+	}
+	return keywords, nil
+}
+
+// Me returns the user that owns the current access token.
+func (c *MeliClient) Me(ctx context.Context) (*User, error) {
+	endpoint := fmt.Sprintf("%s/users/me", c.baseURL)
+
+	req, err := c.newRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, "users/me")
+	}
+
+	var user User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUser returns the public profile of a user, with their seller
+// reputation.
+func (c *MeliClient) GetUser(ctx context.Context, userID int64) (*User, error) {
+	endpoint := fmt.Sprintf("%s/users/%d", c.baseURL, userID)
+
+	body, err := c.getBody(ctx, endpoint, "users")
+	if err != nil {
+		return nil, err
+	}
+	var user User
+	if err := json.Unmarshal(body, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SellerItems are the active listings of a seller found by the site search.
+// Total counts them all; Items stops at the first 1000, the deepest the
+// search pages.
+type SellerItems struct {
+	Total int
+	Items []CategorySearchResult
+}
+
+// GetSellerItems lists any seller's active listings through the site
+// search. Unlike UserItemIDs it works for sellers other
+// than the authenticated one.
+func (c *MeliClient) GetSellerItems(ctx context.Context, sellerID int64) (*SellerItems, error) {
+	const (
+		pageSize  = 50
+		maxOffset = 1000
+	)
+	out := &SellerItems{}
+	for offset := 0; offset < maxOffset; offset += pageSize {
+		q := url.Values{}
+		q.Set("seller_id", strconv.FormatInt(sellerID, 10))
+		q.Set("offset", strconv.Itoa(offset))
+		q.Set("limit", strconv.Itoa(pageSize))
+		page, err := c.searchPage(ctx, q, "seller search")
+		if err != nil {
+			return nil, err
+		}
+		out.Total = page.Paging.Total
+		out.Items = append(out.Items, page.Results...)
+		if len(page.Results) < pageSize || offset+pageSize >= out.Total {
+			break
+		}
+	}
+	return out, nil
+}
+
+// UserItemIDs lists the IDs of all listings published by the given seller,
+// following `/users/{id}/items/search` pagination.
+func (c *MeliClient) UserItemIDs(ctx context.Context, userID int64) ([]string, error) {
+	const pageSize = 50
+	endpoint := fmt.Sprintf("%s/users/%d/items/search?offset=0&limit=%d", c.baseURL, userID, pageSize)
+
+	ids := make([]string, 0)
+	err := c.fetchPages(ctx, endpoint, "user items", func(body []byte) (paging, error) {
+		var page userItemsSearchResponse
+		if err := json.Unmarshal(body, &page); err != nil {
+			return paging{}, err
+		}
+		ids = append(ids, page.Results...)
+		if len(page.Results) == 0 {
+			return paging{}, nil
+		}
+		return page.Paging, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// CatalogEligibility returns whether an item can be opted into a catalog
+// product (and compete for the buy box).
+func (c *MeliClient) CatalogEligibility(ctx context.Context, itemID string) (*CatalogEligibility, error) {
+	endpoint := fmt.Sprintf("%s/items/%s/catalog_listing_eligibility", c.baseURL, itemID)
+
+	req, err := c.newRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, "catalog eligibility")
+	}
+
+	var elig CatalogEligibility
+	if err := json.NewDecoder(resp.Body).Decode(&elig); err != nil {
+		return nil, err
+	}
+	return &elig, nil
+}
+
+// SearchCategoryPage fetches one page of listings for a category from the
+// site search. 429 responses are retried a few times, honoring Retry-After.
+func (c *MeliClient) SearchCategoryPage(ctx context.Context, categoryID string, offset, limit int) (*CategorySearchPage, error) {
+	q := url.Values{}
+	q.Set("category", categoryID)
+	q.Set("offset", fmt.Sprintf("%d", offset))
+	q.Set("limit", fmt.Sprintf("%d", limit))
+	return c.searchPage(ctx, q, "category search")
+}
+
+// SearchListings fetches the first listings matching a free-text query
+// (a title or a GTIN/EAN) from the site search.
+func (c *MeliClient) SearchListings(ctx context.Context, query string, limit int) (*CategorySearchPage, error) {
+	q := url.Values{}
+	q.Set("q", query)
+	q.Set("limit", fmt.Sprintf("%d", limit))
+	return c.searchPage(ctx, q, "listing search")
+}
+
+// SearchQueryPage fetches one page of the site search for a free-text
+// query, in the order buyers see it.
+func (c *MeliClient) SearchQueryPage(ctx context.Context, query string, offset, limit int) (*CategorySearchPage, error) {
+	q := url.Values{}
+	q.Set("q", query)
+	q.Set("offset", fmt.Sprintf("%d", offset))
+	q.Set("limit", fmt.Sprintf("%d", limit))
+	return c.searchPage(ctx, q, "keyword search")
+}
+
+// searchPage runs a site search, retrying 429 responses a few times.
+func (c *MeliClient) searchPage(ctx context.Context, q url.Values, op string) (*CategorySearchPage, error) {
+	endpoint := fmt.Sprintf("%s/sites/%s/search?%s", c.baseURL, c.siteID, q.Encode())
+
+	ctx, cancel := c.endpointContext(ctx, EndpointSearch)
+	defer cancel()
+
+	const maxAttempts = 3
+	for attempt := 1; ; attempt++ {
+		req, err := c.newRequest(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxAttempts {
+			wait := retryAfter(resp.Header.Get("Retry-After"), time.Duration(attempt)*2*time.Second)
+			resp.Body.Close()
+			log.Printf("[WARN] meli search rate limited, retrying in %s", wait)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			err := statusError(resp, op)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var page CategorySearchPage
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		return &page, nil
+	}
+}
+
+// retryAfter parses a Retry-After header expressed in seconds.
+func retryAfter(header string, fallback time.Duration) time.Duration {
+	if secs, err := strconv.Atoi(header); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return fallback
+}
+
+// CreateTestUser creates a Mercado Livre test user for the client's site.
+// Test users can buy and sell among themselves without real money.
+func (c *MeliClient) CreateTestUser(ctx context.Context) (*TestUser, error) {
+	endpoint := fmt.Sprintf("%s/users/test_user", c.baseURL)
+	body := strings.NewReader(fmt.Sprintf(`{"site_id":%q}`, c.siteID))
+
+	req, err := c.newRequest(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, statusError(resp, "create test user")
+	}
+
+	var user TestUser
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateItem publishes a listing for the client's account. With a test
+// user, only other test users can buy it.
+func (c *MeliClient) CreateItem(ctx context.Context, item NewItem) (*Item, error) {
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, c.baseURL+"/items", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, statusError(resp, "create item")
+	}
+
+	var created Item
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// ValidateItem runs a listing through Mercado Livre's validation without
+// publishing it. It returns the problems found, none when the listing
+// would be accepted.
+func (c *MeliClient) ValidateItem(ctx context.Context, item NewItem) ([]ValidationCause, error) {
+	payload, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, c.baseURL+"/items/validate", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil, nil
+	case http.StatusBadRequest:
+		var body struct {
+			Message string            `json:"message"`
+			Cause   []ValidationCause `json:"cause"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		if len(body.Cause) == 0 {
+			return []ValidationCause{{Code: "item.invalid", Type: "error", Message: body.Message}}, nil
+		}
+		return body.Cause, nil
+	}
+	return nil, statusError(resp, "validate item")
+}
+
+// ListingPrices returns the fees of listing an item of a category at a
+// price with a listing type, e.g. "gold_special".
+func (c *MeliClient) ListingPrices(ctx context.Context, categoryID, listingType string, price float64) (*ListingPrice, error) {
+	q := url.Values{}
+	q.Set("price", strconv.FormatFloat(price, 'f', 2, 64))
+	q.Set("listing_type_id", listingType)
+	q.Set("category_id", categoryID)
+	endpoint := fmt.Sprintf("%s/sites/%s/listing_prices?%s", c.baseURL, c.siteID, q.Encode())
+
+	ctx, cancel := c.endpointContext(ctx, EndpointCategories)
+	defer cancel()
+
+	body, err := c.getBody(ctx, endpoint, "listing prices")
+	if err != nil {
+		return nil, err
+	}
+	var prices ListingPrice
+	if err := json.Unmarshal(body, &prices); err != nil {
+		return nil, err
+	}
+	return &prices, nil
+}
+
+// CloseItem ends one of the account's listings for good.
+func (c *MeliClient) CloseItem(ctx context.Context, itemID string) error {
+	return c.SetItemStatus(ctx, itemID, ItemStatusClosed)
+}
+
+// SetItemStatus pauses, reactivates or closes one of the account's
+// listings.
+func (c *MeliClient) SetItemStatus(ctx context.Context, itemID, status string) error {
+	return c.updateItem(ctx, itemID, map[string]interface{}{"status": status}, "set item status")
+}
+
+// SetItemPrice sets the price of one of the account's listings. Listings
+// with variations price each variation instead.
+func (c *MeliClient) SetItemPrice(ctx context.Context, itemID string, price float64) error {
+	return c.updateItem(ctx, itemID, map[string]interface{}{"price": price}, "set item price")
+}
+
+// SetItemTitle renames one of the account's listings. Mercado Livre
+// rejects it once the listing has sales.
+func (c *MeliClient) SetItemTitle(ctx context.Context, itemID, title string) error {
+	return c.updateItem(ctx, itemID, map[string]interface{}{"title": title}, "set item title")
+}
+
+// SetItemStock sets the available quantity of one of the account's
+// listings. FULL listings do not accept it, and listings with variations
+// take it per variation, see SetVariationStock.
+func (c *MeliClient) SetItemStock(ctx context.Context, itemID string, quantity int) error {
+	return c.updateItem(ctx, itemID, map[string]interface{}{"available_quantity": quantity}, "set item stock")
+}
+
+// SetVariationStock sets the available quantity of variations of one of
+// the account's listings, by variation ID. Every variation of item is sent,
+// since Mercado Livre deletes the ones left out.
+func (c *MeliClient) SetVariationStock(ctx context.Context, item *Item, quantities map[int64]int) error {
+	variations := make([]map[string]interface{}, 0, len(item.Variations))
+	for _, v := range item.Variations {
+		entry := map[string]interface{}{"id": v.ID}
+		if q, ok := quantities[v.ID]; ok {
+			entry["available_quantity"] = q
+		}
+		variations = append(variations, entry)
+	}
+	return c.updateItem(ctx, item.ID, map[string]interface{}{"variations": variations}, "set variation stock")
+}
+
+// updateItem changes fields of one of the account's listings.
+func (c *MeliClient) updateItem(ctx context.Context, itemID string, fields map[string]interface{}, op string) error {
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/items/%s", c.baseURL, itemID)
+	req, err := c.newRequest(ctx, http.MethodPut, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp, op)
+	}
+	c.details.drop(detailKey("items", itemID))
+	return nil
+}
+
+// GetQuestion returns a question asked on one of the seller's listings.
+func (c *MeliClient) GetQuestion(ctx context.Context, questionID int64) (*Question, error) {
+	endpoint := fmt.Sprintf("%s/questions/%d?api_version=4", c.baseURL, questionID)
+
+	ctx, cancel := c.endpointContext(ctx, EndpointDetail)
+	defer cancel()
+
+	body, err := c.getBody(ctx, endpoint, "question")
+	if err != nil {
+		return nil, err
+	}
+	var q Question
+	if err := json.Unmarshal(body, &q); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// AnswerQuestion publishes the seller's answer to a question.
+func (c *MeliClient) AnswerQuestion(ctx context.Context, questionID int64, text string) error {
+	payload, err := json.Marshal(map[string]interface{}{"question_id": questionID, "text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, c.baseURL+"/answers", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return statusError(resp, "answer question")
+	}
+	return nil
+}
+
+// CountQuestions returns how many questions on the seller's listings are in
+// a status, e.g. QuestionStatusUnanswered.
+func (c *MeliClient) CountQuestions(ctx context.Context, sellerID int64, status string) (int, error) {
+	q := url.Values{}
+	q.Set("seller_id", strconv.FormatInt(sellerID, 10))
+	q.Set("status", status)
+	q.Set("limit", "1")
+	q.Set("api_version", "4")
+	endpoint := fmt.Sprintf("%s/questions/search?%s", c.baseURL, q.Encode())
+
+	ctx, cancel := c.endpointContext(ctx, EndpointSearch)
+	defer cancel()
+
+	body, err := c.getBody(ctx, endpoint, "questions search")
+	if err != nil {
+		return 0, err
+	}
+	var page struct {
+		Total int `json:"total"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return 0, err
+	}
+	return page.Total, nil
+}
+
+// GetShipment returns the status and handling deadline of a shipment.
+func (c *MeliClient) GetShipment(ctx context.Context, shipmentID int64) (*Shipment, error) {
+	endpoint := fmt.Sprintf("%s/shipments/%d", c.baseURL, shipmentID)
+
+	ctx, cancel := c.endpointContext(ctx, EndpointDetail)
+	defer cancel()
+
+	body, err := c.getBody(ctx, endpoint, "shipment")
+	if err != nil {
+		return nil, err
+	}
+	var s Shipment
+	if err := json.Unmarshal(body, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetFulfillmentStock returns the FULL stock of an inventory.
+func (c *MeliClient) GetFulfillmentStock(ctx context.Context, inventoryID string) (*FulfillmentStock, error) {
+	endpoint := fmt.Sprintf("%s/inventories/%s/stock/fulfillment", c.baseURL, url.PathEscape(inventoryID))
+
+	ctx, cancel := c.endpointContext(ctx, EndpointDetail)
+	defer cancel()
+
+	body, err := c.getBody(ctx, endpoint, "fulfillment stock")
+	if err != nil {
+		return nil, err
+	}
+	var stock FulfillmentStock
+	if err := json.Unmarshal(body, &stock); err != nil {
+		return nil, err
+	}
+	return &stock, nil
+}
+
+// GetOrder returns one of the seller's orders.
+func (c *MeliClient) GetOrder(ctx context.Context, orderID int64) (*Order, error) {
+	endpoint := fmt.Sprintf("%s/orders/%d", c.baseURL, orderID)
+
+	ctx, cancel := c.endpointContext(ctx, EndpointDetail)
+	defer cancel()
+
+	body, err := c.getBody(ctx, endpoint, "order")
+	if err != nil {
+		return nil, err
+	}
+	var order Order
+	if err := json.Unmarshal(body, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
@@ -0,0 +1,114 @@
+package meli
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// metricsWindow is how far back UpstreamStats looks.
+const metricsWindow = 15 * time.Minute
+
+// minuteBucket aggregates upstream calls that happened within one minute.
+type minuteBucket struct {
+	minute   int64
+	requests int
+	errors   int
+}
+
+// upstreamMetrics counts outbound calls to Mercado Livre across all clients.
+type upstreamMetrics struct {
+	mu          sync.Mutex
+	buckets     [int(metricsWindow / time.Minute)]minuteBucket
+	total       int64
+	lastSuccess time.Time
+	lastError   time.Time
+	lastErrMsg  string
+}
+
+var metrics = &upstreamMetrics{}
+
+// UpstreamStats summarizes recent Mercado Livre API health.
+type UpstreamStats struct {
+	TotalRequests  int64     `json:"total_requests"`
+	WindowRequests int       `json:"window_requests"`
+	WindowErrors   int       `json:"window_errors"`
+	ErrorRate      float64   `json:"error_rate"`
+	Window         string    `json:"window"`
+	LastSuccess    time.Time `json:"last_success,omitempty"`
+	LastError      time.Time `json:"last_error,omitempty"`
+	LastErrorMsg   string    `json:"last_error_message,omitempty"`
+	Healthy        bool      `json:"healthy"`
+}
+
+func (m *upstreamMetrics) record(now time.Time, failed bool, errMsg string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	minute := now.Unix() / 60
+	b := &m.buckets[minute%int64(len(m.buckets))]
+	if b.minute != minute {
+		*b = minuteBucket{minute: minute}
+	}
+	b.requests++
+	m.total++
+	if failed {
+		b.errors++
+		m.lastError = now
+		m.lastErrMsg = errMsg
+	} else {
+		m.lastSuccess = now
+	}
+}
+
+// GetUpstreamStats returns the upstream call metrics for the last metricsWindow.
+func GetUpstreamStats() UpstreamStats {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+
+	s := UpstreamStats{
+		TotalRequests: metrics.total,
+		Window:        metricsWindow.String(),
+		LastSuccess:   metrics.lastSuccess,
+		LastError:     metrics.lastError,
+		LastErrorMsg:  metrics.lastErrMsg,
+	}
+
+	oldest := time.Now().Unix()/60 - int64(len(metrics.buckets)) + 1
+	for _, b := range metrics.buckets {
+		if b.minute >= oldest {
+			s.WindowRequests += b.requests
+			s.WindowErrors += b.errors
+		}
+	}
+	if s.WindowRequests > 0 {
+		s.ErrorRate = float64(s.WindowErrors) / float64(s.WindowRequests)
+	}
+	// Healthy unless most recent calls are failing.
+	s.Healthy = s.WindowRequests == 0 || s.ErrorRate < 0.5
+	return s
+}
+
+// instrumentedTransport records every round trip in the package metrics and
+// in the usage of the account whose token made it. Transport errors, 429 and
+// 5xx responses count as errors.
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	now := time.Now()
+	failed := true
+	switch {
+	case err != nil:
+		metrics.record(now, true, err.Error())
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		metrics.record(now, true, req.URL.Path+": "+resp.Status)
+	default:
+		metrics.record(now, false, "")
+		failed = false
+	}
+	usage.record(req.Header.Get("Authorization"), now, failed)
+	return resp, err
+}
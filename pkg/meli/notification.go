@@ -0,0 +1,44 @@
+package meli
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notification topics the application subscribes to.
+const (
+	TopicQuestions = "questions"
+	TopicOrders    = "orders_v2"
+	TopicItems     = "items"
+	TopicClaims    = "claims"
+)
+
+// Notification is the body Mercado Livre POSTs to the application's
+// callback URL. Resource is the path of the changed resource, e.g.
+// "/questions/123"; it has to be fetched to learn what changed.
+type Notification struct {
+	ID            string    `json:"_id"`
+	Resource      string    `json:"resource"`
+	UserID        int64     `json:"user_id"`
+	Topic         string    `json:"topic"`
+	ApplicationID int64     `json:"application_id"`
+	Attempts      int       `json:"attempts"`
+	Sent          time.Time `json:"sent"`
+	Received      time.Time `json:"received"`
+}
+
+// ResourceID returns the numeric ID at the end of Resource, or 0.
+func (n *Notification) ResourceID() int64 {
+	id, err := strconv.ParseInt(n.ResourceKey(), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// ResourceKey returns the ID at the end of Resource, e.g. "MLB123" for
+// "/items/MLB123".
+func (n *Notification) ResourceKey() string {
+	return n.Resource[strings.LastIndex(n.Resource, "/")+1:]
+}
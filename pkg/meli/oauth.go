@@ -0,0 +1,160 @@
+package meli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// OAuth base URLs - Mercado Livre uses different endpoints
+	oauthAuthURL  = "https://auth.mercadolivre.com.br/authorization"
+	oauthTokenURL = "https://api.mercadolibre.com/oauth/token"
+)
+
+// OAuthClient handles OAuth 2.0 flow for Mercado Livre
+type OAuthClient struct {
+	clientID     string
+	mu           sync.RWMutex
+	clientSecret string
+	redirectURI  string
+	tokenURL     string
+	httpClient   *http.Client
+}
+
+// OAuthOption customizes an OAuthClient.
+type OAuthOption func(*OAuthClient)
+
+// WithTokenURL overrides the token endpoint, e.g. to use a fake server in tests.
+func WithTokenURL(tokenURL string) OAuthOption {
+	return func(o *OAuthClient) {
+		o.tokenURL = tokenURL
+	}
+}
+
+// WithOAuthTransport sets the transport of token requests, e.g. a proxy.
+func WithOAuthTransport(rt http.RoundTripper) OAuthOption {
+	return func(o *OAuthClient) {
+		o.httpClient.Transport = rt
+	}
+}
+
+// NewOAuthClient returns a client for the authorization-code flow of the
+// application registered as clientID.
+func NewOAuthClient(clientID, clientSecret, redirectURI string, opts ...OAuthOption) *OAuthClient {
+	o := &OAuthClient{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURI:  redirectURI,
+		tokenURL:     oauthTokenURL,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// SetClientSecret replaces the client secret, e.g. after it was rotated in
+// a secret manager.
+func (o *OAuthClient) SetClientSecret(clientSecret string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.clientSecret = clientSecret
+}
+
+func (o *OAuthClient) secret() string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.clientSecret
+}
+
+// GetAuthorizationURL returns the URL to redirect the user for OAuth authorization
+func (o *OAuthClient) GetAuthorizationURL() string {
+	return o.AuthorizationURL("")
+}
+
+// AuthorizationURL is GetAuthorizationURL with a state parameter, which
+// Mercado Livre sends back to the callback; empty leaves it out.
+func (o *OAuthClient) AuthorizationURL(state string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", o.clientID)
+	params.Set("redirect_uri", o.redirectURI)
+	if state != "" {
+		params.Set("state", state)
+	}
+	// Note: redirect_uri must match exactly what's configured in Mercado Livre DevCenter
+	return oauthAuthURL + "?" + params.Encode()
+}
+
+// TokenResponse represents the OAuth token response
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	UserID       int    `json:"user_id"`
+}
+
+// ExchangeCodeForToken exchanges an authorization code for an access token
+func (o *OAuthClient) ExchangeCodeForToken(ctx context.Context, code string) (*TokenResponse, error) {
+	params := url.Values{}
+	params.Set("grant_type", "authorization_code")
+	params.Set("client_id", o.clientID)
+	params.Set("client_secret", o.secret())
+	params.Set("code", code)
+	params.Set("redirect_uri", o.redirectURI)
+
+	return o.requestToken(ctx, params, "exchange")
+}
+
+// RefreshToken trades a refresh token for a new access token. Mercado Livre
+// refresh tokens are single-use: the response carries the next one.
+func (o *OAuthClient) RefreshToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
+	params := url.Values{}
+	params.Set("grant_type", "refresh_token")
+	params.Set("client_id", o.clientID)
+	params.Set("client_secret", o.secret())
+	params.Set("refresh_token", refreshToken)
+
+	return o.requestToken(ctx, params, "refresh")
+}
+
+func (o *OAuthClient) requestToken(ctx context.Context, params url.Values, op string) (*TokenResponse, error) {
+	// For POST requests, params must be in the body
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.tokenURL, strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errorBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("oauth token %s failed: status %d - %s", op, resp.StatusCode, string(errorBody))
+	}
+
+	var tokenResp TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, err
+	}
+
+	return &tokenResp, nil
+}
@@ -0,0 +1,141 @@
+package meli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// paging is the paging block of Mercado Livre list responses.
+type paging struct {
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// fetchPages GETs endpoint and then every following page reported by the
+// paging block, passing each body to decode. decode returns the paging of the
+// body it parsed; a zero paging stops the walk. what names the resource in
+// errors.
+func (c *MeliClient) fetchPages(ctx context.Context, endpoint, what string, decode func(body []byte) (paging, error)) error {
+	body, err := c.getBody(ctx, endpoint, what)
+	if err != nil {
+		return err
+	}
+	p, err := decode(body)
+	if err != nil {
+		return fmt.Errorf("json decode %s: %w - body: %s", what, err, string(body))
+	}
+	if p.Total <= 0 || p.Limit <= 0 {
+		return nil
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	limit := p.Limit
+	for offset := p.Offset + limit; offset < p.Total; offset += limit {
+		q := u.Query()
+		q.Set("offset", strconv.Itoa(offset))
+		q.Set("limit", strconv.Itoa(limit))
+		u.RawQuery = q.Encode()
+
+		body, err := c.getBody(ctx, u.String(), what+" (paged)")
+		if err != nil {
+			return err
+		}
+		next, err := decode(body)
+		if err != nil {
+			return fmt.Errorf("json decode %s (paged): %w - body: %s", what, err, string(body))
+		}
+		if next.Limit <= 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// getBody GETs endpoint and returns the body of a 200 response.
+func (c *MeliClient) getBody(ctx context.Context, endpoint, what string) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp, what)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// listedItem is one offer of a product, whatever response shape it came in.
+type listedItem struct {
+	ID        string
+	Title     string
+	Permalink string
+	Condition string
+	Price     float64
+	Active    bool
+}
+
+// decodeProductItems parses the shapes `/products/{id}/items` has been seen
+// to answer with: {"items": [...]}, a plain []Item, and {paging, results}.
+// Paged results only list active offers, so they carry no status.
+func decodeProductItems(body []byte) ([]listedItem, paging, error) {
+	fromItems := func(items []Item) []listedItem {
+		out := make([]listedItem, 0, len(items))
+		for _, it := range items {
+			out = append(out, listedItem{
+				ID:        it.ID,
+				Title:     it.Title,
+				Permalink: it.Permalink,
+				Price:     it.Price,
+				Active:    it.Status == "active",
+			})
+		}
+		return out
+	}
+
+	// 1) {"items": [...]}
+	var iw struct {
+		Items []Item `json:"items"`
+	}
+	if err := json.Unmarshal(body, &iw); err == nil && len(iw.Items) > 0 {
+		return fromItems(iw.Items), paging{}, nil
+	}
+
+	// 2) plain []Item
+	var items []Item
+	if err := json.Unmarshal(body, &items); err == nil && len(items) > 0 {
+		return fromItems(items), paging{}, nil
+	}
+
+	// 3) {paging, results}
+	var hp struct {
+		Paging  paging `json:"paging"`
+		Results []struct {
+			ItemID    string  `json:"item_id"`
+			Price     float64 `json:"price"`
+			Condition string  `json:"condition"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &hp); err == nil && len(hp.Results) > 0 {
+		out := make([]listedItem, 0, len(hp.Results))
+		for _, r := range hp.Results {
+			out = append(out, listedItem{ID: r.ItemID, Price: r.Price, Condition: r.Condition, Active: true})
+		}
+		return out, hp.Paging, nil
+	}
+
+	return nil, paging{}, fmt.Errorf("unknown items response format")
+}
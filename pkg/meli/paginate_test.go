@@ -0,0 +1,183 @@
+package meli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeProductItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []listedItem
+		paging  paging
+		wantErr bool
+	}{
+		{
+			name: "items wrapper",
+			body: `{"items": [
+				{"id": "MLB1", "title": "Fone", "permalink": "https://x/MLB1", "price": 99.9, "status": "active"},
+				{"id": "MLB2", "title": "Fone", "price": 89.9, "status": "paused"}
+			]}`,
+			want: []listedItem{
+				{ID: "MLB1", Title: "Fone", Permalink: "https://x/MLB1", Price: 99.9, Active: true},
+				{ID: "MLB2", Title: "Fone", Price: 89.9},
+			},
+		},
+		{
+			name: "plain array",
+			body: `[{"id": "MLB3", "title": "Cabo", "price": 10, "status": "active"}]`,
+			want: []listedItem{{ID: "MLB3", Title: "Cabo", Price: 10, Active: true}},
+		},
+		{
+			name: "paged results",
+			body: `{
+				"paging": {"total": 3, "offset": 0, "limit": 2},
+				"results": [
+					{"item_id": "MLB4", "price": 50, "condition": "new"},
+					{"item_id": "MLB5", "price": 45.5, "condition": "used"}
+				]
+			}`,
+			want: []listedItem{
+				{ID: "MLB4", Price: 50, Condition: "new", Active: true},
+				{ID: "MLB5", Price: 45.5, Condition: "used", Active: true},
+			},
+			paging: paging{Total: 3, Offset: 0, Limit: 2},
+		},
+		{name: "empty items", body: `{"items": []}`, wantErr: true},
+		{name: "empty results", body: `{"paging": {"total": 0}, "results": []}`, wantErr: true},
+		{name: "error body", body: `{"message": "resource not found", "status": 404}`, wantErr: true},
+		{name: "not json", body: `<html></html>`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, p, err := decodeProductItems([]byte(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("items = %+v, want %+v", got, tt.want)
+			}
+			if p != tt.paging {
+				t.Errorf("paging = %+v, want %+v", p, tt.paging)
+			}
+		})
+	}
+}
+
+func TestFetchPages(t *testing.T) {
+	tests := []struct {
+		name string
+		// pages maps the offset query parameter ("" for the first request)
+		// to the body served for it
+		pages       map[string]string
+		wantIDs     []string
+		wantOffsets []string
+		wantErr     string
+	}{
+		{
+			name:        "items wrapper is a single page",
+			pages:       map[string]string{"": `{"items": [{"id": "MLB1", "price": 10, "status": "active"}]}`},
+			wantIDs:     []string{"MLB1"},
+			wantOffsets: []string{""},
+		},
+		{
+			name:        "plain array is a single page",
+			pages:       map[string]string{"": `[{"id": "MLB1", "price": 10}, {"id": "MLB2", "price": 12}]`},
+			wantIDs:     []string{"MLB1", "MLB2"},
+			wantOffsets: []string{""},
+		},
+		{
+			name: "paged results walk every page",
+			pages: map[string]string{
+				"":  `{"paging": {"total": 5, "offset": 0, "limit": 2}, "results": [{"item_id": "MLB1"}, {"item_id": "MLB2"}]}`,
+				"2": `{"paging": {"total": 5, "offset": 2, "limit": 2}, "results": [{"item_id": "MLB3"}, {"item_id": "MLB4"}]}`,
+				"4": `{"paging": {"total": 5, "offset": 4, "limit": 2}, "results": [{"item_id": "MLB5"}]}`,
+			},
+			wantIDs:     []string{"MLB1", "MLB2", "MLB3", "MLB4", "MLB5"},
+			wantOffsets: []string{"", "2", "4"},
+		},
+		{
+			name: "paged results fitting one page",
+			pages: map[string]string{
+				"": `{"paging": {"total": 2, "offset": 0, "limit": 50}, "results": [{"item_id": "MLB1"}, {"item_id": "MLB2"}]}`,
+			},
+			wantIDs:     []string{"MLB1", "MLB2"},
+			wantOffsets: []string{""},
+		},
+		{
+			name: "undecodable later page",
+			pages: map[string]string{
+				"":  `{"paging": {"total": 4, "offset": 0, "limit": 2}, "results": [{"item_id": "MLB1"}, {"item_id": "MLB2"}]}`,
+				"2": `{"results": []}`,
+			},
+			wantErr: "json decode product items (paged)",
+		},
+		{
+			name:    "unknown first page",
+			pages:   map[string]string{"": `{"message": "not found"}`},
+			wantErr: "json decode product items: unknown items response format",
+		},
+		{
+			name: "missing later page",
+			pages: map[string]string{
+				"": `{"paging": {"total": 4, "offset": 0, "limit": 2}, "results": [{"item_id": "MLB1"}, {"item_id": "MLB2"}]}`,
+			},
+			wantErr: "404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var offsets []string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				offset := r.URL.Query().Get("offset")
+				offsets = append(offsets, offset)
+				body, ok := tt.pages[offset]
+				if !ok {
+					http.Error(w, `{"message": "not found"}`, http.StatusNotFound)
+					return
+				}
+				w.Write([]byte(body))
+			}))
+			defer srv.Close()
+
+			c := NewMeliClient("", "", WithBaseURL(srv.URL))
+			var ids []string
+			err := c.fetchPages(context.Background(), srv.URL+"/products/MLB19615317/items", "product items", func(body []byte) (paging, error) {
+				items, p, err := decodeProductItems(body)
+				for _, it := range items {
+					ids = append(ids, it.ID)
+				}
+				return p, err
+			})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(offsets, tt.wantOffsets) {
+				t.Errorf("offsets = %v, want %v", offsets, tt.wantOffsets)
+			}
+		})
+	}
+}
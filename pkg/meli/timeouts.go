@@ -0,0 +1,24 @@
+package meli
+
+import (
+	"context"
+)
+
+// Endpoint groups that accept their own timeout via WithEndpointTimeout.
+const (
+	EndpointHighlights   = "highlights"
+	EndpointDetail       = "detail"
+	EndpointProductItems = "product_items"
+	EndpointCategories   = "categories"
+	EndpointPredictor    = "predictor"
+	EndpointSearch       = "search"
+)
+
+// endpointContext derives a context bounded by the endpoint's timeout, if
+// one is configured. The parent deadline still wins when it is earlier.
+func (c *MeliClient) endpointContext(ctx context.Context, endpoint string) (context.Context, context.CancelFunc) {
+	if d, ok := c.endpointTimeouts[endpoint]; ok && d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,154 @@
+package meli
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrNoRefreshToken is returned when a refresh is needed but no refresh
+// token (or OAuth client) is available.
+var ErrNoRefreshToken = errors.New("no refresh token available; log in again")
+
+// TokenRefresher obtains a new access token after stale was rejected.
+type TokenRefresher interface {
+	Refresh(ctx context.Context, stale string) (string, error)
+}
+
+// TokenManager holds the current access/refresh token pair and refreshes it
+// through OAuth. Concurrent refreshes of the same stale token share one call.
+type TokenManager struct {
+	oauth *OAuthClient
+
+	// refreshMu serializes refresh calls; mu only guards the pair, so
+	// readers never wait for the OAuth round-trip
+	refreshMu    sync.Mutex
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+}
+
+// NewTokenManager returns a manager that refreshes through oauth, which may
+// be nil when OAuth is not configured.
+func NewTokenManager(oauth *OAuthClient) *TokenManager {
+	return &TokenManager{oauth: oauth}
+}
+
+// AccessToken returns the current access token.
+func (m *TokenManager) AccessToken() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.accessToken
+}
+
+// RefreshToken returns the current refresh token.
+func (m *TokenManager) RefreshToken() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.refreshToken
+}
+
+// Set replaces the token pair. An empty refresh token keeps the current one.
+func (m *TokenManager) Set(accessToken, refreshToken string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accessToken = accessToken
+	if refreshToken != "" {
+		m.refreshToken = refreshToken
+	}
+}
+
+// Clear forgets both tokens.
+func (m *TokenManager) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accessToken, m.refreshToken = "", ""
+}
+
+// Refresh returns a new access token. When the current token already differs
+// from stale, another caller refreshed it first and it is returned as is.
+func (m *TokenManager) Refresh(ctx context.Context, stale string) (string, error) {
+	m.refreshMu.Lock()
+	defer m.refreshMu.Unlock()
+
+	m.mu.Lock()
+	access, refresh := m.accessToken, m.refreshToken
+	m.mu.Unlock()
+	if access != "" && access != stale {
+		return access, nil
+	}
+	if m.oauth == nil || refresh == "" {
+		return "", ErrNoRefreshToken
+	}
+
+	tok, err := m.oauth.RefreshToken(ctx, refresh)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// A login during the call set a newer pair, which wins
+	if m.refreshToken != refresh {
+		return m.accessToken, nil
+	}
+	m.accessToken = tok.AccessToken
+	if tok.RefreshToken != "" {
+		m.refreshToken = tok.RefreshToken
+	}
+	return m.accessToken, nil
+}
+
+// refreshTransport retries a request once with a fresh token when the
+// upstream answers 401.
+type refreshTransport struct {
+	next      http.RoundTripper
+	refresher TokenRefresher
+
+	mu    sync.Mutex
+	token string // replaces the client's token once refreshed
+}
+
+func (t *refreshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	token := t.token
+	t.mu.Unlock()
+	if token != "" {
+		req = withBearer(req, token)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+
+	stale := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	fresh, err := t.refresher.Refresh(req.Context(), stale)
+	if err != nil || fresh == stale {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	t.mu.Lock()
+	t.token = fresh
+	t.mu.Unlock()
+
+	retry := withBearer(req, fresh)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(retry)
+}
+
+func withBearer(req *http.Request, token string) *http.Request {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
@@ -0,0 +1,87 @@
+package meli
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenManagerRefresh(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "fresh", "refresh_token": "TG-2", "expires_in": 21600}`))
+	}))
+	defer srv.Close()
+
+	m := NewTokenManager(NewOAuthClient("app", "secret", "https://example.com/callback", WithTokenURL(srv.URL)))
+	m.Set("stale", "TG-1")
+
+	const callers = 5
+	var wg sync.WaitGroup
+	tokens := make([]string, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], errs[i] = m.Refresh(context.Background(), "stale")
+		}(i)
+	}
+
+	// Readers are not held up by the refresh in flight
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	read := make(chan string)
+	go func() { read <- m.AccessToken() + " " + m.RefreshToken() }()
+	select {
+	case got := <-read:
+		if got != "stale TG-1" {
+			t.Errorf("tokens during refresh = %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AccessToken blocked during the refresh")
+	}
+
+	close(release)
+	wg.Wait()
+	for i := range tokens {
+		if errs[i] != nil || tokens[i] != "fresh" {
+			t.Errorf("caller %d: token %q, err %v", i, tokens[i], errs[i])
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("refreshed %d times, want 1", n)
+	}
+	if m.RefreshToken() != "TG-2" {
+		t.Errorf("refresh token = %q", m.RefreshToken())
+	}
+}
+
+func TestTokenManagerRefreshKeepsNewerLogin(t *testing.T) {
+	m := NewTokenManager(nil)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A login lands while the refresh is in flight
+		m.Set("login", "TG-login")
+		w.Write([]byte(`{"access_token": "fresh", "refresh_token": "TG-2"}`))
+	}))
+	defer srv.Close()
+	m.oauth = NewOAuthClient("app", "secret", "https://example.com/callback", WithTokenURL(srv.URL))
+	m.Set("stale", "TG-1")
+
+	got, err := m.Refresh(context.Background(), "stale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "login" || m.RefreshToken() != "TG-login" {
+		t.Errorf("token = %q, refresh token = %q, want the login's", got, m.RefreshToken())
+	}
+}
@@ -0,0 +1,125 @@
+package meli
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxStoredTraces bounds the in-memory debug report.
+const maxStoredTraces = 50
+
+// ProductTrace is the verbose log of how a product's data was resolved.
+type ProductTrace struct {
+	ProductID string      `json:"product_id"`
+	Source    string      `json:"source"`
+	StartedAt time.Time   `json:"started_at"`
+	Lines     []TraceLine `json:"lines"`
+}
+
+// TraceLine is one message of a ProductTrace.
+type TraceLine struct {
+	At      time.Time `json:"at"`
+	Message string    `json:"message"`
+}
+
+type (
+	traceCtxKey  struct{}
+	tracerCtxKey struct{}
+)
+
+var traces = struct {
+	sync.Mutex
+	products []string
+	recent   []ProductTrace
+}{}
+
+// SetDebugProducts enables tracing for the given product IDs on every
+// request (e.g. from ML_DEBUG_PRODUCTS).
+func SetDebugProducts(ids []string) {
+	traces.Lock()
+	defer traces.Unlock()
+	traces.products = ids
+}
+
+// WithProductTrace enables tracing of productID for calls made with the
+// returned context.
+func WithProductTrace(ctx context.Context, productID string) context.Context {
+	return context.WithValue(ctx, traceCtxKey{}, productID)
+}
+
+// RecentTraces returns the stored traces, newest first, optionally filtered
+// by product.
+func RecentTraces(productID string) []ProductTrace {
+	traces.Lock()
+	defer traces.Unlock()
+
+	out := make([]ProductTrace, 0, len(traces.recent))
+	for i := len(traces.recent) - 1; i >= 0; i-- {
+		if productID == "" || traces.recent[i].ProductID == productID {
+			out = append(out, traces.recent[i])
+		}
+	}
+	return out
+}
+
+// tracer collects lines for one product; a nil tracer discards everything,
+// so call sites don't need to check whether tracing is on.
+type tracer struct {
+	mu    sync.Mutex
+	trace ProductTrace
+}
+
+func startTrace(ctx context.Context, productID string) *tracer {
+	source := ""
+	if id, _ := ctx.Value(traceCtxKey{}).(string); id == productID {
+		source = "request"
+	} else {
+		traces.Lock()
+		if slices.Contains(traces.products, productID) {
+			source = "env"
+		}
+		traces.Unlock()
+	}
+	if source == "" {
+		return nil
+	}
+	return &tracer{trace: ProductTrace{ProductID: productID, Source: source, StartedAt: time.Now()}}
+}
+
+func (t *tracer) Logf(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.trace.Lines = append(t.trace.Lines, TraceLine{At: time.Now(), Message: fmt.Sprintf(format, args...)})
+}
+
+// finish stores the trace in the debug report.
+func (t *tracer) finish() {
+	if t == nil {
+		return
+	}
+	traces.Lock()
+	defer traces.Unlock()
+	traces.recent = append(traces.recent, t.trace)
+	if len(traces.recent) > maxStoredTraces {
+		traces.recent = traces.recent[len(traces.recent)-maxStoredTraces:]
+	}
+}
+
+// withTracer hands an active tracer down to helpers.
+func withTracer(ctx context.Context, t *tracer) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, tracerCtxKey{}, t)
+}
+
+func tracerFrom(ctx context.Context) *tracer {
+	t, _ := ctx.Value(tracerCtxKey{}).(*tracer)
+	return t
+}
@@ -0,0 +1,70 @@
+package meli
+
+import (
+	"sync"
+	"time"
+)
+
+// AccountUsage counts the calls made with one account's token within an
+// hour. Calls without a token are counted under account 0.
+type AccountUsage struct {
+	AccountID int64
+	Hour      time.Time
+	Requests  int
+	Errors    int
+}
+
+type usageKey struct {
+	accountID int64
+	hour      int64
+}
+
+// accountUsage accumulates per-account call counts across all clients until
+// they are taken for persisting.
+type accountUsage struct {
+	mu      sync.Mutex
+	pending map[usageKey]*AccountUsage
+}
+
+var usage = &accountUsage{pending: make(map[usageKey]*AccountUsage)}
+
+func (u *accountUsage) record(authorization string, now time.Time, failed bool) {
+	accountID, _ := tokenUserID(authorization)
+	hour := now.UTC().Truncate(time.Hour)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	key := usageKey{accountID: accountID, hour: hour.Unix()}
+	a, ok := u.pending[key]
+	if !ok {
+		a = &AccountUsage{AccountID: accountID, Hour: hour}
+		u.pending[key] = a
+	}
+	a.Requests++
+	if failed {
+		a.Errors++
+	}
+}
+
+// TakeAccountUsage returns the calls counted since its previous call, per
+// account and hour, and resets the counts. The server adds them to the
+// stored totals periodically.
+func TakeAccountUsage() []AccountUsage {
+	usage.mu.Lock()
+	defer usage.mu.Unlock()
+	out := make([]AccountUsage, 0, len(usage.pending))
+	for _, a := range usage.pending {
+		out = append(out, *a)
+	}
+	clear(usage.pending)
+	return out
+}
+
+// AccountID returns the user ID the client's token was issued for, false
+// for an anonymous client.
+func (c *MeliClient) AccountID() (int64, bool) {
+	if c.accessToken == "" {
+		return 0, false
+	}
+	return tokenUserID("Bearer " + c.accessToken)
+}
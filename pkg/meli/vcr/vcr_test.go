@@ -0,0 +1,99 @@
+package vcr
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReplayMatchesRequestBody(t *testing.T) {
+	// The upstream echoes the request body, so each recording is told apart
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Write([]byte("got " + r.Method + " " + string(b)))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	recorder, err := New(ModeRecord, dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, err := New(ModeReplay, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(rt http.RoundTripper, method, body string) (string, error) {
+		t.Helper()
+		var r io.Reader
+		if body != "" {
+			r = strings.NewReader(body)
+		}
+		req, err := http.NewRequest(method, srv.URL+"/answers", r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := rt.RoundTrip(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		return string(b), err
+	}
+
+	recorded := []struct{ method, body string }{
+		{http.MethodPost, `{"question_id":1,"text":"Sim"}`},
+		{http.MethodPost, `{"question_id":2,"text":"Não"}`},
+		{http.MethodPost, `grant_type=authorization_code&code=TG-1`},
+		{http.MethodGet, ""},
+	}
+	for _, r := range recorded {
+		if _, err := do(recorder, r.method, r.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(recorded) {
+		t.Fatalf("recorded %d cassettes, want %d", len(entries), len(recorded))
+	}
+
+	tests := []struct {
+		name         string
+		method, body string
+		want         string
+		wantErr      bool
+	}{
+		{name: "first body", method: http.MethodPost, body: `{"question_id":1,"text":"Sim"}`, want: `got POST {"question_id":1,"text":"Sim"}`},
+		{name: "second body", method: http.MethodPost, body: `{"question_id":2,"text":"Não"}`, want: `got POST {"question_id":2,"text":"Não"}`},
+		// Secrets are scrubbed before hashing, so a new code still matches
+		{name: "other secret", method: http.MethodPost, body: `grant_type=authorization_code&code=TG-2`, want: "got POST grant_type=authorization_code&code=REDACTED"},
+		{name: "get", method: http.MethodGet, want: "got GET "},
+		{name: "unrecorded body", method: http.MethodPost, body: `{"question_id":3}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := do(player, tt.method, tt.body)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1305 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"melibot/database"
+	"melibot/internal/cache"
+	"melibot/internal/events"
+	"melibot/internal/handlers"
+	"melibot/internal/i18n"
+	"melibot/internal/jobs"
+	"melibot/internal/middleware"
+	"melibot/internal/repository"
+	"melibot/internal/scheduler"
+	"melibot/internal/service"
+	"melibot/pkg/meli"
+)
+
+// runServe starts the web server together with the job queue and the
+// scheduler.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	port := fs.String("port", envString("SERVER_PORT", "8080"), "HTTP port (the HTTPS port when TLS is on)")
+	var tlsOpts tlsSettings
+	fs.StringVar(&tlsOpts.certFile, "tls-cert", os.Getenv("TLS_CERT_FILE"), "TLS certificate file (PEM)")
+	fs.StringVar(&tlsOpts.keyFile, "tls-key", os.Getenv("TLS_KEY_FILE"), "TLS private key file (PEM)")
+	autocertDomains := fs.String("autocert-domains", os.Getenv("TLS_AUTOCERT_DOMAINS"), "comma-separated domains to get Let's Encrypt certificates for")
+	fs.StringVar(&tlsOpts.cacheDir, "autocert-cache", envString("TLS_AUTOCERT_CACHE", "certs"), "directory where Let's Encrypt certificates are kept")
+	fs.StringVar(&tlsOpts.email, "autocert-email", os.Getenv("TLS_AUTOCERT_EMAIL"), "contact email for Let's Encrypt")
+	webDir := fs.String("web-dir", os.Getenv("WEB_DIR"), "serve the dashboard from this directory instead of the embedded copy, e.g. ./web")
+	fs.StringVar(&tlsOpts.redirectAddr, "redirect-http", os.Getenv("HTTP_REDIRECT_ADDR"), "address of a plain HTTP listener redirecting to HTTPS, e.g. :80")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	tlsOpts.domains = splitList(*autocertDomains)
+	assets, err := webFS(*webDir)
+	if err != nil {
+		return fmt.Errorf("dashboard assets: %w", err)
+	}
+	if err := tlsOpts.validate(); err != nil {
+		return err
+	}
+
+	a, err := newApp()
+	if err != nil {
+		return err
+	}
+	a.connectDB()
+
+	// Run repository migrations
+	if err := repository.AutoMigrate(); err != nil {
+		return fmt.Errorf("failed to run repository migrations: %w", err)
+	}
+	// Multi-tenant mode starts with one organization holding the data stored
+	// so far
+	if a.orgs != nil {
+		if err := a.orgs.Bootstrap(context.Background()); err != nil {
+			return fmt.Errorf("failed to set up organizations: %w", err)
+		}
+	}
+
+	// Wire dependencies
+	trendRepo := repository.NewTrendRepository()
+	statsRepo := repository.NewCategoryStatsRepository()
+	responseCache := cache.New(envDuration("CACHE_TTL", 10*time.Minute))
+	a.settings.Watch(func() { responseCache.SetTTL(envDuration("CACHE_TTL", 10*time.Minute)) }, "CACHE_TTL")
+
+	// Background job queue; jobs run with the tokens of their organization
+	// (the token currently in memory outside multi-tenant mode), and their
+	// calls wait behind interactive ones
+	newBackgroundClient := func(ctx context.Context, opts ...meli.Option) *meli.MeliClient {
+		return a.clientFor(ctx, append([]meli.Option{meli.WithPriority(meli.PriorityLow)}, opts...)...)
+	}
+	// Subscription plans of multi-tenant mode, set by Stripe webhooks
+	var billing *service.BillingService
+	// In multi-tenant mode, background work over organization data runs once
+	// per organization with a Mercado Livre account (and a working plan,
+	// with billing)
+	perOrg := func(task scheduler.TaskFunc) scheduler.TaskFunc {
+		if a.orgs == nil {
+			return task
+		}
+		return func(ctx context.Context) error {
+			ids, err := a.orgs.ConnectedIDs(ctx)
+			if err != nil {
+				return err
+			}
+			if billing != nil {
+				if ids, err = billing.Entitled(ctx, ids); err != nil {
+					return err
+				}
+			}
+			var errs []error
+			for _, id := range ids {
+				if err := task(database.WithOrg(ctx, id)); err != nil {
+					errs = append(errs, fmt.Errorf("organization %d: %w", id, err))
+				}
+			}
+			return errors.Join(errs...)
+		}
+	}
+	// and notifications run for the organization that connected their seller
+	forSeller := func(fn handlers.NotificationFunc) handlers.NotificationFunc {
+		if a.orgs == nil {
+			return fn
+		}
+		return func(ctx context.Context, n meli.Notification) error {
+			orgID, ok, err := a.orgs.ForSeller(ctx, n.UserID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("no organization connected seller %d", n.UserID)
+			}
+			return fn(database.WithOrg(ctx, orgID), n)
+		}
+	}
+
+	// Encrypted credentials of third-party integrations, read by the ones
+	// below (CREDENTIALS_MASTER_KEY set)
+	box, err := credentialBox()
+	if err != nil {
+		return err
+	}
+	var credentialService *service.CredentialService
+	if box != nil {
+		credentialService = service.NewCredentialService(box, repository.NewCredentialRepository())
+	}
+
+	// Alerts are also sent to Telegram or by email when configured
+	alerts, err := alertNotifier(credentialService)
+	if err != nil {
+		return err
+	}
+
+	// In-process events between modules: producers publish, the notifier
+	// (the log and alerts) subscribes
+	bus := events.New()
+	events.Subscribe(bus, func(ctx context.Context, e events.OrderCreated) {
+		log.Printf("[INFO] New order %d from %s: %.2f %s (%s)", e.Order.ID, e.Order.BuyerNickname, e.Order.TotalAmount, e.Order.Currency, e.Order.Status)
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.OrderStatusChanged) {
+		log.Printf("[INFO] Order %d moved from %s to %s", e.Order.ID, e.From, e.To)
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.PriceChanged) {
+		log.Printf("[INFO] Best price of %s moved from %.2f to %.2f", e.ProductID, e.OldPrice, e.NewPrice)
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.CompetitorPriceDropped) {
+		log.Printf("[INFO] Competitor %s dropped the price of %s from %.2f to %.2f", e.Nickname, e.ItemID, e.OldPrice, e.NewPrice)
+		alerts.Notify(ctx, "Competitor price drop", fmt.Sprintf("%s dropped the price of %s from %.2f to %.2f", e.Nickname, e.ItemID, e.OldPrice, e.NewPrice))
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.CompetitorListingLaunched) {
+		log.Printf("[INFO] Competitor %s launched %s (%s) at %.2f", e.Nickname, e.ItemID, e.Title, e.Price)
+		alerts.Notify(ctx, "New competitor listing", fmt.Sprintf("%s launched %s (%s) at %.2f", e.Nickname, e.ItemID, e.Title, e.Price))
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.RankDropped) {
+		if e.NewPosition == 0 {
+			log.Printf("[WARN] %s fell out of the search for %q (was #%d)", e.ItemID, e.Keyword, e.OldPosition)
+			alerts.Notify(ctx, "Rank drop", fmt.Sprintf("%s fell out of the search for %q (was #%d)", e.ItemID, e.Keyword, e.OldPosition))
+			return
+		}
+		log.Printf("[WARN] %s dropped from #%d to #%d in the search for %q", e.ItemID, e.OldPosition, e.NewPosition, e.Keyword)
+		alerts.Notify(ctx, "Rank drop", fmt.Sprintf("%s dropped from #%d to #%d in the search for %q", e.ItemID, e.OldPosition, e.NewPosition, e.Keyword))
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.CostsUpdated) {
+		if len(e.LossMaking) > 0 {
+			log.Printf("[WARN] New costs from %s put %d listings below cost: %s", e.Source, len(e.LossMaking), strings.Join(e.LossMaking, ", "))
+			alerts.Notify(ctx, "Listings below cost", fmt.Sprintf("New costs from %s put %d listings below cost: %s", e.Source, len(e.LossMaking), strings.Join(e.LossMaking, ", ")))
+		}
+	})
+	events.Subscribe(bus, func(ctx context.Context, e events.PlanChanged) {
+		log.Printf("[INFO] Plan %s of organization %d moved from %q to %q", e.Plan, e.OrgID, e.From, e.To)
+	})
+	if secret := os.Getenv("STRIPE_WEBHOOK_SECRET"); a.orgs != nil && secret != "" {
+		// Stripe prices to plans, e.g. BILLING_PRICES=price_123=pro,price_456=business
+		prices := make(map[string]string)
+		for _, pair := range splitList(os.Getenv("BILLING_PRICES")) {
+			price, plan, _ := strings.Cut(pair, "=")
+			if _, ok := service.Plans[plan]; !ok {
+				log.Printf("[WARN] invalid BILLING_PRICES entry %q: unknown plan", pair)
+				continue
+			}
+			prices[price] = plan
+		}
+		billing = service.NewBillingService(repository.NewOrganizationRepository(), bus, service.BillingConfig{
+			WebhookSecret: secret,
+			Prices:        prices,
+			GracePeriod:   envDuration("BILLING_GRACE_PERIOD", 7*24*time.Hour),
+		})
+	}
+	questionRepo := repository.NewQuestionRepository()
+
+	// Outbound webhooks: every event is forwarded through the job queue so
+	// failed deliveries are retried
+	webhookService := service.NewOutboundWebhookService(repository.NewWebhookRepository(), nil)
+	webhookSubscriptionHandler := handlers.NewWebhookSubscriptionHandler(webhookService)
+
+	// Large exports are uploaded to object storage instead of streamed
+	exports, err := exportBucket(credentialService)
+	if err != nil {
+		return err
+	}
+	exportURLTTL := envDuration("EXPORT_URL_TTL", 24*time.Hour)
+
+	// Request budget: outbound calls are counted per account, and crawls and
+	// other non-essential background work stop once BACKGROUND_BUDGET_SHARE
+	// of an hourly or daily budget is used. Zero budgets are unlimited.
+	quotaService := service.NewQuotaService(repository.NewUsageRepository(), service.QuotaBudget{
+		Hourly:          envInt("ML_HOURLY_BUDGET", 0),
+		Daily:           envInt("ML_DAILY_BUDGET", 0),
+		BackgroundShare: min(envFloat("BACKGROUND_BUDGET_SHARE", 0.8), 1),
+	})
+
+	orderRepo := repository.NewOrderRepository()
+	jobRepo := repository.NewJobRepository()
+	jobQueue := jobs.NewQueue(jobRepo, envInt("JOB_WORKERS", 2))
+	jobs.RegisterTasks(jobQueue, jobs.Deps{
+		NewMeliClient: newBackgroundClient,
+		TrendRepo:     trendRepo,
+		StatsRepo:     statsRepo,
+		OrderRepo:     orderRepo,
+		Cache:         responseCache,
+		Bus:           bus,
+		Webhooks:      webhookService,
+		Exports:       exports,
+		ExportURLTTL:  exportURLTTL,
+		Quota:         quotaService,
+	})
+	jobs.ForwardEvents(jobQueue, bus, webhookService)
+	// Background work runs on every replica, each scheduled task under its
+	// own lease (BACKGROUND_MODE=locks), or on the elected leader only, queue
+	// workers included (BACKGROUND_MODE=leader); every replica serves HTTP
+	lockTTL := envDuration("SCHEDULER_LOCK_TTL", service.DefaultTaskLockTTL)
+	var (
+		taskLocker *service.TaskLocker
+		leader     *service.LeaderElector
+	)
+	switch mode := envString("BACKGROUND_MODE", "locks"); mode {
+	case "locks":
+		taskLocker = service.NewTaskLocker(repository.NewTaskLockRepository(), lockTTL)
+		// Workers claim the jobs of every organization
+		jobQueue.Start(database.AllOrgs(context.Background()))
+	case "leader":
+		leader = service.NewLeaderElector(repository.NewTaskLockRepository(), lockTTL)
+		go leader.Run(context.Background(), func(ctx context.Context) {
+			jobQueue.Start(database.AllOrgs(ctx))
+		})
+	default:
+		return fmt.Errorf("BACKGROUND_MODE must be locks or leader, got %q", mode)
+	}
+	jobHandler := handlers.NewJobHandler(jobQueue)
+	exportHandler := handlers.NewExportHandler(jobQueue, exports, exportURLTTL)
+
+	// Mercado Livre notifications, dispatched by topic. Failed ones are kept
+	// and retried until they succeed, so no order or question is lost.
+	applicationID, _ := strconv.ParseInt(a.clientID, 10, 64)
+	webhookHandler := handlers.NewWebhookHandler(applicationID)
+	webhookHandler.Handle(meli.TopicQuestions, forSeller(func(ctx context.Context, n meli.Notification) error {
+		// Subscribers may see a question again when it is retried, as they
+		// do on Mercado Livre redeliveries; the auto-responder skips
+		// questions it already handled.
+		bus.Publish(ctx, events.QuestionReceived{QuestionID: n.ResourceID(), SellerID: n.UserID})
+		return service.NewQuestionService(newBackgroundClient(ctx), questionRepo).HandleQuestion(ctx, n.ResourceID())
+	}))
+	webhookHandler.Handle(meli.TopicOrders, forSeller(func(ctx context.Context, n meli.Notification) error {
+		return service.NewOrderService(newBackgroundClient(ctx), orderRepo, bus).HandleOrderNotification(ctx, n)
+	}))
+	// Listing changes, e.g. restocks, re-evaluate the listing's pause rules
+	listingRuleRepo := repository.NewListingRuleRepository()
+	webhookHandler.Handle(meli.TopicItems, forSeller(func(ctx context.Context, n meli.Notification) error {
+		return service.NewListingAutomationService(newBackgroundClient(ctx), listingRuleRepo).HandleItemNotification(ctx, n)
+	}))
+	claimRepo := repository.NewClaimRepository()
+	paymentRepo := repository.NewPaymentRepository()
+	webhookHandler.Handle(meli.TopicClaims, forSeller(func(ctx context.Context, n meli.Notification) error {
+		return service.NewReturnsService(newBackgroundClient(ctx), claimRepo).HandleClaimNotification(ctx, n)
+	}))
+	replayService := service.NewNotificationReplayService(repository.NewNotificationRepository(), webhookHandler.Process)
+	webhookHandler.OnFailure(replayService.Record)
+	notificationReplayHandler := handlers.NewNotificationReplayHandler(replayService)
+
+	// Periodic tasks: warm the response cache for configured hot categories
+	sched := scheduler.New()
+	// Replicas take turns: each run happens on the instance holding the
+	// task's lease, or on the leader, except for tasks on the instance's own
+	// state
+	if leader != nil {
+		sched.SetLocker(leader)
+	} else {
+		sched.SetLocker(taskLocker)
+	}
+	// every registers a task running on the interval of key, which a
+	// settings reload can change
+	every := func(name, key string, def time.Duration, fn scheduler.TaskFunc, opts ...scheduler.TaskOption) {
+		sched.Every(name, envDuration(key, def), fn, opts...)
+		a.settings.Watch(func() {
+			if err := sched.SetInterval(name, envDuration(key, def)); err != nil {
+				log.Printf("[WARN] scheduler: failed to change the interval of %s: %v", name, err)
+			}
+		}, key)
+	}
+	hotCategories := splitList(os.Getenv("CACHE_WARM_CATEGORIES"))
+	every("cache_warmer", "CACHE_WARM_INTERVAL", 5*time.Minute, perOrg(func(ctx context.Context) error {
+		client := newBackgroundClient(ctx)
+		if err := quotaService.CheckBackground(ctx, client); err != nil {
+			return err
+		}
+		svc := service.NewMarketingService(client, trendRepo, responseCache, bus)
+		return svc.WarmCache(ctx, hotCategories, 10)
+	}), scheduler.PerInstance())
+	// Trending keyword snapshots feed the seasonality analysis
+	keywordRepo := repository.NewKeywordTrendRepository()
+	every("keyword_trends", "KEYWORD_TRENDS_INTERVAL", 6*time.Hour, perOrg(func(ctx context.Context) error {
+		client := newBackgroundClient(ctx)
+		if err := quotaService.CheckBackground(ctx, client); err != nil {
+			return err
+		}
+		return service.NewKeywordService(client, keywordRepo).SnapshotKeywords(ctx, hotCategories)
+	}))
+	// Order polling; webhooks may deliver them sooner
+	every("orders_sync", "ORDERS_SYNC_INTERVAL", 10*time.Minute, perOrg(func(ctx context.Context) error {
+		_, err := service.NewOrderService(newBackgroundClient(ctx), orderRepo, bus).SyncOrders(ctx)
+		return err
+	}))
+	// Claims polling, for the returns analytics; webhooks may deliver them sooner
+	every("claims_sync", "CLAIMS_SYNC_INTERVAL", 30*time.Minute, perOrg(func(ctx context.Context) error {
+		_, err := service.NewReturnsService(newBackgroundClient(ctx), claimRepo).SyncClaims(ctx)
+		return err
+	}))
+	// Mercado Pago payments polling, for the payout reconciliation
+	every("payments_sync", "PAYMENTS_SYNC_INTERVAL", time.Hour, perOrg(func(ctx context.Context) error {
+		_, err := service.NewFinanceService(newBackgroundClient(ctx), orderRepo, paymentRepo).SyncPayments(ctx)
+		return err
+	}))
+	// Alert thresholds are read on every run, so a settings reload applies
+	// them to the next one
+	a.settings.Watch(nil, "DISPATCH_WARNING_WINDOW", "REPUTATION_ALERT_MARGIN", "RANK_DROP_THRESHOLD",
+		"INVENTORY_WINDOW_DAYS", "INVENTORY_LEAD_TIME_DAYS", "INVENTORY_SAFETY_DAYS", "INVENTORY_COVERAGE_DAYS")
+	dispatchWarning := func() time.Duration { return envDuration("DISPATCH_WARNING_WINDOW", 12*time.Hour) }
+	every("dispatch_deadlines", "DISPATCH_CHECK_INTERVAL", 30*time.Minute, perOrg(func(ctx context.Context) error {
+		return service.NewShipmentService(newBackgroundClient(ctx), orderRepo, dispatchWarning()).CheckDispatchDeadlines(ctx)
+	}))
+	// Reputation guardrails, fed by the synced orders and claims and the
+	// late dispatches recorded above
+	reputationRepo := repository.NewReputationRepository()
+	reputationMargin := func() float64 { return min(envFloat("REPUTATION_ALERT_MARGIN", 0.2), 1) }
+	every("reputation_guardrails", "REPUTATION_CHECK_INTERVAL", time.Hour, perOrg(func(ctx context.Context) error {
+		return service.NewReputationService(newBackgroundClient(ctx), reputationRepo, reputationMargin()).CheckGuardrails(ctx)
+	}))
+	inventoryRepo := repository.NewInventoryRepository()
+	forecastSettings := func() service.ForecastSettings {
+		return service.ForecastSettings{
+			WindowDays:   envInt("INVENTORY_WINDOW_DAYS", service.DefaultForecastSettings.WindowDays),
+			LeadTimeDays: envInt("INVENTORY_LEAD_TIME_DAYS", service.DefaultForecastSettings.LeadTimeDays),
+			SafetyDays:   envInt("INVENTORY_SAFETY_DAYS", service.DefaultForecastSettings.SafetyDays),
+			CoverageDays: envInt("INVENTORY_COVERAGE_DAYS", service.DefaultForecastSettings.CoverageDays),
+		}
+	}
+	every("reorder_points", "INVENTORY_CHECK_INTERVAL", time.Hour, perOrg(func(ctx context.Context) error {
+		return service.NewInventoryService(newBackgroundClient(ctx), orderRepo, inventoryRepo, forecastSettings()).CheckReorderPoints(ctx)
+	}))
+	watchlistRepo := repository.NewWatchlistRepository()
+	every("watchlist_prices", "WATCHLIST_REFRESH_INTERVAL", 15*time.Minute, perOrg(func(ctx context.Context) error {
+		return service.NewWatchlistService(newBackgroundClient(ctx), watchlistRepo, bus).RefreshPrices(ctx)
+	}))
+	// Sourcing candidates: buy/abort signals from the catalog best price
+	sourcingRepo := repository.NewSourcingRepository()
+	every("sourcing_prices", "SOURCING_REFRESH_INTERVAL", time.Hour, perOrg(func(ctx context.Context) error {
+		return service.NewSourcingService(newBackgroundClient(ctx), sourcingRepo, bus).RefreshPrices(ctx)
+	}))
+	// Competitor listings, diffed against the previous snapshot
+	competitorRepo := repository.NewCompetitorRepository()
+	every("competitor_snapshots", "COMPETITOR_REFRESH_INTERVAL", time.Hour, perOrg(func(ctx context.Context) error {
+		client := newBackgroundClient(ctx)
+		if err := quotaService.CheckBackground(ctx, client); err != nil {
+			return err
+		}
+		return service.NewCompetitorService(client, competitorRepo, bus).RefreshAll(ctx)
+	}))
+	// Share of the top search results of my categories, mine vs. others
+	shelfRepo := repository.NewShelfRepository()
+	every("share_of_shelf", "SHARE_OF_SHELF_INTERVAL", 6*time.Hour, perOrg(func(ctx context.Context) error {
+		client := newBackgroundClient(ctx)
+		if err := quotaService.CheckBackground(ctx, client); err != nil {
+			return err
+		}
+		return service.NewShareOfShelfService(client, shelfRepo, competitorRepo).SnapshotAll(ctx)
+	}))
+	// Search positions of my listings on tracked keywords
+	rankRepo := repository.NewRankRepository()
+	rankDropThreshold := func() int { return envInt("RANK_DROP_THRESHOLD", 10) }
+	every("rank_tracking", "RANK_TRACKING_INTERVAL", 6*time.Hour, perOrg(func(ctx context.Context) error {
+		client := newBackgroundClient(ctx)
+		if err := quotaService.CheckBackground(ctx, client); err != nil {
+			return err
+		}
+		return service.NewRankTrackingService(client, rankRepo, bus, rankDropThreshold()).CheckAll(ctx)
+	}))
+	// Price and title experiments moving to their next variant
+	experimentRepo := repository.NewExperimentRepository()
+	every("experiments", "EXPERIMENTS_INTERVAL", 15*time.Minute, perOrg(func(ctx context.Context) error {
+		return service.NewExperimentService(newBackgroundClient(ctx), experimentRepo, orderRepo).Advance(ctx)
+	}))
+	// Listing pause/reactivate rules
+	every("listing_rules", "LISTING_RULES_INTERVAL", 5*time.Minute, perOrg(func(ctx context.Context) error {
+		return service.NewListingAutomationService(newBackgroundClient(ctx), listingRuleRepo).EvaluateAll(ctx)
+	}))
+	// Custom reports on their schedule, to object storage or a webhook
+	reportDefinitionRepo := repository.NewReportDefinitionRepository()
+	every("custom_reports", "CUSTOM_REPORTS_INTERVAL", 5*time.Minute, perOrg(func(ctx context.Context) error {
+		return service.NewCustomReportService(newBackgroundClient(ctx), reportDefinitionRepo, trendRepo, orderRepo, exports, exportURLTTL, nil).RunDue(ctx)
+	}))
+	// Snapshots, orders and price history replicated to the analytics
+	// warehouse, for every organization at once
+	warehouse, err := warehouseTarget(credentialService)
+	if err != nil {
+		return err
+	}
+	var warehouseService *service.WarehouseService
+	if warehouse != nil {
+		warehouseService = service.NewWarehouseService(warehouse, repository.NewWarehouseRepository(), trendRepo, orderRepo)
+		every("warehouse_sync", "WAREHOUSE_SYNC_INTERVAL", time.Hour, warehouseService.Sync)
+	}
+	// Secrets kept in a secret manager, fetched again to pick up rotations
+	if a.refreshSecrets != nil {
+		every("secrets_refresh", "SECRETS_REFRESH_INTERVAL", 15*time.Minute, a.refreshSecrets, scheduler.PerInstance())
+	}
+	// Failed notifications whose backoff elapsed
+	every("notification_retries", "NOTIFICATION_RETRY_INTERVAL", time.Minute, replayService.RetryDue)
+	// Per-account call counts, stored for the request budget
+	every("api_usage", "API_USAGE_FLUSH_INTERVAL", time.Minute, quotaService.Flush, scheduler.PerInstance())
+	// Past-due plans whose grace period ended
+	if billing != nil {
+		every("billing_grace", "BILLING_GRACE_CHECK_INTERVAL", time.Hour, billing.ExpireGrace)
+	}
+	// Schedules edited at runtime, see /api/admin/schedules; synced so
+	// edits made on other instances apply here too
+	scheduleService := service.NewScheduleService(sched, repository.NewScheduleRepository())
+	if err := scheduleService.Restore(context.Background()); err != nil {
+		log.Printf("[ERROR] scheduler: failed to restore schedules: %v", err)
+	}
+	every("schedules_sync", "SCHEDULES_SYNC_INTERVAL", time.Minute, scheduleService.Restore, scheduler.PerInstance())
+	// Key guessing counters whose window and lockout are over
+	lockoutRepo := repository.NewLockoutRepository()
+	every("lockout_purge", "LOCKOUT_PURGE_INTERVAL", time.Hour, lockoutRepo.Purge)
+	// Organization logins whose callback never arrived
+	if a.orgs != nil {
+		every("org_login_purge", "ORG_LOGIN_PURGE_INTERVAL", time.Hour, a.orgs.PurgeLogins)
+	}
+	// Failed and panicking tasks are reported to Sentry
+	if a.tracker != nil {
+		sched.Wrap(func(name string, fn scheduler.TaskFunc) scheduler.TaskFunc {
+			return a.tracker.Task(name, fn)
+		})
+	}
+	sched.Start(context.Background())
+	// SIGHUP reloads the tuning settings of .env, see SettingsService
+	reloadOnHangup(a.settings)
+
+	// Setup Gin router
+	router := gin.Default()
+	if err := configureProxies(router); err != nil {
+		return err
+	}
+	// Panics and 5xx answers are reported to Sentry
+	router.Use(a.tracker.Middleware())
+	// Messages follow Accept-Language (en, pt-BR, es)
+	router.Use(i18n.Middleware())
+	router.Use(middleware.Compress(middleware.CompressionConfig{
+		Level:        envInt("COMPRESSION_LEVEL", 5),
+		MinSize:      envInt("COMPRESSION_MIN_SIZE", 1024),
+		ExcludePaths: splitList(os.Getenv("COMPRESSION_EXCLUDE_PATHS")),
+	}))
+	// Debug capture of requests, redacted, when switched on; the captures
+	// themselves are not captured
+	router.Use(a.capture.Middleware("/api/admin/debug/capture"))
+	router.Use(middleware.Audit(repository.NewAuditRepository()))
+	// Mutations from the dashboard carry its CSRF token
+	router.Use(middleware.CSRF())
+
+	// Simple health check route
+	router.GET("/health", func(c *gin.Context) {
+		environment := "production"
+		if a.sandboxMode {
+			environment = "sandbox"
+		}
+		c.JSON(200, gin.H{
+			"status":      "ok",
+			"environment": environment,
+		})
+	})
+
+	// OAuth routes (must be registered before API routes)
+	handlers.RegisterOAuthRoutes(router)
+
+	// Mercado Livre notifications (callback URL configured on the application)
+	router.POST("/webhooks/meli", webhookHandler.Receive)
+
+	// Clients guessing the admin or organization keys are locked out for a
+	// while, on every instance: the counters are kept in the database
+	lockout := middleware.NewLockout(middleware.LockoutConfig{
+		MaxFailures:        envInt("LOCKOUT_MAX_FAILURES", 10),
+		AccountMaxFailures: envInt("LOCKOUT_ADMIN_MAX_FAILURES", 50),
+		Window:             envDuration("LOCKOUT_WINDOW", 15*time.Minute),
+		Lockout:            envDuration("LOCKOUT_DURATION", 15*time.Minute),
+	}, lockoutRepo)
+
+	// Multi-tenant mode: API calls carry the key of an organization and only
+	// reach its data
+	orgScope := func(c *gin.Context) { c.Next() }
+	if a.orgs != nil {
+		orgScope = middleware.RequireOrg(a.orgs.Resolve, os.Getenv("ADMIN_API_KEY"), lockout)
+	}
+	// Endpoints that send the seller's data to third parties need the key of
+	// an organization, which orgScope checks, or the admin key; a Mercado
+	// Livre session is not enough
+	requireKey := middleware.RequireAdmin(os.Getenv("ADMIN_API_KEY"), lockout)
+	if a.orgs != nil {
+		requireKey = func(c *gin.Context) { c.Next() }
+	}
+	// With billing, organizations need a working plan, which also sets
+	// their rate limit
+	planLimits := func(c *gin.Context) { c.Next() }
+	if billing != nil {
+		planLimits = middleware.PlanLimits(func(ctx context.Context) (middleware.PlanLimit, error) {
+			e, err := billing.Entitlement(ctx)
+			if err != nil {
+				return middleware.PlanLimit{}, err
+			}
+			return middleware.PlanLimit{Plan: e.Plan, Status: e.Status, Allowed: e.Allowed, RPS: e.RPS, Burst: e.Burst}, nil
+		})
+		router.POST("/billing/stripe/webhook", handlers.NewBillingHandler(billing).ReceiveStripe)
+	}
+
+	// Create middleware to validate token for protected routes
+	requireAuth := func(c *gin.Context) {
+		var token string
+		if a.orgs != nil {
+			token = a.orgs.AccessToken(c.Request.Context())
+		} else {
+			token = handlers.GetTokenFromContext(c)
+		}
+		if token == "" {
+			c.JSON(401, gin.H{"error": i18n.Tr(c, "Authentication required. Please log in first.")})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+
+	// Create a function to get a fresh client with the current token, the
+	// organization's in multi-tenant mode
+	getMeliClient := func(c *gin.Context) *meli.MeliClient {
+		if a.orgs != nil {
+			return a.clientFor(c.Request.Context())
+		}
+		meliAccessToken := handlers.GetTokenFromContext(c)
+		if meliAccessToken == "" {
+			meliAccessToken = os.Getenv("ML_ACCESS_TOKEN") // fallback to env
+			if meliAccessToken == "" {
+				log.Println("[DEBUG] Warning: No token found in context or .env for API request")
+			}
+		}
+		return meli.NewMeliClient(meliAccessToken, a.clientID, a.clientOpts...)
+	}
+
+	scoringService := service.NewScoringService(repository.NewScoringProfileRepository(), trendRepo, statsRepo)
+	scoringHandler := handlers.NewScoringHandler(scoringService)
+
+	historyHandler := handlers.NewHistoryHandler(service.NewHistoryService(trendRepo, keywordRepo))
+	reportHandler := handlers.NewReportHandler(service.NewReportService(trendRepo, responseCache))
+	streamHandler := handlers.NewStreamHandler(service.NewStreamService(trendRepo, statsRepo, keywordRepo, competitorRepo))
+
+	getHeatmapHandler := func(c *gin.Context) *handlers.HeatmapHandler {
+		return handlers.NewHeatmapHandler(service.NewHeatmapService(getMeliClient(c), trendRepo, responseCache))
+	}
+
+	getMarketingHandler := func(c *gin.Context) *handlers.MarketingHandler {
+		marketingService := service.NewMarketingService(getMeliClient(c), trendRepo, responseCache, bus)
+		return handlers.NewMarketingHandler(marketingService, scoringService)
+	}
+
+	getCategoryStatsHandler := func(c *gin.Context) *handlers.CategoryStatsHandler {
+		statsService := service.NewCategoryStatsService(getMeliClient(c), statsRepo)
+		return handlers.NewCategoryStatsHandler(statsService)
+	}
+
+	testUserRepo := repository.NewTestUserRepository()
+	getSandboxHandler := func(c *gin.Context) *handlers.SandboxHandler {
+		sandboxService := service.NewSandboxService(getMeliClient(c), testUserRepo, a.sandboxMode)
+		return handlers.NewSandboxHandler(sandboxService)
+	}
+	e2eRunRepo := repository.NewE2ERunRepository()
+	getE2EHandler := func(c *gin.Context) *handlers.E2EHandler {
+		return handlers.NewE2EHandler(service.NewE2EService(getMeliClient(c), orderRepo, testUserRepo, e2eRunRepo, bus, a.sandboxMode))
+	}
+
+	getSellerHandler := func(c *gin.Context) *handlers.SellerHandler {
+		sellerService := service.NewSellerService(getMeliClient(c))
+		return handlers.NewSellerHandler(sellerService)
+	}
+
+	getItemHandler := func(c *gin.Context) *handlers.ItemHandler {
+		return handlers.NewItemHandler(service.NewItemService(getMeliClient(c)))
+	}
+
+	getSellerProfileHandler := func(c *gin.Context) *handlers.SellerProfileHandler {
+		return handlers.NewSellerProfileHandler(service.NewSellerProfileService(getMeliClient(c), trendRepo))
+	}
+
+	getCompetitorHandler := func(c *gin.Context) *handlers.CompetitorHandler {
+		return handlers.NewCompetitorHandler(service.NewCompetitorService(getMeliClient(c), competitorRepo, bus))
+	}
+
+	getTitleHandler := func(c *gin.Context) *handlers.TitleHandler {
+		return handlers.NewTitleHandler(service.NewTitleService(getMeliClient(c)))
+	}
+
+	getSearchHandler := func(c *gin.Context) *handlers.SearchHandler {
+		return handlers.NewSearchHandler(service.NewSearchService(getMeliClient(c), trendRepo, orderRepo, questionRepo))
+	}
+	getOrderHandler := func(c *gin.Context) *handlers.OrderHandler {
+		return handlers.NewOrderHandler(service.NewOrderService(getMeliClient(c), orderRepo, bus))
+	}
+
+	getShipmentHandler := func(c *gin.Context) *handlers.ShipmentHandler {
+		return handlers.NewShipmentHandler(service.NewShipmentService(getMeliClient(c), orderRepo, dispatchWarning()))
+	}
+
+	getFulfillmentHandler := func(c *gin.Context) *handlers.FulfillmentHandler {
+		return handlers.NewFulfillmentHandler(service.NewFulfillmentService(getMeliClient(c), orderRepo))
+	}
+
+	costRepo := repository.NewProductCostRepository()
+	getInventoryHandler := func(c *gin.Context) *handlers.InventoryHandler {
+		return handlers.NewInventoryHandler(service.NewInventoryService(getMeliClient(c), orderRepo, inventoryRepo, forecastSettings()))
+	}
+	getProfitHandler := func(c *gin.Context) *handlers.ProfitHandler {
+		return handlers.NewProfitHandler(service.NewProfitService(getMeliClient(c), orderRepo, costRepo))
+	}
+	getReturnsHandler := func(c *gin.Context) *handlers.ReturnsHandler {
+		return handlers.NewReturnsHandler(service.NewReturnsService(getMeliClient(c), claimRepo))
+	}
+	getCustomReportHandler := func(c *gin.Context) *handlers.CustomReportHandler {
+		return handlers.NewCustomReportHandler(service.NewCustomReportService(getMeliClient(c), reportDefinitionRepo, trendRepo, orderRepo, exports, exportURLTTL, nil))
+	}
+	getFinanceHandler := func(c *gin.Context) *handlers.FinanceHandler {
+		return handlers.NewFinanceHandler(service.NewFinanceService(getMeliClient(c), orderRepo, paymentRepo))
+	}
+	getReputationHandler := func(c *gin.Context) *handlers.ReputationHandler {
+		return handlers.NewReputationHandler(service.NewReputationService(getMeliClient(c), reputationRepo, reputationMargin()))
+	}
+	getRankTrackingHandler := func(c *gin.Context) *handlers.RankTrackingHandler {
+		return handlers.NewRankTrackingHandler(service.NewRankTrackingService(getMeliClient(c), rankRepo, bus, rankDropThreshold()))
+	}
+	getShareOfShelfHandler := func(c *gin.Context) *handlers.ShareOfShelfHandler {
+		return handlers.NewShareOfShelfHandler(service.NewShareOfShelfService(getMeliClient(c), shelfRepo, competitorRepo))
+	}
+	getExperimentHandler := func(c *gin.Context) *handlers.ExperimentHandler {
+		return handlers.NewExperimentHandler(service.NewExperimentService(getMeliClient(c), experimentRepo, orderRepo))
+	}
+	getListingAutomationHandler := func(c *gin.Context) *handlers.ListingAutomationHandler {
+		return handlers.NewListingAutomationHandler(service.NewListingAutomationService(getMeliClient(c), listingRuleRepo))
+	}
+	skuMappingRepo := repository.NewSKUMappingRepository()
+	getStockHandler := func(c *gin.Context) *handlers.StockHandler {
+		return handlers.NewStockHandler(service.NewStockService(getMeliClient(c), skuMappingRepo))
+	}
+	getSKUMappingHandler := func(c *gin.Context) *handlers.SKUMappingHandler {
+		return handlers.NewSKUMappingHandler(service.NewSKUMappingService(getMeliClient(c), skuMappingRepo))
+	}
+	// Supplier price lists; inbound emails carry no user token and run with
+	// the one in memory, like background jobs
+	supplierEmailToken := os.Getenv("SUPPLIER_EMAIL_TOKEN")
+	supplierEmailSenders := splitList(os.Getenv("SUPPLIER_EMAIL_SENDERS"))
+	newCostImportHandler := func(client *meli.MeliClient) *handlers.CostImportHandler {
+		svc := service.NewCostImportService(client, costRepo, skuMappingRepo, bus, supplierEmailSenders)
+		return handlers.NewCostImportHandler(svc, supplierEmailToken)
+	}
+	router.POST("/inbound/supplier-costs", orgScope, planLimits, func(c *gin.Context) {
+		newCostImportHandler(newBackgroundClient(c.Request.Context())).ReceiveEmail(c)
+	})
+	listingTemplateRepo := repository.NewListingTemplateRepository()
+	getListingHandler := func(c *gin.Context) *handlers.ListingHandler {
+		return handlers.NewListingHandler(service.NewListingService(getMeliClient(c), costRepo, listingTemplateRepo))
+	}
+
+	getQuestionHandler := func(c *gin.Context) *handlers.QuestionHandler {
+		return handlers.NewQuestionHandler(service.NewQuestionService(getMeliClient(c), questionRepo))
+	}
+
+	getWatchlistHandler := func(c *gin.Context) *handlers.WatchlistHandler {
+		watchlistService := service.NewWatchlistService(getMeliClient(c), watchlistRepo, bus)
+		return handlers.NewWatchlistHandler(watchlistService)
+	}
+
+	getSourcingHandler := func(c *gin.Context) *handlers.SourcingHandler {
+		return handlers.NewSourcingHandler(service.NewSourcingService(getMeliClient(c), sourcingRepo, bus))
+	}
+
+	getDashboardHandler := func(c *gin.Context) *handlers.DashboardHandler {
+		meliClient := getMeliClient(c)
+		marketingService := service.NewMarketingService(meliClient, trendRepo, responseCache, bus)
+		return handlers.NewDashboardHandler(service.NewDashboardService(meliClient, marketingService, trendRepo, orderRepo, questionRepo))
+	}
+
+	getGraphQLHandler := func(c *gin.Context) *handlers.GraphQLHandler {
+		meliClient := getMeliClient(c)
+		marketingService := service.NewMarketingService(meliClient, trendRepo, responseCache, bus)
+		return handlers.NewGraphQLHandler(service.NewGraphService(meliClient, marketingService, trendRepo, orderRepo, watchlistRepo), envInt("GRAPHQL_MAX_COMPLEXITY", 0))
+	}
+
+	// One budget per client, shared by the REST and GraphQL APIs
+	rateLimitConfig := func() middleware.RateLimitConfig {
+		return middleware.RateLimitConfig{
+			RPS:   envFloat("RATE_LIMIT_RPS", 5),
+			Burst: envInt("RATE_LIMIT_BURST", 20),
+		}
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimitConfig())
+	a.settings.Watch(func() { rateLimiter.SetConfig(rateLimitConfig()) }, "RATE_LIMIT_RPS", "RATE_LIMIT_BURST")
+	rateLimit := rateLimiter.Handler()
+
+	// GraphQL for the dashboard: nested queries over the same data as /api
+	graphqlGroup := router.Group("/graphql")
+	graphqlGroup.Use(orgScope, rateLimit, planLimits, requireAuth)
+	{
+		graphqlGroup.GET("", func(c *gin.Context) {
+			getGraphQLHandler(c).Query(c)
+		})
+		graphqlGroup.POST("", func(c *gin.Context) {
+			getGraphQLHandler(c).Query(c)
+		})
+		graphqlGroup.GET("/schema", func(c *gin.Context) {
+			getGraphQLHandler(c).GetSchema(c)
+		})
+	}
+
+	// API routes with dynamic token refresh
+	apiGroup := router.Group("/api")
+	apiGroup.Use(orgScope, rateLimit, planLimits)
+	{
+		// Categories - can work without auth for public data
+		apiGroup.GET("/categories", func(c *gin.Context) {
+			getMarketingHandler(c).GetCategories(c)
+		})
+		// Category stats from the latest full crawl
+		apiGroup.GET("/categories/:id/stats", func(c *gin.Context) {
+			getCategoryStatsHandler(c).GetCategoryStats(c)
+		})
+		// How hard entering a category is, from a live sample of its results
+		apiGroup.GET("/categories/:id/entry-report", requireAuth, func(c *gin.Context) {
+			getCategoryStatsHandler(c).GetEntryReport(c)
+		})
+		// Seasonal demand from stored snapshots
+		apiGroup.GET("/categories/:id/seasonality", historyHandler.GetSeasonality)
+		// Full category crawl - runs as a background job
+		apiGroup.POST("/categories/:id/crawl", requireAuth, jobHandler.EnqueueCategoryCrawl)
+		// Trends - requires authentication
+		apiGroup.GET("/trends", requireAuth, func(c *gin.Context) {
+			if c.Query("async") == "true" {
+				jobHandler.EnqueueTopTrends(c)
+				return
+			}
+			if c.Query("debug_product") != "" && !middleware.IsAdmin(c, os.Getenv("ADMIN_API_KEY")) {
+				c.JSON(http.StatusForbidden, gin.H{"error": i18n.Tr(c, "debug_product requires the admin key")})
+				return
+			}
+			getMarketingHandler(c).GetTopTrends(c)
+		})
+		// Category suggest - requires authentication
+		apiGroup.GET("/category_suggest", requireAuth, func(c *gin.Context) {
+			getMarketingHandler(c).SuggestCategory(c)
+		})
+		apiGroup.POST("/category_suggest/batch", requireAuth, func(c *gin.Context) {
+			getMarketingHandler(c).SuggestCategoryBatch(c)
+		})
+		// Supplier catalog screening: CSV in, ranked report out
+		apiGroup.POST("/imports/supplier-catalog", requireAuth, jobHandler.EnqueueSupplierScreening)
+		apiGroup.GET("/imports/supplier-catalog/:id/report.csv", requireAuth, jobHandler.GetScreeningReport)
+		// Large exports: a job uploads the file to object storage
+		apiGroup.POST("/exports", requireAuth, exportHandler.EnqueueExport)
+		apiGroup.GET("/exports/:id", requireAuth, exportHandler.DownloadExport)
+		// Incremental NDJSON pulls for data pipelines
+		apiGroup.GET("/export/stream", requireAuth, streamHandler.Stream)
+		// Outbound webhooks for internal events
+		apiGroup.GET("/webhooks", requireKey, webhookSubscriptionHandler.List)
+		apiGroup.POST("/webhooks", requireKey, webhookSubscriptionHandler.Create)
+		apiGroup.PUT("/webhooks/:id", requireKey, webhookSubscriptionHandler.Put)
+		apiGroup.DELETE("/webhooks/:id", requireKey, webhookSubscriptionHandler.Delete)
+		apiGroup.POST("/webhooks/:id/test", requireKey, webhookSubscriptionHandler.Test)
+		// Single listing with its buy box
+		apiGroup.GET("/items/:id", requireAuth, func(c *gin.Context) {
+			getItemHandler(c).GetItem(c)
+		})
+		// Other sellers: reputation, catalog and trending listings
+		apiGroup.GET("/sellers/:id", requireAuth, func(c *gin.Context) {
+			getSellerProfileHandler(c).GetSellerProfile(c)
+		})
+		// Competitor watch: snapshots by the scheduler, change feed
+		apiGroup.GET("/competitors", func(c *gin.Context) {
+			getCompetitorHandler(c).ListCompetitors(c)
+		})
+		apiGroup.POST("/competitors", requireAuth, func(c *gin.Context) {
+			getCompetitorHandler(c).Watch(c)
+		})
+		apiGroup.DELETE("/competitors/:id", requireAuth, func(c *gin.Context) {
+			getCompetitorHandler(c).Unwatch(c)
+		})
+		apiGroup.GET("/competitors/:id/changes", func(c *gin.Context) {
+			getCompetitorHandler(c).GetChanges(c)
+		})
+		// Rank history from stored highlight snapshots
+		apiGroup.GET("/products/:id/rank-history", historyHandler.GetRankHistory)
+		// Weekly movers across categories, from stored snapshots
+		apiGroup.GET("/reports/movers", reportHandler.GetMovers)
+		// Demand and price heat per level-2 category, for a treemap
+		apiGroup.GET("/reports/heatmap", func(c *gin.Context) {
+			getHeatmapHandler(c).GetHeatmap(c)
+		})
+		// Local search over persisted data; orders and questions are the
+		// authenticated seller's
+		apiGroup.GET("/search/local", requireAuth, func(c *gin.Context) {
+			getSearchHandler(c).SearchLocal(c)
+		})
+		// Sandbox test users (ML_ENVIRONMENT=sandbox only); listed under
+		// /api/admin
+		apiGroup.POST("/sandbox/test-users", requireAuth, func(c *gin.Context) {
+			getSandboxHandler(c).CreateTestUser(c)
+		})
+		// Scoring profiles for /api/trends?profile=<name>
+		apiGroup.GET("/scoring-profiles", scoringHandler.ListProfiles)
+		apiGroup.GET("/scoring-profiles/:name", scoringHandler.GetProfile)
+		apiGroup.PUT("/scoring-profiles/:name", requireAuth, scoringHandler.PutProfile)
+		apiGroup.DELETE("/scoring-profiles/:name", requireAuth, scoringHandler.DeleteProfile)
+		// Watchlist with price alerts - refreshed by the scheduler
+		apiGroup.GET("/watchlist", func(c *gin.Context) {
+			getWatchlistHandler(c).ListWatched(c)
+		})
+		apiGroup.POST("/watchlist", requireAuth, func(c *gin.Context) {
+			getWatchlistHandler(c).Watch(c)
+		})
+		apiGroup.PUT("/watchlist/:product_id", requireAuth, func(c *gin.Context) {
+			getWatchlistHandler(c).UpdateThresholds(c)
+		})
+		apiGroup.DELETE("/watchlist/:product_id", requireAuth, func(c *gin.Context) {
+			getWatchlistHandler(c).Unwatch(c)
+		})
+		apiGroup.GET("/watchlist/alerts", func(c *gin.Context) {
+			getWatchlistHandler(c).ListAlerts(c)
+		})
+		// Products removed from the watchlist, until restored
+		apiGroup.GET("/watchlist/trash", func(c *gin.Context) {
+			getWatchlistHandler(c).ListTrash(c)
+		})
+		apiGroup.POST("/watchlist/:product_id/restore", func(c *gin.Context) {
+			getWatchlistHandler(c).Restore(c)
+		})
+		// Products I'm considering sourcing: margin at the catalog best price
+		apiGroup.GET("/sourcing", func(c *gin.Context) {
+			getSourcingHandler(c).ListCandidates(c)
+		})
+		apiGroup.POST("/sourcing", requireAuth, func(c *gin.Context) {
+			getSourcingHandler(c).Track(c)
+		})
+		apiGroup.PUT("/sourcing/:product_id", requireAuth, func(c *gin.Context) {
+			getSourcingHandler(c).UpdateTerms(c)
+		})
+		apiGroup.DELETE("/sourcing/:product_id", requireAuth, func(c *gin.Context) {
+			getSourcingHandler(c).Untrack(c)
+		})
+		apiGroup.GET("/sourcing/signals", func(c *gin.Context) {
+			getSourcingHandler(c).ListSignals(c)
+		})
+		// Index page widgets in one call
+		apiGroup.GET("/dashboard/summary", requireAuth, func(c *gin.Context) {
+			getDashboardHandler(c).GetSummary(c)
+		})
+		// Background job status polling; job results hold the seller's
+		// data, and in multi-tenant mode only the jobs of the caller's
+		// organization are found
+		apiGroup.GET("/jobs/:id", requireAuth, jobHandler.GetJob)
+	}
+
+	// My listings - in sandbox mode, changes are only allowed for test users
+	myGroup := apiGroup.Group("/my")
+	if a.sandboxMode {
+		myGroup.Use(handlers.RequireTestAccount(testUserRepo, getMeliClient))
+	}
+	{
+		// Catalog eligibility of my listings - requires authentication
+		myGroup.GET("/items/catalog-eligibility", requireAuth, func(c *gin.Context) {
+			if c.Query("async") == "true" {
+				jobHandler.EnqueueCatalogEligibility(c)
+				return
+			}
+			getSellerHandler(c).GetCatalogEligibility(c)
+		})
+		// Listings of mine the category predictor would place elsewhere
+		myGroup.GET("/items/category-audit", requireAuth, func(c *gin.Context) {
+			if c.Query("async") == "true" {
+				jobHandler.EnqueueCategoryAudit(c)
+				return
+			}
+			getSellerHandler(c).GetCategoryAudit(c)
+		})
+		// Listings of mine that cannibalize each other
+		myGroup.GET("/items/overlaps", requireAuth, func(c *gin.Context) {
+			getSellerHandler(c).GetOverlaps(c)
+		})
+		// Orders synced from Mercado Livre and their analytics
+		myGroup.POST("/orders/sync", requireAuth, func(c *gin.Context) {
+			getOrderHandler(c).SyncOrders(c)
+		})
+		myGroup.GET("/orders/:id/history", requireAuth, func(c *gin.Context) {
+			getOrderHandler(c).GetOrderHistory(c)
+		})
+		myGroup.GET("/analytics/sales", requireAuth, func(c *gin.Context) {
+			getOrderHandler(c).GetSalesAnalytics(c)
+		})
+		// Claims and returns per SKU and category
+		myGroup.POST("/claims/sync", requireAuth, func(c *gin.Context) {
+			getReturnsHandler(c).SyncClaims(c)
+		})
+		myGroup.GET("/analytics/returns", requireAuth, func(c *gin.Context) {
+			getReturnsHandler(c).GetReturns(c)
+		})
+		// Mercado Pago payments against orders: which sales were paid out
+		myGroup.POST("/finance/payments/sync", requireAuth, func(c *gin.Context) {
+			getFinanceHandler(c).SyncPayments(c)
+		})
+		myGroup.GET("/finance/reconciliation", requireAuth, func(c *gin.Context) {
+			getFinanceHandler(c).GetReconciliation(c)
+		})
+		// Cash Mercado Pago will release per day, for purchase planning
+		myGroup.GET("/finance/projection", requireAuth, func(c *gin.Context) {
+			getFinanceHandler(c).GetProjection(c)
+		})
+		// Custom reports over my orders and the snapshots, run on demand
+		// or on their schedule
+		myGroup.GET("/reports/sources", requireAuth, func(c *gin.Context) {
+			getCustomReportHandler(c).ListSources(c)
+		})
+		myGroup.GET("/reports", requireAuth, func(c *gin.Context) {
+			getCustomReportHandler(c).List(c)
+		})
+		myGroup.POST("/reports", requireAuth, func(c *gin.Context) {
+			getCustomReportHandler(c).Create(c)
+		})
+		myGroup.GET("/reports/:id", requireAuth, func(c *gin.Context) {
+			getCustomReportHandler(c).Get(c)
+		})
+		myGroup.PUT("/reports/:id", requireAuth, func(c *gin.Context) {
+			getCustomReportHandler(c).Put(c)
+		})
+		myGroup.DELETE("/reports/:id", requireAuth, func(c *gin.Context) {
+			getCustomReportHandler(c).Delete(c)
+		})
+		myGroup.POST("/reports/:id/run", requireAuth, func(c *gin.Context) {
+			getCustomReportHandler(c).Run(c)
+		})
+		myGroup.GET("/reports/:id/data", requireAuth, func(c *gin.Context) {
+			getCustomReportHandler(c).GetData(c)
+		})
+		// Share of the top search results of my categories
+		myGroup.GET("/analytics/share-of-shelf", requireAuth, func(c *gin.Context) {
+			getShareOfShelfHandler(c).GetShareOfShelf(c)
+		})
+		myGroup.POST("/analytics/share-of-shelf/snapshot", requireAuth, func(c *gin.Context) {
+			getShareOfShelfHandler(c).TakeSnapshot(c)
+		})
+		// Search positions of my listings on chosen keywords
+		myGroup.GET("/rank-tracking", requireAuth, func(c *gin.Context) {
+			getRankTrackingHandler(c).ListTrackers(c)
+		})
+		myGroup.POST("/rank-tracking", requireAuth, func(c *gin.Context) {
+			getRankTrackingHandler(c).CreateTracker(c)
+		})
+		myGroup.POST("/rank-tracking/check", requireAuth, func(c *gin.Context) {
+			getRankTrackingHandler(c).CheckNow(c)
+		})
+		myGroup.GET("/rank-tracking/:id", requireAuth, func(c *gin.Context) {
+			getRankTrackingHandler(c).GetHistory(c)
+		})
+		myGroup.DELETE("/rank-tracking/:id", requireAuth, func(c *gin.Context) {
+			getRankTrackingHandler(c).DeleteTracker(c)
+		})
+		// Price and title experiments on my listings
+		myGroup.GET("/experiments", requireAuth, func(c *gin.Context) {
+			getExperimentHandler(c).ListExperiments(c)
+		})
+		myGroup.POST("/experiments", requireAuth, func(c *gin.Context) {
+			getExperimentHandler(c).CreateExperiment(c)
+		})
+		myGroup.GET("/experiments/:id", requireAuth, func(c *gin.Context) {
+			getExperimentHandler(c).GetExperiment(c)
+		})
+		myGroup.POST("/experiments/:id/stop", requireAuth, func(c *gin.Context) {
+			getExperimentHandler(c).StopExperiment(c)
+		})
+		// Reputation metrics against Mercado Livre's levels, and alerts
+		myGroup.GET("/reputation", requireAuth, func(c *gin.Context) {
+			getReputationHandler(c).GetStanding(c)
+		})
+		myGroup.GET("/reputation/alerts", requireAuth, func(c *gin.Context) {
+			getReputationHandler(c).ListAlerts(c)
+		})
+		// Orders awaiting dispatch and their handling deadlines
+		myGroup.GET("/shipments/pending", requireAuth, func(c *gin.Context) {
+			getShipmentHandler(c).GetPendingShipments(c)
+		})
+		// Stock in Mercado Livre's warehouses (FULL) vs. our own
+		myGroup.GET("/fulfillment/stock", requireAuth, func(c *gin.Context) {
+			getFulfillmentHandler(c).GetStock(c)
+		})
+		// Unit costs per SKU and the resulting profit
+		myGroup.GET("/costs", requireAuth, func(c *gin.Context) {
+			getProfitHandler(c).ListCosts(c)
+		})
+		myGroup.POST("/costs/import", requireAuth, func(c *gin.Context) {
+			newCostImportHandler(getMeliClient(c)).ImportCosts(c)
+		})
+		myGroup.PUT("/costs/:sku", requireAuth, func(c *gin.Context) {
+			getProfitHandler(c).PutCost(c)
+		})
+		myGroup.DELETE("/costs/:sku", requireAuth, func(c *gin.Context) {
+			getProfitHandler(c).DeleteCost(c)
+		})
+		myGroup.GET("/analytics/profit", requireAuth, func(c *gin.Context) {
+			getProfitHandler(c).GetProfit(c)
+		})
+		// Stockout forecast and reorder suggestions
+		myGroup.GET("/inventory/forecast", requireAuth, func(c *gin.Context) {
+			getInventoryHandler(c).GetForecast(c)
+		})
+		myGroup.GET("/inventory/alerts", requireAuth, func(c *gin.Context) {
+			getInventoryHandler(c).ListReorderAlerts(c)
+		})
+		// Question auto-responder: templates, opt-in and review queue
+		myGroup.GET("/questions/templates", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).ListTemplates(c)
+		})
+		myGroup.POST("/questions/templates", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).CreateTemplate(c)
+		})
+		myGroup.PUT("/questions/templates/:id", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).PutTemplate(c)
+		})
+		myGroup.DELETE("/questions/templates/:id", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).DeleteTemplate(c)
+		})
+		myGroup.GET("/questions/templates/trash", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).ListTrashedTemplates(c)
+		})
+		myGroup.POST("/questions/templates/:id/restore", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).RestoreTemplate(c)
+		})
+		myGroup.GET("/questions/auto-responder", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).GetAutoResponder(c)
+		})
+		myGroup.PUT("/questions/auto-responder", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).PutAutoResponder(c)
+		})
+		myGroup.PUT("/questions/auto-responder/opt-out/:item_id", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).OptOutItem(c)
+		})
+		myGroup.DELETE("/questions/auto-responder/opt-out/:item_id", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).OptInItem(c)
+		})
+		myGroup.GET("/questions/review", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).ListReviewQueue(c)
+		})
+		myGroup.POST("/questions/review/:id/approve", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).ApproveAnswer(c)
+		})
+		myGroup.POST("/questions/review/:id/dismiss", requireAuth, func(c *gin.Context) {
+			getQuestionHandler(c).DismissAnswer(c)
+		})
+		// Title suggestions against the category's top sellers
+		myGroup.GET("/items/:id/title-suggestions", requireAuth, func(c *gin.Context) {
+			getTitleHandler(c).GetTitleSuggestions(c)
+		})
+		// Experiments of one listing, e.g. A/B tests of two titles
+		myGroup.GET("/items/:id/experiments", requireAuth, func(c *gin.Context) {
+			getExperimentHandler(c).ListItemExperiments(c)
+		})
+		myGroup.POST("/items/:id/experiments", requireAuth, func(c *gin.Context) {
+			getExperimentHandler(c).CreateItemExperiment(c)
+		})
+		// Preflight checks of a listing draft before publishing it
+		myGroup.POST("/items/validate", requireAuth, func(c *gin.Context) {
+			getListingHandler(c).ValidateItem(c)
+		})
+		// Listing templates for repeated product types
+		myGroup.GET("/listing-templates", requireAuth, func(c *gin.Context) {
+			getListingHandler(c).ListTemplates(c)
+		})
+		myGroup.POST("/listing-templates", requireAuth, func(c *gin.Context) {
+			getListingHandler(c).CreateTemplate(c)
+		})
+		myGroup.PUT("/listing-templates/:id", requireAuth, func(c *gin.Context) {
+			getListingHandler(c).PutTemplate(c)
+		})
+		myGroup.DELETE("/listing-templates/:id", requireAuth, func(c *gin.Context) {
+			getListingHandler(c).DeleteTemplate(c)
+		})
+		myGroup.GET("/listing-templates/trash", requireAuth, func(c *gin.Context) {
+			getListingHandler(c).ListTrashedTemplates(c)
+		})
+		myGroup.POST("/listing-templates/:id/restore", requireAuth, func(c *gin.Context) {
+			getListingHandler(c).RestoreTemplate(c)
+		})
+		myGroup.POST("/items/from-template", requireAuth, func(c *gin.Context) {
+			getListingHandler(c).CreateFromTemplate(c)
+		})
+		// Bulk stock adjustment, e.g. after an inventory count
+		myGroup.PATCH("/items/stock", requireAuth, func(c *gin.Context) {
+			getStockHandler(c).AdjustStock(c)
+		})
+		// My own SKUs (e.g. ERP codes) mapped to listings and variations
+		myGroup.GET("/sku-mappings", requireAuth, func(c *gin.Context) {
+			getSKUMappingHandler(c).ListMappings(c)
+		})
+		myGroup.PUT("/sku-mappings", requireAuth, func(c *gin.Context) {
+			getSKUMappingHandler(c).PutMappings(c)
+		})
+		myGroup.DELETE("/sku-mappings/:id", requireAuth, func(c *gin.Context) {
+			getSKUMappingHandler(c).DeleteMapping(c)
+		})
+		// Pause/reactivate rules of my listings and their action log
+		myGroup.GET("/listing-rules", requireAuth, func(c *gin.Context) {
+			getListingAutomationHandler(c).ListRules(c)
+		})
+		myGroup.POST("/listing-rules", requireAuth, func(c *gin.Context) {
+			getListingAutomationHandler(c).CreateRule(c)
+		})
+		myGroup.PUT("/listing-rules/:id", requireAuth, func(c *gin.Context) {
+			getListingAutomationHandler(c).PutRule(c)
+		})
+		myGroup.DELETE("/listing-rules/:id", requireAuth, func(c *gin.Context) {
+			getListingAutomationHandler(c).DeleteRule(c)
+		})
+		myGroup.GET("/listing-rules/trash", requireAuth, func(c *gin.Context) {
+			getListingAutomationHandler(c).ListTrashedRules(c)
+		})
+		myGroup.POST("/listing-rules/:id/restore", requireAuth, func(c *gin.Context) {
+			getListingAutomationHandler(c).RestoreRule(c)
+		})
+		myGroup.GET("/listing-rules/actions", requireAuth, func(c *gin.Context) {
+			getListingAutomationHandler(c).ListActions(c)
+		})
+		myGroup.POST("/listing-rules/evaluate", requireAuth, func(c *gin.Context) {
+			getListingAutomationHandler(c).Evaluate(c)
+		})
+	}
+
+	// Operator endpoints, protected by ADMIN_API_KEY
+	statusService := service.NewStatusService(jobRepo, repository.NewSystemRepository(), responseCache, sched, taskLocker, leader, a.limiter, func() int {
+		if handlers.GetCurrentToken() != "" {
+			return 1
+		}
+		return 0
+	})
+	adminHandler := handlers.NewAdminHandler(repository.NewAuditRepository(), statusService, quotaService)
+	adminGroup := apiGroup.Group("/admin", middleware.RequireAdmin(os.Getenv("ADMIN_API_KEY"), lockout))
+	{
+		adminGroup.GET("/audit", adminHandler.GetAuditLog)
+		adminGroup.GET("/sandbox/test-users", func(c *gin.Context) {
+			getSandboxHandler(c).ListTestUsers(c)
+		})
+		adminGroup.GET("/status", adminHandler.GetStatus)
+		adminGroup.GET("/quota", adminHandler.GetQuota)
+		adminGroup.GET("/jobs/failed", adminHandler.ListFailedJobs)
+		scheduleHandler := handlers.NewScheduleHandler(scheduleService)
+		adminGroup.GET("/schedules", scheduleHandler.ListSchedules)
+		adminGroup.GET("/schedules/:name", scheduleHandler.GetSchedule)
+		adminGroup.PATCH("/schedules/:name", scheduleHandler.UpdateSchedule)
+		adminGroup.GET("/webhooks/failed", notificationReplayHandler.ListFailed)
+		adminGroup.POST("/webhooks/replay", notificationReplayHandler.Replay)
+		// End-to-end check of the order pipeline with test users
+		// (ML_ENVIRONMENT=sandbox only)
+		adminGroup.POST("/e2e", func(c *gin.Context) {
+			getE2EHandler(c).StartRun(c)
+		})
+		adminGroup.GET("/e2e/:id", func(c *gin.Context) {
+			getE2EHandler(c).VerifyRun(c)
+		})
+		adminGroup.GET("/debug/traces", adminHandler.GetDebugTraces)
+		adminGroup.GET("/debug/traces/:product_id", adminHandler.GetDebugTraces)
+		captureHandler := handlers.NewCaptureHandler(a.capture)
+		adminGroup.GET("/debug/captures", captureHandler.ListCaptures)
+		adminGroup.POST("/debug/capture", captureHandler.SetCapture)
+		// Automation config as YAML, to promote it between environments
+		configHandler := handlers.NewConfigHandler(service.NewConfigService(watchlistRepo, listingRuleRepo, listingTemplateRepo, questionRepo, repository.NewScoringProfileRepository()))
+		adminGroup.GET("/config/export", configHandler.Export)
+		adminGroup.POST("/config/import", configHandler.Import)
+		// Tuning settings reloaded from .env without a restart, like SIGHUP
+		settingsHandler := handlers.NewSettingsHandler(a.settings)
+		adminGroup.GET("/settings", settingsHandler.GetSettings)
+		adminGroup.POST("/settings/reload", settingsHandler.Reload)
+		if credentialService != nil {
+			credentialHandler := handlers.NewCredentialHandler(credentialService)
+			adminGroup.GET("/credentials", credentialHandler.ListCredentials)
+			adminGroup.GET("/credentials/:name", credentialHandler.GetCredential)
+			adminGroup.PUT("/credentials/:name", credentialHandler.PutCredential)
+			adminGroup.DELETE("/credentials/:name", credentialHandler.DeleteCredential)
+		}
+		// Replication to the analytics warehouse (WAREHOUSE_PROVIDER set)
+		if warehouseService != nil {
+			warehouseHandler := handlers.NewWarehouseHandler(warehouseService)
+			adminGroup.GET("/warehouse/checkpoints", warehouseHandler.GetCheckpoints)
+			adminGroup.POST("/warehouse/sync", warehouseHandler.Sync)
+		}
+	}
+
+	// Organizations of multi-tenant mode: operators manage them, and each one
+	// connects its own Mercado Livre account. These routes work without a
+	// plan, so organizations can see and fix theirs.
+	if a.orgs != nil {
+		orgHandler := handlers.NewOrganizationHandler(a.orgs)
+		handlers.HandleStateCallbacks(orgHandler.Callback)
+		orgGroup := router.Group("/api/org", orgScope, rateLimit)
+		orgGroup.GET("", orgHandler.GetCurrent)
+		orgGroup.GET("/login", orgHandler.Login)
+		adminGroup.GET("/organizations", orgHandler.ListOrganizations)
+		adminGroup.POST("/organizations", orgHandler.CreateOrganization)
+		adminGroup.POST("/organizations/:id/rotate-key", orgHandler.RotateKey)
+		adminGroup.DELETE("/organizations/:id", orgHandler.DeleteOrganization)
+		if billing != nil {
+			billingHandler := handlers.NewBillingHandler(billing)
+			orgGroup.GET("/plan", billingHandler.GetPlan)
+			adminGroup.PUT("/organizations/:id/plan", billingHandler.SetPlan)
+		}
+	}
+
+	// Static dashboard, embedded unless --web-dir is set
+	router.StaticFS("/static", http.FS(assets))
+	router.GET("/", servePage(assets, "index.html"))
+	router.GET("/oauth-help", servePage(assets, "oauth_help.html"))
+
+	if tlsOpts.enabled() {
+		log.Println("Servidor HTTPS iniciado na porta", *port)
+	} else {
+		log.Println("Servidor iniciado na porta", *port)
+	}
+	return listen(":"+*port, router, tlsOpts)
+}
+
+// reloadOnHangup reloads settings on every SIGHUP.
+func reloadOnHangup(settings *service.SettingsService) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			result, err := settings.Reload()
+			if err != nil {
+				log.Printf("[ERROR] settings: reload failed: %v", err)
+				continue
+			}
+			log.Printf("[INFO] settings: reloaded, %d changed", len(result.Changed))
+		}
+	}()
+}
+
+// configureProxies decides which client IP request logs, rate limiting and
+// the audit log see. Forwarding headers are honored only when the request
+// comes from TRUSTED_PROXIES (IPs or CIDRs, e.g. the nginx host); without
+// it the peer address is used, so clients can't spoof their IP. TRUSTED_PLATFORM=cloudflare reads CF-Connecting-IP.
+func configureProxies(router *gin.Engine) error {
+	proxies := splitList(os.Getenv("TRUSTED_PROXIES"))
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	if headers := splitList(os.Getenv("REMOTE_IP_HEADERS")); len(headers) > 0 {
+		router.RemoteIPHeaders = headers
+	}
+
+	switch platform := os.Getenv("TRUSTED_PLATFORM"); strings.ToLower(platform) {
+	case "":
+	case "cloudflare":
+		router.TrustedPlatform = gin.PlatformCloudflare
+	case "google", "appengine":
+		router.TrustedPlatform = gin.PlatformGoogleAppEngine
+	default:
+		// Any other value names the header the platform sets
+		router.TrustedPlatform = platform
+	}
+	if len(proxies) > 0 {
+		log.Printf("[INFO] Trusting %s from proxies %s", strings.Join(router.RemoteIPHeaders, ", "), strings.Join(proxies, ", "))
+	}
+	return nil
+}
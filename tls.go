@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// tlsSettings selects how serve listens: plain HTTP, HTTPS with a
+// certificate from disk, or HTTPS with certificates obtained from Let's
+// Encrypt for the configured domains.
+type tlsSettings struct {
+	certFile     string
+	keyFile      string
+	domains      []string
+	cacheDir     string
+	email        string
+	redirectAddr string // plain HTTP listener redirecting to HTTPS, "" for none
+}
+
+func (s tlsSettings) enabled() bool {
+	return s.certFile != "" || len(s.domains) > 0
+}
+
+func (s tlsSettings) validate() error {
+	if (s.certFile == "") != (s.keyFile == "") {
+		return errors.New("--tls-cert and --tls-key must be set together")
+	}
+	if s.certFile != "" && len(s.domains) > 0 {
+		return errors.New("use either a certificate file or --autocert-domains, not both")
+	}
+	if s.redirectAddr != "" && !s.enabled() {
+		return errors.New("--redirect-http needs TLS to be configured")
+	}
+	return nil
+}
+
+// listen serves handler on addr until it fails.
+func listen(addr string, handler http.Handler, s tlsSettings) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	if !s.enabled() {
+		return srv.ListenAndServe()
+	}
+
+	srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	var redirect http.Handler = httpsRedirect(addr)
+	if len(s.domains) > 0 {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(s.domains...),
+			Cache:      autocert.DirCache(s.cacheDir),
+			Email:      s.email,
+		}
+		srv.TLSConfig = m.TLSConfig()
+		srv.TLSConfig.MinVersion = tls.VersionTLS12
+		// Let's Encrypt validates the domains over plain HTTP, so the
+		// redirect listener also answers its challenges
+		redirect = m.HTTPHandler(redirect)
+		if s.redirectAddr == "" {
+			log.Println("[WARN] autocert without --redirect-http only works with the TLS-ALPN challenge on port 443")
+		}
+	}
+
+	if s.redirectAddr != "" {
+		go func() {
+			plain := &http.Server{
+				Addr:              s.redirectAddr,
+				Handler:           redirect,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			log.Println("Redirecionando HTTP para HTTPS em", s.redirectAddr)
+			if err := plain.ListenAndServe(); err != nil {
+				log.Printf("[ERROR] HTTP redirect listener stopped: %v", err)
+			}
+		}()
+	}
+	return srv.ListenAndServeTLS(s.certFile, s.keyFile)
+}
+
+// httpsRedirect sends every request to the same URL over HTTPS on the port
+// of tlsAddr. 308 keeps the method, so OAuth and webhook POSTs survive it.
+func httpsRedirect(tlsAddr string) http.HandlerFunc {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed web
+var embeddedWeb embed.FS
+
+// webFS returns the dashboard assets: the copy embedded in the binary, or
+// the files under dir when it is set, so edits show up without a rebuild.
+func webFS(dir string) (fs.FS, error) {
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, err
+		}
+		return os.DirFS(dir), nil
+	}
+	return fs.Sub(embeddedWeb, "web")
+}
+
+// servePage serves one HTML page of the dashboard. It reads the file on
+// every request rather than going through http.FileServer, which redirects
+// requests for index.html.
+func servePage(assets fs.FS, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := fs.ReadFile(assets, name)
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", data)
+	}
+}